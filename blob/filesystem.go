@@ -0,0 +1,85 @@
+// Package blob stores file contents outside of database.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidKey returned when a key escapes root directory of store.
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// FileSystem represents a blob store backed by a local directory.
+type FileSystem struct {
+	root string
+}
+
+// NewFileSystem returns a new instance of FileSystem rooted at dir.
+func NewFileSystem(dir string) FileSystem {
+	return FileSystem{root: dir}
+}
+
+// Put writes contents of r under key and returns number of written bytes.
+func (f FileSystem) Put(_ context.Context, key string, r io.Reader) (int64, error) {
+	path, err := f.path(key)
+	if err != nil {
+		return 0, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o750)
+	if err != nil {
+		return 0, err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := io.Copy(file, r)
+	if err != nil {
+		file.Close()
+		os.Remove(path)
+		return 0, err
+	}
+
+	return n, file.Close()
+}
+
+// Get opens contents stored under key.
+func (f FileSystem) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	path, err := f.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Open(path)
+}
+
+// Delete removes contents stored under key.
+func (f FileSystem) Delete(_ context.Context, key string) error {
+	path, err := f.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (f FileSystem) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if key == "" || strings.Contains(key, "..") {
+		return "", ErrInvalidKey
+	}
+
+	return filepath.Join(f.root, clean), nil
+}
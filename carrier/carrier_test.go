@@ -0,0 +1,48 @@
+package carrier_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mortezadadgar/ecommerce-api/carrier"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+func TestMock(t *testing.T) {
+	mock := carrier.NewMock([]byte("secret"))
+
+	payload := []byte(`{"id":"trk_1","tracking_number":"1Z999","status":"in_transit",` +
+		`"description":"left facility","occurred_at":"2024-01-02T03:04:05Z"}`)
+
+	_, err := mock.ParseEvent(payload, "bad")
+	if err != domain.ErrInvalidWebhookSignature {
+		t.Errorf("expected %q from ParseEvent, got %q", domain.ErrInvalidWebhookSignature, err)
+	}
+
+	_, err = carrier.NewMock(nil).ParseEvent(payload, mock.Sign(payload))
+	if err != domain.ErrInvalidWebhookSignature {
+		t.Errorf("expected %q without secret, got %q", domain.ErrInvalidWebhookSignature, err)
+	}
+
+	event, err := mock.ParseEvent(payload, mock.Sign(payload))
+	if err != nil {
+		t.Fatalf("ParseEvent: %v", err)
+	}
+
+	want := domain.TrackingEvent{
+		ID:             "trk_1",
+		TrackingNumber: "1Z999",
+		Status:         domain.ShipmentStatusInTransit,
+		Description:    "left facility",
+		OccurredAt:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	if event != want {
+		t.Errorf("mismatch\n got: %#v\nwant: %#v", event, want)
+	}
+
+	payload = []byte(`{"id":"trk_2","status":"delivered"}`)
+	_, err = mock.ParseEvent(payload, mock.Sign(payload))
+	if err != domain.ErrInvalidWebhookSignature {
+		t.Errorf("expected %q without tracking number, got %q", domain.ErrInvalidWebhookSignature, err)
+	}
+}
@@ -0,0 +1,74 @@
+// Package carrier implements shipping carriers tracking shipments.
+package carrier
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// MockSignatureHeader is the header holding signature of mock webhooks.
+const MockSignatureHeader = "Mock-Signature"
+
+// Mock represents a local carrier standing in for a real one, it is meant
+// for development and tests. Events carry statuses of shipments as is.
+type Mock struct {
+	secret []byte
+}
+
+// mockEvent represents webhook payloads of mock carrier.
+type mockEvent struct {
+	ID             string    `json:"id"`
+	TrackingNumber string    `json:"tracking_number"`
+	Status         string    `json:"status"`
+	Description    string    `json:"description"`
+	OccurredAt     time.Time `json:"occurred_at"`
+}
+
+// NewMock returns a new instance of Mock verifying webhooks with secret.
+func NewMock(secret []byte) *Mock {
+	return &Mock{secret: secret}
+}
+
+// SignatureHeader returns name of the header holding signature of webhooks.
+func (m *Mock) SignatureHeader() string {
+	return MockSignatureHeader
+}
+
+// ParseEvent verifies signature of a webhook payload and returns its event,
+// all webhooks are rejected without a secret. Events without time are taken
+// as happened now.
+func (m *Mock) ParseEvent(payload []byte, signature string) (domain.TrackingEvent, error) {
+	if len(m.secret) == 0 || !hmac.Equal([]byte(m.Sign(payload)), []byte(signature)) {
+		return domain.TrackingEvent{}, domain.ErrInvalidWebhookSignature
+	}
+
+	var event mockEvent
+	err := json.Unmarshal(payload, &event)
+	if err != nil || event.ID == "" || event.TrackingNumber == "" {
+		return domain.TrackingEvent{}, domain.ErrInvalidWebhookSignature
+	}
+
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	return domain.TrackingEvent{
+		ID:             event.ID,
+		TrackingNumber: event.TrackingNumber,
+		Status:         event.Status,
+		Description:    event.Description,
+		OccurredAt:     event.OccurredAt,
+	}, nil
+}
+
+// Sign returns signature of a webhook payload.
+func (m *Mock) Sign(payload []byte) string {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
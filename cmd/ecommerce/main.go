@@ -2,14 +2,19 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/mortezadadgar/ecommerce-api/domain"
 	"github.com/mortezadadgar/ecommerce-api/http"
+	"github.com/mortezadadgar/ecommerce-api/notify"
 	"github.com/mortezadadgar/ecommerce-api/postgres"
+	"github.com/mortezadadgar/ecommerce-api/scheduler"
 )
 
 func main() {
@@ -30,8 +35,20 @@ func main() {
 		log.Fatal(err)
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	jobs := newScheduler(pg)
+	done := make(chan struct{})
+	go func() {
+		jobs.Run(ctx)
+		close(done)
+	}()
+
 	// wait for user signal
 	<-registerSignalNotify()
+	cancel()
+	<-done
 
 	err = closeMain(server, &pg)
 	if err != nil {
@@ -40,6 +57,62 @@ func main() {
 	}
 }
 
+// newScheduler returns scheduler of periodic jobs, instances share jobs
+// through advisory locks.
+func newScheduler(pg postgres.Postgres) *scheduler.Scheduler {
+	carts := postgres.NewCartStore(pg.DB)
+	tokens := postgres.NewTokenStore(pg.DB)
+	idempotency := postgres.NewIdempotencyStore(pg.DB)
+	loyalty := postgres.NewLoyaltyStore(pg.DB)
+
+	dispatcher := notify.NewDispatcher(postgres.NewNotificationStore(pg.DB), notify.NewLog(log.Default()))
+
+	jobs := scheduler.New(postgres.NewLocker(pg.DB))
+	jobs.Add("deliver_notifications", 10*time.Second, dispatcher.Dispatch)
+
+	// recovery links are rejected without a secret, so none are sent.
+	secret := []byte(os.Getenv("CART_RECOVERY_SECRET"))
+	if len(secret) > 0 {
+		recoverer := notify.NewRecoverer(postgres.NewCartRecoveryStore(pg.DB), secret, cartAbandonAfter())
+		jobs.Add("recover_abandoned_carts", time.Minute, recoverer.Recover)
+	} else {
+		log.Println("CART_RECOVERY_SECRET is not set, abandoned carts are not recovered")
+	}
+	jobs.Add("release_expired_reservations", time.Minute, carts.ReleaseExpiredReservations)
+	jobs.Add("delete_stale_guest_carts", time.Hour, func(ctx context.Context) (int, error) {
+		return carts.DeleteStaleGuests(ctx, time.Now().Add(-domain.GuestCartExpiry))
+	})
+	jobs.Add("purge_expired_tokens", time.Hour, tokens.DeleteExpired)
+	jobs.Add("purge_idempotency_keys", time.Hour, idempotency.DeleteExpired)
+	jobs.Add("expire_loyalty_points", time.Hour, func(ctx context.Context) (int, error) {
+		return loyalty.ExpirePoints(ctx, time.Now().Add(-loyaltyExpiry()))
+	})
+
+	return jobs
+}
+
+// cartAbandonAfter returns how long carts are left alone before they are
+// abandoned, falling back to the default when it is not configured.
+func cartAbandonAfter() time.Duration {
+	idle, err := time.ParseDuration(os.Getenv("CART_ABANDON_AFTER"))
+	if err != nil || idle <= 0 {
+		return domain.DefaultCartAbandonAfter
+	}
+
+	return idle
+}
+
+// loyaltyExpiry returns how long loyalty points are kept before they
+// expire, falling back to the default when it is not configured.
+func loyaltyExpiry() time.Duration {
+	expiry, err := time.ParseDuration(os.Getenv("LOYALTY_POINTS_EXPIRE_AFTER"))
+	if err != nil || expiry <= 0 {
+		return domain.DefaultLoyaltyExpiry
+	}
+
+	return expiry
+}
+
 func registerSignalNotify() <-chan os.Signal {
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
@@ -0,0 +1,23 @@
+-- +goose Up
+ALTER TABLE products
+	ADD COLUMN type            text NOT NULL DEFAULT 'simple',
+	ADD COLUMN bundle_pricing  text NOT NULL DEFAULT 'fixed',
+	ADD COLUMN bundle_discount int  NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS bundle_components(
+	bundle_id  bigint NOT NULL,
+	product_id bigint NOT NULL,
+	quantity   int    NOT NULL CHECK(quantity > 0),
+
+	PRIMARY KEY(bundle_id, product_id),
+	FOREIGN KEY(bundle_id)  REFERENCES products(id) ON DELETE CASCADE,
+	FOREIGN KEY(product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS bundle_components;
+
+ALTER TABLE products
+	DROP COLUMN IF EXISTS type,
+	DROP COLUMN IF EXISTS bundle_pricing,
+	DROP COLUMN IF EXISTS bundle_discount;
@@ -0,0 +1,34 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS product_translations(
+	product_id  bigint      NOT NULL,
+	locale      text        NOT NULL,
+	name        text        NOT NULL,
+	description text        NOT NULL,
+	created_at  timestamptz DEFAULT NOW(),
+	updated_at  timestamptz DEFAULT NOW(),
+
+	PRIMARY KEY(product_id, locale),
+	FOREIGN KEY(product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS category_translations(
+	category_id bigint      NOT NULL,
+	locale      text        NOT NULL,
+	name        text        NOT NULL,
+	description text        NOT NULL,
+	created_at  timestamptz DEFAULT NOW(),
+	updated_at  timestamptz DEFAULT NOW(),
+
+	PRIMARY KEY(category_id, locale),
+	FOREIGN KEY(category_id) REFERENCES categories(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS product_translations_search_idx
+	ON product_translations USING GIN (to_tsvector('simple', name));
+
+CREATE INDEX IF NOT EXISTS category_translations_search_idx
+	ON category_translations USING GIN (to_tsvector('simple', name));
+
+-- +goose Down
+DROP TABLE IF EXISTS category_translations;
+DROP TABLE IF EXISTS product_translations;
@@ -0,0 +1,46 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS product_files(
+	id           bigserial   NOT NULL,
+	product_id   bigint      NOT NULL,
+	name         text        NOT NULL,
+	blob_key     text        NOT NULL UNIQUE,
+	content_type text        NOT NULL,
+	size         bigint      NOT NULL,
+	created_at   timestamptz DEFAULT NOW(),
+
+	PRIMARY KEY(id),
+	FOREIGN KEY(product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS download_grants(
+	id            bigserial   NOT NULL,
+	user_id       bigint      NOT NULL,
+	product_id    bigint      NOT NULL,
+	quantity      int         NOT NULL,
+	downloads     int         NOT NULL DEFAULT 0,
+	max_downloads int         NOT NULL,
+	created_at    timestamptz DEFAULT NOW(),
+
+	PRIMARY KEY(id),
+	FOREIGN KEY(user_id)    REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY(product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS license_keys(
+	id          bigserial   NOT NULL,
+	product_id  bigint      NOT NULL,
+	key         text        NOT NULL,
+	grant_id    bigint,
+	assigned_at timestamptz,
+	created_at  timestamptz DEFAULT NOW(),
+
+	PRIMARY KEY(id),
+	UNIQUE(product_id, key),
+	FOREIGN KEY(product_id) REFERENCES products(id) ON DELETE CASCADE,
+	FOREIGN KEY(grant_id)   REFERENCES download_grants(id) ON DELETE SET NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS license_keys;
+DROP TABLE IF EXISTS download_grants;
+DROP TABLE IF EXISTS product_files;
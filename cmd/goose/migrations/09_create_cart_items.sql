@@ -0,0 +1,47 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS cart_items(
+	id         bigserial NOT NULL,
+	cart_id    bigint    NOT NULL,
+	product_id bigint    NOT NULL,
+	quantity   int       NOT NULL CHECK(quantity > 0),
+
+	PRIMARY KEY(id),
+	UNIQUE(cart_id, product_id),
+	FOREIGN KEY(cart_id)    REFERENCES carts(id) ON DELETE CASCADE,
+	FOREIGN KEY(product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+-- every user keeps its oldest cart row and lines of the same product
+-- are merged into one item.
+INSERT INTO cart_items(cart_id, product_id, quantity)
+SELECT (SELECT MIN(id) FROM carts WHERE user_id = c.user_id), c.product_id, SUM(c.quantity)
+FROM carts c
+GROUP BY c.user_id, c.product_id
+HAVING SUM(c.quantity) > 0;
+
+DELETE FROM carts c
+WHERE c.id <> (SELECT MIN(id) FROM carts WHERE user_id = c.user_id);
+
+ALTER TABLE carts
+	DROP COLUMN product_id,
+	DROP COLUMN quantity,
+	ADD CONSTRAINT carts_user_id_key UNIQUE(user_id);
+
+-- +goose Down
+ALTER TABLE carts
+	DROP CONSTRAINT carts_user_id_key,
+	ADD COLUMN product_id bigint REFERENCES products(id) ON DELETE CASCADE,
+	ADD COLUMN quantity   int;
+
+INSERT INTO carts(user_id, product_id, quantity)
+SELECT c.user_id, i.product_id, i.quantity
+FROM cart_items i
+INNER JOIN carts c ON c.id = i.cart_id;
+
+DELETE FROM carts WHERE product_id IS NULL;
+
+ALTER TABLE carts
+	ALTER COLUMN product_id SET NOT NULL,
+	ALTER COLUMN quantity   SET NOT NULL;
+
+DROP TABLE IF EXISTS cart_items;
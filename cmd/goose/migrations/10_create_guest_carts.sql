@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE carts
+	ALTER COLUMN user_id DROP DEFAULT,
+	ALTER COLUMN user_id DROP NOT NULL,
+	ADD COLUMN token bytea UNIQUE,
+	ADD CONSTRAINT carts_owner_check CHECK(user_id IS NOT NULL OR token IS NOT NULL);
+
+ALTER TABLE cart_items
+	ADD COLUMN updated_at timestamptz NOT NULL DEFAULT NOW();
+
+-- +goose Down
+ALTER TABLE cart_items
+	DROP COLUMN updated_at;
+
+DELETE FROM carts WHERE user_id IS NULL;
+
+ALTER TABLE carts
+	DROP CONSTRAINT carts_owner_check,
+	DROP COLUMN token,
+	ALTER COLUMN user_id SET NOT NULL;
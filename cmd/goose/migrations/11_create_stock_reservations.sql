@@ -0,0 +1,56 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS stock_reservations(
+	cart_item_id bigint      NOT NULL,
+	product_id   bigint      NOT NULL,
+	quantity     int         NOT NULL CHECK(quantity > 0),
+	expires_at   timestamptz NOT NULL,
+
+	PRIMARY KEY(cart_item_id, product_id),
+	FOREIGN KEY(cart_item_id) REFERENCES cart_items(id) ON DELETE CASCADE,
+	FOREIGN KEY(product_id)   REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS stock_reservations_product_id_idx
+	ON stock_reservations(product_id, expires_at);
+
+CREATE VIEW active_reservations AS
+	SELECT product_id, SUM(quantity)::int AS quantity
+	FROM stock_reservations
+	WHERE expires_at > NOW()
+	GROUP BY product_id;
+
+-- bundles in carts used to take their components out of stock, give it
+-- back and hold it with reservations instead.
+UPDATE products p
+SET quantity = p.quantity + r.quantity
+FROM (
+	SELECT b.product_id, SUM(b.quantity * i.quantity) AS quantity
+	FROM cart_items i
+	INNER JOIN bundle_components b ON b.bundle_id = i.product_id
+	GROUP BY b.product_id
+) r
+WHERE p.id = r.product_id;
+
+INSERT INTO stock_reservations(cart_item_id, product_id, quantity, expires_at)
+SELECT i.id, b.product_id, b.quantity * i.quantity, NOW() + INTERVAL '30 minutes'
+FROM cart_items i
+INNER JOIN bundle_components b ON b.bundle_id = i.product_id
+UNION ALL
+SELECT i.id, i.product_id, i.quantity, NOW() + INTERVAL '30 minutes'
+FROM cart_items i
+INNER JOIN products p ON p.id = i.product_id
+WHERE p.type <> 'bundle';
+
+-- +goose Down
+UPDATE products p
+SET quantity = p.quantity - r.quantity
+FROM (
+	SELECT b.product_id, SUM(b.quantity * i.quantity) AS quantity
+	FROM cart_items i
+	INNER JOIN bundle_components b ON b.bundle_id = i.product_id
+	GROUP BY b.product_id
+) r
+WHERE p.id = r.product_id;
+
+DROP VIEW IF EXISTS active_reservations;
+DROP TABLE IF EXISTS stock_reservations;
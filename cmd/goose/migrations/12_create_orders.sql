@@ -0,0 +1,46 @@
+-- +goose Up
+ALTER TABLE products
+	ADD COLUMN sku text NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS products_sku_key ON products(sku) WHERE sku <> '';
+
+CREATE TABLE IF NOT EXISTS orders(
+	id         bigserial   NOT NULL,
+	user_id    bigint      NOT NULL,
+	status     text        NOT NULL DEFAULT 'pending',
+	subtotal   int         NOT NULL,
+	discount   int         NOT NULL DEFAULT 0,
+	tax        int         NOT NULL DEFAULT 0,
+	total      int         NOT NULL,
+	created_at timestamptz DEFAULT NOW(),
+	updated_at timestamptz DEFAULT NOW(),
+	version    int         DEFAULT 1,
+
+	PRIMARY KEY(id),
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS orders_user_id_idx ON orders(user_id);
+
+CREATE TABLE IF NOT EXISTS order_lines(
+	id         bigserial NOT NULL,
+	order_id   bigint    NOT NULL,
+	product_id bigint,
+	name       text      NOT NULL,
+	sku        text      NOT NULL,
+	unit_price int       NOT NULL,
+	quantity   int       NOT NULL CHECK(quantity > 0),
+	line_total int       NOT NULL,
+
+	PRIMARY KEY(id),
+	FOREIGN KEY(order_id)   REFERENCES orders(id) ON DELETE CASCADE,
+	FOREIGN KEY(product_id) REFERENCES products(id) ON DELETE SET NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS order_lines;
+DROP TABLE IF EXISTS orders;
+DROP INDEX IF EXISTS products_sku_key;
+
+ALTER TABLE products
+	DROP COLUMN sku;
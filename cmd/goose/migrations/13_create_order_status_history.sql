@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS order_status_history(
+	id          bigserial   NOT NULL,
+	order_id    bigint      NOT NULL,
+	from_status text        NOT NULL DEFAULT '',
+	to_status   text        NOT NULL,
+	changed_by  bigint,
+	note        text        NOT NULL DEFAULT '',
+	created_at  timestamptz NOT NULL DEFAULT NOW(),
+
+	PRIMARY KEY(id),
+	FOREIGN KEY(order_id)   REFERENCES orders(id) ON DELETE CASCADE,
+	FOREIGN KEY(changed_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS order_status_history_order_id_idx ON order_status_history(order_id);
+
+INSERT INTO order_status_history(order_id, to_status, created_at)
+SELECT id, status, created_at FROM orders;
+
+-- +goose Down
+DROP TABLE IF EXISTS order_status_history;
@@ -0,0 +1,33 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS payments(
+	id         bigserial   NOT NULL,
+	order_id   bigint      NOT NULL,
+	provider   text        NOT NULL,
+	intent_id  text        NOT NULL,
+	status     text        NOT NULL,
+	amount     int         NOT NULL,
+	captured   int         NOT NULL DEFAULT 0,
+	refunded   int         NOT NULL DEFAULT 0,
+	currency   text        NOT NULL,
+	created_at timestamptz NOT NULL DEFAULT NOW(),
+	updated_at timestamptz NOT NULL DEFAULT NOW(),
+
+	PRIMARY KEY(id),
+	UNIQUE(provider, intent_id),
+	FOREIGN KEY(order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS payments_order_id_idx ON payments(order_id);
+
+CREATE TABLE IF NOT EXISTS payment_events(
+	provider   text        NOT NULL,
+	event_id   text        NOT NULL,
+	type       text        NOT NULL,
+	created_at timestamptz NOT NULL DEFAULT NOW(),
+
+	PRIMARY KEY(provider, event_id)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS payment_events;
+DROP TABLE IF EXISTS payments;
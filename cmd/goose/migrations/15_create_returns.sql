@@ -0,0 +1,46 @@
+-- +goose Up
+ALTER TABLE order_lines
+	ADD COLUMN returned int NOT NULL DEFAULT 0,
+	ADD COLUMN refunded int NOT NULL DEFAULT 0;
+
+CREATE SEQUENCE IF NOT EXISTS returns_rma_seq;
+
+CREATE TABLE IF NOT EXISTS returns(
+	id         bigserial   NOT NULL,
+	order_id   bigint      NOT NULL,
+	rma_number text        NOT NULL DEFAULT 'RMA-' || lpad(nextval('returns_rma_seq')::text, 8, '0'),
+	status     text        NOT NULL DEFAULT 'requested',
+	reason     text        NOT NULL,
+	note       text        NOT NULL DEFAULT '',
+	restocked  boolean     NOT NULL DEFAULT false,
+	refunded   int         NOT NULL DEFAULT 0,
+	created_at timestamptz NOT NULL DEFAULT NOW(),
+	updated_at timestamptz NOT NULL DEFAULT NOW(),
+
+	PRIMARY KEY(id),
+	UNIQUE(rma_number),
+	FOREIGN KEY(order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS returns_order_id_idx ON returns(order_id);
+
+CREATE TABLE IF NOT EXISTS return_lines(
+	id            bigserial NOT NULL,
+	return_id     bigint    NOT NULL,
+	order_line_id bigint    NOT NULL,
+	quantity      int       NOT NULL CHECK(quantity > 0),
+	refunded      int       NOT NULL DEFAULT 0,
+
+	PRIMARY KEY(id),
+	FOREIGN KEY(return_id)     REFERENCES returns(id) ON DELETE CASCADE,
+	FOREIGN KEY(order_line_id) REFERENCES order_lines(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS return_lines;
+DROP TABLE IF EXISTS returns;
+DROP SEQUENCE IF EXISTS returns_rma_seq;
+
+ALTER TABLE order_lines
+	DROP COLUMN returned,
+	DROP COLUMN refunded;
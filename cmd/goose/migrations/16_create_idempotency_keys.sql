@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS idempotency_keys(
+	scope        text        NOT NULL,
+	key          text        NOT NULL,
+	request_hash text        NOT NULL,
+	status_code  int,
+	header       jsonb,
+	body         bytea,
+	created_at   timestamptz NOT NULL DEFAULT NOW(),
+
+	PRIMARY KEY(scope, key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_created_at_idx ON idempotency_keys(created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS idempotency_keys;
@@ -0,0 +1,44 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS warehouses(
+	id         bigserial   NOT NULL,
+	code       text        NOT NULL,
+	name       text        NOT NULL,
+	created_at timestamptz NOT NULL DEFAULT NOW(),
+
+	PRIMARY KEY(id),
+	UNIQUE(code)
+);
+
+INSERT INTO warehouses(id, code, name) VALUES(1, 'default', 'Default warehouse');
+SELECT setval('warehouses_id_seq', 1);
+
+CREATE TABLE IF NOT EXISTS stock_movements(
+	id           bigserial   NOT NULL,
+	product_id   bigint      NOT NULL,
+	warehouse_id bigint      NOT NULL,
+	type         text        NOT NULL CHECK(type IN ('receipt', 'sale', 'return', 'adjustment', 'transfer')),
+	quantity     int         NOT NULL CHECK(quantity <> 0),
+	reference    text        NOT NULL DEFAULT '',
+	note         text        NOT NULL DEFAULT '',
+	created_by   bigint,
+	created_at   timestamptz NOT NULL DEFAULT NOW(),
+
+	PRIMARY KEY(id),
+	FOREIGN KEY(product_id)   REFERENCES products(id) ON DELETE CASCADE,
+	FOREIGN KEY(warehouse_id) REFERENCES warehouses(id),
+	FOREIGN KEY(created_by)   REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS stock_movements_product_id_idx ON stock_movements(product_id, warehouse_id);
+CREATE INDEX IF NOT EXISTS stock_movements_reference_idx ON stock_movements(reference);
+
+-- opening balance of existing stock, products quantity is kept as a sum
+-- of movements from now on.
+INSERT INTO stock_movements(product_id, warehouse_id, type, quantity, note)
+SELECT id, 1, 'adjustment', quantity, 'opening balance'
+FROM products
+WHERE type <> 'bundle' AND quantity <> 0;
+
+-- +goose Down
+DROP TABLE IF EXISTS stock_movements;
+DROP TABLE IF EXISTS warehouses;
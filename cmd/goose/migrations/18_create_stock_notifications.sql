@@ -0,0 +1,40 @@
+-- +goose Up
+ALTER TABLE products
+	ADD COLUMN reorder_threshold int NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS stock_subscriptions(
+	id          bigserial   NOT NULL,
+	product_id  bigint      NOT NULL,
+	user_id     bigint      NOT NULL,
+	email       text        NOT NULL,
+	created_at  timestamptz NOT NULL DEFAULT NOW(),
+	notified_at timestamptz,
+
+	PRIMARY KEY(id),
+	UNIQUE(product_id, user_id),
+	FOREIGN KEY(product_id) REFERENCES products(id) ON DELETE CASCADE,
+	FOREIGN KEY(user_id)    REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- notifications is an outbox of messages delivered by the notifier.
+CREATE TABLE IF NOT EXISTS notifications(
+	id         bigserial   NOT NULL,
+	kind       text        NOT NULL,
+	recipient  text        NOT NULL,
+	subject    text        NOT NULL,
+	body       text        NOT NULL,
+	attempts   int         NOT NULL DEFAULT 0,
+	created_at timestamptz NOT NULL DEFAULT NOW(),
+	sent_at    timestamptz,
+
+	PRIMARY KEY(id)
+);
+
+CREATE INDEX IF NOT EXISTS notifications_pending_idx ON notifications(id) WHERE sent_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS stock_subscriptions;
+
+ALTER TABLE products
+	DROP COLUMN reorder_threshold;
@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE products
+	ADD COLUMN inventory_policy   text NOT NULL DEFAULT 'deny'
+		CHECK(inventory_policy IN ('deny', 'backorder', 'preorder')),
+	ADD COLUMN backorder_limit    int  NOT NULL DEFAULT 0 CHECK(backorder_limit >= 0),
+	ADD COLUMN expected_ship_date timestamptz;
+
+ALTER TABLE order_lines
+	ADD COLUMN backordered int NOT NULL DEFAULT 0 CHECK(backordered >= 0);
+
+CREATE INDEX IF NOT EXISTS order_lines_backordered_idx ON order_lines(product_id) WHERE backordered > 0;
+
+-- +goose Down
+DROP INDEX IF EXISTS order_lines_backordered_idx;
+
+ALTER TABLE order_lines
+	DROP COLUMN backordered;
+
+ALTER TABLE products
+	DROP COLUMN inventory_policy,
+	DROP COLUMN backorder_limit,
+	DROP COLUMN expected_ship_date;
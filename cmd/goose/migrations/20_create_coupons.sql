@@ -0,0 +1,64 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS coupons(
+	id               bigserial   NOT NULL,
+	code             text        NOT NULL DEFAULT '',
+	name             text        NOT NULL,
+	type             text        NOT NULL
+		CHECK(type IN ('percentage', 'fixed', 'free_shipping', 'buy_x_get_y')),
+	value            int         NOT NULL DEFAULT 0 CHECK(value >= 0),
+	min_subtotal     int         NOT NULL DEFAULT 0 CHECK(min_subtotal >= 0),
+	product_ids      bigint[]    NOT NULL DEFAULT '{}',
+	category_ids     bigint[]    NOT NULL DEFAULT '{}',
+	buy_quantity     int         NOT NULL DEFAULT 0,
+	get_quantity     int         NOT NULL DEFAULT 0,
+	usage_limit      int         NOT NULL DEFAULT 0 CHECK(usage_limit >= 0),
+	user_usage_limit int         NOT NULL DEFAULT 0 CHECK(user_usage_limit >= 0),
+	stackable        boolean     NOT NULL DEFAULT false,
+	priority         int         NOT NULL DEFAULT 0,
+	active           boolean     NOT NULL DEFAULT true,
+	starts_at        timestamptz,
+	ends_at          timestamptz,
+	created_at       timestamptz NOT NULL DEFAULT NOW(),
+	updated_at       timestamptz NOT NULL DEFAULT NOW(),
+
+	PRIMARY KEY(id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS coupons_code_key ON coupons(code) WHERE code <> '';
+
+CREATE TABLE IF NOT EXISTS cart_coupons(
+	cart_id   bigint NOT NULL,
+	coupon_id bigint NOT NULL,
+
+	PRIMARY KEY(cart_id, coupon_id),
+	FOREIGN KEY(cart_id)   REFERENCES carts(id)   ON DELETE CASCADE,
+	FOREIGN KEY(coupon_id) REFERENCES coupons(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS coupon_redemptions(
+	id         bigserial   NOT NULL,
+	coupon_id  bigint      NOT NULL,
+	order_id   bigint      NOT NULL,
+	user_id    bigint      NOT NULL,
+	amount     int         NOT NULL,
+	created_at timestamptz NOT NULL DEFAULT NOW(),
+
+	PRIMARY KEY(id),
+	UNIQUE(coupon_id, order_id),
+	FOREIGN KEY(coupon_id) REFERENCES coupons(id) ON DELETE CASCADE,
+	FOREIGN KEY(order_id)  REFERENCES orders(id)  ON DELETE CASCADE,
+	FOREIGN KEY(user_id)   REFERENCES users(id)   ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS coupon_redemptions_user_id_idx ON coupon_redemptions(coupon_id, user_id);
+
+ALTER TABLE order_lines
+	ADD COLUMN discount int NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE order_lines
+	DROP COLUMN discount;
+
+DROP TABLE IF EXISTS coupon_redemptions;
+DROP TABLE IF EXISTS cart_coupons;
+DROP TABLE IF EXISTS coupons;
@@ -0,0 +1,59 @@
+-- +goose Up
+ALTER TABLE products
+	ADD COLUMN tax_category text NOT NULL DEFAULT 'standard';
+
+CREATE TABLE IF NOT EXISTS tax_zones(
+	id                 bigserial   NOT NULL,
+	name               text        NOT NULL,
+	country            text        NOT NULL,
+	region             text        NOT NULL DEFAULT '',
+	postal_pattern     text        NOT NULL DEFAULT '',
+	priority           int         NOT NULL DEFAULT 0,
+	prices_include_tax boolean     NOT NULL DEFAULT false,
+	created_at         timestamptz NOT NULL DEFAULT NOW(),
+
+	PRIMARY KEY(id)
+);
+
+CREATE TABLE IF NOT EXISTS tax_rates(
+	zone_id      bigint NOT NULL,
+	tax_category text   NOT NULL,
+	name         text   NOT NULL DEFAULT '',
+	rate         int    NOT NULL CHECK(rate >= 0),
+
+	PRIMARY KEY(zone_id, tax_category),
+	FOREIGN KEY(zone_id) REFERENCES tax_zones(id) ON DELETE CASCADE
+);
+
+ALTER TABLE carts
+	ADD COLUMN country     text NOT NULL DEFAULT '',
+	ADD COLUMN region      text NOT NULL DEFAULT '',
+	ADD COLUMN postal_code text NOT NULL DEFAULT '';
+
+ALTER TABLE orders
+	ADD COLUMN tax_inclusive boolean NOT NULL DEFAULT false;
+
+ALTER TABLE order_lines
+	ADD COLUMN tax_category text NOT NULL DEFAULT '',
+	ADD COLUMN tax_rate     int  NOT NULL DEFAULT 0,
+	ADD COLUMN tax          int  NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE order_lines
+	DROP COLUMN tax_category,
+	DROP COLUMN tax_rate,
+	DROP COLUMN tax;
+
+ALTER TABLE orders
+	DROP COLUMN tax_inclusive;
+
+ALTER TABLE carts
+	DROP COLUMN country,
+	DROP COLUMN region,
+	DROP COLUMN postal_code;
+
+DROP TABLE IF EXISTS tax_rates;
+DROP TABLE IF EXISTS tax_zones;
+
+ALTER TABLE products
+	DROP COLUMN tax_category;
@@ -0,0 +1,61 @@
+-- +goose Up
+ALTER TABLE products
+	ADD COLUMN weight int NOT NULL DEFAULT 0 CHECK(weight >= 0),
+	ADD COLUMN length int NOT NULL DEFAULT 0 CHECK(length >= 0),
+	ADD COLUMN width  int NOT NULL DEFAULT 0 CHECK(width >= 0),
+	ADD COLUMN height int NOT NULL DEFAULT 0 CHECK(height >= 0);
+
+CREATE TABLE IF NOT EXISTS shipping_zones(
+	id             bigserial   NOT NULL,
+	name           text        NOT NULL,
+	country        text        NOT NULL,
+	region         text        NOT NULL DEFAULT '',
+	postal_pattern text        NOT NULL DEFAULT '',
+	priority       int         NOT NULL DEFAULT 0,
+	created_at     timestamptz NOT NULL DEFAULT NOW(),
+
+	PRIMARY KEY(id)
+);
+
+CREATE TABLE IF NOT EXISTS shipping_methods(
+	id         bigserial   NOT NULL,
+	zone_id    bigint      NOT NULL,
+	name       text        NOT NULL,
+	type       text        NOT NULL CHECK(type IN ('flat', 'weight', 'price')),
+	rate       int         NOT NULL DEFAULT 0 CHECK(rate >= 0),
+	tiers      jsonb       NOT NULL DEFAULT '[]',
+	free_above int         NOT NULL DEFAULT 0 CHECK(free_above >= 0),
+	active     boolean     NOT NULL DEFAULT true,
+	created_at timestamptz NOT NULL DEFAULT NOW(),
+
+	PRIMARY KEY(id),
+	FOREIGN KEY(zone_id) REFERENCES shipping_zones(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS shipping_methods_zone_id_idx ON shipping_methods(zone_id);
+
+ALTER TABLE carts
+	ADD COLUMN shipping_method_id bigint REFERENCES shipping_methods(id) ON DELETE SET NULL;
+
+ALTER TABLE orders
+	ADD COLUMN shipping           int    NOT NULL DEFAULT 0,
+	ADD COLUMN shipping_method_id bigint REFERENCES shipping_methods(id) ON DELETE SET NULL,
+	ADD COLUMN shipping_method    text   NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE orders
+	DROP COLUMN shipping,
+	DROP COLUMN shipping_method_id,
+	DROP COLUMN shipping_method;
+
+ALTER TABLE carts
+	DROP COLUMN shipping_method_id;
+
+DROP TABLE IF EXISTS shipping_methods;
+DROP TABLE IF EXISTS shipping_zones;
+
+ALTER TABLE products
+	DROP COLUMN weight,
+	DROP COLUMN length,
+	DROP COLUMN width,
+	DROP COLUMN height;
@@ -0,0 +1,35 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS addresses(
+	id               bigserial   NOT NULL,
+	user_id          bigint      NOT NULL,
+	name             text        NOT NULL,
+	line1            text        NOT NULL,
+	line2            text        NOT NULL DEFAULT '',
+	city             text        NOT NULL,
+	region           text        NOT NULL DEFAULT '',
+	postal_code      text        NOT NULL DEFAULT '',
+	country          text        NOT NULL,
+	phone            text        NOT NULL DEFAULT '',
+	default_shipping boolean     NOT NULL DEFAULT false,
+	default_billing  boolean     NOT NULL DEFAULT false,
+	created_at       timestamptz NOT NULL DEFAULT NOW(),
+	updated_at       timestamptz NOT NULL DEFAULT NOW(),
+
+	PRIMARY KEY(id),
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS addresses_user_id_idx ON addresses(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS addresses_default_shipping_key ON addresses(user_id) WHERE default_shipping;
+CREATE UNIQUE INDEX IF NOT EXISTS addresses_default_billing_key ON addresses(user_id) WHERE default_billing;
+
+ALTER TABLE orders
+	ADD COLUMN shipping_address jsonb,
+	ADD COLUMN billing_address  jsonb;
+
+-- +goose Down
+ALTER TABLE orders
+	DROP COLUMN shipping_address,
+	DROP COLUMN billing_address;
+
+DROP TABLE IF EXISTS addresses;
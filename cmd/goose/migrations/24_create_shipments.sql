@@ -0,0 +1,65 @@
+-- +goose Up
+ALTER TABLE order_lines
+	ADD COLUMN shippable boolean NOT NULL DEFAULT true,
+	ADD COLUMN shipped   int     NOT NULL DEFAULT 0 CHECK(shipped >= 0);
+
+UPDATE order_lines l
+SET shippable = false
+FROM products p
+WHERE p.id = l.product_id AND p.type = 'digital';
+
+CREATE TABLE IF NOT EXISTS shipments(
+	id              bigserial   NOT NULL,
+	order_id        bigint      NOT NULL,
+	carrier         text        NOT NULL,
+	tracking_number text        NOT NULL DEFAULT '',
+	status          text        NOT NULL DEFAULT 'pending',
+	shipped_at      timestamptz,
+	delivered_at    timestamptz,
+	created_at      timestamptz NOT NULL DEFAULT NOW(),
+	updated_at      timestamptz NOT NULL DEFAULT NOW(),
+
+	PRIMARY KEY(id),
+	FOREIGN KEY(order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS shipments_order_id_idx ON shipments(order_id);
+CREATE UNIQUE INDEX IF NOT EXISTS shipments_tracking_number_key
+	ON shipments(carrier, tracking_number) WHERE tracking_number <> '';
+
+CREATE TABLE IF NOT EXISTS shipment_lines(
+	id            bigserial NOT NULL,
+	shipment_id   bigint    NOT NULL,
+	order_line_id bigint    NOT NULL,
+	quantity      int       NOT NULL CHECK(quantity > 0),
+
+	PRIMARY KEY(id),
+	FOREIGN KEY(shipment_id)   REFERENCES shipments(id) ON DELETE CASCADE,
+	FOREIGN KEY(order_line_id) REFERENCES order_lines(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS shipment_events(
+	id          bigserial   NOT NULL,
+	shipment_id bigint      NOT NULL,
+	carrier     text        NOT NULL,
+	event_id    text,
+	status      text        NOT NULL,
+	description text        NOT NULL DEFAULT '',
+	occurred_at timestamptz NOT NULL DEFAULT NOW(),
+	created_at  timestamptz NOT NULL DEFAULT NOW(),
+
+	PRIMARY KEY(id),
+	UNIQUE(carrier, event_id),
+	FOREIGN KEY(shipment_id) REFERENCES shipments(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS shipment_events_shipment_id_idx ON shipment_events(shipment_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS shipment_events;
+DROP TABLE IF EXISTS shipment_lines;
+DROP TABLE IF EXISTS shipments;
+
+ALTER TABLE order_lines
+	DROP COLUMN shippable,
+	DROP COLUMN shipped;
@@ -0,0 +1,46 @@
+-- +goose Up
+-- invoice_sequences holds last number given out to each kind of invoice per
+-- year, it is updated in the transaction issuing the invoice so numbers of
+-- rolled back invoices are given out again.
+CREATE TABLE IF NOT EXISTS invoice_sequences(
+	kind text NOT NULL,
+	year int  NOT NULL,
+	last int  NOT NULL,
+
+	PRIMARY KEY(kind, year)
+);
+
+CREATE TABLE IF NOT EXISTS invoices(
+	id               bigserial   NOT NULL,
+	order_id         bigint      NOT NULL,
+	invoice_id       bigint,
+	kind             text        NOT NULL CHECK(kind IN ('invoice', 'credit_note')),
+	number           text        NOT NULL,
+	year             int         NOT NULL,
+	sequence         int         NOT NULL,
+	subtotal         int         NOT NULL DEFAULT 0,
+	discount         int         NOT NULL DEFAULT 0,
+	tax              int         NOT NULL DEFAULT 0,
+	tax_inclusive    boolean     NOT NULL DEFAULT false,
+	shipping         int         NOT NULL DEFAULT 0,
+	total            int         NOT NULL,
+	currency         text        NOT NULL,
+	lines            jsonb       NOT NULL DEFAULT '[]',
+	billing_address  jsonb,
+	shipping_address jsonb,
+	reason           text        NOT NULL DEFAULT '',
+	issued_at        timestamptz NOT NULL DEFAULT NOW(),
+
+	PRIMARY KEY(id),
+	UNIQUE(number),
+	UNIQUE(kind, year, sequence),
+	FOREIGN KEY(order_id)   REFERENCES orders(id) ON DELETE CASCADE,
+	FOREIGN KEY(invoice_id) REFERENCES invoices(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS invoices_order_id_idx ON invoices(order_id);
+CREATE UNIQUE INDEX IF NOT EXISTS invoices_order_id_key ON invoices(order_id) WHERE kind = 'invoice';
+
+-- +goose Down
+DROP TABLE IF EXISTS invoices;
+DROP TABLE IF EXISTS invoice_sequences;
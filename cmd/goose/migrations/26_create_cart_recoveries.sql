@@ -0,0 +1,47 @@
+-- +goose Up
+ALTER TABLE carts
+	ADD COLUMN created_at timestamptz NOT NULL DEFAULT NOW(),
+	ADD COLUMN updated_at timestamptz NOT NULL DEFAULT NOW();
+
+-- carts were last touched when their newest item was.
+UPDATE carts c
+SET updated_at = i.updated_at, created_at = i.created_at
+FROM (
+	SELECT cart_id, MAX(updated_at) AS updated_at, MIN(updated_at) AS created_at
+	FROM cart_items
+	GROUP BY cart_id
+) i
+WHERE i.cart_id = c.id;
+
+CREATE INDEX IF NOT EXISTS carts_updated_at_idx ON carts(updated_at) WHERE user_id IS NOT NULL;
+
+-- cart_recoveries records recovery emails of abandoned carts along with
+-- items carts held at the time, they outlive carts and orders for stats.
+CREATE TABLE IF NOT EXISTS cart_recoveries(
+	id           bigserial   NOT NULL,
+	cart_id      bigint,
+	user_id      bigint      NOT NULL,
+	email        text        NOT NULL,
+	items        jsonb       NOT NULL DEFAULT '[]',
+	total        int         NOT NULL,
+	sent_at      timestamptz NOT NULL DEFAULT NOW(),
+	restored_at  timestamptz,
+	order_id     bigint,
+	order_total  int         NOT NULL DEFAULT 0,
+	converted_at timestamptz,
+
+	PRIMARY KEY(id),
+	FOREIGN KEY(cart_id)  REFERENCES carts(id)  ON DELETE SET NULL,
+	FOREIGN KEY(user_id)  REFERENCES users(id)  ON DELETE CASCADE,
+	FOREIGN KEY(order_id) REFERENCES orders(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS cart_recoveries_cart_id_idx ON cart_recoveries(cart_id);
+CREATE INDEX IF NOT EXISTS cart_recoveries_sent_at_idx ON cart_recoveries(sent_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS cart_recoveries;
+
+ALTER TABLE carts
+	DROP COLUMN updated_at,
+	DROP COLUMN created_at;
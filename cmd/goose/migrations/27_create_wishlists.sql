@@ -0,0 +1,36 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS wishlists(
+	id          bigserial   NOT NULL,
+	user_id     bigint      NOT NULL,
+	name        text        NOT NULL,
+	share_token text,
+	created_at  timestamptz NOT NULL DEFAULT NOW(),
+	updated_at  timestamptz NOT NULL DEFAULT NOW(),
+
+	PRIMARY KEY(id),
+	UNIQUE(user_id, name),
+	UNIQUE(share_token),
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- seen_price is price of product last seen by owner of wishlist, owners
+-- are notified when price falls below it.
+CREATE TABLE IF NOT EXISTS wishlist_items(
+	id          bigserial   NOT NULL,
+	wishlist_id bigint      NOT NULL,
+	product_id  bigint      NOT NULL,
+	quantity    int         NOT NULL CHECK(quantity > 0),
+	seen_price  int         NOT NULL,
+	added_at    timestamptz NOT NULL DEFAULT NOW(),
+
+	PRIMARY KEY(id),
+	UNIQUE(wishlist_id, product_id),
+	FOREIGN KEY(wishlist_id) REFERENCES wishlists(id) ON DELETE CASCADE,
+	FOREIGN KEY(product_id)  REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS wishlist_items_product_id_idx ON wishlist_items(product_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS wishlist_items;
+DROP TABLE IF EXISTS wishlists;
@@ -0,0 +1,59 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS gift_cards(
+	id              bigserial   NOT NULL,
+	code            text        NOT NULL,
+	initial_balance int         NOT NULL CHECK(initial_balance > 0),
+	balance         int         NOT NULL CHECK(balance >= 0),
+	currency        text        NOT NULL,
+	note            text        NOT NULL DEFAULT '',
+	expires_at      timestamptz,
+	voided_at       timestamptz,
+	created_at      timestamptz NOT NULL DEFAULT NOW(),
+	updated_at      timestamptz NOT NULL DEFAULT NOW(),
+
+	PRIMARY KEY(id),
+	UNIQUE(code)
+);
+
+-- balance of gift cards is kept as a sum of their transactions, balance
+-- of each transaction is balance of card after it.
+CREATE TABLE IF NOT EXISTS gift_card_transactions(
+	id           bigserial   NOT NULL,
+	gift_card_id bigint      NOT NULL,
+	order_id     bigint,
+	kind         text        NOT NULL CHECK(kind IN ('issue', 'redeem', 'restore', 'adjust', 'void')),
+	amount       int         NOT NULL,
+	balance      int         NOT NULL CHECK(balance >= 0),
+	note         text        NOT NULL DEFAULT '',
+	created_at   timestamptz NOT NULL DEFAULT NOW(),
+
+	PRIMARY KEY(id),
+	FOREIGN KEY(gift_card_id) REFERENCES gift_cards(id) ON DELETE CASCADE,
+	FOREIGN KEY(order_id)     REFERENCES orders(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS gift_card_transactions_gift_card_id_idx ON gift_card_transactions(gift_card_id);
+
+-- store credit of users is an append-only ledger, balance of a user is
+-- balance of its latest transaction.
+CREATE TABLE IF NOT EXISTS store_credit_transactions(
+	id         bigserial   NOT NULL,
+	user_id    bigint      NOT NULL,
+	order_id   bigint,
+	kind       text        NOT NULL CHECK(kind IN ('refund', 'redeem', 'restore', 'adjust')),
+	amount     int         NOT NULL CHECK(amount <> 0),
+	balance    int         NOT NULL CHECK(balance >= 0),
+	note       text        NOT NULL DEFAULT '',
+	created_at timestamptz NOT NULL DEFAULT NOW(),
+
+	PRIMARY KEY(id),
+	FOREIGN KEY(user_id)  REFERENCES users(id)  ON DELETE CASCADE,
+	FOREIGN KEY(order_id) REFERENCES orders(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS store_credit_transactions_user_id_idx ON store_credit_transactions(user_id, id);
+
+-- +goose Down
+DROP TABLE IF EXISTS store_credit_transactions;
+DROP TABLE IF EXISTS gift_card_transactions;
+DROP TABLE IF EXISTS gift_cards;
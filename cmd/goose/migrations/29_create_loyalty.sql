@@ -0,0 +1,48 @@
+-- +goose Up
+-- rule without a category is the base rate of every line, rules of
+-- categories are bonus points added to it.
+CREATE TABLE IF NOT EXISTS loyalty_earn_rules(
+	id              bigserial   NOT NULL,
+	category_id     bigint,
+	points_per_unit int         NOT NULL CHECK(points_per_unit > 0),
+	created_at      timestamptz NOT NULL DEFAULT NOW(),
+
+	PRIMARY KEY(id),
+	FOREIGN KEY(category_id) REFERENCES categories(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS loyalty_earn_rules_category_id_key ON loyalty_earn_rules(COALESCE(category_id, 0));
+
+-- loyalty points of users are an append-only ledger, balance of a user is
+-- balance of its latest transaction. remaining is what is left of points
+-- added by a transaction to be redeemed, they are redeemed and expired
+-- oldest first.
+CREATE TABLE IF NOT EXISTS loyalty_transactions(
+	id         bigserial   NOT NULL,
+	user_id    bigint      NOT NULL,
+	order_id   bigint,
+	kind       text        NOT NULL CHECK(kind IN ('earn', 'redeem', 'restore', 'revoke', 'expire')),
+	points     int         NOT NULL CHECK(points <> 0),
+	balance    int         NOT NULL CHECK(balance >= 0),
+	remaining  int         NOT NULL DEFAULT 0 CHECK(remaining >= 0),
+	note       text        NOT NULL DEFAULT '',
+	created_at timestamptz NOT NULL DEFAULT NOW(),
+
+	PRIMARY KEY(id),
+	FOREIGN KEY(user_id)  REFERENCES users(id)  ON DELETE CASCADE,
+	FOREIGN KEY(order_id) REFERENCES orders(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS loyalty_transactions_user_id_idx ON loyalty_transactions(user_id, id);
+CREATE INDEX IF NOT EXISTS loyalty_transactions_remaining_idx ON loyalty_transactions(created_at) WHERE remaining > 0;
+
+-- loyalty discount of orders is part of their discount.
+ALTER TABLE orders
+	ADD COLUMN loyalty_discount int NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE orders
+	DROP COLUMN loyalty_discount;
+
+DROP TABLE IF EXISTS loyalty_transactions;
+DROP TABLE IF EXISTS loyalty_earn_rules;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- admins manage the store, routes moving money are restricted to them.
+ALTER TABLE users
+	ADD COLUMN admin boolean NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE users
+	DROP COLUMN admin;
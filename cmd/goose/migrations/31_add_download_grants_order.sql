@@ -0,0 +1,11 @@
+-- +goose Up
+-- grants of orders are issued once they are paid and taken back when they
+-- are cancelled or refunded.
+ALTER TABLE download_grants
+	ADD COLUMN order_id bigint REFERENCES orders(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS download_grants_order_id_idx ON download_grants(order_id);
+
+-- +goose Down
+ALTER TABLE download_grants
+	DROP COLUMN order_id;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- descriptions are searched along with names.
+DROP INDEX IF EXISTS product_translations_search_idx;
+DROP INDEX IF EXISTS category_translations_search_idx;
+
+CREATE INDEX IF NOT EXISTS product_translations_search_idx
+	ON product_translations USING GIN (to_tsvector('simple', name || ' ' || description));
+
+CREATE INDEX IF NOT EXISTS category_translations_search_idx
+	ON category_translations USING GIN (to_tsvector('simple', name || ' ' || description));
+
+-- +goose Down
+DROP INDEX IF EXISTS product_translations_search_idx;
+DROP INDEX IF EXISTS category_translations_search_idx;
+
+CREATE INDEX IF NOT EXISTS product_translations_search_idx
+	ON product_translations USING GIN (to_tsvector('simple', name));
+
+CREATE INDEX IF NOT EXISTS category_translations_search_idx
+	ON category_translations USING GIN (to_tsvector('simple', name));
@@ -0,0 +1,101 @@
+package document_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mortezadadgar/ecommerce-api/document"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+func TestLoadTemplate(t *testing.T) {
+	template, err := document.LoadTemplate("")
+	if err != nil {
+		t.Fatalf("LoadTemplate: %v", err)
+	}
+
+	if template.InvoiceTitle != document.DefaultTemplate().InvoiceTitle {
+		t.Errorf("expected default template, got: %#v", template)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "template.json")
+	err = os.WriteFile(path, []byte(`{"seller":"Shop Ltd","currency":"€"}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template, err = document.LoadTemplate(path)
+	if err != nil {
+		t.Fatalf("LoadTemplate: %v", err)
+	}
+
+	if template.Seller != "Shop Ltd" || template.Currency != "€" || template.InvoiceTitle != "Invoice" {
+		t.Errorf("expected template over defaults, got: %#v", template)
+	}
+
+	err = os.WriteFile(path, []byte(`{"accent_color":"blue"}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = document.LoadTemplate(path)
+	if err == nil {
+		t.Errorf("expected error for invalid accent color")
+	}
+}
+
+func TestPDF(t *testing.T) {
+	pdf := document.NewPDF(document.DefaultTemplate())
+
+	address := &domain.OrderAddress{Name: "Jürgen", Line1: "Hauptstraße 1", City: "Berlin", PostalCode: "10115", Country: "DE"}
+	order := domain.Order{
+		ID:              1,
+		CreatedAt:       time.Now(),
+		ShippingAddress: address,
+		BillingAddress:  address,
+		Lines: []domain.OrderLine{
+			{ID: 1, Name: "Book", SKU: "B-1", Quantity: 2, UnitPrice: 1000, LineTotal: 2000, Shippable: true},
+			{ID: 2, Name: "E-Book", SKU: "E-1", Quantity: 1, UnitPrice: 500, LineTotal: 500},
+		},
+		Subtotal: 2500,
+		Total:    2500,
+	}
+
+	invoice := domain.NewInvoice(order)
+	invoice.SetNumber(2024, 1)
+
+	var buf bytes.Buffer
+	err := pdf.RenderInvoice(&buf, invoice)
+	if err != nil {
+		t.Fatalf("RenderInvoice: %v", err)
+	}
+
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF")) {
+		t.Errorf("expected pdf from RenderInvoice")
+	}
+
+	buf.Reset()
+	err = pdf.RenderInvoice(&buf, invoice.CreditNote(500, "returned"))
+	if err != nil {
+		t.Fatalf("RenderInvoice: %v", err)
+	}
+
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF")) {
+		t.Errorf("expected pdf from RenderInvoice of credit note")
+	}
+
+	buf.Reset()
+	shipment := &domain.Shipment{ID: 1, Lines: []domain.ShipmentLine{{OrderLineID: 1, Quantity: 1}}}
+	err = pdf.RenderPackingSlip(&buf, order, shipment)
+	if err != nil {
+		t.Fatalf("RenderPackingSlip: %v", err)
+	}
+
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF")) {
+		t.Errorf("expected pdf from RenderPackingSlip")
+	}
+}
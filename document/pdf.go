@@ -0,0 +1,315 @@
+package document
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// column represents a column of documents table.
+type column struct {
+	title string
+	width float64
+	align string
+}
+
+// PDF represents a renderer of order documents as PDF.
+type PDF struct {
+	template Template
+}
+
+// NewPDF returns a new instance of PDF rendering with template.
+func NewPDF(template Template) *PDF {
+	return &PDF{template: template}
+}
+
+// RenderInvoice renders invoice or credit note to w.
+func (p *PDF) RenderInvoice(w io.Writer, invoice domain.Invoice) error {
+	title := p.template.InvoiceTitle
+	if invoice.Kind == domain.InvoiceKindCreditNote {
+		title = p.template.CreditNoteTitle
+	}
+
+	doc, tr := p.newDocument(title)
+	p.header(doc, tr, title, []string{
+		"Number: " + invoice.Number,
+		"Date: " + invoice.IssuedAt.Format("2006-01-02"),
+		fmt.Sprintf("Order: #%d", invoice.OrderID),
+	})
+	p.addresses(doc, tr, invoice.BillingAddress, invoice.ShippingAddress)
+
+	if invoice.Kind == domain.InvoiceKindCreditNote {
+		if invoice.Reason != "" {
+			doc.MultiCell(0, 6, tr("Reason: "+invoice.Reason), "", "L", false)
+			doc.Ln(4)
+		}
+		p.totals(doc, tr, [][2]string{{"Credited", p.money(invoice.Total)}})
+		return p.output(w, doc)
+	}
+
+	columns := []column{
+		{"Item", 70, "L"},
+		{"Qty", 15, "R"},
+		{"Unit price", 25, "R"},
+		{"Discount", 25, "R"},
+		{"Tax", 25, "R"},
+		{"Total", 30, "R"},
+	}
+
+	rows := make([][]string, 0, len(invoice.Lines))
+	for _, line := range invoice.Lines {
+		rows = append(rows, []string{
+			line.Name,
+			fmt.Sprint(line.Quantity),
+			p.money(line.UnitPrice),
+			p.money(line.Discount),
+			fmt.Sprintf("%s (%s)", p.money(line.Tax), rate(line.TaxRate)),
+			p.money(line.Total),
+		})
+	}
+	p.table(doc, tr, columns, rows)
+
+	taxLabel := "Tax"
+	if invoice.TaxInclusive {
+		taxLabel = "Tax (included)"
+	}
+
+	p.totals(doc, tr, [][2]string{
+		{"Subtotal", p.money(invoice.Subtotal)},
+		{"Discount", p.money(invoice.Discount)},
+		{taxLabel, p.money(invoice.Tax)},
+		{"Shipping", p.money(invoice.Shipping)},
+		{"Total", p.money(invoice.Total)},
+	})
+
+	return p.output(w, doc)
+}
+
+// RenderPackingSlip renders packing slip of order to w.
+func (p *PDF) RenderPackingSlip(w io.Writer, order domain.Order, shipment *domain.Shipment) error {
+	title := p.template.PackingSlipTitle
+
+	details := []string{
+		fmt.Sprintf("Order: #%d", order.ID),
+		"Date: " + order.CreatedAt.Format("2006-01-02"),
+	}
+	if shipment != nil {
+		details = append(details, fmt.Sprintf("Shipment: #%d", shipment.ID))
+		if shipment.TrackingNumber != "" {
+			details = append(details, "Tracking: "+shipment.Carrier+" "+shipment.TrackingNumber)
+		}
+	}
+
+	doc, tr := p.newDocument(title)
+	p.header(doc, tr, title, details)
+	p.addresses(doc, tr, nil, order.ShippingAddress)
+
+	quantities := make(map[int]int)
+	if shipment != nil {
+		for _, line := range shipment.Lines {
+			quantities[line.OrderLineID] += line.Quantity
+		}
+	}
+
+	columns := []column{
+		{"SKU", 45, "L"},
+		{"Item", 115, "L"},
+		{"Qty", 30, "R"},
+	}
+
+	rows := make([][]string, 0, len(order.Lines))
+	for _, line := range order.Lines {
+		quantity := line.Quantity
+		if shipment != nil {
+			quantity = quantities[line.ID]
+		}
+
+		if !line.Shippable || quantity == 0 {
+			continue
+		}
+
+		rows = append(rows, []string{line.SKU, line.Name, fmt.Sprint(quantity)})
+	}
+	p.table(doc, tr, columns, rows)
+
+	return p.output(w, doc)
+}
+
+// newDocument returns a new A4 document with its footer and a translator of
+// UTF-8 text to the document encoding.
+func (p *PDF) newDocument(title string) (*fpdf.Fpdf, func(string) string) {
+	doc := fpdf.New("P", "mm", "A4", "")
+	tr := doc.UnicodeTranslatorFromDescriptor("")
+
+	doc.SetTitle(title, true)
+	doc.SetAuthor(p.template.Seller, true)
+	doc.AliasNbPages("")
+	doc.SetFooterFunc(func() {
+		doc.SetY(-15)
+		doc.SetFont("Helvetica", "", 8)
+		doc.SetTextColor(128, 128, 128)
+		doc.CellFormat(0, 5, tr(p.template.Footer), "", 0, "L", false, 0, "")
+		doc.CellFormat(0, 5, fmt.Sprintf("%d/{nb}", doc.PageNo()), "", 0, "R", false, 0, "")
+	})
+	doc.AddPage()
+
+	return doc, tr
+}
+
+// header writes seller details, logo, title and details of document.
+func (p *PDF) header(doc *fpdf.Fpdf, tr func(string) string, title string, details []string) {
+	if p.template.Logo != "" {
+		doc.ImageOptions(p.template.Logo, 160, 10, 40, 0, false, fpdf.ImageOptions{ReadDpi: true}, 0, "")
+	}
+
+	doc.SetFont("Helvetica", "B", 14)
+	doc.CellFormat(0, 7, tr(p.template.Seller), "", 1, "L", false, 0, "")
+
+	doc.SetFont("Helvetica", "", 9)
+	for _, line := range p.template.Address {
+		doc.CellFormat(0, 5, tr(line), "", 1, "L", false, 0, "")
+	}
+	if p.template.TaxID != "" {
+		doc.CellFormat(0, 5, tr("Tax ID: "+p.template.TaxID), "", 1, "L", false, 0, "")
+	}
+	doc.Ln(8)
+
+	r, g, b := p.accent()
+	doc.SetTextColor(r, g, b)
+	doc.SetFont("Helvetica", "B", 20)
+	doc.CellFormat(0, 10, tr(title), "", 1, "L", false, 0, "")
+	doc.SetTextColor(0, 0, 0)
+
+	doc.SetFont("Helvetica", "", 10)
+	for _, detail := range details {
+		doc.CellFormat(0, 5, tr(detail), "", 1, "L", false, 0, "")
+	}
+	doc.Ln(6)
+}
+
+// addresses writes billing and shipping addresses side by side, nil
+// addresses are left out.
+func (p *PDF) addresses(doc *fpdf.Fpdf, tr func(string) string, billing, shipping *domain.OrderAddress) {
+	blocks := make([][]string, 0, 2)
+	if billing != nil {
+		blocks = append(blocks, append([]string{"Bill to"}, addressLines(billing)...))
+	}
+	if shipping != nil {
+		blocks = append(blocks, append([]string{"Ship to"}, addressLines(shipping)...))
+	}
+
+	if len(blocks) == 0 {
+		return
+	}
+
+	height := 0
+	for _, block := range blocks {
+		if len(block) > height {
+			height = len(block)
+		}
+	}
+
+	for i := 0; i < height; i++ {
+		for _, block := range blocks {
+			text := ""
+			if i < len(block) {
+				text = block[i]
+			}
+
+			doc.SetFont("Helvetica", "", 10)
+			if i == 0 {
+				doc.SetFont("Helvetica", "B", 10)
+			}
+			doc.CellFormat(95, 5, tr(text), "", 0, "L", false, 0, "")
+		}
+		doc.Ln(5)
+	}
+	doc.Ln(6)
+}
+
+// table writes rows under a header of columns.
+func (p *PDF) table(doc *fpdf.Fpdf, tr func(string) string, columns []column, rows [][]string) {
+	r, g, b := p.accent()
+	doc.SetFillColor(r, g, b)
+	doc.SetTextColor(255, 255, 255)
+	doc.SetFont("Helvetica", "B", 9)
+	for _, column := range columns {
+		doc.CellFormat(column.width, 7, tr(column.title), "", 0, column.align, true, 0, "")
+	}
+	doc.Ln(-1)
+
+	doc.SetTextColor(0, 0, 0)
+	doc.SetFont("Helvetica", "", 9)
+	for _, row := range rows {
+		for i, column := range columns {
+			doc.CellFormat(column.width, 6, tr(row[i]), "B", 0, column.align, false, 0, "")
+		}
+		doc.Ln(-1)
+	}
+	doc.Ln(4)
+}
+
+// totals writes labeled amounts aligned right, last one is emphasized.
+func (p *PDF) totals(doc *fpdf.Fpdf, tr func(string) string, amounts [][2]string) {
+	for i, amount := range amounts {
+		doc.SetFont("Helvetica", "", 10)
+		if i == len(amounts)-1 {
+			doc.SetFont("Helvetica", "B", 11)
+		}
+
+		doc.CellFormat(150, 6, tr(amount[0]), "", 0, "R", false, 0, "")
+		doc.CellFormat(40, 6, tr(amount[1]), "", 1, "R", false, 0, "")
+	}
+}
+
+// output writes doc to w.
+func (p *PDF) output(w io.Writer, doc *fpdf.Fpdf) error {
+	err := doc.Output(w)
+	if err != nil {
+		return fmt.Errorf("failed to render pdf: %v", err)
+	}
+
+	return nil
+}
+
+// accent returns accent color of template, black if it is invalid.
+func (p *PDF) accent() (int, int, int) {
+	r, g, b, err := p.template.accent()
+	if err != nil {
+		return 0, 0, 0
+	}
+
+	return r, g, b
+}
+
+// money formats amount in minor units.
+func (p *PDF) money(amount int) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	return fmt.Sprintf("%s%s%d.%02d", sign, p.template.Currency, amount/100, amount%100)
+}
+
+// rate formats tax rate in domain.TaxRateBase as percentage.
+func rate(rate int) string {
+	percent := fmt.Sprintf("%d.%02d", rate/100, rate%100)
+	percent = strings.TrimSuffix(strings.TrimRight(percent, "0"), ".")
+	return percent + "%"
+}
+
+// addressLines returns printable lines of address.
+func addressLines(address *domain.OrderAddress) []string {
+	lines := []string{address.Name, address.Line1}
+	if address.Line2 != "" {
+		lines = append(lines, address.Line2)
+	}
+
+	city := strings.TrimSpace(strings.Join([]string{address.City, address.Region, address.PostalCode}, " "))
+	return append(lines, city, address.Country)
+}
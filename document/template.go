@@ -0,0 +1,85 @@
+// Package document renders order documents such as invoices and packing
+// slips.
+package document
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var errInvalidAccentColor = errors.New("accent_color must be in #rrggbb format")
+
+// Template represents customizable parts of documents, fields not given
+// in a template file keep their defaults.
+type Template struct {
+	Seller      string   `json:"seller"`
+	Address     []string `json:"address"`
+	TaxID       string   `json:"tax_id"`
+	Footer      string   `json:"footer"`
+	Logo        string   `json:"logo"`
+	AccentColor string   `json:"accent_color"`
+	Currency    string   `json:"currency"`
+
+	InvoiceTitle     string `json:"invoice_title"`
+	CreditNoteTitle  string `json:"credit_note_title"`
+	PackingSlipTitle string `json:"packing_slip_title"`
+}
+
+// DefaultTemplate returns template documents are rendered with unless
+// customized.
+func DefaultTemplate() Template {
+	return Template{
+		Seller:      "e-commerce",
+		AccentColor: "#2f4f6f",
+		Currency:    "$",
+
+		InvoiceTitle:     "Invoice",
+		CreditNoteTitle:  "Credit Note",
+		PackingSlipTitle: "Packing Slip",
+	}
+}
+
+// LoadTemplate reads a JSON template file over default template, empty path
+// returns default template.
+func LoadTemplate(path string) (Template, error) {
+	template := DefaultTemplate()
+	if path == "" {
+		return template, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Template{}, fmt.Errorf("failed to read document template: %v", err)
+	}
+
+	err = json.Unmarshal(data, &template)
+	if err != nil {
+		return Template{}, fmt.Errorf("failed to parse document template: %v", err)
+	}
+
+	_, _, _, err = template.accent()
+	if err != nil {
+		return Template{}, err
+	}
+
+	return template, nil
+}
+
+// accent returns red, green and blue of accent color.
+func (t Template) accent() (int, int, int, error) {
+	color := strings.TrimPrefix(t.AccentColor, "#")
+	if len(color) != 6 {
+		return 0, 0, 0, errInvalidAccentColor
+	}
+
+	value, err := strconv.ParseUint(color, 16, 32)
+	if err != nil {
+		return 0, 0, 0, errInvalidAccentColor
+	}
+
+	return int(value >> 16 & 0xff), int(value >> 8 & 0xff), int(value & 0xff), nil
+}
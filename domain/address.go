@@ -0,0 +1,285 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoAddressesFound = errors.New("no addresses found")
+	ErrInvalidAddress   = errors.New("invalid address")
+
+	errAddressNameRequired  = errors.New("name is required")
+	errAddressLineRequired  = errors.New("line1 is required")
+	errAddressCityRequired  = errors.New("city is required")
+	errAddressRegionMissing = errors.New("region is required in country")
+	errPostalCodeRequired   = errors.New("postal_code is required in country")
+	errInvalidPostalCode    = errors.New("invalid postal_code for country")
+	errInvalidPhone         = errors.New("invalid phone")
+)
+
+// addressRule represents rules of addresses in a country.
+type addressRule struct {
+	postalCode     *regexp.Regexp
+	regionRequired bool
+}
+
+// addressRules are rules of countries, addresses of other countries are
+// only checked for required fields.
+var addressRules = map[string]addressRule{
+	"US": {postalCode: regexp.MustCompile(`^\d{5}(-\d{4})?$`), regionRequired: true},
+	"CA": {postalCode: regexp.MustCompile(`^[A-Z]\d[A-Z] ?\d[A-Z]\d$`), regionRequired: true},
+	"AU": {postalCode: regexp.MustCompile(`^\d{4}$`), regionRequired: true},
+	"GB": {postalCode: regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`)},
+	"DE": {postalCode: regexp.MustCompile(`^\d{5}$`)},
+	"FR": {postalCode: regexp.MustCompile(`^\d{5}$`)},
+	"NL": {postalCode: regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`)},
+	"JP": {postalCode: regexp.MustCompile(`^\d{3}-?\d{4}$`)},
+	"IR": {postalCode: regexp.MustCompile(`^\d{5}-?\d{5}$`)},
+}
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9 ()-]{6,20}$`)
+
+// WrapAddress wraps addresses for user representation.
+type WrapAddress struct {
+	Address Address `json:"address"`
+}
+
+// WrapAddressList wraps list of addresses for user representation.
+type WrapAddressList struct {
+	Addresses []Address `json:"addresses"`
+}
+
+// Address represents addresses model, a user has at most a default
+// shipping and a default billing address.
+type Address struct {
+	ID              int       `json:"id"`
+	UserID          int       `json:"-" db:"user_id"`
+	Name            string    `json:"name"`
+	Line1           string    `json:"line1"`
+	Line2           string    `json:"line2"`
+	City            string    `json:"city"`
+	Region          string    `json:"region"`
+	PostalCode      string    `json:"postal_code" db:"postal_code"`
+	Country         string    `json:"country"`
+	Phone           string    `json:"phone"`
+	DefaultShipping bool      `json:"default_shipping" db:"default_shipping"`
+	DefaultBilling  bool      `json:"default_billing" db:"default_billing"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// OrderAddress represents an address as it was when an order was placed.
+type OrderAddress struct {
+	Name       string `json:"name"`
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2"`
+	City       string `json:"city"`
+	Region     string `json:"region"`
+	PostalCode string `json:"postal_code"`
+	Country    string `json:"country"`
+	Phone      string `json:"phone"`
+}
+
+// AddressCreate represents addresses model for POST requests.
+type AddressCreate struct {
+	Name            string `json:"name"`
+	Line1           string `json:"line1"`
+	Line2           string `json:"line2"`
+	City            string `json:"city"`
+	Region          string `json:"region"`
+	PostalCode      string `json:"postal_code"`
+	Country         string `json:"country"`
+	Phone           string `json:"phone"`
+	DefaultShipping bool   `json:"default_shipping"`
+	DefaultBilling  bool   `json:"default_billing"`
+}
+
+// AddressUpdate represents addresses model for PATCH requests.
+type AddressUpdate struct {
+	Name            *string `json:"name"`
+	Line1           *string `json:"line1"`
+	Line2           *string `json:"line2"`
+	City            *string `json:"city"`
+	Region          *string `json:"region"`
+	PostalCode      *string `json:"postal_code"`
+	Country         *string `json:"country"`
+	Phone           *string `json:"phone"`
+	DefaultShipping *bool   `json:"default_shipping"`
+	DefaultBilling  *bool   `json:"default_billing"`
+}
+
+// AddressService represents a service for managing addresses of users,
+// addresses of other users are not found.
+type AddressService interface {
+	Create(ctx context.Context, address *Address) error
+	GetByID(ctx context.Context, userID int, ID int) (Address, error)
+	List(ctx context.Context, userID int) ([]Address, error)
+	Update(ctx context.Context, userID int, ID int, input AddressUpdate) (Address, error)
+	Delete(ctx context.Context, userID int, ID int) error
+}
+
+// Validate validates POST requests model.
+func (a AddressCreate) Validate() error {
+	return a.CreateModel(0).Validate()
+}
+
+// CreateModel set input values to a new struct and return a new instance.
+func (a AddressCreate) CreateModel(userID int) Address {
+	address := Address{
+		UserID:          userID,
+		Name:            a.Name,
+		Line1:           a.Line1,
+		Line2:           a.Line2,
+		City:            a.City,
+		Region:          a.Region,
+		PostalCode:      a.PostalCode,
+		Country:         a.Country,
+		Phone:           a.Phone,
+		DefaultShipping: a.DefaultShipping,
+		DefaultBilling:  a.DefaultBilling,
+	}
+	address.normalize()
+
+	return address
+}
+
+// UpdateModel checks whether addresses input are not nil and set values,
+// the resulting address is validated.
+func (a AddressUpdate) UpdateModel(address *Address) error {
+	if a.Name != nil {
+		address.Name = *a.Name
+	}
+
+	if a.Line1 != nil {
+		address.Line1 = *a.Line1
+	}
+
+	if a.Line2 != nil {
+		address.Line2 = *a.Line2
+	}
+
+	if a.City != nil {
+		address.City = *a.City
+	}
+
+	if a.Region != nil {
+		address.Region = *a.Region
+	}
+
+	if a.PostalCode != nil {
+		address.PostalCode = *a.PostalCode
+	}
+
+	if a.Country != nil {
+		address.Country = *a.Country
+	}
+
+	if a.Phone != nil {
+		address.Phone = *a.Phone
+	}
+
+	if a.DefaultShipping != nil {
+		address.DefaultShipping = *a.DefaultShipping
+	}
+
+	if a.DefaultBilling != nil {
+		address.DefaultBilling = *a.DefaultBilling
+	}
+
+	address.normalize()
+
+	err := address.Validate()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAddress, err)
+	}
+
+	return nil
+}
+
+// normalize trims fields of address, country and postal code are kept in
+// upper case.
+func (a *Address) normalize() {
+	a.Name = strings.TrimSpace(a.Name)
+	a.Line1 = strings.TrimSpace(a.Line1)
+	a.Line2 = strings.TrimSpace(a.Line2)
+	a.City = strings.TrimSpace(a.City)
+	a.Region = strings.TrimSpace(a.Region)
+	a.PostalCode = strings.ToUpper(strings.TrimSpace(a.PostalCode))
+	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+	a.Phone = strings.TrimSpace(a.Phone)
+}
+
+// Validate validates address by rules of its country.
+func (a Address) Validate() error {
+	switch {
+	case a.Name == "":
+		return errAddressNameRequired
+	case a.Line1 == "":
+		return errAddressLineRequired
+	case a.City == "":
+		return errAddressCityRequired
+	case len(a.Country) != 2:
+		return errCountryRequired
+	case a.Phone != "" && !phonePattern.MatchString(a.Phone):
+		return errInvalidPhone
+	}
+
+	rule, ok := addressRules[a.Country]
+	if !ok {
+		return nil
+	}
+
+	switch {
+	case rule.regionRequired && a.Region == "":
+		return errAddressRegionMissing
+	case a.PostalCode == "":
+		return errPostalCodeRequired
+	case !rule.postalCode.MatchString(a.PostalCode):
+		return errInvalidPostalCode
+	}
+
+	return nil
+}
+
+// Location returns location of address.
+func (a Address) Location() Location {
+	return Location{Country: a.Country, Region: a.Region, PostalCode: a.PostalCode}.Normalize()
+}
+
+// Snapshot returns address to keep on orders.
+func (a Address) Snapshot() *OrderAddress {
+	return &OrderAddress{
+		Name:       a.Name,
+		Line1:      a.Line1,
+		Line2:      a.Line2,
+		City:       a.City,
+		Region:     a.Region,
+		PostalCode: a.PostalCode,
+		Country:    a.Country,
+		Phone:      a.Phone,
+	}
+}
+
+// DefaultAddresses returns default shipping and billing addresses among
+// addresses, billing falls back to shipping address.
+func DefaultAddresses(addresses []Address) (shipping *Address, billing *Address) {
+	for i := range addresses {
+		if addresses[i].DefaultShipping {
+			shipping = &addresses[i]
+		}
+		if addresses[i].DefaultBilling {
+			billing = &addresses[i]
+		}
+	}
+
+	if billing == nil {
+		billing = shipping
+	}
+
+	return shipping, billing
+}
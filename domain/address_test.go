@@ -0,0 +1,76 @@
+package domain_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+func TestAddressCreateValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		address domain.AddressCreate
+		valid   bool
+	}{
+		{"us", domain.AddressCreate{Name: "a", Line1: "l", City: "c", Region: "CA", PostalCode: "94107", Country: "us"}, true},
+		{"us zip+4", domain.AddressCreate{Name: "a", Line1: "l", City: "c", Region: "CA", PostalCode: "94107-1234", Country: "US"}, true},
+		{"us without region", domain.AddressCreate{Name: "a", Line1: "l", City: "c", PostalCode: "94107", Country: "US"}, false},
+		{"us invalid zip", domain.AddressCreate{Name: "a", Line1: "l", City: "c", Region: "CA", PostalCode: "9410", Country: "US"}, false},
+		{"gb lower case", domain.AddressCreate{Name: "a", Line1: "l", City: "c", PostalCode: "sw1a 1aa", Country: "gb"}, true},
+		{"de without postal code", domain.AddressCreate{Name: "a", Line1: "l", City: "c", Country: "DE"}, false},
+		{"unknown country", domain.AddressCreate{Name: "a", Line1: "l", City: "c", Country: "ZZ"}, true},
+		{"missing line1", domain.AddressCreate{Name: "a", City: "c", Country: "ZZ"}, false},
+		{"invalid phone", domain.AddressCreate{Name: "a", Line1: "l", City: "c", Country: "ZZ", Phone: "call me"}, false},
+		{"missing country", domain.AddressCreate{Name: "a", Line1: "l", City: "c"}, false},
+	}
+
+	for _, tt := range tests {
+		err := tt.address.Validate()
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid %t, got: %v", tt.name, tt.valid, err)
+		}
+	}
+}
+
+func TestAddressUpdateModel(t *testing.T) {
+	address := domain.AddressCreate{Name: "a", Line1: "l", City: "c", Region: "CA", PostalCode: "94107", Country: "US"}.CreateModel(1)
+
+	country := "GB"
+	err := domain.AddressUpdate{Country: &country}.UpdateModel(&address)
+	if !errors.Is(err, domain.ErrInvalidAddress) {
+		t.Errorf("expected %q, got %q", domain.ErrInvalidAddress, err)
+	}
+
+	address = domain.AddressCreate{Name: "a", Line1: "l", City: "c", Region: "CA", PostalCode: "94107", Country: "US"}.CreateModel(1)
+	country = "DE"
+	postalCode := " 10115 "
+	err = domain.AddressUpdate{Country: &country, PostalCode: &postalCode}.UpdateModel(&address)
+	if err != nil {
+		t.Fatalf("UpdateModel: %v", err)
+	}
+
+	if address.PostalCode != "10115" || address.Country != "DE" {
+		t.Errorf("expected normalized address, got: %+v", address)
+	}
+}
+
+func TestDefaultAddresses(t *testing.T) {
+	addresses := []domain.Address{{ID: 1}, {ID: 2, DefaultShipping: true}}
+
+	shipping, billing := domain.DefaultAddresses(addresses)
+	if shipping == nil || shipping.ID != 2 || billing != shipping {
+		t.Errorf("expected billing to fall back to shipping address 2, got: %v, %v", shipping, billing)
+	}
+
+	addresses[0].DefaultBilling = true
+	shipping, billing = domain.DefaultAddresses(addresses)
+	if shipping.ID != 2 || billing.ID != 1 {
+		t.Errorf("expected shipping 2 and billing 1, got: %d, %d", shipping.ID, billing.ID)
+	}
+
+	shipping, billing = domain.DefaultAddresses(nil)
+	if shipping != nil || billing != nil {
+		t.Errorf("expected no defaults, got: %v, %v", shipping, billing)
+	}
+}
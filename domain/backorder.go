@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"errors"
+)
+
+var (
+	errInvalidInventoryPolicy  = errors.New("invalid inventory policy")
+	errBackorderLimit          = errors.New("backorder_limit must not be negative")
+	errExpectedShipDate        = errors.New("expected_ship_date is required for pre-orders")
+	errPolicyNotForBundle      = errors.New("bundles can not be backordered or pre-ordered")
+	errBackorderLimitForPolicy = errors.New("backorder_limit is only for backorder policy")
+)
+
+// Inventory policies of products deciding whether they are sold beyond
+// stock on hand.
+const (
+	InventoryPolicyDeny      = "deny"
+	InventoryPolicyBackorder = "backorder"
+	InventoryPolicyPreorder  = "preorder"
+)
+
+// BackorderedLine represents quantity of an order line waiting for stock.
+type BackorderedLine struct {
+	ID          int
+	OrderID     int
+	ProductID   int
+	Backordered int
+}
+
+// validateInventoryPolicy validates policy fields of products.
+func validateInventoryPolicy(policy string, limit int, expected bool, bundle bool) error {
+	switch {
+	case policy != "" && policy != InventoryPolicyDeny &&
+		policy != InventoryPolicyBackorder && policy != InventoryPolicyPreorder:
+		return errInvalidInventoryPolicy
+	case limit < 0:
+		return errBackorderLimit
+	case bundle && policy != "" && policy != InventoryPolicyDeny:
+		return errPolicyNotForBundle
+	case policy == InventoryPolicyPreorder && !expected:
+		return errExpectedShipDate
+	case limit > 0 && policy != InventoryPolicyBackorder:
+		return errBackorderLimitForPolicy
+	}
+	return nil
+}
+
+// SellsBeyondStock reports whether product may be sold when out of stock.
+func (p Product) SellsBeyondStock() bool {
+	return p.InventoryPolicy == InventoryPolicyBackorder || p.InventoryPolicy == InventoryPolicyPreorder
+}
+
+// AllowsBackorder reports whether shortfall of stock may be sold given
+// quantity of product already backordered, pre-orders are not limited.
+func (p Product) AllowsBackorder(shortfall int, backordered int) bool {
+	switch p.InventoryPolicy {
+	case InventoryPolicyPreorder:
+		return true
+	case InventoryPolicyBackorder:
+		return backordered+shortfall <= p.BackorderLimit
+	}
+	return false
+}
+
+// OrderableQuantity returns quantity of product that may be ordered given
+// its available stock and quantity already backordered, ok is false for
+// pre-orders which are not limited.
+func (p Product) OrderableQuantity(backordered int) (quantity int, ok bool) {
+	available := p.Available
+	if available < 0 {
+		available = 0
+	}
+
+	switch p.InventoryPolicy {
+	case InventoryPolicyPreorder:
+		return 0, false
+	case InventoryPolicyBackorder:
+		if backordered < p.BackorderLimit {
+			available += p.BackorderLimit - backordered
+		}
+	}
+	return available, true
+}
+
+// ApplyBackorders sets backordered quantities by product id on lines of
+// order.
+func (o *Order) ApplyBackorders(backordered map[int]int) {
+	for i := range o.Lines {
+		line := &o.Lines[i]
+		if line.ProductID != nil {
+			line.Backordered = backordered[*line.ProductID]
+		}
+	}
+}
+
+// AllocateBackorders allocates quantity to backordered lines in order, it
+// returns quantity allocated to each line by its id.
+func AllocateBackorders(quantity int, lines []BackorderedLine) map[int]int {
+	allocated := make(map[int]int)
+	for _, line := range lines {
+		if quantity <= 0 {
+			break
+		}
+
+		take := line.Backordered
+		if take > quantity {
+			take = quantity
+		}
+
+		allocated[line.ID] = take
+		quantity -= take
+	}
+	return allocated
+}
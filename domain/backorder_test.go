@@ -0,0 +1,74 @@
+package domain_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+func TestAllocateBackorders(t *testing.T) {
+	lines := []domain.BackorderedLine{
+		{ID: 3, OrderID: 1, Backordered: 2},
+		{ID: 1, OrderID: 2, Backordered: 4},
+		{ID: 2, OrderID: 3, Backordered: 1},
+	}
+
+	got := domain.AllocateBackorders(5, lines)
+	if want := map[int]int{3: 2, 1: 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("mismatch\n got: %v\nwant: %v", got, want)
+	}
+
+	if got := domain.AllocateBackorders(-1, lines); len(got) != 0 {
+		t.Errorf("expected nothing allocated, got: %v", got)
+	}
+}
+
+func TestInventoryPolicy(t *testing.T) {
+	backorder := domain.Product{InventoryPolicy: domain.InventoryPolicyBackorder, BackorderLimit: 5}
+	if !backorder.AllowsBackorder(2, 3) || backorder.AllowsBackorder(3, 3) {
+		t.Errorf("expected backorders up to limit of %d", backorder.BackorderLimit)
+	}
+
+	preorder := domain.Product{InventoryPolicy: domain.InventoryPolicyPreorder}
+	if !preorder.AllowsBackorder(100, 100) {
+		t.Errorf("expected pre-orders to be unlimited")
+	}
+
+	if (domain.Product{InventoryPolicy: domain.InventoryPolicyDeny}).AllowsBackorder(1, 0) {
+		t.Errorf("expected deny policy to reject backorders")
+	}
+
+	backorder.Available = 2
+	if quantity, ok := backorder.OrderableQuantity(1); !ok || quantity != 6 {
+		t.Errorf("expected orderable quantity of %d, got: %d", 6, quantity)
+	}
+
+	if _, ok := preorder.OrderableQuantity(100); ok {
+		t.Errorf("expected pre-orders to be unlimited")
+	}
+
+	input := domain.ProductCreate{
+		Name:            "product",
+		Description:     "description",
+		CategoryID:      1,
+		Price:           10,
+		InventoryPolicy: domain.InventoryPolicyPreorder,
+	}
+	if input.Validate() == nil {
+		t.Errorf("expected pre-order without expected ship date to be rejected")
+	}
+
+	shipDate := time.Now().Add(24 * time.Hour)
+	input.ExpectedShipDate = &shipDate
+	if err := input.Validate(); err != nil {
+		t.Errorf("expected pre-order without stock to be valid, got %q", err)
+	}
+
+	product := input.CreateModel()
+	product.ApplyReservations(0)
+	if product.StockStatus != domain.StockPreorder {
+		t.Errorf("expected stock status %q, got %q", domain.StockPreorder, product.StockStatus)
+	}
+}
@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrInvalidBundleComponent = errors.New("invalid bundle component")
+	ErrNotBundle              = errors.New("product is not a bundle")
+	ErrInsufficientStock      = errors.New("insufficient stock")
+
+	errComponentsRequired      = errors.New("components are required")
+	errComponentQuantity       = errors.New("component quantity must be positive")
+	errInvalidBundlePricing    = errors.New("invalid bundle pricing")
+	errInvalidBundleDiscount   = errors.New("bundle discount must be between 0 and 100")
+	errDuplicatedComponent     = errors.New("duplicated bundle component")
+	errComponentsOnlyForBundle = errors.New("components are only allowed on bundles")
+)
+
+// Bundle pricing modes.
+const (
+	// BundlePricingFixed uses the price stored on the bundle itself.
+	BundlePricingFixed = "fixed"
+	// BundlePricingDerived sums component prices and applies BundleDiscount.
+	BundlePricingDerived = "derived"
+)
+
+// WrapBundleComponentList wraps list of bundle components for user representation.
+type WrapBundleComponentList struct {
+	Components []BundleComponent `json:"components"`
+}
+
+// BundleComponent represents a product and its quantity within a bundle.
+type BundleComponent struct {
+	BundleID  int `json:"-" db:"bundle_id"`
+	ProductID int `json:"product_id" db:"product_id"`
+	Quantity  int `json:"quantity"`
+	Price     int `json:"price"`
+	Stock     int `json:"stock"`
+}
+
+// BundleComponentInput represents bundle components model for PUT requests.
+type BundleComponentInput struct {
+	Components []BundleComponent `json:"components"`
+}
+
+// BundleService represents a service for managing bundle components.
+type BundleService interface {
+	ListComponents(ctx context.Context, bundleID int) ([]BundleComponent, error)
+	SetComponents(ctx context.Context, bundleID int, components []BundleComponent) ([]BundleComponent, error)
+}
+
+// Validate validates PUT requests model.
+func (b BundleComponentInput) Validate() error {
+	return validateComponents(b.Components)
+}
+
+func validateComponents(components []BundleComponent) error {
+	if len(components) == 0 {
+		return errComponentsRequired
+	}
+
+	seen := make(map[int]bool, len(components))
+	for _, c := range components {
+		switch {
+		case c.ProductID == 0:
+			return errProductIDRequired
+		case c.Quantity <= 0:
+			return errComponentQuantity
+		case seen[c.ProductID]:
+			return errDuplicatedComponent
+		}
+		seen[c.ProductID] = true
+	}
+
+	return nil
+}
+
+// IsBundle reports whether product is a bundle.
+func (p Product) IsBundle() bool {
+	return p.Type == ProductTypeBundle
+}
+
+// ApplyComponents attaches components to a bundle and computes its
+// price and available quantity from them.
+func (p *Product) ApplyComponents(components []BundleComponent) {
+	p.Components = components
+	p.Quantity = BundleStock(components)
+
+	if p.BundlePricing == BundlePricingDerived {
+		p.Price = DerivedBundlePrice(components, p.BundleDiscount)
+	}
+}
+
+// DerivedBundlePrice returns sum of components price with discount
+// percentage applied.
+func DerivedBundlePrice(components []BundleComponent, discount int) int {
+	var total int
+	for _, c := range components {
+		total += c.Price * c.Quantity
+	}
+
+	return total - total*discount/100
+}
+
+// BundleStock returns how many bundles can be assembled from components stock.
+func BundleStock(components []BundleComponent) int {
+	if len(components) == 0 {
+		return 0
+	}
+
+	stock := -1
+	for _, c := range components {
+		n := c.Stock / c.Quantity
+		if n < 0 {
+			n = 0
+		}
+		if stock == -1 || n < stock {
+			stock = n
+		}
+	}
+
+	return stock
+}
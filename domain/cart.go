@@ -3,17 +3,34 @@ package domain
 import (
 	"context"
 	"errors"
+	"sort"
+	"time"
 )
 
 var (
 	ErrCartInvalidUserID    = errors.New("invalid user id cart")
 	ErrCartInvalidProductID = errors.New("invalid product id cart")
 	ErrNoCartsFound         = errors.New("no carts found")
+	ErrNoCartItemsFound     = errors.New("no cart items found")
 
 	errUserIDRequired    = errors.New("user_id is required")
 	errProductIDRequired = errors.New("product_id is required")
 )
 
+// Strategies of merging a guest cart into user's cart when both hold the
+// same product.
+const (
+	// CartMergeSum adds up quantities of both lines.
+	CartMergeSum = "sum"
+	// CartMergeNewest keeps the most recently updated line.
+	CartMergeNewest = "newest"
+	// CartMergeCap adds up quantities but caps them at what may be ordered.
+	CartMergeCap = "cap"
+)
+
+// GuestCartExpiry is lifetime of cart tokens handed to guests.
+const GuestCartExpiry = 30 * 24 * time.Hour
+
 // WrapCart wraps carts for user representation.
 type WrapCart struct {
 	Cart Cart `json:"cart"`
@@ -24,25 +41,90 @@ type WrapCartList struct {
 	Carts []Cart `json:"carts"`
 }
 
-// Cart represents carts model.
+// Cart represents carts model, a user or a guest owns a single cart
+// holding its line items. Coupons are codes applied to cart and Discounts
+// the coupons and promotions actually discounting it.
 type Cart struct {
-	ID        int `json:"id"`
-	ProductID int `json:"product_id" db:"product_id"`
-	Quantity  int `json:"quantity" db:"quantity"`
-	UserID    int `json:"user_id" db:"user_id"`
+	ID     int        `json:"id"`
+	UserID int        `json:"user_id" db:"user_id"`
+	Guest  bool       `json:"guest"`
+	Items  []CartItem `json:"items" db:"-"`
+	Totals CartTotals `json:"totals" db:"-"`
+
+	Coupons      []string          `json:"coupons" db:"-"`
+	Discounts    []AppliedDiscount `json:"discounts" db:"-"`
+	FreeShipping bool              `json:"free_shipping" db:"-"`
+
+	// Destination is location cart is delivered to, taxes are
+	// calculated by it.
+	Destination Location `json:"destination" db:"-"`
+
+	// ShippingMethodID is shipping method selected for cart, Shipping is
+	// its rate once quoted.
+	ShippingMethodID *int          `json:"shipping_method_id" db:"-"`
+	Shipping         *ShippingRate `json:"shipping" db:"-"`
+
+	// UpdatedAt is last time cart or its items changed, carts of users
+	// left alone for a while are abandoned.
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
-// CartCreate represents carts model for POST requests.
-type CartCreate struct {
-	UserID    int `json:"user_id"`
+// CartItem represents a line of cart, prices are taken from current
+// product prices. Discount is share of line in discounts of cart and line
+// total is net of it, tax of line is included in line total when prices
+// include tax.
+type CartItem struct {
+	ID        int    `json:"id"`
+	CartID    int    `json:"-" db:"cart_id"`
+	ProductID int    `json:"product_id" db:"product_id"`
+	Quantity  int    `json:"quantity"`
+	Name      string `json:"name" db:"-"`
+	UnitPrice int    `json:"unit_price" db:"-"`
+	Discount  int    `json:"discount" db:"-"`
+	LineTotal int    `json:"line_total" db:"-"`
+	TaxRate   int    `json:"tax_rate" db:"-"`
+	Tax       int    `json:"tax" db:"-"`
+
+	StockStatus string `json:"stock_status" db:"-"`
+	Reserved    bool   `json:"reserved" db:"-"`
+
+	// Weight and volume are of the whole line, digital products are not
+	// shippable.
+	Weight    int  `json:"weight" db:"-"`
+	Volume    int  `json:"-" db:"-"`
+	Shippable bool `json:"-" db:"-"`
+
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// CartOwner identifies owner of a cart, either a user or a guest by
+// the hashed cart token.
+type CartOwner struct {
+	UserID int
+	Token  []byte
+}
+
+// CartTotals represents computed totals of a cart, tax is added to grand
+// total unless it is included in prices. Shipping is added once quoted.
+type CartTotals struct {
+	Subtotal     int  `json:"subtotal"`
+	Discount     int  `json:"discount"`
+	Tax          int  `json:"tax"`
+	TaxInclusive bool `json:"tax_inclusive"`
+	Shipping     int  `json:"shipping"`
+	GrandTotal   int  `json:"grand_total"`
+}
+
+// CartItemCreate represents cart items model for POST requests.
+type CartItemCreate struct {
 	ProductID int `json:"product_id"`
 	Quantity  int `json:"quantity"`
 }
 
-// CartUpdate represents carts model for PATCH requests.
-type CartUpdate struct {
-	ProductID *int `json:"product_id"`
-	Quantity  *int `json:"quantity"`
+// CartItemUpdate represents cart items model for PATCH requests.
+type CartItemUpdate struct {
+	Quantity int `json:"quantity"`
 }
 
 // CartFilter represents filters passed to List.
@@ -57,54 +139,179 @@ type CartFilter struct {
 
 // CartService represents a service for managing carts.
 type CartService interface {
-	GetByUser(ctx context.Context, userID int) ([]Cart, error)
 	GetByID(ctx context.Context, ID int) (Cart, error)
+	GetByOwner(ctx context.Context, owner CartOwner) (Cart, error)
 	List(ctx context.Context, filter CartFilter) ([]Cart, error)
-	Create(ctx context.Context, cart *Cart) error
-	Update(ctx context.Context, ID int, cart CartUpdate) (Cart, error)
-	Delete(ctx context.Context, userID int) error
+	Delete(ctx context.Context, ID int) error
+
+	AddItem(ctx context.Context, owner CartOwner, item CartItem) (Cart, error)
+	UpdateItem(ctx context.Context, owner CartOwner, itemID int, quantity int) (Cart, error)
+	RemoveItem(ctx context.Context, owner CartOwner, itemID int) (Cart, error)
+	Clear(ctx context.Context, owner CartOwner) error
+
+	// Merge moves items of guest cart identified by hashed token into
+	// user's cart and deletes the guest cart.
+	Merge(ctx context.Context, userID int, token []byte, strategy string) (Cart, error)
+
+	// ApplyCoupon applies a coupon code to owner's cart, it is evaluated
+	// along with other coupons of cart whenever the cart is loaded.
+	ApplyCoupon(ctx context.Context, owner CartOwner, code string) (Cart, error)
+	RemoveCoupon(ctx context.Context, owner CartOwner, code string) (Cart, error)
+
+	// SetDestination sets location owner's cart is delivered to.
+	SetDestination(ctx context.Context, owner CartOwner, destination Location) (Cart, error)
+
+	// SetShippingMethod selects shipping method of owner's cart.
+	SetShippingMethod(ctx context.Context, owner CartOwner, methodID int) (Cart, error)
+
+	// DeleteStaleGuests deletes guest carts not updated since before and
+	// returns number of them.
+	DeleteStaleGuests(ctx context.Context, before time.Time) (int, error)
+	// ReleaseExpiredReservations deletes expired stock reservations of
+	// cart items and returns number of them.
+	ReleaseExpiredReservations(ctx context.Context) (int, error)
+}
+
+// IsGuest reports whether owner is a guest.
+func (o CartOwner) IsGuest() bool {
+	return o.UserID == 0
+}
+
+// GenerateCartToken returns a plain cart token for guests along with
+// its hashed form.
+func GenerateCartToken() (string, []byte, error) {
+	token, err := GenerateToken(0, 16, GuestCartExpiry)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token.Plain, token.Hashed, nil
 }
 
-// Validate validates update products.
-func (c CartCreate) Validate() error {
+// MergeCartItems merges guest items into user items by product according
+// to strategy, falling back to CartMergeSum on unknown strategies. limits
+// holds orderable quantities of products for CartMergeCap, capped lines
+// keep at least quantity of user's line and products missing from limits
+// are not capped.
+func MergeCartItems(items []CartItem, guest []CartItem, strategy string, limits map[int]int) []CartItem {
+	merged := make(map[int]CartItem, len(items)+len(guest))
+	for _, item := range items {
+		merged[item.ProductID] = item
+	}
+
+	for _, g := range guest {
+		item, ok := merged[g.ProductID]
+		if !ok {
+			merged[g.ProductID] = CartItem{ProductID: g.ProductID, Quantity: g.Quantity, UpdatedAt: g.UpdatedAt}
+			continue
+		}
+
+		switch strategy {
+		case CartMergeNewest:
+			if g.UpdatedAt.After(item.UpdatedAt) {
+				item.Quantity = g.Quantity
+				item.UpdatedAt = g.UpdatedAt
+			}
+		case CartMergeCap:
+			quantity := item.Quantity + g.Quantity
+			if limit, ok := limits[g.ProductID]; ok && quantity > limit {
+				quantity = limit
+				if quantity < item.Quantity {
+					quantity = item.Quantity
+				}
+			}
+			item.Quantity = quantity
+		default:
+			item.Quantity += g.Quantity
+		}
+
+		merged[g.ProductID] = item
+	}
+
+	result := make([]CartItem, 0, len(merged))
+	for _, item := range merged {
+		result = append(result, item)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ProductID < result[j].ProductID
+	})
+
+	return result
+}
+
+// Validate validates POST requests model.
+func (c CartItemCreate) Validate() error {
 	switch {
-	case c.UserID == 0:
-		return errUserIDRequired
 	case c.ProductID == 0:
 		return errProductIDRequired
-	case c.Quantity == 0:
+	case c.Quantity <= 0:
 		return errQuantityRequired
 	}
 	return nil
 }
 
 // CreateModel set input values to a new struct and return a new instance.
-func (c CartCreate) CreateModel() Cart {
-	return Cart{
+func (c CartItemCreate) CreateModel() CartItem {
+	return CartItem{
 		ProductID: c.ProductID,
 		Quantity:  c.Quantity,
-		UserID:    c.UserID,
 	}
 }
 
-// Validate validates update products.
-func (c CartUpdate) Validate() error {
-	switch {
-	case c.ProductID != nil && *c.ProductID == 0:
-		return errProductIDRequired
-	case c.Quantity != nil && *c.Quantity == 0:
+// Validate validates PATCH requests model.
+func (c CartItemUpdate) Validate() error {
+	if c.Quantity <= 0 {
 		return errQuantityRequired
 	}
 	return nil
 }
 
-// UpdateModel checks whether carts input are not nil and set values.
-func (c CartUpdate) UpdateModel(cart *Cart) {
-	if c.ProductID != nil {
-		cart.ProductID = *c.ProductID
+// ApplyProducts sets names, unit prices and stock states of items from
+// products and computes totals of cart.
+func (c *Cart) ApplyProducts(products map[int]Product) {
+	for i := range c.Items {
+		item := &c.Items[i]
+		product := products[item.ProductID]
+		item.Name = product.Name
+		item.UnitPrice = product.Price
+		item.Weight = product.Weight * item.Quantity
+		item.Volume = product.Volume() * item.Quantity
+		item.Shippable = product.Type != ProductTypeDigital
+
+		// a reserved line holds its quantity out of product availability.
+		available := product.Available
+		if item.Reserved {
+			available += item.Quantity
+		}
+
+		item.StockStatus = StockStatus(available)
+		if available < item.Quantity {
+			item.StockStatus = product.outOfStockStatus()
+		}
+	}
+
+	c.CalculateTotals()
+}
+
+// CalculateTotals computes line totals and totals of cart.
+func (c *Cart) CalculateTotals() {
+	c.Totals = CartTotals{TaxInclusive: c.Totals.TaxInclusive}
+
+	for i := range c.Items {
+		item := &c.Items[i]
+		item.LineTotal = item.UnitPrice*item.Quantity - item.Discount
+		c.Totals.Subtotal += item.UnitPrice * item.Quantity
+		c.Totals.Discount += item.Discount
+		c.Totals.Tax += item.Tax
+	}
+
+	if c.Shipping != nil {
+		c.Totals.Shipping = c.Shipping.Cost
 	}
 
-	if c.Quantity != nil {
-		cart.Quantity = *c.Quantity
+	c.Totals.GrandTotal = c.Totals.Subtotal - c.Totals.Discount + c.Totals.Shipping
+	if !c.Totals.TaxInclusive {
+		c.Totals.GrandTotal += c.Totals.Tax
 	}
 }
@@ -0,0 +1,77 @@
+package domain_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+func TestMergeCartItems(t *testing.T) {
+	older := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	items := []domain.CartItem{
+		{ID: 1, ProductID: 1, Quantity: 2, UpdatedAt: older},
+		{ID: 2, ProductID: 2, Quantity: 1, UpdatedAt: newer},
+		{ID: 3, ProductID: 4, Quantity: 3, UpdatedAt: older},
+	}
+	guest := []domain.CartItem{
+		{ID: 3, ProductID: 1, Quantity: 3, UpdatedAt: newer},
+		{ID: 4, ProductID: 2, Quantity: 5, UpdatedAt: older},
+		{ID: 5, ProductID: 3, Quantity: 1, UpdatedAt: older},
+		{ID: 6, ProductID: 4, Quantity: 2, UpdatedAt: older},
+	}
+	limits := map[int]int{1: 4, 2: 10, 3: 0, 4: 1}
+
+	tests := []struct {
+		strategy string
+		want     []int
+	}{
+		{domain.CartMergeSum, []int{5, 6, 1, 5}},
+		{"", []int{5, 6, 1, 5}},
+		{domain.CartMergeNewest, []int{3, 1, 1, 3}},
+		// only lines in both carts are capped, never below user's line.
+		{domain.CartMergeCap, []int{4, 6, 1, 3}},
+	}
+
+	for _, tt := range tests {
+		merged := domain.MergeCartItems(items, guest, tt.strategy, limits)
+
+		got := make([]int, 0, len(merged))
+		for _, item := range merged {
+			got = append(got, item.Quantity)
+		}
+
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: mismatch\n got: %v\nwant: %v", tt.strategy, got, tt.want)
+		}
+	}
+}
+
+func TestCartApplyProducts(t *testing.T) {
+	cart := domain.Cart{Items: []domain.CartItem{
+		{ProductID: 1, Quantity: 2, Reserved: true},
+		{ProductID: 2, Quantity: 3},
+		{ProductID: 3, Quantity: 1},
+	}}
+
+	products := map[int]domain.Product{
+		1: {ID: 1, Price: 10, Available: 0},
+		2: {ID: 2, Price: 5, Available: 2},
+		3: {ID: 3, Price: 7, Available: 100},
+	}
+
+	cart.ApplyProducts(products)
+
+	got := []string{cart.Items[0].StockStatus, cart.Items[1].StockStatus, cart.Items[2].StockStatus}
+	want := []string{domain.StockLow, domain.StockOutOfStock, domain.StockAvailable}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mismatch\n got: %v\nwant: %v", got, want)
+	}
+
+	if cart.Totals.Subtotal != 42 || cart.Totals.GrandTotal != 42 {
+		t.Errorf("expected subtotal and grand total of %d, got: %#v", 42, cart.Totals)
+	}
+}
@@ -36,6 +36,7 @@ type Category struct {
 	CreatedAt   time.Time `json:"-" db:"created_at"`
 	UpdatedAt   time.Time `json:"-" db:"updated_at"`
 	Version     int       `json:"version"`
+	Locale      string    `json:"locale" db:"-"`
 }
 
 // CategoryCreate represents categories model for POST requests.
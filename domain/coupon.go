@@ -0,0 +1,554 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoCouponsFound      = errors.New("no coupons found")
+	ErrDuplicatedCoupon    = errors.New("duplicated coupon code")
+	ErrInvalidCoupon       = errors.New("invalid coupon")
+	ErrCouponNotActive     = errors.New("coupon is not active")
+	ErrCouponUsageExceeded = errors.New("coupon usage limit reached")
+	ErrCouponNotApplicable = errors.New("coupon is not applicable to cart")
+	ErrCouponNotStackable  = errors.New("coupon can not be combined with other coupons")
+	ErrCouponRequiresUser  = errors.New("coupon requires a signed in user")
+
+	errCouponNameRequired  = errors.New("name is required")
+	errCouponCodeRequired  = errors.New("code is required")
+	errInvalidCouponType   = errors.New("invalid coupon type")
+	errCouponPercentage    = errors.New("value of percentage coupons must be between 1 and 100")
+	errCouponAmount        = errors.New("value of fixed coupons must be greater than zero")
+	errCouponBuyGet        = errors.New("buy_quantity and get_quantity must be greater than zero")
+	errCouponMinSubtotal   = errors.New("min_subtotal must not be negative")
+	errCouponUsageLimit    = errors.New("usage limits must not be negative")
+	errCouponValidity      = errors.New("ends_at must be after starts_at")
+	errCouponCodeAutomatic = errors.New("automatic promotions have no code")
+)
+
+// Coupon types.
+const (
+	// CouponPercentage takes value percent off eligible lines.
+	CouponPercentage = "percentage"
+	// CouponFixed takes value off eligible lines.
+	CouponFixed = "fixed"
+	// CouponFreeShipping waives shipping of cart.
+	CouponFreeShipping = "free_shipping"
+	// CouponBuyXGetY gives get_quantity cheapest units free for every
+	// buy_quantity units of eligible lines.
+	CouponBuyXGetY = "buy_x_get_y"
+)
+
+// Scopes of applied discounts, coupons restricted to products or
+// categories discount lines and the others discount the whole order.
+const (
+	DiscountScopeLine  = "line"
+	DiscountScopeOrder = "order"
+)
+
+// WrapCoupon wraps coupons for user representation.
+type WrapCoupon struct {
+	Coupon Coupon `json:"coupon"`
+}
+
+// WrapCouponList wraps list of coupons for user representation.
+type WrapCouponList struct {
+	Coupons []Coupon `json:"coupons"`
+}
+
+// Coupon represents coupons model, coupons without a code are promotions
+// applied automatically to eligible carts. Limits of zero are unlimited.
+// Used is the number of orders redeeming coupon and UsedByUser the number
+// of them placed by the user it is loaded for.
+type Coupon struct {
+	ID             int        `json:"id"`
+	Code           string     `json:"code"`
+	Name           string     `json:"name"`
+	Type           string     `json:"type"`
+	Value          int        `json:"value"`
+	MinSubtotal    int        `json:"min_subtotal" db:"min_subtotal"`
+	ProductIDs     []int      `json:"product_ids" db:"product_ids"`
+	CategoryIDs    []int      `json:"category_ids" db:"category_ids"`
+	BuyQuantity    int        `json:"buy_quantity" db:"buy_quantity"`
+	GetQuantity    int        `json:"get_quantity" db:"get_quantity"`
+	UsageLimit     int        `json:"usage_limit" db:"usage_limit"`
+	UserUsageLimit int        `json:"user_usage_limit" db:"user_usage_limit"`
+	Stackable      bool       `json:"stackable"`
+	Priority       int        `json:"priority"`
+	Active         bool       `json:"active"`
+	StartsAt       *time.Time `json:"starts_at" db:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at" db:"ends_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+
+	Used       int `json:"used"`
+	UsedByUser int `json:"-" db:"used_by_user"`
+}
+
+// CouponCreate represents coupons model for POST requests.
+type CouponCreate struct {
+	Code           string     `json:"code"`
+	Name           string     `json:"name"`
+	Type           string     `json:"type"`
+	Value          int        `json:"value"`
+	MinSubtotal    int        `json:"min_subtotal"`
+	ProductIDs     []int      `json:"product_ids"`
+	CategoryIDs    []int      `json:"category_ids"`
+	BuyQuantity    int        `json:"buy_quantity"`
+	GetQuantity    int        `json:"get_quantity"`
+	UsageLimit     int        `json:"usage_limit"`
+	UserUsageLimit int        `json:"user_usage_limit"`
+	Stackable      bool       `json:"stackable"`
+	Priority       int        `json:"priority"`
+	Automatic      bool       `json:"automatic"`
+	Active         *bool      `json:"active"`
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
+}
+
+// CouponUpdate represents coupons model for PATCH requests.
+type CouponUpdate struct {
+	Name           *string    `json:"name"`
+	Value          *int       `json:"value"`
+	MinSubtotal    *int       `json:"min_subtotal"`
+	ProductIDs     *[]int     `json:"product_ids"`
+	CategoryIDs    *[]int     `json:"category_ids"`
+	BuyQuantity    *int       `json:"buy_quantity"`
+	GetQuantity    *int       `json:"get_quantity"`
+	UsageLimit     *int       `json:"usage_limit"`
+	UserUsageLimit *int       `json:"user_usage_limit"`
+	Stackable      *bool      `json:"stackable"`
+	Priority       *int       `json:"priority"`
+	Active         *bool      `json:"active"`
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
+}
+
+// CouponApply represents coupons model for requests applying a code to
+// cart.
+type CouponApply struct {
+	Code string `json:"code"`
+}
+
+// CouponFilter represents filters passed to List.
+type CouponFilter struct {
+	ID   int    `json:"id"`
+	Code string `json:"code"`
+
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+	Sort   string `json:"sort"`
+}
+
+// AppliedDiscount represents discount of a coupon applied to cart.
+type AppliedDiscount struct {
+	CouponID     int    `json:"coupon_id"`
+	Code         string `json:"code,omitempty"`
+	Name         string `json:"name"`
+	Scope        string `json:"scope"`
+	Amount       int    `json:"amount"`
+	FreeShipping bool   `json:"free_shipping,omitempty"`
+}
+
+// CouponService represents a service for managing coupons.
+type CouponService interface {
+	Create(ctx context.Context, coupon *Coupon) error
+	GetByID(ctx context.Context, ID int) (Coupon, error)
+	Update(ctx context.Context, ID int, coupon CouponUpdate) (Coupon, error)
+	Delete(ctx context.Context, ID int) error
+	List(ctx context.Context, filter CouponFilter) ([]Coupon, error)
+}
+
+// NormalizeCouponCode returns code as stored, codes are case insensitive.
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate validates requests model applying a code to cart.
+func (c CouponApply) Validate() error {
+	if NormalizeCouponCode(c.Code) == "" {
+		return errCouponCodeRequired
+	}
+	return nil
+}
+
+// Validate validates POST requests model.
+func (c CouponCreate) Validate() error {
+	switch {
+	case c.Automatic && NormalizeCouponCode(c.Code) != "":
+		return errCouponCodeAutomatic
+	case !c.Automatic && NormalizeCouponCode(c.Code) == "":
+		return errCouponCodeRequired
+	}
+	return c.CreateModel().validate()
+}
+
+// CreateModel set input values to a new struct and return a new instance.
+func (c CouponCreate) CreateModel() Coupon {
+	coupon := Coupon{
+		Code:           NormalizeCouponCode(c.Code),
+		Name:           c.Name,
+		Type:           c.Type,
+		Value:          c.Value,
+		MinSubtotal:    c.MinSubtotal,
+		ProductIDs:     c.ProductIDs,
+		CategoryIDs:    c.CategoryIDs,
+		BuyQuantity:    c.BuyQuantity,
+		GetQuantity:    c.GetQuantity,
+		UsageLimit:     c.UsageLimit,
+		UserUsageLimit: c.UserUsageLimit,
+		Stackable:      c.Stackable,
+		Priority:       c.Priority,
+		Active:         c.Active == nil || *c.Active,
+		StartsAt:       c.StartsAt,
+		EndsAt:         c.EndsAt,
+	}
+
+	if coupon.ProductIDs == nil {
+		coupon.ProductIDs = []int{}
+	}
+
+	if coupon.CategoryIDs == nil {
+		coupon.CategoryIDs = []int{}
+	}
+
+	return coupon
+}
+
+// UpdateModel checks whether coupons input are not nil and set values, it
+// returns ErrInvalidCoupon when the updated coupon is not valid.
+func (c CouponUpdate) UpdateModel(coupon *Coupon) error {
+	if c.Name != nil {
+		coupon.Name = *c.Name
+	}
+
+	if c.Value != nil {
+		coupon.Value = *c.Value
+	}
+
+	if c.MinSubtotal != nil {
+		coupon.MinSubtotal = *c.MinSubtotal
+	}
+
+	if c.ProductIDs != nil {
+		coupon.ProductIDs = *c.ProductIDs
+	}
+
+	if c.CategoryIDs != nil {
+		coupon.CategoryIDs = *c.CategoryIDs
+	}
+
+	if c.BuyQuantity != nil {
+		coupon.BuyQuantity = *c.BuyQuantity
+	}
+
+	if c.GetQuantity != nil {
+		coupon.GetQuantity = *c.GetQuantity
+	}
+
+	if c.UsageLimit != nil {
+		coupon.UsageLimit = *c.UsageLimit
+	}
+
+	if c.UserUsageLimit != nil {
+		coupon.UserUsageLimit = *c.UserUsageLimit
+	}
+
+	if c.Stackable != nil {
+		coupon.Stackable = *c.Stackable
+	}
+
+	if c.Priority != nil {
+		coupon.Priority = *c.Priority
+	}
+
+	if c.Active != nil {
+		coupon.Active = *c.Active
+	}
+
+	if c.StartsAt != nil {
+		coupon.StartsAt = c.StartsAt
+	}
+
+	if c.EndsAt != nil {
+		coupon.EndsAt = c.EndsAt
+	}
+
+	if coupon.ProductIDs == nil {
+		coupon.ProductIDs = []int{}
+	}
+
+	if coupon.CategoryIDs == nil {
+		coupon.CategoryIDs = []int{}
+	}
+
+	err := coupon.validate()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCoupon, err)
+	}
+
+	return nil
+}
+
+// validate validates fields of coupon.
+func (c Coupon) validate() error {
+	if c.Name == "" {
+		return errCouponNameRequired
+	}
+
+	switch c.Type {
+	case CouponPercentage:
+		if c.Value <= 0 || c.Value > 100 {
+			return errCouponPercentage
+		}
+	case CouponFixed:
+		if c.Value <= 0 {
+			return errCouponAmount
+		}
+	case CouponBuyXGetY:
+		if c.BuyQuantity <= 0 || c.GetQuantity <= 0 {
+			return errCouponBuyGet
+		}
+	case CouponFreeShipping:
+	default:
+		return errInvalidCouponType
+	}
+
+	switch {
+	case c.MinSubtotal < 0:
+		return errCouponMinSubtotal
+	case c.UsageLimit < 0 || c.UserUsageLimit < 0:
+		return errCouponUsageLimit
+	case c.StartsAt != nil && c.EndsAt != nil && !c.EndsAt.After(*c.StartsAt):
+		return errCouponValidity
+	}
+
+	return nil
+}
+
+// IsAutomatic reports whether coupon is a promotion applied without a
+// code.
+func (c Coupon) IsAutomatic() bool {
+	return c.Code == ""
+}
+
+// Redeemable returns an error when coupon can not be redeemed by user at
+// now, guests are identified by zero userID.
+func (c Coupon) Redeemable(now time.Time, userID int) error {
+	switch {
+	case !c.Active:
+		return ErrCouponNotActive
+	case c.StartsAt != nil && now.Before(*c.StartsAt):
+		return ErrCouponNotActive
+	case c.EndsAt != nil && !now.Before(*c.EndsAt):
+		return ErrCouponNotActive
+	case c.UsageLimit > 0 && c.Used >= c.UsageLimit:
+		return ErrCouponUsageExceeded
+	case c.UserUsageLimit > 0 && userID == 0:
+		return ErrCouponRequiresUser
+	case c.UserUsageLimit > 0 && c.UsedByUser >= c.UserUsageLimit:
+		return ErrCouponUsageExceeded
+	}
+	return nil
+}
+
+// restricted reports whether coupon is restricted to some products or
+// categories.
+func (c Coupon) restricted() bool {
+	return len(c.ProductIDs) > 0 || len(c.CategoryIDs) > 0
+}
+
+// eligible reports whether a product is eligible for coupon.
+func (c Coupon) eligible(product Product) bool {
+	if !c.restricted() {
+		return true
+	}
+
+	for _, ID := range c.ProductIDs {
+		if ID == product.ID {
+			return true
+		}
+	}
+
+	for _, ID := range c.CategoryIDs {
+		if ID == product.CategoryID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scope returns scope of discounts given by coupon.
+func (c Coupon) scope() string {
+	if c.restricted() || c.Type == CouponBuyXGetY {
+		return DiscountScopeLine
+	}
+	return DiscountScopeOrder
+}
+
+// discounts returns discount of coupon on each line of items by index,
+// remaining is amount of each line not discounted yet.
+func (c Coupon) discounts(items []CartItem, remaining []int, products map[int]Product) []int {
+	amounts := make([]int, len(items))
+
+	eligible := make([]int, len(items))
+	total := 0
+	for i, item := range items {
+		if c.eligible(products[item.ProductID]) {
+			eligible[i] = remaining[i]
+			total += remaining[i]
+		}
+	}
+
+	switch c.Type {
+	case CouponPercentage:
+		return distribute(total*c.Value/100, eligible)
+	case CouponFixed:
+		amount := c.Value
+		if amount > total {
+			amount = total
+		}
+		return distribute(amount, eligible)
+	case CouponBuyXGetY:
+		type unit struct{ line, price int }
+
+		var units []unit
+		for i, item := range items {
+			if eligible[i] == 0 {
+				continue
+			}
+			for n := 0; n < item.Quantity; n++ {
+				units = append(units, unit{line: i, price: item.UnitPrice})
+			}
+		}
+
+		sort.SliceStable(units, func(i, j int) bool {
+			return units[i].price > units[j].price
+		})
+
+		free := len(units) / (c.BuyQuantity + c.GetQuantity) * c.GetQuantity
+		for _, u := range units[len(units)-free:] {
+			amounts[u.line] += u.price
+		}
+
+		for i := range amounts {
+			if amounts[i] > remaining[i] {
+				amounts[i] = remaining[i]
+			}
+		}
+	}
+
+	return amounts
+}
+
+// distribute splits amount over lines in proportion to weights, the
+// rounding remainder is given to lines in order one unit at a time.
+func distribute(amount int, weights []int) []int {
+	shares := make([]int, len(weights))
+
+	total := 0
+	for _, w := range weights {
+		total += w
+	}
+
+	if total == 0 || amount <= 0 {
+		return shares
+	}
+
+	left := amount
+	for i, w := range weights {
+		shares[i] = amount * w / total
+		left -= shares[i]
+	}
+
+	for i := 0; left > 0; i = (i + 1) % len(weights) {
+		if weights[i] > shares[i] {
+			shares[i]++
+			left--
+		}
+	}
+
+	return shares
+}
+
+// ApplyCoupons discounts cart by coupons redeemable at now, cart must have
+// its products applied. Coupons are evaluated by priority and then by id,
+// a coupon not stackable is only applied alone: it is skipped once another
+// coupon is applied and no other coupon is applied after it. Coupons not
+// redeemable, under their minimum subtotal or giving no discount are
+// skipped.
+func (c *Cart) ApplyCoupons(coupons []Coupon, products map[int]Product, now time.Time) {
+	sorted := make([]Coupon, len(coupons))
+	copy(sorted, coupons)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Priority != sorted[j].Priority {
+			return sorted[i].Priority > sorted[j].Priority
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	c.Discounts = []AppliedDiscount{}
+	c.FreeShipping = false
+	for i := range c.Items {
+		c.Items[i].Discount = 0
+	}
+	c.CalculateTotals()
+
+	remaining := make([]int, len(c.Items))
+	for i, item := range c.Items {
+		remaining[i] = item.LineTotal
+	}
+
+	exclusive := false
+	for _, coupon := range sorted {
+		if exclusive || (len(c.Discounts) > 0 && !coupon.Stackable) {
+			continue
+		}
+
+		if coupon.Redeemable(now, c.UserID) != nil || c.Totals.Subtotal < coupon.MinSubtotal {
+			continue
+		}
+
+		discount := AppliedDiscount{
+			CouponID: coupon.ID,
+			Code:     coupon.Code,
+			Name:     coupon.Name,
+			Scope:    coupon.scope(),
+		}
+
+		if coupon.Type == CouponFreeShipping {
+			eligible := false
+			for _, item := range c.Items {
+				eligible = eligible || coupon.eligible(products[item.ProductID])
+			}
+			if !eligible {
+				continue
+			}
+
+			discount.FreeShipping = true
+			c.FreeShipping = true
+		}
+
+		amounts := coupon.discounts(c.Items, remaining, products)
+		for i, amount := range amounts {
+			c.Items[i].Discount += amount
+			remaining[i] -= amount
+			discount.Amount += amount
+		}
+
+		if discount.Amount == 0 && !discount.FreeShipping {
+			continue
+		}
+
+		c.Discounts = append(c.Discounts, discount)
+		exclusive = !coupon.Stackable
+	}
+
+	c.CalculateTotals()
+}
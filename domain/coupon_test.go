@@ -0,0 +1,187 @@
+package domain_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+func couponTestCart() (domain.Cart, map[int]domain.Product) {
+	cart := domain.Cart{UserID: 1, Items: []domain.CartItem{
+		{ProductID: 1, Quantity: 2},
+		{ProductID: 2, Quantity: 1},
+		{ProductID: 3, Quantity: 3},
+	}}
+
+	products := map[int]domain.Product{
+		1: {ID: 1, CategoryID: 1, Price: 1000, Available: 100},
+		2: {ID: 2, CategoryID: 2, Price: 500, Available: 100},
+		3: {ID: 3, CategoryID: 2, Price: 200, Available: 100},
+	}
+
+	cart.ApplyProducts(products)
+	return cart, products
+}
+
+func TestCartApplyCoupons(t *testing.T) {
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+
+	percentage := domain.Coupon{ID: 1, Code: "TEN", Type: domain.CouponPercentage, Value: 10, Active: true}
+	fixed := domain.Coupon{ID: 2, Code: "CAT", Type: domain.CouponFixed, Value: 1000, CategoryIDs: []int{2}, Active: true}
+	buyGet := domain.Coupon{ID: 3, Type: domain.CouponBuyXGetY, BuyQuantity: 2, GetQuantity: 1, CategoryIDs: []int{2}, Active: true}
+	shipping := domain.Coupon{ID: 4, Code: "SHIP", Type: domain.CouponFreeShipping, Active: true}
+
+	tests := []struct {
+		name      string
+		coupons   []domain.Coupon
+		discounts []int
+		applied   []int
+	}{
+		{
+			name:      "percentage of order",
+			coupons:   []domain.Coupon{percentage},
+			discounts: []int{200, 50, 60},
+			applied:   []int{1},
+		},
+		{
+			name:      "fixed amount on category",
+			coupons:   []domain.Coupon{fixed},
+			discounts: []int{0, 455, 545},
+			applied:   []int{2},
+		},
+		{
+			name:      "fixed amount capped at lines",
+			coupons:   []domain.Coupon{{ID: 5, Type: domain.CouponFixed, Value: 5000, ProductIDs: []int{2}, Active: true}},
+			discounts: []int{0, 500, 0},
+			applied:   []int{5},
+		},
+		{
+			name:      "buy two get cheapest free",
+			coupons:   []domain.Coupon{buyGet},
+			discounts: []int{0, 0, 200},
+			applied:   []int{3},
+		},
+		{
+			name:      "under minimum subtotal",
+			coupons:   []domain.Coupon{{ID: 6, Type: domain.CouponFixed, Value: 100, MinSubtotal: 5000, Active: true}},
+			discounts: []int{0, 0, 0},
+			applied:   []int{},
+		},
+		{
+			name: "not redeemable",
+			coupons: []domain.Coupon{
+				{ID: 7, Type: domain.CouponFixed, Value: 100, EndsAt: &past, Active: true},
+				{ID: 8, Type: domain.CouponFixed, Value: 100, UsageLimit: 1, Used: 1, Active: true},
+				{ID: 9, Type: domain.CouponFixed, Value: 100, Active: false},
+			},
+			discounts: []int{0, 0, 0},
+			applied:   []int{},
+		},
+		{
+			name: "not stackable after higher priority",
+			coupons: []domain.Coupon{
+				percentage,
+				{ID: 10, Type: domain.CouponFixed, Value: 310, Stackable: true, Priority: 1, Active: true},
+			},
+			discounts: []int{200, 50, 60},
+			applied:   []int{10},
+		},
+		{
+			name: "nothing stacks on exclusive",
+			coupons: []domain.Coupon{
+				{ID: 11, Type: domain.CouponFixed, Value: 310, Stackable: true, Active: true},
+				{ID: 12, Type: domain.CouponPercentage, Value: 10, Priority: 1, Active: true},
+			},
+			discounts: []int{200, 50, 60},
+			applied:   []int{12},
+		},
+		{
+			name: "stacked on remaining amounts",
+			coupons: []domain.Coupon{
+				{ID: 13, Type: domain.CouponPercentage, Value: 50, Stackable: true, Active: true},
+				{ID: 14, Type: domain.CouponBuyXGetY, BuyQuantity: 2, GetQuantity: 1, ProductIDs: []int{3}, Stackable: true, Active: true},
+			},
+			discounts: []int{1000, 250, 500},
+			applied:   []int{13, 14},
+		},
+		{
+			name:      "free shipping",
+			coupons:   []domain.Coupon{shipping},
+			discounts: []int{0, 0, 0},
+			applied:   []int{4},
+		},
+	}
+
+	for _, tt := range tests {
+		cart, products := couponTestCart()
+
+		// evaluation does not depend on order of coupons.
+		reversed := make([]domain.Coupon, 0, len(tt.coupons))
+		for i := len(tt.coupons) - 1; i >= 0; i-- {
+			reversed = append(reversed, tt.coupons[i])
+		}
+
+		for _, coupons := range [][]domain.Coupon{tt.coupons, reversed} {
+			cart.ApplyCoupons(coupons, products, now)
+
+			discounts := make([]int, 0, len(cart.Items))
+			total := 0
+			for _, item := range cart.Items {
+				discounts = append(discounts, item.Discount)
+				total += item.Discount
+			}
+
+			applied := []int{}
+			for _, d := range cart.Discounts {
+				applied = append(applied, d.CouponID)
+			}
+
+			if !reflect.DeepEqual(discounts, tt.discounts) {
+				t.Errorf("%s: mismatch of discounts\n got: %v\nwant: %v", tt.name, discounts, tt.discounts)
+			}
+
+			if !reflect.DeepEqual(applied, tt.applied) {
+				t.Errorf("%s: mismatch of applied coupons\n got: %v\nwant: %v", tt.name, applied, tt.applied)
+			}
+
+			if cart.Totals.Discount != total || cart.Totals.GrandTotal != cart.Totals.Subtotal-total {
+				t.Errorf("%s: totals do not add up: %#v", tt.name, cart.Totals)
+			}
+		}
+	}
+
+	cart, products := couponTestCart()
+	cart.ApplyCoupons([]domain.Coupon{shipping}, products, now)
+	if !cart.FreeShipping {
+		t.Errorf("expected free shipping to be applied")
+	}
+}
+
+func TestCouponRedeemable(t *testing.T) {
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+
+	tests := []struct {
+		coupon domain.Coupon
+		userID int
+		want   error
+	}{
+		{domain.Coupon{Active: true}, 0, nil},
+		{domain.Coupon{Active: false}, 1, domain.ErrCouponNotActive},
+		{domain.Coupon{Active: true, StartsAt: &later}, 1, domain.ErrCouponNotActive},
+		{domain.Coupon{Active: true, EndsAt: &now}, 1, domain.ErrCouponNotActive},
+		{domain.Coupon{Active: true, UsageLimit: 2, Used: 2}, 1, domain.ErrCouponUsageExceeded},
+		{domain.Coupon{Active: true, UserUsageLimit: 1}, 0, domain.ErrCouponRequiresUser},
+		{domain.Coupon{Active: true, UserUsageLimit: 1, UsedByUser: 1}, 1, domain.ErrCouponUsageExceeded},
+		{domain.Coupon{Active: true, UserUsageLimit: 2, UsedByUser: 1}, 1, nil},
+	}
+
+	for i, tt := range tests {
+		if got := tt.coupon.Redeemable(now, tt.userID); got != tt.want {
+			t.Errorf("%d: expected %v, got %v", i, tt.want, got)
+		}
+	}
+}
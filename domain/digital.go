@@ -0,0 +1,189 @@
+package domain
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var (
+	ErrNotDigital           = errors.New("product is not digital")
+	ErrNoFilesFound         = errors.New("no files found")
+	ErrNoGrantsFound        = errors.New("no download grants found")
+	ErrNoLicenseKeys        = errors.New("not enough license keys available")
+	ErrDuplicatedLicenseKey = errors.New("duplicated license key")
+	ErrDownloadLimitReached = errors.New("download limit reached")
+	ErrInvalidDownloadLink  = errors.New("invalid download link")
+	ErrDownloadLinkExpired  = errors.New("download link expired")
+
+	errLicenseKeysRequired = errors.New("keys are required")
+	errLicenseKeyEmpty     = errors.New("license key must not be empty")
+)
+
+const (
+	// DefaultMaxDownloads is the number of downloads allowed per grant.
+	DefaultMaxDownloads = 5
+
+	// DownloadLinkExpiry is how long a signed download link stays valid.
+	DownloadLinkExpiry = 15 * time.Minute
+)
+
+// BlobStore represents a storage for contents of digital product files.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// WrapProductFile wraps product files for user representation.
+type WrapProductFile struct {
+	File ProductFile `json:"file"`
+}
+
+// WrapProductFileList wraps list of product files for user representation.
+type WrapProductFileList struct {
+	Files []ProductFile `json:"files"`
+}
+
+// WrapDownloadGrant wraps download grants for user representation.
+type WrapDownloadGrant struct {
+	Grant DownloadGrant `json:"grant"`
+}
+
+// WrapDownloadGrantList wraps list of download grants for user representation.
+type WrapDownloadGrantList struct {
+	Grants []DownloadGrant `json:"grants"`
+}
+
+// WrapDownloadLink wraps download links for user representation.
+type WrapDownloadLink struct {
+	Link DownloadLink `json:"link"`
+}
+
+// ProductFile represents files attached to digital products.
+type ProductFile struct {
+	ID          int       `json:"id"`
+	ProductID   int       `json:"product_id" db:"product_id"`
+	Name        string    `json:"name"`
+	BlobKey     string    `json:"-" db:"blob_key"`
+	ContentType string    `json:"content_type" db:"content_type"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// DownloadGrant represents the right of a user to download files of a
+// purchased digital product.
+type DownloadGrant struct {
+	ID           int           `json:"id"`
+	UserID       int           `json:"user_id" db:"user_id"`
+	OrderID      *int          `json:"order_id" db:"order_id"`
+	ProductID    int           `json:"product_id" db:"product_id"`
+	Quantity     int           `json:"quantity"`
+	Downloads    int           `json:"downloads"`
+	MaxDownloads int           `json:"max_downloads" db:"max_downloads"`
+	CreatedAt    time.Time     `json:"created_at" db:"created_at"`
+	LicenseKeys  []string      `json:"license_keys,omitempty" db:"-"`
+	Files        []ProductFile `json:"files,omitempty" db:"-"`
+}
+
+// GrantCreate represents download grants model for POST requests.
+type GrantCreate struct {
+	UserID   int `json:"user_id"`
+	Quantity int `json:"quantity"`
+}
+
+// LicenseKeysCreate represents license keys model for POST requests.
+type LicenseKeysCreate struct {
+	Keys []string `json:"keys"`
+}
+
+// LicenseKeysResult represents result of adding license keys to a pool.
+type LicenseKeysResult struct {
+	Added     int `json:"added"`
+	Available int `json:"available"`
+}
+
+// DownloadLink represents a signed, time limited download url.
+type DownloadLink struct {
+	URL    string    `json:"url"`
+	Expiry time.Time `json:"expiry"`
+}
+
+// DigitalService represents a service for managing digital products.
+type DigitalService interface {
+	CreateFile(ctx context.Context, file *ProductFile) error
+	GetFile(ctx context.Context, ID int) (ProductFile, error)
+	ListFiles(ctx context.Context, productID int) ([]ProductFile, error)
+	DeleteFile(ctx context.Context, ID int) (ProductFile, error)
+
+	AddLicenseKeys(ctx context.Context, productID int, keys []string) (LicenseKeysResult, error)
+
+	Grant(ctx context.Context, grant *DownloadGrant) error
+	GetGrant(ctx context.Context, ID int) (DownloadGrant, error)
+	ListGrants(ctx context.Context, userID int) ([]DownloadGrant, error)
+	UseDownload(ctx context.Context, grantID int) error
+}
+
+// Validate validates POST requests model.
+func (g GrantCreate) Validate() error {
+	switch {
+	case g.UserID == 0:
+		return errUserIDRequired
+	case g.Quantity <= 0:
+		return errQuantityRequired
+	}
+	return nil
+}
+
+// CreateModel set input values to a new struct and return a new instance.
+func (g GrantCreate) CreateModel(productID int) DownloadGrant {
+	return DownloadGrant{
+		UserID:       g.UserID,
+		ProductID:    productID,
+		Quantity:     g.Quantity,
+		MaxDownloads: DefaultMaxDownloads * g.Quantity,
+	}
+}
+
+// Validate validates POST requests model.
+func (l LicenseKeysCreate) Validate() error {
+	if len(l.Keys) == 0 {
+		return errLicenseKeysRequired
+	}
+
+	for _, key := range l.Keys {
+		if key == "" {
+			return errLicenseKeyEmpty
+		}
+	}
+
+	return nil
+}
+
+// SignDownload returns signature of a download link of file for a grant
+// owned by userID.
+func SignDownload(secret []byte, grant DownloadGrant, fileID int, expiry time.Time) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%d:%d:%d:%d", grant.ID, grant.UserID, fileID, expiry.Unix())
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyDownload checks signature and expiry of a download link, all
+// links are rejected without a secret.
+func VerifyDownload(secret []byte, grant DownloadGrant, fileID int, expiry time.Time, signature string) error {
+	expected := SignDownload(secret, grant, fileID, expiry)
+	if len(secret) == 0 || !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidDownloadLink
+	}
+
+	if time.Now().After(expiry) {
+		return ErrDownloadLinkExpired
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+func TestVerifyDownload(t *testing.T) {
+	secret := []byte("secret")
+	grant := domain.DownloadGrant{ID: 1, UserID: 2}
+	expiry := time.Now().Add(time.Minute).Truncate(time.Second)
+	signature := domain.SignDownload(secret, grant, 3, expiry)
+
+	err := domain.VerifyDownload(secret, grant, 3, expiry, signature)
+	if err != nil {
+		t.Errorf("expected valid link, got: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		secret []byte
+		grant  domain.DownloadGrant
+		fileID int
+		expiry time.Time
+	}{
+		{"other secret", []byte("other"), grant, 3, expiry},
+		{"other grant", secret, domain.DownloadGrant{ID: 2, UserID: 2}, 3, expiry},
+		{"other user", secret, domain.DownloadGrant{ID: 1, UserID: 3}, 3, expiry},
+		{"other file", secret, grant, 4, expiry},
+		{"other expiry", secret, grant, 3, expiry.Add(time.Hour)},
+	}
+
+	for _, test := range tests {
+		err = domain.VerifyDownload(test.secret, test.grant, test.fileID, test.expiry, signature)
+		if err != domain.ErrInvalidDownloadLink {
+			t.Errorf("%s: expected ErrInvalidDownloadLink, got: %v", test.name, err)
+		}
+	}
+
+	expired := time.Now().Add(-time.Minute).Truncate(time.Second)
+	err = domain.VerifyDownload(secret, grant, 3, expired, domain.SignDownload(secret, grant, 3, expired))
+	if err != domain.ErrDownloadLinkExpired {
+		t.Errorf("expected ErrDownloadLinkExpired, got: %v", err)
+	}
+}
+
+func TestVerifyDownload_EmptySecret(t *testing.T) {
+	grant := domain.DownloadGrant{ID: 1, UserID: 2}
+	expiry := time.Now().Add(time.Minute)
+
+	err := domain.VerifyDownload(nil, grant, 3, expiry, domain.SignDownload(nil, grant, 3, expiry))
+	if err != domain.ErrInvalidDownloadLink {
+		t.Errorf("expected links to be rejected without a secret, got: %v", err)
+	}
+}
+
+func TestGrantCreate(t *testing.T) {
+	if err := (domain.GrantCreate{Quantity: 1}).Validate(); err == nil {
+		t.Errorf("expected grant without user to be invalid")
+	}
+
+	if err := (domain.GrantCreate{UserID: 1}).Validate(); err == nil {
+		t.Errorf("expected grant without quantity to be invalid")
+	}
+
+	grant := domain.GrantCreate{UserID: 1, Quantity: 2}.CreateModel(3)
+	if grant.ProductID != 3 || grant.MaxDownloads != 2*domain.DefaultMaxDownloads {
+		t.Errorf("expected downloads allowed per unit, got: %+v", grant)
+	}
+}
@@ -0,0 +1,346 @@
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoGiftCardsFound     = errors.New("no gift cards found")
+	ErrDuplicatedGiftCard   = errors.New("duplicated gift card code")
+	ErrGiftCardVoided       = errors.New("gift card is voided")
+	ErrGiftCardExpired      = errors.New("gift card is expired")
+	ErrGiftCardCurrency     = errors.New("gift card currency does not match order")
+	ErrGiftCardBalance      = errors.New("adjustment exceeds gift card balance")
+	ErrStoreCreditBalance   = errors.New("adjustment exceeds store credit balance")
+	ErrNoStoreCreditPayment = errors.New("payment can not be refunded as store credit")
+
+	errGiftCardCode     = errors.New("code must be 8 to 32 letters or digits")
+	errGiftCardBalance  = errors.New("initial_balance must be greater than zero")
+	errGiftCardCurrency = errors.New("currency must be a 3 letter code")
+	errGiftCardExpiry   = errors.New("expires_at must be in the future")
+	errAdjustmentAmount = errors.New("amount must not be zero")
+)
+
+// Providers of payments made with stored value, they are captured at
+// checkout without a payment gateway.
+const (
+	PaymentProviderGiftCard    = "gift_card"
+	PaymentProviderStoreCredit = "store_credit"
+)
+
+// Kinds of gift card and store credit transactions, refunds are only
+// credited to store credit.
+const (
+	CreditIssue   = "issue"
+	CreditRedeem  = "redeem"
+	CreditRestore = "restore"
+	CreditAdjust  = "adjust"
+	CreditVoid    = "void"
+	CreditRefund  = "refund"
+)
+
+// giftCardCodeAlphabet holds characters of generated codes, characters
+// easily mistaken for others are left out.
+const giftCardCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// giftCardCodeLength is length of generated codes.
+const giftCardCodeLength = 16
+
+// WrapGiftCard wraps gift cards for user representation.
+type WrapGiftCard struct {
+	GiftCard GiftCard `json:"gift_card"`
+}
+
+// WrapGiftCardList wraps list of gift cards for user representation.
+type WrapGiftCardList struct {
+	GiftCards []GiftCard `json:"gift_cards"`
+}
+
+// WrapGiftCardBalance wraps gift card balances for public representation.
+type WrapGiftCardBalance struct {
+	GiftCard GiftCardBalance `json:"gift_card"`
+}
+
+// WrapStoreCredit wraps store credits for user representation.
+type WrapStoreCredit struct {
+	StoreCredit StoreCredit `json:"store_credit"`
+}
+
+// GiftCard represents gift cards model, amounts are in minor unit of
+// currency. Balance is what is left of initial balance after redemptions
+// and adjustments.
+type GiftCard struct {
+	ID             int        `json:"id"`
+	Code           string     `json:"code"`
+	InitialBalance int        `json:"initial_balance" db:"initial_balance"`
+	Balance        int        `json:"balance"`
+	Currency       string     `json:"currency"`
+	Note           string     `json:"note"`
+	ExpiresAt      *time.Time `json:"expires_at" db:"expires_at"`
+	VoidedAt       *time.Time `json:"voided_at" db:"voided_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+
+	Transactions []CreditTransaction `json:"transactions,omitempty" db:"-"`
+}
+
+// GiftCardBalance represents what holders of a gift card code see of it.
+type GiftCardBalance struct {
+	Balance   int        `json:"balance"`
+	Currency  string     `json:"currency"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Voided    bool       `json:"voided"`
+}
+
+// StoreCredit represents store credit of a user, transactions are listed
+// from newest.
+type StoreCredit struct {
+	UserID       int                 `json:"user_id"`
+	Balance      int                 `json:"balance"`
+	Currency     string              `json:"currency"`
+	Transactions []CreditTransaction `json:"transactions"`
+}
+
+// CreditTransaction represents a transaction of gift card or store credit
+// ledgers, amount is signed and balance is what is left after it.
+type CreditTransaction struct {
+	ID        int       `json:"id"`
+	OrderID   *int      `json:"order_id" db:"order_id"`
+	Kind      string    `json:"kind"`
+	Amount    int       `json:"amount"`
+	Balance   int       `json:"balance"`
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// GiftCardCreate represents gift cards model for POST requests, a code is
+// generated unless given and currency defaults to payments currency.
+type GiftCardCreate struct {
+	Code           string     `json:"code"`
+	InitialBalance int        `json:"initial_balance"`
+	Currency       string     `json:"currency"`
+	Note           string     `json:"note"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+}
+
+// CreditAdjustment represents adjustment requests model of gift cards and
+// store credit, negative amounts take balance away.
+type CreditAdjustment struct {
+	Amount int    `json:"amount"`
+	Note   string `json:"note"`
+}
+
+// GiftCardVoid represents gift cards model for void requests.
+type GiftCardVoid struct {
+	Note string `json:"note"`
+}
+
+// GiftCardFilter represents filters passed to List.
+type GiftCardFilter struct {
+	ID   int    `json:"id"`
+	Code string `json:"code"`
+
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+	Sort   string `json:"sort"`
+}
+
+// GiftCardService represents a service for managing gift cards.
+type GiftCardService interface {
+	// Create issues a gift card with its initial balance.
+	Create(ctx context.Context, card *GiftCard) error
+	// GetByID get gift card along with its transactions.
+	GetByID(ctx context.Context, ID int) (GiftCard, error)
+	List(ctx context.Context, filter GiftCardFilter) ([]GiftCard, error)
+
+	// Adjust adds amount to balance of gift card, ErrGiftCardBalance is
+	// returned when balance would go negative.
+	Adjust(ctx context.Context, ID int, adjustment CreditAdjustment) (GiftCard, error)
+	// Void takes all balance of gift card away, voided cards can not be
+	// redeemed.
+	Void(ctx context.Context, ID int, note string) (GiftCard, error)
+}
+
+// StoreCreditService represents a service for managing store credit of
+// users.
+type StoreCreditService interface {
+	// Get returns balance of user along with its transactions.
+	Get(ctx context.Context, userID int) (StoreCredit, error)
+	// Adjust adds amount to store credit of user, ErrStoreCreditBalance is
+	// returned when balance would go negative.
+	Adjust(ctx context.Context, userID int, adjustment CreditAdjustment) (StoreCredit, error)
+	// Refund refunds amount of a captured payment as store credit of user
+	// of its order instead of through payment gateway.
+	Refund(ctx context.Context, paymentID int, amount int, note string) (Payment, error)
+}
+
+// NormalizeGiftCardCode returns code as stored, codes are case insensitive
+// and may be written in groups.
+func NormalizeGiftCardCode(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// GenerateGiftCardCode returns a new random gift card code.
+func GenerateGiftCardCode() (string, error) {
+	size := big.NewInt(int64(len(giftCardCodeAlphabet)))
+
+	var code strings.Builder
+	for i := 0; i < giftCardCodeLength; i++ {
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", err
+		}
+		code.WriteByte(giftCardCodeAlphabet[n.Int64()])
+	}
+
+	return code.String(), nil
+}
+
+// Validate validates POST requests model.
+func (g GiftCardCreate) Validate() error {
+	code := NormalizeGiftCardCode(g.Code)
+	switch {
+	case code != "" && !validGiftCardCode(code):
+		return errGiftCardCode
+	case g.InitialBalance <= 0:
+		return errGiftCardBalance
+	case g.Currency != "" && len(g.Currency) != 3:
+		return errGiftCardCurrency
+	case g.ExpiresAt != nil && !g.ExpiresAt.After(time.Now()):
+		return errGiftCardExpiry
+	}
+	return nil
+}
+
+// CreateModel set input values to a new struct and return a new instance,
+// code is used when none is given.
+func (g GiftCardCreate) CreateModel(code string) GiftCard {
+	card := GiftCard{
+		Code:           NormalizeGiftCardCode(g.Code),
+		InitialBalance: g.InitialBalance,
+		Balance:        g.InitialBalance,
+		Currency:       strings.ToLower(g.Currency),
+		Note:           g.Note,
+		ExpiresAt:      g.ExpiresAt,
+	}
+
+	if card.Code == "" {
+		card.Code = code
+	}
+
+	if card.Currency == "" {
+		card.Currency = PaymentCurrency
+	}
+
+	return card
+}
+
+// Validate validates adjustment requests model.
+func (c CreditAdjustment) Validate() error {
+	if c.Amount == 0 {
+		return errAdjustmentAmount
+	}
+	return nil
+}
+
+// validGiftCardCode reports whether a normalized code is made of 8 to 32
+// letters or digits.
+func validGiftCardCode(code string) bool {
+	if len(code) < 8 || len(code) > 32 {
+		return false
+	}
+
+	for _, c := range code {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Redeemable returns an error when gift card can not pay for an order in
+// currency at now.
+func (g GiftCard) Redeemable(now time.Time, currency string) error {
+	switch {
+	case g.VoidedAt != nil:
+		return ErrGiftCardVoided
+	case g.ExpiresAt != nil && !now.Before(*g.ExpiresAt):
+		return ErrGiftCardExpired
+	case g.Currency != currency:
+		return ErrGiftCardCurrency
+	}
+	return nil
+}
+
+// BalanceView returns gift card as it is shown to holders of its code.
+func (g GiftCard) BalanceView() GiftCardBalance {
+	return GiftCardBalance{
+		Balance:   g.Balance,
+		Currency:  g.Currency,
+		ExpiresAt: g.ExpiresAt,
+		Voided:    g.VoidedAt != nil,
+	}
+}
+
+// StoredValue reports whether payment is made with a gift card or store
+// credit.
+func (p Payment) StoredValue() bool {
+	return p.Provider == PaymentProviderGiftCard || p.Provider == PaymentProviderStoreCredit
+}
+
+// NewStoredValuePayment returns a captured payment of amount taken from a
+// gift card or store credit for order, reference tells it apart from
+// other payments of provider.
+func NewStoredValuePayment(orderID int, provider string, reference string, amount int) Payment {
+	return Payment{
+		OrderID:  orderID,
+		Provider: provider,
+		IntentID: reference,
+		Status:   PaymentStatusSucceeded,
+		Amount:   amount,
+		Captured: amount,
+		Currency: PaymentCurrency,
+	}
+}
+
+// RefundToStoreCredit records refund of amount on payment as credited to
+// store credit.
+func (p *Payment) RefundToStoreCredit(amount int) error {
+	if p.Status != PaymentStatusSucceeded || p.Captured-p.Refunded < amount {
+		return ErrNoStoreCreditPayment
+	}
+
+	p.ApplyEvent(PaymentEvent{Type: PaymentEventRefunded, Amount: p.Refunded + amount})
+	return nil
+}
+
+// AmountDue returns what is left to pay of order after captured payments.
+func AmountDue(order Order, payments []Payment) int {
+	due := order.Total
+	for _, p := range payments {
+		if p.Status == PaymentStatusSucceeded {
+			due -= p.Captured
+		}
+	}
+
+	if due < 0 {
+		return 0
+	}
+	return due
+}
+
+// StoredValueAmount returns amount of due paid from balance, balance is
+// used in part when it is not enough.
+func StoredValueAmount(balance int, due int) int {
+	if balance < due {
+		return balance
+	}
+	return due
+}
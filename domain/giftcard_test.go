@@ -0,0 +1,139 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+func TestGiftCardCreateValidate(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name  string
+		input domain.GiftCardCreate
+		valid bool
+	}{
+		{"generated code", domain.GiftCardCreate{InitialBalance: 100}, true},
+		{"grouped code", domain.GiftCardCreate{Code: "abcd-efgh-2345", InitialBalance: 100}, true},
+		{"short code", domain.GiftCardCreate{Code: "abc", InitialBalance: 100}, false},
+		{"symbol in code", domain.GiftCardCreate{Code: "ABCD_EFGH", InitialBalance: 100}, false},
+		{"no balance", domain.GiftCardCreate{}, false},
+		{"long currency", domain.GiftCardCreate{InitialBalance: 100, Currency: "euro"}, false},
+		{"expired", domain.GiftCardCreate{InitialBalance: 100, ExpiresAt: &past}, false},
+	}
+
+	for _, test := range tests {
+		err := test.input.Validate()
+		if (err == nil) != test.valid {
+			t.Errorf("%s: expected valid %v, got: %v", test.name, test.valid, err)
+		}
+	}
+}
+
+func TestGiftCardCreateModel(t *testing.T) {
+	card := domain.GiftCardCreate{Code: "abcd-efgh 2345", InitialBalance: 100, Currency: "EUR"}.CreateModel("GENERATED")
+	if card.Code != "ABCDEFGH2345" || card.Currency != "eur" || card.Balance != 100 {
+		t.Errorf("expected normalized code and currency, got: %+v", card)
+	}
+
+	card = domain.GiftCardCreate{InitialBalance: 100}.CreateModel("GENERATED")
+	if card.Code != "GENERATED" || card.Currency != domain.PaymentCurrency {
+		t.Errorf("expected generated code and payment currency, got: %+v", card)
+	}
+}
+
+func TestGenerateGiftCardCode(t *testing.T) {
+	code, err := domain.GenerateGiftCardCode()
+	if err != nil {
+		t.Fatalf("GenerateGiftCardCode: %v", err)
+	}
+
+	if len(code) != 16 || domain.NormalizeGiftCardCode(code) != code {
+		t.Errorf("expected 16 characters of normalized code, got: %q", code)
+	}
+
+	err = domain.GiftCardCreate{Code: code, InitialBalance: 1}.Validate()
+	if err != nil {
+		t.Errorf("expected generated code to be valid, got: %v", err)
+	}
+}
+
+func TestGiftCardRedeemable(t *testing.T) {
+	now := time.Now()
+	expiry := now.Add(time.Hour)
+
+	card := domain.GiftCard{Balance: 100, Currency: domain.PaymentCurrency, ExpiresAt: &expiry}
+	if err := card.Redeemable(now, domain.PaymentCurrency); err != nil {
+		t.Errorf("expected redeemable card, got: %v", err)
+	}
+
+	if err := card.Redeemable(expiry, domain.PaymentCurrency); err != domain.ErrGiftCardExpired {
+		t.Errorf("expected ErrGiftCardExpired, got: %v", err)
+	}
+
+	if err := card.Redeemable(now, "eur"); err != domain.ErrGiftCardCurrency {
+		t.Errorf("expected ErrGiftCardCurrency, got: %v", err)
+	}
+
+	card.VoidedAt = &now
+	if err := card.Redeemable(now, domain.PaymentCurrency); err != domain.ErrGiftCardVoided {
+		t.Errorf("expected ErrGiftCardVoided, got: %v", err)
+	}
+}
+
+func TestAmountDue(t *testing.T) {
+	order := domain.Order{Total: 100}
+	payments := []domain.Payment{
+		domain.NewStoredValuePayment(1, domain.PaymentProviderGiftCard, "card", 30),
+		{Status: domain.PaymentStatusFailed, Captured: 70},
+	}
+
+	if due := domain.AmountDue(order, payments); due != 70 {
+		t.Errorf("expected %d due, got: %d", 70, due)
+	}
+
+	payments = append(payments, domain.NewStoredValuePayment(1, domain.PaymentProviderStoreCredit, "credit", 90))
+	if due := domain.AmountDue(order, payments); due != 0 {
+		t.Errorf("expected nothing due, got: %d", due)
+	}
+
+	if amount := domain.StoredValueAmount(30, 100); amount != 30 {
+		t.Errorf("expected partial use of balance, got: %d", amount)
+	}
+
+	if amount := domain.StoredValueAmount(300, 100); amount != 100 {
+		t.Errorf("expected amount due, got: %d", amount)
+	}
+}
+
+func TestPaymentRefundToStoreCredit(t *testing.T) {
+	payment := domain.NewStoredValuePayment(1, domain.PaymentProviderGiftCard, "card", 100)
+	if !payment.StoredValue() {
+		t.Errorf("expected stored value payment")
+	}
+
+	err := payment.RefundToStoreCredit(40)
+	if err != nil {
+		t.Fatalf("RefundToStoreCredit: %v", err)
+	}
+
+	if payment.Refunded != 40 || payment.Status != domain.PaymentStatusSucceeded {
+		t.Errorf("expected partial refund, got: %+v", payment)
+	}
+
+	err = payment.RefundToStoreCredit(70)
+	if err != domain.ErrNoStoreCreditPayment {
+		t.Errorf("expected ErrNoStoreCreditPayment, got: %v", err)
+	}
+
+	err = payment.RefundToStoreCredit(60)
+	if err != nil {
+		t.Fatalf("RefundToStoreCredit: %v", err)
+	}
+
+	if payment.Status != domain.PaymentStatusRefunded || payment.OrderStatus() != domain.OrderStatusRefunded {
+		t.Errorf("expected refunded payment, got: %+v", payment)
+	}
+}
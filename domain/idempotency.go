@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+var (
+	ErrIdempotencyKeyInFlight = errors.New("a request with this idempotency key is in progress")
+	ErrIdempotencyKeyReused   = errors.New("idempotency key was used with a different request")
+	ErrInvalidIdempotencyKey  = errors.New("idempotency key must be 1 to 255 characters")
+)
+
+const (
+	// IdempotencyExpiry is how long responses of idempotency keys are kept.
+	IdempotencyExpiry = 24 * time.Hour
+
+	// IdempotencyLockTimeout is how long a key stays claimed by a request
+	// that has not completed, claims of crashed requests are taken over
+	// afterwards.
+	IdempotencyLockTimeout = time.Minute
+
+	maxIdempotencyKeyLength = 255
+)
+
+// IdempotentResponse represents a stored response replayed for retries.
+type IdempotentResponse struct {
+	StatusCode int
+	Header     map[string][]string
+	Body       []byte
+}
+
+// IdempotencyService represents a store of responses by idempotency keys.
+type IdempotencyService interface {
+	// Begin claims key within scope for a request, a nil response means the
+	// request should be handled. The stored response is returned for
+	// retries, ErrIdempotencyKeyInFlight while the first request is not
+	// completed and ErrIdempotencyKeyReused when hash of request differs.
+	Begin(ctx context.Context, scope string, key string, requestHash string) (*IdempotentResponse, error)
+	// Complete stores response of a claimed key.
+	Complete(ctx context.Context, scope string, key string, response IdempotentResponse) error
+	// Release gives up a claimed key so it may be retried.
+	Release(ctx context.Context, scope string, key string) error
+	// DeleteExpired deletes keys older than IdempotencyExpiry.
+	DeleteExpired(ctx context.Context) (int, error)
+}
+
+// ValidateIdempotencyKey validates idempotency keys sent by clients.
+func ValidateIdempotencyKey(key string) error {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return ErrInvalidIdempotencyKey
+	}
+	return nil
+}
+
+// HashRequest returns a hash identifying a request by its method, path and
+// body.
+func HashRequest(method string, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,324 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrNoWarehousesFound     = errors.New("no warehouses found")
+	ErrDuplicatedWarehouse   = errors.New("duplicated warehouse code")
+	ErrNoStockMovementsFound = errors.New("no stock movements found")
+	ErrBundleStockMovement   = errors.New("stock of bundles is derived from their components")
+
+	errWarehouseCodeRequired    = errors.New("code is required")
+	errWarehouseNameRequired    = errors.New("name is required")
+	errWarehouseIDRequired      = errors.New("warehouse_id is required")
+	errToWarehouseIDRequired    = errors.New("to_warehouse_id is required for transfers")
+	errSameWarehouseTransfer    = errors.New("transfer must be between different warehouses")
+	errInvalidMovementType      = errors.New("invalid stock movement type")
+	errMovementQuantity         = errors.New("quantity must not be zero")
+	errMovementQuantityPositive = errors.New("quantity must be greater than zero")
+)
+
+// DefaultWarehouseID is the warehouse stock of products is received into
+// when no warehouse is given.
+const DefaultWarehouseID = 1
+
+// Stock movement types, sales are recorded by checkout and returns are
+// recorded when orders are cancelled or returns restocked.
+const (
+	StockMovementReceipt    = "receipt"
+	StockMovementSale       = "sale"
+	StockMovementReturn     = "return"
+	StockMovementAdjustment = "adjustment"
+	StockMovementTransfer   = "transfer"
+)
+
+// WrapWarehouse wraps warehouses for user representation.
+type WrapWarehouse struct {
+	Warehouse Warehouse `json:"warehouse"`
+}
+
+// WrapWarehouseList wraps list of warehouses for user representation.
+type WrapWarehouseList struct {
+	Warehouses []Warehouse `json:"warehouses"`
+}
+
+// WrapStockMovementList wraps list of stock movements for user
+// representation.
+type WrapStockMovementList struct {
+	Movements []StockMovement `json:"movements"`
+}
+
+// WrapProductStock wraps stock of a product for user representation.
+type WrapProductStock struct {
+	Stock ProductStock `json:"stock"`
+}
+
+// WrapLocationStockList wraps stock of a warehouse for user representation.
+type WrapLocationStockList struct {
+	Stock []LocationStock `json:"stock"`
+}
+
+// Warehouse represents warehouses model, a location stock is kept in.
+type Warehouse struct {
+	ID        int       `json:"id"`
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// WarehouseCreate represents warehouses model for POST requests.
+type WarehouseCreate struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// StockMovement represents an entry of the stock ledger, quantity is
+// positive for stock coming in and negative for stock going out.
+type StockMovement struct {
+	ID          int       `json:"id"`
+	ProductID   int       `json:"product_id" db:"product_id"`
+	WarehouseID int       `json:"warehouse_id" db:"warehouse_id"`
+	Type        string    `json:"type"`
+	Quantity    int       `json:"quantity"`
+	Reference   string    `json:"reference"`
+	Note        string    `json:"note"`
+	CreatedBy   *int      `json:"created_by" db:"created_by"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// StockMovementCreate represents stock movements model for POST requests.
+// Receipts and returns take positive quantities, adjustments take signed
+// quantities and transfers move quantity to ToWarehouseID.
+type StockMovementCreate struct {
+	ProductID     int    `json:"product_id"`
+	WarehouseID   int    `json:"warehouse_id"`
+	ToWarehouseID int    `json:"to_warehouse_id"`
+	Type          string `json:"type"`
+	Quantity      int    `json:"quantity"`
+	Reference     string `json:"reference"`
+	Note          string `json:"note"`
+}
+
+// LocationStock represents on hand quantity of a product in a warehouse.
+type LocationStock struct {
+	ProductID   int `json:"product_id" db:"product_id"`
+	WarehouseID int `json:"warehouse_id" db:"warehouse_id"`
+	OnHand      int `json:"on_hand" db:"on_hand"`
+}
+
+// ProductStock represents stock of a product across warehouses, available
+// is on hand quantity not reserved by carts.
+type ProductStock struct {
+	ProductID int             `json:"product_id"`
+	OnHand    int             `json:"on_hand"`
+	Reserved  int             `json:"reserved"`
+	Available int             `json:"available"`
+	Locations []LocationStock `json:"locations"`
+}
+
+// StockMovementFilter represents filters passed to ListMovements.
+type StockMovementFilter struct {
+	ProductID   int    `json:"product_id"`
+	WarehouseID int    `json:"warehouse_id"`
+	Type        string `json:"type"`
+	Reference   string `json:"reference"`
+
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+	Sort   string `json:"sort"`
+}
+
+// InventoryService represents a service for managing warehouses and the
+// stock ledger.
+type InventoryService interface {
+	CreateWarehouse(ctx context.Context, warehouse *Warehouse) error
+	GetWarehouse(ctx context.Context, ID int) (Warehouse, error)
+	ListWarehouses(ctx context.Context) ([]Warehouse, error)
+
+	// RecordMovements appends movements to the ledger at once, it returns
+	// ErrInsufficientStock when a warehouse would go below zero.
+	RecordMovements(ctx context.Context, movements []StockMovement) ([]StockMovement, error)
+	ListMovements(ctx context.Context, filter StockMovementFilter) ([]StockMovement, error)
+
+	// ProductStock returns stock of a product by warehouse.
+	ProductStock(ctx context.Context, productID int) (ProductStock, error)
+	// WarehouseStock returns stock of products kept in a warehouse.
+	WarehouseStock(ctx context.Context, warehouseID int) ([]LocationStock, error)
+}
+
+// Validate validates POST requests model.
+func (w WarehouseCreate) Validate() error {
+	switch {
+	case w.Code == "":
+		return errWarehouseCodeRequired
+	case w.Name == "":
+		return errWarehouseNameRequired
+	}
+	return nil
+}
+
+// CreateModel set input values to a new struct and return a new instance.
+func (w WarehouseCreate) CreateModel() Warehouse {
+	return Warehouse{
+		Code: w.Code,
+		Name: w.Name,
+	}
+}
+
+// Validate validates POST requests model, sales are only recorded by
+// checkout.
+func (s StockMovementCreate) Validate() error {
+	switch {
+	case s.ProductID == 0:
+		return errProductIDRequired
+	case s.WarehouseID == 0:
+		return errWarehouseIDRequired
+	case s.Quantity == 0:
+		return errMovementQuantity
+	}
+
+	switch s.Type {
+	case StockMovementReceipt, StockMovementReturn:
+		if s.Quantity < 0 {
+			return errMovementQuantityPositive
+		}
+	case StockMovementAdjustment:
+	case StockMovementTransfer:
+		switch {
+		case s.Quantity < 0:
+			return errMovementQuantityPositive
+		case s.ToWarehouseID == 0:
+			return errToWarehouseIDRequired
+		case s.ToWarehouseID == s.WarehouseID:
+			return errSameWarehouseTransfer
+		}
+	default:
+		return errInvalidMovementType
+	}
+
+	return nil
+}
+
+// CreateModel returns movements of input recorded by user, transfers are
+// recorded as a movement out of source and one into destination.
+func (s StockMovementCreate) CreateModel(userID int) []StockMovement {
+	movement := StockMovement{
+		ProductID:   s.ProductID,
+		WarehouseID: s.WarehouseID,
+		Type:        s.Type,
+		Quantity:    s.Quantity,
+		Reference:   s.Reference,
+		Note:        s.Note,
+	}
+
+	if userID != 0 {
+		movement.CreatedBy = &userID
+	}
+
+	if s.Type != StockMovementTransfer {
+		return []StockMovement{movement}
+	}
+
+	in := movement
+	in.WarehouseID = s.ToWarehouseID
+	movement.Quantity = -s.Quantity
+
+	return []StockMovement{movement, in}
+}
+
+// AllocateStock takes quantity out of locations in order, it returns
+// quantities taken from each location or ErrInsufficientStock when
+// locations fall short.
+func AllocateStock(quantity int, locations []LocationStock) ([]LocationStock, error) {
+	var taken []LocationStock
+	for _, l := range locations {
+		if quantity == 0 {
+			break
+		}
+
+		if l.OnHand <= 0 {
+			continue
+		}
+
+		take := l.OnHand
+		if take > quantity {
+			take = quantity
+		}
+
+		l.OnHand = take
+		taken = append(taken, l)
+		quantity -= take
+	}
+
+	if quantity > 0 {
+		return nil, ErrInsufficientStock
+	}
+
+	return taken, nil
+}
+
+// RestoreStock gives quantity back to locations it was sold from up to sold
+// quantity of each, the rest goes to the default warehouse.
+func RestoreStock(productID int, quantity int, sold []LocationStock) []LocationStock {
+	var restored []LocationStock
+	for _, l := range sold {
+		if quantity == 0 {
+			break
+		}
+
+		if l.OnHand <= 0 {
+			continue
+		}
+
+		give := l.OnHand
+		if give > quantity {
+			give = quantity
+		}
+
+		l.OnHand = give
+		restored = append(restored, l)
+		quantity -= give
+	}
+
+	if quantity > 0 {
+		restored = append(restored, LocationStock{
+			ProductID:   productID,
+			WarehouseID: DefaultWarehouseID,
+			OnHand:      quantity,
+		})
+	}
+
+	return restored
+}
+
+// NewProductStock returns stock of a product from its locations and
+// quantity reserved by carts.
+func NewProductStock(productID int, locations []LocationStock, reserved int) ProductStock {
+	stock := ProductStock{
+		ProductID: productID,
+		Reserved:  reserved,
+		Locations: locations,
+	}
+
+	for _, l := range locations {
+		stock.OnHand += l.OnHand
+	}
+
+	stock.Available = stock.OnHand - reserved
+	if stock.Available < 0 {
+		stock.Available = 0
+	}
+
+	return stock
+}
+
+// OrderStockReference returns reference of stock movements recorded for an
+// order.
+func OrderStockReference(orderID int) string {
+	return fmt.Sprintf("order:%d", orderID)
+}
@@ -0,0 +1,76 @@
+package domain_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+func TestAllocateStock(t *testing.T) {
+	locations := []domain.LocationStock{
+		{ProductID: 1, WarehouseID: 1, OnHand: 2},
+		{ProductID: 1, WarehouseID: 2, OnHand: 0},
+		{ProductID: 1, WarehouseID: 3, OnHand: 5},
+	}
+
+	taken, err := domain.AllocateStock(4, locations)
+	if err != nil {
+		t.Fatalf("AllocateStock: %v", err)
+	}
+
+	want := []domain.LocationStock{
+		{ProductID: 1, WarehouseID: 1, OnHand: 2},
+		{ProductID: 1, WarehouseID: 3, OnHand: 2},
+	}
+	if !reflect.DeepEqual(taken, want) {
+		t.Errorf("mismatch\n got: %v\nwant: %v", taken, want)
+	}
+
+	_, err = domain.AllocateStock(8, locations)
+	if err != domain.ErrInsufficientStock {
+		t.Errorf("expected %q from AllocateStock, got %q", domain.ErrInsufficientStock, err)
+	}
+
+	restored := domain.RestoreStock(1, 5, want)
+	want = []domain.LocationStock{
+		{ProductID: 1, WarehouseID: 1, OnHand: 2},
+		{ProductID: 1, WarehouseID: 3, OnHand: 2},
+		{ProductID: 1, WarehouseID: domain.DefaultWarehouseID, OnHand: 1},
+	}
+	if !reflect.DeepEqual(restored, want) {
+		t.Errorf("mismatch\n got: %v\nwant: %v", restored, want)
+	}
+}
+
+func TestStockMovementCreate(t *testing.T) {
+	input := domain.StockMovementCreate{
+		ProductID:     1,
+		WarehouseID:   1,
+		ToWarehouseID: 2,
+		Type:          domain.StockMovementTransfer,
+		Quantity:      3,
+	}
+
+	err := input.Validate()
+	if err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	movements := input.CreateModel(0)
+	if len(movements) != 2 || movements[0].Quantity != -3 || movements[1].Quantity != 3 ||
+		movements[1].WarehouseID != 2 {
+		t.Errorf("expected transfer out of 1 into 2, got: %#v", movements)
+	}
+
+	input.Type = domain.StockMovementSale
+	if input.Validate() == nil {
+		t.Errorf("expected sales to be rejected")
+	}
+
+	input.Type = domain.StockMovementAdjustment
+	input.Quantity = -2
+	if err := input.Validate(); err != nil {
+		t.Errorf("expected negative adjustment to be valid, got %q", err)
+	}
+}
@@ -0,0 +1,168 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var (
+	ErrNoInvoicesFound = errors.New("no invoices found")
+
+	errInvoiceSort = errors.New("sort must be one of id, issued_at, number or total")
+)
+
+// Invoice kinds, credit notes are issued against an invoice when its order
+// is refunded. Each kind is numbered apart.
+const (
+	InvoiceKindInvoice    = "invoice"
+	InvoiceKindCreditNote = "credit_note"
+)
+
+// invoicePrefixes holds number prefixes of invoice kinds.
+var invoicePrefixes = map[string]string{
+	InvoiceKindInvoice:    "INV",
+	InvoiceKindCreditNote: "CN",
+}
+
+// DocumentRenderer represents a renderer of order documents.
+type DocumentRenderer interface {
+	RenderInvoice(w io.Writer, invoice Invoice) error
+	// RenderPackingSlip renders packing slip of shippable lines of order,
+	// only lines of shipment are listed unless it is nil.
+	RenderPackingSlip(w io.Writer, order Order, shipment *Shipment) error
+}
+
+// WrapInvoice wraps invoices for user representation.
+type WrapInvoice struct {
+	Invoice Invoice `json:"invoice"`
+}
+
+// WrapInvoiceList wraps list of invoices for user representation.
+type WrapInvoiceList struct {
+	Invoices []Invoice `json:"invoices"`
+}
+
+// Invoice represents invoices and credit notes model, invoices are
+// numbered without gaps per year and never change once issued. Total of
+// credit notes is the credited amount.
+type Invoice struct {
+	ID              int           `json:"id"`
+	OrderID         int           `json:"order_id" db:"order_id"`
+	InvoiceID       *int          `json:"invoice_id" db:"invoice_id"`
+	Kind            string        `json:"kind"`
+	Number          string        `json:"number"`
+	Year            int           `json:"year"`
+	Sequence        int           `json:"sequence"`
+	Subtotal        int           `json:"subtotal"`
+	Discount        int           `json:"discount"`
+	Tax             int           `json:"tax"`
+	TaxInclusive    bool          `json:"tax_inclusive" db:"tax_inclusive"`
+	Shipping        int           `json:"shipping"`
+	Total           int           `json:"total"`
+	Currency        string        `json:"currency"`
+	Lines           []InvoiceLine `json:"lines"`
+	BillingAddress  *OrderAddress `json:"billing_address" db:"billing_address"`
+	ShippingAddress *OrderAddress `json:"shipping_address" db:"shipping_address"`
+	Reason          string        `json:"reason"`
+	IssuedAt        time.Time     `json:"issued_at" db:"issued_at"`
+}
+
+// InvoiceLine represents a line of invoice as it was at issue time.
+type InvoiceLine struct {
+	Name      string `json:"name"`
+	SKU       string `json:"sku"`
+	Quantity  int    `json:"quantity"`
+	UnitPrice int    `json:"unit_price"`
+	Discount  int    `json:"discount"`
+	TaxRate   int    `json:"tax_rate"`
+	Tax       int    `json:"tax"`
+	Total     int    `json:"total"`
+}
+
+// InvoiceFilter represents filters passed to List.
+type InvoiceFilter struct {
+	ID      int    `json:"id"`
+	OrderID int    `json:"order_id"`
+	UserID  int    `json:"user_id"`
+	Kind    string `json:"kind"`
+
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+	Sort   string `json:"sort"`
+}
+
+// InvoiceService represents a service for reading invoices, invoices are
+// issued once orders are paid and credit notes once they are refunded.
+type InvoiceService interface {
+	GetByID(ctx context.Context, ID int) (Invoice, error)
+	List(ctx context.Context, filter InvoiceFilter) ([]Invoice, error)
+}
+
+// NewInvoice returns invoice of order, order must have its lines.
+func NewInvoice(order Order) Invoice {
+	invoice := Invoice{
+		OrderID:         order.ID,
+		Kind:            InvoiceKindInvoice,
+		Subtotal:        order.Subtotal,
+		Discount:        order.Discount,
+		Tax:             order.Tax,
+		TaxInclusive:    order.TaxInclusive,
+		Shipping:        order.Shipping,
+		Total:           order.Total,
+		Currency:        PaymentCurrency,
+		Lines:           make([]InvoiceLine, 0, len(order.Lines)),
+		BillingAddress:  order.BillingAddress,
+		ShippingAddress: order.ShippingAddress,
+	}
+
+	for _, line := range order.Lines {
+		invoice.Lines = append(invoice.Lines, InvoiceLine{
+			Name:      line.Name,
+			SKU:       line.SKU,
+			Quantity:  line.Quantity,
+			UnitPrice: line.UnitPrice,
+			Discount:  line.Discount,
+			TaxRate:   line.TaxRate,
+			Tax:       line.Tax,
+			Total:     line.LineTotal,
+		})
+	}
+
+	return invoice
+}
+
+// Validate validates filters of /invoices requests, only known columns
+// are sortable.
+func (f InvoiceFilter) Validate() error {
+	switch f.Sort {
+	case "", "id", "issued_at", "number", "total":
+		return nil
+	}
+	return errInvoiceSort
+}
+
+// CreditNote returns credit note of amount against invoice.
+func (i Invoice) CreditNote(amount int, reason string) Invoice {
+	invoiceID := i.ID
+	return Invoice{
+		OrderID:         i.OrderID,
+		InvoiceID:       &invoiceID,
+		Kind:            InvoiceKindCreditNote,
+		Total:           amount,
+		Currency:        i.Currency,
+		Lines:           []InvoiceLine{},
+		BillingAddress:  i.BillingAddress,
+		ShippingAddress: i.ShippingAddress,
+		Reason:          reason,
+	}
+}
+
+// SetNumber numbers invoice by its sequence in year.
+func (i *Invoice) SetNumber(year int, sequence int) {
+	i.Year = year
+	i.Sequence = sequence
+	i.Number = fmt.Sprintf("%s-%d-%06d", invoicePrefixes[i.Kind], year, sequence)
+}
@@ -0,0 +1,65 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+func TestNewInvoice(t *testing.T) {
+	address := &domain.OrderAddress{Name: "John", Country: "US"}
+	order := domain.Order{
+		ID:              1,
+		Subtotal:        2000,
+		Discount:        200,
+		Tax:             180,
+		Shipping:        500,
+		Total:           2480,
+		BillingAddress:  address,
+		ShippingAddress: address,
+		Lines: []domain.OrderLine{
+			{Name: "Book", SKU: "B-1", Quantity: 2, UnitPrice: 1000, Discount: 200, TaxRate: 1000, Tax: 180, LineTotal: 1800},
+		},
+	}
+
+	invoice := domain.NewInvoice(order)
+	if invoice.Kind != domain.InvoiceKindInvoice || invoice.Total != order.Total || invoice.Shipping != order.Shipping {
+		t.Errorf("expected invoice of order totals, got: %#v", invoice)
+	}
+
+	want := domain.InvoiceLine{Name: "Book", SKU: "B-1", Quantity: 2, UnitPrice: 1000, Discount: 200, TaxRate: 1000, Tax: 180, Total: 1800}
+	if len(invoice.Lines) != 1 || invoice.Lines[0] != want {
+		t.Errorf("mismatch\n got: %#v\nwant: %#v", invoice.Lines, want)
+	}
+
+	invoice.ID = 7
+	invoice.SetNumber(2024, 42)
+	if invoice.Number != "INV-2024-000042" {
+		t.Errorf("expected number INV-2024-000042, got: %s", invoice.Number)
+	}
+
+	note := invoice.CreditNote(500, "damaged")
+	note.SetNumber(2024, 1)
+	if note.Kind != domain.InvoiceKindCreditNote || note.Total != 500 || note.InvoiceID == nil || *note.InvoiceID != 7 {
+		t.Errorf("expected credit note of 500 against invoice, got: %#v", note)
+	}
+
+	if note.Number != "CN-2024-000001" || note.BillingAddress != address {
+		t.Errorf("expected credit note CN-2024-000001 to invoice address, got: %#v", note)
+	}
+}
+
+func TestInvoiceFilterValidate(t *testing.T) {
+	for sort, valid := range map[string]bool{
+		"":              true,
+		"issued_at":     true,
+		"number":        true,
+		"1; SELECT 1":   false,
+		"order_id DESC": false,
+	} {
+		err := domain.InvoiceFilter{Sort: sort}.Validate()
+		if (err == nil) != valid {
+			t.Errorf("%q: expected valid %v, got: %v", sort, valid, err)
+		}
+	}
+}
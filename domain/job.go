@@ -0,0 +1,11 @@
+package domain
+
+import "context"
+
+// Locker represents a lock shared by instances of the application, jobs
+// run under it are run by one instance at a time.
+type Locker interface {
+	// TryRun runs fn holding lock of name unless another instance holds
+	// it, it reports whether fn was run.
+	TryRun(ctx context.Context, name string, fn func(ctx context.Context) error) (bool, error)
+}
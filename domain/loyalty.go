@@ -0,0 +1,171 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrLoyaltyBalance         = errors.New("not enough loyalty points")
+	ErrLoyaltyInvalidCategory = errors.New("category of earn rule does not exist")
+
+	errRedeemPoints            = errors.New("redeem_points must not be negative")
+	errEarnRate                = errors.New("points_per_unit must not be negative")
+	errBonusRate               = errors.New("points_per_unit of bonus categories must be greater than zero")
+	errDuplicatedBonusCategory = errors.New("duplicated bonus category")
+)
+
+// Kinds of loyalty transactions, earned and restored points are redeemed
+// and expired oldest first.
+const (
+	LoyaltyEarn    = "earn"
+	LoyaltyRedeem  = "redeem"
+	LoyaltyRestore = "restore"
+	LoyaltyRevoke  = "revoke"
+	LoyaltyExpire  = "expire"
+)
+
+const (
+	// LoyaltyUnit is amount in minor unit of currency points are earned
+	// per, i.e. a currency unit.
+	LoyaltyUnit = 100
+
+	// LoyaltyPointValue is amount in minor unit of currency a point takes
+	// off orders.
+	LoyaltyPointValue = 1
+
+	// DefaultLoyaltyExpiry is how long points are kept before they
+	// expire.
+	DefaultLoyaltyExpiry = 365 * 24 * time.Hour
+)
+
+// WrapLoyaltyAccount wraps loyalty accounts for user representation.
+type WrapLoyaltyAccount struct {
+	Loyalty LoyaltyAccount `json:"loyalty"`
+}
+
+// WrapLoyaltyRules wraps loyalty earn rules for user representation.
+type WrapLoyaltyRules struct {
+	Rules LoyaltyRules `json:"rules"`
+}
+
+// LoyaltyAccount represents loyalty points of a user, value is what its
+// balance takes off orders. Transactions are listed from newest.
+type LoyaltyAccount struct {
+	UserID       int                  `json:"user_id"`
+	Balance      int                  `json:"balance"`
+	Value        int                  `json:"value"`
+	Currency     string               `json:"currency"`
+	Transactions []LoyaltyTransaction `json:"transactions"`
+}
+
+// LoyaltyTransaction represents a transaction of loyalty points ledger,
+// points are signed and balance is what is left after it.
+type LoyaltyTransaction struct {
+	ID        int       `json:"id"`
+	OrderID   *int      `json:"order_id" db:"order_id"`
+	Kind      string    `json:"kind"`
+	Points    int       `json:"points"`
+	Balance   int       `json:"balance"`
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// LoyaltyRules represents loyalty earn rules model, lines of paid orders
+// earn points per currency unit of their total net of tax. Bonus points of
+// categories are added to the base rate, no points are earned when there
+// are no rules.
+type LoyaltyRules struct {
+	PointsPerUnit   int            `json:"points_per_unit"`
+	BonusCategories []LoyaltyBonus `json:"bonus_categories"`
+}
+
+// LoyaltyBonus represents bonus points per currency unit of a category.
+type LoyaltyBonus struct {
+	CategoryID    int `json:"category_id"`
+	PointsPerUnit int `json:"points_per_unit"`
+}
+
+// LoyaltyService represents a service for managing loyalty points.
+type LoyaltyService interface {
+	GetRules(ctx context.Context) (LoyaltyRules, error)
+	// SetRules replaces earn rules, points already earned are kept.
+	SetRules(ctx context.Context, rules LoyaltyRules) (LoyaltyRules, error)
+
+	// Get returns balance of user along with its transactions.
+	Get(ctx context.Context, userID int) (LoyaltyAccount, error)
+	// ExpirePoints expires points earned before and not redeemed yet, it
+	// returns number of expired transactions.
+	ExpirePoints(ctx context.Context, before time.Time) (int, error)
+}
+
+// Validate validates checkout requests model.
+func (c CheckoutInput) Validate() error {
+	if c.RedeemPoints < 0 {
+		return errRedeemPoints
+	}
+	return nil
+}
+
+// Validate validates PUT requests model.
+func (r LoyaltyRules) Validate() error {
+	if r.PointsPerUnit < 0 {
+		return errEarnRate
+	}
+
+	seen := make(map[int]bool)
+	for _, bonus := range r.BonusCategories {
+		if bonus.PointsPerUnit <= 0 {
+			return errBonusRate
+		}
+
+		if seen[bonus.CategoryID] {
+			return errDuplicatedBonusCategory
+		}
+		seen[bonus.CategoryID] = true
+	}
+
+	return nil
+}
+
+// Points returns points earned by order, categories holds category of
+// products of its lines. Lines of deleted products earn the base rate.
+func (r LoyaltyRules) Points(order Order, categories map[int]int) int {
+	bonuses := make(map[int]int)
+	for _, bonus := range r.BonusCategories {
+		bonuses[bonus.CategoryID] = bonus.PointsPerUnit
+	}
+
+	var points int
+	for _, line := range order.Lines {
+		rate := r.PointsPerUnit
+		if line.ProductID != nil {
+			rate += bonuses[categories[*line.ProductID]]
+		}
+
+		amount := line.LineTotal
+		if order.TaxInclusive {
+			amount -= line.Tax
+		}
+
+		points += amount * rate / LoyaltyUnit
+	}
+
+	return points
+}
+
+// ApplyLoyaltyDiscount takes value of points off order total as far as it
+// goes and returns points used.
+func (o *Order) ApplyLoyaltyDiscount(points int) int {
+	if points*LoyaltyPointValue > o.Total {
+		points = o.Total / LoyaltyPointValue
+	}
+
+	discount := points * LoyaltyPointValue
+	o.LoyaltyDiscount += discount
+	o.Discount += discount
+	o.Total -= discount
+
+	return points
+}
@@ -0,0 +1,88 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+func TestLoyaltyRulesValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules domain.LoyaltyRules
+		valid bool
+	}{
+		{"no rules", domain.LoyaltyRules{}, true},
+		{"base and bonus", domain.LoyaltyRules{PointsPerUnit: 1, BonusCategories: []domain.LoyaltyBonus{{CategoryID: 1, PointsPerUnit: 2}}}, true},
+		{"negative base", domain.LoyaltyRules{PointsPerUnit: -1}, false},
+		{"zero bonus", domain.LoyaltyRules{BonusCategories: []domain.LoyaltyBonus{{CategoryID: 1}}}, false},
+		{"duplicated bonus", domain.LoyaltyRules{BonusCategories: []domain.LoyaltyBonus{
+			{CategoryID: 1, PointsPerUnit: 1},
+			{CategoryID: 1, PointsPerUnit: 2},
+		}}, false},
+	}
+
+	for _, test := range tests {
+		err := test.rules.Validate()
+		if (err == nil) != test.valid {
+			t.Errorf("%s: expected valid %v, got: %v", test.name, test.valid, err)
+		}
+	}
+}
+
+func TestLoyaltyRulesPoints(t *testing.T) {
+	rules := domain.LoyaltyRules{
+		PointsPerUnit:   2,
+		BonusCategories: []domain.LoyaltyBonus{{CategoryID: 7, PointsPerUnit: 3}},
+	}
+
+	first, second := 1, 2
+	order := domain.Order{
+		Lines: []domain.OrderLine{
+			{ProductID: &first, LineTotal: 1050},
+			{ProductID: &second, LineTotal: 1000},
+			{LineTotal: 500},
+		},
+	}
+	categories := map[int]int{1: 7, 2: 8}
+
+	// 10.50 at 5 points, 10.00 at 2 points and 5.00 of a deleted product
+	// at base rate.
+	if points := rules.Points(order, categories); points != 52+20+10 {
+		t.Errorf("expected %d points, got: %d", 82, points)
+	}
+
+	order.TaxInclusive = true
+	order.Lines[1].Tax = 500
+	if points := rules.Points(order, categories); points != 52+10+10 {
+		t.Errorf("expected %d points net of tax, got: %d", 72, points)
+	}
+
+	if points := (domain.LoyaltyRules{}).Points(order, categories); points != 0 {
+		t.Errorf("expected no points without rules, got: %d", points)
+	}
+}
+
+func TestOrderApplyLoyaltyDiscount(t *testing.T) {
+	order := domain.Order{Subtotal: 100, Discount: 10, Total: 90}
+
+	points := order.ApplyLoyaltyDiscount(30)
+	if points != 30 || order.Total != 60 || order.Discount != 40 || order.LoyaltyDiscount != 30 {
+		t.Errorf("expected 30 points off, got: %d, %+v", points, order)
+	}
+
+	points = order.ApplyLoyaltyDiscount(100)
+	if points != 60 || order.Total != 0 || order.LoyaltyDiscount != 90 {
+		t.Errorf("expected points capped at total, got: %d, %+v", points, order)
+	}
+}
+
+func TestCheckoutInputValidate(t *testing.T) {
+	if err := (domain.CheckoutInput{RedeemPoints: 10}).Validate(); err != nil {
+		t.Errorf("expected valid input, got: %v", err)
+	}
+
+	if err := (domain.CheckoutInput{RedeemPoints: -1}).Validate(); err == nil {
+		t.Errorf("expected negative points to be invalid")
+	}
+}
@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrNoStockSubscriptionsFound = errors.New("no stock subscriptions found")
+	ErrProductInStock            = errors.New("product is in stock")
+)
+
+// Notification kinds.
+const (
+	NotificationLowStock     = "low_stock"
+	NotificationBackInStock  = "back_in_stock"
+	NotificationCartRecovery = "cart_recovery"
+	NotificationPriceDrop    = "price_drop"
+)
+
+// StaffRecipient is recipient of notifications meant for staff, notifiers
+// route them to wherever staff is reached.
+const StaffRecipient = "staff"
+
+// NotificationMaxAttempts is number of times delivery of a notification is
+// tried before it is given up.
+const NotificationMaxAttempts = 5
+
+// Notifier delivers notifications to their recipient.
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// Notification represents a message queued for delivery.
+type Notification struct {
+	ID        int        `json:"id"`
+	Kind      string     `json:"kind"`
+	Recipient string     `json:"recipient"`
+	Subject   string     `json:"subject"`
+	Body      string     `json:"body"`
+	Attempts  int        `json:"attempts"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	SentAt    *time.Time `json:"sent_at" db:"sent_at"`
+}
+
+// NotificationService represents a service for delivering queued
+// notifications.
+type NotificationService interface {
+	// Deliver sends up to limit pending notifications through notifier and
+	// returns number of sent ones, failed ones are retried on next calls up
+	// to NotificationMaxAttempts.
+	Deliver(ctx context.Context, notifier Notifier, limit int) (int, error)
+}
+
+// WrapStockSubscription wraps stock subscriptions for user representation.
+type WrapStockSubscription struct {
+	Subscription StockSubscription `json:"subscription"`
+}
+
+// StockSubscription represents a customer waiting for a product to be back
+// in stock, a subscription is notified once.
+type StockSubscription struct {
+	ID         int        `json:"id"`
+	ProductID  int        `json:"product_id" db:"product_id"`
+	UserID     int        `json:"user_id" db:"user_id"`
+	Email      string     `json:"email"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	NotifiedAt *time.Time `json:"notified_at" db:"notified_at"`
+}
+
+// StockSubscriptionService represents a service for managing back in
+// stock subscriptions.
+type StockSubscriptionService interface {
+	// Subscribe subscribes user to a product out of stock, it returns
+	// ErrProductInStock when product is available.
+	Subscribe(ctx context.Context, subscription *StockSubscription) error
+	Unsubscribe(ctx context.Context, productID int, userID int) error
+}
+
+// LowStockNotification returns notification of staff about available
+// quantity of product falling to its reorder threshold.
+func LowStockNotification(product Product, available int) Notification {
+	return Notification{
+		Kind:      NotificationLowStock,
+		Recipient: StaffRecipient,
+		Subject:   fmt.Sprintf("Low stock: %s", product.Name),
+		Body: fmt.Sprintf("%s (product %d, SKU %q) has %d available, reorder threshold is %d.",
+			product.Name, product.ID, product.SKU, available, product.ReorderThreshold),
+	}
+}
+
+// BackInStockNotification returns notification of a subscriber about
+// product being available again.
+func BackInStockNotification(product Product, email string) Notification {
+	return Notification{
+		Kind:      NotificationBackInStock,
+		Recipient: email,
+		Subject:   fmt.Sprintf("%s is back in stock", product.Name),
+		Body:      fmt.Sprintf("%s you asked about is available again at /products/%d.", product.Name, product.ID),
+	}
+}
@@ -0,0 +1,30 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+func TestStockCrossings(t *testing.T) {
+	tests := []struct {
+		before, after, threshold int
+		below, back              bool
+	}{
+		{before: 8, after: 5, threshold: 5, below: true},
+		{before: 5, after: 3, threshold: 5},
+		{before: 8, after: 0, threshold: 0},
+		{before: 0, after: 4, threshold: 5, back: true},
+		{before: -2, after: 0, threshold: 5},
+	}
+
+	for _, tt := range tests {
+		if got := domain.BelowReorderThreshold(tt.before, tt.after, tt.threshold); got != tt.below {
+			t.Errorf("BelowReorderThreshold(%d, %d, %d) = %v, want %v", tt.before, tt.after, tt.threshold, got, tt.below)
+		}
+
+		if got := domain.BackInStock(tt.before, tt.after); got != tt.back {
+			t.Errorf("BackInStock(%d, %d) = %v, want %v", tt.before, tt.after, got, tt.back)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNoOrdersFound          = errors.New("no orders found")
+	ErrEmptyCart              = errors.New("cart is empty")
+	ErrInvalidOrderTransition = errors.New("order status transition not allowed")
+	ErrOrderNotCancellable    = errors.New("order can not be cancelled after fulfillment started")
+
+	errInvalidOrderStatus = errors.New("invalid order status")
+	errOrderSort          = errors.New("sort must be one of id, created_at, total or status")
+)
+
+// Order statuses, newly placed orders are pending.
+const (
+	OrderStatusPending          = "pending"
+	OrderStatusAwaitingPayment  = "awaiting_payment"
+	OrderStatusPaid             = "paid"
+	OrderStatusFulfilling       = "fulfilling"
+	OrderStatusPartiallyShipped = "partially_shipped"
+	OrderStatusShipped          = "shipped"
+	OrderStatusDelivered        = "delivered"
+	OrderStatusCancelled        = "cancelled"
+	OrderStatusRefunded         = "refunded"
+)
+
+// orderTransitions holds statuses an order is allowed to move to from
+// each status.
+var orderTransitions = map[string][]string{
+	OrderStatusPending:          {OrderStatusAwaitingPayment, OrderStatusPaid, OrderStatusCancelled},
+	OrderStatusAwaitingPayment:  {OrderStatusPaid, OrderStatusCancelled},
+	OrderStatusPaid:             {OrderStatusFulfilling, OrderStatusCancelled, OrderStatusRefunded},
+	OrderStatusFulfilling:       {OrderStatusPartiallyShipped, OrderStatusShipped, OrderStatusRefunded},
+	OrderStatusPartiallyShipped: {OrderStatusShipped, OrderStatusRefunded},
+	OrderStatusShipped:          {OrderStatusDelivered},
+	OrderStatusDelivered:        {OrderStatusRefunded},
+	OrderStatusCancelled:        {},
+	OrderStatusRefunded:         {},
+}
+
+// WrapOrder wraps orders for user representation.
+type WrapOrder struct {
+	Order Order `json:"order"`
+}
+
+// WrapOrderList wraps list of orders for user representation.
+type WrapOrderList struct {
+	Orders []Order `json:"orders"`
+}
+
+// Order represents orders model, lines keep products as they were at
+// purchase time.
+type Order struct {
+	ID           int         `json:"id"`
+	UserID       int         `json:"user_id" db:"user_id"`
+	Status       string      `json:"status"`
+	Subtotal     int         `json:"subtotal"`
+	Discount     int         `json:"discount"`
+	Tax          int         `json:"tax"`
+	TaxInclusive bool        `json:"tax_inclusive" db:"tax_inclusive"`
+	Shipping     int         `json:"shipping"`
+	Total        int         `json:"total"`
+	CreatedAt    time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at" db:"updated_at"`
+	Version      int         `json:"version"`
+	Lines        []OrderLine `json:"lines" db:"-"`
+
+	// LoyaltyDiscount is value of loyalty points redeemed on order, it is
+	// part of discount.
+	LoyaltyDiscount int `json:"loyalty_discount" db:"loyalty_discount"`
+
+	ShippingMethodID *int   `json:"shipping_method_id" db:"shipping_method_id"`
+	ShippingMethod   string `json:"shipping_method" db:"shipping_method"`
+
+	// addresses are kept as they were at purchase time.
+	ShippingAddress *OrderAddress `json:"shipping_address" db:"shipping_address"`
+	BillingAddress  *OrderAddress `json:"billing_address" db:"billing_address"`
+}
+
+// OrderLine represents a line of order, product is nil once the product
+// is deleted. Line total is net of discount and returned is quantity under
+// returns not rejected. Tax is included in line total when order prices
+// include tax.
+type OrderLine struct {
+	ID        int    `json:"id"`
+	OrderID   int    `json:"-" db:"order_id"`
+	ProductID *int   `json:"product_id" db:"product_id"`
+	Name      string `json:"name"`
+	SKU       string `json:"sku"`
+	UnitPrice int    `json:"unit_price" db:"unit_price"`
+	Quantity  int    `json:"quantity"`
+	Discount  int    `json:"discount"`
+	LineTotal int    `json:"line_total" db:"line_total"`
+	Returned  int    `json:"returned"`
+	Refunded  int    `json:"refunded"`
+
+	TaxCategory string `json:"tax_category" db:"tax_category"`
+	TaxRate     int    `json:"tax_rate" db:"tax_rate"`
+	Tax         int    `json:"tax"`
+
+	// Backordered is quantity of line waiting for stock to arrive.
+	Backordered int `json:"backordered"`
+
+	// Shipped is quantity of line in shipments not cancelled, digital
+	// products are not shippable.
+	Shippable bool `json:"shippable"`
+	Shipped   int  `json:"shipped"`
+}
+
+// OrderStatusChange represents a record of order status history.
+type OrderStatusChange struct {
+	ID         int       `json:"id"`
+	OrderID    int       `json:"order_id" db:"order_id"`
+	FromStatus string    `json:"from_status" db:"from_status"`
+	ToStatus   string    `json:"to_status" db:"to_status"`
+	ChangedBy  *int      `json:"changed_by" db:"changed_by"`
+	Note       string    `json:"note"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// WrapOrderStatusChangeList wraps order status history for user
+// representation.
+type WrapOrderStatusChangeList struct {
+	History []OrderStatusChange `json:"history"`
+}
+
+// OrderStatusUpdate represents orders model for status change requests.
+type OrderStatusUpdate struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+// CheckoutInput represents checkout model for POST requests, default
+// addresses of user are used unless given. Loyalty points are taken off
+// order total, gift cards and then store credit pay for the rest as far as
+// their balance goes.
+type CheckoutInput struct {
+	ShippingMethodID  int      `json:"shipping_method_id"`
+	ShippingAddressID int      `json:"shipping_address_id"`
+	BillingAddressID  int      `json:"billing_address_id"`
+	GiftCards         []string `json:"gift_cards"`
+	UseStoreCredit    bool     `json:"use_store_credit"`
+	RedeemPoints      int      `json:"redeem_points"`
+}
+
+// CheckoutDetails represents what an order is placed with besides its
+// cart. Destination and ShippingMethodID are set on the cart when given.
+// Shipping is charged by quote unless it is nil, a quote not matching the
+// cart fails with ErrShippingQuoteChanged.
+type CheckoutDetails struct {
+	Destination      *Location
+	ShippingMethodID int
+
+	Shipping        *ShippingQuote
+	ShippingAddress *OrderAddress
+	BillingAddress  *OrderAddress
+	GiftCards       []string
+	UseStoreCredit  bool
+	RedeemPoints    int
+}
+
+// OrderFilter represents filters passed to List.
+type OrderFilter struct {
+	ID     int `json:"id"`
+	UserID int `json:"user_id"`
+
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+	Sort   string `json:"sort"`
+}
+
+// OrderService represents a service for managing orders.
+type OrderService interface {
+	GetByID(ctx context.Context, ID int) (Order, error)
+	List(ctx context.Context, filter OrderFilter) ([]Order, error)
+
+	// Checkout places an order from user's cart, takes its stock and
+	// empties the cart.
+	Checkout(ctx context.Context, userID int, details CheckoutDetails) (Order, error)
+
+	// UpdateStatus moves an order through an allowed transition, stock is
+	// given back to inventory on cancellation.
+	UpdateStatus(ctx context.Context, ID int, status string, changedBy int, note string) (Order, error)
+	// Cancel cancels an order of user before its fulfillment starts,
+	// payments made through payment gateways are left to be refunded.
+	Cancel(ctx context.Context, ID int, userID int) (Order, error)
+	History(ctx context.Context, ID int) ([]OrderStatusChange, error)
+}
+
+// Validate validates status change requests model.
+func (o OrderStatusUpdate) Validate() error {
+	if _, ok := orderTransitions[o.Status]; !ok {
+		return errInvalidOrderStatus
+	}
+	return nil
+}
+
+// Validate validates filters of /orders requests, only known columns are
+// sortable.
+func (f OrderFilter) Validate() error {
+	switch f.Sort {
+	case "", "id", "created_at", "total", "status":
+		return nil
+	}
+	return errOrderSort
+}
+
+// CanTransition reports whether order status may move from one to another.
+func CanTransition(from string, to string) bool {
+	for _, status := range orderTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition moves order to status and returns record of the change.
+func (o *Order) Transition(to string, changedBy int, note string) (OrderStatusChange, error) {
+	if !CanTransition(o.Status, to) {
+		return OrderStatusChange{}, ErrInvalidOrderTransition
+	}
+
+	change := OrderStatusChange{
+		OrderID:    o.ID,
+		FromStatus: o.Status,
+		ToStatus:   to,
+		Note:       note,
+	}
+	if changedBy != 0 {
+		change.ChangedBy = &changedBy
+	}
+
+	o.Status = to
+	return change, nil
+}
+
+// CanCancel reports whether customer may cancel the order, that is before
+// its fulfillment starts. Payments of paid orders are refunded.
+func (o Order) CanCancel() bool {
+	switch o.Status {
+	case OrderStatusPending, OrderStatusAwaitingPayment, OrderStatusPaid:
+		return true
+	}
+	return false
+}
+
+// NewOrder returns a pending order of cart, products are used to snapshot
+// SKUs and tax categories of lines; cart must have its products and taxes
+// applied.
+func NewOrder(cart Cart, products map[int]Product) Order {
+	order := Order{
+		UserID:       cart.UserID,
+		Status:       OrderStatusPending,
+		Subtotal:     cart.Totals.Subtotal,
+		Discount:     cart.Totals.Discount,
+		Tax:          cart.Totals.Tax,
+		TaxInclusive: cart.Totals.TaxInclusive,
+		Shipping:     cart.Totals.Shipping,
+		Total:        cart.Totals.GrandTotal,
+		Lines:        make([]OrderLine, 0, len(cart.Items)),
+	}
+
+	if cart.Shipping != nil {
+		methodID := cart.Shipping.MethodID
+		order.ShippingMethodID = &methodID
+		order.ShippingMethod = cart.Shipping.Name
+	}
+
+	for _, item := range cart.Items {
+		productID := item.ProductID
+		order.Lines = append(order.Lines, OrderLine{
+			ProductID: &productID,
+			Name:      item.Name,
+			SKU:       products[item.ProductID].SKU,
+			UnitPrice: item.UnitPrice,
+			Quantity:  item.Quantity,
+			Discount:  item.Discount,
+			LineTotal: item.LineTotal,
+
+			TaxCategory: products[item.ProductID].TaxCategory,
+			TaxRate:     item.TaxRate,
+			Tax:         item.Tax,
+
+			Shippable: products[item.ProductID].Type != ProductTypeDigital,
+		})
+	}
+
+	return order
+}
+
+// Paid returns amount paid for line, that is its total along with its tax
+// unless included.
+func (l OrderLine) Paid(taxInclusive bool) int {
+	if taxInclusive {
+		return l.LineTotal
+	}
+	return l.LineTotal + l.Tax
+}
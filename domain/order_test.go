@@ -0,0 +1,74 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+func TestOrderTransition(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+		want bool
+	}{
+		{domain.OrderStatusPending, domain.OrderStatusPaid, true},
+		{domain.OrderStatusPaid, domain.OrderStatusFulfilling, true},
+		{domain.OrderStatusFulfilling, domain.OrderStatusShipped, true},
+		{domain.OrderStatusShipped, domain.OrderStatusDelivered, true},
+		{domain.OrderStatusDelivered, domain.OrderStatusRefunded, true},
+		{domain.OrderStatusPending, domain.OrderStatusShipped, false},
+		{domain.OrderStatusFulfilling, domain.OrderStatusCancelled, false},
+		{domain.OrderStatusCancelled, domain.OrderStatusPaid, false},
+		{domain.OrderStatusRefunded, domain.OrderStatusPending, false},
+	}
+
+	for _, tt := range tests {
+		order := domain.Order{ID: 1, Status: tt.from}
+
+		change, err := order.Transition(tt.to, 2, "")
+		if got := err == nil; got != tt.want {
+			t.Errorf("%s -> %s: expected allowed %v, got: %v", tt.from, tt.to, tt.want, err)
+			continue
+		}
+
+		if !tt.want {
+			if order.Status != tt.from {
+				t.Errorf("%s -> %s: status changed on rejected transition", tt.from, tt.to)
+			}
+			continue
+		}
+
+		if order.Status != tt.to || change.FromStatus != tt.from || *change.ChangedBy != 2 {
+			t.Errorf("%s -> %s: unexpected change %#v", tt.from, tt.to, change)
+		}
+	}
+}
+
+func TestOrderCanCancel(t *testing.T) {
+	for status, want := range map[string]bool{
+		domain.OrderStatusPending:    true,
+		domain.OrderStatusPaid:       true,
+		domain.OrderStatusFulfilling: false,
+		domain.OrderStatusShipped:    false,
+	} {
+		if got := (domain.Order{Status: status}).CanCancel(); got != want {
+			t.Errorf("%s: expected %v, got: %v", status, want, got)
+		}
+	}
+}
+
+func TestOrderFilterValidate(t *testing.T) {
+	for sort, valid := range map[string]bool{
+		"":                 true,
+		"created_at":       true,
+		"total":            true,
+		"id; DROP TABLE x": false,
+		"user_id":          false,
+	} {
+		err := domain.OrderFilter{Sort: sort}.Validate()
+		if (err == nil) != valid {
+			t.Errorf("%q: expected valid %v, got: %v", sort, valid, err)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNoPaymentsFound           = errors.New("no payments found")
+	ErrUnknownPaymentProvider    = errors.New("unknown payment provider")
+	ErrOrderNotPayable           = errors.New("order is not awaiting payment")
+	ErrPaymentDeclined           = errors.New("payment declined by provider")
+	ErrInvalidPaymentState       = errors.New("payment is not in a state allowing this operation")
+	ErrInvalidWebhookSignature   = errors.New("invalid webhook signature")
+	ErrDuplicatedPaymentEvent    = errors.New("payment event already processed")
+	ErrPaymentProviderConnection = errors.New("failed to connect to payment provider")
+	ErrPaymentInProgress         = errors.New("order has a payment in progress")
+
+	errPaymentAmount = errors.New("amount must be greater than zero")
+)
+
+// PaymentCurrency is the currency of payments, prices are in its minor unit.
+const PaymentCurrency = "usd"
+
+// Payment statuses, intents start as pending until customer authorizes
+// them.
+const (
+	PaymentStatusPending    = "pending"
+	PaymentStatusAuthorized = "authorized"
+	PaymentStatusSucceeded  = "succeeded"
+	PaymentStatusFailed     = "failed"
+	PaymentStatusCancelled  = "cancelled"
+	PaymentStatusRefunded   = "refunded"
+)
+
+// Payment event types reported by gateways through webhooks.
+const (
+	PaymentEventAuthorized = "payment.authorized"
+	PaymentEventSucceeded  = "payment.succeeded"
+	PaymentEventFailed     = "payment.failed"
+	PaymentEventCancelled  = "payment.cancelled"
+	PaymentEventRefunded   = "payment.refunded"
+)
+
+// PaymentGateway represents a payment provider, intents are authorized by
+// customer and captured separately.
+type PaymentGateway interface {
+	CreateIntent(ctx context.Context, input PaymentIntentCreate) (PaymentIntent, error)
+	Capture(ctx context.Context, intentID string, amount int) (PaymentIntent, error)
+	Void(ctx context.Context, intentID string) (PaymentIntent, error)
+	Refund(ctx context.Context, intentID string, amount int) (PaymentIntent, error)
+
+	// SignatureHeader returns name of the header holding signature of
+	// webhook requests.
+	SignatureHeader() string
+	// ParseEvent verifies signature of a webhook payload and returns its
+	// event, ErrInvalidWebhookSignature is returned on mismatch.
+	ParseEvent(payload []byte, signature string) (PaymentEvent, error)
+}
+
+// WrapPayment wraps payments for user representation.
+type WrapPayment struct {
+	Payment Payment `json:"payment"`
+}
+
+// WrapPaymentList wraps list of payments for user representation.
+type WrapPaymentList struct {
+	Payments []Payment `json:"payments"`
+}
+
+// Payment represents payments model, amounts are in minor unit of
+// currency.
+type Payment struct {
+	ID           int       `json:"id"`
+	OrderID      int       `json:"order_id" db:"order_id"`
+	Provider     string    `json:"provider"`
+	IntentID     string    `json:"intent_id" db:"intent_id"`
+	Status       string    `json:"status"`
+	Amount       int       `json:"amount"`
+	Captured     int       `json:"captured"`
+	Refunded     int       `json:"refunded"`
+	Currency     string    `json:"currency"`
+	ClientSecret string    `json:"client_secret,omitempty" db:"-"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// PaymentIntentCreate represents input of gateways to create intents.
+type PaymentIntentCreate struct {
+	OrderID  int
+	Amount   int
+	Currency string
+}
+
+// PaymentIntent represents state of an intent at payment provider.
+type PaymentIntent struct {
+	ID           string
+	Status       string
+	Amount       int
+	Captured     int
+	Refunded     int
+	ClientSecret string
+}
+
+// PaymentEvent represents an event received from payment provider, amount
+// is the captured amount of succeeded events and the total refunded amount
+// of refunded events.
+type PaymentEvent struct {
+	ID       string
+	Type     string
+	IntentID string
+	Amount   int
+}
+
+// PaymentAmount represents payments model for capture and refund requests,
+// zero amount means the whole payment.
+type PaymentAmount struct {
+	Amount int `json:"amount"`
+}
+
+// PaymentFilter represents filters passed to List.
+type PaymentFilter struct {
+	ID      int `json:"id"`
+	OrderID int `json:"order_id"`
+
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+	Sort   string `json:"sort"`
+}
+
+// PaymentService represents a service for managing payments.
+type PaymentService interface {
+	GetByID(ctx context.Context, ID int) (Payment, error)
+	List(ctx context.Context, filter PaymentFilter) ([]Payment, error)
+
+	// Create records a payment and moves its order to awaiting payment.
+	Create(ctx context.Context, payment *Payment) error
+	// Sync updates payment from state of its intent and moves its order
+	// accordingly.
+	Sync(ctx context.Context, ID int, intent PaymentIntent) (Payment, error)
+	// HandleEvent applies a webhook event once, ErrDuplicatedPaymentEvent
+	// is returned for events already processed.
+	HandleEvent(ctx context.Context, provider string, event PaymentEvent) error
+}
+
+// Validate validates capture and refund requests model.
+func (p PaymentAmount) Validate() error {
+	if p.Amount < 0 {
+		return errPaymentAmount
+	}
+	return nil
+}
+
+// NewPayment returns a payment of order for an intent created at provider.
+func NewPayment(order Order, provider string, intent PaymentIntent) Payment {
+	return Payment{
+		OrderID:      order.ID,
+		Provider:     provider,
+		IntentID:     intent.ID,
+		Status:       intent.Status,
+		Amount:       intent.Amount,
+		Captured:     intent.Captured,
+		Refunded:     intent.Refunded,
+		Currency:     PaymentCurrency,
+		ClientSecret: intent.ClientSecret,
+	}
+}
+
+// CanPay reports whether a payment may be started for order.
+func (o Order) CanPay() bool {
+	return o.Status == OrderStatusPending || o.Status == OrderStatusAwaitingPayment
+}
+
+// ApplyIntent updates payment from state of its intent.
+func (p *Payment) ApplyIntent(intent PaymentIntent) {
+	p.Status = intent.Status
+	p.Captured = intent.Captured
+	p.Refunded = intent.Refunded
+}
+
+// ApplyEvent updates payment from a webhook event, unknown events leave
+// payment untouched.
+func (p *Payment) ApplyEvent(event PaymentEvent) {
+	switch event.Type {
+	case PaymentEventAuthorized:
+		p.Status = PaymentStatusAuthorized
+	case PaymentEventSucceeded:
+		p.Status = PaymentStatusSucceeded
+		p.Captured = event.Amount
+	case PaymentEventFailed:
+		p.Status = PaymentStatusFailed
+	case PaymentEventCancelled:
+		p.Status = PaymentStatusCancelled
+	case PaymentEventRefunded:
+		p.Refunded = event.Amount
+		if p.Refunded >= p.Captured {
+			p.Status = PaymentStatusRefunded
+		}
+	}
+}
+
+// Open reports whether payment is started and may still be completed.
+func (p Payment) Open() bool {
+	return p.Status == PaymentStatusPending || p.Status == PaymentStatusAuthorized
+}
+
+// OrderStatus returns status order of payment should move to, empty when
+// payment does not affect its order.
+func (p Payment) OrderStatus() string {
+	switch p.Status {
+	case PaymentStatusSucceeded:
+		return OrderStatusPaid
+	case PaymentStatusCancelled:
+		return OrderStatusCancelled
+	case PaymentStatusRefunded:
+		return OrderStatusRefunded
+	}
+	return ""
+}
+
+// RefundablePayment returns first captured payment having amount left to
+// refund.
+func RefundablePayment(payments []Payment, amount int) (Payment, error) {
+	for _, p := range payments {
+		if p.Status == PaymentStatusSucceeded && p.Captured-p.Refunded >= amount {
+			return p, nil
+		}
+	}
+	return Payment{}, ErrNoRefundablePayment
+}
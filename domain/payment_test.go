@@ -0,0 +1,39 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+func TestPaymentApplyEvent(t *testing.T) {
+	payment := domain.Payment{Status: domain.PaymentStatusPending, Amount: 100}
+
+	steps := []struct {
+		event  domain.PaymentEvent
+		status string
+		order  string
+	}{
+		{domain.PaymentEvent{Type: domain.PaymentEventAuthorized}, domain.PaymentStatusAuthorized, ""},
+		{domain.PaymentEvent{Type: "unknown"}, domain.PaymentStatusAuthorized, ""},
+		{domain.PaymentEvent{Type: domain.PaymentEventSucceeded, Amount: 100}, domain.PaymentStatusSucceeded, domain.OrderStatusPaid},
+		{domain.PaymentEvent{Type: domain.PaymentEventRefunded, Amount: 40}, domain.PaymentStatusSucceeded, domain.OrderStatusPaid},
+		{domain.PaymentEvent{Type: domain.PaymentEventRefunded, Amount: 100}, domain.PaymentStatusRefunded, domain.OrderStatusRefunded},
+	}
+
+	for i, step := range steps {
+		payment.ApplyEvent(step.event)
+
+		if payment.Status != step.status {
+			t.Errorf("step %d: expected status %q, got: %q", i, step.status, payment.Status)
+		}
+
+		if got := payment.OrderStatus(); got != step.order {
+			t.Errorf("step %d: expected order status %q, got: %q", i, step.order, got)
+		}
+	}
+
+	if payment.Captured != 100 || payment.Refunded != 100 {
+		t.Errorf("expected captured and refunded of %d, got: %#v", 100, payment)
+	}
+}
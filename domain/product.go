@@ -9,15 +9,27 @@ import (
 var (
 	ErrInvalidProductCategory = errors.New("invalid category")
 	ErrDuplicatedProduct      = errors.New("duplicated product")
+	ErrDuplicatedSKU          = errors.New("duplicated sku")
 	ErrNoProductsFound        = errors.New("products not found")
 	ErrProductConflict        = errors.New("update conflict error")
 
 	errProductNameRequired        = errors.New("name is required")
 	errProductDescriptionRequired = errors.New("description is required")
 	errQuantityRequired           = errors.New("quantity is required")
+	errQuantityNegative           = errors.New("quantity must not be negative")
 	errPriceRequired              = errors.New("price is required")
 	errCategoryIDRequired         = errors.New("CategoryID is required")
 	errVersionRequired            = errors.New("version is required")
+	errInvalidProductType         = errors.New("invalid product type")
+	errInvalidReorderThreshold    = errors.New("reorder_threshold must not be negative")
+	errDimensionNegative          = errors.New("weight and dimensions must not be negative")
+)
+
+// Product types.
+const (
+	ProductTypeSimple  = "simple"
+	ProductTypeBundle  = "bundle"
+	ProductTypeDigital = "digital"
 )
 
 // WrapProduct wraps products for user representation.
@@ -33,6 +45,7 @@ type WrapProductList struct {
 // Product represents products model.
 type Product struct {
 	ID          int       `json:"id"`
+	SKU         string    `json:"sku"`
 	Name        string    `json:"name"`
 	Description string    `json:"description"`
 	CategoryID  int       `json:"category_id" db:"category_id"`
@@ -41,25 +54,80 @@ type Product struct {
 	CreatedAt   time.Time `json:"-" db:"created_at"`
 	UpdatedAt   time.Time `json:"-" db:"updated_at"`
 	Version     int       `json:"version"`
+	Locale      string    `json:"locale" db:"-"`
+
+	Type           string            `json:"type"`
+	BundlePricing  string            `json:"bundle_pricing,omitempty" db:"bundle_pricing"`
+	BundleDiscount int               `json:"bundle_discount,omitempty" db:"bundle_discount"`
+	Components     []BundleComponent `json:"components,omitempty" db:"-"`
+
+	Available        int    `json:"available" db:"-"`
+	StockStatus      string `json:"stock_status" db:"-"`
+	ReorderThreshold int    `json:"reorder_threshold" db:"reorder_threshold"`
+	TaxCategory      string `json:"tax_category" db:"tax_category"`
+
+	// Weight is in grams and dimensions in millimetres.
+	Weight int `json:"weight"`
+	Length int `json:"length"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	InventoryPolicy  string     `json:"inventory_policy" db:"inventory_policy"`
+	BackorderLimit   int        `json:"backorder_limit,omitempty" db:"backorder_limit"`
+	ExpectedShipDate *time.Time `json:"expected_ship_date,omitempty" db:"expected_ship_date"`
 }
 
 // ProductCreate represents products model for POST requests.
 type ProductCreate struct {
+	SKU         string `json:"sku"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
 	CategoryID  int    `json:"category_id"`
 	Price       int    `json:"price"`
 	Quantity    int    `json:"quantity"`
+
+	ReorderThreshold int    `json:"reorder_threshold"`
+	TaxCategory      string `json:"tax_category"`
+
+	Weight int `json:"weight"`
+	Length int `json:"length"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	InventoryPolicy  string     `json:"inventory_policy"`
+	BackorderLimit   int        `json:"backorder_limit"`
+	ExpectedShipDate *time.Time `json:"expected_ship_date"`
+
+	Type           string            `json:"type"`
+	BundlePricing  string            `json:"bundle_pricing"`
+	BundleDiscount int               `json:"bundle_discount"`
+	Components     []BundleComponent `json:"components"`
 }
 
 // ProductUpdate represents products model for PATCH requests.
 type ProductUpdate struct {
+	SKU         *string `json:"sku"`
 	Name        *string `json:"name"`
 	Description *string `json:"description"`
 	CategoryID  *int    `json:"category_id"`
 	Price       *int    `json:"price"`
 	Quantity    *int    `json:"quantity"`
 	Version     int     `json:"version"`
+
+	ReorderThreshold *int    `json:"reorder_threshold"`
+	TaxCategory      *string `json:"tax_category"`
+
+	Weight *int `json:"weight"`
+	Length *int `json:"length"`
+	Width  *int `json:"width"`
+	Height *int `json:"height"`
+
+	InventoryPolicy  *string    `json:"inventory_policy"`
+	BackorderLimit   *int       `json:"backorder_limit"`
+	ExpectedShipDate *time.Time `json:"expected_ship_date"`
+
+	BundlePricing  *string `json:"bundle_pricing"`
+	BundleDiscount *int    `json:"bundle_discount"`
 }
 
 // ProductFilter represents filters passed to List.
@@ -88,25 +156,95 @@ func (p ProductCreate) Validate() error {
 		return errProductNameRequired
 	case p.Description == "":
 		return errProductDescriptionRequired
-	case p.Quantity == 0:
-		return errQuantityRequired
-	case p.Price == 0:
-		return errPriceRequired
 	case p.CategoryID == 0:
 		return errCategoryIDRequired
+	case p.ReorderThreshold < 0:
+		return errInvalidReorderThreshold
+	case p.Weight < 0 || p.Length < 0 || p.Width < 0 || p.Height < 0:
+		return errDimensionNegative
+	case p.Type != "" && p.Type != ProductTypeSimple &&
+		p.Type != ProductTypeBundle && p.Type != ProductTypeDigital:
+		return errInvalidProductType
 	}
-	return nil
+
+	err := validateInventoryPolicy(p.InventoryPolicy, p.BackorderLimit, p.ExpectedShipDate != nil,
+		p.Type == ProductTypeBundle)
+	if err != nil {
+		return err
+	}
+
+	if p.Type != ProductTypeBundle {
+		switch {
+		case p.Quantity == 0 && !(Product{InventoryPolicy: p.InventoryPolicy}).SellsBeyondStock():
+			return errQuantityRequired
+		case p.Quantity < 0:
+			return errQuantityNegative
+		case p.Price == 0:
+			return errPriceRequired
+		case len(p.Components) != 0:
+			return errComponentsOnlyForBundle
+		}
+		return nil
+	}
+
+	switch {
+	case p.BundlePricing != "" && p.BundlePricing != BundlePricingFixed &&
+		p.BundlePricing != BundlePricingDerived:
+		return errInvalidBundlePricing
+	case p.BundlePricing != BundlePricingDerived && p.Price == 0:
+		return errPriceRequired
+	case p.BundleDiscount < 0 || p.BundleDiscount > 100:
+		return errInvalidBundleDiscount
+	}
+
+	return validateComponents(p.Components)
 }
 
 // CreateModel set input values to a new struct and return a new instance.
 func (p ProductCreate) CreateModel() Product {
-	return Product{
+	product := Product{
+		SKU:         p.SKU,
 		Name:        p.Name,
 		Description: p.Description,
 		CategoryID:  p.CategoryID,
 		Price:       p.Price,
 		Quantity:    p.Quantity,
+
+		ReorderThreshold: p.ReorderThreshold,
+		TaxCategory:      p.TaxCategory,
+		Weight:           p.Weight,
+		Length:           p.Length,
+		Width:            p.Width,
+		Height:           p.Height,
+		InventoryPolicy:  p.InventoryPolicy,
+		BackorderLimit:   p.BackorderLimit,
+		ExpectedShipDate: p.ExpectedShipDate,
+	}
+
+	if product.TaxCategory == "" {
+		product.TaxCategory = DefaultTaxCategory
+	}
+
+	if product.InventoryPolicy == "" {
+		product.InventoryPolicy = InventoryPolicyDeny
+	}
+
+	product.Type = p.Type
+	if product.Type == "" {
+		product.Type = ProductTypeSimple
+	}
+
+	if p.Type == ProductTypeBundle {
+		product.Quantity = 0
+		product.BundlePricing = BundlePricingFixed
+		if p.BundlePricing != "" {
+			product.BundlePricing = p.BundlePricing
+		}
+		product.BundleDiscount = p.BundleDiscount
+		product.Components = p.Components
 	}
+
+	return product
 }
 
 // Validate validates PATCH requests model.
@@ -118,18 +256,40 @@ func (p ProductUpdate) Validate() error {
 		return errProductDescriptionRequired
 	case p.Quantity != nil && *p.Quantity == 0:
 		return errQuantityRequired
+	case p.Quantity != nil && *p.Quantity < 0:
+		return errQuantityNegative
 	case p.Price != nil && *p.Price == 0:
 		return errPriceRequired
 	case p.CategoryID != nil && *p.CategoryID == 0:
 		return errCategoryIDRequired
+	case p.ReorderThreshold != nil && *p.ReorderThreshold < 0:
+		return errInvalidReorderThreshold
+	case p.TaxCategory != nil && *p.TaxCategory == "":
+		return errTaxCategoryRequired
+	case negative(p.Weight) || negative(p.Length) || negative(p.Width) || negative(p.Height):
+		return errDimensionNegative
+	case p.InventoryPolicy != nil && *p.InventoryPolicy != InventoryPolicyDeny &&
+		*p.InventoryPolicy != InventoryPolicyBackorder && *p.InventoryPolicy != InventoryPolicyPreorder:
+		return errInvalidInventoryPolicy
+	case p.BackorderLimit != nil && *p.BackorderLimit < 0:
+		return errBackorderLimit
 	case p.Version == 0:
 		return errVersionRequired
+	case p.BundlePricing != nil && *p.BundlePricing != BundlePricingFixed &&
+		*p.BundlePricing != BundlePricingDerived:
+		return errInvalidBundlePricing
+	case p.BundleDiscount != nil && (*p.BundleDiscount < 0 || *p.BundleDiscount > 100):
+		return errInvalidBundleDiscount
 	}
 	return nil
 }
 
 // UpdateModel checks whether products input are not nil and set values.
 func (p ProductUpdate) UpdateModel(product *Product) {
+	if p.SKU != nil {
+		product.SKU = *p.SKU
+	}
+
 	if p.Name != nil {
 		product.Name = *p.Name
 	}
@@ -150,5 +310,59 @@ func (p ProductUpdate) UpdateModel(product *Product) {
 		product.Quantity = *p.Quantity
 	}
 
+	if p.ReorderThreshold != nil {
+		product.ReorderThreshold = *p.ReorderThreshold
+	}
+
+	if p.TaxCategory != nil {
+		product.TaxCategory = *p.TaxCategory
+	}
+
+	if p.Weight != nil {
+		product.Weight = *p.Weight
+	}
+
+	if p.Length != nil {
+		product.Length = *p.Length
+	}
+
+	if p.Width != nil {
+		product.Width = *p.Width
+	}
+
+	if p.Height != nil {
+		product.Height = *p.Height
+	}
+
+	if p.InventoryPolicy != nil {
+		product.InventoryPolicy = *p.InventoryPolicy
+	}
+
+	if p.BackorderLimit != nil {
+		product.BackorderLimit = *p.BackorderLimit
+	}
+
+	if p.ExpectedShipDate != nil {
+		product.ExpectedShipDate = p.ExpectedShipDate
+	}
+
+	if p.BundlePricing != nil {
+		product.BundlePricing = *p.BundlePricing
+	}
+
+	if p.BundleDiscount != nil {
+		product.BundleDiscount = *p.BundleDiscount
+	}
+
 	product.Version = p.Version
 }
+
+// Volume returns volume of product in cubic centimetres.
+func (p Product) Volume() int {
+	return p.Length * p.Width * p.Height / 1000
+}
+
+// negative reports whether an optional value is given and negative.
+func negative(v *int) bool {
+	return v != nil && *v < 0
+}
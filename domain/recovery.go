@@ -0,0 +1,166 @@
+package domain
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoCartRecoveriesFound = errors.New("no cart recoveries found")
+	ErrInvalidRecoveryLink   = errors.New("invalid recovery link")
+	ErrRecoveryLinkExpired   = errors.New("recovery link expired")
+)
+
+const (
+	// DefaultCartAbandonAfter is how long a cart of user is left alone
+	// before it is abandoned.
+	DefaultCartAbandonAfter = 24 * time.Hour
+
+	// CartRecoveryWindow is how long recovery links stay valid, orders
+	// placed within it are attributed to the recovery email.
+	CartRecoveryWindow = 7 * 24 * time.Hour
+)
+
+// WrapCartRecoveryStats wraps cart recovery stats for user representation.
+type WrapCartRecoveryStats struct {
+	Stats CartRecoveryStats `json:"stats"`
+}
+
+// CartRecovery represents a recovery email sent for an abandoned cart,
+// items are kept as they were so the cart can be restored after it is
+// emptied. Restored is when its link was used and converted is when its
+// cart was checked out.
+type CartRecovery struct {
+	ID          int                `json:"id"`
+	CartID      *int               `json:"cart_id" db:"cart_id"`
+	UserID      int                `json:"user_id" db:"user_id"`
+	Email       string             `json:"email"`
+	Items       []CartRecoveryItem `json:"items"`
+	Total       int                `json:"total"`
+	SentAt      time.Time          `json:"sent_at" db:"sent_at"`
+	RestoredAt  *time.Time         `json:"restored_at" db:"restored_at"`
+	OrderID     *int               `json:"order_id" db:"order_id"`
+	OrderTotal  int                `json:"order_total" db:"order_total"`
+	ConvertedAt *time.Time         `json:"converted_at" db:"converted_at"`
+}
+
+// CartRecoveryItem represents a line of an abandoned cart.
+type CartRecoveryItem struct {
+	ProductID int    `json:"product_id"`
+	Name      string `json:"name"`
+	Quantity  int    `json:"quantity"`
+}
+
+// CartRecoveryStats represents how recovery emails sent in a period
+// performed, rates are fractions of sent emails and revenue is total of
+// converted orders.
+type CartRecoveryStats struct {
+	From           *time.Time `json:"from"`
+	To             *time.Time `json:"to"`
+	Sent           int        `json:"sent"`
+	Restored       int        `json:"restored"`
+	Converted      int        `json:"converted"`
+	Revenue        int        `json:"revenue"`
+	RestoreRate    float64    `json:"restore_rate"`
+	ConversionRate float64    `json:"conversion_rate"`
+}
+
+// CartRecoveryService represents a service for recovering abandoned carts.
+type CartRecoveryService interface {
+	// DetectAbandoned records up to limit carts of users left alone since
+	// before and queues their recovery emails with links signed by secret,
+	// a cart is emailed once until it changes. It returns number of emails
+	// queued.
+	DetectAbandoned(ctx context.Context, before time.Time, secret []byte, limit int) (int, error)
+	// Restore puts items of recovery back into cart of its user, items
+	// still in cart keep the larger quantity.
+	Restore(ctx context.Context, ID int) (Cart, error)
+	// Stats reports recovery emails sent between from and to, nil bounds
+	// are open.
+	Stats(ctx context.Context, from *time.Time, to *time.Time) (CartRecoveryStats, error)
+}
+
+// NewCartRecovery returns recovery of a cart of user reached at email.
+func NewCartRecovery(cart Cart, email string) CartRecovery {
+	cartID := cart.ID
+	recovery := CartRecovery{
+		CartID: &cartID,
+		UserID: cart.UserID,
+		Email:  email,
+		Items:  make([]CartRecoveryItem, 0, len(cart.Items)),
+		Total:  cart.Totals.GrandTotal,
+	}
+
+	for _, item := range cart.Items {
+		recovery.Items = append(recovery.Items, CartRecoveryItem{
+			ProductID: item.ProductID,
+			Name:      item.Name,
+			Quantity:  item.Quantity,
+		})
+	}
+
+	return recovery
+}
+
+// SignCartRecovery returns signature of a recovery link valid until expiry.
+func SignCartRecovery(secret []byte, recoveryID int, expiry time.Time) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "recovery:%d:%d", recoveryID, expiry.Unix())
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyCartRecovery checks signature and expiry of a recovery link, all
+// links are rejected without a secret.
+func VerifyCartRecovery(secret []byte, recoveryID int, expiry time.Time, signature string) error {
+	expected := SignCartRecovery(secret, recoveryID, expiry)
+	if len(secret) == 0 || !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidRecoveryLink
+	}
+
+	if time.Now().After(expiry) {
+		return ErrRecoveryLinkExpired
+	}
+
+	return nil
+}
+
+// CartRecoveryLink returns link restoring cart of recovery signed by
+// secret, it stays valid for CartRecoveryWindow since now.
+func CartRecoveryLink(secret []byte, recoveryID int, now time.Time) string {
+	expiry := now.Add(CartRecoveryWindow).Truncate(time.Second)
+	return fmt.Sprintf("/carts/recoveries/%d/restore?expires=%d&signature=%s",
+		recoveryID, expiry.Unix(), SignCartRecovery(secret, recoveryID, expiry))
+}
+
+// CartRecoveryNotification returns recovery email of an abandoned cart.
+func CartRecoveryNotification(recovery CartRecovery, link string) Notification {
+	names := make([]string, 0, len(recovery.Items))
+	for _, item := range recovery.Items {
+		names = append(names, fmt.Sprintf("%d x %s", item.Quantity, item.Name))
+	}
+
+	return Notification{
+		Kind:      NotificationCartRecovery,
+		Recipient: recovery.Email,
+		Subject:   "You left items in your cart",
+		Body: fmt.Sprintf("Your cart still holds %s. Pick up where you left off at %s.",
+			strings.Join(names, ", "), link),
+	}
+}
+
+// CalculateRates computes rates of stats from its counts.
+func (s *CartRecoveryStats) CalculateRates() {
+	s.RestoreRate, s.ConversionRate = 0, 0
+	if s.Sent == 0 {
+		return
+	}
+
+	s.RestoreRate = float64(s.Restored) / float64(s.Sent)
+	s.ConversionRate = float64(s.Converted) / float64(s.Sent)
+}
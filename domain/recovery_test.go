@@ -0,0 +1,93 @@
+package domain_test
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+func TestCartRecoveryLink(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Now()
+
+	link := domain.CartRecoveryLink(secret, 7, now)
+	if !strings.HasPrefix(link, "/carts/recoveries/7/restore?") {
+		t.Fatalf("expected link restoring recovery 7, got: %s", link)
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expires, err := strconv.ParseInt(u.Query().Get("expires"), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiry := time.Unix(expires, 0)
+	signature := u.Query().Get("signature")
+
+	tests := []struct {
+		name      string
+		secret    []byte
+		ID        int
+		expiry    time.Time
+		signature string
+		err       error
+	}{
+		{"valid", secret, 7, expiry, signature, nil},
+		{"other recovery", secret, 8, expiry, signature, domain.ErrInvalidRecoveryLink},
+		{"other secret", []byte("other"), 7, expiry, signature, domain.ErrInvalidRecoveryLink},
+		{"extended expiry", secret, 7, expiry.Add(time.Hour), signature, domain.ErrInvalidRecoveryLink},
+		{"expired", secret, 7, now.Add(-time.Hour), domain.SignCartRecovery(secret, 7, now.Add(-time.Hour)), domain.ErrRecoveryLinkExpired},
+		{"no secret", nil, 7, expiry, domain.SignCartRecovery(nil, 7, expiry), domain.ErrInvalidRecoveryLink},
+	}
+
+	for _, tt := range tests {
+		err := domain.VerifyCartRecovery(tt.secret, tt.ID, tt.expiry, tt.signature)
+		if err != tt.err {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+func TestNewCartRecovery(t *testing.T) {
+	cart := domain.Cart{
+		ID:     3,
+		UserID: 1,
+		Items: []domain.CartItem{
+			{ProductID: 1, Name: "Book", Quantity: 2},
+			{ProductID: 2, Name: "Pen", Quantity: 1},
+		},
+		Totals: domain.CartTotals{GrandTotal: 2500},
+	}
+
+	recovery := domain.NewCartRecovery(cart, "john@example.com")
+	if recovery.CartID == nil || *recovery.CartID != 3 || recovery.Total != 2500 || len(recovery.Items) != 2 {
+		t.Errorf("expected recovery of cart, got: %#v", recovery)
+	}
+
+	notification := domain.CartRecoveryNotification(recovery, "/link")
+	if notification.Recipient != "john@example.com" || !strings.Contains(notification.Body, "2 x Book, 1 x Pen") ||
+		!strings.Contains(notification.Body, "/link") {
+		t.Errorf("expected recovery email listing items with link, got: %#v", notification)
+	}
+}
+
+func TestCartRecoveryStatsCalculateRates(t *testing.T) {
+	stats := domain.CartRecoveryStats{}
+	stats.CalculateRates()
+	if stats.RestoreRate != 0 || stats.ConversionRate != 0 {
+		t.Errorf("expected zero rates without emails, got: %#v", stats)
+	}
+
+	stats = domain.CartRecoveryStats{Sent: 4, Restored: 2, Converted: 1}
+	stats.CalculateRates()
+	if stats.RestoreRate != 0.5 || stats.ConversionRate != 0.25 {
+		t.Errorf("expected rates 0.5 and 0.25, got: %#v", stats)
+	}
+}
@@ -0,0 +1,306 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNoReturnsFound          = errors.New("no returns found")
+	ErrOrderNotReturnable      = errors.New("only delivered orders can be returned")
+	ErrInvalidReturnTransition = errors.New("return status transition not allowed")
+	ErrInvalidReturnQuantity   = errors.New("return quantity exceeds purchased quantity")
+	ErrRefundExceedsLine       = errors.New("refund exceeds amount paid for line")
+	ErrNothingToRefund         = errors.New("nothing to refund")
+	ErrNoRefundablePayment     = errors.New("order has no refundable payment")
+	ErrDuplicatedReturnLine    = errors.New("order line is returned more than once")
+
+	errReasonRequired      = errors.New("reason is required")
+	errReturnLinesRequired = errors.New("lines are required")
+	errOrderLineIDRequired = errors.New("order_line_id is required")
+	errRefundAmount        = errors.New("refund amount must not be negative")
+)
+
+// Return statuses, returns are requested by customers and handled by
+// admins.
+const (
+	ReturnStatusRequested = "requested"
+	ReturnStatusApproved  = "approved"
+	ReturnStatusRejected  = "rejected"
+	ReturnStatusReceived  = "received"
+	ReturnStatusRefunding = "refunding"
+	ReturnStatusRefunded  = "refunded"
+)
+
+// returnTransitions holds statuses a return is allowed to move to from
+// each status.
+var returnTransitions = map[string][]string{
+	ReturnStatusRequested: {ReturnStatusApproved, ReturnStatusRejected},
+	ReturnStatusApproved:  {ReturnStatusReceived, ReturnStatusRejected},
+	ReturnStatusReceived:  {ReturnStatusRefunding},
+	ReturnStatusRefunding: {ReturnStatusRefunded, ReturnStatusReceived},
+	ReturnStatusRejected:  {},
+	ReturnStatusRefunded:  {},
+}
+
+// WrapReturn wraps returns for user representation.
+type WrapReturn struct {
+	Return Return `json:"return"`
+}
+
+// WrapReturnList wraps list of returns for user representation.
+type WrapReturnList struct {
+	Returns []Return `json:"returns"`
+}
+
+// Return represents returns (RMA) model of order lines.
+type Return struct {
+	ID        int          `json:"id"`
+	OrderID   int          `json:"order_id" db:"order_id"`
+	RMANumber string       `json:"rma_number" db:"rma_number"`
+	Status    string       `json:"status"`
+	Reason    string       `json:"reason"`
+	Note      string       `json:"note"`
+	Restocked bool         `json:"restocked"`
+	Refunded  int          `json:"refunded"`
+	CreatedAt time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at" db:"updated_at"`
+	Lines     []ReturnLine `json:"lines" db:"-"`
+}
+
+// ReturnLine represents a returned quantity of an order line.
+type ReturnLine struct {
+	ID          int `json:"id"`
+	ReturnID    int `json:"-" db:"return_id"`
+	OrderLineID int `json:"order_line_id" db:"order_line_id"`
+	Quantity    int `json:"quantity"`
+	Refunded    int `json:"refunded"`
+}
+
+// ReturnCreate represents returns model for POST requests.
+type ReturnCreate struct {
+	Reason string             `json:"reason"`
+	Lines  []ReturnLineCreate `json:"lines"`
+}
+
+// ReturnLineCreate represents return lines model for POST requests.
+type ReturnLineCreate struct {
+	OrderLineID int `json:"order_line_id"`
+	Quantity    int `json:"quantity"`
+}
+
+// ReturnStatusUpdate represents returns model for approve and reject
+// requests.
+type ReturnStatusUpdate struct {
+	Note string `json:"note"`
+}
+
+// ReturnReceive represents returns model for receive requests.
+type ReturnReceive struct {
+	Restock bool `json:"restock"`
+}
+
+// ReturnRefund represents returns model for refund requests, no lines
+// means a full refund of every line.
+type ReturnRefund struct {
+	Lines []ReturnLineRefund `json:"lines"`
+
+	// StoreCredit refunds to store credit of user instead of payment,
+	// payments made with gift cards or store credit are always refunded
+	// so.
+	StoreCredit bool `json:"store_credit"`
+}
+
+// ReturnLineRefund represents refund of a return line.
+type ReturnLineRefund struct {
+	ReturnLineID int `json:"return_line_id"`
+	Amount       int `json:"amount"`
+}
+
+// ReturnSettlement represents how refund of a return is paid out, it is
+// recorded along with the refund. Amount is credited to store credit of
+// user when StoreCredit is set, otherwise payment is synced with Intent
+// refunded at payment gateway. No payment means money was moved already
+// and is left to webhooks of payment provider.
+type ReturnSettlement struct {
+	PaymentID   int
+	Amount      int
+	StoreCredit bool
+	Intent      PaymentIntent
+	Note        string
+}
+
+// ReturnFilter represents filters passed to List.
+type ReturnFilter struct {
+	ID      int `json:"id"`
+	OrderID int `json:"order_id"`
+
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+	Sort   string `json:"sort"`
+}
+
+// ReturnService represents a service for managing returns.
+type ReturnService interface {
+	GetByID(ctx context.Context, ID int) (Return, error)
+	List(ctx context.Context, filter ReturnFilter) ([]Return, error)
+
+	// Create requests a return of delivered order lines, a RMA number is
+	// assigned to it.
+	Create(ctx context.Context, rma *Return) error
+	// UpdateStatus approves or rejects a return.
+	UpdateStatus(ctx context.Context, ID int, status string, note string) (Return, error)
+	// Receive marks items of a return as received, they are optionally
+	// given back to inventory.
+	Receive(ctx context.Context, ID int, restock bool) (Return, error)
+	// StartRefund claims a received return for refunding, a return is only
+	// refunded by whoever claimed it.
+	StartRefund(ctx context.Context, ID int) (Return, error)
+	// CancelRefund gives a claimed return back to received when its refund
+	// failed before money was moved.
+	CancelRefund(ctx context.Context, ID int) (Return, error)
+	// Refund records refunded amounts by return line id of a claimed return
+	// and its settlement in a single transaction.
+	Refund(ctx context.Context, ID int, amounts map[int]int, settlement ReturnSettlement) (Return, error)
+}
+
+// Validate validates POST requests model.
+func (r ReturnCreate) Validate() error {
+	switch {
+	case r.Reason == "":
+		return errReasonRequired
+	case len(r.Lines) == 0:
+		return errReturnLinesRequired
+	}
+
+	seen := make(map[int]bool, len(r.Lines))
+	for _, line := range r.Lines {
+		switch {
+		case line.OrderLineID == 0:
+			return errOrderLineIDRequired
+		case line.Quantity <= 0:
+			return errQuantityRequired
+		case seen[line.OrderLineID]:
+			return ErrDuplicatedReturnLine
+		}
+		seen[line.OrderLineID] = true
+	}
+
+	return nil
+}
+
+// CreateModel set input values to a new struct and return a new instance.
+func (r ReturnCreate) CreateModel(orderID int) Return {
+	rma := Return{
+		OrderID: orderID,
+		Status:  ReturnStatusRequested,
+		Reason:  r.Reason,
+		Lines:   make([]ReturnLine, 0, len(r.Lines)),
+	}
+
+	for _, line := range r.Lines {
+		rma.Lines = append(rma.Lines, ReturnLine{
+			OrderLineID: line.OrderLineID,
+			Quantity:    line.Quantity,
+		})
+	}
+
+	return rma
+}
+
+// Validate validates refund requests model.
+func (r ReturnRefund) Validate() error {
+	for _, line := range r.Lines {
+		if line.Amount < 0 {
+			return errRefundAmount
+		}
+	}
+	return nil
+}
+
+// CanReturn checks lines of a return against order, only delivered orders
+// are returnable and lines may not exceed quantities not returned yet.
+func (o Order) CanReturn(rma Return) error {
+	if o.Status != OrderStatusDelivered {
+		return ErrOrderNotReturnable
+	}
+
+	lines := make(map[int]OrderLine, len(o.Lines))
+	for _, line := range o.Lines {
+		lines[line.ID] = line
+	}
+
+	for _, line := range rma.Lines {
+		orderLine, ok := lines[line.OrderLineID]
+		if !ok || line.Quantity > orderLine.Quantity-orderLine.Returned {
+			return ErrInvalidReturnQuantity
+		}
+	}
+
+	return nil
+}
+
+// Transition moves return to status.
+func (r *Return) Transition(to string) error {
+	for _, status := range returnTransitions[r.Status] {
+		if status == to {
+			r.Status = to
+			return nil
+		}
+	}
+	return ErrInvalidReturnTransition
+}
+
+// RefundAmounts returns refunded amount of each return line by its id and
+// their total. A line may be refunded up to its paid price times returned
+// quantity, and never beyond what is left unrefunded of its order line.
+// Return must be claimed for refunding.
+func (r Return) RefundAmounts(order Order, input ReturnRefund) (map[int]int, int, error) {
+	if r.Status != ReturnStatusRefunding {
+		return nil, 0, ErrInvalidReturnTransition
+	}
+
+	orderLines := make(map[int]OrderLine, len(order.Lines))
+	for _, line := range order.Lines {
+		orderLines[line.ID] = line
+	}
+
+	limits := make(map[int]int, len(r.Lines))
+	for _, line := range r.Lines {
+		orderLine := orderLines[line.OrderLineID]
+
+		paid := orderLine.Paid(order.TaxInclusive)
+		limit := paid * line.Quantity / orderLine.Quantity
+		if left := paid - orderLine.Refunded; left < limit {
+			limit = left
+		}
+		limits[line.ID] = limit
+	}
+
+	amounts := make(map[int]int, len(r.Lines))
+	if len(input.Lines) == 0 {
+		for ID, limit := range limits {
+			amounts[ID] = limit
+		}
+	}
+
+	for _, line := range input.Lines {
+		limit, ok := limits[line.ReturnLineID]
+		if !ok || line.Amount > limit {
+			return nil, 0, ErrRefundExceedsLine
+		}
+		amounts[line.ReturnLineID] = line.Amount
+	}
+
+	total := 0
+	for _, amount := range amounts {
+		total += amount
+	}
+
+	if total <= 0 {
+		return nil, 0, ErrNothingToRefund
+	}
+
+	return amounts, total, nil
+}
@@ -0,0 +1,56 @@
+package domain_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+func TestReturnRefundAmounts(t *testing.T) {
+	order := domain.Order{
+		Status: domain.OrderStatusDelivered,
+		Lines: []domain.OrderLine{
+			{ID: 1, UnitPrice: 10, Quantity: 3, LineTotal: 30, Returned: 2},
+			{ID: 2, UnitPrice: 25, Quantity: 1, LineTotal: 25, Refunded: 20},
+		},
+	}
+
+	rma := domain.Return{
+		Status: domain.ReturnStatusRefunding,
+		Lines: []domain.ReturnLine{
+			{ID: 5, OrderLineID: 1, Quantity: 2},
+			{ID: 6, OrderLineID: 2, Quantity: 1},
+		},
+	}
+
+	amounts, total, err := rma.RefundAmounts(order, domain.ReturnRefund{})
+	if err != nil {
+		t.Fatalf("RefundAmounts: %v", err)
+	}
+
+	if want := map[int]int{5: 20, 6: 5}; !reflect.DeepEqual(amounts, want) || total != 25 {
+		t.Errorf("mismatch\n got: %v (%d)\nwant: %v (%d)", amounts, total, want, 25)
+	}
+
+	partial := domain.ReturnRefund{Lines: []domain.ReturnLineRefund{{ReturnLineID: 5, Amount: 15}}}
+	amounts, total, err = rma.RefundAmounts(order, partial)
+	if err != nil {
+		t.Fatalf("RefundAmounts: %v", err)
+	}
+
+	if want := map[int]int{5: 15}; !reflect.DeepEqual(amounts, want) || total != 15 {
+		t.Errorf("mismatch\n got: %v (%d)\nwant: %v (%d)", amounts, total, want, 15)
+	}
+
+	exceeding := domain.ReturnRefund{Lines: []domain.ReturnLineRefund{{ReturnLineID: 6, Amount: 6}}}
+	_, _, err = rma.RefundAmounts(order, exceeding)
+	if err != domain.ErrRefundExceedsLine {
+		t.Errorf("expected %q from RefundAmounts, got %q", domain.ErrRefundExceedsLine, err)
+	}
+
+	err = order.CanReturn(domain.Return{Lines: []domain.ReturnLine{{OrderLineID: 1, Quantity: 2}}})
+	if err != domain.ErrInvalidReturnQuantity {
+		t.Errorf("expected %q from CanReturn, got %q", domain.ErrInvalidReturnQuantity, err)
+	}
+}
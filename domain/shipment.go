@@ -0,0 +1,406 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNoShipmentsFound          = errors.New("no shipments found")
+	ErrOrderNotFulfillable       = errors.New("order is not ready for fulfillment")
+	ErrInvalidShipmentQuantity   = errors.New("shipment quantity exceeds quantity left to ship")
+	ErrInvalidShipmentTransition = errors.New("shipment status transition not allowed")
+	ErrDuplicatedShipmentLine    = errors.New("order line is shipped more than once")
+	ErrDuplicatedTrackingNumber  = errors.New("tracking number already exists for carrier")
+	ErrDuplicatedTrackingEvent   = errors.New("tracking event already processed")
+	ErrUnknownCarrier            = errors.New("unknown carrier")
+
+	errCarrierRequired       = errors.New("carrier is required")
+	errShipmentLinesRequired = errors.New("lines are required")
+	errInvalidShipmentStatus = errors.New("invalid shipment status")
+)
+
+// Shipment statuses, shipments are pending until carrier picks them up.
+const (
+	ShipmentStatusPending        = "pending"
+	ShipmentStatusInTransit      = "in_transit"
+	ShipmentStatusOutForDelivery = "out_for_delivery"
+	ShipmentStatusDelivered      = "delivered"
+	ShipmentStatusException      = "exception"
+	ShipmentStatusCancelled      = "cancelled"
+)
+
+// shipmentTransitions holds statuses a shipment is allowed to move to from
+// each status, carriers may skip statuses of a shipment.
+var shipmentTransitions = map[string][]string{
+	ShipmentStatusPending: {
+		ShipmentStatusInTransit, ShipmentStatusOutForDelivery, ShipmentStatusDelivered,
+		ShipmentStatusException, ShipmentStatusCancelled,
+	},
+	ShipmentStatusInTransit:      {ShipmentStatusOutForDelivery, ShipmentStatusDelivered, ShipmentStatusException},
+	ShipmentStatusOutForDelivery: {ShipmentStatusInTransit, ShipmentStatusDelivered, ShipmentStatusException},
+	ShipmentStatusException:      {ShipmentStatusInTransit, ShipmentStatusOutForDelivery, ShipmentStatusDelivered},
+	ShipmentStatusDelivered:      {},
+	ShipmentStatusCancelled:      {},
+}
+
+// fulfillmentStatuses holds order statuses driven by shipments in order of
+// progress.
+var fulfillmentStatuses = []string{
+	OrderStatusPaid,
+	OrderStatusFulfilling,
+	OrderStatusPartiallyShipped,
+	OrderStatusShipped,
+	OrderStatusDelivered,
+}
+
+// CarrierTracker represents a carrier reporting progress of shipments
+// through webhooks.
+type CarrierTracker interface {
+	// SignatureHeader returns name of the header holding signature of
+	// webhook requests.
+	SignatureHeader() string
+	// ParseEvent verifies signature of a webhook payload and returns its
+	// event, ErrInvalidWebhookSignature is returned on mismatch.
+	ParseEvent(payload []byte, signature string) (TrackingEvent, error)
+}
+
+// WrapShipment wraps shipments for user representation.
+type WrapShipment struct {
+	Shipment Shipment `json:"shipment"`
+}
+
+// WrapShipmentList wraps list of shipments for user representation.
+type WrapShipmentList struct {
+	Shipments []Shipment `json:"shipments"`
+}
+
+// Shipment represents shipments model, a package holding lines of an
+// order.
+type Shipment struct {
+	ID             int             `json:"id"`
+	OrderID        int             `json:"order_id" db:"order_id"`
+	Carrier        string          `json:"carrier"`
+	TrackingNumber string          `json:"tracking_number" db:"tracking_number"`
+	Status         string          `json:"status"`
+	ShippedAt      *time.Time      `json:"shipped_at" db:"shipped_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at" db:"delivered_at"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at" db:"updated_at"`
+	Lines          []ShipmentLine  `json:"lines" db:"-"`
+	Events         []ShipmentEvent `json:"events" db:"-"`
+}
+
+// ShipmentLine represents a shipped quantity of an order line.
+type ShipmentLine struct {
+	ID          int `json:"id"`
+	ShipmentID  int `json:"-" db:"shipment_id"`
+	OrderLineID int `json:"order_line_id" db:"order_line_id"`
+	Quantity    int `json:"quantity"`
+}
+
+// ShipmentEvent represents a record of shipment tracking history, events
+// not reported by carrier have no event id.
+type ShipmentEvent struct {
+	ID          int       `json:"id"`
+	ShipmentID  int       `json:"-" db:"shipment_id"`
+	Carrier     string    `json:"-"`
+	EventID     *string   `json:"-" db:"event_id"`
+	Status      string    `json:"status"`
+	Description string    `json:"description"`
+	OccurredAt  time.Time `json:"occurred_at" db:"occurred_at"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// TrackingEvent represents an event received from carrier.
+type TrackingEvent struct {
+	ID             string
+	TrackingNumber string
+	Status         string
+	Description    string
+	OccurredAt     time.Time
+}
+
+// ShipmentCreate represents shipments model for POST requests.
+type ShipmentCreate struct {
+	Carrier        string               `json:"carrier"`
+	TrackingNumber string               `json:"tracking_number"`
+	Lines          []ShipmentLineCreate `json:"lines"`
+}
+
+// ShipmentLineCreate represents shipment lines model for POST requests.
+type ShipmentLineCreate struct {
+	OrderLineID int `json:"order_line_id"`
+	Quantity    int `json:"quantity"`
+}
+
+// ShipmentUpdate represents shipments model for PATCH requests.
+type ShipmentUpdate struct {
+	Carrier        *string `json:"carrier"`
+	TrackingNumber *string `json:"tracking_number"`
+}
+
+// ShipmentStatusUpdate represents shipments model for status change
+// requests.
+type ShipmentStatusUpdate struct {
+	Status      string `json:"status"`
+	Description string `json:"description"`
+}
+
+// ShipmentFilter represents filters passed to List.
+type ShipmentFilter struct {
+	ID      int `json:"id"`
+	OrderID int `json:"order_id"`
+
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+	Sort   string `json:"sort"`
+}
+
+// ShipmentService represents a service for managing shipments, status of
+// orders follows progress of their shipments.
+type ShipmentService interface {
+	GetByID(ctx context.Context, ID int) (Shipment, error)
+	List(ctx context.Context, filter ShipmentFilter) ([]Shipment, error)
+
+	// Create creates a shipment of order lines, lines are checked against
+	// quantities of order left to ship.
+	Create(ctx context.Context, shipment *Shipment) error
+	Update(ctx context.Context, ID int, input ShipmentUpdate) (Shipment, error)
+	// UpdateStatus moves a shipment to status and records it in tracking
+	// history, cancelled shipments free their quantities to ship again.
+	UpdateStatus(ctx context.Context, ID int, input ShipmentStatusUpdate, changedBy int) (Shipment, error)
+	// HandleTrackingEvent applies a carrier event once,
+	// ErrDuplicatedTrackingEvent is returned for events already processed.
+	HandleTrackingEvent(ctx context.Context, carrier string, event TrackingEvent) error
+}
+
+// Validate validates POST requests model.
+func (s ShipmentCreate) Validate() error {
+	switch {
+	case s.Carrier == "":
+		return errCarrierRequired
+	case len(s.Lines) == 0:
+		return errShipmentLinesRequired
+	}
+
+	seen := make(map[int]bool, len(s.Lines))
+	for _, line := range s.Lines {
+		switch {
+		case line.OrderLineID == 0:
+			return errOrderLineIDRequired
+		case line.Quantity <= 0:
+			return errQuantityRequired
+		case seen[line.OrderLineID]:
+			return ErrDuplicatedShipmentLine
+		}
+		seen[line.OrderLineID] = true
+	}
+
+	return nil
+}
+
+// CreateModel set input values to a new struct and return a new instance.
+func (s ShipmentCreate) CreateModel(orderID int) Shipment {
+	shipment := Shipment{
+		OrderID:        orderID,
+		Carrier:        s.Carrier,
+		TrackingNumber: s.TrackingNumber,
+		Status:         ShipmentStatusPending,
+		Lines:          make([]ShipmentLine, 0, len(s.Lines)),
+	}
+
+	for _, line := range s.Lines {
+		shipment.Lines = append(shipment.Lines, ShipmentLine{
+			OrderLineID: line.OrderLineID,
+			Quantity:    line.Quantity,
+		})
+	}
+
+	return shipment
+}
+
+// Validate validates PATCH requests model.
+func (s ShipmentUpdate) Validate() error {
+	if s.Carrier != nil && *s.Carrier == "" {
+		return errCarrierRequired
+	}
+	return nil
+}
+
+// UpdateModel checks whether shipments input are not nil and set values.
+func (s ShipmentUpdate) UpdateModel(shipment *Shipment) {
+	if s.Carrier != nil {
+		shipment.Carrier = *s.Carrier
+	}
+
+	if s.TrackingNumber != nil {
+		shipment.TrackingNumber = *s.TrackingNumber
+	}
+}
+
+// Validate validates status change requests model.
+func (s ShipmentStatusUpdate) Validate() error {
+	if _, ok := shipmentTransitions[s.Status]; !ok {
+		return errInvalidShipmentStatus
+	}
+	return nil
+}
+
+// CanShip checks lines of a shipment against order, lines may not exceed
+// quantities neither shipped nor waiting for stock.
+func (o Order) CanShip(shipment Shipment) error {
+	switch o.Status {
+	case OrderStatusPaid, OrderStatusFulfilling, OrderStatusPartiallyShipped:
+	default:
+		return ErrOrderNotFulfillable
+	}
+
+	lines := make(map[int]OrderLine, len(o.Lines))
+	for _, line := range o.Lines {
+		lines[line.ID] = line
+	}
+
+	for _, line := range shipment.Lines {
+		orderLine, ok := lines[line.OrderLineID]
+		if !ok || !orderLine.Shippable ||
+			line.Quantity > orderLine.Quantity-orderLine.Backordered-orderLine.Shipped {
+			return ErrInvalidShipmentQuantity
+		}
+	}
+
+	return nil
+}
+
+// Transition moves shipment to status and returns record of the change,
+// moving to the current status only records the event. The record is
+// returned along ErrInvalidShipmentTransition too, so carrier events out of
+// order are kept in tracking history.
+func (s *Shipment) Transition(to string, description string, at time.Time) (ShipmentEvent, error) {
+	event := ShipmentEvent{
+		ShipmentID:  s.ID,
+		Carrier:     s.Carrier,
+		Status:      to,
+		Description: description,
+		OccurredAt:  at,
+	}
+
+	if to == s.Status {
+		return event, nil
+	}
+
+	for _, status := range shipmentTransitions[s.Status] {
+		if status != to {
+			continue
+		}
+
+		if s.ShippedAt == nil && to != ShipmentStatusCancelled {
+			s.ShippedAt = &at
+		}
+		if to == ShipmentStatusDelivered {
+			s.DeliveredAt = &at
+		}
+
+		s.Status = to
+		return event, nil
+	}
+
+	return event, ErrInvalidShipmentTransition
+}
+
+// Shipped reports whether shipment left to carrier.
+func (s Shipment) Shipped() bool {
+	return s.Status != ShipmentStatusPending && s.Status != ShipmentStatusCancelled
+}
+
+// FulfillmentStatus returns status order should be in by progress of its
+// shipments, empty when shipments do not affect the order.
+func (o Order) FulfillmentStatus(shipments []Shipment) string {
+	shipped := make(map[int]int)
+	delivered := make(map[int]int)
+	active := false
+
+	for _, shipment := range shipments {
+		if shipment.Status == ShipmentStatusCancelled {
+			continue
+		}
+		active = true
+
+		for _, line := range shipment.Lines {
+			if shipment.Shipped() {
+				shipped[line.OrderLineID] += line.Quantity
+			}
+			if shipment.Status == ShipmentStatusDelivered {
+				delivered[line.OrderLineID] += line.Quantity
+			}
+		}
+	}
+
+	if !active {
+		return ""
+	}
+
+	allShipped, allDelivered, anyShipped := true, true, false
+	for _, line := range o.Lines {
+		if !line.Shippable {
+			continue
+		}
+
+		if shipped[line.ID] > 0 {
+			anyShipped = true
+		}
+		if shipped[line.ID] < line.Quantity {
+			allShipped = false
+		}
+		if delivered[line.ID] < line.Quantity {
+			allDelivered = false
+		}
+	}
+
+	switch {
+	case allDelivered:
+		return OrderStatusDelivered
+	case allShipped:
+		return OrderStatusShipped
+	case anyShipped:
+		return OrderStatusPartiallyShipped
+	}
+	return OrderStatusFulfilling
+}
+
+// FulfillmentPath returns statuses order moves through from one status to
+// another by allowed transitions, orders do not move back and orders out of
+// fulfillment do not move at all.
+func FulfillmentPath(from string, to string) []string {
+	rank := func(status string) int {
+		for i, s := range fulfillmentStatuses {
+			if s == status {
+				return i
+			}
+		}
+		return -1
+	}
+
+	current, target := rank(from), rank(to)
+	if current < 0 || target <= current {
+		return nil
+	}
+
+	var path []string
+	for current < target {
+		// furthest status reachable from current one is taken.
+		next := current + 1
+		for i := target; i > current; i-- {
+			if CanTransition(fulfillmentStatuses[current], fulfillmentStatuses[i]) {
+				next = i
+				break
+			}
+		}
+
+		path = append(path, fulfillmentStatuses[next])
+		current = next
+	}
+
+	return path
+}
@@ -0,0 +1,150 @@
+package domain_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+func TestOrderCanShip(t *testing.T) {
+	order := domain.Order{Status: domain.OrderStatusPaid, Lines: []domain.OrderLine{
+		{ID: 1, Quantity: 3, Shipped: 1, Shippable: true},
+		{ID: 2, Quantity: 2, Backordered: 2, Shippable: true},
+		{ID: 3, Quantity: 1},
+	}}
+
+	tests := []struct {
+		name  string
+		lines []domain.ShipmentLine
+		err   error
+	}{
+		{"rest of line", []domain.ShipmentLine{{OrderLineID: 1, Quantity: 2}}, nil},
+		{"beyond rest of line", []domain.ShipmentLine{{OrderLineID: 1, Quantity: 3}}, domain.ErrInvalidShipmentQuantity},
+		{"backordered", []domain.ShipmentLine{{OrderLineID: 2, Quantity: 1}}, domain.ErrInvalidShipmentQuantity},
+		{"not shippable", []domain.ShipmentLine{{OrderLineID: 3, Quantity: 1}}, domain.ErrInvalidShipmentQuantity},
+		{"other order", []domain.ShipmentLine{{OrderLineID: 4, Quantity: 1}}, domain.ErrInvalidShipmentQuantity},
+	}
+
+	for _, tt := range tests {
+		err := order.CanShip(domain.Shipment{Lines: tt.lines})
+		if err != tt.err {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+
+	order.Status = domain.OrderStatusPending
+	err := order.CanShip(domain.Shipment{Lines: []domain.ShipmentLine{{OrderLineID: 1, Quantity: 1}}})
+	if err != domain.ErrOrderNotFulfillable {
+		t.Errorf("expected %q, got %q", domain.ErrOrderNotFulfillable, err)
+	}
+}
+
+func TestShipmentTransition(t *testing.T) {
+	at := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	shipment := domain.Shipment{ID: 1, Carrier: "mock", Status: domain.ShipmentStatusPending}
+
+	event, err := shipment.Transition(domain.ShipmentStatusDelivered, "left at door", at)
+	if err != nil {
+		t.Fatalf("Transition: %v", err)
+	}
+
+	if shipment.ShippedAt == nil || shipment.DeliveredAt == nil || !shipment.DeliveredAt.Equal(at) {
+		t.Errorf("expected shipped and delivered at %v, got: %v, %v", at, shipment.ShippedAt, shipment.DeliveredAt)
+	}
+
+	if event.ShipmentID != 1 || event.Status != domain.ShipmentStatusDelivered || event.Description != "left at door" {
+		t.Errorf("unexpected event: %#v", event)
+	}
+
+	event, err = shipment.Transition(domain.ShipmentStatusInTransit, "late scan", at)
+	if err != domain.ErrInvalidShipmentTransition {
+		t.Errorf("expected %q, got %q", domain.ErrInvalidShipmentTransition, err)
+	}
+
+	if shipment.Status != domain.ShipmentStatusDelivered || event.Status != domain.ShipmentStatusInTransit {
+		t.Errorf("expected delivered shipment and in transit event, got: %s, %s", shipment.Status, event.Status)
+	}
+
+	_, err = shipment.Transition(domain.ShipmentStatusDelivered, "again", at)
+	if err != nil {
+		t.Errorf("expected same status to be recorded, got %q", err)
+	}
+}
+
+func TestOrderFulfillmentStatus(t *testing.T) {
+	order := domain.Order{Lines: []domain.OrderLine{
+		{ID: 1, Quantity: 2, Shippable: true},
+		{ID: 2, Quantity: 1, Shippable: true},
+		{ID: 3, Quantity: 1},
+	}}
+
+	first := domain.Shipment{Lines: []domain.ShipmentLine{{OrderLineID: 1, Quantity: 2}}}
+	second := domain.Shipment{Lines: []domain.ShipmentLine{{OrderLineID: 2, Quantity: 1}}}
+
+	shipment := func(s domain.Shipment, status string) domain.Shipment {
+		s.Status = status
+		return s
+	}
+
+	tests := []struct {
+		name      string
+		shipments []domain.Shipment
+		status    string
+	}{
+		{"no shipments", nil, ""},
+		{"cancelled", []domain.Shipment{shipment(first, domain.ShipmentStatusCancelled)}, ""},
+		{"pending", []domain.Shipment{shipment(first, domain.ShipmentStatusPending)}, domain.OrderStatusFulfilling},
+		{
+			"partially shipped",
+			[]domain.Shipment{shipment(first, domain.ShipmentStatusInTransit), shipment(second, domain.ShipmentStatusPending)},
+			domain.OrderStatusPartiallyShipped,
+		},
+		{
+			"shipped",
+			[]domain.Shipment{shipment(first, domain.ShipmentStatusDelivered), shipment(second, domain.ShipmentStatusException)},
+			domain.OrderStatusShipped,
+		},
+		{
+			"delivered",
+			[]domain.Shipment{shipment(first, domain.ShipmentStatusDelivered), shipment(second, domain.ShipmentStatusDelivered)},
+			domain.OrderStatusDelivered,
+		},
+	}
+
+	for _, tt := range tests {
+		status := order.FulfillmentStatus(tt.shipments)
+		if status != tt.status {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.status, status)
+		}
+	}
+}
+
+func TestFulfillmentPath(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+		path []string
+	}{
+		{domain.OrderStatusPaid, domain.OrderStatusFulfilling, []string{domain.OrderStatusFulfilling}},
+		{
+			domain.OrderStatusPaid, domain.OrderStatusDelivered,
+			[]string{domain.OrderStatusFulfilling, domain.OrderStatusShipped, domain.OrderStatusDelivered},
+		},
+		{
+			domain.OrderStatusPartiallyShipped, domain.OrderStatusDelivered,
+			[]string{domain.OrderStatusShipped, domain.OrderStatusDelivered},
+		},
+		{domain.OrderStatusShipped, domain.OrderStatusFulfilling, nil},
+		{domain.OrderStatusRefunded, domain.OrderStatusShipped, nil},
+		{domain.OrderStatusPaid, "", nil},
+	}
+
+	for _, tt := range tests {
+		path := domain.FulfillmentPath(tt.from, tt.to)
+		if !reflect.DeepEqual(path, tt.path) {
+			t.Errorf("%s to %s: expected %v, got %v", tt.from, tt.to, tt.path, path)
+		}
+	}
+}
@@ -0,0 +1,373 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoShippingZonesFound       = errors.New("no shipping zones found")
+	ErrNoShippingMethodsFound     = errors.New("no shipping methods found")
+	ErrShippingMethodRequired     = errors.New("a shipping method is required")
+	ErrShippingMethodNotAvailable = errors.New("shipping method is not available for cart")
+	ErrShippingQuoteChanged       = errors.New("cart changed since shipping was quoted")
+
+	errShippingZoneNameRequired   = errors.New("name is required")
+	errShippingMethodNameRequired = errors.New("name is required")
+	errInvalidShippingMethodType  = errors.New("invalid shipping method type")
+	errShippingRateNegative       = errors.New("rate must not be negative")
+	errShippingTiersRequired      = errors.New("tiers are required by weight and price methods")
+	errInvalidShippingTiers       = errors.New("tiers must be in ascending order of up_to with only the last one unbounded")
+	errFreeAboveNegative          = errors.New("free_above must not be negative")
+)
+
+// Shipping method types.
+const (
+	ShippingFlat   = "flat"
+	ShippingWeight = "weight"
+	ShippingPrice  = "price"
+)
+
+// WrapShippingZone wraps shipping zones for user representation.
+type WrapShippingZone struct {
+	ShippingZone ShippingZone `json:"shipping_zone"`
+}
+
+// WrapShippingZoneList wraps list of shipping zones for user representation.
+type WrapShippingZoneList struct {
+	ShippingZones []ShippingZone `json:"shipping_zones"`
+}
+
+// WrapShippingMethod wraps shipping methods for user representation.
+type WrapShippingMethod struct {
+	ShippingMethod ShippingMethod `json:"shipping_method"`
+}
+
+// WrapShippingRateList wraps list of shipping rates for user representation.
+type WrapShippingRateList struct {
+	ShippingRates []ShippingRate `json:"shipping_rates"`
+}
+
+// ShippingZone represents shipping zones model, zones match locations
+// the same way tax zones do.
+type ShippingZone struct {
+	ID            int              `json:"id"`
+	Name          string           `json:"name"`
+	Country       string           `json:"country"`
+	Region        string           `json:"region"`
+	PostalPattern string           `json:"postal_pattern" db:"postal_pattern"`
+	Priority      int              `json:"priority"`
+	Methods       []ShippingMethod `json:"methods" db:"-"`
+	CreatedAt     time.Time        `json:"created_at" db:"created_at"`
+}
+
+// ShippingMethod represents shipping methods model. Flat methods cost
+// their rate, weight and price methods cost the first tier covering
+// weight or subtotal of cart and are not available beyond their tiers.
+// Shipping is free once subtotal reaches FreeAbove unless it is zero.
+type ShippingMethod struct {
+	ID        int            `json:"id"`
+	ZoneID    int            `json:"zone_id" db:"zone_id"`
+	Name      string         `json:"name"`
+	Type      string         `json:"type"`
+	Rate      int            `json:"rate"`
+	Tiers     []ShippingTier `json:"tiers"`
+	FreeAbove int            `json:"free_above" db:"free_above"`
+	Active    bool           `json:"active"`
+	CreatedAt time.Time      `json:"created_at" db:"created_at"`
+}
+
+// ShippingTier represents cost of shipping up to a weight in grams or a
+// subtotal, a tier up to zero is unbounded.
+type ShippingTier struct {
+	UpTo int `json:"up_to"`
+	Cost int `json:"cost"`
+}
+
+// ShippingZoneCreate represents shipping zones model for POST requests.
+type ShippingZoneCreate struct {
+	Name          string `json:"name"`
+	Country       string `json:"country"`
+	Region        string `json:"region"`
+	PostalPattern string `json:"postal_pattern"`
+	Priority      int    `json:"priority"`
+}
+
+// ShippingMethodCreate represents shipping methods model for POST requests.
+type ShippingMethodCreate struct {
+	Name      string         `json:"name"`
+	Type      string         `json:"type"`
+	Rate      int            `json:"rate"`
+	Tiers     []ShippingTier `json:"tiers"`
+	FreeAbove int            `json:"free_above"`
+	Active    *bool          `json:"active"`
+}
+
+// ShippingMethodSelect represents model of selecting a shipping method.
+type ShippingMethodSelect struct {
+	ShippingMethodID int `json:"shipping_method_id"`
+}
+
+// ShippingRequest represents what is shipped and where, rates are quoted
+// for it. Weight is in grams and volume in cubic centimetres.
+type ShippingRequest struct {
+	Destination  Location
+	Weight       int
+	Volume       int
+	Subtotal     int
+	FreeShipping bool
+}
+
+// ShippingRate represents cost of shipping by a method.
+type ShippingRate struct {
+	MethodID int    `json:"method_id"`
+	Name     string `json:"name"`
+	Cost     int    `json:"cost"`
+}
+
+// ShippingQuote represents a rate along with the request it was quoted for.
+type ShippingQuote struct {
+	Request ShippingRequest
+	Rate    ShippingRate
+}
+
+// ShippingRateProvider represents a service quoting rates of shipping,
+// rates must be of existing shipping methods so they can be selected.
+type ShippingRateProvider interface {
+	Rates(ctx context.Context, request ShippingRequest) ([]ShippingRate, error)
+}
+
+// ShippingService represents a service for managing shipping zones and
+// their methods, rates are quoted by methods stored.
+type ShippingService interface {
+	CreateZone(ctx context.Context, zone *ShippingZone) error
+	GetZone(ctx context.Context, ID int) (ShippingZone, error)
+	ListZones(ctx context.Context) ([]ShippingZone, error)
+	DeleteZone(ctx context.Context, ID int) error
+
+	CreateMethod(ctx context.Context, method *ShippingMethod) error
+	DeleteMethod(ctx context.Context, ID int) error
+
+	ShippingRateProvider
+}
+
+// Validate validates POST requests model.
+func (s ShippingZoneCreate) Validate() error {
+	switch {
+	case s.Name == "":
+		return errShippingZoneNameRequired
+	case len(strings.TrimSpace(s.Country)) != 2:
+		return errCountryRequired
+	}
+
+	_, err := path.Match(s.PostalPattern, "")
+	if err != nil {
+		return errInvalidPostalPattern
+	}
+
+	return nil
+}
+
+// CreateModel set input values to a new struct and return a new instance.
+func (s ShippingZoneCreate) CreateModel() ShippingZone {
+	location := Location{Country: s.Country, Region: s.Region}.Normalize()
+
+	return ShippingZone{
+		Name:          s.Name,
+		Country:       location.Country,
+		Region:        location.Region,
+		PostalPattern: strings.ToUpper(strings.TrimSpace(s.PostalPattern)),
+		Priority:      s.Priority,
+		Methods:       []ShippingMethod{},
+	}
+}
+
+// Validate validates POST requests model.
+func (s ShippingMethodCreate) Validate() error {
+	switch {
+	case s.Name == "":
+		return errShippingMethodNameRequired
+	case s.Type != ShippingFlat && s.Type != ShippingWeight && s.Type != ShippingPrice:
+		return errInvalidShippingMethodType
+	case s.Rate < 0:
+		return errShippingRateNegative
+	case s.FreeAbove < 0:
+		return errFreeAboveNegative
+	case s.Type != ShippingFlat && len(s.Tiers) == 0:
+		return errShippingTiersRequired
+	}
+
+	for i, tier := range s.Tiers {
+		last := i == len(s.Tiers)-1
+		switch {
+		case tier.Cost < 0:
+			return errShippingRateNegative
+		case tier.UpTo < 0, tier.UpTo == 0 && !last:
+			return errInvalidShippingTiers
+		case i > 0 && tier.UpTo != 0 && tier.UpTo <= s.Tiers[i-1].UpTo:
+			return errInvalidShippingTiers
+		}
+	}
+
+	return nil
+}
+
+// CreateModel set input values to a new struct and return a new instance.
+func (s ShippingMethodCreate) CreateModel(zoneID int) ShippingMethod {
+	method := ShippingMethod{
+		ZoneID:    zoneID,
+		Name:      s.Name,
+		Type:      s.Type,
+		Rate:      s.Rate,
+		Tiers:     s.Tiers,
+		FreeAbove: s.FreeAbove,
+		Active:    true,
+	}
+
+	if s.Active != nil {
+		method.Active = *s.Active
+	}
+
+	if method.Tiers == nil || method.Type == ShippingFlat {
+		method.Tiers = []ShippingTier{}
+	}
+
+	return method
+}
+
+// Matches reports whether location is in zone.
+func (z ShippingZone) Matches(location Location) bool {
+	return location.within(z.Country, z.Region, z.PostalPattern)
+}
+
+// Cost returns cost of shipping request by method, it reports false when
+// method is not available for request.
+func (m ShippingMethod) Cost(request ShippingRequest) (int, bool) {
+	if !m.Active {
+		return 0, false
+	}
+
+	cost := m.Rate
+	if m.Type != ShippingFlat {
+		measure := request.Weight
+		if m.Type == ShippingPrice {
+			measure = request.Subtotal
+		}
+
+		covered := false
+		for _, tier := range m.Tiers {
+			if tier.UpTo == 0 || measure <= tier.UpTo {
+				cost, covered = tier.Cost, true
+				break
+			}
+		}
+
+		if !covered {
+			return 0, false
+		}
+	}
+
+	if request.FreeShipping || m.FreeAbove > 0 && request.Subtotal >= m.FreeAbove {
+		cost = 0
+	}
+
+	return cost, true
+}
+
+// ShippingTable is a local ShippingRateProvider of zones held in memory.
+type ShippingTable struct {
+	zones []ShippingZone
+}
+
+// NewShippingTable returns a new instance of ShippingTable, zones are
+// matched by priority, then by specificity and then by id.
+func NewShippingTable(zones []ShippingZone) ShippingTable {
+	sorted := make([]ShippingZone, len(zones))
+	copy(sorted, zones)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		switch {
+		case a.Priority != b.Priority:
+			return a.Priority > b.Priority
+		case areaSpecificity(a.Region, a.PostalPattern) != areaSpecificity(b.Region, b.PostalPattern):
+			return areaSpecificity(a.Region, a.PostalPattern) > areaSpecificity(b.Region, b.PostalPattern)
+		}
+		return a.ID < b.ID
+	})
+
+	return ShippingTable{zones: sorted}
+}
+
+// Rates returns rates of methods available in zone of destination of
+// request, cheapest first. Destinations out of every zone have no rates.
+func (t ShippingTable) Rates(_ context.Context, request ShippingRequest) ([]ShippingRate, error) {
+	rates := []ShippingRate{}
+
+	for _, zone := range t.zones {
+		if !zone.Matches(request.Destination) {
+			continue
+		}
+
+		for _, method := range zone.Methods {
+			cost, ok := method.Cost(request)
+			if ok {
+				rates = append(rates, ShippingRate{MethodID: method.ID, Name: method.Name, Cost: cost})
+			}
+		}
+		break
+	}
+
+	sort.SliceStable(rates, func(i, j int) bool {
+		return rates[i].Cost < rates[j].Cost
+	})
+
+	return rates, nil
+}
+
+// FindShippingRate returns rate of method among rates.
+func FindShippingRate(rates []ShippingRate, methodID int) (ShippingRate, bool) {
+	for _, rate := range rates {
+		if rate.MethodID == methodID {
+			return rate, true
+		}
+	}
+	return ShippingRate{}, false
+}
+
+// Shippable reports whether cart holds anything to ship, digital products
+// are not shipped.
+func (c Cart) Shippable() bool {
+	for _, item := range c.Items {
+		if item.Shippable {
+			return true
+		}
+	}
+	return false
+}
+
+// ShippingRequest returns request of shipping cart to its destination,
+// subtotal is net of discounts.
+func (c Cart) ShippingRequest() ShippingRequest {
+	request := ShippingRequest{
+		Destination:  c.Destination.Normalize(),
+		Subtotal:     c.Totals.Subtotal - c.Totals.Discount,
+		FreeShipping: c.FreeShipping,
+	}
+
+	for _, item := range c.Items {
+		request.Weight += item.Weight
+		request.Volume += item.Volume
+	}
+
+	return request
+}
+
+// ApplyShipping sets rate as shipping of cart and computes its totals.
+func (c *Cart) ApplyShipping(rate ShippingRate) {
+	c.Shipping = &rate
+	c.CalculateTotals()
+}
@@ -0,0 +1,139 @@
+package domain_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+func TestShippingMethodCost(t *testing.T) {
+	weight := domain.ShippingMethod{Type: domain.ShippingWeight, Active: true, Tiers: []domain.ShippingTier{
+		{UpTo: 1000, Cost: 500},
+		{UpTo: 5000, Cost: 900},
+	}}
+	price := domain.ShippingMethod{Type: domain.ShippingPrice, Active: true, Tiers: []domain.ShippingTier{
+		{UpTo: 2000, Cost: 700},
+		{Cost: 300},
+	}}
+
+	tests := []struct {
+		name    string
+		method  domain.ShippingMethod
+		request domain.ShippingRequest
+		cost    int
+		ok      bool
+	}{
+		{"flat", domain.ShippingMethod{Type: domain.ShippingFlat, Rate: 400, Active: true}, domain.ShippingRequest{}, 400, true},
+		{"inactive", domain.ShippingMethod{Type: domain.ShippingFlat, Rate: 400}, domain.ShippingRequest{}, 0, false},
+		{"first weight tier", weight, domain.ShippingRequest{Weight: 1000}, 500, true},
+		{"second weight tier", weight, domain.ShippingRequest{Weight: 1001}, 900, true},
+		{"beyond weight tiers", weight, domain.ShippingRequest{Weight: 5001}, 0, false},
+		{"unbounded price tier", price, domain.ShippingRequest{Subtotal: 10000}, 300, true},
+		{
+			"free above threshold",
+			domain.ShippingMethod{Type: domain.ShippingFlat, Rate: 400, FreeAbove: 5000, Active: true},
+			domain.ShippingRequest{Subtotal: 5000},
+			0, true,
+		},
+		{"free shipping coupon", weight, domain.ShippingRequest{Weight: 10, FreeShipping: true}, 0, true},
+	}
+
+	for _, tt := range tests {
+		cost, ok := tt.method.Cost(tt.request)
+		if cost != tt.cost || ok != tt.ok {
+			t.Errorf("%s: expected (%d, %t), got (%d, %t)", tt.name, tt.cost, tt.ok, cost, ok)
+		}
+	}
+}
+
+func TestShippingMethodCreateValidate(t *testing.T) {
+	tests := []struct {
+		tiers []domain.ShippingTier
+		valid bool
+	}{
+		{[]domain.ShippingTier{{UpTo: 10, Cost: 1}, {UpTo: 20, Cost: 2}, {Cost: 3}}, true},
+		{[]domain.ShippingTier{{UpTo: 20, Cost: 1}, {UpTo: 10, Cost: 2}}, false},
+		{[]domain.ShippingTier{{Cost: 1}, {UpTo: 10, Cost: 2}}, false},
+		{[]domain.ShippingTier{{UpTo: 10, Cost: -1}}, false},
+		{nil, false},
+	}
+
+	for i, tt := range tests {
+		input := domain.ShippingMethodCreate{Name: "standard", Type: domain.ShippingWeight, Tiers: tt.tiers}
+		if err := input.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%d: expected valid to be %t, got error %v", i, tt.valid, err)
+		}
+	}
+}
+
+func TestShippingTableRates(t *testing.T) {
+	flat := domain.ShippingMethod{ID: 1, Name: "standard", Type: domain.ShippingFlat, Rate: 500, Active: true}
+	express := domain.ShippingMethod{ID: 2, Name: "express", Type: domain.ShippingFlat, Rate: 1500, Active: true}
+	heavy := domain.ShippingMethod{ID: 3, Name: "light", Type: domain.ShippingWeight, Active: true,
+		Tiers: []domain.ShippingTier{{UpTo: 100, Cost: 200}}}
+	local := domain.ShippingMethod{ID: 4, Name: "courier", Type: domain.ShippingFlat, Rate: 300, Active: true}
+
+	table := domain.NewShippingTable([]domain.ShippingZone{
+		{ID: 1, Country: "US", Methods: []domain.ShippingMethod{express, flat, heavy}},
+		{ID: 2, Country: "US", Region: "NY", Methods: []domain.ShippingMethod{local}},
+	})
+
+	tests := []struct {
+		location domain.Location
+		rates    []domain.ShippingRate
+	}{
+		{domain.Location{Country: "us", Region: "ca"}, []domain.ShippingRate{
+			{MethodID: 1, Name: "standard", Cost: 500},
+			{MethodID: 2, Name: "express", Cost: 1500},
+		}},
+		{domain.Location{Country: "US", Region: "NY"}, []domain.ShippingRate{
+			{MethodID: 4, Name: "courier", Cost: 300},
+		}},
+		{domain.Location{Country: "FR"}, []domain.ShippingRate{}},
+	}
+
+	for _, tt := range tests {
+		rates, err := table.Rates(context.Background(), domain.ShippingRequest{Destination: tt.location, Weight: 500})
+		if err != nil {
+			t.Fatalf("Rates: %v", err)
+		}
+
+		if !reflect.DeepEqual(rates, tt.rates) {
+			t.Errorf("%v: mismatch of rates\n got: %v\nwant: %v", tt.location, rates, tt.rates)
+		}
+	}
+}
+
+func TestCartShipping(t *testing.T) {
+	cart := domain.Cart{Items: []domain.CartItem{
+		{ProductID: 1, Quantity: 2},
+		{ProductID: 2, Quantity: 1},
+	}}
+
+	products := map[int]domain.Product{
+		1: {ID: 1, Price: 1000, Available: 10, Type: domain.ProductTypeSimple, Weight: 300, Length: 100, Width: 100, Height: 100},
+		2: {ID: 2, Price: 500, Available: 10, Type: domain.ProductTypeDigital},
+	}
+
+	cart.ApplyProducts(products)
+	if !cart.Shippable() {
+		t.Fatalf("expected cart to be shippable")
+	}
+
+	request := cart.ShippingRequest()
+	if request.Weight != 600 || request.Volume != 2000 || request.Subtotal != 2500 {
+		t.Errorf("unexpected shipping request: %#v", request)
+	}
+
+	cart.ApplyShipping(domain.ShippingRate{MethodID: 1, Cost: 400})
+	if cart.Totals.Shipping != 400 || cart.Totals.GrandTotal != 2900 {
+		t.Errorf("unexpected totals: %#v", cart.Totals)
+	}
+
+	order := domain.NewOrder(cart, products)
+	if order.Shipping != 400 || order.Total != 2900 || order.ShippingMethodID == nil || *order.ShippingMethodID != 1 {
+		t.Errorf("expected shipping on order, got: %#v", order)
+	}
+}
@@ -0,0 +1,74 @@
+package domain
+
+import "time"
+
+// Stock states reported in product and cart responses.
+const (
+	StockAvailable  = "available"
+	StockLow        = "low_stock"
+	StockOutOfStock = "out_of_stock"
+	StockBackorder  = "backorder"
+	StockPreorder   = "preorder"
+)
+
+// LowStockThreshold is the available quantity at or below which a
+// product is reported as low in stock.
+const LowStockThreshold = 5
+
+// ReservationExpiry is lifetime of stock reservations of cart lines, it
+// is extended on every change to the cart.
+const ReservationExpiry = 30 * time.Minute
+
+// StockStatus returns stock state of available quantity.
+func StockStatus(available int) string {
+	switch {
+	case available <= 0:
+		return StockOutOfStock
+	case available <= LowStockThreshold:
+		return StockLow
+	default:
+		return StockAvailable
+	}
+}
+
+// ApplyReservations sets quantity available to sell and stock state of
+// product, reserved is quantity held by carts; bundles have reservations
+// already taken out of their components.
+func (p *Product) ApplyReservations(reserved int) {
+	p.Available = p.Quantity
+	if !p.IsBundle() {
+		p.Available -= reserved
+	}
+
+	if p.Available < 0 {
+		p.Available = 0
+	}
+
+	p.StockStatus = StockStatus(p.Available)
+	if p.Available == 0 {
+		p.StockStatus = p.outOfStockStatus()
+	}
+}
+
+// outOfStockStatus returns stock state of product having no stock on hand
+// to sell.
+func (p Product) outOfStockStatus() string {
+	switch p.InventoryPolicy {
+	case InventoryPolicyBackorder:
+		return StockBackorder
+	case InventoryPolicyPreorder:
+		return StockPreorder
+	}
+	return StockOutOfStock
+}
+
+// BelowReorderThreshold reports whether available quantity crossed down to
+// reorder threshold of a product, products without a threshold never do.
+func BelowReorderThreshold(before int, after int, threshold int) bool {
+	return threshold > 0 && before > threshold && after <= threshold
+}
+
+// BackInStock reports whether available quantity went from none to some.
+func BackInStock(before int, after int) bool {
+	return before <= 0 && after > 0
+}
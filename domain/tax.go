@@ -0,0 +1,355 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoTaxZonesFound = errors.New("no tax zones found")
+
+	errTaxZoneNameRequired   = errors.New("name is required")
+	errCountryRequired       = errors.New("country is required as a two letter code")
+	errInvalidPostalPattern  = errors.New("invalid postal_pattern")
+	errTaxCategoryRequired   = errors.New("tax_category is required")
+	errTaxRateNegative       = errors.New("rate must not be negative")
+	errDuplicatedTaxCategory = errors.New("duplicated tax category")
+)
+
+// DefaultTaxCategory is tax category of products not given one.
+const DefaultTaxCategory = "standard"
+
+// TaxRateBase is the base of tax rates, a rate of 2000 is 20%.
+const TaxRateBase = 10000
+
+// WrapTaxZone wraps tax zones for user representation.
+type WrapTaxZone struct {
+	TaxZone TaxZone `json:"tax_zone"`
+}
+
+// WrapTaxZoneList wraps list of tax zones for user representation.
+type WrapTaxZoneList struct {
+	TaxZones []TaxZone `json:"tax_zones"`
+}
+
+// Location represents where goods are delivered to, taxes and shipping of
+// carts are decided by it.
+type Location struct {
+	Country    string `json:"country"`
+	Region     string `json:"region"`
+	PostalCode string `json:"postal_code" db:"postal_code"`
+}
+
+// TaxZone represents tax zones model, a zone matches locations of its
+// country, and of its region and postal code pattern when given. Pattern
+// is matched as a shell pattern, e.g. "90*". Prices of zones including tax
+// already hold tax of their rates.
+type TaxZone struct {
+	ID               int       `json:"id"`
+	Name             string    `json:"name"`
+	Country          string    `json:"country"`
+	Region           string    `json:"region"`
+	PostalPattern    string    `json:"postal_pattern" db:"postal_pattern"`
+	Priority         int       `json:"priority"`
+	PricesIncludeTax bool      `json:"prices_include_tax" db:"prices_include_tax"`
+	Rates            []TaxRate `json:"rates" db:"-"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+}
+
+// TaxRate represents rate of a tax category in a zone, in TaxRateBase.
+type TaxRate struct {
+	ZoneID      int    `json:"-" db:"zone_id"`
+	TaxCategory string `json:"tax_category" db:"tax_category"`
+	Name        string `json:"name"`
+	Rate        int    `json:"rate"`
+}
+
+// TaxZoneCreate represents tax zones model for POST requests.
+type TaxZoneCreate struct {
+	Name             string    `json:"name"`
+	Country          string    `json:"country"`
+	Region           string    `json:"region"`
+	PostalPattern    string    `json:"postal_pattern"`
+	Priority         int       `json:"priority"`
+	PricesIncludeTax bool      `json:"prices_include_tax"`
+	Rates            []TaxRate `json:"rates"`
+}
+
+// TaxRatesInput represents tax rates model for PUT requests.
+type TaxRatesInput struct {
+	Rates []TaxRate `json:"rates"`
+}
+
+// TaxableLine represents an amount taxed by rate of its tax category.
+type TaxableLine struct {
+	TaxCategory string
+	Amount      int
+}
+
+// LineTax represents tax of a taxable line.
+type LineTax struct {
+	TaxCategory string `json:"tax_category"`
+	Rate        int    `json:"rate"`
+	Amount      int    `json:"amount"`
+}
+
+// TaxResult represents taxes of lines in their order, Inclusive reports
+// whether taxes are included in amounts of lines.
+type TaxResult struct {
+	ZoneID    int
+	Inclusive bool
+	Lines     []LineTax
+	Total     int
+}
+
+// TaxCalculator represents a service calculating taxes of lines delivered
+// to a location.
+type TaxCalculator interface {
+	Calculate(ctx context.Context, location Location, lines []TaxableLine) (TaxResult, error)
+}
+
+// TaxService represents a service for managing tax zones and their rates.
+type TaxService interface {
+	CreateZone(ctx context.Context, zone *TaxZone) error
+	GetZone(ctx context.Context, ID int) (TaxZone, error)
+	ListZones(ctx context.Context) ([]TaxZone, error)
+	DeleteZone(ctx context.Context, ID int) error
+
+	// SetRates replaces rates of a zone.
+	SetRates(ctx context.Context, zoneID int, rates []TaxRate) (TaxZone, error)
+}
+
+// Normalize returns location with country and region in upper case and
+// surrounding spaces removed.
+func (l Location) Normalize() Location {
+	return Location{
+		Country:    strings.ToUpper(strings.TrimSpace(l.Country)),
+		Region:     strings.ToUpper(strings.TrimSpace(l.Region)),
+		PostalCode: strings.ToUpper(strings.TrimSpace(l.PostalCode)),
+	}
+}
+
+// Validate validates location.
+func (l Location) Validate() error {
+	if len(l.Normalize().Country) != 2 {
+		return errCountryRequired
+	}
+	return nil
+}
+
+// within reports whether location is in area of country, and of region
+// and postal code pattern when given.
+func (l Location) within(country string, region string, postalPattern string) bool {
+	l = l.Normalize()
+
+	switch {
+	case country != l.Country:
+		return false
+	case region != "" && region != l.Region:
+		return false
+	case postalPattern != "":
+		ok, _ := path.Match(postalPattern, l.PostalCode)
+		return ok
+	}
+
+	return true
+}
+
+// areaSpecificity returns how narrowly an area is defined, areas of postal
+// codes are more specific than areas of regions.
+func areaSpecificity(region string, postalPattern string) int {
+	specificity := 0
+	if region != "" {
+		specificity++
+	}
+	if postalPattern != "" {
+		specificity += 2
+	}
+	return specificity
+}
+
+// Validate validates POST requests model.
+func (t TaxZoneCreate) Validate() error {
+	switch {
+	case t.Name == "":
+		return errTaxZoneNameRequired
+	case len(strings.TrimSpace(t.Country)) != 2:
+		return errCountryRequired
+	}
+
+	_, err := path.Match(t.PostalPattern, "")
+	if err != nil {
+		return errInvalidPostalPattern
+	}
+
+	return validateTaxRates(t.Rates)
+}
+
+// CreateModel set input values to a new struct and return a new instance.
+func (t TaxZoneCreate) CreateModel() TaxZone {
+	location := Location{Country: t.Country, Region: t.Region}.Normalize()
+
+	zone := TaxZone{
+		Name:             t.Name,
+		Country:          location.Country,
+		Region:           location.Region,
+		PostalPattern:    strings.ToUpper(strings.TrimSpace(t.PostalPattern)),
+		Priority:         t.Priority,
+		PricesIncludeTax: t.PricesIncludeTax,
+		Rates:            t.Rates,
+	}
+
+	if zone.Rates == nil {
+		zone.Rates = []TaxRate{}
+	}
+
+	return zone
+}
+
+// Validate validates PUT requests model.
+func (t TaxRatesInput) Validate() error {
+	return validateTaxRates(t.Rates)
+}
+
+func validateTaxRates(rates []TaxRate) error {
+	seen := make(map[string]bool, len(rates))
+	for _, r := range rates {
+		switch {
+		case r.TaxCategory == "":
+			return errTaxCategoryRequired
+		case r.Rate < 0:
+			return errTaxRateNegative
+		case seen[r.TaxCategory]:
+			return errDuplicatedTaxCategory
+		}
+		seen[r.TaxCategory] = true
+	}
+
+	return nil
+}
+
+// Matches reports whether location is in zone.
+func (z TaxZone) Matches(location Location) bool {
+	return location.within(z.Country, z.Region, z.PostalPattern)
+}
+
+// specificity returns how narrowly zone is defined.
+func (z TaxZone) specificity() int {
+	return areaSpecificity(z.Region, z.PostalPattern)
+}
+
+// rate returns rate of a tax category in zone, categories without a rate
+// are not taxed.
+func (z TaxZone) rate(category string) int {
+	for _, r := range z.Rates {
+		if r.TaxCategory == category {
+			return r.Rate
+		}
+	}
+	return 0
+}
+
+// TaxTable is a TaxCalculator of zones held in memory.
+type TaxTable struct {
+	zones []TaxZone
+}
+
+// NewTaxTable returns a new instance of TaxTable, zones are matched by
+// priority, then by specificity and then by id.
+func NewTaxTable(zones []TaxZone) TaxTable {
+	sorted := make([]TaxZone, len(zones))
+	copy(sorted, zones)
+	sort.Slice(sorted, func(i, j int) bool {
+		switch {
+		case sorted[i].Priority != sorted[j].Priority:
+			return sorted[i].Priority > sorted[j].Priority
+		case sorted[i].specificity() != sorted[j].specificity():
+			return sorted[i].specificity() > sorted[j].specificity()
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	return TaxTable{zones: sorted}
+}
+
+// Zone returns the zone of location.
+func (t TaxTable) Zone(location Location) (TaxZone, bool) {
+	for _, zone := range t.zones {
+		if zone.Matches(location) {
+			return zone, true
+		}
+	}
+	return TaxZone{}, false
+}
+
+// Calculate calculates taxes of lines by rates of zone of location, lines
+// delivered out of every zone are not taxed. Tax of each line is rounded
+// half up.
+func (t TaxTable) Calculate(_ context.Context, location Location, lines []TaxableLine) (TaxResult, error) {
+	result := TaxResult{Lines: make([]LineTax, len(lines))}
+
+	zone, ok := t.Zone(location)
+	if ok {
+		result.ZoneID = zone.ID
+		result.Inclusive = zone.PricesIncludeTax
+	}
+
+	for i, line := range lines {
+		tax := LineTax{TaxCategory: line.TaxCategory}
+		if ok {
+			tax.Rate = zone.rate(line.TaxCategory)
+			tax.Amount = LineTaxAmount(line.Amount, tax.Rate, zone.PricesIncludeTax)
+		}
+
+		result.Lines[i] = tax
+		result.Total += tax.Amount
+	}
+
+	return result, nil
+}
+
+// LineTaxAmount returns tax of amount at rate rounded half up, inclusive
+// amounts already hold their tax.
+func LineTaxAmount(amount int, rate int, inclusive bool) int {
+	if amount <= 0 || rate <= 0 {
+		return 0
+	}
+
+	base := TaxRateBase
+	if inclusive {
+		base += rate
+	}
+
+	return (2*amount*rate + base) / (2 * base)
+}
+
+// TaxableLines returns lines of cart to tax, net of their discounts.
+func (c Cart) TaxableLines(products map[int]Product) []TaxableLine {
+	lines := make([]TaxableLine, 0, len(c.Items))
+	for _, item := range c.Items {
+		category := products[item.ProductID].TaxCategory
+		if category == "" {
+			category = DefaultTaxCategory
+		}
+
+		lines = append(lines, TaxableLine{TaxCategory: category, Amount: item.LineTotal})
+	}
+
+	return lines
+}
+
+// ApplyTax sets taxes of result to lines of cart and computes its totals,
+// result must be of lines returned by TaxableLines.
+func (c *Cart) ApplyTax(result TaxResult) {
+	for i := range c.Items {
+		c.Items[i].TaxRate = result.Lines[i].Rate
+		c.Items[i].Tax = result.Lines[i].Amount
+	}
+
+	c.Totals.TaxInclusive = result.Inclusive
+	c.CalculateTotals()
+}
@@ -0,0 +1,121 @@
+package domain_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+func TestTaxTableZone(t *testing.T) {
+	zones := []domain.TaxZone{
+		{ID: 1, Country: "US"},
+		{ID: 2, Country: "US", Region: "CA"},
+		{ID: 3, Country: "US", Region: "CA", PostalPattern: "90*"},
+		{ID: 4, Country: "DE"},
+		{ID: 5, Country: "DE", PostalPattern: "10*", Priority: -1},
+	}
+
+	tests := []struct {
+		location domain.Location
+		zoneID   int
+	}{
+		{domain.Location{Country: "us", Region: "ny"}, 1},
+		{domain.Location{Country: "US", Region: "CA", PostalCode: "94105"}, 2},
+		{domain.Location{Country: "US", Region: "ca", PostalCode: "90210"}, 3},
+		{domain.Location{Country: "DE", PostalCode: "10115"}, 4},
+		{domain.Location{Country: "FR"}, 0},
+	}
+
+	table := domain.NewTaxTable(zones)
+	for _, tt := range tests {
+		zone, _ := table.Zone(tt.location)
+		if zone.ID != tt.zoneID {
+			t.Errorf("%v: expected zone %d, got %d", tt.location, tt.zoneID, zone.ID)
+		}
+	}
+}
+
+func TestTaxTableCalculate(t *testing.T) {
+	rates := []domain.TaxRate{
+		{TaxCategory: domain.DefaultTaxCategory, Rate: 2000},
+		{TaxCategory: "reduced", Rate: 700},
+	}
+
+	table := domain.NewTaxTable([]domain.TaxZone{
+		{ID: 1, Country: "GB", Rates: rates, PricesIncludeTax: true},
+		{ID: 2, Country: "US", Rates: rates},
+	})
+
+	lines := []domain.TaxableLine{
+		{TaxCategory: domain.DefaultTaxCategory, Amount: 1199},
+		{TaxCategory: "reduced", Amount: 1000},
+		{TaxCategory: "exempt", Amount: 500},
+	}
+
+	tests := []struct {
+		country   string
+		inclusive bool
+		taxes     []int
+	}{
+		{"US", false, []int{240, 70, 0}},
+		{"GB", true, []int{200, 65, 0}},
+		{"FR", false, []int{0, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		result, err := table.Calculate(context.Background(), domain.Location{Country: tt.country}, lines)
+		if err != nil {
+			t.Fatalf("Calculate: %v", err)
+		}
+
+		taxes := []int{}
+		total := 0
+		for _, line := range result.Lines {
+			taxes = append(taxes, line.Amount)
+			total += line.Amount
+		}
+
+		if !reflect.DeepEqual(taxes, tt.taxes) {
+			t.Errorf("%s: mismatch of taxes\n got: %v\nwant: %v", tt.country, taxes, tt.taxes)
+		}
+
+		if result.Inclusive != tt.inclusive || result.Total != total {
+			t.Errorf("%s: unexpected result: %#v", tt.country, result)
+		}
+	}
+}
+
+func TestCartApplyTax(t *testing.T) {
+	table := domain.NewTaxTable([]domain.TaxZone{
+		{ID: 1, Country: "GB", PricesIncludeTax: true, Rates: []domain.TaxRate{{TaxCategory: "standard", Rate: 2000}}},
+		{ID: 2, Country: "US", Rates: []domain.TaxRate{{TaxCategory: "standard", Rate: 1000}}},
+	})
+
+	tests := []struct {
+		country    string
+		tax        int
+		grandTotal int
+	}{
+		{"US", 290, 3190},
+		{"GB", 483, 2900},
+	}
+
+	for _, tt := range tests {
+		cart, products := couponTestCart()
+		cart.ApplyCoupons([]domain.Coupon{{ID: 1, Type: domain.CouponFixed, Value: 200, ProductIDs: []int{2}, Active: true}},
+			products, time.Now())
+
+		result, err := table.Calculate(context.Background(), domain.Location{Country: tt.country}, cart.TaxableLines(products))
+		if err != nil {
+			t.Fatalf("Calculate: %v", err)
+		}
+
+		cart.ApplyTax(result)
+		if cart.Totals.Tax != tt.tax || cart.Totals.GrandTotal != tt.grandTotal {
+			t.Errorf("%s: unexpected totals: %#v", tt.country, cart.Totals)
+		}
+	}
+}
@@ -32,6 +32,8 @@ type Token struct {
 type TokenService interface {
 	Create(ctx context.Context, token Token) error
 	GetUserID(ctx context.Context, hashedToken string) (int, error)
+	// DeleteExpired deletes expired tokens and returns number of them.
+	DeleteExpired(ctx context.Context) (int, error)
 }
 
 // GenerateToken returns generated token.
@@ -0,0 +1,188 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNoTranslationFound = errors.New("no translations found")
+	ErrInvalidLocale      = errors.New("invalid locale")
+	ErrDefaultLocale      = errors.New("content of default locale is stored on resource itself")
+
+	errTranslationNameRequired        = errors.New("name is required")
+	errTranslationDescriptionRequired = errors.New("description is required")
+)
+
+// DefaultLocale is the locale of content stored on products and
+// categories themselves, translations are kept for the other locales.
+const DefaultLocale = "en"
+
+var localeRegexp = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z]{2})?$`)
+
+// WrapTranslation wraps translations for user representation.
+type WrapTranslation struct {
+	Translation Translation `json:"translation"`
+}
+
+// WrapTranslationList wraps list of translations for user representation.
+type WrapTranslationList struct {
+	Translations []Translation `json:"translations"`
+}
+
+// Translation represents localized name and description of a product
+// or category.
+type Translation struct {
+	OwnerID     int    `json:"-" db:"owner_id"`
+	Locale      string `json:"locale"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// TranslationInput represents translations model for PUT requests.
+type TranslationInput struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// TranslationService represents a service for managing translations of
+// a resource.
+type TranslationService interface {
+	List(ctx context.Context, ownerID int) ([]Translation, error)
+	Set(ctx context.Context, ownerID int, translation *Translation) error
+	Delete(ctx context.Context, ownerID int, locale string) error
+	Resolve(ctx context.Context, ownerIDs []int, locales []string) (map[int]Translation, error)
+}
+
+// Validate validates PUT requests model.
+func (t TranslationInput) Validate() error {
+	switch {
+	case t.Name == "":
+		return errTranslationNameRequired
+	case t.Description == "":
+		return errTranslationDescriptionRequired
+	}
+	return nil
+}
+
+// CreateModel set input values to a new struct and return a new instance.
+func (t TranslationInput) CreateModel(locale string) Translation {
+	return Translation{
+		Locale:      locale,
+		Name:        t.Name,
+		Description: t.Description,
+	}
+}
+
+// NormalizeLocale returns locale in its canonical form (e.g. "pt-BR").
+func NormalizeLocale(locale string) (string, error) {
+	locale = strings.ReplaceAll(strings.TrimSpace(locale), "_", "-")
+
+	lang, region, found := strings.Cut(locale, "-")
+	locale = strings.ToLower(lang)
+	if found {
+		locale += "-" + strings.ToUpper(region)
+	}
+
+	if !localeRegexp.MatchString(locale) {
+		return "", ErrInvalidLocale
+	}
+
+	return locale, nil
+}
+
+// ParseAcceptLanguage returns locales of an Accept-Language header
+// ordered by their quality, invalid and wildcard entries are dropped.
+func ParseAcceptLanguage(header string) []string {
+	type entry struct {
+		locale  string
+		quality float64
+	}
+
+	var entries []entry
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			v, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = v
+		}
+
+		locale, err := NormalizeLocale(tag)
+		if err != nil || quality <= 0 {
+			continue
+		}
+
+		entries = append(entries, entry{locale: locale, quality: quality})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].quality > entries[j].quality
+	})
+
+	locales := make([]string, 0, len(entries))
+	for _, e := range entries {
+		locales = append(locales, e.locale)
+	}
+
+	return locales
+}
+
+// LocaleChain returns fallback chain of requested locales, each regional
+// locale falls back to its language before moving to the next one and
+// the chain ends at DefaultLocale.
+func LocaleChain(requested ...string) []string {
+	seen := make(map[string]bool)
+	var chain []string
+
+	add := func(locale string) {
+		if !seen[locale] {
+			seen[locale] = true
+			chain = append(chain, locale)
+		}
+	}
+
+	for _, locale := range requested {
+		add(locale)
+		if lang, _, found := strings.Cut(locale, "-"); found {
+			add(lang)
+		}
+	}
+	add(DefaultLocale)
+
+	return chain
+}
+
+// TranslatedLocales returns locales of chain which need a translation,
+// locales after DefaultLocale are never reached.
+func TranslatedLocales(chain []string) []string {
+	for i, locale := range chain {
+		if locale == DefaultLocale {
+			return chain[:i]
+		}
+	}
+
+	return chain
+}
+
+// Localize replaces name and description of product by translation.
+func (p *Product) Localize(t Translation) {
+	p.Name = t.Name
+	p.Description = t.Description
+	p.Locale = t.Locale
+}
+
+// Localize replaces name and description of category by translation.
+func (c *Category) Localize(t Translation) {
+	c.Name = t.Name
+	c.Description = t.Description
+	c.Locale = t.Locale
+}
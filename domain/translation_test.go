@@ -0,0 +1,41 @@
+package domain_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{"", []string{}},
+		{"fr-ch, fr;q=0.9, en;q=0.8, *;q=0.5", []string{"fr-CH", "fr", "en"}},
+		{"de;q=0.2, pt_br", []string{"pt-BR", "de"}},
+		{"es;q=0, it;q=abc, nl", []string{"nl"}},
+	}
+
+	for _, tt := range tests {
+		got := domain.ParseAcceptLanguage(tt.header)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: mismatch\n got: %#v\nwant: %#v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestLocaleChain(t *testing.T) {
+	got := domain.LocaleChain("fr-CA", "de", "fr")
+	want := []string{"fr-CA", "fr", "de", domain.DefaultLocale}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mismatch\n got: %#v\nwant: %#v", got, want)
+	}
+
+	got = domain.TranslatedLocales(domain.LocaleChain("en-GB", "fr"))
+	want = []string{"en-GB"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mismatch\n got: %#v\nwant: %#v", got, want)
+	}
+}
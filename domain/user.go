@@ -35,6 +35,7 @@ type User struct {
 	ID       int    `json:"id"`
 	Email    string `json:"email"`
 	Password []byte `json:"-" db:"password_hash"`
+	Admin    bool   `json:"admin"`
 }
 
 // UserCreate represents users model for POST requests.
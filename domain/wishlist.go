@@ -0,0 +1,242 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoWishlistsFound         = errors.New("no wishlists found")
+	ErrNoWishlistItemsFound     = errors.New("no wishlist items found")
+	ErrDuplicatedWishlist       = errors.New("wishlist with this name already exists")
+	ErrWishlistInvalidProductID = errors.New("invalid product id wishlist")
+
+	errWishlistNameRequired = errors.New("name is required")
+	errWishlistNameTooLong  = errors.New("name must be at most 100 characters")
+)
+
+// maxWishlistNameLength is maximum length of wishlist names.
+const maxWishlistNameLength = 100
+
+// DefaultWishlistName is name of wishlist created when items are added
+// without one.
+const DefaultWishlistName = "Wishlist"
+
+// WrapWishlist wraps wishlists for user representation.
+type WrapWishlist struct {
+	Wishlist Wishlist `json:"wishlist"`
+}
+
+// WrapWishlistList wraps list of wishlists for user representation.
+type WrapWishlistList struct {
+	Wishlists []Wishlist `json:"wishlists"`
+}
+
+// WrapSharedWishlist wraps shared wishlists for public representation.
+type WrapSharedWishlist struct {
+	Wishlist SharedWishlist `json:"wishlist"`
+}
+
+// Wishlist represents wishlists model, a user keeps any number of named
+// wishlists. Anyone holding share token of a wishlist can read it.
+type Wishlist struct {
+	ID         int            `json:"id"`
+	UserID     int            `json:"-" db:"user_id"`
+	Name       string         `json:"name"`
+	ShareToken *string        `json:"share_token" db:"share_token"`
+	Items      []WishlistItem `json:"items" db:"-"`
+	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// WishlistItem represents a product in wishlist, name, unit price and
+// stock status are taken from current product. Seen price is price of
+// product last seen by owner.
+type WishlistItem struct {
+	ID          int       `json:"id"`
+	WishlistID  int       `json:"-" db:"wishlist_id"`
+	ProductID   int       `json:"product_id" db:"product_id"`
+	Quantity    int       `json:"quantity"`
+	SeenPrice   int       `json:"seen_price" db:"seen_price"`
+	AddedAt     time.Time `json:"added_at" db:"added_at"`
+	Name        string    `json:"name" db:"-"`
+	UnitPrice   int       `json:"unit_price" db:"-"`
+	StockStatus string    `json:"stock_status" db:"-"`
+}
+
+// SharedWishlist represents a wishlist as it is shown to holders of its
+// share token.
+type SharedWishlist struct {
+	Name      string               `json:"name"`
+	Items     []SharedWishlistItem `json:"items"`
+	UpdatedAt time.Time            `json:"updated_at"`
+}
+
+// SharedWishlistItem represents an item of shared wishlist.
+type SharedWishlistItem struct {
+	ProductID   int    `json:"product_id"`
+	Name        string `json:"name"`
+	Quantity    int    `json:"quantity"`
+	UnitPrice   int    `json:"unit_price"`
+	StockStatus string `json:"stock_status"`
+}
+
+// WishlistCreate represents wishlists model for POST requests.
+type WishlistCreate struct {
+	Name string `json:"name"`
+}
+
+// WishlistUpdate represents wishlists model for PATCH requests.
+type WishlistUpdate struct {
+	Name *string `json:"name"`
+}
+
+// WishlistItemCreate represents wishlist items model for POST requests,
+// quantity defaults to one.
+type WishlistItemCreate struct {
+	ProductID int `json:"product_id"`
+	Quantity  int `json:"quantity"`
+}
+
+// WishlistService represents a service for managing wishlists of users,
+// wishlists of other users are not found.
+type WishlistService interface {
+	Create(ctx context.Context, wishlist *Wishlist) error
+	GetByID(ctx context.Context, userID int, ID int) (Wishlist, error)
+	List(ctx context.Context, userID int) ([]Wishlist, error)
+	Update(ctx context.Context, userID int, ID int, input WishlistUpdate) (Wishlist, error)
+	Delete(ctx context.Context, userID int, ID int) error
+
+	// Share sets share token of wishlist replacing the previous one,
+	// Unshare removes it.
+	Share(ctx context.Context, userID int, ID int, token string) (Wishlist, error)
+	Unshare(ctx context.Context, userID int, ID int) (Wishlist, error)
+	// GetShared get wishlist by its share token.
+	GetShared(ctx context.Context, token string) (Wishlist, error)
+
+	// AddItem adds a product to wishlist, quantity is added up when
+	// product is already in wishlist.
+	AddItem(ctx context.Context, userID int, wishlistID int, item WishlistItem) (Wishlist, error)
+	RemoveItem(ctx context.Context, userID int, wishlistID int, itemID int) (Wishlist, error)
+
+	// MoveToCart moves an item of wishlist into cart of user.
+	MoveToCart(ctx context.Context, userID int, wishlistID int, itemID int) (Cart, error)
+	// MoveFromCart moves an item of cart of user into wishlist, default
+	// wishlist is used and created when wishlistID is zero.
+	MoveFromCart(ctx context.Context, userID int, cartItemID int, wishlistID int) (Wishlist, error)
+}
+
+// Validate validates POST requests model.
+func (w WishlistCreate) Validate() error {
+	return validateWishlistName(strings.TrimSpace(w.Name))
+}
+
+// CreateModel set input values to a new struct and return a new instance.
+func (w WishlistCreate) CreateModel(userID int) Wishlist {
+	return Wishlist{
+		UserID: userID,
+		Name:   strings.TrimSpace(w.Name),
+	}
+}
+
+// Validate validates PATCH requests model.
+func (w WishlistUpdate) Validate() error {
+	if w.Name == nil {
+		return nil
+	}
+	return validateWishlistName(strings.TrimSpace(*w.Name))
+}
+
+// Validate validates POST requests model.
+func (w WishlistItemCreate) Validate() error {
+	switch {
+	case w.ProductID == 0:
+		return errProductIDRequired
+	case w.Quantity < 0:
+		return errQuantityRequired
+	}
+	return nil
+}
+
+// CreateModel set input values to a new struct and return a new instance.
+func (w WishlistItemCreate) CreateModel() WishlistItem {
+	item := WishlistItem{ProductID: w.ProductID, Quantity: w.Quantity}
+	if item.Quantity == 0 {
+		item.Quantity = 1
+	}
+
+	return item
+}
+
+// validateWishlistName validates a trimmed wishlist name.
+func validateWishlistName(name string) error {
+	switch {
+	case name == "":
+		return errWishlistNameRequired
+	case len(name) > maxWishlistNameLength:
+		return errWishlistNameTooLong
+	}
+	return nil
+}
+
+// GenerateShareToken returns a token sharing a wishlist.
+func GenerateShareToken() (string, error) {
+	token, err := GenerateToken(0, 16, 0)
+	if err != nil {
+		return "", err
+	}
+
+	return token.Plain, nil
+}
+
+// ApplyProducts sets names, unit prices and stock states of items from
+// products.
+func (w *Wishlist) ApplyProducts(products map[int]Product) {
+	for i := range w.Items {
+		item := &w.Items[i]
+		product := products[item.ProductID]
+		item.Name = product.Name
+		item.UnitPrice = product.Price
+
+		item.StockStatus = StockStatus(product.Available)
+		if product.Available < item.Quantity {
+			item.StockStatus = product.outOfStockStatus()
+		}
+	}
+}
+
+// Shared returns wishlist as it is shown to holders of its share token.
+func (w Wishlist) Shared() SharedWishlist {
+	shared := SharedWishlist{
+		Name:      w.Name,
+		Items:     make([]SharedWishlistItem, 0, len(w.Items)),
+		UpdatedAt: w.UpdatedAt,
+	}
+
+	for _, item := range w.Items {
+		shared.Items = append(shared.Items, SharedWishlistItem{
+			ProductID:   item.ProductID,
+			Name:        item.Name,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			StockStatus: item.StockStatus,
+		})
+	}
+
+	return shared
+}
+
+// PriceDropNotification returns notification of a user wishing for
+// product about its price falling from price seen by user.
+func PriceDropNotification(product Product, from int, email string) Notification {
+	return Notification{
+		Kind:      NotificationPriceDrop,
+		Recipient: email,
+		Subject:   fmt.Sprintf("Price drop: %s", product.Name),
+		Body: fmt.Sprintf("%s on your wishlist dropped from %d to %d at /products/%d.",
+			product.Name, from, product.Price, product.ID),
+	}
+}
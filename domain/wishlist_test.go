@@ -0,0 +1,92 @@
+package domain_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+func TestWishlistCreate_Validate(t *testing.T) {
+	tests := []struct {
+		name  string
+		input domain.WishlistCreate
+		valid bool
+	}{
+		{"valid", domain.WishlistCreate{Name: "Birthday"}, true},
+		{"blank", domain.WishlistCreate{Name: "  "}, false},
+		{"too long", domain.WishlistCreate{Name: strings.Repeat("a", 101)}, false},
+	}
+
+	for _, tt := range tests {
+		err := tt.input.Validate()
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid %v, got %v", tt.name, tt.valid, err)
+		}
+	}
+
+	wishlist := domain.WishlistCreate{Name: " Birthday "}.CreateModel(1)
+	if wishlist.Name != "Birthday" || wishlist.UserID != 1 {
+		t.Errorf("expected trimmed wishlist of user 1, got: %+v", wishlist)
+	}
+}
+
+func TestWishlistItemCreate_CreateModel(t *testing.T) {
+	if err := (domain.WishlistItemCreate{}).Validate(); err == nil {
+		t.Error("expected product_id required")
+	}
+
+	item := domain.WishlistItemCreate{ProductID: 1}.CreateModel()
+	if item.Quantity != 1 {
+		t.Errorf("expected default quantity 1, got %d", item.Quantity)
+	}
+}
+
+func TestWishlist_Shared(t *testing.T) {
+	token := "token"
+	wishlist := domain.Wishlist{
+		ID:         1,
+		UserID:     2,
+		Name:       "Birthday",
+		ShareToken: &token,
+		Items: []domain.WishlistItem{
+			{ID: 1, ProductID: 1, Quantity: 2, SeenPrice: 150},
+			{ID: 2, ProductID: 2, Quantity: 1, SeenPrice: 50},
+		},
+	}
+
+	wishlist.ApplyProducts(map[int]domain.Product{
+		1: {ID: 1, Name: "Mug", Price: 100, Available: 1},
+		2: {ID: 2, Name: "Pen", Price: 50, Available: 10},
+	})
+
+	shared := wishlist.Shared()
+	if shared.Name != "Birthday" || len(shared.Items) != 2 {
+		t.Fatalf("expected shared wishlist with 2 items, got: %+v", shared)
+	}
+
+	if shared.Items[0].Name != "Mug" || shared.Items[0].UnitPrice != 100 {
+		t.Errorf("expected current product of item, got: %+v", shared.Items[0])
+	}
+
+	if shared.Items[0].StockStatus != domain.StockOutOfStock {
+		t.Errorf("expected wished quantity out of stock, got: %s", shared.Items[0].StockStatus)
+	}
+
+	if shared.Items[1].StockStatus != domain.StockAvailable {
+		t.Errorf("expected item available, got: %s", shared.Items[1].StockStatus)
+	}
+}
+
+func TestPriceDropNotification(t *testing.T) {
+	product := domain.Product{ID: 3, Name: "Mug", Price: 80}
+
+	n := domain.PriceDropNotification(product, 100, "name@gmail.com")
+	if n.Kind != domain.NotificationPriceDrop || n.Recipient != "name@gmail.com" {
+		t.Errorf("expected price drop notification of recipient, got: %+v", n)
+	}
+
+	if !strings.Contains(n.Body, "from 100 to 80") || !strings.Contains(n.Body, "/products/3") {
+		t.Errorf("expected prices and link in body, got: %s", n.Body)
+	}
+}
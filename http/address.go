@@ -0,0 +1,218 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// @Summary      List addresses
+// @Description  Lists addresses of current user, defaults first.
+// @Tags 		 Addresses
+// @Security     Bearer
+// @Produce      json
+// @Success      200  {object}  domain.WrapAddressList
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /users/me/addresses   [get]
+func (s *server) listAddressesHandler(w http.ResponseWriter, r *http.Request) {
+	addresses, err := s.AddressesStore.List(r.Context(), userIDFromContext(r.Context()))
+	if err != nil {
+		if errors.Is(err, domain.ErrNoAddressesFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapAddressList{Addresses: addresses}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Create address
+// @Description  The first address of user becomes its default shipping and billing address.
+// @Tags 		 Addresses
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        address  body     domain.AddressCreate true "Create address"
+// @Success      201  {object}  domain.WrapAddress
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /users/me/addresses   [post]
+func (s *server) createAddressHandler(w http.ResponseWriter, r *http.Request) {
+	input := domain.AddressCreate{}
+	err := FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	address := input.CreateModel(userIDFromContext(r.Context()))
+	err = s.AddressesStore.Create(r.Context(), &address)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/users/me/addresses/%d", address.ID))
+	err = ToJSON(w, domain.WrapAddress{Address: address}, http.StatusCreated)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Get address
+// @Tags 		 Addresses
+// @Security     Bearer
+// @Produce      json
+// @Param        addressID   path      int  true "Address ID"
+// @Success      200  {object}  domain.WrapAddress
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /users/me/addresses/{addressID}   [get]
+func (s *server) getAddressHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "addressID"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	address, err := s.AddressesStore.GetByID(r.Context(), userIDFromContext(r.Context()), ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoAddressesFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapAddress{Address: address}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Update address
+// @Tags 		 Addresses
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        addressID  path      int  true "Address ID"
+// @Param        address    body      domain.AddressUpdate true "Update address"
+// @Success      200  {object}  domain.WrapAddress
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /users/me/addresses/{addressID}   [patch]
+func (s *server) updateAddressHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "addressID"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	input := domain.AddressUpdate{}
+	err = FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	address, err := s.AddressesStore.Update(r.Context(), userIDFromContext(r.Context()), ID, input)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidAddress) {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+		} else if errors.Is(err, domain.ErrNoAddressesFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapAddress{Address: address}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Delete address
+// @Tags 		 Addresses
+// @Security     Bearer
+// @Param        addressID   path      int  true "Address ID"
+// @Success      200
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /users/me/addresses/{addressID}   [delete]
+func (s *server) deleteAddressHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "addressID"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	err = s.AddressesStore.Delete(r.Context(), userIDFromContext(r.Context()), ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoAddressesFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+	}
+}
+
+// checkoutAddresses returns addresses of user to place an order with,
+// addresses not given are taken from defaults of user.
+func (s *server) checkoutAddresses(r *http.Request, input domain.CheckoutInput) (shipping *domain.Address, billing *domain.Address, err error) {
+	userID := userIDFromContext(r.Context())
+
+	if input.ShippingAddressID == 0 || input.BillingAddressID == 0 {
+		addresses, err := s.AddressesStore.List(r.Context(), userID)
+		if err != nil && !errors.Is(err, domain.ErrNoAddressesFound) {
+			return nil, nil, err
+		}
+		shipping, billing = domain.DefaultAddresses(addresses)
+	}
+
+	if input.ShippingAddressID != 0 {
+		address, err := s.AddressesStore.GetByID(r.Context(), userID, input.ShippingAddressID)
+		if err != nil {
+			return nil, nil, err
+		}
+		shipping = &address
+	}
+
+	if input.BillingAddressID != 0 {
+		address, err := s.AddressesStore.GetByID(r.Context(), userID, input.BillingAddressID)
+		if err != nil {
+			return nil, nil, err
+		}
+		billing = &address
+	} else if billing == nil {
+		billing = shipping
+	}
+
+	return shipping, billing, nil
+}
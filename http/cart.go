@@ -2,6 +2,7 @@ package http
 
 import (
 	"errors"
+	"log"
 	"net/http"
 	"strconv"
 
@@ -9,16 +10,30 @@ import (
 	"github.com/mortezadadgar/ecommerce-api/domain"
 )
 
+const (
+	cartTokenHeader = "X-Cart-Token"
+	cartTokenCookie = "cart_token"
+)
+
 func (s *server) registerCartsRoutes(r *chi.Mux) {
 	r.Route("/carts", func(r chi.Router) {
-		r.Get("/", s.listCartsHandler)
-		r.Get("/{id}", s.getCartsHandler)
-		r.Post("/", s.postCartsHandler)
-		r.Patch("/{id}", s.updateCartshandler)
-		r.Delete("/{id}", s.deleteCartshandler)
-
-		r.Route("/user", func(r chi.Router) {
-			r.Get("/{id}", s.getUserCartsHandler)
+		r.With(requireAuth).Get("/", s.listCartsHandler)
+		r.With(requireAuth).Get("/{id}", s.getCartsHandler)
+		r.With(requireAuth).Delete("/{id}", s.deleteCartshandler)
+		r.Route("/recoveries", s.registerRecoveriesRoutes)
+
+		r.Route("/me", func(r chi.Router) {
+			r.Get("/", s.getMyCartHandler)
+			r.Delete("/", s.clearMyCartHandler)
+			r.Post("/items", s.addCartItemHandler)
+			r.Patch("/items/{itemID}", s.updateCartItemHandler)
+			r.Delete("/items/{itemID}", s.removeCartItemHandler)
+			r.With(requireUser).Post("/items/{itemID}/move-to-wishlist", s.moveToWishlistHandler)
+			r.Post("/coupons", s.applyCouponHandler)
+			r.Delete("/coupons/{code}", s.removeCouponHandler)
+			r.Put("/destination", s.setCartDestinationHandler)
+			r.Get("/shipping-rates", s.listShippingRatesHandler)
+			r.Put("/shipping-method", s.setShippingMethodHandler)
 		})
 	})
 }
@@ -56,42 +71,10 @@ func (s *server) getCartsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// @Summary      Get user carts
-// @Tags 		 Carts
-// @Security     Bearer
-// @Produce      json
-// @Param        id    path       int  true "User ID"
-// @Success      200  {array}     domain.WrapCartList
-// @Failure      400  {object}    http.WrapError
-// @Failure      404  {object}    http.WrapError
-// @Failure      500  {object}    http.WrapError
-// @Router       /carts/user/{id} [get]
-func (s *server) getUserCartsHandler(w http.ResponseWriter, r *http.Request) {
-	UserID, err := strconv.Atoi(chi.URLParam(r, "id"))
-	if err != nil {
-		ErrorInvalidQuery(w, r)
-		return
-	}
-
-	cart, err := s.CartsStore.GetByUser(r.Context(), UserID)
-	if err != nil {
-		if errors.Is(err, domain.ErrNoCartsFound) {
-			Errorf(w, r, http.StatusNotFound, err.Error())
-		} else {
-			Errorf(w, r, http.StatusInternalServerError, err.Error())
-		}
-		return
-	}
-
-	err = ToJSON(w, domain.WrapCartList{Carts: cart}, http.StatusOK)
-	if err != nil {
-		Errorf(w, r, http.StatusInternalServerError, err.Error())
-	}
-}
-
 // @Summary      List carts
 // @Tags 		 Carts
 // @Security     Bearer
+// @Param        user_id      query       string  false "Filter by user"
 // @Param        limit        query       string  false "Limit results"
 // @Param        offset       query       string  false "Offset results"
 // @Param        sort         query       string  false "Sort by a column"
@@ -101,6 +84,12 @@ func (s *server) getUserCartsHandler(w http.ResponseWriter, r *http.Request) {
 // @Failure      500  {object}  http.WrapError
 // @Router       /carts/        [get]
 func (s *server) listCartsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := ParseIntQuery(r, "user_id")
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
 	limit, err := ParseIntQuery(r, "limit")
 	if err != nil {
 		ErrorInvalidQuery(w, r)
@@ -114,6 +103,7 @@ func (s *server) listCartsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	filter := domain.CartFilter{
+		UserID: userID,
 		Sort:   r.URL.Query().Get("sort"),
 		Limit:  limit,
 		Offset: offset,
@@ -135,21 +125,180 @@ func (s *server) listCartsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// @Summary      Create cart
+// @Summary      Delete carts
 // @Tags 		 Carts
 // @Security     Bearer
-// @Produce      json
-// @Accept       json
-// @Param        cart         body        domain.CartCreate true "Create cart"
-// @Success      201          {array}     domain.WrapCart
+// @Param        id           path        int  true "cart ID"
+// @Success      200          {array}     domain.WrapCart
 // @Failure      400          {object}    http.WrapError
 // @Failure      403          {object}    http.WrapError
 // @Failure      404          {object}    http.WrapError
+// @Failure      500          {object}    http.WrapError
+// @Router       /carts/{id}  [delete]
+func (s *server) deleteCartshandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	err = s.CartsStore.Delete(r.Context(), ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoCartsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+	}
+}
+
+// cartToken returns plain cart token of guests from header or cookie.
+func cartToken(r *http.Request) string {
+	if token := r.Header.Get(cartTokenHeader); token != "" {
+		return token
+	}
+
+	cookie, err := r.Cookie(cartTokenCookie)
+	if err != nil {
+		return ""
+	}
+
+	return cookie.Value
+}
+
+// setCartToken hands a cart token to guest through both header and cookie,
+// an empty token expires the cookie.
+func setCartToken(w http.ResponseWriter, token string) {
+	maxAge := int(domain.GuestCartExpiry.Seconds())
+	if token == "" {
+		maxAge = -1
+	}
+
+	w.Header().Set(cartTokenHeader, token)
+	http.SetCookie(w, &http.Cookie{
+		Name:     cartTokenCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// cartOwner returns owner of cart requested, authenticated users take
+// precedence over cart tokens.
+func cartOwner(r *http.Request) domain.CartOwner {
+	if userID := userIDFromContext(r.Context()); userID != 0 {
+		return domain.CartOwner{UserID: userID}
+	}
+
+	token := cartToken(r)
+	if token == "" {
+		return domain.CartOwner{}
+	}
+
+	return domain.CartOwner{Token: domain.HashToken(token)}
+}
+
+// mergeGuestCart merges guest cart of request into user's cart, failures
+// are only logged as they must not prevent users from logging in.
+func (s *server) mergeGuestCart(w http.ResponseWriter, r *http.Request, userID int) {
+	token := cartToken(r)
+	if token == "" {
+		return
+	}
+
+	_, err := s.CartsStore.Merge(r.Context(), userID, domain.HashToken(token), s.CartMergeStrategy)
+	if err != nil && !errors.Is(err, domain.ErrNoCartsFound) {
+		log.Printf("[ERROR]: failed to merge guest cart of user %d: %v", userID, err)
+		return
+	}
+
+	setCartToken(w, "")
+}
+
+// @Summary      Get current cart
+// @Description  Returns cart of authenticated user or of guest identified by X-Cart-Token header or cart_token cookie.
+// @Tags 		 Carts
+// @Security     Bearer
+// @Produce      json
+// @Param        X-Cart-Token  header   string  false "Guest cart token"
+// @Success      200  {object}  domain.WrapCart
+// @Failure      500  {object}  http.WrapError
+// @Router       /carts/me      [get]
+func (s *server) getMyCartHandler(w http.ResponseWriter, r *http.Request) {
+	owner := cartOwner(r)
+
+	cart, err := s.CartsStore.GetByOwner(r.Context(), owner)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoCartsFound) {
+			cart = domain.Cart{
+				UserID:    owner.UserID,
+				Guest:     owner.IsGuest(),
+				Items:     []domain.CartItem{},
+				Coupons:   []string{},
+				Discounts: []domain.AppliedDiscount{},
+			}
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	// shipping is shown once a method available for cart is selected.
+	if cart.ShippingMethodID != nil {
+		quote, err := s.shippingQuote(r.Context(), cart)
+		if err != nil && !errors.Is(err, domain.ErrShippingMethodNotAvailable) {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if quote != nil {
+			cart.ApplyShipping(quote.Rate)
+		}
+	}
+
+	err = ToJSON(w, domain.WrapCart{Cart: cart}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Clear current cart
+// @Tags 		 Carts
+// @Security     Bearer
+// @Param        X-Cart-Token  header   string  false "Guest cart token"
+// @Success      200
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /carts/me      [delete]
+func (s *server) clearMyCartHandler(w http.ResponseWriter, r *http.Request) {
+	err := s.CartsStore.Clear(r.Context(), cartOwner(r))
+	if err != nil {
+		if errors.Is(err, domain.ErrNoCartsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+	}
+}
+
+// @Summary      Add item to cart
+// @Description  Guests without a cart token get a new one in X-Cart-Token header and cart_token cookie.
+// @Tags 		 Carts
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        X-Cart-Token  header     string  false "Guest cart token"
+// @Param        item         body        domain.CartItemCreate true "Add item"
+// @Success      201          {object}    domain.WrapCart
+// @Failure      400          {object}    http.WrapError
+// @Failure      409          {object}    http.WrapError
 // @Failure      413          {object}    http.WrapError
 // @Failure      500          {object}    http.WrapError
-// @Router       /carts/{id}  [post]
-func (s *server) postCartsHandler(w http.ResponseWriter, r *http.Request) {
-	input := domain.CartCreate{}
+// @Router       /carts/me/items  [post]
+func (s *server) addCartItemHandler(w http.ResponseWriter, r *http.Request) {
+	input := domain.CartItemCreate{}
 	err := FromJSON(w, r, &input)
 	if err != nil {
 		Errorf(w, r, http.StatusBadRequest, err.Error())
@@ -162,12 +311,24 @@ func (s *server) postCartsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cart := input.CreateModel()
-	err = s.CartsStore.Create(r.Context(), &cart)
+	owner := cartOwner(r)
+	if owner.IsGuest() && owner.Token == nil {
+		var token string
+		token, owner.Token, err = domain.GenerateCartToken()
+		if err != nil {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		setCartToken(w, token)
+	}
+
+	cart, err := s.CartsStore.AddItem(r.Context(), owner, input.CreateModel())
 	if err != nil {
 		if errors.Is(err, domain.ErrCartInvalidUserID) ||
 			errors.Is(err, domain.ErrCartInvalidProductID) {
 			Errorf(w, r, http.StatusBadRequest, err.Error())
+		} else if errors.Is(err, domain.ErrInsufficientStock) {
+			Errorf(w, r, http.StatusConflict, err.Error())
 		} else {
 			Errorf(w, r, http.StatusInternalServerError, err.Error())
 		}
@@ -180,26 +341,29 @@ func (s *server) postCartsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// @Summary      Update cart
+// @Summary      Update cart item
 // @Tags 		 Carts
 // @Security     Bearer
 // @Produce      json
 // @Accept       json
-// @Param        cart         body        domain.CartUpdate true "Update cart"
-// @Success      200          {array}     domain.WrapCart
+// @Param        X-Cart-Token  header     string  false "Guest cart token"
+// @Param        itemID       path        int  true "Item ID"
+// @Param        item         body        domain.CartItemUpdate true "Update item"
+// @Success      200          {object}    domain.WrapCart
 // @Failure      400          {object}    http.WrapError
-// @Failure      403          {object}    http.WrapError
+// @Failure      404          {object}    http.WrapError
+// @Failure      409          {object}    http.WrapError
 // @Failure      413          {object}    http.WrapError
 // @Failure      500          {object}    http.WrapError
-// @Router       /carts/{id}  [patch]
-func (s *server) updateCartshandler(w http.ResponseWriter, r *http.Request) {
-	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+// @Router       /carts/me/items/{itemID}  [patch]
+func (s *server) updateCartItemHandler(w http.ResponseWriter, r *http.Request) {
+	itemID, err := strconv.Atoi(chi.URLParam(r, "itemID"))
 	if err != nil {
 		ErrorInvalidQuery(w, r)
 		return
 	}
 
-	input := domain.CartUpdate{}
+	input := domain.CartItemUpdate{}
 	err = FromJSON(w, r, &input)
 	if err != nil {
 		Errorf(w, r, http.StatusBadRequest, err.Error())
@@ -212,12 +376,12 @@ func (s *server) updateCartshandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cart, err := s.CartsStore.Update(r.Context(), ID, input)
+	cart, err := s.CartsStore.UpdateItem(r.Context(), cartOwner(r), itemID, input.Quantity)
 	if err != nil {
-		if errors.Is(err, domain.ErrCartInvalidProductID) {
-			Errorf(w, r, http.StatusBadRequest, err.Error())
-		} else if errors.Is(err, domain.ErrNoCartsFound) {
+		if errors.Is(err, domain.ErrNoCartItemsFound) {
 			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else if errors.Is(err, domain.ErrInsufficientStock) {
+			Errorf(w, r, http.StatusConflict, err.Error())
 		} else {
 			Errorf(w, r, http.StatusInternalServerError, err.Error())
 		}
@@ -230,29 +394,36 @@ func (s *server) updateCartshandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// @Summary      Delete carts
+// @Summary      Remove cart item
 // @Tags 		 Carts
 // @Security     Bearer
-// @Param        id           path        int  true "cart ID"
-// @Success      200          {array}     domain.WrapCart
+// @Produce      json
+// @Param        X-Cart-Token  header     string  false "Guest cart token"
+// @Param        itemID       path        int  true "Item ID"
+// @Success      200          {object}    domain.WrapCart
 // @Failure      400          {object}    http.WrapError
-// @Failure      403          {object}    http.WrapError
 // @Failure      404          {object}    http.WrapError
 // @Failure      500          {object}    http.WrapError
-// @Router       /carts/{id}  [delete]
-func (s *server) deleteCartshandler(w http.ResponseWriter, r *http.Request) {
-	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+// @Router       /carts/me/items/{itemID}  [delete]
+func (s *server) removeCartItemHandler(w http.ResponseWriter, r *http.Request) {
+	itemID, err := strconv.Atoi(chi.URLParam(r, "itemID"))
 	if err != nil {
 		ErrorInvalidQuery(w, r)
 		return
 	}
 
-	err = s.CartsStore.Delete(r.Context(), ID)
+	cart, err := s.CartsStore.RemoveItem(r.Context(), cartOwner(r), itemID)
 	if err != nil {
-		if errors.Is(err, domain.ErrNoCartsFound) {
+		if errors.Is(err, domain.ErrNoCartItemsFound) {
 			Errorf(w, r, http.StatusNotFound, err.Error())
 		} else {
 			Errorf(w, r, http.StatusInternalServerError, err.Error())
 		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapCart{Cart: cart}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
 	}
 }
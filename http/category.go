@@ -17,6 +17,10 @@ func (s *server) registerCategoriesRoutes(r *chi.Mux) {
 		r.With(requireAuth).Post("/", s.createCategoryHandler)
 		r.With(requireAuth).Patch("/{id}", s.updateCategoryHandler)
 		r.With(requireAuth).Delete("/{id}", s.deleteCategoryHandler)
+
+		r.Get("/{id}/translations", listTranslationsHandler(s.CategoryTranslationsStore))
+		r.With(requireAuth).Put("/{id}/translations/{locale}", setTranslationHandler(s.CategoryTranslationsStore))
+		r.With(requireAuth).Delete("/{id}/translations/{locale}", deleteTranslationHandler(s.CategoryTranslationsStore))
 	})
 }
 
@@ -24,6 +28,7 @@ func (s *server) registerCategoriesRoutes(r *chi.Mux) {
 // @Tags 		 Categories
 // @Produce      json
 // @Param        id    path       int  true "Category ID"
+// @Param        locale query     string  false "Content locale"
 // @Success      200  {array}     domain.WrapCategory
 // @Failure      400  {object}    http.WrapError
 // @Failure      404  {object}    http.WrapError
@@ -36,6 +41,12 @@ func (s *server) getCategoryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	chain, err := localeChain(r)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	category, err := s.CategoriesStore.GetByID(r.Context(), ID)
 	if err != nil {
 		if errors.Is(err, domain.ErrNoCategoryFound) {
@@ -46,6 +57,14 @@ func (s *server) getCategoryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	categories := []domain.Category{category}
+	err = s.localizeCategories(r.Context(), chain, categories)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	category = categories[0]
+
 	err = ToJSON(w, domain.WrapCategory{Category: category}, http.StatusOK)
 	if err != nil {
 		Errorf(w, r, http.StatusInternalServerError, err.Error())
@@ -58,6 +77,7 @@ func (s *server) getCategoryHandler(w http.ResponseWriter, r *http.Request) {
 // @Param        offset       query       string  false "Offset results"
 // @Param        name         query       string  false "List by name"
 // @Param        sort         query       string  false "Sort by a column"
+// @Param        locale       query       string  false "Content locale"
 // @Success      200  {array}   domain.WrapCategoryList
 // @Failure      400  {object}  http.WrapError
 // @Failure      404  {object}  http.WrapError
@@ -76,6 +96,12 @@ func (s *server) listCategoriesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	chain, err := localeChain(r)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	filter := domain.CategoryFilter{
 		Name:   r.URL.Query().Get("name"),
 		Sort:   r.URL.Query().Get("sort"),
@@ -93,6 +119,12 @@ func (s *server) listCategoriesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = s.localizeCategories(r.Context(), chain, categories)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	err = ToJSON(w, domain.WrapCategoryList{Categories: categories}, http.StatusOK)
 	if err != nil {
 		Errorf(w, r, http.StatusInternalServerError, err.Error())
@@ -0,0 +1,303 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// registerCouponsRoutes registers routes of coupons and promotions.
+func (s *server) registerCouponsRoutes(r *chi.Mux) {
+	r.Route("/coupons", func(r chi.Router) {
+		r.With(requireAuth).Get("/", s.listCouponsHandler)
+		r.With(requireAuth).Post("/", s.createCouponHandler)
+		r.With(requireAuth).Get("/{id}", s.getCouponHandler)
+		r.With(requireAuth).Patch("/{id}", s.updateCouponHandler)
+		r.With(requireAuth).Delete("/{id}", s.deleteCouponHandler)
+	})
+}
+
+// @Summary      List coupons
+// @Tags 		 Coupons
+// @Security     Bearer
+// @Produce      json
+// @Param        code         query       string  false "Filter by code"
+// @Param        limit        query       string  false "Limit results"
+// @Param        offset       query       string  false "Offset results"
+// @Param        sort         query       string  false "Sort by a column"
+// @Success      200  {object}  domain.WrapCouponList
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /coupons   [get]
+func (s *server) listCouponsHandler(w http.ResponseWriter, r *http.Request) {
+	limit, err := ParseIntQuery(r, "limit")
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	offset, err := ParseIntQuery(r, "offset")
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	filter := domain.CouponFilter{
+		Code:   r.URL.Query().Get("code"),
+		Sort:   r.URL.Query().Get("sort"),
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	coupons, err := s.CouponsStore.List(r.Context(), filter)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoCouponsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapCouponList{Coupons: coupons}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Create coupon
+// @Description  Coupons created as automatic have no code and apply to every eligible cart.
+// @Tags 		 Coupons
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        coupon  body     domain.CouponCreate true "Create coupon"
+// @Success      201  {object}  domain.WrapCoupon
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /coupons   [post]
+func (s *server) createCouponHandler(w http.ResponseWriter, r *http.Request) {
+	input := domain.CouponCreate{}
+	err := FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	coupon := input.CreateModel()
+	err = s.CouponsStore.Create(r.Context(), &coupon)
+	if err != nil {
+		if errors.Is(err, domain.ErrDuplicatedCoupon) {
+			Errorf(w, r, http.StatusConflict, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/coupons/%d", coupon.ID))
+	err = ToJSON(w, domain.WrapCoupon{Coupon: coupon}, http.StatusCreated)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Get coupon
+// @Tags 		 Coupons
+// @Security     Bearer
+// @Produce      json
+// @Param        id   path      int  true "Coupon ID"
+// @Success      200  {object}  domain.WrapCoupon
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /coupons/{id}   [get]
+func (s *server) getCouponHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	coupon, err := s.CouponsStore.GetByID(r.Context(), ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoCouponsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapCoupon{Coupon: coupon}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Update coupon
+// @Tags 		 Coupons
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        id      path      int  true "Coupon ID"
+// @Param        coupon  body      domain.CouponUpdate true "Update coupon"
+// @Success      200  {object}  domain.WrapCoupon
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /coupons/{id}   [patch]
+func (s *server) updateCouponHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	input := domain.CouponUpdate{}
+	err = FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	coupon, err := s.CouponsStore.Update(r.Context(), ID, input)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidCoupon) {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+		} else if errors.Is(err, domain.ErrNoCouponsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapCoupon{Coupon: coupon}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Delete coupon
+// @Tags 		 Coupons
+// @Security     Bearer
+// @Param        id   path      int  true "Coupon ID"
+// @Success      200
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /coupons/{id}   [delete]
+func (s *server) deleteCouponHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	err = s.CouponsStore.Delete(r.Context(), ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoCouponsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+	}
+}
+
+// @Summary      Apply coupon to cart
+// @Tags 		 Carts
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        X-Cart-Token  header     string  false "Guest cart token"
+// @Param        coupon       body        domain.CouponApply true "Apply coupon"
+// @Success      200          {object}    domain.WrapCart
+// @Failure      400          {object}    http.WrapError
+// @Failure      404          {object}    http.WrapError
+// @Failure      409          {object}    http.WrapError
+// @Failure      422          {object}    http.WrapError
+// @Failure      500          {object}    http.WrapError
+// @Router       /carts/me/coupons  [post]
+func (s *server) applyCouponHandler(w http.ResponseWriter, r *http.Request) {
+	input := domain.CouponApply{}
+	err := FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	cart, err := s.CartsStore.ApplyCoupon(r.Context(), cartOwner(r), input.Code)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoCartsFound) ||
+			errors.Is(err, domain.ErrNoCouponsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else if errors.Is(err, domain.ErrCouponNotStackable) {
+			Errorf(w, r, http.StatusConflict, err.Error())
+		} else if errors.Is(err, domain.ErrCouponNotActive) ||
+			errors.Is(err, domain.ErrCouponUsageExceeded) ||
+			errors.Is(err, domain.ErrCouponNotApplicable) ||
+			errors.Is(err, domain.ErrCouponRequiresUser) {
+			Errorf(w, r, http.StatusUnprocessableEntity, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapCart{Cart: cart}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Remove coupon from cart
+// @Tags 		 Carts
+// @Security     Bearer
+// @Produce      json
+// @Param        X-Cart-Token  header     string  false "Guest cart token"
+// @Param        code         path        string  true "Coupon code"
+// @Success      200          {object}    domain.WrapCart
+// @Failure      404          {object}    http.WrapError
+// @Failure      500          {object}    http.WrapError
+// @Router       /carts/me/coupons/{code}  [delete]
+func (s *server) removeCouponHandler(w http.ResponseWriter, r *http.Request) {
+	cart, err := s.CartsStore.RemoveCoupon(r.Context(), cartOwner(r), chi.URLParam(r, "code"))
+	if err != nil {
+		if errors.Is(err, domain.ErrNoCartsFound) ||
+			errors.Is(err, domain.ErrNoCouponsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapCart{Cart: cart}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,486 @@
+package http
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// maxBytesFileUpload is maximum size of uploaded files of digital products.
+const maxBytesFileUpload = 512 << 20
+
+func (s *server) registerDownloadsRoutes(r *chi.Mux) {
+	r.Route("/downloads", func(r chi.Router) {
+		r.Get("/{grantID}/files/{fileID}", s.downloadHandler)
+	})
+}
+
+// @Summary      List product files
+// @Tags 		 Digital
+// @Security     Bearer
+// @Produce      json
+// @Param        id                    path        int  true "Product ID"
+// @Success      200                   {array}     domain.WrapProductFileList
+// @Failure      400                   {object}    http.WrapError
+// @Failure      404                   {object}    http.WrapError
+// @Failure      500                   {object}    http.WrapError
+// @Router       /products/{id}/files  [get]
+func (s *server) listProductFilesHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	files, err := s.DigitalStore.ListFiles(r.Context(), ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoFilesFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapProductFileList{Files: files}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Upload product file
+// @Tags 		 Digital
+// @Security     Bearer
+// @Produce      json
+// @Accept       mpfd
+// @Param        id                    path        int  true "Product ID"
+// @Param        file                  formData    file true "File contents"
+// @Success      201                   {array}     domain.WrapProductFile
+// @Failure      400                   {object}    http.WrapError
+// @Failure      403                   {object}    http.WrapError
+// @Failure      404                   {object}    http.WrapError
+// @Failure      413                   {object}    http.WrapError
+// @Failure      500                   {object}    http.WrapError
+// @Router       /products/{id}/files  [post]
+func (s *server) uploadProductFileHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytesFileUpload)
+	upload, header, err := r.FormFile("file")
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, "failed to read uploaded file")
+		return
+	}
+	defer upload.Close()
+
+	key, err := newBlobKey(ID)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	size, err := s.BlobStore.Put(r.Context(), key, upload)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	contentType := header.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	file := domain.ProductFile{
+		ProductID:   ID,
+		Name:        filepath.Base(header.Filename),
+		BlobKey:     key,
+		ContentType: contentType,
+		Size:        size,
+	}
+
+	err = s.DigitalStore.CreateFile(r.Context(), &file)
+	if err != nil {
+		_ = s.BlobStore.Delete(r.Context(), key)
+		if errors.Is(err, domain.ErrNoProductsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else if errors.Is(err, domain.ErrNotDigital) {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapProductFile{File: file}, http.StatusCreated)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Delete product file
+// @Tags 		 Digital
+// @Security     Bearer
+// @Param        id                             path        int  true "Product ID"
+// @Param        fileID                         path        int  true "File ID"
+// @Success      200
+// @Failure      400                            {object}    http.WrapError
+// @Failure      403                            {object}    http.WrapError
+// @Failure      404                            {object}    http.WrapError
+// @Failure      500                            {object}    http.WrapError
+// @Router       /products/{id}/files/{fileID}  [delete]
+func (s *server) deleteProductFileHandler(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	ID, err := strconv.Atoi(chi.URLParam(r, "fileID"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	file, err := s.DigitalStore.GetFile(r.Context(), ID)
+	if err == nil && file.ProductID != productID {
+		err = domain.ErrNoFilesFound
+	}
+	if err == nil {
+		file, err = s.DigitalStore.DeleteFile(r.Context(), ID)
+	}
+	if err != nil {
+		if errors.Is(err, domain.ErrNoFilesFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = s.BlobStore.Delete(r.Context(), file.BlobKey)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Add license keys
+// @Tags 		 Digital
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        id                           path        int  true "Product ID"
+// @Param        keys                         body        domain.LicenseKeysCreate true "License keys"
+// @Success      201                          {array}     domain.LicenseKeysResult
+// @Failure      400                          {object}    http.WrapError
+// @Failure      401                          {object}    http.WrapError
+// @Failure      403                          {object}    http.WrapError
+// @Failure      404                          {object}    http.WrapError
+// @Failure      413                          {object}    http.WrapError
+// @Failure      500                          {object}    http.WrapError
+// @Router       /products/{id}/license-keys  [post]
+func (s *server) addLicenseKeysHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	input := domain.LicenseKeysCreate{}
+	err = FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := s.DigitalStore.AddLicenseKeys(r.Context(), ID, input.Keys)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoProductsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else if errors.Is(err, domain.ErrNotDigital) ||
+			errors.Is(err, domain.ErrDuplicatedLicenseKey) {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, result, http.StatusCreated)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Grant digital product
+// @Tags 		 Digital
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        id                     path        int  true "Product ID"
+// @Param        grant                  body        domain.GrantCreate true "Grant"
+// @Success      201                    {array}     domain.WrapDownloadGrant
+// @Failure      400                    {object}    http.WrapError
+// @Failure      401                    {object}    http.WrapError
+// @Failure      403                    {object}    http.WrapError
+// @Failure      404                    {object}    http.WrapError
+// @Failure      409                    {object}    http.WrapError
+// @Failure      413                    {object}    http.WrapError
+// @Failure      500                    {object}    http.WrapError
+// @Router       /products/{id}/grants  [post]
+func (s *server) createGrantHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	input := domain.GrantCreate{}
+	err = FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	grant := input.CreateModel(ID)
+	err = s.DigitalStore.Grant(r.Context(), &grant)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoProductsFound) ||
+			errors.Is(err, domain.ErrNoUsersFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else if errors.Is(err, domain.ErrNotDigital) {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+		} else if errors.Is(err, domain.ErrNoLicenseKeys) {
+			Errorf(w, r, http.StatusConflict, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapDownloadGrant{Grant: grant}, http.StatusCreated)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      List user downloads
+// @Tags 		 Digital
+// @Security     Bearer
+// @Produce      json
+// @Success      200                  {array}     domain.WrapDownloadGrantList
+// @Failure      401                  {object}    http.WrapError
+// @Failure      404                  {object}    http.WrapError
+// @Failure      500                  {object}    http.WrapError
+// @Router       /users/me/downloads  [get]
+func (s *server) listUserGrantsHandler(w http.ResponseWriter, r *http.Request) {
+	grants, err := s.DigitalStore.ListGrants(r.Context(), userIDFromContext(r.Context()))
+	if err != nil {
+		if errors.Is(err, domain.ErrNoGrantsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapDownloadGrantList{Grants: grants}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Create download link
+// @Tags 		 Digital
+// @Security     Bearer
+// @Produce      json
+// @Param        grantID                                          path        int  true "Grant ID"
+// @Param        fileID                                           path        int  true "File ID"
+// @Success      201                                              {array}     domain.WrapDownloadLink
+// @Failure      400                                              {object}    http.WrapError
+// @Failure      401                                              {object}    http.WrapError
+// @Failure      404                                              {object}    http.WrapError
+// @Failure      500                                              {object}    http.WrapError
+// @Router       /users/me/downloads/{grantID}/files/{fileID}/link [post]
+func (s *server) createDownloadLinkHandler(w http.ResponseWriter, r *http.Request) {
+	grantID, err := strconv.Atoi(chi.URLParam(r, "grantID"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	fileID, err := strconv.Atoi(chi.URLParam(r, "fileID"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	grant, file, err := s.grantedFile(r, grantID, fileID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoGrantsFound) ||
+			errors.Is(err, domain.ErrNoFilesFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	if grant.UserID != userIDFromContext(r.Context()) {
+		Errorf(w, r, http.StatusNotFound, domain.ErrNoGrantsFound.Error())
+		return
+	}
+
+	if grant.Downloads >= grant.MaxDownloads {
+		Errorf(w, r, http.StatusForbidden, domain.ErrDownloadLimitReached.Error())
+		return
+	}
+
+	expiry := time.Now().Add(domain.DownloadLinkExpiry).Truncate(time.Second)
+	link := domain.DownloadLink{
+		URL: fmt.Sprintf("/downloads/%d/files/%d?expires=%d&signature=%s",
+			grant.ID, file.ID, expiry.Unix(),
+			domain.SignDownload(s.DownloadSecret, grant, file.ID, expiry)),
+		Expiry: expiry,
+	}
+
+	err = ToJSON(w, domain.WrapDownloadLink{Link: link}, http.StatusCreated)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Download file
+// @Tags 		 Digital
+// @Produce      octet-stream
+// @Param        grantID                                   path        int     true "Grant ID"
+// @Param        fileID                                    path        int     true "File ID"
+// @Param        expires                                   query       int     true "Link expiry"
+// @Param        signature                                 query       string  true "Link signature"
+// @Success      200
+// @Failure      400                                       {object}    http.WrapError
+// @Failure      403                                       {object}    http.WrapError
+// @Failure      404                                       {object}    http.WrapError
+// @Failure      500                                       {object}    http.WrapError
+// @Router       /downloads/{grantID}/files/{fileID}       [get]
+func (s *server) downloadHandler(w http.ResponseWriter, r *http.Request) {
+	grantID, err := strconv.Atoi(chi.URLParam(r, "grantID"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	fileID, err := strconv.Atoi(chi.URLParam(r, "fileID"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	expires, err := ParseIntQuery(r, "expires")
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	grant, file, err := s.grantedFile(r, grantID, fileID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoGrantsFound) ||
+			errors.Is(err, domain.ErrNoFilesFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = domain.VerifyDownload(s.DownloadSecret, grant, file.ID,
+		time.Unix(int64(expires), 0), r.URL.Query().Get("signature"))
+	if err != nil {
+		Errorf(w, r, http.StatusForbidden, err.Error())
+		return
+	}
+
+	err = s.DigitalStore.UseDownload(r.Context(), grant.ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrDownloadLimitReached) {
+			Errorf(w, r, http.StatusForbidden, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	contents, err := s.BlobStore.Get(r.Context(), file.BlobKey)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer contents.Close()
+
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(file.Size, 10))
+	w.Header().Set("Content-Disposition",
+		mime.FormatMediaType("attachment", map[string]string{"filename": file.Name}))
+	w.WriteHeader(http.StatusOK)
+
+	_, err = io.Copy(w, contents)
+	if err != nil {
+		logError(r, err.Error())
+	}
+}
+
+// grantedFile returns grant and file when the file belongs to the
+// product of grant.
+func (s *server) grantedFile(r *http.Request, grantID int, fileID int) (domain.DownloadGrant, domain.ProductFile, error) {
+	grant, err := s.DigitalStore.GetGrant(r.Context(), grantID)
+	if err != nil {
+		return domain.DownloadGrant{}, domain.ProductFile{}, err
+	}
+
+	file, err := s.DigitalStore.GetFile(r.Context(), fileID)
+	if err != nil {
+		return domain.DownloadGrant{}, domain.ProductFile{}, err
+	}
+
+	if file.ProductID != grant.ProductID {
+		return domain.DownloadGrant{}, domain.ProductFile{}, domain.ErrNoFilesFound
+	}
+
+	return grant, file, nil
+}
+
+// newBlobKey returns a random blob key for files of product.
+func newBlobKey(productID int) (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("products/%d/%s", productID, hex.EncodeToString(b)), nil
+}
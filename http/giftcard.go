@@ -0,0 +1,386 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// registerGiftCardsRoutes registers routes of gift cards, balance of a
+// code is public and the rest is for admins.
+func (s *server) registerGiftCardsRoutes(r *chi.Mux) {
+	r.Route("/gift-cards", func(r chi.Router) {
+		r.Get("/balance", s.getGiftCardBalanceHandler)
+
+		r.With(s.requireAdmin).Get("/", s.listGiftCardsHandler)
+		r.With(s.requireAdmin).Post("/", s.createGiftCardHandler)
+		r.With(s.requireAdmin).Get("/{id}", s.getGiftCardHandler)
+		r.With(s.requireAdmin).Post("/{id}/adjust", s.adjustGiftCardHandler)
+		r.With(s.requireAdmin).Post("/{id}/void", s.voidGiftCardHandler)
+	})
+}
+
+// @Summary      List gift cards
+// @Tags 		 GiftCards
+// @Security     Bearer
+// @Produce      json
+// @Param        code         query       string  false "Filter by code"
+// @Param        limit        query       string  false "Limit results"
+// @Param        offset       query       string  false "Offset results"
+// @Param        sort         query       string  false "Sort by column"
+// @Success      200  {object}  domain.WrapGiftCardList
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /gift-cards   [get]
+func (s *server) listGiftCardsHandler(w http.ResponseWriter, r *http.Request) {
+	limit, err := ParseIntQuery(r, "limit")
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	offset, err := ParseIntQuery(r, "offset")
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	filter := domain.GiftCardFilter{
+		Code:   r.URL.Query().Get("code"),
+		Sort:   r.URL.Query().Get("sort"),
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	cards, err := s.GiftCardsStore.List(r.Context(), filter)
+	if err != nil {
+		errorGiftCard(w, r, err)
+		return
+	}
+
+	err = ToJSON(w, domain.WrapGiftCardList{GiftCards: cards}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Issue gift card
+// @Description  A random code is generated unless one is given.
+// @Tags 		 GiftCards
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        gift_card  body     domain.GiftCardCreate true "Issue gift card"
+// @Success      201  {object}  domain.WrapGiftCard
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /gift-cards   [post]
+func (s *server) createGiftCardHandler(w http.ResponseWriter, r *http.Request) {
+	input := domain.GiftCardCreate{}
+	err := FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	code, err := domain.GenerateGiftCardCode()
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	card := input.CreateModel(code)
+	err = s.GiftCardsStore.Create(r.Context(), &card)
+	if err != nil {
+		errorGiftCard(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/gift-cards/%d", card.ID))
+	err = ToJSON(w, domain.WrapGiftCard{GiftCard: card}, http.StatusCreated)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Get gift card
+// @Tags 		 GiftCards
+// @Security     Bearer
+// @Produce      json
+// @Param        id   path      int  true "Gift card ID"
+// @Success      200  {object}  domain.WrapGiftCard
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /gift-cards/{id}   [get]
+func (s *server) getGiftCardHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	card, err := s.GiftCardsStore.GetByID(r.Context(), ID)
+	if err != nil {
+		errorGiftCard(w, r, err)
+		return
+	}
+
+	err = ToJSON(w, domain.WrapGiftCard{GiftCard: card}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Adjust gift card balance
+// @Tags 		 GiftCards
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        id          path      int  true "Gift card ID"
+// @Param        adjustment  body      domain.CreditAdjustment true "Adjust balance"
+// @Success      200  {object}  domain.WrapGiftCard
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /gift-cards/{id}/adjust   [post]
+func (s *server) adjustGiftCardHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	input := domain.CreditAdjustment{}
+	err = FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	card, err := s.GiftCardsStore.Adjust(r.Context(), ID, input)
+	if err != nil {
+		errorGiftCard(w, r, err)
+		return
+	}
+
+	err = ToJSON(w, domain.WrapGiftCard{GiftCard: card}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Void gift card
+// @Tags 		 GiftCards
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        id    path      int  true "Gift card ID"
+// @Param        void  body      domain.GiftCardVoid false "Void gift card"
+// @Success      200  {object}  domain.WrapGiftCard
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /gift-cards/{id}/void   [post]
+func (s *server) voidGiftCardHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	input := domain.GiftCardVoid{}
+	if r.ContentLength != 0 {
+		err = FromJSON(w, r, &input)
+		if err != nil {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	card, err := s.GiftCardsStore.Void(r.Context(), ID, input.Note)
+	if err != nil {
+		errorGiftCard(w, r, err)
+		return
+	}
+
+	err = ToJSON(w, domain.WrapGiftCard{GiftCard: card}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Get gift card balance
+// @Tags 		 GiftCards
+// @Produce      json
+// @Param        code  query     string  true "Gift card code"
+// @Success      200  {object}  domain.WrapGiftCardBalance
+// @Failure      400  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /gift-cards/balance   [get]
+func (s *server) getGiftCardBalanceHandler(w http.ResponseWriter, r *http.Request) {
+	code := domain.NormalizeGiftCardCode(r.URL.Query().Get("code"))
+	if code == "" {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	cards, err := s.GiftCardsStore.List(r.Context(), domain.GiftCardFilter{Code: code})
+	if err != nil {
+		errorGiftCard(w, r, err)
+		return
+	}
+
+	err = ToJSON(w, domain.WrapGiftCardBalance{GiftCard: cards[0].BalanceView()}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Get store credit of current user
+// @Tags 		 StoreCredit
+// @Security     Bearer
+// @Produce      json
+// @Success      200  {object}  domain.WrapStoreCredit
+// @Failure      401  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /users/me/store-credit   [get]
+func (s *server) getUserStoreCreditHandler(w http.ResponseWriter, r *http.Request) {
+	credit, err := s.StoreCreditStore.Get(r.Context(), userIDFromContext(r.Context()))
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = ToJSON(w, domain.WrapStoreCredit{StoreCredit: credit}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Get store credit of user
+// @Tags 		 StoreCredit
+// @Security     Bearer
+// @Produce      json
+// @Param        id   path      int  true "User ID"
+// @Success      200  {object}  domain.WrapStoreCredit
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /users/{id}/store-credit   [get]
+func (s *server) getStoreCreditHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	credit, err := s.StoreCreditStore.Get(r.Context(), ID)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = ToJSON(w, domain.WrapStoreCredit{StoreCredit: credit}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Adjust store credit of user
+// @Tags 		 StoreCredit
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        id          path      int  true "User ID"
+// @Param        adjustment  body      domain.CreditAdjustment true "Adjust balance"
+// @Success      200  {object}  domain.WrapStoreCredit
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /users/{id}/store-credit/adjust   [post]
+func (s *server) adjustStoreCreditHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	input := domain.CreditAdjustment{}
+	err = FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	credit, err := s.StoreCreditStore.Adjust(r.Context(), ID, input)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoUsersFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else if errors.Is(err, domain.ErrStoreCreditBalance) {
+			Errorf(w, r, http.StatusConflict, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapStoreCredit{StoreCredit: credit}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// errorGiftCard writes error of gift card stores.
+func errorGiftCard(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, domain.ErrNoGiftCardsFound) {
+		Errorf(w, r, http.StatusNotFound, err.Error())
+	} else if errors.Is(err, domain.ErrDuplicatedGiftCard) ||
+		errors.Is(err, domain.ErrGiftCardVoided) ||
+		errors.Is(err, domain.ErrGiftCardBalance) {
+		Errorf(w, r, http.StatusConflict, err.Error())
+	} else {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
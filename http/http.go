@@ -27,7 +27,11 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/mortezadadgar/ecommerce-api/blob"
+	"github.com/mortezadadgar/ecommerce-api/carrier"
+	"github.com/mortezadadgar/ecommerce-api/document"
 	"github.com/mortezadadgar/ecommerce-api/domain"
+	"github.com/mortezadadgar/ecommerce-api/payment"
 	"github.com/mortezadadgar/ecommerce-api/postgres"
 
 	// http-swagger
@@ -42,9 +46,56 @@ type server struct {
 	CategoriesStore domain.CategoryService
 	TokensStore     domain.TokenService
 	CartsStore      domain.CartService
+	BundlesStore    domain.BundleService
 	SearchStore     domain.Searcher
 	Store           store
 
+	ProductTranslationsStore  domain.TranslationService
+	CategoryTranslationsStore domain.TranslationService
+
+	DigitalStore   domain.DigitalService
+	BlobStore      domain.BlobStore
+	DownloadSecret []byte
+
+	CartMergeStrategy string
+
+	RecoveriesStore domain.CartRecoveryService
+	RecoverySecret  []byte
+
+	OrdersStore domain.OrderService
+
+	PaymentsStore   domain.PaymentService
+	PaymentGateways map[string]domain.PaymentGateway
+	PaymentProvider string
+
+	ReturnsStore domain.ReturnService
+
+	IdempotencyStore domain.IdempotencyService
+
+	InventoryStore          domain.InventoryService
+	StockSubscriptionsStore domain.StockSubscriptionService
+
+	CouponsStore domain.CouponService
+
+	TaxStore domain.TaxService
+
+	ShippingStore    domain.ShippingService
+	ShippingProvider domain.ShippingRateProvider
+
+	AddressesStore domain.AddressService
+	WishlistsStore domain.WishlistService
+
+	ShipmentsStore domain.ShipmentService
+	Carriers       map[string]domain.CarrierTracker
+
+	InvoicesStore domain.InvoiceService
+	Documents     domain.DocumentRenderer
+
+	GiftCardsStore   domain.GiftCardService
+	StoreCreditStore domain.StoreCreditService
+
+	LoyaltyStore domain.LoyaltyService
+
 	*http.Server
 }
 
@@ -62,7 +113,37 @@ func New(pg postgres.Postgres) *server {
 	s.CategoriesStore = postgres.NewCategoryStore(pg.DB)
 	s.TokensStore = postgres.NewTokenStore(pg.DB)
 	s.CartsStore = postgres.NewCartStore(pg.DB)
+	s.BundlesStore = postgres.NewBundleStore(pg.DB)
 	s.SearchStore = postgres.NewSearchStore(pg.DB)
+	s.ProductTranslationsStore = postgres.NewProductTranslationStore(pg.DB)
+	s.CategoryTranslationsStore = postgres.NewCategoryTranslationStore(pg.DB)
+	s.DigitalStore = postgres.NewDigitalStore(pg.DB)
+	s.BlobStore = blob.NewFileSystem(os.Getenv("BLOB_DIR"))
+	s.DownloadSecret = []byte(os.Getenv("DOWNLOAD_SECRET"))
+	s.CartMergeStrategy = os.Getenv("CART_MERGE_STRATEGY")
+	s.RecoveriesStore = postgres.NewCartRecoveryStore(pg.DB)
+	s.RecoverySecret = []byte(os.Getenv("CART_RECOVERY_SECRET"))
+	s.OrdersStore = postgres.NewOrderStore(pg.DB)
+	s.PaymentsStore = postgres.NewPaymentStore(pg.DB)
+	s.PaymentProvider = os.Getenv("PAYMENT_PROVIDER")
+	s.PaymentGateways = newPaymentGateways()
+	s.ReturnsStore = postgres.NewReturnStore(pg.DB)
+	s.IdempotencyStore = postgres.NewIdempotencyStore(pg.DB)
+	s.InventoryStore = postgres.NewInventoryStore(pg.DB)
+	s.StockSubscriptionsStore = postgres.NewStockSubscriptionStore(pg.DB)
+	s.CouponsStore = postgres.NewCouponStore(pg.DB)
+	s.TaxStore = postgres.NewTaxStore(pg.DB)
+	s.ShippingStore = postgres.NewShippingStore(pg.DB)
+	s.ShippingProvider = s.ShippingStore
+	s.AddressesStore = postgres.NewAddressStore(pg.DB)
+	s.WishlistsStore = postgres.NewWishlistStore(pg.DB)
+	s.ShipmentsStore = postgres.NewShipmentStore(pg.DB)
+	s.Carriers = newCarriers()
+	s.InvoicesStore = postgres.NewInvoiceStore(pg.DB)
+	s.Documents = newDocuments()
+	s.GiftCardsStore = postgres.NewGiftCardStore(pg.DB)
+	s.StoreCreditStore = postgres.NewStoreCreditStore(pg.DB)
+	s.LoyaltyStore = postgres.NewLoyaltyStore(pg.DB)
 	s.Store = &pg
 
 	r.Use(middleware.Logger)
@@ -70,12 +151,24 @@ func New(pg postgres.Postgres) *server {
 	r.Use(middleware.StripSlashes)
 	r.Use(middleware.Timeout(5 * time.Second))
 	r.Use(s.authentication)
+	r.Use(s.idempotency)
 
 	s.registerUsersRoutes(r)
 	s.registerProductsRoutes(r)
 	s.registerCategoriesRoutes(r)
 	s.registerCartsRoutes(r)
 	s.registerSearchRoutes(r)
+	s.registerDownloadsRoutes(r)
+	s.registerOrdersRoutes(r)
+	s.registerPaymentsRoutes(r)
+	s.registerInventoryRoutes(r)
+	s.registerCouponsRoutes(r)
+	s.registerTaxRoutes(r)
+	s.registerShippingRoutes(r)
+	s.registerInvoicesRoutes(r)
+	s.registerWishlistsRoutes(r)
+	s.registerGiftCardsRoutes(r)
+	s.registerLoyaltyRoutes(r)
 	registerSwaggerUI(r)
 
 	r.Get("/healthcheck", s.healthHandler)
@@ -85,6 +178,47 @@ func New(pg postgres.Postgres) *server {
 	return &s
 }
 
+// newPaymentGateways returns gateways configured by environment, mock
+// gateway is only available when it is the payment provider.
+func newPaymentGateways() map[string]domain.PaymentGateway {
+	gateways := make(map[string]domain.PaymentGateway)
+
+	if os.Getenv("PAYMENT_PROVIDER") == "mock" {
+		gateways["mock"] = payment.NewMock([]byte(os.Getenv("MOCK_WEBHOOK_SECRET")))
+	}
+
+	if key := os.Getenv("STRIPE_SECRET_KEY"); key != "" {
+		gateways["stripe"] = payment.NewStripe(os.Getenv("STRIPE_API_URL"), key,
+			[]byte(os.Getenv("STRIPE_WEBHOOK_SECRET")))
+	}
+
+	return gateways
+}
+
+// newCarriers returns carriers configured by environment, mock carrier
+// stands in for real ones when its webhook secret is set.
+func newCarriers() map[string]domain.CarrierTracker {
+	carriers := make(map[string]domain.CarrierTracker)
+
+	if secret := os.Getenv("MOCK_CARRIER_SECRET"); secret != "" {
+		carriers["mock"] = carrier.NewMock([]byte(secret))
+	}
+
+	return carriers
+}
+
+// newDocuments returns renderer of documents with template configured by
+// environment, default template is used when it fails to load.
+func newDocuments() domain.DocumentRenderer {
+	template, err := document.LoadTemplate(os.Getenv("DOCUMENT_TEMPLATE"))
+	if err != nil {
+		log.Printf("using default document template: %v", err)
+		template = document.DefaultTemplate()
+	}
+
+	return document.NewPDF(template)
+}
+
 // Start starts the server.
 func (s *server) Start() error {
 	l, err := net.Listen("tcp", os.Getenv("ADDRESS"))
@@ -240,6 +374,46 @@ func requireAuth(next http.Handler) http.Handler {
 
 }
 
+// requireAdmin rejects requests of users other than admins.
+func (s *server) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := userIDFromContext(r.Context())
+		if userID == 0 {
+			Errorf(w, r, http.StatusUnauthorized, "unauthorized access")
+			return
+		}
+
+		user, err := s.UsersStore.GetByID(r.Context(), userID)
+		if err != nil {
+			if errors.Is(err, domain.ErrNoUsersFound) {
+				Errorf(w, r, http.StatusUnauthorized, "unauthorized access")
+			} else {
+				Errorf(w, r, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+
+		if !user.Admin {
+			Errorf(w, r, http.StatusForbidden, "forbidden access")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireUser rejects requests without an authenticated user.
+func requireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if userIDFromContext(r.Context()) == 0 {
+			Errorf(w, r, http.StatusUnauthorized, "unauthorized access")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func registerSwaggerUI(r *chi.Mux) {
 	fs := http.FileServer(http.Dir("./swagger"))
 	r.With(requireAuth).Handle("/swagger/swagger.json", http.StripPrefix("/swagger", fs))
@@ -0,0 +1,132 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// responseRecorder records status, headers and body of a response while
+// writing it.
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(code int) {
+	rec.statusCode = code
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// idempotency a middleware that replays the first response of POST
+// requests retried with the same Idempotency-Key header. Server errors are
+// not stored so they may be retried.
+func (s *server) idempotency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		err := domain.ValidateIdempotencyKey(key)
+		if err != nil {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytesBodyRead))
+		if err != nil {
+			var MaxBytesError *http.MaxBytesError
+			if errors.As(err, &MaxBytesError) {
+				Errorf(w, r, http.StatusRequestEntityTooLarge, "exceeded maximum of 1M request body size")
+			} else {
+				Errorf(w, r, http.StatusBadRequest, "failed to read request body")
+			}
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := idempotencyScope(r)
+		hash := domain.HashRequest(r.Method, r.URL.Path, body)
+
+		response, err := s.IdempotencyStore.Begin(r.Context(), scope, key, hash)
+		if err != nil {
+			if errors.Is(err, domain.ErrIdempotencyKeyInFlight) {
+				Errorf(w, r, http.StatusConflict, err.Error())
+			} else if errors.Is(err, domain.ErrIdempotencyKeyReused) {
+				Errorf(w, r, http.StatusUnprocessableEntity, err.Error())
+			} else {
+				Errorf(w, r, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+
+		if response != nil {
+			for name, values := range response.Header {
+				w.Header()[name] = values
+			}
+			w.Header().Set(idempotentReplayedHeader, strconv.FormatBool(true))
+			w.WriteHeader(response.StatusCode)
+			_, _ = w.Write(response.Body)
+			return
+		}
+
+		// request context may be cancelled by now, keys are settled anyway
+		// so retries are not blocked until lock timeout.
+		ctx := context.Background()
+
+		defer func() {
+			if p := recover(); p != nil {
+				_ = s.IdempotencyStore.Release(ctx, scope, key)
+				panic(p)
+			}
+		}()
+
+		rec := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		if rec.statusCode >= http.StatusInternalServerError {
+			err = s.IdempotencyStore.Release(ctx, scope, key)
+		} else {
+			err = s.IdempotencyStore.Complete(ctx, scope, key, domain.IdempotentResponse{
+				StatusCode: rec.statusCode,
+				Header:     w.Header().Clone(),
+				Body:       rec.body.Bytes(),
+			})
+		}
+		if err != nil {
+			logError(r, err.Error())
+		}
+	})
+}
+
+// idempotencyScope returns scope of idempotency keys of request, keys of
+// users and guests never collide with each other.
+func idempotencyScope(r *http.Request) string {
+	owner := cartOwner(r)
+	switch {
+	case owner.UserID != 0:
+		return "user:" + strconv.Itoa(owner.UserID)
+	case owner.Token != nil:
+		return "guest:" + hex.EncodeToString(owner.Token)
+	}
+	return "anonymous"
+}
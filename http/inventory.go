@@ -0,0 +1,315 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// registerInventoryRoutes registers routes of warehouses and stock ledger.
+func (s *server) registerInventoryRoutes(r *chi.Mux) {
+	r.Route("/warehouses", func(r chi.Router) {
+		r.With(requireAuth).Get("/", s.listWarehousesHandler)
+		r.With(requireAuth).Post("/", s.createWarehouseHandler)
+		r.With(requireAuth).Get("/{id}", s.getWarehouseHandler)
+		r.With(requireAuth).Get("/{id}/stock", s.warehouseStockHandler)
+	})
+
+	r.Route("/stock/movements", func(r chi.Router) {
+		r.With(requireAuth).Get("/", s.listStockMovementsHandler)
+		r.With(requireAuth).Post("/", s.createStockMovementHandler)
+	})
+}
+
+// @Summary      List warehouses
+// @Tags 		 Inventory
+// @Security     Bearer
+// @Produce      json
+// @Success      200  {object}  domain.WrapWarehouseList
+// @Failure      403  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /warehouses   [get]
+func (s *server) listWarehousesHandler(w http.ResponseWriter, r *http.Request) {
+	warehouses, err := s.InventoryStore.ListWarehouses(r.Context())
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = ToJSON(w, domain.WrapWarehouseList{Warehouses: warehouses}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Create warehouse
+// @Tags 		 Inventory
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        warehouse  body     domain.WarehouseCreate true "Create warehouse"
+// @Success      201  {object}  domain.WrapWarehouse
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /warehouses   [post]
+func (s *server) createWarehouseHandler(w http.ResponseWriter, r *http.Request) {
+	input := domain.WarehouseCreate{}
+	err := FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	warehouse := input.CreateModel()
+	err = s.InventoryStore.CreateWarehouse(r.Context(), &warehouse)
+	if err != nil {
+		if errors.Is(err, domain.ErrDuplicatedWarehouse) {
+			Errorf(w, r, http.StatusConflict, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/warehouses/%d", warehouse.ID))
+	err = ToJSON(w, domain.WrapWarehouse{Warehouse: warehouse}, http.StatusCreated)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Get warehouse
+// @Tags 		 Inventory
+// @Security     Bearer
+// @Produce      json
+// @Param        id   path      int  true "Warehouse ID"
+// @Success      200  {object}  domain.WrapWarehouse
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /warehouses/{id}   [get]
+func (s *server) getWarehouseHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	warehouse, err := s.InventoryStore.GetWarehouse(r.Context(), ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoWarehousesFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapWarehouse{Warehouse: warehouse}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Get warehouse stock
+// @Description  Returns on hand quantity of products kept in a warehouse.
+// @Tags 		 Inventory
+// @Security     Bearer
+// @Produce      json
+// @Param        id   path      int  true "Warehouse ID"
+// @Success      200  {object}  domain.WrapLocationStockList
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /warehouses/{id}/stock   [get]
+func (s *server) warehouseStockHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	stock, err := s.InventoryStore.WarehouseStock(r.Context(), ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoWarehousesFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapLocationStockList{Stock: stock}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Get product stock
+// @Description  Returns on hand, reserved and available quantity of a product by warehouse.
+// @Tags 		 Inventory
+// @Security     Bearer
+// @Produce      json
+// @Param        id   path      int  true "Product ID"
+// @Success      200  {object}  domain.WrapProductStock
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /products/{id}/stock   [get]
+func (s *server) productStockHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	stock, err := s.InventoryStore.ProductStock(r.Context(), ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoProductsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else if errors.Is(err, domain.ErrBundleStockMovement) {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapProductStock{Stock: stock}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      List stock movements
+// @Tags 		 Inventory
+// @Security     Bearer
+// @Produce      json
+// @Param        product_id    query     int     false "Product ID"
+// @Param        warehouse_id  query     int     false "Warehouse ID"
+// @Param        type          query     string  false "Movement type"
+// @Param        reference     query     string  false "Reference"
+// @Param        limit         query     int     false "Limit"
+// @Param        offset        query     int     false "Offset"
+// @Param        sort          query     string  false "Sort"
+// @Success      200  {object}  domain.WrapStockMovementList
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /stock/movements   [get]
+func (s *server) listStockMovementsHandler(w http.ResponseWriter, r *http.Request) {
+	productID, err := ParseIntQuery(r, "product_id")
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	warehouseID, err := ParseIntQuery(r, "warehouse_id")
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	limit, err := ParseIntQuery(r, "limit")
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	offset, err := ParseIntQuery(r, "offset")
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	filter := domain.StockMovementFilter{
+		ProductID:   productID,
+		WarehouseID: warehouseID,
+		Type:        r.URL.Query().Get("type"),
+		Reference:   r.URL.Query().Get("reference"),
+		Sort:        r.URL.Query().Get("sort"),
+		Limit:       limit,
+		Offset:      offset,
+	}
+
+	movements, err := s.InventoryStore.ListMovements(r.Context(), filter)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoStockMovementsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapStockMovementList{Movements: movements}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Record stock movement
+// @Description  Records a receipt, return, adjustment or transfer in the stock ledger.
+// @Tags 		 Inventory
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        movement  body     domain.StockMovementCreate true "Record stock movement"
+// @Success      201  {object}  domain.WrapStockMovementList
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /stock/movements   [post]
+func (s *server) createStockMovementHandler(w http.ResponseWriter, r *http.Request) {
+	input := domain.StockMovementCreate{}
+	err := FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	movements := input.CreateModel(userIDFromContext(r.Context()))
+	movements, err = s.InventoryStore.RecordMovements(r.Context(), movements)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoProductsFound) ||
+			errors.Is(err, domain.ErrNoWarehousesFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else if errors.Is(err, domain.ErrBundleStockMovement) {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+		} else if errors.Is(err, domain.ErrInsufficientStock) {
+			Errorf(w, r, http.StatusConflict, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapStockMovementList{Movements: movements}, http.StatusCreated)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
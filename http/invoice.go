@@ -0,0 +1,383 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// registerInvoicesRoutes registers routes of invoices.
+func (s *server) registerInvoicesRoutes(r *chi.Mux) {
+	r.Route("/invoices", func(r chi.Router) {
+		r.With(requireUser).Get("/", s.listInvoicesHandler)
+		r.With(requireUser).Get("/{id}", s.getInvoiceHandler)
+		r.With(requireUser).Get("/{id}/pdf", s.invoicePDFHandler)
+	})
+}
+
+// @Summary      List invoices
+// @Description  Lists invoices and credit notes of orders of current user.
+// @Tags 		 Invoices
+// @Security     Bearer
+// @Produce      json
+// @Param        order_id     query       string  false "Filter by order"
+// @Param        kind         query       string  false "Filter by kind"
+// @Param        limit        query       string  false "Limit results"
+// @Param        offset       query       string  false "Offset results"
+// @Param        sort         query       string  false "Sort by id, issued_at, number or total"
+// @Success      200  {object}  domain.WrapInvoiceList
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /invoices        [get]
+func (s *server) listInvoicesHandler(w http.ResponseWriter, r *http.Request) {
+	orderID, err := ParseIntQuery(r, "order_id")
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	limit, err := ParseIntQuery(r, "limit")
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	offset, err := ParseIntQuery(r, "offset")
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	filter := domain.InvoiceFilter{
+		OrderID: orderID,
+		UserID:  userIDFromContext(r.Context()),
+		Kind:    r.URL.Query().Get("kind"),
+		Sort:    r.URL.Query().Get("sort"),
+		Limit:   limit,
+		Offset:  offset,
+	}
+
+	err = filter.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	invoices, err := s.InvoicesStore.List(r.Context(), filter)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoInvoicesFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapInvoiceList{Invoices: invoices}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Get invoice
+// @Description  Returns an invoice or credit note of an order of current user.
+// @Tags 		 Invoices
+// @Security     Bearer
+// @Produce      json
+// @Param        id    path     int  true "Invoice ID"
+// @Success      200  {object}  domain.WrapInvoice
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /invoices/{id}   [get]
+func (s *server) getInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	invoice, err := s.userInvoice(r, ID)
+	if err != nil {
+		errorInvoice(w, r, err)
+		return
+	}
+
+	err = ToJSON(w, domain.WrapInvoice{Invoice: invoice}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Download invoice
+// @Description  Downloads an invoice or credit note of an order of current user as PDF.
+// @Tags 		 Invoices
+// @Security     Bearer
+// @Produce      application/pdf
+// @Param        id    path     int  true "Invoice ID"
+// @Success      200  {file}    file
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /invoices/{id}/pdf   [get]
+func (s *server) invoicePDFHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	invoice, err := s.userInvoice(r, ID)
+	if err != nil {
+		errorInvoice(w, r, err)
+		return
+	}
+
+	s.writeInvoicePDF(w, r, invoice)
+}
+
+// @Summary      List order invoices
+// @Description  Lists invoice and credit notes of an order of current user.
+// @Tags 		 Invoices
+// @Security     Bearer
+// @Produce      json
+// @Param        id    path     int  true "Order ID"
+// @Success      200  {object}  domain.WrapInvoiceList
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /orders/{id}/invoices   [get]
+func (s *server) listOrderInvoicesHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	_, err = s.userOrder(r, ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoOrdersFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	invoices, err := s.InvoicesStore.List(r.Context(), domain.InvoiceFilter{OrderID: ID, Sort: "id"})
+	if err != nil {
+		if errors.Is(err, domain.ErrNoInvoicesFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapInvoiceList{Invoices: invoices}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Download order invoice
+// @Description  Downloads invoice of an order of current user as PDF, orders are invoiced once paid.
+// @Tags 		 Invoices
+// @Security     Bearer
+// @Produce      application/pdf
+// @Param        id    path     int  true "Order ID"
+// @Success      200  {file}    file
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /orders/{id}/invoice.pdf   [get]
+func (s *server) orderInvoicePDFHandler(w http.ResponseWriter, r *http.Request) {
+	invoice, err := s.orderInvoice(r, domain.InvoiceFilter{Kind: domain.InvoiceKindInvoice})
+	if err != nil {
+		errorInvoice(w, r, err)
+		return
+	}
+
+	s.writeInvoicePDF(w, r, invoice)
+}
+
+// @Summary      Download order invoice by id
+// @Description  Downloads an invoice or credit note of an order of current user as PDF.
+// @Tags 		 Invoices
+// @Security     Bearer
+// @Produce      application/pdf
+// @Param        id         path     int  true "Order ID"
+// @Param        invoiceID  path     int  true "Invoice ID"
+// @Success      200  {file}    file
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /orders/{id}/invoices/{invoiceID}/pdf   [get]
+func (s *server) orderInvoiceIDPDFHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "invoiceID"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	invoice, err := s.orderInvoice(r, domain.InvoiceFilter{ID: ID})
+	if err != nil {
+		errorInvoice(w, r, err)
+		return
+	}
+
+	s.writeInvoicePDF(w, r, invoice)
+}
+
+// @Summary      Download packing slip
+// @Description  Downloads packing slip of shippable lines of an order as PDF, only lines of shipment are listed when one is given.
+// @Tags 		 Invoices
+// @Security     Bearer
+// @Produce      application/pdf
+// @Param        id           path     int  true  "Order ID"
+// @Param        shipment_id  query    int  false "Shipment ID"
+// @Success      200  {file}    file
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /orders/{id}/packing-slip.pdf   [get]
+func (s *server) packingSlipPDFHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	shipmentID, err := ParseIntQuery(r, "shipment_id")
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	order, err := s.OrdersStore.GetByID(r.Context(), ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoOrdersFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	var shipment *domain.Shipment
+	if shipmentID != 0 {
+		found, err := s.ShipmentsStore.GetByID(r.Context(), shipmentID)
+		if err == nil && found.OrderID != order.ID {
+			err = domain.ErrNoShipmentsFound
+		}
+		if err != nil {
+			if errors.Is(err, domain.ErrNoShipmentsFound) {
+				Errorf(w, r, http.StatusNotFound, err.Error())
+			} else {
+				Errorf(w, r, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+		shipment = &found
+	}
+
+	var buf bytes.Buffer
+	err = s.Documents.RenderPackingSlip(&buf, order, shipment)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writePDF(w, r, "packing-slip-"+strconv.Itoa(order.ID)+".pdf", buf.Bytes())
+}
+
+// orderInvoice returns invoice matching filter of order of current user,
+// invoices of other orders are reported as not found.
+func (s *server) orderInvoice(r *http.Request, filter domain.InvoiceFilter) (domain.Invoice, error) {
+	orderID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		return domain.Invoice{}, errInvalidID
+	}
+
+	_, err = s.userOrder(r, orderID)
+	if err != nil {
+		return domain.Invoice{}, err
+	}
+
+	filter.OrderID = orderID
+	invoices, err := s.InvoicesStore.List(r.Context(), filter)
+	if err != nil {
+		return domain.Invoice{}, err
+	}
+
+	return invoices[0], nil
+}
+
+// userInvoice returns invoice of an order of current user, invoices of
+// other users are reported as not found.
+func (s *server) userInvoice(r *http.Request, ID int) (domain.Invoice, error) {
+	invoice, err := s.InvoicesStore.GetByID(r.Context(), ID)
+	if err != nil {
+		return domain.Invoice{}, err
+	}
+
+	_, err = s.userOrder(r, invoice.OrderID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoOrdersFound) {
+			return domain.Invoice{}, domain.ErrNoInvoicesFound
+		}
+		return domain.Invoice{}, err
+	}
+
+	return invoice, nil
+}
+
+// writeInvoicePDF renders invoice and writes it to w.
+func (s *server) writeInvoicePDF(w http.ResponseWriter, r *http.Request, invoice domain.Invoice) {
+	var buf bytes.Buffer
+	err := s.Documents.RenderInvoice(&buf, invoice)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writePDF(w, r, invoice.Number+".pdf", buf.Bytes())
+}
+
+// writePDF writes rendered PDF document to w.
+func writePDF(w http.ResponseWriter, r *http.Request, name string, document []byte) {
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Length", strconv.Itoa(len(document)))
+	w.Header().Set("Content-Disposition",
+		mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	w.WriteHeader(http.StatusOK)
+
+	_, err := w.Write(document)
+	if err != nil {
+		logError(r, err.Error())
+	}
+}
+
+// errorInvoice reports errors of invoices.
+func errorInvoice(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errInvalidID) {
+		ErrorInvalidQuery(w, r)
+	} else if errors.Is(err, domain.ErrNoInvoicesFound) || errors.Is(err, domain.ErrNoOrdersFound) {
+		Errorf(w, r, http.StatusNotFound, err.Error())
+	} else {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// registerLoyaltyRoutes registers routes of loyalty earn rules.
+func (s *server) registerLoyaltyRoutes(r *chi.Mux) {
+	r.Route("/loyalty/rules", func(r chi.Router) {
+		r.With(s.requireAdmin).Get("/", s.getLoyaltyRulesHandler)
+		r.With(s.requireAdmin).Put("/", s.setLoyaltyRulesHandler)
+	})
+}
+
+// @Summary      Get loyalty earn rules
+// @Tags 		 Loyalty
+// @Security     Bearer
+// @Produce      json
+// @Success      200  {object}  domain.WrapLoyaltyRules
+// @Failure      401  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /loyalty/rules   [get]
+func (s *server) getLoyaltyRulesHandler(w http.ResponseWriter, r *http.Request) {
+	rules, err := s.LoyaltyStore.GetRules(r.Context())
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = ToJSON(w, domain.WrapLoyaltyRules{Rules: rules}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Set loyalty earn rules
+// @Description  Replaces earn rules, lines of paid orders earn points per currency unit of their total net of tax. Bonus points of categories are added to the base rate. Points already earned are kept.
+// @Tags 		 Loyalty
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        rules  body     domain.LoyaltyRules true "Earn rules"
+// @Success      200  {object}  domain.WrapLoyaltyRules
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /loyalty/rules   [put]
+func (s *server) setLoyaltyRulesHandler(w http.ResponseWriter, r *http.Request) {
+	input := domain.LoyaltyRules{}
+	err := FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rules, err := s.LoyaltyStore.SetRules(r.Context(), input)
+	if err != nil {
+		if errors.Is(err, domain.ErrLoyaltyInvalidCategory) {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapLoyaltyRules{Rules: rules}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Get loyalty points of current user
+// @Description  Returns balance of loyalty points and its transactions from newest, points are redeemed at checkout and expire once they are kept unused long enough.
+// @Tags 		 Loyalty
+// @Security     Bearer
+// @Produce      json
+// @Success      200  {object}  domain.WrapLoyaltyAccount
+// @Failure      401  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /users/me/loyalty   [get]
+func (s *server) getUserLoyaltyHandler(w http.ResponseWriter, r *http.Request) {
+	account, err := s.LoyaltyStore.Get(r.Context(), userIDFromContext(r.Context()))
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = ToJSON(w, domain.WrapLoyaltyAccount{Loyalty: account}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
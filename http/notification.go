@@ -0,0 +1,83 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// @Summary      Subscribe to back in stock
+// @Description  Notifies current user once an out of stock product is available again.
+// @Tags 		 Products
+// @Security     Bearer
+// @Produce      json
+// @Param        id   path      int  true "Product ID"
+// @Success      201  {object}  domain.WrapStockSubscription
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /products/{id}/stock-subscription   [post]
+func (s *server) subscribeStockHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	subscription := domain.StockSubscription{
+		ProductID: ID,
+		UserID:    userIDFromContext(r.Context()),
+	}
+
+	err = s.StockSubscriptionsStore.Subscribe(r.Context(), &subscription)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoProductsFound) ||
+			errors.Is(err, domain.ErrNoUsersFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else if errors.Is(err, domain.ErrBundleStockMovement) {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+		} else if errors.Is(err, domain.ErrProductInStock) {
+			Errorf(w, r, http.StatusConflict, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapStockSubscription{Subscription: subscription}, http.StatusCreated)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Unsubscribe from back in stock
+// @Tags 		 Products
+// @Security     Bearer
+// @Param        id   path      int  true "Product ID"
+// @Success      200
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /products/{id}/stock-subscription   [delete]
+func (s *server) unsubscribeStockHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	err = s.StockSubscriptionsStore.Unsubscribe(r.Context(), ID, userIDFromContext(r.Context()))
+	if err != nil {
+		if errors.Is(err, domain.ErrNoStockSubscriptionsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+	}
+}
@@ -0,0 +1,438 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+func (s *server) registerOrdersRoutes(r *chi.Mux) {
+	r.With(requireUser).Post("/checkout", s.checkoutHandler)
+
+	r.Route("/orders", func(r chi.Router) {
+		r.With(requireUser).Get("/", s.listOrdersHandler)
+		r.With(requireUser).Get("/{id}", s.getOrderHandler)
+		r.With(requireUser).Get("/{id}/history", s.orderHistoryHandler)
+		r.With(requireUser).Post("/{id}/cancel", s.cancelOrderHandler)
+		r.With(s.requireAdmin).Post("/{id}/status", s.updateOrderStatusHandler)
+		r.With(requireUser).Get("/{id}/payments", s.listOrderPaymentsHandler)
+		r.With(requireUser).Post("/{id}/payments", s.createPaymentHandler)
+		r.Route("/{id}/returns", s.registerReturnsRoutes)
+		r.Route("/{id}/shipments", s.registerShipmentsRoutes)
+		r.With(requireUser).Get("/{id}/invoices", s.listOrderInvoicesHandler)
+		r.With(requireUser).Get("/{id}/invoices/{invoiceID}/pdf", s.orderInvoiceIDPDFHandler)
+		r.With(requireUser).Get("/{id}/invoice.pdf", s.orderInvoicePDFHandler)
+		r.With(requireAuth).Get("/{id}/packing-slip.pdf", s.packingSlipPDFHandler)
+	})
+
+	r.Post("/webhooks/carriers/{carrier}", s.carrierWebhookHandler)
+}
+
+// @Summary      Checkout
+// @Description  Places an order from current user's cart and empties the cart. A shipping method may be selected along, one is required once cart can be shipped to its destination. Default addresses of user are used unless given, the order keeps a copy of them. Loyalty points are taken off the order total, gift cards and then store credit pay for the rest as far as their balance goes. The order is paid once they cover its total, orders with nothing to pay are paid right away.
+// @Tags 		 Orders
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        checkout  body   domain.CheckoutInput false "Checkout"
+// @Success      201  {object}  domain.WrapOrder
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      422  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /checkout      [post]
+func (s *server) checkoutHandler(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+	owner := domain.CartOwner{UserID: userID}
+
+	input := domain.CheckoutInput{}
+	if r.ContentLength != 0 {
+		err := FromJSON(w, r, &input)
+		if err != nil {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		err = input.Validate()
+		if err != nil {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	shippingAddress, billingAddress, err := s.checkoutAddresses(r, input)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoAddressesFound) {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	details := domain.CheckoutDetails{ShippingMethodID: input.ShippingMethodID}
+	if shippingAddress != nil {
+		destination := shippingAddress.Location()
+		details.Destination = &destination
+		details.ShippingAddress = shippingAddress.Snapshot()
+	}
+
+	if billingAddress != nil {
+		details.BillingAddress = billingAddress.Snapshot()
+	}
+
+	cart, err := s.CartsStore.GetByOwner(r.Context(), owner)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoCartsFound) {
+			Errorf(w, r, http.StatusBadRequest, domain.ErrEmptyCart.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	// cart is quoted as it is going to be checked out, Checkout sets
+	// destination and shipping method on it along the order.
+	if details.Destination != nil {
+		cart.Destination = *details.Destination
+	}
+	if details.ShippingMethodID != 0 {
+		cart.ShippingMethodID = &details.ShippingMethodID
+	}
+
+	quote, err := s.shippingQuote(r.Context(), cart)
+	if err != nil {
+		if errors.Is(err, domain.ErrShippingMethodRequired) {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+		} else if errors.Is(err, domain.ErrShippingMethodNotAvailable) {
+			Errorf(w, r, http.StatusUnprocessableEntity, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	details.Shipping = quote
+	details.GiftCards = input.GiftCards
+	details.UseStoreCredit = input.UseStoreCredit
+	details.RedeemPoints = input.RedeemPoints
+	order, err := s.OrdersStore.Checkout(r.Context(), userID, details)
+	if err != nil {
+		if errors.Is(err, domain.ErrEmptyCart) ||
+			errors.Is(err, domain.ErrNoShippingMethodsFound) ||
+			errors.Is(err, domain.ErrNoGiftCardsFound) ||
+			errors.Is(err, domain.ErrGiftCardVoided) ||
+			errors.Is(err, domain.ErrGiftCardExpired) ||
+			errors.Is(err, domain.ErrGiftCardCurrency) {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+		} else if errors.Is(err, domain.ErrInsufficientStock) ||
+			errors.Is(err, domain.ErrProductConflict) ||
+			errors.Is(err, domain.ErrNoLicenseKeys) ||
+			errors.Is(err, domain.ErrShippingQuoteChanged) ||
+			errors.Is(err, domain.ErrLoyaltyBalance) {
+			Errorf(w, r, http.StatusConflict, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/orders/%d", order.ID))
+	err = ToJSON(w, domain.WrapOrder{Order: order}, http.StatusCreated)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      List orders
+// @Description  Lists orders of current user.
+// @Tags 		 Orders
+// @Security     Bearer
+// @Produce      json
+// @Param        limit        query       string  false "Limit results"
+// @Param        offset       query       string  false "Offset results"
+// @Param        sort         query       string  false "Sort by id, created_at, total or status"
+// @Success      200  {object}  domain.WrapOrderList
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /orders        [get]
+func (s *server) listOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	limit, err := ParseIntQuery(r, "limit")
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	offset, err := ParseIntQuery(r, "offset")
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	filter := domain.OrderFilter{
+		UserID: userIDFromContext(r.Context()),
+		Sort:   r.URL.Query().Get("sort"),
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	err = filter.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	orders, err := s.OrdersStore.List(r.Context(), filter)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoOrdersFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapOrderList{Orders: orders}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Get order
+// @Description  Gets an order of current user.
+// @Tags 		 Orders
+// @Security     Bearer
+// @Produce      json
+// @Param        id    path     int  true "Order ID"
+// @Success      200  {object}  domain.WrapOrder
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /orders/{id}   [get]
+func (s *server) getOrderHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	order, err := s.userOrder(r, ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoOrdersFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapOrder{Order: order}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Get order status history
+// @Tags 		 Orders
+// @Security     Bearer
+// @Produce      json
+// @Param        id    path     int  true "Order ID"
+// @Success      200  {object}  domain.WrapOrderStatusChangeList
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /orders/{id}/history   [get]
+func (s *server) orderHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	_, err = s.userOrder(r, ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoOrdersFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	history, err := s.OrdersStore.History(r.Context(), ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoOrdersFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapOrderStatusChangeList{History: history}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Cancel order
+// @Description  Cancels an order of current user before its fulfillment starts. Stock is given back, gift cards and store credit are restored and payments of paid orders are refunded through their payment gateway.
+// @Tags 		 Orders
+// @Security     Bearer
+// @Produce      json
+// @Param        id    path     int  true "Order ID"
+// @Success      200  {object}  domain.WrapOrder
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Failure      502  {object}  http.WrapError
+// @Router       /orders/{id}/cancel    [post]
+func (s *server) cancelOrderHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	order, err := s.OrdersStore.Cancel(r.Context(), ID, userIDFromContext(r.Context()))
+	if err != nil {
+		if errors.Is(err, domain.ErrNoOrdersFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else if errors.Is(err, domain.ErrOrderNotCancellable) ||
+			errors.Is(err, domain.ErrInvalidOrderTransition) {
+			Errorf(w, r, http.StatusConflict, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = s.refundCancelledOrder(r, order)
+	if err != nil {
+		logError(r, fmt.Sprintf("order %d is cancelled but not refunded: %v", order.ID, err))
+		errorGateway(w, r, err)
+		return
+	}
+
+	err = ToJSON(w, domain.WrapOrder{Order: order}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// refundCancelledOrder refunds what is left of payments of a cancelled
+// order made through payment gateways, gift cards and store credit are
+// restored by cancellation.
+func (s *server) refundCancelledOrder(r *http.Request, order domain.Order) error {
+	payments, err := s.PaymentsStore.List(r.Context(), domain.PaymentFilter{OrderID: order.ID, Sort: "id"})
+	if err != nil {
+		if errors.Is(err, domain.ErrNoPaymentsFound) {
+			return nil
+		}
+		return err
+	}
+
+	for _, payment := range payments {
+		amount := payment.Captured - payment.Refunded
+		if payment.Status != domain.PaymentStatusSucceeded || payment.StoredValue() || amount <= 0 {
+			continue
+		}
+
+		gateway, ok := s.PaymentGateways[payment.Provider]
+		if !ok {
+			return domain.ErrUnknownPaymentProvider
+		}
+
+		intent, err := gateway.Refund(r.Context(), payment.IntentID, amount)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.PaymentsStore.Sync(r.Context(), payment.ID, intent)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// @Summary      Update order status
+// @Tags 		 Orders
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        id      path     int  true "Order ID"
+// @Param        status  body     domain.OrderStatusUpdate true "Update status"
+// @Success      200  {object}  domain.WrapOrder
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      413  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /orders/{id}/status    [post]
+func (s *server) updateOrderStatusHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	input := domain.OrderStatusUpdate{}
+	err = FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	order, err := s.OrdersStore.UpdateStatus(r.Context(), ID, input.Status, userIDFromContext(r.Context()), input.Note)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoOrdersFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else if errors.Is(err, domain.ErrInvalidOrderTransition) {
+			Errorf(w, r, http.StatusConflict, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapOrder{Order: order}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// userOrder returns order of current user by id, orders of other users
+// are reported as not found.
+func (s *server) userOrder(r *http.Request, ID int) (domain.Order, error) {
+	order, err := s.OrdersStore.GetByID(r.Context(), ID)
+	if err != nil {
+		return domain.Order{}, err
+	}
+
+	if order.UserID != userIDFromContext(r.Context()) {
+		return domain.Order{}, domain.ErrNoOrdersFound
+	}
+
+	return order, nil
+}
@@ -0,0 +1,446 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+func (s *server) registerPaymentsRoutes(r *chi.Mux) {
+	r.Route("/payments", func(r chi.Router) {
+		r.With(s.requireAdmin).Get("/{id}", s.getPaymentHandler)
+		r.With(s.requireAdmin).Post("/{id}/capture", s.capturePaymentHandler)
+		r.With(s.requireAdmin).Post("/{id}/void", s.voidPaymentHandler)
+		r.With(s.requireAdmin).Post("/{id}/refund", s.refundPaymentHandler)
+	})
+
+	r.Post("/webhooks/payments/{provider}", s.paymentWebhookHandler)
+}
+
+type webhookResponse struct {
+	Received bool `json:"received"`
+}
+
+// @Summary      Start order payment
+// @Description  Creates a payment intent of what is left to pay of order at configured provider, client secret is used to authorize it. Orders paid in full by gift cards and store credit are not payable, nor are orders having a payment in progress.
+// @Tags 		 Payments
+// @Security     Bearer
+// @Produce      json
+// @Param        id    path     int  true "Order ID"
+// @Success      201  {object}  domain.WrapPayment
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      402  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Failure      502  {object}  http.WrapError
+// @Router       /orders/{id}/payments  [post]
+func (s *server) createPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	order, err := s.userOrder(r, ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoOrdersFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	if !order.CanPay() {
+		Errorf(w, r, http.StatusConflict, domain.ErrOrderNotPayable.Error())
+		return
+	}
+
+	gateway, ok := s.PaymentGateways[s.PaymentProvider]
+	if !ok {
+		Errorf(w, r, http.StatusInternalServerError, domain.ErrUnknownPaymentProvider.Error())
+		return
+	}
+
+	// gift cards and store credit may have paid part of order at checkout.
+	payments, err := s.PaymentsStore.List(r.Context(), domain.PaymentFilter{OrderID: order.ID})
+	if err != nil && !errors.Is(err, domain.ErrNoPaymentsFound) {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// customer completes or cancels an open intent before starting another.
+	for _, p := range payments {
+		if p.Open() {
+			Errorf(w, r, http.StatusConflict, domain.ErrPaymentInProgress.Error())
+			return
+		}
+	}
+
+	due := domain.AmountDue(order, payments)
+	if due == 0 {
+		Errorf(w, r, http.StatusConflict, domain.ErrOrderNotPayable.Error())
+		return
+	}
+
+	input := domain.PaymentIntentCreate{
+		OrderID:  order.ID,
+		Amount:   due,
+		Currency: domain.PaymentCurrency,
+	}
+
+	intent, err := gateway.CreateIntent(r.Context(), input)
+	if err != nil {
+		errorGateway(w, r, err)
+		return
+	}
+
+	payment := domain.NewPayment(order, s.PaymentProvider, intent)
+	err = s.PaymentsStore.Create(r.Context(), &payment)
+	if err != nil {
+		if errors.Is(err, domain.ErrOrderNotPayable) ||
+			errors.Is(err, domain.ErrPaymentInProgress) {
+			Errorf(w, r, http.StatusConflict, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapPayment{Payment: payment}, http.StatusCreated)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      List order payments
+// @Tags 		 Payments
+// @Security     Bearer
+// @Produce      json
+// @Param        id    path     int  true "Order ID"
+// @Success      200  {object}  domain.WrapPaymentList
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /orders/{id}/payments  [get]
+func (s *server) listOrderPaymentsHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	_, err = s.userOrder(r, ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoOrdersFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	payments, err := s.PaymentsStore.List(r.Context(), domain.PaymentFilter{OrderID: ID, Sort: "id"})
+	if err != nil {
+		if errors.Is(err, domain.ErrNoPaymentsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapPaymentList{Payments: payments}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Get payment
+// @Tags 		 Payments
+// @Security     Bearer
+// @Produce      json
+// @Param        id    path     int  true "Payment ID"
+// @Success      200  {object}  domain.WrapPayment
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /payments/{id} [get]
+func (s *server) getPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	payment, err := s.PaymentsStore.GetByID(r.Context(), ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoPaymentsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapPayment{Payment: payment}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Capture payment
+// @Description  Captures an authorized payment, zero amount captures the whole payment.
+// @Tags 		 Payments
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        id      path     int  true "Payment ID"
+// @Param        amount  body     domain.PaymentAmount true "Amount to capture"
+// @Success      200  {object}  domain.WrapPayment
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Failure      502  {object}  http.WrapError
+// @Router       /payments/{id}/capture [post]
+func (s *server) capturePaymentHandler(w http.ResponseWriter, r *http.Request) {
+	input := domain.PaymentAmount{}
+	err := FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.updatePayment(w, r, func(gateway domain.PaymentGateway, payment domain.Payment) (domain.PaymentIntent, error) {
+		return gateway.Capture(r.Context(), payment.IntentID, input.Amount)
+	})
+}
+
+// @Summary      Void payment
+// @Description  Cancels a payment not captured yet, its order is cancelled as well.
+// @Tags 		 Payments
+// @Security     Bearer
+// @Produce      json
+// @Param        id      path     int  true "Payment ID"
+// @Success      200  {object}  domain.WrapPayment
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Failure      502  {object}  http.WrapError
+// @Router       /payments/{id}/void [post]
+func (s *server) voidPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	s.updatePayment(w, r, func(gateway domain.PaymentGateway, payment domain.Payment) (domain.PaymentIntent, error) {
+		return gateway.Void(r.Context(), payment.IntentID)
+	})
+}
+
+// @Summary      Refund payment
+// @Description  Refunds a captured payment, zero amount refunds the rest of captured amount. Payments made with gift cards or store credit are refunded as store credit.
+// @Tags 		 Payments
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        id      path     int  true "Payment ID"
+// @Param        amount  body     domain.PaymentAmount true "Amount to refund"
+// @Success      200  {object}  domain.WrapPayment
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Failure      502  {object}  http.WrapError
+// @Router       /payments/{id}/refund [post]
+func (s *server) refundPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	input := domain.PaymentAmount{}
+	err := FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	payment, err := s.PaymentsStore.GetByID(r.Context(), ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoPaymentsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	if !payment.StoredValue() {
+		s.updatePayment(w, r, func(gateway domain.PaymentGateway, payment domain.Payment) (domain.PaymentIntent, error) {
+			return gateway.Refund(r.Context(), payment.IntentID, input.Amount)
+		})
+		return
+	}
+
+	amount := input.Amount
+	if amount == 0 {
+		amount = payment.Captured - payment.Refunded
+	}
+
+	payment, err = s.StoreCreditStore.Refund(r.Context(), payment.ID, amount, "payment refunded")
+	if err != nil {
+		if errors.Is(err, domain.ErrNoStoreCreditPayment) {
+			Errorf(w, r, http.StatusConflict, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapPayment{Payment: payment}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Receive payment webhook
+// @Description  Receives signed events of a payment provider, events are applied once.
+// @Tags 		 Payments
+// @Accept       json
+// @Produce      json
+// @Param        provider  path     string  true "Payment provider"
+// @Success      200  {object}  webhookResponse
+// @Failure      400  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /webhooks/payments/{provider} [post]
+func (s *server) paymentWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+	gateway, ok := s.PaymentGateways[provider]
+	if !ok {
+		Errorf(w, r, http.StatusNotFound, domain.ErrUnknownPaymentProvider.Error())
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytesBodyRead))
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, "failed to read webhook body")
+		return
+	}
+
+	event, err := gateway.ParseEvent(payload, r.Header.Get(gateway.SignatureHeader()))
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidWebhookSignature) {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = s.PaymentsStore.HandleEvent(r.Context(), provider, event)
+	if err != nil && !errors.Is(err, domain.ErrDuplicatedPaymentEvent) {
+		if errors.Is(err, domain.ErrNoPaymentsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, webhookResponse{Received: true}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// updatePayment runs an operation on payment by id at its gateway and
+// stores the resulting state.
+func (s *server) updatePayment(w http.ResponseWriter, r *http.Request,
+	fn func(gateway domain.PaymentGateway, payment domain.Payment) (domain.PaymentIntent, error),
+) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	payment, err := s.PaymentsStore.GetByID(r.Context(), ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoPaymentsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	gateway, ok := s.PaymentGateways[payment.Provider]
+	if !ok {
+		Errorf(w, r, http.StatusInternalServerError, domain.ErrUnknownPaymentProvider.Error())
+		return
+	}
+
+	intent, err := fn(gateway, payment)
+	if err != nil {
+		errorGateway(w, r, err)
+		return
+	}
+
+	payment, err = s.PaymentsStore.Sync(r.Context(), ID, intent)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoPaymentsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapPayment{Payment: payment}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// errorGateway reports errors of payment gateways.
+func errorGateway(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, domain.ErrPaymentDeclined) {
+		Errorf(w, r, http.StatusPaymentRequired, err.Error())
+	} else if errors.Is(err, domain.ErrInvalidPaymentState) {
+		Errorf(w, r, http.StatusConflict, err.Error())
+	} else if errors.Is(err, domain.ErrNoPaymentsFound) {
+		Errorf(w, r, http.StatusNotFound, err.Error())
+	} else if errors.Is(err, domain.ErrPaymentProviderConnection) {
+		Errorf(w, r, http.StatusBadGateway, err.Error())
+	} else {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -17,6 +17,22 @@ func (s *server) registerProductsRoutes(r *chi.Mux) {
 		r.With(requireAuth).Post("/", s.createProductHandler)
 		r.With(requireAuth).Patch("/{id}", s.updateProductHandler)
 		r.With(requireAuth).Delete("/{id}", s.deleteProductHandler)
+
+		r.Get("/{id}/translations", listTranslationsHandler(s.ProductTranslationsStore))
+		r.With(requireAuth).Put("/{id}/translations/{locale}", setTranslationHandler(s.ProductTranslationsStore))
+		r.With(requireAuth).Delete("/{id}/translations/{locale}", deleteTranslationHandler(s.ProductTranslationsStore))
+
+		r.Get("/{id}/components", s.listBundleComponentsHandler)
+		r.With(requireAuth).Get("/{id}/stock", s.productStockHandler)
+		r.With(requireUser).Post("/{id}/stock-subscription", s.subscribeStockHandler)
+		r.With(requireUser).Delete("/{id}/stock-subscription", s.unsubscribeStockHandler)
+		r.With(requireAuth).Put("/{id}/components", s.setBundleComponentsHandler)
+
+		r.With(requireAuth).Get("/{id}/files", s.listProductFilesHandler)
+		r.With(requireAuth).Post("/{id}/files", s.uploadProductFileHandler)
+		r.With(requireAuth).Delete("/{id}/files/{fileID}", s.deleteProductFileHandler)
+		r.With(s.requireAdmin).Post("/{id}/license-keys", s.addLicenseKeysHandler)
+		r.With(s.requireAdmin).Post("/{id}/grants", s.createGrantHandler)
 		// bulk inserts data to db
 	})
 }
@@ -25,6 +41,7 @@ func (s *server) registerProductsRoutes(r *chi.Mux) {
 // @Tags 		 Products
 // @Produce      json
 // @Param        id             path        int  true "Product ID"
+// @Param        locale         query       string  false "Content locale"
 // @Success      200            {array}     domain.WrapProduct
 // @Failure      400            {object}    http.WrapError
 // @Failure      404            {object}    http.WrapError
@@ -37,6 +54,12 @@ func (s *server) getProductHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	chain, err := localeChain(r)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	product, err := s.ProductsStore.GetByID(r.Context(), ID)
 	if err != nil {
 		if errors.Is(err, domain.ErrNoProductsFound) {
@@ -47,6 +70,14 @@ func (s *server) getProductHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	products := []domain.Product{product}
+	err = s.localizeProducts(r.Context(), chain, products)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	product = products[0]
+
 	err = ToJSON(w, domain.WrapProduct{Product: product}, http.StatusOK)
 	if err != nil {
 		Errorf(w, r, http.StatusInternalServerError, err.Error())
@@ -59,6 +90,7 @@ func (s *server) getProductHandler(w http.ResponseWriter, r *http.Request) {
 // @Param        offset       query       string  false "Offset results"
 // @Param        category_id  query       string  false "List by category id"
 // @Param        sort         query       string  false "Sort by a column"
+// @Param        locale       query       string  false "Content locale"
 // @Success      200          {array}     domain.WrapProductList
 // @Failure      400          {object}    http.WrapError
 // @Failure      404          {object}    http.WrapError
@@ -83,6 +115,12 @@ func (s *server) listProductsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	chain, err := localeChain(r)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	filter := domain.ProductFilter{
 		Sort:       r.URL.Query().Get("sort"),
 		CategoryID: category,
@@ -100,6 +138,12 @@ func (s *server) listProductsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = s.localizeProducts(r.Context(), chain, products)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	err = ToJSON(w, domain.WrapProductList{Products: products}, http.StatusOK)
 	if err != nil {
 		Errorf(w, r, http.StatusInternalServerError, err.Error())
@@ -136,7 +180,9 @@ func (s *server) createProductHandler(w http.ResponseWriter, r *http.Request) {
 	err = s.ProductsStore.Create(r.Context(), &product)
 	if err != nil {
 		if errors.Is(err, domain.ErrInvalidProductCategory) ||
-			errors.Is(err, domain.ErrDuplicatedProduct) {
+			errors.Is(err, domain.ErrDuplicatedProduct) ||
+			errors.Is(err, domain.ErrDuplicatedSKU) ||
+			errors.Is(err, domain.ErrInvalidBundleComponent) {
 			Errorf(w, r, http.StatusBadRequest, err.Error())
 		} else {
 			Errorf(w, r, http.StatusInternalServerError, err.Error())
@@ -186,7 +232,8 @@ func (s *server) updateProductHandler(w http.ResponseWriter, r *http.Request) {
 	product, err := s.ProductsStore.Update(r.Context(), ID, input)
 	if err != nil {
 		if errors.Is(err, domain.ErrInvalidProductCategory) ||
-			errors.Is(err, domain.ErrDuplicatedProduct) {
+			errors.Is(err, domain.ErrDuplicatedProduct) ||
+			errors.Is(err, domain.ErrDuplicatedSKU) {
 			Errorf(w, r, http.StatusBadRequest, err.Error())
 		} else if errors.Is(err, domain.ErrNoProductsFound) {
 			Errorf(w, r, http.StatusNotFound, err.Error())
@@ -228,3 +275,90 @@ func (s *server) deleteProductHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 }
+
+// @Summary      List bundle components
+// @Tags 		 Products
+// @Produce      json
+// @Param        id                        path        int  true "Product ID"
+// @Success      200                       {array}     domain.WrapBundleComponentList
+// @Failure      400                       {object}    http.WrapError
+// @Failure      404                       {object}    http.WrapError
+// @Failure      500                       {object}    http.WrapError
+// @Router       /products/{id}/components [get]
+func (s *server) listBundleComponentsHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	components, err := s.BundlesStore.ListComponents(r.Context(), ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoProductsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else if errors.Is(err, domain.ErrNotBundle) {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapBundleComponentList{Components: components}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Replace bundle components
+// @Tags 		 Products
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        id                        path        int  true "Product ID"
+// @Param        components                body        domain.BundleComponentInput true "Bundle components"
+// @Success      200                       {array}     domain.WrapBundleComponentList
+// @Failure      400                       {object}    http.WrapError
+// @Failure      403                       {object}    http.WrapError
+// @Failure      404                       {object}    http.WrapError
+// @Failure      413                       {object}    http.WrapError
+// @Failure      500                       {object}    http.WrapError
+// @Router       /products/{id}/components [put]
+func (s *server) setBundleComponentsHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	input := domain.BundleComponentInput{}
+	err = FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	components, err := s.BundlesStore.SetComponents(r.Context(), ID, input.Components)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoProductsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else if errors.Is(err, domain.ErrNotBundle) ||
+			errors.Is(err, domain.ErrInvalidBundleComponent) {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapBundleComponentList{Components: components}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,121 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// registerRecoveriesRoutes registers routes of abandoned cart recoveries.
+func (s *server) registerRecoveriesRoutes(r chi.Router) {
+	r.With(requireAuth).Get("/stats", s.cartRecoveryStatsHandler)
+	r.Get("/{id}/restore", s.restoreCartHandler)
+}
+
+// @Summary      Restore abandoned cart
+// @Description  Puts items of an abandoned cart back into cart of its user, the signed link is sent in recovery emails.
+// @Tags 		 Carts
+// @Produce      json
+// @Param        id         path     int     true "Recovery ID"
+// @Param        expires    query    int     true "Link expiry"
+// @Param        signature  query    string  true "Link signature"
+// @Success      200  {object}  domain.WrapCart
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /carts/recoveries/{id}/restore   [get]
+func (s *server) restoreCartHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	expires, err := ParseIntQuery(r, "expires")
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	err = domain.VerifyCartRecovery(s.RecoverySecret, ID, time.Unix(int64(expires), 0),
+		r.URL.Query().Get("signature"))
+	if err != nil {
+		Errorf(w, r, http.StatusForbidden, err.Error())
+		return
+	}
+
+	cart, err := s.RecoveriesStore.Restore(r.Context(), ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoCartRecoveriesFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else if errors.Is(err, domain.ErrInsufficientStock) {
+			Errorf(w, r, http.StatusConflict, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapCart{Cart: cart}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Cart recovery stats
+// @Description  Reports how recovery emails of abandoned carts sent in a period converted.
+// @Tags 		 Carts
+// @Security     Bearer
+// @Produce      json
+// @Param        from  query    string  false "Sent since, RFC 3339"
+// @Param        to    query    string  false "Sent before, RFC 3339"
+// @Success      200  {object}  domain.WrapCartRecoveryStats
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /carts/recoveries/stats   [get]
+func (s *server) cartRecoveryStatsHandler(w http.ResponseWriter, r *http.Request) {
+	from, err := parseTimeQuery(r, "from")
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	to, err := parseTimeQuery(r, "to")
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	stats, err := s.RecoveriesStore.Stats(r.Context(), from, to)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = ToJSON(w, domain.WrapCartRecoveryStats{Stats: stats}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// parseTimeQuery parses an RFC 3339 query value, nil is returned when it
+// is not given.
+func parseTimeQuery(r *http.Request, v string) (*time.Time, error) {
+	if !r.URL.Query().Has(v) {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, r.URL.Query().Get(v))
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
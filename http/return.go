@@ -0,0 +1,543 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// registerReturnsRoutes registers routes of returns under an order.
+func (s *server) registerReturnsRoutes(r chi.Router) {
+	r.With(requireUser).Get("/", s.listReturnsHandler)
+	r.With(requireUser).Post("/", s.createReturnHandler)
+	r.With(requireUser).Get("/{returnID}", s.getReturnHandler)
+	r.With(s.requireAdmin).Post("/{returnID}/approve", s.approveReturnHandler)
+	r.With(s.requireAdmin).Post("/{returnID}/reject", s.rejectReturnHandler)
+	r.With(s.requireAdmin).Post("/{returnID}/receive", s.receiveReturnHandler)
+	r.With(s.requireAdmin).Post("/{returnID}/refund", s.refundReturnHandler)
+	r.With(s.requireAdmin).Post("/{returnID}/refund/complete", s.completeReturnRefundHandler)
+	r.With(s.requireAdmin).Post("/{returnID}/refund/cancel", s.cancelReturnRefundHandler)
+}
+
+// @Summary      List order returns
+// @Tags 		 Returns
+// @Security     Bearer
+// @Produce      json
+// @Param        id    path     int  true "Order ID"
+// @Success      200  {object}  domain.WrapReturnList
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /orders/{id}/returns   [get]
+func (s *server) listReturnsHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	_, err = s.userOrder(r, ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoOrdersFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	returns, err := s.ReturnsStore.List(r.Context(), domain.ReturnFilter{OrderID: ID, Sort: "id"})
+	if err != nil {
+		if errors.Is(err, domain.ErrNoReturnsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapReturnList{Returns: returns}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Request return
+// @Description  Requests a return of delivered order lines.
+// @Tags 		 Returns
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        id      path     int  true "Order ID"
+// @Param        return  body     domain.ReturnCreate true "Create return"
+// @Success      201  {object}  domain.WrapReturn
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      413  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /orders/{id}/returns   [post]
+func (s *server) createReturnHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	input := domain.ReturnCreate{}
+	err = FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	_, err = s.userOrder(r, ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoOrdersFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	rma := input.CreateModel(ID)
+	err = s.ReturnsStore.Create(r.Context(), &rma)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidReturnQuantity) {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+		} else if errors.Is(err, domain.ErrOrderNotReturnable) {
+			Errorf(w, r, http.StatusConflict, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/orders/%d/returns/%d", ID, rma.ID))
+	err = ToJSON(w, domain.WrapReturn{Return: rma}, http.StatusCreated)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Get return
+// @Tags 		 Returns
+// @Security     Bearer
+// @Produce      json
+// @Param        id        path     int  true "Order ID"
+// @Param        returnID  path     int  true "Return ID"
+// @Success      200  {object}  domain.WrapReturn
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /orders/{id}/returns/{returnID}   [get]
+func (s *server) getReturnHandler(w http.ResponseWriter, r *http.Request) {
+	rma, err := s.orderReturn(r)
+	if err == nil {
+		_, err = s.userOrder(r, rma.OrderID)
+	}
+	if err != nil {
+		if errors.Is(err, errInvalidID) {
+			ErrorInvalidQuery(w, r)
+		} else if errors.Is(err, domain.ErrNoReturnsFound) || errors.Is(err, domain.ErrNoOrdersFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapReturn{Return: rma}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Approve return
+// @Tags 		 Returns
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        id        path     int  true "Order ID"
+// @Param        returnID  path     int  true "Return ID"
+// @Param        note      body     domain.ReturnStatusUpdate true "Note of approval"
+// @Success      200  {object}  domain.WrapReturn
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /orders/{id}/returns/{returnID}/approve   [post]
+func (s *server) approveReturnHandler(w http.ResponseWriter, r *http.Request) {
+	s.updateReturnStatus(w, r, domain.ReturnStatusApproved)
+}
+
+// @Summary      Reject return
+// @Tags 		 Returns
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        id        path     int  true "Order ID"
+// @Param        returnID  path     int  true "Return ID"
+// @Param        note      body     domain.ReturnStatusUpdate true "Note of rejection"
+// @Success      200  {object}  domain.WrapReturn
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /orders/{id}/returns/{returnID}/reject   [post]
+func (s *server) rejectReturnHandler(w http.ResponseWriter, r *http.Request) {
+	s.updateReturnStatus(w, r, domain.ReturnStatusRejected)
+}
+
+// @Summary      Receive return
+// @Description  Marks items of an approved return as received, restock gives them back to inventory.
+// @Tags 		 Returns
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        id        path     int  true "Order ID"
+// @Param        returnID  path     int  true "Return ID"
+// @Param        receive   body     domain.ReturnReceive true "Receive return"
+// @Success      200  {object}  domain.WrapReturn
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /orders/{id}/returns/{returnID}/receive   [post]
+func (s *server) receiveReturnHandler(w http.ResponseWriter, r *http.Request) {
+	rma, err := s.orderReturn(r)
+	if err != nil {
+		errorReturn(w, r, err)
+		return
+	}
+
+	input := domain.ReturnReceive{}
+	err = FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rma, err = s.ReturnsStore.Receive(r.Context(), rma.ID, input.Restock)
+	if err != nil {
+		errorReturn(w, r, err)
+		return
+	}
+
+	err = ToJSON(w, domain.WrapReturn{Return: rma}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Refund return
+// @Description  Refunds a received return through payment gateway of order or as store credit of user, no lines refunds every line in full. Payments made with gift cards or store credit are refunded as store credit. The return is claimed while it is refunded, concurrent refunds of it conflict. A return left refunding after money was moved is completed or cancelled by admins.
+// @Tags 		 Returns
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        id        path     int  true "Order ID"
+// @Param        returnID  path     int  true "Return ID"
+// @Param        refund    body     domain.ReturnRefund true "Refund return"
+// @Success      200  {object}  domain.WrapReturn
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      402  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Failure      502  {object}  http.WrapError
+// @Router       /orders/{id}/returns/{returnID}/refund   [post]
+func (s *server) refundReturnHandler(w http.ResponseWriter, r *http.Request) {
+	rma, err := s.orderReturn(r)
+	if err != nil {
+		errorReturn(w, r, err)
+		return
+	}
+
+	input := domain.ReturnRefund{}
+	err = FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// return is claimed before money is moved so concurrent refunds of it
+	// fail, the claim is released when refund fails before money is moved.
+	rma, err = s.ReturnsStore.StartRefund(r.Context(), rma.ID)
+	if err != nil {
+		errorReturn(w, r, err)
+		return
+	}
+
+	order, err := s.OrdersStore.GetByID(r.Context(), rma.OrderID)
+	if err != nil {
+		s.releaseRefund(r, rma.ID)
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	amounts, total, err := rma.RefundAmounts(order, input)
+	if err != nil {
+		s.releaseRefund(r, rma.ID)
+		errorReturn(w, r, err)
+		return
+	}
+
+	payments, err := s.PaymentsStore.List(r.Context(), domain.PaymentFilter{OrderID: order.ID, Sort: "id"})
+	if err != nil && !errors.Is(err, domain.ErrNoPaymentsFound) {
+		s.releaseRefund(r, rma.ID)
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	payment, err := domain.RefundablePayment(payments, total)
+	if err != nil {
+		s.releaseRefund(r, rma.ID)
+		Errorf(w, r, http.StatusConflict, err.Error())
+		return
+	}
+
+	ID := rma.ID
+	settlement := domain.ReturnSettlement{PaymentID: payment.ID, Amount: total, Note: "return " + rma.RMANumber}
+
+	// store credit is credited along with the refund, nothing is moved when
+	// it fails.
+	if input.StoreCredit || payment.StoredValue() {
+		settlement.StoreCredit = true
+		rma, err = s.ReturnsStore.Refund(r.Context(), ID, amounts, settlement)
+		if err != nil {
+			s.releaseRefund(r, ID)
+			errorReturn(w, r, err)
+			return
+		}
+	} else {
+		gateway, ok := s.PaymentGateways[payment.Provider]
+		if !ok {
+			s.releaseRefund(r, ID)
+			Errorf(w, r, http.StatusInternalServerError, domain.ErrUnknownPaymentProvider.Error())
+			return
+		}
+
+		settlement.Intent, err = gateway.Refund(r.Context(), payment.IntentID, total)
+		if err != nil {
+			s.releaseRefund(r, ID)
+			errorGateway(w, r, err)
+			return
+		}
+
+		// money is moved, a return failing to record its refund is left
+		// claimed to be completed or cancelled by admins.
+		rma, err = s.ReturnsStore.Refund(r.Context(), ID, amounts, settlement)
+		if err != nil {
+			logError(r, fmt.Sprintf("refund of return %d is not recorded: %v", ID, err))
+			errorReturn(w, r, err)
+			return
+		}
+	}
+
+	err = ToJSON(w, domain.WrapReturn{Return: rma}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Complete return refund
+// @Description  Records refund of a return left refunding when its money was moved but the refund was not recorded, no money is moved. Payments are updated by webhooks of payment provider.
+// @Tags 		 Returns
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        id        path     int  true "Order ID"
+// @Param        returnID  path     int  true "Return ID"
+// @Param        refund    body     domain.ReturnRefund true "Refunded lines"
+// @Success      200  {object}  domain.WrapReturn
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /orders/{id}/returns/{returnID}/refund/complete   [post]
+func (s *server) completeReturnRefundHandler(w http.ResponseWriter, r *http.Request) {
+	rma, err := s.orderReturn(r)
+	if err != nil {
+		errorReturn(w, r, err)
+		return
+	}
+
+	input := domain.ReturnRefund{}
+	err = FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	order, err := s.OrdersStore.GetByID(r.Context(), rma.OrderID)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	amounts, _, err := rma.RefundAmounts(order, input)
+	if err != nil {
+		errorReturn(w, r, err)
+		return
+	}
+
+	rma, err = s.ReturnsStore.Refund(r.Context(), rma.ID, amounts, domain.ReturnSettlement{})
+	if err != nil {
+		errorReturn(w, r, err)
+		return
+	}
+
+	err = ToJSON(w, domain.WrapReturn{Return: rma}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Cancel return refund
+// @Description  Gives a return left refunding back to received when no money was moved for it.
+// @Tags 		 Returns
+// @Security     Bearer
+// @Produce      json
+// @Param        id        path     int  true "Order ID"
+// @Param        returnID  path     int  true "Return ID"
+// @Success      200  {object}  domain.WrapReturn
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /orders/{id}/returns/{returnID}/refund/cancel   [post]
+func (s *server) cancelReturnRefundHandler(w http.ResponseWriter, r *http.Request) {
+	rma, err := s.orderReturn(r)
+	if err != nil {
+		errorReturn(w, r, err)
+		return
+	}
+
+	rma, err = s.ReturnsStore.CancelRefund(r.Context(), rma.ID)
+	if err != nil {
+		errorReturn(w, r, err)
+		return
+	}
+
+	err = ToJSON(w, domain.WrapReturn{Return: rma}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// releaseRefund gives a return claimed for refunding back to received,
+// failures are only logged.
+func (s *server) releaseRefund(r *http.Request, ID int) {
+	_, err := s.ReturnsStore.CancelRefund(r.Context(), ID)
+	if err != nil {
+		logError(r, fmt.Sprintf("failed to release refund of return %d: %v", ID, err))
+	}
+}
+
+// updateReturnStatus moves return of request to status with a note.
+func (s *server) updateReturnStatus(w http.ResponseWriter, r *http.Request, status string) {
+	rma, err := s.orderReturn(r)
+	if err != nil {
+		errorReturn(w, r, err)
+		return
+	}
+
+	input := domain.ReturnStatusUpdate{}
+	err = FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rma, err = s.ReturnsStore.UpdateStatus(r.Context(), rma.ID, status, input.Note)
+	if err != nil {
+		errorReturn(w, r, err)
+		return
+	}
+
+	err = ToJSON(w, domain.WrapReturn{Return: rma}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+var errInvalidID = errors.New("invalid id")
+
+// orderReturn returns return of request by its id, returns of other orders
+// are reported as not found.
+func (s *server) orderReturn(r *http.Request) (domain.Return, error) {
+	orderID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		return domain.Return{}, errInvalidID
+	}
+
+	ID, err := strconv.Atoi(chi.URLParam(r, "returnID"))
+	if err != nil {
+		return domain.Return{}, errInvalidID
+	}
+
+	rma, err := s.ReturnsStore.GetByID(r.Context(), ID)
+	if err != nil {
+		return domain.Return{}, err
+	}
+
+	if rma.OrderID != orderID {
+		return domain.Return{}, domain.ErrNoReturnsFound
+	}
+
+	return rma, nil
+}
+
+// errorReturn reports errors of returns.
+func errorReturn(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errInvalidID) {
+		ErrorInvalidQuery(w, r)
+	} else if errors.Is(err, domain.ErrNoReturnsFound) {
+		Errorf(w, r, http.StatusNotFound, err.Error())
+	} else if errors.Is(err, domain.ErrRefundExceedsLine) || errors.Is(err, domain.ErrNothingToRefund) {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+	} else if errors.Is(err, domain.ErrInvalidReturnTransition) || errors.Is(err, domain.ErrNoStoreCreditPayment) {
+		Errorf(w, r, http.StatusConflict, err.Error())
+	} else {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
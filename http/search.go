@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"errors"
 	"net/http"
 
@@ -21,6 +22,12 @@ func (s *server) searchHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	chain, err := localeChain(r)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	result, err := s.SearchStore.Search(r.Context(), query)
 	if err != nil {
 		if errors.Is(err, domain.ErrNoSearchResult) {
@@ -31,8 +38,52 @@ func (s *server) searchHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = s.localizeSearch(r.Context(), chain, result)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	err = ToJSON(w, result, http.StatusOK)
 	if err != nil {
 		Errorf(w, r, http.StatusInternalServerError, err.Error())
 	}
 }
+
+// localizeSearch replaces content of search results by their translations.
+func (s *server) localizeSearch(ctx context.Context, chain []string, results []domain.Search) error {
+	var (
+		products   []domain.Product
+		categories []domain.Category
+	)
+
+	for _, result := range results {
+		if result.Prodcuts != nil {
+			products = append(products, *result.Prodcuts)
+		}
+		if result.Categories != nil {
+			categories = append(categories, *result.Categories)
+		}
+	}
+
+	err := s.localizeProducts(ctx, chain, products)
+	if err != nil {
+		return err
+	}
+
+	err = s.localizeCategories(ctx, chain, categories)
+	if err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		if result.Prodcuts != nil {
+			*result.Prodcuts, products = products[0], products[1:]
+		}
+		if result.Categories != nil {
+			*result.Categories, categories = categories[0], categories[1:]
+		}
+	}
+
+	return nil
+}
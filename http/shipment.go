@@ -0,0 +1,343 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// registerShipmentsRoutes registers routes of shipments under an order.
+func (s *server) registerShipmentsRoutes(r chi.Router) {
+	r.With(requireUser).Get("/", s.listShipmentsHandler)
+	r.With(requireAuth).Post("/", s.createShipmentHandler)
+	r.With(requireUser).Get("/{shipmentID}", s.getShipmentHandler)
+	r.With(requireAuth).Patch("/{shipmentID}", s.updateShipmentHandler)
+	r.With(requireAuth).Post("/{shipmentID}/status", s.updateShipmentStatusHandler)
+}
+
+// @Summary      List order shipments
+// @Tags 		 Shipments
+// @Security     Bearer
+// @Produce      json
+// @Param        id    path     int  true "Order ID"
+// @Success      200  {object}  domain.WrapShipmentList
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /orders/{id}/shipments   [get]
+func (s *server) listShipmentsHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	_, err = s.userOrder(r, ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoOrdersFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	shipments, err := s.ShipmentsStore.List(r.Context(), domain.ShipmentFilter{OrderID: ID, Sort: "id"})
+	if err != nil {
+		if errors.Is(err, domain.ErrNoShipmentsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapShipmentList{Shipments: shipments}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Create shipment
+// @Description  Ships order lines in a package, an order may be shipped in several packages. Order status follows progress of its shipments.
+// @Tags 		 Shipments
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        id        path     int  true "Order ID"
+// @Param        shipment  body     domain.ShipmentCreate true "Create shipment"
+// @Success      201  {object}  domain.WrapShipment
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      413  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /orders/{id}/shipments   [post]
+func (s *server) createShipmentHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	input := domain.ShipmentCreate{}
+	err = FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	shipment := input.CreateModel(ID)
+	err = s.ShipmentsStore.Create(r.Context(), &shipment)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoOrdersFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			errorShipment(w, r, err)
+		}
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/orders/%d/shipments/%d", ID, shipment.ID))
+	err = ToJSON(w, domain.WrapShipment{Shipment: shipment}, http.StatusCreated)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Get shipment
+// @Description  Gets a shipment along with its tracking history.
+// @Tags 		 Shipments
+// @Security     Bearer
+// @Produce      json
+// @Param        id          path     int  true "Order ID"
+// @Param        shipmentID  path     int  true "Shipment ID"
+// @Success      200  {object}  domain.WrapShipment
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /orders/{id}/shipments/{shipmentID}   [get]
+func (s *server) getShipmentHandler(w http.ResponseWriter, r *http.Request) {
+	shipment, err := s.orderShipment(r)
+	if err == nil {
+		_, err = s.userOrder(r, shipment.OrderID)
+	}
+	if err != nil {
+		if errors.Is(err, errInvalidID) {
+			ErrorInvalidQuery(w, r)
+		} else if errors.Is(err, domain.ErrNoShipmentsFound) || errors.Is(err, domain.ErrNoOrdersFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapShipment{Shipment: shipment}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Update shipment
+// @Description  Updates carrier and tracking number of a shipment.
+// @Tags 		 Shipments
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        id          path     int  true "Order ID"
+// @Param        shipmentID  path     int  true "Shipment ID"
+// @Param        shipment    body     domain.ShipmentUpdate true "Update shipment"
+// @Success      200  {object}  domain.WrapShipment
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /orders/{id}/shipments/{shipmentID}   [patch]
+func (s *server) updateShipmentHandler(w http.ResponseWriter, r *http.Request) {
+	shipment, err := s.orderShipment(r)
+	if err != nil {
+		errorShipment(w, r, err)
+		return
+	}
+
+	input := domain.ShipmentUpdate{}
+	err = FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	shipment, err = s.ShipmentsStore.Update(r.Context(), shipment.ID, input)
+	if err != nil {
+		errorShipment(w, r, err)
+		return
+	}
+
+	err = ToJSON(w, domain.WrapShipment{Shipment: shipment}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Update shipment status
+// @Description  Moves a shipment to status and records it in tracking history, cancelling a pending shipment frees its lines to ship again.
+// @Tags 		 Shipments
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        id          path     int  true "Order ID"
+// @Param        shipmentID  path     int  true "Shipment ID"
+// @Param        status      body     domain.ShipmentStatusUpdate true "Update status"
+// @Success      200  {object}  domain.WrapShipment
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /orders/{id}/shipments/{shipmentID}/status   [post]
+func (s *server) updateShipmentStatusHandler(w http.ResponseWriter, r *http.Request) {
+	shipment, err := s.orderShipment(r)
+	if err != nil {
+		errorShipment(w, r, err)
+		return
+	}
+
+	input := domain.ShipmentStatusUpdate{}
+	err = FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	shipment, err = s.ShipmentsStore.UpdateStatus(r.Context(), shipment.ID, input, userIDFromContext(r.Context()))
+	if err != nil {
+		errorShipment(w, r, err)
+		return
+	}
+
+	err = ToJSON(w, domain.WrapShipment{Shipment: shipment}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Receive carrier webhook
+// @Description  Receives signed tracking events of a carrier, events are applied once to shipment of their tracking number.
+// @Tags 		 Shipments
+// @Accept       json
+// @Produce      json
+// @Param        carrier  path     string  true "Carrier"
+// @Success      200  {object}  webhookResponse
+// @Failure      400  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /webhooks/carriers/{carrier} [post]
+func (s *server) carrierWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	carrier := chi.URLParam(r, "carrier")
+	tracker, ok := s.Carriers[carrier]
+	if !ok {
+		Errorf(w, r, http.StatusNotFound, domain.ErrUnknownCarrier.Error())
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytesBodyRead))
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, "failed to read webhook body")
+		return
+	}
+
+	event, err := tracker.ParseEvent(payload, r.Header.Get(tracker.SignatureHeader()))
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidWebhookSignature) {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = s.ShipmentsStore.HandleTrackingEvent(r.Context(), carrier, event)
+	if err != nil && !errors.Is(err, domain.ErrDuplicatedTrackingEvent) {
+		if errors.Is(err, domain.ErrNoShipmentsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, webhookResponse{Received: true}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// orderShipment returns shipment of request by its id, shipments of other
+// orders are reported as not found.
+func (s *server) orderShipment(r *http.Request) (domain.Shipment, error) {
+	orderID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		return domain.Shipment{}, errInvalidID
+	}
+
+	ID, err := strconv.Atoi(chi.URLParam(r, "shipmentID"))
+	if err != nil {
+		return domain.Shipment{}, errInvalidID
+	}
+
+	shipment, err := s.ShipmentsStore.GetByID(r.Context(), ID)
+	if err != nil {
+		return domain.Shipment{}, err
+	}
+
+	if shipment.OrderID != orderID {
+		return domain.Shipment{}, domain.ErrNoShipmentsFound
+	}
+
+	return shipment, nil
+}
+
+// errorShipment reports errors of shipments.
+func errorShipment(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errInvalidID) {
+		ErrorInvalidQuery(w, r)
+	} else if errors.Is(err, domain.ErrNoShipmentsFound) {
+		Errorf(w, r, http.StatusNotFound, err.Error())
+	} else if errors.Is(err, domain.ErrInvalidShipmentQuantity) {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+	} else if errors.Is(err, domain.ErrOrderNotFulfillable) ||
+		errors.Is(err, domain.ErrInvalidShipmentTransition) ||
+		errors.Is(err, domain.ErrDuplicatedTrackingNumber) {
+		Errorf(w, r, http.StatusConflict, err.Error())
+	} else {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
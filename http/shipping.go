@@ -0,0 +1,375 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// registerShippingRoutes registers routes of shipping zones and methods.
+func (s *server) registerShippingRoutes(r *chi.Mux) {
+	r.Route("/shipping", func(r chi.Router) {
+		r.With(requireAuth).Get("/zones", s.listShippingZonesHandler)
+		r.With(requireAuth).Post("/zones", s.createShippingZoneHandler)
+		r.With(requireAuth).Get("/zones/{id}", s.getShippingZoneHandler)
+		r.With(requireAuth).Delete("/zones/{id}", s.deleteShippingZoneHandler)
+		r.With(requireAuth).Post("/zones/{id}/methods", s.createShippingMethodHandler)
+		r.With(requireAuth).Delete("/methods/{id}", s.deleteShippingMethodHandler)
+	})
+}
+
+// @Summary      List shipping zones
+// @Tags 		 Shipping
+// @Security     Bearer
+// @Produce      json
+// @Success      200  {object}  domain.WrapShippingZoneList
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /shipping/zones   [get]
+func (s *server) listShippingZonesHandler(w http.ResponseWriter, r *http.Request) {
+	zones, err := s.ShippingStore.ListZones(r.Context())
+	if err != nil {
+		if errors.Is(err, domain.ErrNoShippingZonesFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapShippingZoneList{ShippingZones: zones}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Create shipping zone
+// @Tags 		 Shipping
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        zone  body     domain.ShippingZoneCreate true "Create shipping zone"
+// @Success      201  {object}  domain.WrapShippingZone
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /shipping/zones   [post]
+func (s *server) createShippingZoneHandler(w http.ResponseWriter, r *http.Request) {
+	input := domain.ShippingZoneCreate{}
+	err := FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	zone := input.CreateModel()
+	err = s.ShippingStore.CreateZone(r.Context(), &zone)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/shipping/zones/%d", zone.ID))
+	err = ToJSON(w, domain.WrapShippingZone{ShippingZone: zone}, http.StatusCreated)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Get shipping zone
+// @Tags 		 Shipping
+// @Security     Bearer
+// @Produce      json
+// @Param        id   path      int  true "Shipping zone ID"
+// @Success      200  {object}  domain.WrapShippingZone
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /shipping/zones/{id}   [get]
+func (s *server) getShippingZoneHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	zone, err := s.ShippingStore.GetZone(r.Context(), ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoShippingZonesFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapShippingZone{ShippingZone: zone}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Delete shipping zone
+// @Tags 		 Shipping
+// @Security     Bearer
+// @Param        id   path      int  true "Shipping zone ID"
+// @Success      200
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /shipping/zones/{id}   [delete]
+func (s *server) deleteShippingZoneHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	err = s.ShippingStore.DeleteZone(r.Context(), ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoShippingZonesFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+	}
+}
+
+// @Summary      Create shipping method
+// @Description  Weight tiers are in grams and price tiers in subtotal of cart net of discounts.
+// @Tags 		 Shipping
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        id      path     int  true "Shipping zone ID"
+// @Param        method  body     domain.ShippingMethodCreate true "Create shipping method"
+// @Success      201  {object}  domain.WrapShippingMethod
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /shipping/zones/{id}/methods   [post]
+func (s *server) createShippingMethodHandler(w http.ResponseWriter, r *http.Request) {
+	zoneID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	input := domain.ShippingMethodCreate{}
+	err = FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	method := input.CreateModel(zoneID)
+	err = s.ShippingStore.CreateMethod(r.Context(), &method)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoShippingZonesFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapShippingMethod{ShippingMethod: method}, http.StatusCreated)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Delete shipping method
+// @Tags 		 Shipping
+// @Security     Bearer
+// @Param        id   path      int  true "Shipping method ID"
+// @Success      200
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /shipping/methods/{id}   [delete]
+func (s *server) deleteShippingMethodHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	err = s.ShippingStore.DeleteMethod(r.Context(), ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoShippingMethodsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+	}
+}
+
+// @Summary      List shipping rates of cart
+// @Description  Rates are quoted for destination of cart unless an address is given.
+// @Tags 		 Carts
+// @Security     Bearer
+// @Produce      json
+// @Param        X-Cart-Token  header     string  false "Guest cart token"
+// @Param        country      query       string  false "Country code"
+// @Param        region       query       string  false "Region"
+// @Param        postal_code  query       string  false "Postal code"
+// @Success      200          {object}    domain.WrapShippingRateList
+// @Failure      400          {object}    http.WrapError
+// @Failure      404          {object}    http.WrapError
+// @Failure      500          {object}    http.WrapError
+// @Router       /carts/me/shipping-rates  [get]
+func (s *server) listShippingRatesHandler(w http.ResponseWriter, r *http.Request) {
+	cart, err := s.CartsStore.GetByOwner(r.Context(), cartOwner(r))
+	if err != nil {
+		if errors.Is(err, domain.ErrNoCartsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	if country := r.URL.Query().Get("country"); country != "" {
+		cart.Destination = domain.Location{
+			Country:    country,
+			Region:     r.URL.Query().Get("region"),
+			PostalCode: r.URL.Query().Get("postal_code"),
+		}
+	}
+
+	err = cart.Destination.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rates := []domain.ShippingRate{}
+	if cart.Shippable() {
+		rates, err = s.ShippingProvider.Rates(r.Context(), cart.ShippingRequest())
+		if err != nil {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	err = ToJSON(w, domain.WrapShippingRateList{ShippingRates: rates}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Select shipping method of cart
+// @Description  Method must be available for destination and contents of cart.
+// @Tags 		 Carts
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        X-Cart-Token  header     string  false "Guest cart token"
+// @Param        method       body        domain.ShippingMethodSelect true "Select shipping method"
+// @Success      200          {object}    domain.WrapCart
+// @Failure      400          {object}    http.WrapError
+// @Failure      404          {object}    http.WrapError
+// @Failure      422          {object}    http.WrapError
+// @Failure      500          {object}    http.WrapError
+// @Router       /carts/me/shipping-method  [put]
+func (s *server) setShippingMethodHandler(w http.ResponseWriter, r *http.Request) {
+	input := domain.ShippingMethodSelect{}
+	err := FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	owner := cartOwner(r)
+	cart, err := s.CartsStore.GetByOwner(r.Context(), owner)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoCartsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	cart.ShippingMethodID = &input.ShippingMethodID
+	quote, err := s.shippingQuote(r.Context(), cart)
+	if err != nil {
+		if errors.Is(err, domain.ErrShippingMethodNotAvailable) {
+			Errorf(w, r, http.StatusUnprocessableEntity, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	cart, err = s.CartsStore.SetShippingMethod(r.Context(), owner, input.ShippingMethodID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoShippingMethodsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	if quote != nil {
+		cart.ApplyShipping(quote.Rate)
+	}
+
+	err = ToJSON(w, domain.WrapCart{Cart: cart}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// shippingQuote quotes shipping of cart by its selected method, carts with
+// nothing to ship are not quoted. A method is required once cart can be
+// shipped to its destination.
+func (s *server) shippingQuote(ctx context.Context, cart domain.Cart) (*domain.ShippingQuote, error) {
+	if !cart.Shippable() {
+		return nil, nil
+	}
+
+	request := cart.ShippingRequest()
+	rates, err := s.ShippingProvider.Rates(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if cart.ShippingMethodID == nil {
+		if len(rates) > 0 {
+			return nil, domain.ErrShippingMethodRequired
+		}
+		return nil, nil
+	}
+
+	rate, ok := domain.FindShippingRate(rates, *cart.ShippingMethodID)
+	if !ok {
+		return nil, domain.ErrShippingMethodNotAvailable
+	}
+
+	return &domain.ShippingQuote{Request: request, Rate: rate}, nil
+}
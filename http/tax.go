@@ -0,0 +1,252 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// registerTaxRoutes registers routes of tax zones and their rates.
+func (s *server) registerTaxRoutes(r *chi.Mux) {
+	r.Route("/tax/zones", func(r chi.Router) {
+		r.With(requireAuth).Get("/", s.listTaxZonesHandler)
+		r.With(requireAuth).Post("/", s.createTaxZoneHandler)
+		r.With(requireAuth).Get("/{id}", s.getTaxZoneHandler)
+		r.With(requireAuth).Delete("/{id}", s.deleteTaxZoneHandler)
+		r.With(requireAuth).Put("/{id}/rates", s.setTaxRatesHandler)
+	})
+}
+
+// @Summary      List tax zones
+// @Tags 		 Taxes
+// @Security     Bearer
+// @Produce      json
+// @Success      200  {object}  domain.WrapTaxZoneList
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /tax/zones   [get]
+func (s *server) listTaxZonesHandler(w http.ResponseWriter, r *http.Request) {
+	zones, err := s.TaxStore.ListZones(r.Context())
+	if err != nil {
+		if errors.Is(err, domain.ErrNoTaxZonesFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapTaxZoneList{TaxZones: zones}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Create tax zone
+// @Description  Rates are given in hundredths of a percent, a rate of 2000 is 20%.
+// @Tags 		 Taxes
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        zone  body     domain.TaxZoneCreate true "Create tax zone"
+// @Success      201  {object}  domain.WrapTaxZone
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /tax/zones   [post]
+func (s *server) createTaxZoneHandler(w http.ResponseWriter, r *http.Request) {
+	input := domain.TaxZoneCreate{}
+	err := FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	zone := input.CreateModel()
+	err = s.TaxStore.CreateZone(r.Context(), &zone)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/tax/zones/%d", zone.ID))
+	err = ToJSON(w, domain.WrapTaxZone{TaxZone: zone}, http.StatusCreated)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Get tax zone
+// @Tags 		 Taxes
+// @Security     Bearer
+// @Produce      json
+// @Param        id   path      int  true "Tax zone ID"
+// @Success      200  {object}  domain.WrapTaxZone
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /tax/zones/{id}   [get]
+func (s *server) getTaxZoneHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	zone, err := s.TaxStore.GetZone(r.Context(), ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoTaxZonesFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapTaxZone{TaxZone: zone}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Delete tax zone
+// @Tags 		 Taxes
+// @Security     Bearer
+// @Param        id   path      int  true "Tax zone ID"
+// @Success      200
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /tax/zones/{id}   [delete]
+func (s *server) deleteTaxZoneHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	err = s.TaxStore.DeleteZone(r.Context(), ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoTaxZonesFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+	}
+}
+
+// @Summary      Set tax rates of zone
+// @Description  Replaces every rate of zone, tax categories without a rate are not taxed.
+// @Tags 		 Taxes
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        id     path      int  true "Tax zone ID"
+// @Param        rates  body      domain.TaxRatesInput true "Tax rates"
+// @Success      200  {object}  domain.WrapTaxZone
+// @Failure      400  {object}  http.WrapError
+// @Failure      403  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /tax/zones/{id}/rates   [put]
+func (s *server) setTaxRatesHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	input := domain.TaxRatesInput{}
+	err = FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	zone, err := s.TaxStore.SetRates(r.Context(), ID, input.Rates)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoTaxZonesFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapTaxZone{TaxZone: zone}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Set destination of cart
+// @Description  Taxes of cart are calculated by zone of its destination. Guests without a cart token get a new one in X-Cart-Token header and cart_token cookie.
+// @Tags 		 Carts
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        X-Cart-Token  header     string  false "Guest cart token"
+// @Param        destination  body        domain.Location true "Destination"
+// @Success      200          {object}    domain.WrapCart
+// @Failure      400          {object}    http.WrapError
+// @Failure      500          {object}    http.WrapError
+// @Router       /carts/me/destination  [put]
+func (s *server) setCartDestinationHandler(w http.ResponseWriter, r *http.Request) {
+	input := domain.Location{}
+	err := FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	owner := cartOwner(r)
+	if owner.IsGuest() && owner.Token == nil {
+		var token string
+		token, owner.Token, err = domain.GenerateCartToken()
+		if err != nil {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		setCartToken(w, token)
+	}
+
+	cart, err := s.CartsStore.SetDestination(r.Context(), owner, input)
+	if err != nil {
+		if errors.Is(err, domain.ErrCartInvalidUserID) {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapCart{Cart: cart}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,213 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// localeChain returns fallback chain of locales requested by locale url
+// query followed by Accept-Language header.
+func localeChain(r *http.Request) ([]string, error) {
+	var requested []string
+
+	if r.URL.Query().Has("locale") {
+		locale, err := domain.NormalizeLocale(r.URL.Query().Get("locale"))
+		if err != nil {
+			return nil, err
+		}
+		requested = append(requested, locale)
+	}
+
+	requested = append(requested, domain.ParseAcceptLanguage(r.Header.Get("Accept-Language"))...)
+
+	return domain.LocaleChain(requested...), nil
+}
+
+// localizeProducts replaces content of products by their translations.
+func (s *server) localizeProducts(ctx context.Context, chain []string, products []domain.Product) error {
+	ids := make([]int, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+
+	translations, err := s.ProductTranslationsStore.Resolve(ctx, ids, domain.TranslatedLocales(chain))
+	if err != nil {
+		return err
+	}
+
+	for i := range products {
+		products[i].Locale = domain.DefaultLocale
+		if t, ok := translations[products[i].ID]; ok {
+			products[i].Localize(t)
+		}
+	}
+
+	return nil
+}
+
+// localizeCategories replaces content of categories by their translations.
+func (s *server) localizeCategories(ctx context.Context, chain []string, categories []domain.Category) error {
+	ids := make([]int, 0, len(categories))
+	for _, c := range categories {
+		ids = append(ids, c.ID)
+	}
+
+	translations, err := s.CategoryTranslationsStore.Resolve(ctx, ids, domain.TranslatedLocales(chain))
+	if err != nil {
+		return err
+	}
+
+	for i := range categories {
+		categories[i].Locale = domain.DefaultLocale
+		if t, ok := translations[categories[i].ID]; ok {
+			categories[i].Localize(t)
+		}
+	}
+
+	return nil
+}
+
+// @Summary      List translations
+// @Tags 		 Translations
+// @Produce      json
+// @Param        id                            path        int  true "Product or category ID"
+// @Success      200                           {array}     domain.WrapTranslationList
+// @Failure      400                           {object}    http.WrapError
+// @Failure      404                           {object}    http.WrapError
+// @Failure      500                           {object}    http.WrapError
+// @Router       /products/{id}/translations   [get]
+// @Router       /categories/{id}/translations [get]
+func listTranslationsHandler(store domain.TranslationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			ErrorInvalidQuery(w, r)
+			return
+		}
+
+		translations, err := store.List(r.Context(), ID)
+		if err != nil {
+			if errors.Is(err, domain.ErrNoTranslationFound) {
+				Errorf(w, r, http.StatusNotFound, err.Error())
+			} else {
+				Errorf(w, r, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+
+		err = ToJSON(w, domain.WrapTranslationList{Translations: translations}, http.StatusOK)
+		if err != nil {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+	}
+}
+
+// @Summary      Create or replace translation
+// @Tags 		 Translations
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        id                                     path        int    true "Product or category ID"
+// @Param        locale                                 path        string true "Locale"
+// @Param        translation                            body        domain.TranslationInput true "Translation"
+// @Success      200                                    {array}     domain.WrapTranslation
+// @Failure      400                                    {object}    http.WrapError
+// @Failure      403                                    {object}    http.WrapError
+// @Failure      404                                    {object}    http.WrapError
+// @Failure      413                                    {object}    http.WrapError
+// @Failure      500                                    {object}    http.WrapError
+// @Router       /products/{id}/translations/{locale}   [put]
+// @Router       /categories/{id}/translations/{locale} [put]
+func setTranslationHandler(store domain.TranslationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			ErrorInvalidQuery(w, r)
+			return
+		}
+
+		locale, err := domain.NormalizeLocale(chi.URLParam(r, "locale"))
+		if err != nil {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// translations of default locale would never be served.
+		if locale == domain.DefaultLocale {
+			Errorf(w, r, http.StatusBadRequest, domain.ErrDefaultLocale.Error())
+			return
+		}
+
+		input := domain.TranslationInput{}
+		err = FromJSON(w, r, &input)
+		if err != nil {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		err = input.Validate()
+		if err != nil {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		translation := input.CreateModel(locale)
+		err = store.Set(r.Context(), ID, &translation)
+		if err != nil {
+			if errors.Is(err, domain.ErrNoProductsFound) ||
+				errors.Is(err, domain.ErrNoCategoryFound) {
+				Errorf(w, r, http.StatusNotFound, err.Error())
+			} else {
+				Errorf(w, r, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+
+		err = ToJSON(w, domain.WrapTranslation{Translation: translation}, http.StatusOK)
+		if err != nil {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+	}
+}
+
+// @Summary      Delete translation
+// @Tags 		 Translations
+// @Security     Bearer
+// @Param        id                                     path        int    true "Product or category ID"
+// @Param        locale                                 path        string true "Locale"
+// @Success      200
+// @Failure      400                                    {object}    http.WrapError
+// @Failure      403                                    {object}    http.WrapError
+// @Failure      404                                    {object}    http.WrapError
+// @Failure      500                                    {object}    http.WrapError
+// @Router       /products/{id}/translations/{locale}   [delete]
+// @Router       /categories/{id}/translations/{locale} [delete]
+func deleteTranslationHandler(store domain.TranslationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			ErrorInvalidQuery(w, r)
+			return
+		}
+
+		locale, err := domain.NormalizeLocale(chi.URLParam(r, "locale"))
+		if err != nil {
+			Errorf(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		err = store.Delete(r.Context(), ID, locale)
+		if err != nil {
+			if errors.Is(err, domain.ErrNoTranslationFound) {
+				Errorf(w, r, http.StatusNotFound, err.Error())
+			} else {
+				Errorf(w, r, http.StatusInternalServerError, err.Error())
+			}
+		}
+	}
+}
@@ -25,6 +25,24 @@ func (s *server) registerUsersRoutes(r *chi.Mux) {
 		r.Get("/", s.listUsersHandler)
 		r.Post("/", s.createUserHandler)
 		r.Delete("/{id}", s.deleteUserHandler)
+		r.With(s.requireAdmin).Get("/{id}/store-credit", s.getStoreCreditHandler)
+		r.With(s.requireAdmin).Post("/{id}/store-credit/adjust", s.adjustStoreCreditHandler)
+
+		r.With(requireUser).Route("/me", func(r chi.Router) {
+			r.Get("/downloads", s.listUserGrantsHandler)
+			r.Post("/downloads/{grantID}/files/{fileID}/link", s.createDownloadLinkHandler)
+
+			r.Get("/addresses", s.listAddressesHandler)
+			r.Post("/addresses", s.createAddressHandler)
+			r.Get("/addresses/{addressID}", s.getAddressHandler)
+			r.Patch("/addresses/{addressID}", s.updateAddressHandler)
+			r.Delete("/addresses/{addressID}", s.deleteAddressHandler)
+
+			r.Route("/wishlists", s.registerUserWishlistsRoutes)
+
+			r.Get("/store-credit", s.getUserStoreCreditHandler)
+			r.Get("/loyalty", s.getUserLoyaltyHandler)
+		})
 	})
 }
 
@@ -189,6 +207,7 @@ func (s *server) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary      User login
+// @Description  A guest cart given by X-Cart-Token header or cart_token cookie is merged into user's cart.
 // @Tags 		 Users
 // @Produce      json
 // @Accept       json
@@ -249,6 +268,8 @@ func (s *server) loginAuthHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.mergeGuestCart(w, r, user.ID)
+
 	err = ToJSON(w, domain.WrapToken{Token: token}, http.StatusOK)
 	if err != nil {
 		Errorf(w, r, http.StatusInternalServerError, err.Error())
@@ -0,0 +1,471 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// registerWishlistsRoutes registers public routes of shared wishlists.
+func (s *server) registerWishlistsRoutes(r *chi.Mux) {
+	r.Get("/wishlists/shared/{token}", s.getSharedWishlistHandler)
+}
+
+// registerUserWishlistsRoutes registers wishlist routes of current user.
+func (s *server) registerUserWishlistsRoutes(r chi.Router) {
+	r.Get("/", s.listWishlistsHandler)
+	r.Post("/", s.createWishlistHandler)
+	r.Get("/{wishlistID}", s.getWishlistHandler)
+	r.Patch("/{wishlistID}", s.updateWishlistHandler)
+	r.Delete("/{wishlistID}", s.deleteWishlistHandler)
+	r.Post("/{wishlistID}/items", s.addWishlistItemHandler)
+	r.Delete("/{wishlistID}/items/{itemID}", s.removeWishlistItemHandler)
+	r.Post("/{wishlistID}/items/{itemID}/move-to-cart", s.moveToCartHandler)
+	r.Post("/{wishlistID}/share", s.shareWishlistHandler)
+	r.Delete("/{wishlistID}/share", s.unshareWishlistHandler)
+}
+
+// @Summary      List wishlists
+// @Tags 		 Wishlists
+// @Security     Bearer
+// @Produce      json
+// @Success      200  {object}  domain.WrapWishlistList
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /users/me/wishlists   [get]
+func (s *server) listWishlistsHandler(w http.ResponseWriter, r *http.Request) {
+	wishlists, err := s.WishlistsStore.List(r.Context(), userIDFromContext(r.Context()))
+	if err != nil {
+		if errors.Is(err, domain.ErrNoWishlistsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapWishlistList{Wishlists: wishlists}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Create wishlist
+// @Tags 		 Wishlists
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        wishlist  body     domain.WishlistCreate true "Create wishlist"
+// @Success      201  {object}  domain.WrapWishlist
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /users/me/wishlists   [post]
+func (s *server) createWishlistHandler(w http.ResponseWriter, r *http.Request) {
+	input := domain.WishlistCreate{}
+	err := FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	wishlist := input.CreateModel(userIDFromContext(r.Context()))
+	err = s.WishlistsStore.Create(r.Context(), &wishlist)
+	if err != nil {
+		if errors.Is(err, domain.ErrDuplicatedWishlist) {
+			Errorf(w, r, http.StatusConflict, err.Error())
+		} else {
+			Errorf(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/users/me/wishlists/%d", wishlist.ID))
+	err = ToJSON(w, domain.WrapWishlist{Wishlist: wishlist}, http.StatusCreated)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Get wishlist
+// @Tags 		 Wishlists
+// @Security     Bearer
+// @Produce      json
+// @Param        wishlistID   path      int  true "Wishlist ID"
+// @Success      200  {object}  domain.WrapWishlist
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /users/me/wishlists/{wishlistID}   [get]
+func (s *server) getWishlistHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "wishlistID"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	wishlist, err := s.WishlistsStore.GetByID(r.Context(), userIDFromContext(r.Context()), ID)
+	if err != nil {
+		errorWishlist(w, r, err)
+		return
+	}
+
+	err = ToJSON(w, domain.WrapWishlist{Wishlist: wishlist}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Update wishlist
+// @Tags 		 Wishlists
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        wishlistID  path      int  true "Wishlist ID"
+// @Param        wishlist    body      domain.WishlistUpdate true "Update wishlist"
+// @Success      200  {object}  domain.WrapWishlist
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /users/me/wishlists/{wishlistID}   [patch]
+func (s *server) updateWishlistHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "wishlistID"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	input := domain.WishlistUpdate{}
+	err = FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	wishlist, err := s.WishlistsStore.Update(r.Context(), userIDFromContext(r.Context()), ID, input)
+	if err != nil {
+		errorWishlist(w, r, err)
+		return
+	}
+
+	err = ToJSON(w, domain.WrapWishlist{Wishlist: wishlist}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Delete wishlist
+// @Tags 		 Wishlists
+// @Security     Bearer
+// @Param        wishlistID   path      int  true "Wishlist ID"
+// @Success      200
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /users/me/wishlists/{wishlistID}   [delete]
+func (s *server) deleteWishlistHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "wishlistID"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	err = s.WishlistsStore.Delete(r.Context(), userIDFromContext(r.Context()), ID)
+	if err != nil {
+		errorWishlist(w, r, err)
+	}
+}
+
+// @Summary      Add item to wishlist
+// @Description  Quantity defaults to one and is added up when product is already in wishlist.
+// @Tags 		 Wishlists
+// @Security     Bearer
+// @Produce      json
+// @Accept       json
+// @Param        wishlistID  path      int  true "Wishlist ID"
+// @Param        item        body      domain.WishlistItemCreate true "Add item"
+// @Success      201  {object}  domain.WrapWishlist
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /users/me/wishlists/{wishlistID}/items   [post]
+func (s *server) addWishlistItemHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "wishlistID"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	input := domain.WishlistItemCreate{}
+	err = FromJSON(w, r, &input)
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	wishlist, err := s.WishlistsStore.AddItem(r.Context(), userIDFromContext(r.Context()), ID, input.CreateModel())
+	if err != nil {
+		errorWishlist(w, r, err)
+		return
+	}
+
+	err = ToJSON(w, domain.WrapWishlist{Wishlist: wishlist}, http.StatusCreated)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Remove item from wishlist
+// @Tags 		 Wishlists
+// @Security     Bearer
+// @Produce      json
+// @Param        wishlistID  path      int  true "Wishlist ID"
+// @Param        itemID      path      int  true "Item ID"
+// @Success      200  {object}  domain.WrapWishlist
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /users/me/wishlists/{wishlistID}/items/{itemID}   [delete]
+func (s *server) removeWishlistItemHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "wishlistID"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	itemID, err := strconv.Atoi(chi.URLParam(r, "itemID"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	wishlist, err := s.WishlistsStore.RemoveItem(r.Context(), userIDFromContext(r.Context()), ID, itemID)
+	if err != nil {
+		errorWishlist(w, r, err)
+		return
+	}
+
+	err = ToJSON(w, domain.WrapWishlist{Wishlist: wishlist}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Move wishlist item to cart
+// @Description  Adds item to cart of current user and removes it from wishlist.
+// @Tags 		 Wishlists
+// @Security     Bearer
+// @Produce      json
+// @Param        wishlistID  path      int  true "Wishlist ID"
+// @Param        itemID      path      int  true "Item ID"
+// @Success      200  {object}  domain.WrapCart
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      409  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /users/me/wishlists/{wishlistID}/items/{itemID}/move-to-cart   [post]
+func (s *server) moveToCartHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "wishlistID"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	itemID, err := strconv.Atoi(chi.URLParam(r, "itemID"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	cart, err := s.WishlistsStore.MoveToCart(r.Context(), userIDFromContext(r.Context()), ID, itemID)
+	if err != nil {
+		if errors.Is(err, domain.ErrInsufficientStock) {
+			Errorf(w, r, http.StatusConflict, err.Error())
+		} else {
+			errorWishlist(w, r, err)
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapCart{Cart: cart}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Move cart item to wishlist
+// @Description  Removes item from cart of current user and adds it to wishlist, default wishlist is used unless one is given.
+// @Tags 		 Carts
+// @Security     Bearer
+// @Produce      json
+// @Param        itemID       path      int  true  "Cart item ID"
+// @Param        wishlist_id  query     int  false "Wishlist ID"
+// @Success      200  {object}  domain.WrapWishlist
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /carts/me/items/{itemID}/move-to-wishlist   [post]
+func (s *server) moveToWishlistHandler(w http.ResponseWriter, r *http.Request) {
+	itemID, err := strconv.Atoi(chi.URLParam(r, "itemID"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	wishlistID, err := ParseIntQuery(r, "wishlist_id")
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	wishlist, err := s.WishlistsStore.MoveFromCart(r.Context(), userIDFromContext(r.Context()), itemID, wishlistID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoCartItemsFound) {
+			Errorf(w, r, http.StatusNotFound, err.Error())
+		} else {
+			errorWishlist(w, r, err)
+		}
+		return
+	}
+
+	err = ToJSON(w, domain.WrapWishlist{Wishlist: wishlist}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Share wishlist
+// @Description  Creates a read-only link token of wishlist, a previous token stops working.
+// @Tags 		 Wishlists
+// @Security     Bearer
+// @Produce      json
+// @Param        wishlistID  path      int  true "Wishlist ID"
+// @Success      200  {object}  domain.WrapWishlist
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /users/me/wishlists/{wishlistID}/share   [post]
+func (s *server) shareWishlistHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "wishlistID"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	token, err := domain.GenerateShareToken()
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	wishlist, err := s.WishlistsStore.Share(r.Context(), userIDFromContext(r.Context()), ID, token)
+	if err != nil {
+		errorWishlist(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/wishlists/shared/%s", token))
+	err = ToJSON(w, domain.WrapWishlist{Wishlist: wishlist}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Unshare wishlist
+// @Tags 		 Wishlists
+// @Security     Bearer
+// @Produce      json
+// @Param        wishlistID  path      int  true "Wishlist ID"
+// @Success      200  {object}  domain.WrapWishlist
+// @Failure      400  {object}  http.WrapError
+// @Failure      401  {object}  http.WrapError
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /users/me/wishlists/{wishlistID}/share   [delete]
+func (s *server) unshareWishlistHandler(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.Atoi(chi.URLParam(r, "wishlistID"))
+	if err != nil {
+		ErrorInvalidQuery(w, r)
+		return
+	}
+
+	wishlist, err := s.WishlistsStore.Unshare(r.Context(), userIDFromContext(r.Context()), ID)
+	if err != nil {
+		errorWishlist(w, r, err)
+		return
+	}
+
+	err = ToJSON(w, domain.WrapWishlist{Wishlist: wishlist}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// @Summary      Get shared wishlist
+// @Description  Shows a wishlist read-only to anyone holding its share token.
+// @Tags 		 Wishlists
+// @Produce      json
+// @Param        token   path      string  true "Share token"
+// @Success      200  {object}  domain.WrapSharedWishlist
+// @Failure      404  {object}  http.WrapError
+// @Failure      500  {object}  http.WrapError
+// @Router       /wishlists/shared/{token}   [get]
+func (s *server) getSharedWishlistHandler(w http.ResponseWriter, r *http.Request) {
+	wishlist, err := s.WishlistsStore.GetShared(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		errorWishlist(w, r, err)
+		return
+	}
+
+	err = ToJSON(w, domain.WrapSharedWishlist{Wishlist: wishlist.Shared()}, http.StatusOK)
+	if err != nil {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// errorWishlist writes error of wishlists store.
+func errorWishlist(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, domain.ErrNoWishlistsFound) ||
+		errors.Is(err, domain.ErrNoWishlistItemsFound) {
+		Errorf(w, r, http.StatusNotFound, err.Error())
+	} else if errors.Is(err, domain.ErrWishlistInvalidProductID) {
+		Errorf(w, r, http.StatusBadRequest, err.Error())
+	} else if errors.Is(err, domain.ErrDuplicatedWishlist) {
+		Errorf(w, r, http.StatusConflict, err.Error())
+	} else {
+		Errorf(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
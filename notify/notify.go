@@ -0,0 +1,69 @@
+// Package notify queues and delivers notifications of stores.
+package notify
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// batchSize is number of notifications handled on every run.
+const batchSize = 50
+
+// Log represents a notifier writing notifications to a logger, it is meant
+// for local development.
+type Log struct {
+	logger *log.Logger
+}
+
+// NewLog returns a new instance of Log writing to logger.
+func NewLog(logger *log.Logger) Log {
+	return Log{logger: logger}
+}
+
+// Notify writes notification to logger.
+func (l Log) Notify(_ context.Context, notification domain.Notification) error {
+	l.logger.Printf("[NOTIFY]: %s to %s: %s: %s", notification.Kind, notification.Recipient,
+		notification.Subject, notification.Body)
+	return nil
+}
+
+// Dispatcher delivers queued notifications through a notifier, it is run
+// periodically by scheduler.
+type Dispatcher struct {
+	store    domain.NotificationService
+	notifier domain.Notifier
+}
+
+// NewDispatcher returns a new instance of Dispatcher.
+func NewDispatcher(store domain.NotificationService, notifier domain.Notifier) Dispatcher {
+	return Dispatcher{store: store, notifier: notifier}
+}
+
+// Dispatch delivers a batch of notifications and returns number of sent
+// ones.
+func (d Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	return d.store.Deliver(ctx, d.notifier, batchSize)
+}
+
+// Recoverer queues recovery emails of carts abandoned for longer than
+// idle, it is run periodically by scheduler.
+type Recoverer struct {
+	store  domain.CartRecoveryService
+	secret []byte
+	idle   time.Duration
+}
+
+// NewRecoverer returns a new instance of Recoverer signing recovery links
+// by secret.
+func NewRecoverer(store domain.CartRecoveryService, secret []byte, idle time.Duration) Recoverer {
+	return Recoverer{store: store, secret: secret, idle: idle}
+}
+
+// Recover detects a batch of abandoned carts and returns number of
+// recovery emails queued.
+func (r Recoverer) Recover(ctx context.Context) (int, error) {
+	return r.store.DetectAbandoned(ctx, time.Now().Add(-r.idle), r.secret, batchSize)
+}
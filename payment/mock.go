@@ -0,0 +1,186 @@
+// Package payment implements payment gateways.
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// MockSignatureHeader is the header holding signature of mock webhooks.
+const MockSignatureHeader = "Mock-Signature"
+
+// Mock represents a local payment gateway keeping intents in memory, it is
+// meant for development and tests. Intents are authorized on creation as
+// if customer confirmed them.
+type Mock struct {
+	secret []byte
+
+	mu      sync.Mutex
+	intents map[string]domain.PaymentIntent
+}
+
+// mockEvent represents webhook payloads of mock gateway.
+type mockEvent struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	IntentID string `json:"intent_id"`
+	Amount   int    `json:"amount"`
+}
+
+// NewMock returns a new instance of Mock verifying webhooks with secret.
+func NewMock(secret []byte) *Mock {
+	return &Mock{
+		secret:  secret,
+		intents: make(map[string]domain.PaymentIntent),
+	}
+}
+
+// CreateIntent creates an authorized intent.
+func (m *Mock) CreateIntent(_ context.Context, input domain.PaymentIntentCreate) (domain.PaymentIntent, error) {
+	if input.Amount <= 0 {
+		return domain.PaymentIntent{}, domain.ErrPaymentDeclined
+	}
+
+	ID, err := randomHex(12)
+	if err != nil {
+		return domain.PaymentIntent{}, err
+	}
+
+	secret, err := randomHex(16)
+	if err != nil {
+		return domain.PaymentIntent{}, err
+	}
+
+	intent := domain.PaymentIntent{
+		ID:           "mock_pi_" + ID,
+		Status:       domain.PaymentStatusAuthorized,
+		Amount:       input.Amount,
+		ClientSecret: "mock_secret_" + secret,
+	}
+
+	m.mu.Lock()
+	m.intents[intent.ID] = intent
+	m.mu.Unlock()
+
+	return intent, nil
+}
+
+// Capture captures amount of an authorized intent, zero amount captures
+// the whole intent.
+func (m *Mock) Capture(_ context.Context, intentID string, amount int) (domain.PaymentIntent, error) {
+	return m.update(intentID, func(intent *domain.PaymentIntent) error {
+		if amount == 0 {
+			amount = intent.Amount
+		}
+
+		if intent.Status != domain.PaymentStatusAuthorized || amount > intent.Amount {
+			return domain.ErrInvalidPaymentState
+		}
+
+		intent.Status = domain.PaymentStatusSucceeded
+		intent.Captured = amount
+		return nil
+	})
+}
+
+// Void cancels an intent not captured yet.
+func (m *Mock) Void(_ context.Context, intentID string) (domain.PaymentIntent, error) {
+	return m.update(intentID, func(intent *domain.PaymentIntent) error {
+		if intent.Status != domain.PaymentStatusPending && intent.Status != domain.PaymentStatusAuthorized {
+			return domain.ErrInvalidPaymentState
+		}
+
+		intent.Status = domain.PaymentStatusCancelled
+		return nil
+	})
+}
+
+// Refund refunds amount of a captured intent, zero amount refunds the rest
+// of captured amount.
+func (m *Mock) Refund(_ context.Context, intentID string, amount int) (domain.PaymentIntent, error) {
+	return m.update(intentID, func(intent *domain.PaymentIntent) error {
+		if amount == 0 {
+			amount = intent.Captured - intent.Refunded
+		}
+
+		if intent.Status != domain.PaymentStatusSucceeded || amount <= 0 ||
+			intent.Refunded+amount > intent.Captured {
+			return domain.ErrInvalidPaymentState
+		}
+
+		intent.Refunded += amount
+		if intent.Refunded == intent.Captured {
+			intent.Status = domain.PaymentStatusRefunded
+		}
+		return nil
+	})
+}
+
+// SignatureHeader returns name of the header holding signature of webhooks.
+func (m *Mock) SignatureHeader() string {
+	return MockSignatureHeader
+}
+
+// ParseEvent verifies signature of a webhook payload and returns its event,
+// all webhooks are rejected without a secret.
+func (m *Mock) ParseEvent(payload []byte, signature string) (domain.PaymentEvent, error) {
+	if len(m.secret) == 0 || !hmac.Equal([]byte(m.Sign(payload)), []byte(signature)) {
+		return domain.PaymentEvent{}, domain.ErrInvalidWebhookSignature
+	}
+
+	var event mockEvent
+	err := json.Unmarshal(payload, &event)
+	if err != nil || event.ID == "" {
+		return domain.PaymentEvent{}, domain.ErrInvalidWebhookSignature
+	}
+
+	return domain.PaymentEvent{
+		ID:       event.ID,
+		Type:     event.Type,
+		IntentID: event.IntentID,
+		Amount:   event.Amount,
+	}, nil
+}
+
+// Sign returns signature of a webhook payload.
+func (m *Mock) Sign(payload []byte) string {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// update applies fn on an intent, intent is left untouched on errors.
+func (m *Mock) update(intentID string, fn func(intent *domain.PaymentIntent) error) (domain.PaymentIntent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	intent, ok := m.intents[intentID]
+	if !ok {
+		return domain.PaymentIntent{}, domain.ErrNoPaymentsFound
+	}
+
+	err := fn(&intent)
+	if err != nil {
+		return domain.PaymentIntent{}, err
+	}
+
+	m.intents[intentID] = intent
+	return intent, nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,135 @@
+package payment_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+	"github.com/mortezadadgar/ecommerce-api/payment"
+)
+
+func TestMock(t *testing.T) {
+	ctx := context.Background()
+	mock := payment.NewMock([]byte("secret"))
+
+	intent, err := mock.CreateIntent(ctx, domain.PaymentIntentCreate{OrderID: 1, Amount: 100})
+	if err != nil {
+		t.Fatalf("CreateIntent: %v", err)
+	}
+
+	_, err = mock.Refund(ctx, intent.ID, 0)
+	if err != domain.ErrInvalidPaymentState {
+		t.Errorf("expected %q from Refund, got %q", domain.ErrInvalidPaymentState, err)
+	}
+
+	intent, err = mock.Capture(ctx, intent.ID, 0)
+	if err != nil {
+		t.Fatalf("Capture: %v", err)
+	}
+
+	intent, err = mock.Refund(ctx, intent.ID, 30)
+	if err != nil {
+		t.Fatalf("Refund: %v", err)
+	}
+
+	if intent.Status != domain.PaymentStatusSucceeded || intent.Captured != 100 || intent.Refunded != 30 {
+		t.Errorf("expected partially refunded intent, got: %#v", intent)
+	}
+
+	payload := []byte(`{"id":"evt_1","type":"payment.succeeded","intent_id":"` + intent.ID + `","amount":100}`)
+
+	_, err = mock.ParseEvent(payload, "bad")
+	if err != domain.ErrInvalidWebhookSignature {
+		t.Errorf("expected %q from ParseEvent, got %q", domain.ErrInvalidWebhookSignature, err)
+	}
+
+	event, err := mock.ParseEvent(payload, mock.Sign(payload))
+	if err != nil {
+		t.Fatalf("ParseEvent: %v", err)
+	}
+
+	want := domain.PaymentEvent{ID: "evt_1", Type: domain.PaymentEventSucceeded, IntentID: intent.ID, Amount: 100}
+	if event != want {
+		t.Errorf("mismatch\n got: %#v\nwant: %#v", event, want)
+	}
+}
+
+func TestStripe(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sk_test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/payment_intents":
+			if r.FormValue("amount") != "100" || r.FormValue("capture_method") != "manual" {
+				t.Errorf("unexpected form: %v", r.Form)
+			}
+			json.NewEncoder(w).Encode(map[string]any{
+				"id": "pi_1", "status": "requires_payment_method", "amount": 100, "client_secret": "pi_1_secret",
+			})
+		case "/v1/payment_intents/pi_1/capture":
+			json.NewEncoder(w).Encode(map[string]any{
+				"id": "pi_1", "status": "succeeded", "amount": 100, "amount_received": 100,
+				"latest_charge": map[string]any{"amount_refunded": 0},
+			})
+		case "/v1/payment_intents/pi_2/cancel":
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]any{
+				"error": map[string]any{"code": "payment_intent_unexpected_state", "message": "already captured"},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	stripe := payment.NewStripe(ts.URL, "sk_test", []byte("whsec"))
+
+	intent, err := stripe.CreateIntent(ctx, domain.PaymentIntentCreate{OrderID: 1, Amount: 100, Currency: "usd"})
+	if err != nil {
+		t.Fatalf("CreateIntent: %v", err)
+	}
+
+	if intent.ID != "pi_1" || intent.Status != domain.PaymentStatusPending || intent.ClientSecret != "pi_1_secret" {
+		t.Errorf("unexpected intent: %#v", intent)
+	}
+
+	intent, err = stripe.Capture(ctx, "pi_1", 0)
+	if err != nil {
+		t.Fatalf("Capture: %v", err)
+	}
+
+	if intent.Status != domain.PaymentStatusSucceeded || intent.Captured != 100 {
+		t.Errorf("unexpected intent: %#v", intent)
+	}
+
+	_, err = stripe.Void(ctx, "pi_2")
+	if !errors.Is(err, domain.ErrInvalidPaymentState) {
+		t.Errorf("expected %q from Void, got %q", domain.ErrInvalidPaymentState, err)
+	}
+
+	payload := []byte(`{"id":"evt_1","type":"charge.refunded","data":{"object":{"payment_intent":"pi_1","amount_refunded":40}}}`)
+
+	_, err = stripe.ParseEvent(payload, stripe.Sign(payload, time.Now().Add(-time.Hour)))
+	if err != domain.ErrInvalidWebhookSignature {
+		t.Errorf("expected %q from ParseEvent, got %q", domain.ErrInvalidWebhookSignature, err)
+	}
+
+	event, err := stripe.ParseEvent(payload, stripe.Sign(payload, time.Now()))
+	if err != nil {
+		t.Fatalf("ParseEvent: %v", err)
+	}
+
+	want := domain.PaymentEvent{ID: "evt_1", Type: domain.PaymentEventRefunded, IntentID: "pi_1", Amount: 40}
+	if event != want {
+		t.Errorf("mismatch\n got: %#v\nwant: %#v", event, want)
+	}
+}
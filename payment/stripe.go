@@ -0,0 +1,339 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+const (
+	// StripeSignatureHeader is the header holding signature of stripe
+	// webhooks.
+	StripeSignatureHeader = "Stripe-Signature"
+
+	// StripeAPIURL is the default base url of stripe API.
+	StripeAPIURL = "https://api.stripe.com"
+
+	// stripeTolerance is how old signed webhooks may be to be accepted.
+	stripeTolerance = 5 * time.Minute
+)
+
+// Stripe represents a payment gateway speaking stripe API, base url can be
+// pointed at any compatible server.
+type Stripe struct {
+	baseURL       string
+	secretKey     string
+	webhookSecret []byte
+	client        *http.Client
+}
+
+// stripeIntent represents payment intents of stripe API, latest charge is
+// expanded to find refunded amount.
+type stripeIntent struct {
+	ID             string          `json:"id"`
+	Status         string          `json:"status"`
+	Amount         int             `json:"amount"`
+	AmountReceived int             `json:"amount_received"`
+	ClientSecret   string          `json:"client_secret"`
+	LatestCharge   json.RawMessage `json:"latest_charge"`
+}
+
+// stripeCharge represents charges of stripe API.
+type stripeCharge struct {
+	PaymentIntent  string `json:"payment_intent"`
+	AmountCaptured int    `json:"amount_captured"`
+	AmountRefunded int    `json:"amount_refunded"`
+}
+
+// stripeEvent represents webhook payloads of stripe.
+type stripeEvent struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// stripeError represents error responses of stripe API.
+type stripeError struct {
+	Error struct {
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// NewStripe returns a new instance of Stripe, empty baseURL defaults to
+// StripeAPIURL.
+func NewStripe(baseURL string, secretKey string, webhookSecret []byte) Stripe {
+	if baseURL == "" {
+		baseURL = StripeAPIURL
+	}
+
+	return Stripe{
+		baseURL:       strings.TrimSuffix(baseURL, "/"),
+		secretKey:     secretKey,
+		webhookSecret: webhookSecret,
+		client:        &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// CreateIntent creates an intent captured manually after authorization.
+func (s Stripe) CreateIntent(ctx context.Context, input domain.PaymentIntentCreate) (domain.PaymentIntent, error) {
+	form := url.Values{
+		"amount":             {strconv.Itoa(input.Amount)},
+		"currency":           {input.Currency},
+		"capture_method":     {"manual"},
+		"metadata[order_id]": {strconv.Itoa(input.OrderID)},
+		"expand[]":           {"latest_charge"},
+	}
+
+	return s.postIntent(ctx, "/v1/payment_intents", form)
+}
+
+// Capture captures amount of an authorized intent, zero amount captures
+// the whole intent.
+func (s Stripe) Capture(ctx context.Context, intentID string, amount int) (domain.PaymentIntent, error) {
+	form := url.Values{"expand[]": {"latest_charge"}}
+	if amount > 0 {
+		form.Set("amount_to_capture", strconv.Itoa(amount))
+	}
+
+	return s.postIntent(ctx, "/v1/payment_intents/"+url.PathEscape(intentID)+"/capture", form)
+}
+
+// Void cancels an intent not captured yet.
+func (s Stripe) Void(ctx context.Context, intentID string) (domain.PaymentIntent, error) {
+	form := url.Values{"expand[]": {"latest_charge"}}
+	return s.postIntent(ctx, "/v1/payment_intents/"+url.PathEscape(intentID)+"/cancel", form)
+}
+
+// Refund refunds amount of a captured intent, zero amount refunds the rest
+// of captured amount.
+func (s Stripe) Refund(ctx context.Context, intentID string, amount int) (domain.PaymentIntent, error) {
+	form := url.Values{"payment_intent": {intentID}}
+	if amount > 0 {
+		form.Set("amount", strconv.Itoa(amount))
+	}
+
+	err := s.do(ctx, http.MethodPost, "/v1/refunds", form, nil)
+	if err != nil {
+		return domain.PaymentIntent{}, err
+	}
+
+	var intent stripeIntent
+	err = s.do(ctx, http.MethodGet, "/v1/payment_intents/"+url.PathEscape(intentID)+"?expand[]=latest_charge", nil, &intent)
+	if err != nil {
+		return domain.PaymentIntent{}, err
+	}
+
+	return intent.model(), nil
+}
+
+// SignatureHeader returns name of the header holding signature of webhooks.
+func (s Stripe) SignatureHeader() string {
+	return StripeSignatureHeader
+}
+
+// ParseEvent verifies signature of a webhook payload and returns its event,
+// events of no interest are returned with an empty type.
+func (s Stripe) ParseEvent(payload []byte, signature string) (domain.PaymentEvent, error) {
+	err := s.verify(payload, signature, time.Now())
+	if err != nil {
+		return domain.PaymentEvent{}, err
+	}
+
+	var event stripeEvent
+	err = json.Unmarshal(payload, &event)
+	if err != nil || event.ID == "" {
+		return domain.PaymentEvent{}, domain.ErrInvalidWebhookSignature
+	}
+
+	result := domain.PaymentEvent{ID: event.ID}
+
+	if event.Type == "charge.refunded" {
+		var charge stripeCharge
+		err = json.Unmarshal(event.Data.Object, &charge)
+		if err != nil {
+			return domain.PaymentEvent{}, fmt.Errorf("failed to parse stripe charge: %v", err)
+		}
+
+		result.Type = domain.PaymentEventRefunded
+		result.IntentID = charge.PaymentIntent
+		result.Amount = charge.AmountRefunded
+		return result, nil
+	}
+
+	var intent stripeIntent
+	err = json.Unmarshal(event.Data.Object, &intent)
+	if err != nil {
+		return domain.PaymentEvent{}, fmt.Errorf("failed to parse stripe payment intent: %v", err)
+	}
+
+	result.IntentID = intent.ID
+	switch event.Type {
+	case "payment_intent.amount_capturable_updated":
+		result.Type = domain.PaymentEventAuthorized
+	case "payment_intent.succeeded":
+		result.Type = domain.PaymentEventSucceeded
+		result.Amount = intent.AmountReceived
+	case "payment_intent.payment_failed":
+		result.Type = domain.PaymentEventFailed
+	case "payment_intent.canceled":
+		result.Type = domain.PaymentEventCancelled
+	}
+
+	return result, nil
+}
+
+// Sign returns signature header of a webhook payload signed at t.
+func (s Stripe) Sign(payload []byte, t time.Time) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + s.signature(timestamp, payload)
+}
+
+func (s Stripe) signature(timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, s.webhookSecret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks a signature header formatted as t=timestamp,v1=signature,
+// any of v1 signatures may match.
+func (s Stripe) verify(payload []byte, header string, now time.Time) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	t, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(s.webhookSecret) == 0 {
+		return domain.ErrInvalidWebhookSignature
+	}
+
+	age := now.Sub(time.Unix(t, 0))
+	if age > stripeTolerance || age < -stripeTolerance {
+		return domain.ErrInvalidWebhookSignature
+	}
+
+	expected := s.signature(timestamp, payload)
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(expected), []byte(signature)) {
+			return nil
+		}
+	}
+
+	return domain.ErrInvalidWebhookSignature
+}
+
+func (s Stripe) postIntent(ctx context.Context, path string, form url.Values) (domain.PaymentIntent, error) {
+	var intent stripeIntent
+	err := s.do(ctx, http.MethodPost, path, form, &intent)
+	if err != nil {
+		return domain.PaymentIntent{}, err
+	}
+
+	return intent.model(), nil
+}
+
+// do sends a request to stripe API and decodes its response to v when not
+// nil, errors of API are mapped to domain errors.
+func (s Stripe) do(ctx context.Context, method string, path string, form url.Values, v any) error {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+s.secretKey)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrPaymentProviderConnection, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var e stripeError
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+
+		switch {
+		case e.Error.Type == "card_error":
+			return fmt.Errorf("%w: %s", domain.ErrPaymentDeclined, e.Error.Message)
+		case resp.StatusCode == http.StatusNotFound:
+			return domain.ErrNoPaymentsFound
+		case e.Error.Code == "payment_intent_unexpected_state" || e.Error.Code == "charge_already_refunded":
+			return fmt.Errorf("%w: %s", domain.ErrInvalidPaymentState, e.Error.Message)
+		}
+
+		return fmt.Errorf("stripe responded with status %d: %s", resp.StatusCode, e.Error.Message)
+	}
+
+	if v == nil {
+		return nil
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(v)
+	if err != nil {
+		return fmt.Errorf("failed to parse stripe response: %v", err)
+	}
+
+	return nil
+}
+
+// model returns intent as seen by domain.
+func (i stripeIntent) model() domain.PaymentIntent {
+	var charge stripeCharge
+	_ = json.Unmarshal(i.LatestCharge, &charge)
+
+	intent := domain.PaymentIntent{
+		ID:           i.ID,
+		Amount:       i.Amount,
+		Captured:     i.AmountReceived,
+		Refunded:     charge.AmountRefunded,
+		ClientSecret: i.ClientSecret,
+	}
+
+	switch i.Status {
+	case "requires_capture":
+		intent.Status = domain.PaymentStatusAuthorized
+	case "succeeded":
+		intent.Status = domain.PaymentStatusSucceeded
+		if intent.Captured > 0 && intent.Refunded >= intent.Captured {
+			intent.Status = domain.PaymentStatusRefunded
+		}
+	case "canceled":
+		intent.Status = domain.PaymentStatusCancelled
+	default:
+		intent.Status = domain.PaymentStatusPending
+	}
+
+	return intent
+}
@@ -0,0 +1,243 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// addressStore represents addresses database.
+type addressStore struct {
+	db *pgxpool.Pool
+}
+
+// NewAddressStore returns a new instance of AddressStore.
+func NewAddressStore(db *pgxpool.Pool) addressStore {
+	return addressStore{db: db}
+}
+
+// Create creates a new address of user in database, the first address of
+// user becomes its default shipping and billing address.
+func (a addressStore) Create(ctx context.Context, address *domain.Address) error {
+	tx, err := a.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	// user is locked so defaults of its first address are given out once.
+	_, err = tx.Exec(ctx, `SELECT id FROM users WHERE id = @user_id FOR UPDATE`,
+		pgx.NamedArgs{"user_id": address.UserID})
+	if err != nil {
+		return fmt.Errorf("failed to lock user: %v", err)
+	}
+
+	var count int
+	err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM addresses WHERE user_id = @user_id`,
+		pgx.NamedArgs{"user_id": address.UserID}).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to query addresses: %v", err)
+	}
+
+	if count == 0 {
+		address.DefaultShipping = true
+		address.DefaultBilling = true
+	}
+
+	err = unsetDefaultAddresses(ctx, tx, *address)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO addresses(user_id, name, line1, line2, city, region, postal_code, country, phone,
+		default_shipping, default_billing)
+	VALUES(@user_id, @name, @line1, @line2, @city, @region, @postal_code, @country, @phone,
+		@default_shipping, @default_billing)
+	RETURNING id, created_at, updated_at
+	`
+
+	err = tx.QueryRow(ctx, query, addressArgs(*address)).Scan(&address.ID, &address.CreatedAt, &address.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert address: %v", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return nil
+}
+
+// GetByID get address of user by id from database.
+func (a addressStore) GetByID(ctx context.Context, userID int, ID int) (domain.Address, error) {
+	query := `SELECT * FROM addresses WHERE id = @id AND user_id = @user_id`
+
+	rows, err := a.db.Query(ctx, query, pgx.NamedArgs{"id": ID, "user_id": userID})
+	if err != nil {
+		return domain.Address{}, fmt.Errorf("failed to query address: %v", err)
+	}
+
+	address, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.Address])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Address{}, domain.ErrNoAddressesFound
+		}
+		return domain.Address{}, fmt.Errorf("failed to scan row of address: %v", err)
+	}
+
+	return address, nil
+}
+
+// List lists addresses of user, defaults first.
+func (a addressStore) List(ctx context.Context, userID int) ([]domain.Address, error) {
+	query := `
+	SELECT * FROM addresses
+	WHERE user_id = @user_id
+	ORDER BY default_shipping DESC, default_billing DESC, id
+	`
+
+	rows, err := a.db.Query(ctx, query, pgx.NamedArgs{"user_id": userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query list addresses: %v", err)
+	}
+
+	addresses, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.Address])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of addresses: %v", err)
+	}
+
+	if len(addresses) == 0 {
+		return nil, domain.ErrNoAddressesFound
+	}
+
+	return addresses, nil
+}
+
+// Update updates an address of user by id in database.
+func (a addressStore) Update(ctx context.Context, userID int, ID int, input domain.AddressUpdate) (domain.Address, error) {
+	tx, err := a.db.Begin(ctx)
+	if err != nil {
+		return domain.Address{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `SELECT * FROM addresses WHERE id = @id AND user_id = @user_id FOR UPDATE`
+	rows, err := tx.Query(ctx, query, pgx.NamedArgs{"id": ID, "user_id": userID})
+	if err != nil {
+		return domain.Address{}, fmt.Errorf("failed to query address: %v", err)
+	}
+
+	address, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.Address])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Address{}, domain.ErrNoAddressesFound
+		}
+		return domain.Address{}, fmt.Errorf("failed to scan row of address: %v", err)
+	}
+
+	err = input.UpdateModel(&address)
+	if err != nil {
+		return domain.Address{}, err
+	}
+
+	err = unsetDefaultAddresses(ctx, tx, address)
+	if err != nil {
+		return domain.Address{}, err
+	}
+
+	query = `
+	UPDATE addresses
+	SET name             = @name,
+		line1            = @line1,
+		line2            = @line2,
+		city             = @city,
+		region           = @region,
+		postal_code      = @postal_code,
+		country          = @country,
+		phone            = @phone,
+		default_shipping = @default_shipping,
+		default_billing  = @default_billing,
+		updated_at       = NOW()
+	WHERE id = @id
+	RETURNING updated_at
+	`
+
+	args := addressArgs(address)
+	args["id"] = ID
+
+	err = tx.QueryRow(ctx, query, args).Scan(&address.UpdatedAt)
+	if err != nil {
+		return domain.Address{}, fmt.Errorf("failed to update address: %v", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.Address{}, fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return address, nil
+}
+
+// Delete deletes an address of user by id from database, orders keep their
+// own copy of it.
+func (a addressStore) Delete(ctx context.Context, userID int, ID int) error {
+	result, err := a.db.Exec(ctx, `DELETE FROM addresses WHERE id = @id AND user_id = @user_id`,
+		pgx.NamedArgs{"id": ID, "user_id": userID})
+	if err != nil {
+		return fmt.Errorf("failed to delete from addresses: %v", err)
+	}
+
+	if rows := result.RowsAffected(); rows != 1 {
+		return domain.ErrNoAddressesFound
+	}
+
+	return nil
+}
+
+// unsetDefaultAddresses takes default flags of address away from other
+// addresses of its user.
+func unsetDefaultAddresses(ctx context.Context, q querier, address domain.Address) error {
+	query := `
+	UPDATE addresses
+	SET default_shipping = default_shipping AND NOT @default_shipping,
+		default_billing  = default_billing AND NOT @default_billing
+	WHERE user_id = @user_id AND id <> @id
+	`
+
+	args := pgx.NamedArgs{
+		"id":               address.ID,
+		"user_id":          address.UserID,
+		"default_shipping": address.DefaultShipping,
+		"default_billing":  address.DefaultBilling,
+	}
+
+	_, err := q.Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("failed to update default addresses: %v", err)
+	}
+
+	return nil
+}
+
+// addressArgs returns named arguments of address columns.
+func addressArgs(address domain.Address) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"user_id":          address.UserID,
+		"name":             address.Name,
+		"line1":            address.Line1,
+		"line2":            address.Line2,
+		"city":             address.City,
+		"region":           address.Region,
+		"postal_code":      address.PostalCode,
+		"country":          address.Country,
+		"phone":            address.Phone,
+		"default_shipping": address.DefaultShipping,
+		"default_billing":  address.DefaultBilling,
+	}
+}
@@ -0,0 +1,108 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+	"github.com/mortezadadgar/ecommerce-api/postgres"
+)
+
+func TestAddressService(t *testing.T) {
+	db := newCartTestDB(t, "addresses")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := postgres.NewAddressStore(db)
+
+	home := domain.AddressCreate{
+		Name: "home", Line1: "1 main st", City: "springfield", Region: "IL", PostalCode: "62701", Country: "us",
+	}.CreateModel(1)
+	err := store.Create(ctx, &home)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if !home.DefaultShipping || !home.DefaultBilling {
+		t.Errorf("expected first address to be default, got: %+v", home)
+	}
+
+	office := domain.AddressCreate{
+		Name: "office", Line1: "2 high st", City: "london", PostalCode: "SW1A 1AA", Country: "GB",
+		DefaultBilling: true,
+	}.CreateModel(1)
+	err = store.Create(ctx, &office)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	addresses, err := store.List(ctx, 1)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+
+	shipping, billing := domain.DefaultAddresses(addresses)
+	if shipping.ID != home.ID || billing.ID != office.ID {
+		t.Errorf("expected shipping %d and billing %d, got: %d, %d", home.ID, office.ID, shipping.ID, billing.ID)
+	}
+
+	defaultShipping := true
+	updated, err := store.Update(ctx, 1, office.ID, domain.AddressUpdate{DefaultShipping: &defaultShipping})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	if !updated.DefaultShipping {
+		t.Errorf("expected office to be default shipping address")
+	}
+
+	home, err = store.GetByID(ctx, 1, home.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+
+	if home.DefaultShipping {
+		t.Errorf("expected home to no longer be default shipping address")
+	}
+
+	_, err = store.GetByID(ctx, 2, home.ID)
+	if err != domain.ErrNoAddressesFound {
+		t.Errorf("expected %q, got %q", domain.ErrNoAddressesFound, err)
+	}
+
+	// orders keep their own copy of addresses.
+	owner := domain.CartOwner{UserID: 1}
+	_, err = postgres.NewCartStore(db).AddItem(ctx, owner, domain.CartItem{ProductID: 1, Quantity: 1})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	orders := postgres.NewOrderStore(db)
+	order, err := orders.Checkout(ctx, 1, domain.CheckoutDetails{
+		ShippingAddress: updated.Snapshot(),
+		BillingAddress:  home.Snapshot(),
+	})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+
+	err = store.Delete(ctx, 1, office.ID)
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	order, err = orders.GetByID(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+
+	if order.ShippingAddress == nil || *order.ShippingAddress != *updated.Snapshot() {
+		t.Errorf("expected shipping address %+v, got: %+v", updated.Snapshot(), order.ShippingAddress)
+	}
+
+	if order.BillingAddress == nil || order.BillingAddress.Country != "US" {
+		t.Errorf("expected billing address in US, got: %+v", order.BillingAddress)
+	}
+}
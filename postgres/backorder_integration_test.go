@@ -0,0 +1,81 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+	"github.com/mortezadadgar/ecommerce-api/postgres"
+)
+
+func TestOrderService_Backorders(t *testing.T) {
+	db := newCartTestDB(t, "orders_backorders")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	product := domain.ProductCreate{
+		SKU:             "SKU-2",
+		Name:            "backorderable",
+		Description:     "description",
+		CategoryID:      1,
+		Price:           10,
+		Quantity:        1,
+		InventoryPolicy: domain.InventoryPolicyBackorder,
+		BackorderLimit:  2,
+	}.CreateModel()
+	err := postgres.NewProductStore(db).Create(ctx, &product)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	owner := domain.CartOwner{UserID: 1}
+	_, err = postgres.NewCartStore(db).AddItem(ctx, owner, domain.CartItem{ProductID: product.ID, Quantity: 4})
+	if err != domain.ErrInsufficientStock {
+		t.Errorf("expected %q from AddItem, got %q", domain.ErrInsufficientStock, err)
+	}
+
+	_, err = postgres.NewCartStore(db).AddItem(ctx, owner, domain.CartItem{ProductID: product.ID, Quantity: 3})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	order, err := postgres.NewOrderStore(db).Checkout(ctx, 1, domain.CheckoutDetails{})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+
+	if order.Lines[0].Backordered != 2 {
+		t.Errorf("expected backordered quantity of %d, got: %d", 2, order.Lines[0].Backordered)
+	}
+
+	receipt := domain.StockMovementCreate{
+		ProductID:   product.ID,
+		WarehouseID: domain.DefaultWarehouseID,
+		Type:        domain.StockMovementReceipt,
+		Quantity:    5,
+	}
+	_, err = postgres.NewInventoryStore(db).RecordMovements(ctx, receipt.CreateModel(0))
+	if err != nil {
+		t.Fatalf("RecordMovements: %v", err)
+	}
+
+	order, err = postgres.NewOrderStore(db).GetByID(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+
+	if order.Lines[0].Backordered != 0 {
+		t.Errorf("expected backorder to be allocated, got: %d", order.Lines[0].Backordered)
+	}
+
+	got, err := postgres.NewProductStore(db).GetByID(ctx, product.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+
+	if got.Quantity != 3 {
+		t.Errorf("expected quantity of %d, got: %d", 3, got.Quantity)
+	}
+}
@@ -0,0 +1,171 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// bundleStore represents bundle components database.
+type bundleStore struct {
+	db *pgxpool.Pool
+}
+
+// NewBundleStore returns a new instance of BundleStore.
+func NewBundleStore(db *pgxpool.Pool) bundleStore {
+	return bundleStore{db: db}
+}
+
+// ListComponents lists components of a bundle.
+func (b bundleStore) ListComponents(ctx context.Context, bundleID int) ([]domain.BundleComponent, error) {
+	err := checkBundle(ctx, b.db, bundleID)
+	if err != nil {
+		return nil, err
+	}
+
+	components, err := listComponents(ctx, b.db, []int{bundleID})
+	if err != nil {
+		return nil, err
+	}
+
+	return components[bundleID], nil
+}
+
+// SetComponents replaces components of a bundle.
+func (b bundleStore) SetComponents(ctx context.Context, bundleID int, components []domain.BundleComponent) ([]domain.BundleComponent, error) {
+	tx, err := b.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = checkBundle(ctx, tx, bundleID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM bundle_components WHERE bundle_id = @id`, pgx.NamedArgs{"id": bundleID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete bundle components: %v", err)
+	}
+
+	err = insertComponents(ctx, tx, bundleID, components)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := listComponents(ctx, tx, []int{bundleID})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return result[bundleID], nil
+}
+
+// checkBundle returns an error when product does not exist or is not a bundle.
+func checkBundle(ctx context.Context, q querier, productID int) error {
+	typ, err := productType(ctx, q, productID)
+	if err != nil {
+		return err
+	}
+
+	if typ != domain.ProductTypeBundle {
+		return domain.ErrNotBundle
+	}
+
+	return nil
+}
+
+// insertComponents inserts components of a bundle, only simple products
+// are accepted as components.
+func insertComponents(ctx context.Context, q querier, bundleID int, components []domain.BundleComponent) error {
+	query := `
+	INSERT INTO bundle_components(bundle_id, product_id, quantity)
+	SELECT @bundle_id, id, @quantity FROM products
+	WHERE id = @product_id AND type = 'simple'
+	`
+
+	for _, c := range components {
+		args := pgx.NamedArgs{
+			"bundle_id":  bundleID,
+			"product_id": c.ProductID,
+			"quantity":   c.Quantity,
+		}
+
+		result, err := q.Exec(ctx, query, args)
+		if err != nil {
+			return fmt.Errorf("failed to insert bundle component: %v", err)
+		}
+
+		if result.RowsAffected() != 1 {
+			return domain.ErrInvalidBundleComponent
+		}
+	}
+
+	return nil
+}
+
+// listComponents lists components of the given bundles keyed by bundle id.
+func listComponents(ctx context.Context, q querier, bundleIDs []int) (map[int][]domain.BundleComponent, error) {
+	query := `
+	SELECT b.bundle_id, b.product_id, b.quantity, p.price,
+		p.quantity - COALESCE(r.quantity, 0) AS stock
+	FROM bundle_components b
+	INNER JOIN products p ON p.id = b.product_id
+	LEFT JOIN active_reservations r ON r.product_id = b.product_id
+	WHERE b.bundle_id = ANY(@ids)
+	ORDER BY b.bundle_id, b.product_id
+	`
+
+	rows, err := q.Query(ctx, query, pgx.NamedArgs{"ids": bundleIDs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bundle components: %v", err)
+	}
+
+	components, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.BundleComponent])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of bundle components: %v", err)
+	}
+
+	result := make(map[int][]domain.BundleComponent)
+	for _, c := range components {
+		result[c.BundleID] = append(result[c.BundleID], c)
+	}
+
+	return result, nil
+}
+
+// applyBundles computes price and quantity of bundles within products.
+func applyBundles(ctx context.Context, q querier, products []domain.Product) error {
+	var ids []int
+	for _, p := range products {
+		if p.IsBundle() {
+			ids = append(ids, p.ID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	components, err := listComponents(ctx, q, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range products {
+		if products[i].IsBundle() {
+			products[i].ApplyComponents(components[products[i].ID])
+		}
+	}
+
+	return nil
+}
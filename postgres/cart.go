@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
@@ -21,65 +22,35 @@ func NewCartStore(db *pgxpool.Pool) cartStore {
 	return cartStore{db: db}
 }
 
-// Create creates a new cart in database.
-func (c cartStore) Create(ctx context.Context, cart *domain.Cart) error {
-	query := `
-	INSERT INTO carts(product_id, quantity, user_id)
-	VALUES(@product_id, @quantity, @user_id)
-	RETURNING id
-	`
-
-	args := pgx.NamedArgs{
-		"product_id": cart.ProductID,
-		"quantity":   cart.Quantity,
-		"user_id":    cart.UserID,
-	}
-
-	err := c.db.QueryRow(ctx, query, args).Scan(&cart.ID)
-	if err != nil {
-		pgErr := pgError(err)
-		if pgErr.Code == pgerrcode.ForeignKeyViolation {
-			if pgErr.ConstraintName == "carts_user_id_fkey" {
-				return domain.ErrCartInvalidUserID
-			}
-			if pgErr.ConstraintName == "carts_product_id_fkey" {
-				return domain.ErrCartInvalidProductID
-			}
-		}
-		return err
-	}
-
-	return nil
-}
-
-// GetByUser get user's cart by id from database.
-func (c cartStore) GetByUser(ctx context.Context, userID int) ([]domain.Cart, error) {
-	cart, err := c.List(ctx, domain.CartFilter{UserID: userID})
+// GetByID get cart by id from database.
+func (c cartStore) GetByID(ctx context.Context, ID int) (domain.Cart, error) {
+	carts, err := c.List(ctx, domain.CartFilter{ID: ID})
 	if err != nil {
-		return nil, err
+		return domain.Cart{}, err
 	}
 
-	return cart, nil
+	return carts[0], nil
 }
 
-// GetByID get cart by id from database.
-func (c cartStore) GetByID(ctx context.Context, ID int) (domain.Cart, error) {
-	cart, err := c.List(ctx, domain.CartFilter{ID: ID})
+// GetByOwner get cart of a user or a guest from database.
+func (c cartStore) GetByOwner(ctx context.Context, owner domain.CartOwner) (domain.Cart, error) {
+	cartID, err := findCart(ctx, c.db, owner)
 	if err != nil {
 		return domain.Cart{}, err
 	}
 
-	return cart[0], nil
+	return c.GetByID(ctx, cartID)
 }
 
 // List lists carts with optional filter.
 func (c cartStore) List(ctx context.Context, filter domain.CartFilter) ([]domain.Cart, error) {
 	query := `
-	SELECT * FROM carts
+	SELECT id, COALESCE(user_id, 0) AS user_id, user_id IS NULL AS guest, created_at, updated_at
+	FROM carts
 	WHERE 1=1
-	` + FormatSort(filter.Sort) + `
 	` + FormatAndInt("id", filter.ID) + `
 	` + FormatAndInt("user_id", filter.UserID) + `
+	` + FormatSort(filter.Sort) + `
 	` + FormatLimitOffset(filter.Limit, filter.Offset) + `
 	`
 
@@ -97,68 +68,803 @@ func (c cartStore) List(ctx context.Context, filter domain.CartFilter) ([]domain
 		return nil, domain.ErrNoCartsFound
 	}
 
+	err = fillCarts(ctx, c.db, carts)
+	if err != nil {
+		return nil, err
+	}
+
 	return carts, nil
 }
 
-// Update updates a cart by id in database.
-func (c cartStore) Update(ctx context.Context, ID int, input domain.CartUpdate) (domain.Cart, error) {
+// Delete deletes a cart by id from database and releases its reservations.
+func (c cartStore) Delete(ctx context.Context, ID int) error {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = clearCart(ctx, tx, ID)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(ctx, `DELETE FROM carts WHERE id = @id`, pgx.NamedArgs{"id": ID})
+	if err != nil {
+		return fmt.Errorf("failed to delete from carts: %v", err)
+	}
+
+	if rows := result.RowsAffected(); rows != 1 {
+		return domain.ErrNoCartsFound
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return nil
+}
+
+// AddItem adds an item to owner's cart, the cart is created when owner
+// has none and quantity is added up when product is already in cart.
+func (c cartStore) AddItem(ctx context.Context, owner domain.CartOwner, item domain.CartItem) (domain.Cart, error) {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return domain.Cart{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	cartID, err := ensureCart(ctx, tx, owner)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	err = addCartItem(ctx, tx, cartID, item)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	return commitCart(ctx, tx, cartID)
+}
+
+// UpdateItem sets quantity of an item in owner's cart.
+func (c cartStore) UpdateItem(ctx context.Context, owner domain.CartOwner, itemID int, quantity int) (domain.Cart, error) {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return domain.Cart{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	item, err := lockCartItem(ctx, tx, owner, itemID)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	query := `
+	UPDATE cart_items
+	SET quantity = @quantity, updated_at = NOW()
+	WHERE id = @id
+	`
+
+	_, err = tx.Exec(ctx, query, pgx.NamedArgs{"id": itemID, "quantity": quantity})
+	if err != nil {
+		return domain.Cart{}, fmt.Errorf("failed to update cart item: %v", err)
+	}
+
+	item.Quantity = quantity
+	err = reserveStock(ctx, tx, item)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	return commitCart(ctx, tx, item.CartID)
+}
+
+// RemoveItem removes an item from owner's cart.
+func (c cartStore) RemoveItem(ctx context.Context, owner domain.CartOwner, itemID int) (domain.Cart, error) {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return domain.Cart{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	item, err := lockCartItem(ctx, tx, owner, itemID)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	// reservations of item are released by cascade.
+	_, err = tx.Exec(ctx, `DELETE FROM cart_items WHERE id = @id`, pgx.NamedArgs{"id": itemID})
+	if err != nil {
+		return domain.Cart{}, fmt.Errorf("failed to delete from cart items: %v", err)
+	}
+
+	return commitCart(ctx, tx, item.CartID)
+}
+
+// Clear removes all items of owner's cart.
+func (c cartStore) Clear(ctx context.Context, owner domain.CartOwner) error {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	cartID, err := findCart(ctx, tx, owner)
+	if err != nil {
+		return err
+	}
+
+	err = clearCart(ctx, tx, cartID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE carts SET updated_at = NOW() WHERE id = @id`, pgx.NamedArgs{"id": cartID})
+	if err != nil {
+		return fmt.Errorf("failed to update cart: %v", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return nil
+}
+
+// Merge moves items of a guest cart into user's cart, lines of the same
+// product are resolved by strategy and the guest cart is deleted. Merged
+// lines are reserved where stock allows.
+func (c cartStore) Merge(ctx context.Context, userID int, token []byte, strategy string) (domain.Cart, error) {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return domain.Cart{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	guestID, err := findCart(ctx, tx, domain.CartOwner{Token: token})
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	cartID, err := ensureCart(ctx, tx, domain.CartOwner{UserID: userID})
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	items, err := listCartItems(ctx, tx, []int{cartID})
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	guest, err := listCartItems(ctx, tx, []int{guestID})
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	// destination and shipping method of guest are kept when user's cart
+	// has no destination.
+	query := `
+	UPDATE carts c
+	SET country = g.country, region = g.region, postal_code = g.postal_code,
+		shipping_method_id = g.shipping_method_id
+	FROM carts g
+	WHERE c.id = @cart_id AND g.id = @guest_id AND c.country = ''
+	`
+
+	_, err = tx.Exec(ctx, query, pgx.NamedArgs{"cart_id": cartID, "guest_id": guestID})
+	if err != nil {
+		return domain.Cart{}, fmt.Errorf("failed to update cart destination: %v", err)
+	}
+
+	// reservations of both carts are released so stock held by them
+	// counts as available to the merged lines.
+	_, err = tx.Exec(ctx, `DELETE FROM carts WHERE id = @id`, pgx.NamedArgs{"id": guestID})
+	if err != nil {
+		return domain.Cart{}, fmt.Errorf("failed to delete from carts: %v", err)
+	}
+
+	query = `
+	DELETE FROM stock_reservations
+	WHERE cart_item_id IN (SELECT id FROM cart_items WHERE cart_id = @cart_id)
+	`
+
+	_, err = tx.Exec(ctx, query, pgx.NamedArgs{"cart_id": cartID})
+	if err != nil {
+		return domain.Cart{}, fmt.Errorf("failed to delete stock reservations: %v", err)
+	}
+
+	productIDs := make([]int, 0, len(items)+len(guest))
+	for _, item := range items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	for _, item := range guest {
+		productIDs = append(productIDs, item.ProductID)
+	}
+
+	products, err := getProducts(ctx, tx, productIDs)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	backordered, err := backorderedStock(ctx, tx, productIDs)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	limits := make(map[int]int, len(products))
+	for _, p := range products {
+		if limit, ok := p.OrderableQuantity(backordered[p.ID]); ok {
+			limits[p.ID] = limit
+		}
+	}
+
+	for _, item := range domain.MergeCartItems(items, guest, strategy, limits) {
+		item.ID, err = setCartItem(ctx, tx, cartID, item)
+		if err != nil {
+			return domain.Cart{}, err
+		}
+
+		// lines falling short of stock are kept without reservations, same
+		// as lines whose reservations expired, so merging never fails on
+		// stock.
+		sp, err := tx.Begin(ctx)
+		if err != nil {
+			return domain.Cart{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+		}
+
+		err = reserveStock(ctx, sp, item)
+		if errors.Is(err, domain.ErrInsufficientStock) {
+			sp.Rollback(ctx)
+			continue
+		}
+		if err != nil {
+			return domain.Cart{}, err
+		}
+
+		err = sp.Commit(ctx)
+		if err != nil {
+			return domain.Cart{}, fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+		}
+	}
+
+	return commitCart(ctx, tx, cartID)
+}
+
+// ApplyCoupon applies a coupon code to owner's cart.
+func (c cartStore) ApplyCoupon(ctx context.Context, owner domain.CartOwner, code string) (domain.Cart, error) {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return domain.Cart{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	cartID, err := findCart(ctx, tx, owner)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	carts := []domain.Cart{{ID: cartID, UserID: owner.UserID, Guest: owner.IsGuest()}}
+	err = fillCarts(ctx, tx, carts)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	err = applyCoupon(ctx, tx, carts[0], code)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	return commitCart(ctx, tx, cartID)
+}
+
+// RemoveCoupon removes a coupon code from owner's cart.
+func (c cartStore) RemoveCoupon(ctx context.Context, owner domain.CartOwner, code string) (domain.Cart, error) {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return domain.Cart{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	cartID, err := findCart(ctx, tx, owner)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	query := `
+	DELETE FROM cart_coupons
+	WHERE cart_id = @cart_id
+	AND coupon_id IN (SELECT id FROM coupons WHERE code = @code AND code <> '')
+	`
+
+	args := pgx.NamedArgs{"cart_id": cartID, "code": domain.NormalizeCouponCode(code)}
+	result, err := tx.Exec(ctx, query, args)
+	if err != nil {
+		return domain.Cart{}, fmt.Errorf("failed to delete from cart coupons: %v", err)
+	}
+
+	if result.RowsAffected() != 1 {
+		return domain.Cart{}, domain.ErrNoCouponsFound
+	}
+
+	return commitCart(ctx, tx, cartID)
+}
+
+// SetDestination sets location owner's cart is delivered to, the cart is
+// created when owner has none.
+func (c cartStore) SetDestination(ctx context.Context, owner domain.CartOwner, destination domain.Location) (domain.Cart, error) {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return domain.Cart{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	cartID, err := ensureCart(ctx, tx, owner)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	err = setCartDestination(ctx, tx, cartID, destination)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	return commitCart(ctx, tx, cartID)
+}
+
+// SetShippingMethod selects shipping method of owner's cart, it is not
+// checked to be available for the cart.
+func (c cartStore) SetShippingMethod(ctx context.Context, owner domain.CartOwner, methodID int) (domain.Cart, error) {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return domain.Cart{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	cartID, err := ensureCart(ctx, tx, owner)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	err = setCartShippingMethod(ctx, tx, cartID, methodID)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	return commitCart(ctx, tx, cartID)
+}
+
+// setCartDestination sets location cart is delivered to.
+func setCartDestination(ctx context.Context, q querier, cartID int, destination domain.Location) error {
 	query := `
 	UPDATE carts
-	SET product_id = COALESCE(@product_id, product_id),
-		quantity   = COALESCE(@quantity, quantity)
+	SET country = @country, region = @region, postal_code = @postal_code
 	WHERE id = @id
-	RETURNING *
 	`
 
+	destination = destination.Normalize()
 	args := pgx.NamedArgs{
-		"product_id": &input.ProductID,
-		"quantity":   &input.Quantity,
-		"id":         &ID,
+		"id":          cartID,
+		"country":     destination.Country,
+		"region":      destination.Region,
+		"postal_code": destination.PostalCode,
 	}
 
-	row, err := c.db.Query(ctx, query, args)
+	_, err := q.Exec(ctx, query, args)
 	if err != nil {
-		return domain.Cart{}, err
+		return fmt.Errorf("failed to update cart destination: %v", err)
+	}
+
+	return nil
+}
+
+// setCartShippingMethod selects shipping method of cart.
+func setCartShippingMethod(ctx context.Context, q querier, cartID int, methodID int) error {
+	_, err := q.Exec(ctx, `UPDATE carts SET shipping_method_id = @method_id WHERE id = @id`,
+		pgx.NamedArgs{"id": cartID, "method_id": methodID})
+	if err != nil {
+		pgErr := pgError(err)
+		if pgErr.Code == pgerrcode.ForeignKeyViolation && pgErr.ConstraintName == "carts_shipping_method_id_fkey" {
+			return domain.ErrNoShippingMethodsFound
+		}
+		return fmt.Errorf("failed to update cart shipping method: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteStaleGuests deletes guest carts not updated since before, their
+// items and reservations are deleted by cascade.
+func (c cartStore) DeleteStaleGuests(ctx context.Context, before time.Time) (int, error) {
+	query := `
+	DELETE FROM carts
+	WHERE user_id IS NULL AND updated_at < @before
+	`
+
+	result, err := c.db.Exec(ctx, query, pgx.NamedArgs{"before": before})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete stale guest carts: %v", err)
+	}
+
+	return int(result.RowsAffected()), nil
+}
+
+// ReleaseExpiredReservations deletes expired stock reservations, products
+// available again by them notify their subscribers.
+func (c cartStore) ReleaseExpiredReservations(ctx context.Context) (int, error) {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	released, count, err := releaseExpiredReservations(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	if count == 0 {
+		return 0, nil
+	}
+
+	ids := make([]int, 0, len(released))
+	for id := range released {
+		ids = append(ids, id)
+	}
+
+	after, err := stockLevels(ctx, tx, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	// expired reservations stopped holding stock before they are deleted,
+	// products are notified as if stock was held until now.
+	before := make(map[int]stockLevel, len(after))
+	for id, level := range after {
+		level.available -= released[id]
+		before[id] = level
+	}
+
+	err = queueStockNotifications(ctx, tx, before, after)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return count, nil
+}
+
+// findCart returns id of owner's cart.
+func findCart(ctx context.Context, q querier, owner domain.CartOwner) (int, error) {
+	query := `SELECT id FROM carts WHERE user_id = @user_id`
+	args := pgx.NamedArgs{"user_id": owner.UserID}
+	if owner.IsGuest() {
+		query = `SELECT id FROM carts WHERE token = @token`
+		args = pgx.NamedArgs{"token": owner.Token}
+	}
+
+	var cartID int
+	err := q.QueryRow(ctx, query+` FOR UPDATE`, args).Scan(&cartID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, domain.ErrNoCartsFound
+		}
+		return 0, err
+	}
+
+	return cartID, nil
+}
+
+// ensureCart returns id of owner's cart and creates one when missing.
+func ensureCart(ctx context.Context, q querier, owner domain.CartOwner) (int, error) {
+	query := `
+	INSERT INTO carts(user_id)
+	VALUES(@user_id)
+	ON CONFLICT(user_id) DO UPDATE SET user_id = EXCLUDED.user_id
+	RETURNING id
+	`
+	args := pgx.NamedArgs{"user_id": owner.UserID}
+
+	if owner.IsGuest() {
+		query = `
+		INSERT INTO carts(token)
+		VALUES(@token)
+		ON CONFLICT(token) DO UPDATE SET token = EXCLUDED.token
+		RETURNING id
+		`
+		args = pgx.NamedArgs{"token": owner.Token}
+	}
+
+	var cartID int
+	err := q.QueryRow(ctx, query, args).Scan(&cartID)
+	if err != nil {
+		pgErr := pgError(err)
+		if pgErr.Code == pgerrcode.ForeignKeyViolation {
+			if pgErr.ConstraintName == "carts_user_id_fkey" {
+				return 0, domain.ErrCartInvalidUserID
+			}
+		}
+		return 0, err
+	}
+
+	return cartID, nil
+}
+
+// addCartItem inserts an item into cart, merging it into the existing
+// line of the same product, and reserves stock of the line.
+func addCartItem(ctx context.Context, q querier, cartID int, item domain.CartItem) error {
+	query := `
+	INSERT INTO cart_items(cart_id, product_id, quantity)
+	VALUES(@cart_id, @product_id, @quantity)
+	ON CONFLICT(cart_id, product_id) DO UPDATE
+	SET quantity   = cart_items.quantity + EXCLUDED.quantity,
+		updated_at = NOW()
+	RETURNING id, quantity
+	`
+
+	args := pgx.NamedArgs{
+		"cart_id":    cartID,
+		"product_id": item.ProductID,
+		"quantity":   item.Quantity,
 	}
 
-	cart, err := pgx.CollectOneRow(row, pgx.RowToStructByName[domain.Cart])
+	err := q.QueryRow(ctx, query, args).Scan(&item.ID, &item.Quantity)
 	if err != nil {
 		pgErr := pgError(err)
 		if pgErr.Code == pgerrcode.ForeignKeyViolation {
-			if pgErr.ConstraintName == "carts_product_id_fkey" {
-				return domain.Cart{}, domain.ErrCartInvalidProductID
+			if pgErr.ConstraintName == "cart_items_product_id_fkey" {
+				return domain.ErrCartInvalidProductID
 			}
 		}
+		return fmt.Errorf("failed to insert cart item: %v", err)
+	}
+
+	return reserveStock(ctx, q, item)
+}
+
+// setCartItem sets quantity of a product line in cart and returns its id,
+// the line is removed when quantity is not positive.
+func setCartItem(ctx context.Context, q querier, cartID int, item domain.CartItem) (int, error) {
+	query := `
+	INSERT INTO cart_items(cart_id, product_id, quantity, updated_at)
+	VALUES(@cart_id, @product_id, @quantity, @updated_at)
+	ON CONFLICT(cart_id, product_id) DO UPDATE
+	SET quantity   = EXCLUDED.quantity,
+		updated_at = EXCLUDED.updated_at
+	RETURNING id
+	`
+
+	if item.Quantity <= 0 {
+		query = `
+		DELETE FROM cart_items
+		WHERE cart_id = @cart_id AND product_id = @product_id
+		RETURNING id
+		`
+	}
+
+	args := pgx.NamedArgs{
+		"cart_id":    cartID,
+		"product_id": item.ProductID,
+		"quantity":   item.Quantity,
+		"updated_at": item.UpdatedAt,
+	}
 
+	var itemID int
+	err := q.QueryRow(ctx, query, args).Scan(&itemID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("failed to set cart item: %v", err)
+	}
+
+	return itemID, nil
+}
+
+// lockCartItem selects an item of owner's cart for update.
+func lockCartItem(ctx context.Context, q querier, owner domain.CartOwner, itemID int) (domain.CartItem, error) {
+	query := `
+	SELECT i.* FROM cart_items i
+	INNER JOIN carts c ON c.id = i.cart_id
+	WHERE i.id = @id AND (c.user_id = @user_id OR c.token = @token)
+	FOR UPDATE OF i
+	`
+
+	args := pgx.NamedArgs{"id": itemID, "user_id": owner.UserID, "token": owner.Token}
+	if owner.IsGuest() {
+		args["user_id"] = nil
+	}
+
+	rows, err := q.Query(ctx, query, args)
+	if err != nil {
+		return domain.CartItem{}, err
+	}
+
+	item, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.CartItem])
+	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return domain.Cart{}, domain.ErrNoCartsFound
+			return domain.CartItem{}, domain.ErrNoCartItemsFound
 		}
+		return domain.CartItem{}, fmt.Errorf("failed to scan row of cart item: %v", err)
+	}
+
+	return item, nil
+}
 
-		return domain.Cart{}, fmt.Errorf("failed to scan rows of cart: %v", err)
+// clearCart deletes items of a cart, their reservations are released by
+// cascade.
+func clearCart(ctx context.Context, q querier, cartID int) error {
+	_, err := q.Exec(ctx, `DELETE FROM cart_items WHERE cart_id = @cart_id`,
+		pgx.NamedArgs{"cart_id": cartID})
+	if err != nil {
+		return fmt.Errorf("failed to delete from cart items: %v", err)
 	}
 
-	return cart, nil
+	return nil
 }
 
-// Delete deletes a cart by id from database.
-func (c cartStore) Delete(ctx context.Context, ID int) error {
+// commitCart extends reservations of cart, marks it as updated, loads it
+// with its items and commits transaction.
+func commitCart(ctx context.Context, tx pgx.Tx, cartID int) (domain.Cart, error) {
+	err := extendReservations(ctx, tx, cartID)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
 	query := `
-	DELETE FROM carts
+	UPDATE carts SET updated_at = NOW()
 	WHERE id = @id
+	RETURNING COALESCE(user_id, 0), user_id IS NULL, created_at, updated_at
 	`
 
-	args := pgx.NamedArgs{
-		"id": ID,
+	carts := []domain.Cart{{ID: cartID}}
+	c := &carts[0]
+	err = tx.QueryRow(ctx, query, pgx.NamedArgs{"id": cartID}).Scan(&c.UserID, &c.Guest, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return domain.Cart{}, err
 	}
 
-	result, err := c.db.Exec(ctx, query, args)
+	err = fillCarts(ctx, tx, carts)
 	if err != nil {
-		return fmt.Errorf("failed to delete from users: %v", err)
+		return domain.Cart{}, err
 	}
 
-	if rows := result.RowsAffected(); rows != 1 {
-		return domain.ErrNoCartsFound
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.Cart{}, fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return carts[0], nil
+}
+
+// fillCarts loads items of carts and computes their totals from current
+// product prices, coupons of carts and taxes of their destinations.
+func fillCarts(ctx context.Context, q querier, carts []domain.Cart) error {
+	ids := make([]int, 0, len(carts))
+	for _, c := range carts {
+		ids = append(ids, c.ID)
+	}
+
+	items, err := listCartItems(ctx, q, ids)
+	if err != nil {
+		return err
+	}
+
+	itemIDs := make([]int, 0, len(items))
+	for _, item := range items {
+		itemIDs = append(itemIDs, item.ID)
+	}
+
+	reserved, err := reservedItems(ctx, q, itemIDs)
+	if err != nil {
+		return err
+	}
+
+	productIDs := make([]int, 0, len(items))
+	cartItems := make(map[int][]domain.CartItem)
+	for _, item := range items {
+		item.Reserved = reserved[item.ID]
+		productIDs = append(productIDs, item.ProductID)
+		cartItems[item.CartID] = append(cartItems[item.CartID], item)
+	}
+
+	products, err := getProducts(ctx, q, productIDs)
+	if err != nil {
+		return err
+	}
+
+	settings, err := cartSettings(ctx, q, ids)
+	if err != nil {
+		return err
+	}
+
+	zones, err := listTaxZones(ctx, q, 0)
+	if err != nil {
+		return err
+	}
+	calculator := domain.NewTaxTable(zones)
+
+	for i := range carts {
+		carts[i].Items = cartItems[carts[i].ID]
+		if carts[i].Items == nil {
+			carts[i].Items = []domain.CartItem{}
+		}
+		carts[i].ApplyProducts(products)
+
+		codes, coupons, err := cartCoupons(ctx, q, carts[i].ID, carts[i].UserID)
+		if err != nil {
+			return err
+		}
+
+		carts[i].Coupons = codes
+		carts[i].ApplyCoupons(coupons, products, time.Now())
+
+		carts[i].Destination = settings[carts[i].ID].destination
+		carts[i].ShippingMethodID = settings[carts[i].ID].shippingMethodID
+		taxes, err := calculator.Calculate(ctx, carts[i].Destination, carts[i].TaxableLines(products))
+		if err != nil {
+			return err
+		}
+		carts[i].ApplyTax(taxes)
 	}
 
 	return nil
 }
+
+// listCartItems lists items of the given carts.
+func listCartItems(ctx context.Context, q querier, cartIDs []int) ([]domain.CartItem, error) {
+	query := `
+	SELECT * FROM cart_items
+	WHERE cart_id = ANY(@ids)
+	ORDER BY id
+	`
+
+	rows, err := q.Query(ctx, query, pgx.NamedArgs{"ids": cartIDs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query list cart items: %v", err)
+	}
+
+	items, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.CartItem])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of cart items: %v", err)
+	}
+
+	return items, nil
+}
+
+// cartSetting represents where a cart is shipped to and by which method.
+type cartSetting struct {
+	destination      domain.Location
+	shippingMethodID *int
+}
+
+// cartSettings returns destinations and shipping methods of the given
+// carts.
+func cartSettings(ctx context.Context, q querier, cartIDs []int) (map[int]cartSetting, error) {
+	query := `
+	SELECT id, country, region, postal_code, shipping_method_id FROM carts
+	WHERE id = ANY(@ids)
+	`
+
+	rows, err := q.Query(ctx, query, pgx.NamedArgs{"ids": cartIDs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cart settings: %v", err)
+	}
+
+	settings := make(map[int]cartSetting)
+	var cartID int
+	var l domain.Location
+	var methodID *int
+	_, err = pgx.ForEachRow(rows, []any{&cartID, &l.Country, &l.Region, &l.PostalCode, &methodID}, func() error {
+		settings[cartID] = cartSetting{destination: l, shippingMethodID: methodID}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of cart settings: %v", err)
+	}
+
+	return settings, nil
+}
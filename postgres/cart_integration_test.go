@@ -28,7 +28,7 @@ func newCartTestDB(t *testing.T, dbName string) *pgxpool.Pool {
 		t.Fatalf("category Create: %v", err)
 	}
 
-	product := domain.Product{Name: "product", CategoryID: 1}
+	product := domain.Product{Name: "product", CategoryID: 1, Price: 1000, Quantity: 10}
 	err = postgres.NewProductStore(db).Create(ctx, &product)
 	if err != nil {
 		t.Fatalf("product Create: %v", err)
@@ -37,36 +37,86 @@ func newCartTestDB(t *testing.T, dbName string) *pgxpool.Pool {
 	return db
 }
 
-func TestCartService_Create(t *testing.T) {
-	db := newCartTestDB(t, "carts_create")
+func TestCartService_AddItem(t *testing.T) {
+	db := newCartTestDB(t, "carts_add_item")
 	defer db.Close()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	want := domain.Cart{ProductID: 1, UserID: 1, Quantity: 1}
-	err := postgres.NewCartStore(db).Create(ctx, &want)
+	_, err := postgres.NewCartStore(db).AddItem(ctx, domain.CartOwner{UserID: 1}, domain.CartItem{ProductID: 1, Quantity: 1})
 	if err != nil {
-		t.Fatalf("Create: %v", err)
+		t.Fatalf("AddItem: %v", err)
 	}
 
-	got, err := postgres.NewCartStore(db).GetByID(ctx, 1)
+	// Adding the same product again merges into the existing line.
+	got, err := postgres.NewCartStore(db).AddItem(ctx, domain.CartOwner{UserID: 1}, domain.CartItem{ProductID: 1, Quantity: 2})
 	if err != nil {
-		t.Fatalf("GetByID: %v", err)
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	want, err := postgres.NewCartStore(db).GetByOwner(ctx, domain.CartOwner{UserID: 1})
+	if err != nil {
+		t.Fatalf("GetByOwner: %v", err)
 	}
 
 	if !reflect.DeepEqual(want, got) {
 		t.Errorf("mismatch\n got: %#v\nwant: %#v", got, want)
 	}
 
-	err = postgres.NewCartStore(db).Create(ctx, &domain.Cart{UserID: 99})
+	if len(got.Items) != 1 {
+		t.Fatalf("expected length of %d, got: %d", 1, len(got.Items))
+	}
+
+	if got.Items[0].Quantity != 3 {
+		t.Errorf("expected quantity of %d, got: %d", 3, got.Items[0].Quantity)
+	}
+
+	_, err = postgres.NewCartStore(db).AddItem(ctx, domain.CartOwner{UserID: 99}, domain.CartItem{ProductID: 1, Quantity: 1})
 	if err != domain.ErrCartInvalidUserID {
-		t.Errorf("expected %q from Create, got: %q", domain.ErrCartInvalidUserID, err)
+		t.Errorf("expected %q from AddItem, got: %q", domain.ErrCartInvalidUserID, err)
 	}
 
-	err = postgres.NewCartStore(db).Create(ctx, &domain.Cart{ProductID: 99, UserID: 1})
+	_, err = postgres.NewCartStore(db).AddItem(ctx, domain.CartOwner{UserID: 1}, domain.CartItem{ProductID: 99, Quantity: 1})
 	if err != domain.ErrCartInvalidProductID {
-		t.Errorf("expected %q from Create, got: %q", domain.ErrCartInvalidProductID, err)
+		t.Errorf("expected %q from AddItem, got: %q", domain.ErrCartInvalidProductID, err)
+	}
+}
+
+func TestCartService_Totals(t *testing.T) {
+	db := newCartTestDB(t, "carts_totals")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, p := range []domain.Product{
+		{Name: "product1", CategoryID: 1, Price: 10, Quantity: 5},
+		{Name: "product2", CategoryID: 1, Price: 25, Quantity: 5},
+	} {
+		err := postgres.NewProductStore(db).Create(ctx, &p)
+		if err != nil {
+			t.Fatalf("product Create: %v", err)
+		}
+
+		_, err = postgres.NewCartStore(db).AddItem(ctx, domain.CartOwner{UserID: 1}, domain.CartItem{ProductID: p.ID, Quantity: 2})
+		if err != nil {
+			t.Fatalf("AddItem: %v", err)
+		}
+	}
+
+	cart, err := postgres.NewCartStore(db).GetByOwner(ctx, domain.CartOwner{UserID: 1})
+	if err != nil {
+		t.Fatalf("GetByOwner: %v", err)
+	}
+
+	if cart.Items[1].UnitPrice != 25 || cart.Items[1].LineTotal != 50 {
+		t.Errorf("expected unit price %d and line total %d, got: %d and %d",
+			25, 50, cart.Items[1].UnitPrice, cart.Items[1].LineTotal)
+	}
+
+	if cart.Totals.Subtotal != 70 || cart.Totals.GrandTotal != 70 {
+		t.Errorf("expected subtotal and grand total of %d, got: %#v", 70, cart.Totals)
 	}
 }
 
@@ -85,69 +135,83 @@ func TestCartService_List(t *testing.T) {
 
 	const n = 3
 	for i := 0; i < n; i++ {
-		cart := domain.Cart{ProductID: 1, UserID: 1, Quantity: 1}
-		err = postgres.NewCartStore(db).Create(ctx, &cart)
+		_, err = postgres.NewCartStore(db).AddItem(ctx, domain.CartOwner{UserID: 1}, domain.CartItem{ProductID: 1, Quantity: 1})
 		if err != nil {
-			t.Fatalf("Create: %v", err)
+			t.Fatalf("AddItem: %v", err)
 		}
 	}
 
 	carts, err := postgres.NewCartStore(db).List(ctx, domain.CartFilter{})
 	if err != nil {
-		t.Errorf("List: %v", err)
+		t.Fatalf("List: %v", err)
 	}
 
-	if len(carts) != n {
-		t.Errorf("expected length of %d, got: %v", n, len(carts))
+	if len(carts) != 1 {
+		t.Errorf("expected length of %d, got: %v", 1, len(carts))
 	}
 
-	if carts[0].Quantity != 1 {
-		t.Errorf("expected quantity of %d, got: %d", 1, carts[0].Quantity)
+	if carts[0].Items[0].Quantity != n {
+		t.Errorf("expected quantity of %d, got: %d", n, carts[0].Items[0].Quantity)
 	}
 }
 
-func TestCartService_Update(t *testing.T) {
-	db := newCartTestDB(t, "carts_update")
+func TestCartService_UpdateItem(t *testing.T) {
+	db := newCartTestDB(t, "carts_update_item")
 	defer db.Close()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	want := domain.Cart{ProductID: 1, UserID: 1, Quantity: 1}
-	err := postgres.NewCartStore(db).Create(ctx, &want)
+	cart, err := postgres.NewCartStore(db).AddItem(ctx, domain.CartOwner{UserID: 1}, domain.CartItem{ProductID: 1, Quantity: 1})
 	if err != nil {
-		t.Fatalf("Create: %v", err)
+		t.Fatalf("AddItem: %v", err)
 	}
 
-	newQuantity := 10
-	got, err := postgres.NewCartStore(db).Update(ctx, 1, domain.CartUpdate{
-		Quantity: &newQuantity,
-	})
+	got, err := postgres.NewCartStore(db).UpdateItem(ctx, domain.CartOwner{UserID: 1}, cart.Items[0].ID, 10)
 	if err != nil {
-		t.Errorf("Update: %v", err)
+		t.Fatalf("UpdateItem: %v", err)
+	}
+
+	if got.Items[0].Quantity != 10 {
+		t.Errorf("expected quantity of %d, got: %d", 10, got.Items[0].Quantity)
+	}
+
+	_, err = postgres.NewCartStore(db).UpdateItem(ctx, domain.CartOwner{UserID: 1}, 99, 1)
+	if err != domain.ErrNoCartItemsFound {
+		t.Errorf("expected %q from UpdateItem, got %q", domain.ErrNoCartItemsFound, err)
 	}
 
-	want, err = postgres.NewCartStore(db).GetByID(ctx, 1)
+	// Items of other users' carts are not found.
+	_, err = postgres.NewCartStore(db).UpdateItem(ctx, domain.CartOwner{UserID: 2}, cart.Items[0].ID, 1)
+	if err != domain.ErrNoCartItemsFound {
+		t.Errorf("expected %q from UpdateItem, got %q", domain.ErrNoCartItemsFound, err)
+	}
+}
+
+func TestCartService_RemoveItem(t *testing.T) {
+	db := newCartTestDB(t, "carts_remove_item")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cart, err := postgres.NewCartStore(db).AddItem(ctx, domain.CartOwner{UserID: 1}, domain.CartItem{ProductID: 1, Quantity: 1})
 	if err != nil {
-		t.Fatalf("GetByID: %v", err)
+		t.Fatalf("AddItem: %v", err)
 	}
 
-	if got.Quantity != want.Quantity {
-		t.Errorf("expected quantity of %d, got: %d", want.Quantity, got.Quantity)
+	got, err := postgres.NewCartStore(db).RemoveItem(ctx, domain.CartOwner{UserID: 1}, cart.Items[0].ID)
+	if err != nil {
+		t.Fatalf("RemoveItem: %v", err)
 	}
 
-	invalidProductID := 10
-	_, err = postgres.NewCartStore(db).Update(ctx, 1, domain.CartUpdate{
-		ProductID: &invalidProductID,
-	})
-	if err != domain.ErrCartInvalidProductID {
-		t.Errorf("expected %q from Update, got %q", domain.ErrCartInvalidProductID, err)
+	if len(got.Items) != 0 {
+		t.Errorf("expected length of %d, got: %d", 0, len(got.Items))
 	}
 
-	invalidID := 10
-	_, err = postgres.NewCartStore(db).Update(ctx, invalidID, domain.CartUpdate{})
-	if err != domain.ErrNoCartsFound {
-		t.Errorf("expected %q from Update, got %q", domain.ErrNoCartsFound, err)
+	_, err = postgres.NewCartStore(db).RemoveItem(ctx, domain.CartOwner{UserID: 1}, cart.Items[0].ID)
+	if err != domain.ErrNoCartItemsFound {
+		t.Errorf("expected %q from RemoveItem, got %q", domain.ErrNoCartItemsFound, err)
 	}
 }
 
@@ -158,24 +222,290 @@ func TestCartService_Delete(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	cart := domain.Cart{ProductID: 1, UserID: 1, Quantity: 1}
-	err := postgres.NewCartStore(db).Create(ctx, &cart)
+	cart, err := postgres.NewCartStore(db).AddItem(ctx, domain.CartOwner{UserID: 1}, domain.CartItem{ProductID: 1, Quantity: 1})
 	if err != nil {
-		t.Fatalf("Create: %v", err)
+		t.Fatalf("AddItem: %v", err)
 	}
 
-	err = postgres.NewCartStore(db).Delete(ctx, 1)
+	err = postgres.NewCartStore(db).Delete(ctx, cart.ID)
 	if err != nil {
 		t.Fatalf("Delete: %v", err)
 	}
 
-	_, err = postgres.NewCartStore(db).GetByID(ctx, 1)
+	_, err = postgres.NewCartStore(db).GetByID(ctx, cart.ID)
 	if err != domain.ErrNoCartsFound {
 		t.Fatalf("expected %q from List, got %q", domain.ErrNoCartsFound, err)
 	}
 
-	err = postgres.NewCartStore(db).Delete(ctx, 1)
+	err = postgres.NewCartStore(db).Delete(ctx, cart.ID)
 	if err != domain.ErrNoCartsFound {
 		t.Fatalf("expected %q from List, got %q", domain.ErrNoCartsFound, err)
 	}
 }
+
+func TestCartService_AddBundle(t *testing.T) {
+	db := newCartTestDB(t, "carts_add_bundle")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, p := range []domain.Product{
+		{Name: "component1", CategoryID: 1, Price: 10, Quantity: 5},
+		{Name: "component2", CategoryID: 1, Price: 20, Quantity: 3},
+	} {
+		err := postgres.NewProductStore(db).Create(ctx, &p)
+		if err != nil {
+			t.Fatalf("product Create: %v", err)
+		}
+	}
+
+	bundle := domain.Product{
+		Name:           "bundle",
+		CategoryID:     1,
+		Type:           domain.ProductTypeBundle,
+		BundlePricing:  domain.BundlePricingDerived,
+		BundleDiscount: 10,
+		Components: []domain.BundleComponent{
+			{ProductID: 2, Quantity: 2},
+			{ProductID: 3, Quantity: 1},
+		},
+	}
+	err := postgres.NewProductStore(db).Create(ctx, &bundle)
+	if err != nil {
+		t.Fatalf("bundle Create: %v", err)
+	}
+
+	if bundle.Price != 36 {
+		t.Errorf("expected price of %d, got: %d", 36, bundle.Price)
+	}
+
+	if bundle.Quantity != 2 {
+		t.Errorf("expected quantity of %d, got: %d", 2, bundle.Quantity)
+	}
+
+	cart, err := postgres.NewCartStore(db).AddItem(ctx, domain.CartOwner{UserID: 1}, domain.CartItem{ProductID: bundle.ID, Quantity: 2})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	component, err := postgres.NewProductStore(db).GetByID(ctx, 2)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+
+	if component.Available != 1 {
+		t.Errorf("expected available quantity of %d, got: %d", 1, component.Available)
+	}
+
+	_, err = postgres.NewCartStore(db).AddItem(ctx, domain.CartOwner{UserID: 1}, domain.CartItem{ProductID: bundle.ID, Quantity: 1})
+	if err != domain.ErrInsufficientStock {
+		t.Errorf("expected %q from AddItem, got: %q", domain.ErrInsufficientStock, err)
+	}
+
+	err = postgres.NewCartStore(db).Delete(ctx, cart.ID)
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	component, err = postgres.NewProductStore(db).GetByID(ctx, 2)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+
+	if component.Available != 5 {
+		t.Errorf("expected released quantity of %d, got: %d", 5, component.Available)
+	}
+}
+
+func TestCartService_Merge(t *testing.T) {
+	db := newCartTestDB(t, "carts_merge")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	product := domain.Product{Name: "product2", CategoryID: 1, Price: 10, Quantity: 4}
+	err := postgres.NewProductStore(db).Create(ctx, &product)
+	if err != nil {
+		t.Fatalf("product Create: %v", err)
+	}
+
+	user := domain.CartOwner{UserID: 1}
+	guest := domain.CartOwner{Token: domain.HashToken("guest")}
+
+	_, err = postgres.NewCartStore(db).AddItem(ctx, user, domain.CartItem{ProductID: product.ID, Quantity: 3})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	for _, item := range []domain.CartItem{
+		{ProductID: product.ID, Quantity: 2},
+		{ProductID: 1, Quantity: 1},
+	} {
+		_, err = postgres.NewCartStore(db).AddItem(ctx, guest, item)
+		if err != nil {
+			t.Fatalf("AddItem: %v", err)
+		}
+	}
+
+	cart, err := postgres.NewCartStore(db).GetByOwner(ctx, guest)
+	if err != nil {
+		t.Fatalf("GetByOwner: %v", err)
+	}
+
+	if !cart.Guest || len(cart.Items) != 2 {
+		t.Errorf("expected guest cart of %d items, got: %#v", 2, cart)
+	}
+
+	got, err := postgres.NewCartStore(db).Merge(ctx, 1, guest.Token, domain.CartMergeCap)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+
+	if len(got.Items) != 2 {
+		t.Fatalf("expected length of %d, got: %d", 2, len(got.Items))
+	}
+
+	if got.Items[0].ProductID != product.ID || got.Items[0].Quantity != 4 {
+		t.Errorf("expected quantity capped at %d, got: %#v", 4, got.Items[0])
+	}
+
+	if got.Items[1].ProductID != 1 || !got.Items[1].Reserved {
+		t.Errorf("expected reserved line of guest, got: %#v", got.Items[1])
+	}
+
+	_, err = postgres.NewCartStore(db).GetByOwner(ctx, guest)
+	if err != domain.ErrNoCartsFound {
+		t.Errorf("expected %q from GetByOwner, got %q", domain.ErrNoCartsFound, err)
+	}
+
+	_, err = postgres.NewCartStore(db).Merge(ctx, 1, guest.Token, domain.CartMergeSum)
+	if err != domain.ErrNoCartsFound {
+		t.Errorf("expected %q from Merge, got %q", domain.ErrNoCartsFound, err)
+	}
+}
+
+func TestCartService_MergeBackorder(t *testing.T) {
+	db := newCartTestDB(t, "carts_merge_backorder")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	product := domain.ProductCreate{
+		SKU:             "SKU-2",
+		Name:            "backorderable",
+		Description:     "description",
+		CategoryID:      1,
+		Price:           10,
+		Quantity:        1,
+		InventoryPolicy: domain.InventoryPolicyBackorder,
+		BackorderLimit:  2,
+	}.CreateModel()
+	err := postgres.NewProductStore(db).Create(ctx, &product)
+	if err != nil {
+		t.Fatalf("product Create: %v", err)
+	}
+
+	user := domain.CartOwner{UserID: 1}
+	guest := domain.CartOwner{Token: domain.HashToken("guest")}
+
+	_, err = postgres.NewCartStore(db).AddItem(ctx, user, domain.CartItem{ProductID: product.ID, Quantity: 2})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	_, err = postgres.NewCartStore(db).AddItem(ctx, guest, domain.CartItem{ProductID: 1, Quantity: 1})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	// backordered line of user is kept whole.
+	got, err := postgres.NewCartStore(db).Merge(ctx, 1, guest.Token, domain.CartMergeCap)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+
+	if len(got.Items) != 2 || got.Items[0].ProductID != product.ID ||
+		got.Items[0].Quantity != 2 || !got.Items[0].Reserved {
+		t.Fatalf("expected reserved line of %d backordered, got: %#v", 2, got.Items)
+	}
+
+	guest = domain.CartOwner{Token: domain.HashToken("guest2")}
+	_, err = postgres.NewCartStore(db).AddItem(ctx, guest, domain.CartItem{ProductID: 1, Quantity: 10})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	// lines falling short of stock are merged without reservations.
+	got, err = postgres.NewCartStore(db).Merge(ctx, 1, guest.Token, domain.CartMergeSum)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+
+	if !got.Items[0].Reserved {
+		t.Errorf("expected reserved line, got: %#v", got.Items[0])
+	}
+
+	if got.Items[1].Quantity != 11 || got.Items[1].Reserved {
+		t.Errorf("expected unreserved line of %d, got: %#v", 11, got.Items[1])
+	}
+}
+
+func TestCartService_Reservations(t *testing.T) {
+	db := newCartTestDB(t, "carts_reservations")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	user := domain.CartOwner{UserID: 1}
+	guest := domain.CartOwner{Token: domain.HashToken("guest")}
+
+	_, err := postgres.NewCartStore(db).AddItem(ctx, user, domain.CartItem{ProductID: 1, Quantity: 11})
+	if err != domain.ErrInsufficientStock {
+		t.Errorf("expected %q from AddItem, got: %q", domain.ErrInsufficientStock, err)
+	}
+
+	cart, err := postgres.NewCartStore(db).AddItem(ctx, user, domain.CartItem{ProductID: 1, Quantity: 7})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	if !cart.Items[0].Reserved || cart.Items[0].StockStatus != domain.StockAvailable {
+		t.Errorf("expected reserved and available line, got: %#v", cart.Items[0])
+	}
+
+	product, err := postgres.NewProductStore(db).GetByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+
+	if product.Available != 3 || product.StockStatus != domain.StockLow {
+		t.Errorf("expected %d available in %q, got: %d in %q",
+			3, domain.StockLow, product.Available, product.StockStatus)
+	}
+
+	// stock reserved by user is not available to others.
+	_, err = postgres.NewCartStore(db).AddItem(ctx, guest, domain.CartItem{ProductID: 1, Quantity: 4})
+	if err != domain.ErrInsufficientStock {
+		t.Errorf("expected %q from AddItem, got: %q", domain.ErrInsufficientStock, err)
+	}
+
+	// growing own line only needs stock beyond its reservation.
+	_, err = postgres.NewCartStore(db).UpdateItem(ctx, user, cart.Items[0].ID, 10)
+	if err != nil {
+		t.Fatalf("UpdateItem: %v", err)
+	}
+
+	_, err = postgres.NewCartStore(db).RemoveItem(ctx, user, cart.Items[0].ID)
+	if err != nil {
+		t.Fatalf("RemoveItem: %v", err)
+	}
+
+	_, err = postgres.NewCartStore(db).AddItem(ctx, guest, domain.CartItem{ProductID: 1, Quantity: 10})
+	if err != nil {
+		t.Errorf("AddItem: %v", err)
+	}
+}
@@ -0,0 +1,346 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// couponColumns selects coupons along with their usage, in total and by
+// user of @user_id.
+const couponColumns = `
+	c.*,
+	(SELECT COUNT(*) FROM coupon_redemptions r WHERE r.coupon_id = c.id) AS used,
+	(SELECT COUNT(*) FROM coupon_redemptions r
+		WHERE r.coupon_id = c.id AND r.user_id = @user_id) AS used_by_user
+`
+
+// couponStore represents coupons database.
+type couponStore struct {
+	db *pgxpool.Pool
+}
+
+// NewCouponStore returns a new instance of CouponStore.
+func NewCouponStore(db *pgxpool.Pool) couponStore {
+	return couponStore{db: db}
+}
+
+// Create creates a new coupon in database.
+func (c couponStore) Create(ctx context.Context, coupon *domain.Coupon) error {
+	query := `
+	INSERT INTO coupons(code, name, type, value, min_subtotal, product_ids, category_ids,
+		buy_quantity, get_quantity, usage_limit, user_usage_limit, stackable, priority,
+		active, starts_at, ends_at)
+	VALUES(@code, @name, @type, @value, @min_subtotal, @product_ids, @category_ids,
+		@buy_quantity, @get_quantity, @usage_limit, @user_usage_limit, @stackable, @priority,
+		@active, @starts_at, @ends_at)
+	RETURNING id, created_at, updated_at
+	`
+
+	err := c.db.QueryRow(ctx, query, couponArgs(*coupon)).Scan(&coupon.ID, &coupon.CreatedAt, &coupon.UpdatedAt)
+	if err != nil {
+		pgErr := pgError(err)
+		if pgErr.Code == pgerrcode.UniqueViolation && pgErr.ConstraintName == "coupons_code_key" {
+			return domain.ErrDuplicatedCoupon
+		}
+		return fmt.Errorf("failed to insert coupon: %v", err)
+	}
+
+	return nil
+}
+
+// GetByID get coupon by id from database.
+func (c couponStore) GetByID(ctx context.Context, ID int) (domain.Coupon, error) {
+	coupons, err := c.List(ctx, domain.CouponFilter{ID: ID})
+	if err != nil {
+		return domain.Coupon{}, err
+	}
+
+	return coupons[0], nil
+}
+
+// List lists coupons with optional filter.
+func (c couponStore) List(ctx context.Context, filter domain.CouponFilter) ([]domain.Coupon, error) {
+	query := `
+	SELECT ` + couponColumns + ` FROM coupons c
+	WHERE (@code = '' OR code = @code)
+	` + FormatAndInt("id", filter.ID) + `
+	` + FormatSort(filter.Sort) + `
+	` + FormatLimitOffset(filter.Limit, filter.Offset) + `
+	`
+
+	args := pgx.NamedArgs{
+		"code":    domain.NormalizeCouponCode(filter.Code),
+		"user_id": 0,
+	}
+
+	rows, err := c.db.Query(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query list coupons: %v", err)
+	}
+
+	coupons, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.Coupon])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of coupons: %v", err)
+	}
+
+	if len(coupons) == 0 {
+		return nil, domain.ErrNoCouponsFound
+	}
+
+	return coupons, nil
+}
+
+// Update updates a coupon by id in database, code and type of coupons are
+// not changed.
+func (c couponStore) Update(ctx context.Context, ID int, input domain.CouponUpdate) (domain.Coupon, error) {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return domain.Coupon{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `SELECT ` + couponColumns + ` FROM coupons c WHERE id = @id FOR UPDATE OF c`
+	rows, err := tx.Query(ctx, query, pgx.NamedArgs{"id": ID, "user_id": 0})
+	if err != nil {
+		return domain.Coupon{}, fmt.Errorf("failed to query coupon: %v", err)
+	}
+
+	coupon, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.Coupon])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Coupon{}, domain.ErrNoCouponsFound
+		}
+		return domain.Coupon{}, fmt.Errorf("failed to scan row of coupon: %v", err)
+	}
+
+	err = input.UpdateModel(&coupon)
+	if err != nil {
+		return domain.Coupon{}, err
+	}
+
+	query = `
+	UPDATE coupons
+	SET name             = @name,
+		value            = @value,
+		min_subtotal     = @min_subtotal,
+		product_ids      = @product_ids,
+		category_ids     = @category_ids,
+		buy_quantity     = @buy_quantity,
+		get_quantity     = @get_quantity,
+		usage_limit      = @usage_limit,
+		user_usage_limit = @user_usage_limit,
+		stackable        = @stackable,
+		priority         = @priority,
+		active           = @active,
+		starts_at        = @starts_at,
+		ends_at          = @ends_at,
+		updated_at       = NOW()
+	WHERE id = @id
+	RETURNING updated_at
+	`
+
+	args := couponArgs(coupon)
+	args["id"] = ID
+
+	err = tx.QueryRow(ctx, query, args).Scan(&coupon.UpdatedAt)
+	if err != nil {
+		return domain.Coupon{}, fmt.Errorf("failed to update coupon: %v", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.Coupon{}, fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return coupon, nil
+}
+
+// Delete deletes a coupon by id from database, it is removed from carts
+// and its redemptions by cascade.
+func (c couponStore) Delete(ctx context.Context, ID int) error {
+	result, err := c.db.Exec(ctx, `DELETE FROM coupons WHERE id = @id`, pgx.NamedArgs{"id": ID})
+	if err != nil {
+		return fmt.Errorf("failed to delete from coupons: %v", err)
+	}
+
+	if rows := result.RowsAffected(); rows != 1 {
+		return domain.ErrNoCouponsFound
+	}
+
+	return nil
+}
+
+// couponArgs returns named arguments of coupon columns.
+func couponArgs(coupon domain.Coupon) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"code":             coupon.Code,
+		"name":             coupon.Name,
+		"type":             coupon.Type,
+		"value":            coupon.Value,
+		"min_subtotal":     coupon.MinSubtotal,
+		"product_ids":      coupon.ProductIDs,
+		"category_ids":     coupon.CategoryIDs,
+		"buy_quantity":     coupon.BuyQuantity,
+		"get_quantity":     coupon.GetQuantity,
+		"usage_limit":      coupon.UsageLimit,
+		"user_usage_limit": coupon.UserUsageLimit,
+		"stackable":        coupon.Stackable,
+		"priority":         coupon.Priority,
+		"active":           coupon.Active,
+		"starts_at":        coupon.StartsAt,
+		"ends_at":          coupon.EndsAt,
+	}
+}
+
+// cartCoupons returns codes applied to a cart and coupons to evaluate on
+// it, that is the applied ones along with automatic promotions. Usage of
+// coupons is loaded for userID.
+func cartCoupons(ctx context.Context, q querier, cartID int, userID int) ([]string, []domain.Coupon, error) {
+	query := `
+	SELECT ` + couponColumns + ` FROM coupons c
+	WHERE c.code = '' AND c.active
+	OR c.id IN (SELECT coupon_id FROM cart_coupons WHERE cart_id = @cart_id)
+	ORDER BY c.id
+	`
+
+	rows, err := q.Query(ctx, query, pgx.NamedArgs{"cart_id": cartID, "user_id": userID})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query cart coupons: %v", err)
+	}
+
+	coupons, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.Coupon])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to scan rows of cart coupons: %v", err)
+	}
+
+	codes := []string{}
+	for _, coupon := range coupons {
+		if !coupon.IsAutomatic() {
+			codes = append(codes, coupon.Code)
+		}
+	}
+
+	return codes, coupons, nil
+}
+
+// lockCartCoupons locks coupons evaluated on a cart so their usage limits
+// hold until redemptions are recorded.
+func lockCartCoupons(ctx context.Context, q querier, cartID int) error {
+	query := `
+	SELECT id FROM coupons
+	WHERE code = '' AND active
+	OR id IN (SELECT coupon_id FROM cart_coupons WHERE cart_id = @cart_id)
+	ORDER BY id
+	FOR UPDATE
+	`
+
+	_, err := q.Exec(ctx, query, pgx.NamedArgs{"cart_id": cartID})
+	if err != nil {
+		return fmt.Errorf("failed to lock cart coupons: %v", err)
+	}
+
+	return nil
+}
+
+// applyCoupon applies a coupon code to a cart, cart must be filled. It
+// fails when the coupon can not be redeemed by owner of cart, discounts
+// nothing in cart or can not be combined with codes already applied.
+func applyCoupon(ctx context.Context, q querier, cart domain.Cart, code string) error {
+	query := `SELECT ` + couponColumns + ` FROM coupons c WHERE code = @code AND code <> ''`
+
+	rows, err := q.Query(ctx, query, pgx.NamedArgs{"code": domain.NormalizeCouponCode(code), "user_id": cart.UserID})
+	if err != nil {
+		return fmt.Errorf("failed to query coupon: %v", err)
+	}
+
+	coupon, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.Coupon])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrNoCouponsFound
+		}
+		return fmt.Errorf("failed to scan row of coupon: %v", err)
+	}
+
+	for _, applied := range cart.Coupons {
+		if applied == coupon.Code {
+			return nil
+		}
+	}
+
+	err = coupon.Redeemable(time.Now(), cart.UserID)
+	if err != nil {
+		return err
+	}
+
+	if len(cart.Coupons) > 0 && !coupon.Stackable {
+		return domain.ErrCouponNotStackable
+	}
+
+	// coupons of cart are evaluated alone with the new one to tell whether
+	// it discounts anything.
+	products, err := getProducts(ctx, q, cartProductIDs(cart))
+	if err != nil {
+		return err
+	}
+
+	check := domain.Cart{UserID: cart.UserID, Items: make([]domain.CartItem, len(cart.Items))}
+	copy(check.Items, cart.Items)
+	check.ApplyCoupons([]domain.Coupon{coupon}, products, time.Now())
+	if len(check.Discounts) == 0 {
+		return domain.ErrCouponNotApplicable
+	}
+
+	_, err = q.Exec(ctx, `INSERT INTO cart_coupons(cart_id, coupon_id) VALUES(@cart_id, @coupon_id)`,
+		pgx.NamedArgs{"cart_id": cart.ID, "coupon_id": coupon.ID})
+	if err != nil {
+		return fmt.Errorf("failed to insert cart coupon: %v", err)
+	}
+
+	return nil
+}
+
+// redeemCoupons records redemptions of coupons discounting an order and
+// removes codes of its cart.
+func redeemCoupons(ctx context.Context, q querier, cartID int, order domain.Order, discounts []domain.AppliedDiscount) error {
+	query := `
+	INSERT INTO coupon_redemptions(coupon_id, order_id, user_id, amount)
+	VALUES(@coupon_id, @order_id, @user_id, @amount)
+	`
+
+	for _, discount := range discounts {
+		args := pgx.NamedArgs{
+			"coupon_id": discount.CouponID,
+			"order_id":  order.ID,
+			"user_id":   order.UserID,
+			"amount":    discount.Amount,
+		}
+
+		_, err := q.Exec(ctx, query, args)
+		if err != nil {
+			return fmt.Errorf("failed to insert coupon redemption: %v", err)
+		}
+	}
+
+	_, err := q.Exec(ctx, `DELETE FROM cart_coupons WHERE cart_id = @cart_id`, pgx.NamedArgs{"cart_id": cartID})
+	if err != nil {
+		return fmt.Errorf("failed to delete from cart coupons: %v", err)
+	}
+
+	return nil
+}
+
+// cartProductIDs returns ids of products in cart.
+func cartProductIDs(cart domain.Cart) []int {
+	ids := make([]int, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		ids = append(ids, item.ProductID)
+	}
+	return ids
+}
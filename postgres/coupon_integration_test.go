@@ -0,0 +1,94 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+	"github.com/mortezadadgar/ecommerce-api/postgres"
+)
+
+func TestCartService_Coupons(t *testing.T) {
+	db := newCartTestDB(t, "carts_coupons")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	product := domain.Product{Name: "priced", CategoryID: 1, Price: 1000, Quantity: 10}
+	err := postgres.NewProductStore(db).Create(ctx, &product)
+	if err != nil {
+		t.Fatalf("product Create: %v", err)
+	}
+
+	coupon := domain.CouponCreate{
+		Code:           "welcome",
+		Name:           "welcome",
+		Type:           domain.CouponPercentage,
+		Value:          10,
+		UserUsageLimit: 1,
+	}.CreateModel()
+	err = postgres.NewCouponStore(db).Create(ctx, &coupon)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	owner := domain.CartOwner{UserID: 1}
+	_, err = postgres.NewCartStore(db).AddItem(ctx, owner, domain.CartItem{ProductID: product.ID, Quantity: 2})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	_, err = postgres.NewCartStore(db).ApplyCoupon(ctx, owner, "UNKNOWN")
+	if err != domain.ErrNoCouponsFound {
+		t.Errorf("expected %q, got %q", domain.ErrNoCouponsFound, err)
+	}
+
+	cart, err := postgres.NewCartStore(db).ApplyCoupon(ctx, owner, "Welcome")
+	if err != nil {
+		t.Fatalf("ApplyCoupon: %v", err)
+	}
+
+	if cart.Totals.Discount != 200 || cart.Totals.GrandTotal != 1800 {
+		t.Errorf("expected discount of %d, got: %#v", 200, cart.Totals)
+	}
+
+	order, err := postgres.NewOrderStore(db).Checkout(ctx, 1, domain.CheckoutDetails{})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+
+	if order.Discount != 200 || order.Lines[0].Discount != 200 || order.Lines[0].LineTotal != 1800 {
+		t.Errorf("expected discounted order, got: %#v", order)
+	}
+
+	got, err := postgres.NewCouponStore(db).GetByID(ctx, coupon.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+
+	if got.Used != 1 {
+		t.Errorf("expected coupon to be used once, got: %d", got.Used)
+	}
+
+	_, err = postgres.NewCartStore(db).AddItem(ctx, owner, domain.CartItem{ProductID: product.ID, Quantity: 1})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	_, err = postgres.NewCartStore(db).ApplyCoupon(ctx, owner, "welcome")
+	if err != domain.ErrCouponUsageExceeded {
+		t.Errorf("expected %q, got %q", domain.ErrCouponUsageExceeded, err)
+	}
+
+	// cancelling the order gives the coupon back to user.
+	_, err = postgres.NewOrderStore(db).Cancel(ctx, order.ID, 1)
+	if err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+
+	_, err = postgres.NewCartStore(db).ApplyCoupon(ctx, owner, "welcome")
+	if err != nil {
+		t.Errorf("ApplyCoupon: %v", err)
+	}
+}
@@ -0,0 +1,475 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// digitalStore represents digital products database.
+type digitalStore struct {
+	db *pgxpool.Pool
+}
+
+// NewDigitalStore returns a new instance of DigitalStore.
+func NewDigitalStore(db *pgxpool.Pool) digitalStore {
+	return digitalStore{db: db}
+}
+
+// CreateFile creates a new file of digital product in database.
+func (d digitalStore) CreateFile(ctx context.Context, file *domain.ProductFile) error {
+	err := checkDigital(ctx, d.db, file.ProductID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO product_files(product_id, name, blob_key, content_type, size)
+	VALUES(@product_id, @name, @blob_key, @content_type, @size)
+	RETURNING id, created_at
+	`
+
+	args := pgx.NamedArgs{
+		"product_id":   &file.ProductID,
+		"name":         &file.Name,
+		"blob_key":     &file.BlobKey,
+		"content_type": &file.ContentType,
+		"size":         &file.Size,
+	}
+
+	err = d.db.QueryRow(ctx, query, args).Scan(&file.ID, &file.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert product file: %v", err)
+	}
+
+	return nil
+}
+
+// GetFile get file by id from database.
+func (d digitalStore) GetFile(ctx context.Context, ID int) (domain.ProductFile, error) {
+	query := `
+	SELECT * FROM product_files
+	WHERE id = @id
+	`
+
+	rows, err := d.db.Query(ctx, query, pgx.NamedArgs{"id": ID})
+	if err != nil {
+		return domain.ProductFile{}, fmt.Errorf("failed to query product file: %v", err)
+	}
+
+	file, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.ProductFile])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ProductFile{}, domain.ErrNoFilesFound
+		}
+		return domain.ProductFile{}, fmt.Errorf("failed to scan row of product file: %v", err)
+	}
+
+	return file, nil
+}
+
+// ListFiles lists files of a digital product.
+func (d digitalStore) ListFiles(ctx context.Context, productID int) ([]domain.ProductFile, error) {
+	files, err := listFiles(ctx, d.db, []int{productID})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files[productID]) == 0 {
+		return nil, domain.ErrNoFilesFound
+	}
+
+	return files[productID], nil
+}
+
+// DeleteFile deletes a file by id from database and returns it so its
+// contents can be removed from blob store.
+func (d digitalStore) DeleteFile(ctx context.Context, ID int) (domain.ProductFile, error) {
+	query := `
+	DELETE FROM product_files
+	WHERE id = @id
+	RETURNING *
+	`
+
+	rows, err := d.db.Query(ctx, query, pgx.NamedArgs{"id": ID})
+	if err != nil {
+		return domain.ProductFile{}, fmt.Errorf("failed to delete from product files: %v", err)
+	}
+
+	file, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.ProductFile])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ProductFile{}, domain.ErrNoFilesFound
+		}
+		return domain.ProductFile{}, fmt.Errorf("failed to scan row of product file: %v", err)
+	}
+
+	return file, nil
+}
+
+// AddLicenseKeys adds keys to license key pool of a digital product.
+func (d digitalStore) AddLicenseKeys(ctx context.Context, productID int, keys []string) (domain.LicenseKeysResult, error) {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return domain.LicenseKeysResult{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = checkDigital(ctx, tx, productID)
+	if err != nil {
+		return domain.LicenseKeysResult{}, err
+	}
+
+	query := `
+	INSERT INTO license_keys(product_id, key)
+	VALUES(@product_id, @key)
+	`
+
+	for _, key := range keys {
+		_, err = tx.Exec(ctx, query, pgx.NamedArgs{"product_id": productID, "key": key})
+		if err != nil {
+			pgErr := pgError(err)
+			if pgErr.Code == pgerrcode.UniqueViolation {
+				return domain.LicenseKeysResult{}, domain.ErrDuplicatedLicenseKey
+			}
+			return domain.LicenseKeysResult{}, fmt.Errorf("failed to insert license key: %v", err)
+		}
+	}
+
+	result := domain.LicenseKeysResult{Added: len(keys)}
+
+	query = `
+	SELECT COUNT(*) FROM license_keys
+	WHERE product_id = @product_id AND grant_id IS NULL
+	`
+
+	err = tx.QueryRow(ctx, query, pgx.NamedArgs{"product_id": productID}).Scan(&result.Available)
+	if err != nil {
+		return domain.LicenseKeysResult{}, fmt.Errorf("failed to count license keys: %v", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.LicenseKeysResult{}, fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return result, nil
+}
+
+// Grant grants a user to download a digital product, one license key is
+// handed out per unit of quantity when product has a license key pool.
+func (d digitalStore) Grant(ctx context.Context, grant *domain.DownloadGrant) error {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = grantDownload(ctx, tx, grant)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return nil
+}
+
+// GetGrant get download grant by id from database.
+func (d digitalStore) GetGrant(ctx context.Context, ID int) (domain.DownloadGrant, error) {
+	grants, err := d.listGrants(ctx, FormatAndInt("id", ID))
+	if err != nil {
+		return domain.DownloadGrant{}, err
+	}
+
+	return grants[0], nil
+}
+
+// ListGrants lists download grants of a user.
+func (d digitalStore) ListGrants(ctx context.Context, userID int) ([]domain.DownloadGrant, error) {
+	return d.listGrants(ctx, FormatAndInt("user_id", userID))
+}
+
+func (d digitalStore) listGrants(ctx context.Context, where string) ([]domain.DownloadGrant, error) {
+	query := `
+	SELECT * FROM download_grants
+	WHERE 1=1
+	` + where + `
+	ORDER BY id
+	`
+
+	rows, err := d.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query list download grants: %v", err)
+	}
+
+	grants, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.DownloadGrant])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of download grants: %v", err)
+	}
+
+	if len(grants) == 0 {
+		return nil, domain.ErrNoGrantsFound
+	}
+
+	grantIDs := make([]int, 0, len(grants))
+	productIDs := make([]int, 0, len(grants))
+	for _, g := range grants {
+		grantIDs = append(grantIDs, g.ID)
+		productIDs = append(productIDs, g.ProductID)
+	}
+
+	files, err := listFiles(ctx, d.db, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+	SELECT grant_id, key FROM license_keys
+	WHERE grant_id = ANY(@ids)
+	ORDER BY id
+	`
+
+	rows, err = d.db.Query(ctx, query, pgx.NamedArgs{"ids": grantIDs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query license keys: %v", err)
+	}
+
+	keys := make(map[int][]string)
+	var (
+		grantID int
+		key     string
+	)
+	_, err = pgx.ForEachRow(rows, []any{&grantID, &key}, func() error {
+		keys[grantID] = append(keys[grantID], key)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of license keys: %v", err)
+	}
+
+	for i := range grants {
+		grants[i].Files = files[grants[i].ProductID]
+		grants[i].LicenseKeys = keys[grants[i].ID]
+	}
+
+	return grants, nil
+}
+
+// UseDownload counts a download against a grant.
+func (d digitalStore) UseDownload(ctx context.Context, grantID int) error {
+	query := `
+	UPDATE download_grants
+	SET downloads = downloads + 1
+	WHERE id = @id AND downloads < max_downloads
+	`
+
+	result, err := d.db.Exec(ctx, query, pgx.NamedArgs{"id": grantID})
+	if err != nil {
+		return fmt.Errorf("failed to update download grant: %v", err)
+	}
+
+	if result.RowsAffected() != 1 {
+		_, err = d.GetGrant(ctx, grantID)
+		if err != nil {
+			return err
+		}
+		return domain.ErrDownloadLimitReached
+	}
+
+	return nil
+}
+
+// grantDownload inserts a download grant and assigns license keys to it.
+func grantDownload(ctx context.Context, q querier, grant *domain.DownloadGrant) error {
+	err := checkDigital(ctx, q, grant.ProductID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO download_grants(user_id, order_id, product_id, quantity, max_downloads)
+	VALUES(@user_id, @order_id, @product_id, @quantity, @max_downloads)
+	RETURNING id, downloads, created_at
+	`
+
+	args := pgx.NamedArgs{
+		"user_id":       &grant.UserID,
+		"order_id":      grant.OrderID,
+		"product_id":    &grant.ProductID,
+		"quantity":      &grant.Quantity,
+		"max_downloads": &grant.MaxDownloads,
+	}
+
+	err = q.QueryRow(ctx, query, args).Scan(&grant.ID, &grant.Downloads, &grant.CreatedAt)
+	if err != nil {
+		pgErr := pgError(err)
+		if pgErr.Code == pgerrcode.ForeignKeyViolation {
+			return domain.ErrNoUsersFound
+		}
+		return fmt.Errorf("failed to insert download grant: %v", err)
+	}
+
+	var pooled bool
+	query = `SELECT EXISTS(SELECT 1 FROM license_keys WHERE product_id = @product_id)`
+	err = q.QueryRow(ctx, query, pgx.NamedArgs{"product_id": grant.ProductID}).Scan(&pooled)
+	if err != nil {
+		return fmt.Errorf("failed to query license keys: %v", err)
+	}
+
+	if !pooled {
+		return nil
+	}
+
+	query = `
+	UPDATE license_keys
+	SET grant_id = @grant_id, assigned_at = NOW()
+	WHERE id IN (
+		SELECT id FROM license_keys
+		WHERE product_id = @product_id AND grant_id IS NULL
+		ORDER BY id
+		LIMIT @quantity
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING key
+	`
+
+	args = pgx.NamedArgs{
+		"grant_id":   grant.ID,
+		"product_id": grant.ProductID,
+		"quantity":   grant.Quantity,
+	}
+
+	rows, err := q.Query(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("failed to assign license keys: %v", err)
+	}
+
+	grant.LicenseKeys, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("failed to scan rows of license keys: %v", err)
+	}
+
+	if len(grant.LicenseKeys) != grant.Quantity {
+		return domain.ErrNoLicenseKeys
+	}
+
+	return nil
+}
+
+// grantOrder grants digital products of a paid order to its user.
+func grantOrder(ctx context.Context, q querier, order domain.Order) error {
+	orders := []domain.Order{order}
+	err := fillOrders(ctx, q, orders)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range orders[0].Lines {
+		if line.Shippable || line.ProductID == nil {
+			continue
+		}
+
+		grant := domain.GrantCreate{UserID: order.UserID, Quantity: line.Quantity}.CreateModel(*line.ProductID)
+		grant.OrderID = &order.ID
+		err = grantDownload(ctx, q, &grant)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// revokeOrderGrants deletes download grants of a cancelled or refunded
+// order, their license keys are given back to the pool.
+func revokeOrderGrants(ctx context.Context, q querier, orderID int) error {
+	query := `
+	UPDATE license_keys
+	SET grant_id = NULL, assigned_at = NULL
+	WHERE grant_id IN (SELECT id FROM download_grants WHERE order_id = @order_id)
+	`
+
+	_, err := q.Exec(ctx, query, pgx.NamedArgs{"order_id": orderID})
+	if err != nil {
+		return fmt.Errorf("failed to release license keys: %v", err)
+	}
+
+	_, err = q.Exec(ctx, `DELETE FROM download_grants WHERE order_id = @order_id`,
+		pgx.NamedArgs{"order_id": orderID})
+	if err != nil {
+		return fmt.Errorf("failed to delete download grants: %v", err)
+	}
+
+	return nil
+}
+
+// checkLicenseKeys returns ErrNoLicenseKeys when product has a pool of
+// license keys with less than quantity left.
+func checkLicenseKeys(ctx context.Context, q querier, productID int, quantity int) error {
+	query := `
+	SELECT COUNT(*), COUNT(*) FILTER (WHERE grant_id IS NULL)
+	FROM license_keys
+	WHERE product_id = @product_id
+	`
+
+	var pooled, available int
+	err := q.QueryRow(ctx, query, pgx.NamedArgs{"product_id": productID}).Scan(&pooled, &available)
+	if err != nil {
+		return fmt.Errorf("failed to query license keys: %v", err)
+	}
+
+	if pooled > 0 && available < quantity {
+		return domain.ErrNoLicenseKeys
+	}
+
+	return nil
+}
+
+// checkDigital returns an error when product does not exist or is not digital.
+func checkDigital(ctx context.Context, q querier, productID int) error {
+	typ, err := productType(ctx, q, productID)
+	if err != nil {
+		return err
+	}
+
+	if typ != domain.ProductTypeDigital {
+		return domain.ErrNotDigital
+	}
+
+	return nil
+}
+
+// listFiles lists files of the given products keyed by product id.
+func listFiles(ctx context.Context, q querier, productIDs []int) (map[int][]domain.ProductFile, error) {
+	query := `
+	SELECT * FROM product_files
+	WHERE product_id = ANY(@ids)
+	ORDER BY id
+	`
+
+	rows, err := q.Query(ctx, query, pgx.NamedArgs{"ids": productIDs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query list product files: %v", err)
+	}
+
+	files, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.ProductFile])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of product files: %v", err)
+	}
+
+	result := make(map[int][]domain.ProductFile)
+	for _, f := range files {
+		result[f.ProductID] = append(result[f.ProductID], f)
+	}
+
+	return result, nil
+}
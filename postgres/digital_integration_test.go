@@ -0,0 +1,221 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+	"github.com/mortezadadgar/ecommerce-api/postgres"
+)
+
+func TestDigitalService_LicenseKeys(t *testing.T) {
+	db := newCartTestDB(t, "digital_license_keys")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	product := domain.Product{SKU: "SKU-2", Name: "ebook", CategoryID: 1, Type: domain.ProductTypeDigital}
+	err := postgres.NewProductStore(db).Create(ctx, &product)
+	if err != nil {
+		t.Fatalf("product Create: %v", err)
+	}
+
+	store := postgres.NewDigitalStore(db)
+
+	_, err = store.AddLicenseKeys(ctx, 1, []string{"KEY-0"})
+	if err != domain.ErrNotDigital {
+		t.Errorf("expected ErrNotDigital, got: %v", err)
+	}
+
+	result, err := store.AddLicenseKeys(ctx, product.ID, []string{"KEY-1", "KEY-2", "KEY-3"})
+	if err != nil {
+		t.Fatalf("AddLicenseKeys: %v", err)
+	}
+
+	if result.Added != 3 || result.Available != 3 {
+		t.Errorf("expected 3 keys available, got: %+v", result)
+	}
+
+	_, err = store.AddLicenseKeys(ctx, product.ID, []string{"KEY-1"})
+	if err != domain.ErrDuplicatedLicenseKey {
+		t.Errorf("expected ErrDuplicatedLicenseKey, got: %v", err)
+	}
+
+	grant := domain.GrantCreate{UserID: 1, Quantity: 2}.CreateModel(product.ID)
+	err = store.Grant(ctx, &grant)
+	if err != nil {
+		t.Fatalf("Grant: %v", err)
+	}
+
+	if len(grant.LicenseKeys) != 2 {
+		t.Fatalf("expected %d keys assigned, got: %v", 2, grant.LicenseKeys)
+	}
+
+	for _, key := range grant.LicenseKeys {
+		if key == "KEY-3" {
+			t.Errorf("expected oldest keys assigned, got: %v", grant.LicenseKeys)
+		}
+	}
+
+	// a grant not covered by the pool fails as a whole and keeps its key.
+	short := domain.GrantCreate{UserID: 1, Quantity: 2}.CreateModel(product.ID)
+	err = store.Grant(ctx, &short)
+	if err != domain.ErrNoLicenseKeys {
+		t.Errorf("expected ErrNoLicenseKeys, got: %v", err)
+	}
+
+	last := domain.GrantCreate{UserID: 1, Quantity: 1}.CreateModel(product.ID)
+	err = store.Grant(ctx, &last)
+	if err != nil {
+		t.Fatalf("Grant: %v", err)
+	}
+
+	if len(last.LicenseKeys) != 1 || last.LicenseKeys[0] != "KEY-3" {
+		t.Errorf("expected last key assigned, got: %v", last.LicenseKeys)
+	}
+
+	got, err := store.GetGrant(ctx, grant.ID)
+	if err != nil {
+		t.Fatalf("GetGrant: %v", err)
+	}
+
+	if len(got.LicenseKeys) != 2 {
+		t.Errorf("expected keys of grant listed, got: %v", got.LicenseKeys)
+	}
+}
+
+func TestDigitalService_UseDownload(t *testing.T) {
+	db := newCartTestDB(t, "digital_use_download")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	product := domain.Product{SKU: "SKU-2", Name: "ebook", CategoryID: 1, Type: domain.ProductTypeDigital}
+	err := postgres.NewProductStore(db).Create(ctx, &product)
+	if err != nil {
+		t.Fatalf("product Create: %v", err)
+	}
+
+	store := postgres.NewDigitalStore(db)
+
+	grant := domain.GrantCreate{UserID: 1, Quantity: 1}.CreateModel(product.ID)
+	grant.MaxDownloads = 2
+	err = store.Grant(ctx, &grant)
+	if err != nil {
+		t.Fatalf("Grant: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		err = store.UseDownload(ctx, grant.ID)
+		if err != nil {
+			t.Fatalf("UseDownload: %v", err)
+		}
+	}
+
+	err = store.UseDownload(ctx, grant.ID)
+	if err != domain.ErrDownloadLimitReached {
+		t.Errorf("expected ErrDownloadLimitReached, got: %v", err)
+	}
+
+	err = store.UseDownload(ctx, grant.ID+1)
+	if err != domain.ErrNoGrantsFound {
+		t.Errorf("expected ErrNoGrantsFound, got: %v", err)
+	}
+
+	got, err := store.GetGrant(ctx, grant.ID)
+	if err != nil {
+		t.Fatalf("GetGrant: %v", err)
+	}
+
+	if got.Downloads != 2 {
+		t.Errorf("expected %d downloads, got: %d", 2, got.Downloads)
+	}
+
+	err = store.Grant(ctx, &domain.DownloadGrant{UserID: 1, ProductID: 1, Quantity: 1})
+	if err != domain.ErrNotDigital {
+		t.Errorf("expected ErrNotDigital, got: %v", err)
+	}
+}
+
+func TestDigitalService_OrderGrants(t *testing.T) {
+	db := newCartTestDB(t, "digital_order_grants")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	product := domain.Product{SKU: "SKU-2", Name: "ebook", CategoryID: 1, Type: domain.ProductTypeDigital, Price: 500, Quantity: 5}
+	err := postgres.NewProductStore(db).Create(ctx, &product)
+	if err != nil {
+		t.Fatalf("product Create: %v", err)
+	}
+
+	store := postgres.NewDigitalStore(db)
+	orders := postgres.NewOrderStore(db)
+
+	_, err = store.AddLicenseKeys(ctx, product.ID, []string{"KEY-1"})
+	if err != nil {
+		t.Fatalf("AddLicenseKeys: %v", err)
+	}
+
+	_, err = postgres.NewCartStore(db).AddItem(ctx, domain.CartOwner{UserID: 1}, domain.CartItem{ProductID: product.ID, Quantity: 2})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	_, err = orders.Checkout(ctx, 1, domain.CheckoutDetails{})
+	if err != domain.ErrNoLicenseKeys {
+		t.Errorf("expected ErrNoLicenseKeys from Checkout, got: %v", err)
+	}
+
+	_, err = store.AddLicenseKeys(ctx, product.ID, []string{"KEY-2"})
+	if err != nil {
+		t.Fatalf("AddLicenseKeys: %v", err)
+	}
+
+	order, err := orders.Checkout(ctx, 1, domain.CheckoutDetails{})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+
+	// nothing is granted before the order is paid.
+	_, err = store.ListGrants(ctx, 1)
+	if err != domain.ErrNoGrantsFound {
+		t.Errorf("expected ErrNoGrantsFound before payment, got: %v", err)
+	}
+
+	_, err = orders.UpdateStatus(ctx, order.ID, domain.OrderStatusPaid, 0, "")
+	if err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+
+	grants, err := store.ListGrants(ctx, 1)
+	if err != nil {
+		t.Fatalf("ListGrants: %v", err)
+	}
+
+	if len(grants) != 1 || grants[0].OrderID == nil || *grants[0].OrderID != order.ID || len(grants[0].LicenseKeys) != 2 {
+		t.Fatalf("expected grant of order with %d keys, got: %+v", 2, grants)
+	}
+
+	_, err = orders.UpdateStatus(ctx, order.ID, domain.OrderStatusCancelled, 0, "")
+	if err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+
+	_, err = store.ListGrants(ctx, 1)
+	if err != domain.ErrNoGrantsFound {
+		t.Errorf("expected ErrNoGrantsFound after cancel, got: %v", err)
+	}
+
+	result, err := store.AddLicenseKeys(ctx, product.ID, []string{"KEY-3"})
+	if err != nil {
+		t.Fatalf("AddLicenseKeys: %v", err)
+	}
+
+	if result.Available != 3 {
+		t.Errorf("expected keys of cancelled order back in pool, got: %+v", result)
+	}
+}
@@ -0,0 +1,625 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// creditTransactionColumns selects transactions of gift card and store
+// credit ledgers.
+const creditTransactionColumns = `id, order_id, kind, amount, balance, note, created_at`
+
+// giftCardIntentFormat identifies gift card payments by order and card.
+const giftCardIntentFormat = "order-%d-card-%d"
+
+// giftCardStore represents gift cards database.
+type giftCardStore struct {
+	db *pgxpool.Pool
+}
+
+// NewGiftCardStore returns a new instance of GiftCardStore.
+func NewGiftCardStore(db *pgxpool.Pool) giftCardStore {
+	return giftCardStore{db: db}
+}
+
+// Create issues a new gift card in database, its initial balance is
+// recorded as its first transaction.
+func (g giftCardStore) Create(ctx context.Context, card *domain.GiftCard) error {
+	tx, err := g.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+	INSERT INTO gift_cards(code, initial_balance, balance, currency, note, expires_at)
+	VALUES(@code, @initial_balance, 0, @currency, @note, @expires_at)
+	RETURNING id, created_at, updated_at
+	`
+
+	args := pgx.NamedArgs{
+		"code":            card.Code,
+		"initial_balance": card.InitialBalance,
+		"currency":        card.Currency,
+		"note":            card.Note,
+		"expires_at":      card.ExpiresAt,
+	}
+
+	err = tx.QueryRow(ctx, query, args).Scan(&card.ID, &card.CreatedAt, &card.UpdatedAt)
+	if err != nil {
+		pgErr := pgError(err)
+		if pgErr.Code == pgerrcode.UniqueViolation && pgErr.ConstraintName == "gift_cards_code_key" {
+			return domain.ErrDuplicatedGiftCard
+		}
+		return fmt.Errorf("failed to insert gift card: %v", err)
+	}
+
+	card.Balance = 0
+	err = applyGiftCard(ctx, tx, card, domain.CreditIssue, card.InitialBalance, nil, card.Note)
+	if err != nil {
+		return err
+	}
+
+	err = fillGiftCard(ctx, tx, card)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return nil
+}
+
+// GetByID get gift card by id along with its transactions from database.
+func (g giftCardStore) GetByID(ctx context.Context, ID int) (domain.GiftCard, error) {
+	cards, err := g.List(ctx, domain.GiftCardFilter{ID: ID})
+	if err != nil {
+		return domain.GiftCard{}, err
+	}
+
+	card := cards[0]
+	err = fillGiftCard(ctx, g.db, &card)
+	if err != nil {
+		return domain.GiftCard{}, err
+	}
+
+	return card, nil
+}
+
+// List lists gift cards with optional filter.
+func (g giftCardStore) List(ctx context.Context, filter domain.GiftCardFilter) ([]domain.GiftCard, error) {
+	query := `
+	SELECT * FROM gift_cards
+	WHERE (@code = '' OR code = @code)
+	` + FormatAndInt("id", filter.ID) + `
+	` + FormatSort(filter.Sort) + `
+	` + FormatLimitOffset(filter.Limit, filter.Offset) + `
+	`
+
+	rows, err := g.db.Query(ctx, query, pgx.NamedArgs{"code": domain.NormalizeGiftCardCode(filter.Code)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query list gift cards: %v", err)
+	}
+
+	cards, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.GiftCard])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of gift cards: %v", err)
+	}
+
+	if len(cards) == 0 {
+		return nil, domain.ErrNoGiftCardsFound
+	}
+
+	return cards, nil
+}
+
+// Adjust adds amount to balance of a gift card.
+func (g giftCardStore) Adjust(ctx context.Context, ID int, adjustment domain.CreditAdjustment) (domain.GiftCard, error) {
+	tx, err := g.db.Begin(ctx)
+	if err != nil {
+		return domain.GiftCard{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	card, err := lockGiftCard(ctx, tx, "id = @id", pgx.NamedArgs{"id": ID})
+	if err != nil {
+		return domain.GiftCard{}, err
+	}
+
+	if card.VoidedAt != nil {
+		return domain.GiftCard{}, domain.ErrGiftCardVoided
+	}
+
+	if card.Balance+adjustment.Amount < 0 {
+		return domain.GiftCard{}, domain.ErrGiftCardBalance
+	}
+
+	err = applyGiftCard(ctx, tx, &card, domain.CreditAdjust, adjustment.Amount, nil, adjustment.Note)
+	if err != nil {
+		return domain.GiftCard{}, err
+	}
+
+	return commitGiftCard(ctx, tx, card)
+}
+
+// Void voids a gift card taking all of its balance away.
+func (g giftCardStore) Void(ctx context.Context, ID int, note string) (domain.GiftCard, error) {
+	tx, err := g.db.Begin(ctx)
+	if err != nil {
+		return domain.GiftCard{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	card, err := lockGiftCard(ctx, tx, "id = @id", pgx.NamedArgs{"id": ID})
+	if err != nil {
+		return domain.GiftCard{}, err
+	}
+
+	if card.VoidedAt != nil {
+		return domain.GiftCard{}, domain.ErrGiftCardVoided
+	}
+
+	err = applyGiftCard(ctx, tx, &card, domain.CreditVoid, -card.Balance, nil, note)
+	if err != nil {
+		return domain.GiftCard{}, err
+	}
+
+	err = tx.QueryRow(ctx, `UPDATE gift_cards SET voided_at = NOW() WHERE id = @id RETURNING voided_at`,
+		pgx.NamedArgs{"id": ID}).Scan(&card.VoidedAt)
+	if err != nil {
+		return domain.GiftCard{}, fmt.Errorf("failed to void gift card: %v", err)
+	}
+
+	return commitGiftCard(ctx, tx, card)
+}
+
+// storeCreditStore represents store credit ledger database.
+type storeCreditStore struct {
+	db *pgxpool.Pool
+}
+
+// NewStoreCreditStore returns a new instance of StoreCreditStore.
+func NewStoreCreditStore(db *pgxpool.Pool) storeCreditStore {
+	return storeCreditStore{db: db}
+}
+
+// Get returns store credit of user with its transactions from newest,
+// users without any have no balance.
+func (s storeCreditStore) Get(ctx context.Context, userID int) (domain.StoreCredit, error) {
+	return getStoreCredit(ctx, s.db, userID)
+}
+
+// Adjust adds amount to store credit of user.
+func (s storeCreditStore) Adjust(ctx context.Context, userID int, adjustment domain.CreditAdjustment) (domain.StoreCredit, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return domain.StoreCredit{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = insertStoreCredit(ctx, tx, userID, domain.CreditAdjust, adjustment.Amount, nil, adjustment.Note)
+	if err != nil {
+		return domain.StoreCredit{}, err
+	}
+
+	credit, err := getStoreCredit(ctx, tx, userID)
+	if err != nil {
+		return domain.StoreCredit{}, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.StoreCredit{}, fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return credit, nil
+}
+
+// Refund records refund of amount on a payment and credits it to store
+// credit of user of its order.
+func (s storeCreditStore) Refund(ctx context.Context, paymentID int, amount int, note string) (domain.Payment, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return domain.Payment{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	payment, err := refundStoreCredit(ctx, tx, paymentID, amount, note)
+	if err != nil {
+		return domain.Payment{}, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.Payment{}, fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return payment, nil
+}
+
+// refundStoreCredit records refund of amount on a payment and credits it
+// to store credit of user of its order.
+func refundStoreCredit(ctx context.Context, q querier, paymentID int, amount int, note string) (domain.Payment, error) {
+	payment, err := lockPayment(ctx, q, "id = @id", pgx.NamedArgs{"id": paymentID})
+	if err != nil {
+		return domain.Payment{}, err
+	}
+
+	err = payment.RefundToStoreCredit(amount)
+	if err != nil {
+		return domain.Payment{}, err
+	}
+
+	var userID int
+	err = q.QueryRow(ctx, `SELECT user_id FROM orders WHERE id = @id`, pgx.NamedArgs{"id": payment.OrderID}).Scan(&userID)
+	if err != nil {
+		return domain.Payment{}, fmt.Errorf("failed to query order: %v", err)
+	}
+
+	err = insertStoreCredit(ctx, q, userID, domain.CreditRefund, amount, &payment.OrderID, note)
+	if err != nil {
+		return domain.Payment{}, err
+	}
+
+	err = savePayment(ctx, q, &payment, note)
+	if err != nil {
+		return domain.Payment{}, err
+	}
+
+	return payment, nil
+}
+
+// lockGiftCard selects a gift card matching condition for update.
+func lockGiftCard(ctx context.Context, q querier, condition string, args pgx.NamedArgs) (domain.GiftCard, error) {
+	rows, err := q.Query(ctx, `SELECT * FROM gift_cards WHERE `+condition+` FOR UPDATE`, args)
+	if err != nil {
+		return domain.GiftCard{}, fmt.Errorf("failed to query gift card: %v", err)
+	}
+
+	card, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.GiftCard])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.GiftCard{}, domain.ErrNoGiftCardsFound
+		}
+		return domain.GiftCard{}, fmt.Errorf("failed to scan row of gift card: %v", err)
+	}
+
+	return card, nil
+}
+
+// applyGiftCard adds amount to balance of a locked gift card and records
+// it as a transaction.
+func applyGiftCard(ctx context.Context, q querier, card *domain.GiftCard, kind string, amount int, orderID *int, note string) error {
+	query := `
+	UPDATE gift_cards
+	SET balance    = balance + @amount,
+		updated_at = NOW()
+	WHERE id = @id
+	RETURNING balance, updated_at
+	`
+
+	err := q.QueryRow(ctx, query, pgx.NamedArgs{"id": card.ID, "amount": amount}).Scan(&card.Balance, &card.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update gift card balance: %v", err)
+	}
+
+	query = `
+	INSERT INTO gift_card_transactions(gift_card_id, order_id, kind, amount, balance, note)
+	VALUES(@gift_card_id, @order_id, @kind, @amount, @balance, @note)
+	`
+
+	args := pgx.NamedArgs{
+		"gift_card_id": card.ID,
+		"order_id":     orderID,
+		"kind":         kind,
+		"amount":       amount,
+		"balance":      card.Balance,
+		"note":         note,
+	}
+
+	_, err = q.Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("failed to insert gift card transaction: %v", err)
+	}
+
+	return nil
+}
+
+// commitGiftCard loads transactions of gift card and commits transaction.
+func commitGiftCard(ctx context.Context, tx pgx.Tx, card domain.GiftCard) (domain.GiftCard, error) {
+	err := fillGiftCard(ctx, tx, &card)
+	if err != nil {
+		return domain.GiftCard{}, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.GiftCard{}, fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return card, nil
+}
+
+// fillGiftCard loads transactions of gift card from newest.
+func fillGiftCard(ctx context.Context, q querier, card *domain.GiftCard) error {
+	query := `
+	SELECT ` + creditTransactionColumns + ` FROM gift_card_transactions
+	WHERE gift_card_id = @id
+	ORDER BY id DESC
+	`
+
+	rows, err := q.Query(ctx, query, pgx.NamedArgs{"id": card.ID})
+	if err != nil {
+		return fmt.Errorf("failed to query list gift card transactions: %v", err)
+	}
+
+	card.Transactions, err = pgx.CollectRows(rows, pgx.RowToStructByName[domain.CreditTransaction])
+	if err != nil {
+		return fmt.Errorf("failed to scan rows of gift card transactions: %v", err)
+	}
+
+	return nil
+}
+
+// getStoreCredit returns store credit of user with its transactions.
+func getStoreCredit(ctx context.Context, q querier, userID int) (domain.StoreCredit, error) {
+	query := `
+	SELECT ` + creditTransactionColumns + ` FROM store_credit_transactions
+	WHERE user_id = @user_id
+	ORDER BY id DESC
+	`
+
+	rows, err := q.Query(ctx, query, pgx.NamedArgs{"user_id": userID})
+	if err != nil {
+		return domain.StoreCredit{}, fmt.Errorf("failed to query list store credit transactions: %v", err)
+	}
+
+	transactions, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.CreditTransaction])
+	if err != nil {
+		return domain.StoreCredit{}, fmt.Errorf("failed to scan rows of store credit transactions: %v", err)
+	}
+
+	credit := domain.StoreCredit{
+		UserID:       userID,
+		Currency:     domain.PaymentCurrency,
+		Transactions: transactions,
+	}
+
+	if len(transactions) > 0 {
+		credit.Balance = transactions[0].Balance
+	}
+
+	return credit, nil
+}
+
+// lockStoreCredit locks user to append to its store credit ledger and
+// returns its balance.
+func lockStoreCredit(ctx context.Context, q querier, userID int) (int, error) {
+	err := q.QueryRow(ctx, `SELECT id FROM users WHERE id = @user_id FOR UPDATE`,
+		pgx.NamedArgs{"user_id": userID}).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, domain.ErrNoUsersFound
+		}
+		return 0, fmt.Errorf("failed to lock user: %v", err)
+	}
+
+	query := `
+	SELECT balance FROM store_credit_transactions
+	WHERE user_id = @user_id
+	ORDER BY id DESC
+	LIMIT 1
+	`
+
+	var balance int
+	err = q.QueryRow(ctx, query, pgx.NamedArgs{"user_id": userID}).Scan(&balance)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("failed to query store credit balance: %v", err)
+	}
+
+	return balance, nil
+}
+
+// insertStoreCredit appends amount to store credit ledger of user,
+// ErrStoreCreditBalance is returned when balance would go negative.
+func insertStoreCredit(ctx context.Context, q querier, userID int, kind string, amount int, orderID *int, note string) error {
+	balance, err := lockStoreCredit(ctx, q, userID)
+	if err != nil {
+		return err
+	}
+
+	if balance+amount < 0 {
+		return domain.ErrStoreCreditBalance
+	}
+
+	query := `
+	INSERT INTO store_credit_transactions(user_id, order_id, kind, amount, balance, note)
+	VALUES(@user_id, @order_id, @kind, @amount, @balance, @note)
+	`
+
+	args := pgx.NamedArgs{
+		"user_id":  userID,
+		"order_id": orderID,
+		"kind":     kind,
+		"amount":   amount,
+		"balance":  balance + amount,
+		"note":     note,
+	}
+
+	_, err = q.Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("failed to insert store credit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// payWithStoredValue pays for a new order with gift cards and then store
+// credit of its user as far as their balance goes, each is recorded as a
+// captured payment. It returns amount paid.
+func payWithStoredValue(ctx context.Context, q querier, order domain.Order, details domain.CheckoutDetails) (int, error) {
+	due := order.Total
+	redeemed := make(map[string]bool)
+
+	for _, code := range details.GiftCards {
+		code = domain.NormalizeGiftCardCode(code)
+		if due == 0 || redeemed[code] {
+			continue
+		}
+		redeemed[code] = true
+
+		card, err := lockGiftCard(ctx, q, "code = @code", pgx.NamedArgs{"code": code})
+		if err != nil {
+			return 0, err
+		}
+
+		err = card.Redeemable(time.Now(), domain.PaymentCurrency)
+		if err != nil {
+			return 0, err
+		}
+
+		amount := domain.StoredValueAmount(card.Balance, due)
+		if amount == 0 {
+			continue
+		}
+
+		err = applyGiftCard(ctx, q, &card, domain.CreditRedeem, -amount, &order.ID, "")
+		if err != nil {
+			return 0, err
+		}
+
+		payment := domain.NewStoredValuePayment(order.ID, domain.PaymentProviderGiftCard,
+			fmt.Sprintf(giftCardIntentFormat, order.ID, card.ID), amount)
+		err = insertPayment(ctx, q, &payment)
+		if err != nil {
+			return 0, err
+		}
+
+		due -= amount
+	}
+
+	if details.UseStoreCredit && due > 0 {
+		balance, err := lockStoreCredit(ctx, q, order.UserID)
+		if err != nil {
+			return 0, err
+		}
+
+		amount := domain.StoredValueAmount(balance, due)
+		if amount > 0 {
+			err = insertStoreCredit(ctx, q, order.UserID, domain.CreditRedeem, -amount, &order.ID, "")
+			if err != nil {
+				return 0, err
+			}
+
+			payment := domain.NewStoredValuePayment(order.ID, domain.PaymentProviderStoreCredit,
+				fmt.Sprintf("order-%d", order.ID), amount)
+			err = insertPayment(ctx, q, &payment)
+			if err != nil {
+				return 0, err
+			}
+
+			due -= amount
+		}
+	}
+
+	return order.Total - due, nil
+}
+
+// restoreStoredValue gives what is left unrefunded of gift card and store
+// credit payments of an order back and marks them refunded, balance of
+// voided cards is credited to store credit of user instead.
+func restoreStoredValue(ctx context.Context, q querier, order domain.Order) error {
+	query := `
+	SELECT * FROM payments
+	WHERE order_id = @order_id AND provider IN (@gift_card, @store_credit)
+	AND status = @succeeded AND captured > refunded
+	ORDER BY id
+	FOR UPDATE
+	`
+
+	args := pgx.NamedArgs{
+		"order_id":     order.ID,
+		"succeeded":    domain.PaymentStatusSucceeded,
+		"gift_card":    domain.PaymentProviderGiftCard,
+		"store_credit": domain.PaymentProviderStoreCredit,
+	}
+
+	rows, err := q.Query(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("failed to query stored value payments: %v", err)
+	}
+
+	payments, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.Payment])
+	if err != nil {
+		return fmt.Errorf("failed to scan rows of stored value payments: %v", err)
+	}
+
+	if len(payments) == 0 {
+		return nil
+	}
+
+	var credit int
+	paymentIDs := make([]int, 0, len(payments))
+	for _, payment := range payments {
+		paymentIDs = append(paymentIDs, payment.ID)
+		amount := payment.Captured - payment.Refunded
+
+		if payment.Provider == domain.PaymentProviderStoreCredit {
+			credit += amount
+			continue
+		}
+
+		var orderID, cardID int
+		_, err = fmt.Sscanf(payment.IntentID, giftCardIntentFormat, &orderID, &cardID)
+		if err != nil {
+			return fmt.Errorf("failed to parse gift card of payment %d: %v", payment.ID, err)
+		}
+
+		card, err := lockGiftCard(ctx, q, "id = @id", pgx.NamedArgs{"id": cardID})
+		if err != nil {
+			return err
+		}
+
+		if card.VoidedAt != nil {
+			credit += amount
+			continue
+		}
+
+		err = applyGiftCard(ctx, q, &card, domain.CreditRestore, amount, &order.ID, "order cancelled")
+		if err != nil {
+			return err
+		}
+	}
+
+	if credit > 0 {
+		err = insertStoreCredit(ctx, q, order.UserID, domain.CreditRestore, credit, &order.ID, "order cancelled")
+		if err != nil {
+			return err
+		}
+	}
+
+	query = `
+	UPDATE payments
+	SET status     = @status,
+		refunded   = captured,
+		updated_at = NOW()
+	WHERE id = ANY(@ids)
+	`
+
+	_, err = q.Exec(ctx, query, pgx.NamedArgs{"ids": paymentIDs, "status": domain.PaymentStatusRefunded})
+	if err != nil {
+		return fmt.Errorf("failed to update payments: %v", err)
+	}
+
+	return issueCreditNote(ctx, q, order.ID, "order cancelled")
+}
@@ -0,0 +1,214 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+	"github.com/mortezadadgar/ecommerce-api/postgres"
+)
+
+func TestGiftCardService(t *testing.T) {
+	db := newTestDB(t, "gift_cards")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := postgres.NewGiftCardStore(db)
+
+	card := domain.GiftCardCreate{Code: "ABCD-EFGH-2345", InitialBalance: 100}.CreateModel("")
+	err := store.Create(ctx, &card)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if card.Balance != 100 || len(card.Transactions) != 1 || card.Transactions[0].Kind != domain.CreditIssue {
+		t.Fatalf("expected issued card with balance, got: %+v", card)
+	}
+
+	duplicate := domain.GiftCardCreate{Code: "abcdefgh2345", InitialBalance: 50}.CreateModel("")
+	err = store.Create(ctx, &duplicate)
+	if err != domain.ErrDuplicatedGiftCard {
+		t.Errorf("expected ErrDuplicatedGiftCard, got: %v", err)
+	}
+
+	_, err = store.Adjust(ctx, card.ID, domain.CreditAdjustment{Amount: -150})
+	if err != domain.ErrGiftCardBalance {
+		t.Errorf("expected ErrGiftCardBalance, got: %v", err)
+	}
+
+	got, err := store.Adjust(ctx, card.ID, domain.CreditAdjustment{Amount: -30, Note: "damaged"})
+	if err != nil {
+		t.Fatalf("Adjust: %v", err)
+	}
+
+	if got.Balance != 70 || len(got.Transactions) != 2 || got.Transactions[0].Balance != 70 {
+		t.Errorf("expected adjusted balance of %d, got: %+v", 70, got)
+	}
+
+	got, err = store.Void(ctx, card.ID, "lost")
+	if err != nil {
+		t.Fatalf("Void: %v", err)
+	}
+
+	if got.Balance != 0 || got.VoidedAt == nil || got.Transactions[0].Amount != -70 {
+		t.Errorf("expected voided card without balance, got: %+v", got)
+	}
+
+	_, err = store.Adjust(ctx, card.ID, domain.CreditAdjustment{Amount: 10})
+	if err != domain.ErrGiftCardVoided {
+		t.Errorf("expected ErrGiftCardVoided, got: %v", err)
+	}
+
+	cards, err := store.List(ctx, domain.GiftCardFilter{Code: "abcd efgh 2345"})
+	if err != nil || len(cards) != 1 {
+		t.Errorf("expected card listed by code, got: %v, %v", cards, err)
+	}
+}
+
+func TestStoreCreditService(t *testing.T) {
+	db := newCartTestDB(t, "store_credit")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := postgres.NewStoreCreditStore(db)
+
+	credit, err := store.Get(ctx, 1)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	if credit.Balance != 0 || len(credit.Transactions) != 0 {
+		t.Errorf("expected no store credit, got: %+v", credit)
+	}
+
+	_, err = store.Adjust(ctx, 1, domain.CreditAdjustment{Amount: -10})
+	if err != domain.ErrStoreCreditBalance {
+		t.Errorf("expected ErrStoreCreditBalance, got: %v", err)
+	}
+
+	_, err = store.Adjust(ctx, 2, domain.CreditAdjustment{Amount: 10})
+	if err != domain.ErrNoUsersFound {
+		t.Errorf("expected ErrNoUsersFound, got: %v", err)
+	}
+
+	_, err = store.Adjust(ctx, 1, domain.CreditAdjustment{Amount: 50})
+	if err != nil {
+		t.Fatalf("Adjust: %v", err)
+	}
+
+	credit, err = store.Adjust(ctx, 1, domain.CreditAdjustment{Amount: -20})
+	if err != nil {
+		t.Fatalf("Adjust: %v", err)
+	}
+
+	if credit.Balance != 30 || len(credit.Transactions) != 2 || credit.Transactions[0].Amount != -20 {
+		t.Errorf("expected balance of %d, got: %+v", 30, credit)
+	}
+}
+
+func TestCheckout_StoredValue(t *testing.T) {
+	db := newCartTestDB(t, "checkout_stored_value")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	product := domain.Product{SKU: "SKU-2", Name: "product2", CategoryID: 1, Price: 50, Quantity: 5}
+	err := postgres.NewProductStore(db).Create(ctx, &product)
+	if err != nil {
+		t.Fatalf("product Create: %v", err)
+	}
+
+	_, err = postgres.NewCartStore(db).AddItem(ctx, domain.CartOwner{UserID: 1}, domain.CartItem{ProductID: product.ID, Quantity: 2})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	cards := postgres.NewGiftCardStore(db)
+	card := domain.GiftCardCreate{Code: "ABCDEFGH2345", InitialBalance: 60}.CreateModel("")
+	err = cards.Create(ctx, &card)
+	if err != nil {
+		t.Fatalf("gift card Create: %v", err)
+	}
+
+	credits := postgres.NewStoreCreditStore(db)
+	_, err = credits.Adjust(ctx, 1, domain.CreditAdjustment{Amount: 25})
+	if err != nil {
+		t.Fatalf("store credit Adjust: %v", err)
+	}
+
+	orders := postgres.NewOrderStore(db)
+	_, err = orders.Checkout(ctx, 1, domain.CheckoutDetails{GiftCards: []string{"UNKNOWN1"}})
+	if err != domain.ErrNoGiftCardsFound {
+		t.Errorf("expected ErrNoGiftCardsFound, got: %v", err)
+	}
+
+	order, err := orders.Checkout(ctx, 1, domain.CheckoutDetails{
+		GiftCards:      []string{"abcd-efgh-2345", "ABCDEFGH2345"},
+		UseStoreCredit: true,
+	})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+
+	if order.Total != 100 || order.Status != domain.OrderStatusPending {
+		t.Fatalf("expected pending order of %d, got: %+v", 100, order)
+	}
+
+	payments, err := postgres.NewPaymentStore(db).List(ctx, domain.PaymentFilter{OrderID: order.ID, Sort: "id"})
+	if err != nil {
+		t.Fatalf("payments List: %v", err)
+	}
+
+	if len(payments) != 2 || payments[0].Captured != 60 || payments[1].Captured != 25 {
+		t.Fatalf("expected gift card and store credit payments, got: %+v", payments)
+	}
+
+	if due := domain.AmountDue(order, payments); due != 15 {
+		t.Errorf("expected %d due, got: %d", 15, due)
+	}
+
+	_, err = credits.Refund(ctx, payments[0].ID, 20, "")
+	if err != nil {
+		t.Fatalf("store credit Refund: %v", err)
+	}
+
+	_, err = orders.UpdateStatus(ctx, order.ID, domain.OrderStatusCancelled, 0, "")
+	if err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+
+	// what is already refunded of gift card is not restored to it again.
+	got, err := cards.GetByID(ctx, card.ID)
+	if err != nil {
+		t.Fatalf("gift card GetByID: %v", err)
+	}
+
+	if got.Balance != 40 || got.Transactions[0].Kind != domain.CreditRestore {
+		t.Errorf("expected restored gift card balance, got: %+v", got)
+	}
+
+	credit, err := credits.Get(ctx, 1)
+	if err != nil {
+		t.Fatalf("store credit Get: %v", err)
+	}
+
+	if credit.Balance != 45 {
+		t.Errorf("expected refunded and restored store credit of %d, got: %d", 45, credit.Balance)
+	}
+
+	payments, err = postgres.NewPaymentStore(db).List(ctx, domain.PaymentFilter{OrderID: order.ID})
+	if err != nil {
+		t.Fatalf("payments List: %v", err)
+	}
+
+	for _, payment := range payments {
+		if payment.Status != domain.PaymentStatusRefunded {
+			t.Errorf("expected refunded payment, got: %+v", payment)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// idempotencyStore represents idempotency keys database.
+type idempotencyStore struct {
+	db *pgxpool.Pool
+}
+
+// NewIdempotencyStore returns a new instance of IdempotencyStore.
+func NewIdempotencyStore(db *pgxpool.Pool) idempotencyStore {
+	return idempotencyStore{db: db}
+}
+
+// Begin claims key within scope, expired keys and keys of abandoned
+// requests are taken over.
+func (i idempotencyStore) Begin(ctx context.Context, scope string, key string, requestHash string) (*domain.IdempotentResponse, error) {
+	query := `
+	INSERT INTO idempotency_keys(scope, key, request_hash)
+	VALUES(@scope, @key, @request_hash)
+	ON CONFLICT (scope, key) DO UPDATE
+	SET request_hash = EXCLUDED.request_hash,
+		status_code  = NULL,
+		header       = NULL,
+		body         = NULL,
+		created_at   = NOW()
+	WHERE idempotency_keys.created_at < NOW() - make_interval(secs => @expiry)
+	OR (idempotency_keys.status_code IS NULL AND
+		idempotency_keys.created_at < NOW() - make_interval(secs => @lock_timeout))
+	`
+
+	args := pgx.NamedArgs{
+		"scope":        scope,
+		"key":          key,
+		"request_hash": requestHash,
+		"expiry":       domain.IdempotencyExpiry.Seconds(),
+		"lock_timeout": domain.IdempotencyLockTimeout.Seconds(),
+	}
+
+	result, err := i.db.Exec(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim idempotency key: %v", err)
+	}
+
+	if result.RowsAffected() == 1 {
+		return nil, nil
+	}
+
+	query = `
+	SELECT request_hash, status_code, header, body FROM idempotency_keys
+	WHERE scope = @scope AND key = @key
+	`
+
+	var hash string
+	var statusCode *int
+	response := domain.IdempotentResponse{}
+	err = i.db.QueryRow(ctx, query, args).Scan(&hash, &statusCode, &response.Header, &response.Body)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrIdempotencyKeyInFlight
+		}
+		return nil, fmt.Errorf("failed to query idempotency key: %v", err)
+	}
+
+	if hash != requestHash {
+		return nil, domain.ErrIdempotencyKeyReused
+	}
+
+	if statusCode == nil {
+		return nil, domain.ErrIdempotencyKeyInFlight
+	}
+
+	response.StatusCode = *statusCode
+	return &response, nil
+}
+
+// Complete stores response of a claimed key.
+func (i idempotencyStore) Complete(ctx context.Context, scope string, key string, response domain.IdempotentResponse) error {
+	query := `
+	UPDATE idempotency_keys
+	SET status_code = @status_code,
+		header      = @header,
+		body        = @body
+	WHERE scope = @scope AND key = @key
+	`
+
+	args := pgx.NamedArgs{
+		"scope":       scope,
+		"key":         key,
+		"status_code": response.StatusCode,
+		"header":      response.Header,
+		"body":        response.Body,
+	}
+
+	_, err := i.db.Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %v", err)
+	}
+
+	return nil
+}
+
+// Release deletes a claimed key not completed yet.
+func (i idempotencyStore) Release(ctx context.Context, scope string, key string) error {
+	query := `
+	DELETE FROM idempotency_keys
+	WHERE scope = @scope AND key = @key AND status_code IS NULL
+	`
+
+	_, err := i.db.Exec(ctx, query, pgx.NamedArgs{"scope": scope, "key": key})
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteExpired deletes keys older than IdempotencyExpiry.
+func (i idempotencyStore) DeleteExpired(ctx context.Context) (int, error) {
+	query := `
+	DELETE FROM idempotency_keys
+	WHERE created_at < NOW() - make_interval(secs => @expiry)
+	`
+
+	result, err := i.db.Exec(ctx, query, pgx.NamedArgs{"expiry": domain.IdempotencyExpiry.Seconds()})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %v", err)
+	}
+
+	return int(result.RowsAffected()), nil
+}
@@ -0,0 +1,70 @@
+package postgres_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+	"github.com/mortezadadgar/ecommerce-api/postgres"
+)
+
+func TestIdempotencyService_Begin(t *testing.T) {
+	db := newTestDB(t, "idempotency_begin")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := postgres.NewIdempotencyStore(db)
+
+	response, err := store.Begin(ctx, "user:1", "key", "hash")
+	if err != nil || response != nil {
+		t.Fatalf("expected key to be claimed, got: %v, %v", response, err)
+	}
+
+	_, err = store.Begin(ctx, "user:1", "key", "hash")
+	if err != domain.ErrIdempotencyKeyInFlight {
+		t.Errorf("expected %q from Begin, got %q", domain.ErrIdempotencyKeyInFlight, err)
+	}
+
+	want := domain.IdempotentResponse{
+		StatusCode: 201,
+		Header:     map[string][]string{"Content-Type": {"application/json"}},
+		Body:       []byte(`{"id":1}`),
+	}
+
+	err = store.Complete(ctx, "user:1", "key", want)
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+
+	got, err := store.Begin(ctx, "user:1", "key", "hash")
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+
+	if got == nil || !reflect.DeepEqual(*got, want) {
+		t.Errorf("mismatch\n got: %#v\nwant: %#v", got, want)
+	}
+
+	_, err = store.Begin(ctx, "user:1", "key", "other")
+	if err != domain.ErrIdempotencyKeyReused {
+		t.Errorf("expected %q from Begin, got %q", domain.ErrIdempotencyKeyReused, err)
+	}
+
+	response, err = store.Begin(ctx, "user:2", "key", "other")
+	if err != nil || response != nil {
+		t.Errorf("expected key of another scope to be claimed, got: %v, %v", response, err)
+	}
+
+	err = store.Release(ctx, "user:2", "key")
+	if err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+
+	response, err = store.Begin(ctx, "user:2", "key", "hash")
+	if err != nil || response != nil {
+		t.Errorf("expected released key to be claimed, got: %v, %v", response, err)
+	}
+}
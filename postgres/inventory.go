@@ -0,0 +1,570 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// inventoryStore represents warehouses and stock ledger database.
+type inventoryStore struct {
+	db *pgxpool.Pool
+}
+
+// NewInventoryStore returns a new instance of InventoryStore.
+func NewInventoryStore(db *pgxpool.Pool) inventoryStore {
+	return inventoryStore{db: db}
+}
+
+// CreateWarehouse creates a new warehouse in database.
+func (i inventoryStore) CreateWarehouse(ctx context.Context, warehouse *domain.Warehouse) error {
+	query := `
+	INSERT INTO warehouses(code, name)
+	VALUES(@code, @name)
+	RETURNING id, created_at
+	`
+
+	args := pgx.NamedArgs{
+		"code": warehouse.Code,
+		"name": warehouse.Name,
+	}
+
+	err := i.db.QueryRow(ctx, query, args).Scan(&warehouse.ID, &warehouse.CreatedAt)
+	if err != nil {
+		pgErr := pgError(err)
+		if pgErr.Code == pgerrcode.UniqueViolation && pgErr.ConstraintName == "warehouses_code_key" {
+			return domain.ErrDuplicatedWarehouse
+		}
+		return fmt.Errorf("failed to insert warehouse: %v", err)
+	}
+
+	return nil
+}
+
+// GetWarehouse get warehouse by id from database.
+func (i inventoryStore) GetWarehouse(ctx context.Context, ID int) (domain.Warehouse, error) {
+	rows, err := i.db.Query(ctx, `SELECT * FROM warehouses WHERE id = @id`, pgx.NamedArgs{"id": ID})
+	if err != nil {
+		return domain.Warehouse{}, fmt.Errorf("failed to query warehouse: %v", err)
+	}
+
+	warehouse, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.Warehouse])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Warehouse{}, domain.ErrNoWarehousesFound
+		}
+		return domain.Warehouse{}, fmt.Errorf("failed to scan row of warehouse: %v", err)
+	}
+
+	return warehouse, nil
+}
+
+// ListWarehouses lists all warehouses.
+func (i inventoryStore) ListWarehouses(ctx context.Context) ([]domain.Warehouse, error) {
+	rows, err := i.db.Query(ctx, `SELECT * FROM warehouses ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query list warehouses: %v", err)
+	}
+
+	warehouses, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.Warehouse])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of warehouses: %v", err)
+	}
+
+	return warehouses, nil
+}
+
+// RecordMovements appends movements to the stock ledger in a single
+// transaction.
+func (i inventoryStore) RecordMovements(ctx context.Context, movements []domain.StockMovement) ([]domain.StockMovement, error) {
+	tx, err := i.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = recordMovements(ctx, tx, movements)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return movements, nil
+}
+
+// ListMovements lists stock movements with optional filter, latest first
+// unless sorted otherwise.
+func (i inventoryStore) ListMovements(ctx context.Context, filter domain.StockMovementFilter) ([]domain.StockMovement, error) {
+	if filter.Sort == "" {
+		filter.Sort = "id DESC"
+	}
+
+	query := `
+	SELECT * FROM stock_movements
+	WHERE (@type = '' OR type = @type)
+	AND (@reference = '' OR reference = @reference)
+	` + FormatAndInt("product_id", filter.ProductID) + `
+	` + FormatAndInt("warehouse_id", filter.WarehouseID) + `
+	` + FormatSort(filter.Sort) + `
+	` + FormatLimitOffset(filter.Limit, filter.Offset) + `
+	`
+
+	args := pgx.NamedArgs{
+		"type":      filter.Type,
+		"reference": filter.Reference,
+	}
+
+	rows, err := i.db.Query(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query list stock movements: %v", err)
+	}
+
+	movements, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.StockMovement])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of stock movements: %v", err)
+	}
+
+	if len(movements) == 0 {
+		return nil, domain.ErrNoStockMovementsFound
+	}
+
+	return movements, nil
+}
+
+// ProductStock returns on hand quantity of a product by warehouse and its
+// quantity reserved by carts.
+func (i inventoryStore) ProductStock(ctx context.Context, productID int) (domain.ProductStock, error) {
+	kind, err := productType(ctx, i.db, productID)
+	if err != nil {
+		return domain.ProductStock{}, err
+	}
+
+	if kind == domain.ProductTypeBundle {
+		return domain.ProductStock{}, domain.ErrBundleStockMovement
+	}
+
+	locations, err := locationStock(ctx, i.db, []int{productID})
+	if err != nil {
+		return domain.ProductStock{}, err
+	}
+
+	query := `
+	SELECT COALESCE(SUM(quantity), 0)::int FROM active_reservations
+	WHERE product_id = @product_id
+	`
+
+	var reserved int
+	err = i.db.QueryRow(ctx, query, pgx.NamedArgs{"product_id": productID}).Scan(&reserved)
+	if err != nil {
+		return domain.ProductStock{}, fmt.Errorf("failed to query reserved stock: %v", err)
+	}
+
+	if locations[productID] == nil {
+		locations[productID] = []domain.LocationStock{}
+	}
+
+	return domain.NewProductStock(productID, locations[productID], reserved), nil
+}
+
+// WarehouseStock returns on hand quantity of products kept in a warehouse.
+func (i inventoryStore) WarehouseStock(ctx context.Context, warehouseID int) ([]domain.LocationStock, error) {
+	_, err := i.GetWarehouse(ctx, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+	SELECT product_id, warehouse_id, SUM(quantity)::int AS on_hand
+	FROM stock_movements
+	WHERE warehouse_id = @warehouse_id
+	GROUP BY product_id, warehouse_id
+	HAVING SUM(quantity) <> 0
+	ORDER BY product_id
+	`
+
+	rows, err := i.db.Query(ctx, query, pgx.NamedArgs{"warehouse_id": warehouseID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query warehouse stock: %v", err)
+	}
+
+	stock, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.LocationStock])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of warehouse stock: %v", err)
+	}
+
+	return stock, nil
+}
+
+// locationStock returns on hand quantity of products by warehouse, ordered
+// by warehouse id.
+func locationStock(ctx context.Context, q querier, productIDs []int) (map[int][]domain.LocationStock, error) {
+	query := `
+	SELECT product_id, warehouse_id, SUM(quantity)::int AS on_hand
+	FROM stock_movements
+	WHERE product_id = ANY(@ids)
+	GROUP BY product_id, warehouse_id
+	HAVING SUM(quantity) <> 0
+	ORDER BY product_id, warehouse_id
+	`
+
+	rows, err := q.Query(ctx, query, pgx.NamedArgs{"ids": productIDs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query location stock: %v", err)
+	}
+
+	stock, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.LocationStock])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of location stock: %v", err)
+	}
+
+	locations := make(map[int][]domain.LocationStock)
+	for _, s := range stock {
+		locations[s.ProductID] = append(locations[s.ProductID], s)
+	}
+
+	return locations, nil
+}
+
+// recordMovements appends movements to the stock ledger and updates
+// quantity of their products accordingly, stock notifications are queued
+// for products crossing their reorder threshold or coming back in stock.
+// Stock received is allocated to backordered order lines. It returns ErrInsufficientStock when stock of a product in a warehouse
+// would go below zero.
+func recordMovements(ctx context.Context, q querier, movements []domain.StockMovement) error {
+	ids := movementProducts(movements)
+
+	err := lockStockProducts(ctx, q, ids)
+	if err != nil {
+		return err
+	}
+
+	before, err := stockLevels(ctx, q, ids)
+	if err != nil {
+		return err
+	}
+
+	err = applyMovements(ctx, q, movements)
+	if err != nil {
+		return err
+	}
+
+	var received []int
+	for _, m := range movements {
+		if m.Quantity > 0 && m.Type != domain.StockMovementTransfer {
+			received = append(received, m.ProductID)
+		}
+	}
+
+	if len(received) != 0 {
+		err = allocateBackorders(ctx, q, received)
+		if err != nil {
+			return err
+		}
+	}
+
+	after, err := stockLevels(ctx, q, ids)
+	if err != nil {
+		return err
+	}
+
+	return queueStockNotifications(ctx, q, before, after)
+}
+
+// movementProducts returns ids of products of movements.
+func movementProducts(movements []domain.StockMovement) []int {
+	seen := make(map[int]bool)
+	var ids []int
+	for _, m := range movements {
+		if !seen[m.ProductID] {
+			seen[m.ProductID] = true
+			ids = append(ids, m.ProductID)
+		}
+	}
+	return ids
+}
+
+// lockStockProducts locks products in order of id so concurrent movements
+// can not deadlock, bundles are rejected as their stock is derived.
+func lockStockProducts(ctx context.Context, q querier, ids []int) error {
+	query := `
+	SELECT id, type FROM products
+	WHERE id = ANY(@ids)
+	ORDER BY id
+	FOR UPDATE
+	`
+
+	rows, err := q.Query(ctx, query, pgx.NamedArgs{"ids": ids})
+	if err != nil {
+		return fmt.Errorf("failed to lock products: %v", err)
+	}
+
+	var locked int
+	var productID int
+	var kind string
+	_, err = pgx.ForEachRow(rows, []any{&productID, &kind}, func() error {
+		if kind == domain.ProductTypeBundle {
+			return domain.ErrBundleStockMovement
+		}
+		locked++
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrBundleStockMovement) {
+			return err
+		}
+		return fmt.Errorf("failed to scan rows of products: %v", err)
+	}
+
+	if locked != len(ids) {
+		return domain.ErrNoProductsFound
+	}
+
+	return nil
+}
+
+// applyMovements inserts movements of locked products and updates their
+// quantity, it returns ErrInsufficientStock when stock of a product in a
+// warehouse would go below zero.
+func applyMovements(ctx context.Context, q querier, movements []domain.StockMovement) error {
+	type location struct {
+		productID   int
+		warehouseID int
+	}
+
+	deltas := make(map[int]int)
+	locationDeltas := make(map[location]int)
+	for _, m := range movements {
+		deltas[m.ProductID] += m.Quantity
+		locationDeltas[location{m.ProductID, m.WarehouseID}] += m.Quantity
+	}
+
+	locations, err := locationStock(ctx, q, movementProducts(movements))
+	if err != nil {
+		return err
+	}
+
+	onHand := make(map[location]int)
+	for _, stock := range locations {
+		for _, s := range stock {
+			onHand[location{s.ProductID, s.WarehouseID}] = s.OnHand
+		}
+	}
+
+	for l, delta := range locationDeltas {
+		if delta < 0 && onHand[l]+delta < 0 {
+			return domain.ErrInsufficientStock
+		}
+	}
+
+	err = insertMovements(ctx, q, movements)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	UPDATE products
+	SET quantity   = quantity + @quantity,
+		updated_at = NOW(),
+		version    = version + 1
+	WHERE id = @id
+	`
+
+	for id, delta := range deltas {
+		if delta == 0 {
+			continue
+		}
+
+		_, err = q.Exec(ctx, query, pgx.NamedArgs{"id": id, "quantity": delta})
+		if err != nil {
+			return fmt.Errorf("failed to update product stock: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// allocateBackorders allocates stock of locked products not reserved by
+// carts to their backordered order lines, oldest orders first, by
+// recording sale movements of their orders.
+func allocateBackorders(ctx context.Context, q querier, productIDs []int) error {
+	query := `
+	SELECT l.id, l.order_id, l.product_id, l.backordered
+	FROM order_lines l
+	INNER JOIN orders o ON o.id = l.order_id
+	WHERE l.product_id = ANY(@ids) AND l.backordered > 0
+	ORDER BY o.created_at, l.id
+	FOR UPDATE OF l
+	`
+
+	rows, err := q.Query(ctx, query, pgx.NamedArgs{"ids": productIDs})
+	if err != nil {
+		return fmt.Errorf("failed to query backordered lines: %v", err)
+	}
+
+	backorders := make(map[int][]domain.BackorderedLine)
+	var ids []int
+	var line domain.BackorderedLine
+	dest := []any{&line.ID, &line.OrderID, &line.ProductID, &line.Backordered}
+	_, err = pgx.ForEachRow(rows, dest, func() error {
+		if backorders[line.ProductID] == nil {
+			ids = append(ids, line.ProductID)
+		}
+		backorders[line.ProductID] = append(backorders[line.ProductID], line)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan rows of backordered lines: %v", err)
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	levels, err := stockLevels(ctx, q, ids)
+	if err != nil {
+		return err
+	}
+
+	locations, err := locationStock(ctx, q, ids)
+	if err != nil {
+		return err
+	}
+
+	query = `
+	UPDATE order_lines
+	SET backordered = backordered - @quantity
+	WHERE id = @id
+	`
+
+	var movements []domain.StockMovement
+	for _, id := range ids {
+		allocated := domain.AllocateBackorders(levels[id].available, backorders[id])
+
+		for _, line := range backorders[id] {
+			quantity := allocated[line.ID]
+			if quantity == 0 {
+				continue
+			}
+
+			taken, err := domain.AllocateStock(quantity, locations[id])
+			if err != nil {
+				return err
+			}
+
+			for _, t := range taken {
+				movements = append(movements, domain.StockMovement{
+					ProductID:   id,
+					WarehouseID: t.WarehouseID,
+					Type:        domain.StockMovementSale,
+					Quantity:    -t.OnHand,
+					Reference:   domain.OrderStockReference(line.OrderID),
+					Note:        "backorder allocated",
+				})
+				locations[id] = takeLocation(locations[id], t)
+			}
+
+			_, err = q.Exec(ctx, query, pgx.NamedArgs{"id": line.ID, "quantity": quantity})
+			if err != nil {
+				return fmt.Errorf("failed to update backordered quantity: %v", err)
+			}
+		}
+	}
+
+	return applyMovements(ctx, q, movements)
+}
+
+// takeLocation returns locations with quantity taken out of its warehouse.
+func takeLocation(locations []domain.LocationStock, taken domain.LocationStock) []domain.LocationStock {
+	result := make([]domain.LocationStock, len(locations))
+	copy(result, locations)
+
+	for i := range result {
+		if result[i].WarehouseID == taken.WarehouseID {
+			result[i].OnHand -= taken.OnHand
+		}
+	}
+	return result
+}
+
+// adjustStock records an adjustment of stock of a product by delta,
+// increases go to the default warehouse and decreases are drawn from
+// warehouses in order of id.
+func adjustStock(ctx context.Context, q querier, productID int, delta int) error {
+	adjustment := domain.StockMovement{
+		ProductID:   productID,
+		WarehouseID: domain.DefaultWarehouseID,
+		Type:        domain.StockMovementAdjustment,
+		Quantity:    delta,
+		Note:        "quantity updated",
+	}
+
+	if delta > 0 {
+		return recordMovements(ctx, q, []domain.StockMovement{adjustment})
+	}
+
+	locations, err := locationStock(ctx, q, []int{productID})
+	if err != nil {
+		return err
+	}
+
+	taken, err := domain.AllocateStock(-delta, locations[productID])
+	if err != nil {
+		return err
+	}
+
+	movements := make([]domain.StockMovement, 0, len(taken))
+	for _, t := range taken {
+		adjustment.WarehouseID = t.WarehouseID
+		adjustment.Quantity = -t.OnHand
+		movements = append(movements, adjustment)
+	}
+
+	return recordMovements(ctx, q, movements)
+}
+
+// insertMovements inserts movements into the stock ledger without touching
+// quantity of their products.
+func insertMovements(ctx context.Context, q querier, movements []domain.StockMovement) error {
+	query := `
+	INSERT INTO stock_movements(product_id, warehouse_id, type, quantity,
+		reference, note, created_by)
+	VALUES(@product_id, @warehouse_id, @type, @quantity,
+		@reference, @note, @created_by)
+	RETURNING id, created_at
+	`
+
+	for i := range movements {
+		m := &movements[i]
+
+		args := pgx.NamedArgs{
+			"product_id":   m.ProductID,
+			"warehouse_id": m.WarehouseID,
+			"type":         m.Type,
+			"quantity":     m.Quantity,
+			"reference":    m.Reference,
+			"note":         m.Note,
+			"created_by":   m.CreatedBy,
+		}
+
+		err := q.QueryRow(ctx, query, args).Scan(&m.ID, &m.CreatedAt)
+		if err != nil {
+			pgErr := pgError(err)
+			if pgErr.Code == pgerrcode.ForeignKeyViolation &&
+				pgErr.ConstraintName == "stock_movements_warehouse_id_fkey" {
+				return domain.ErrNoWarehousesFound
+			}
+			return fmt.Errorf("failed to insert stock movement: %v", err)
+		}
+	}
+
+	return nil
+}
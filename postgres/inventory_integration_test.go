@@ -0,0 +1,106 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+	"github.com/mortezadadgar/ecommerce-api/postgres"
+)
+
+func TestInventoryService_Ledger(t *testing.T) {
+	db := newCartTestDB(t, "inventory_ledger")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := postgres.NewInventoryStore(db)
+
+	warehouse := domain.WarehouseCreate{Code: "east", Name: "East"}.CreateModel()
+	err := store.CreateWarehouse(ctx, &warehouse)
+	if err != nil {
+		t.Fatalf("CreateWarehouse: %v", err)
+	}
+
+	transfer := domain.StockMovementCreate{
+		ProductID:     1,
+		WarehouseID:   domain.DefaultWarehouseID,
+		ToWarehouseID: warehouse.ID,
+		Type:          domain.StockMovementTransfer,
+		Quantity:      7,
+	}
+	_, err = store.RecordMovements(ctx, transfer.CreateModel(1))
+	if err != nil {
+		t.Fatalf("RecordMovements: %v", err)
+	}
+
+	transfer.Quantity = 4
+	_, err = store.RecordMovements(ctx, transfer.CreateModel(1))
+	if err != domain.ErrInsufficientStock {
+		t.Errorf("expected %q from RecordMovements, got %q", domain.ErrInsufficientStock, err)
+	}
+
+	_, err = postgres.NewCartStore(db).AddItem(ctx, domain.CartOwner{UserID: 1}, domain.CartItem{ProductID: 1, Quantity: 5})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	order, err := postgres.NewOrderStore(db).Checkout(ctx, 1, domain.CheckoutDetails{})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+
+	stock, err := store.ProductStock(ctx, 1)
+	if err != nil {
+		t.Fatalf("ProductStock: %v", err)
+	}
+
+	// sales are drawn from the default warehouse first.
+	if stock.OnHand != 5 || len(stock.Locations) != 1 || stock.Locations[0].WarehouseID != warehouse.ID {
+		t.Errorf("expected 5 left in warehouse %d, got: %#v", warehouse.ID, stock)
+	}
+
+	_, err = postgres.NewOrderStore(db).Cancel(ctx, order.ID, 1)
+	if err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+
+	stock, err = store.ProductStock(ctx, 1)
+	if err != nil {
+		t.Fatalf("ProductStock: %v", err)
+	}
+
+	if stock.OnHand != 10 || len(stock.Locations) != 2 {
+		t.Errorf("expected sale given back to its warehouses, got: %#v", stock)
+	}
+
+	product, err := postgres.NewProductStore(db).GetByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+
+	if product.Quantity != stock.OnHand {
+		t.Errorf("expected quantity of %d, got: %d", stock.OnHand, product.Quantity)
+	}
+
+	quantity := 2
+	_, err = postgres.NewProductStore(db).Update(ctx, 1, domain.ProductUpdate{Quantity: &quantity, Version: product.Version})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	movements, err := store.ListMovements(ctx, domain.StockMovementFilter{ProductID: 1, Type: domain.StockMovementAdjustment})
+	if err != nil {
+		t.Fatalf("ListMovements: %v", err)
+	}
+
+	total := 0
+	for _, m := range movements {
+		total += m.Quantity
+	}
+
+	if total != -8 {
+		t.Errorf("expected adjustments of %d, got: %d", -8, total)
+	}
+}
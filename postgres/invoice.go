@@ -0,0 +1,175 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// invoiceStore represents invoices database.
+type invoiceStore struct {
+	db *pgxpool.Pool
+}
+
+// NewInvoiceStore returns a new instance of InvoiceStore.
+func NewInvoiceStore(db *pgxpool.Pool) invoiceStore {
+	return invoiceStore{db: db}
+}
+
+// GetByID get invoice by id from database.
+func (i invoiceStore) GetByID(ctx context.Context, ID int) (domain.Invoice, error) {
+	invoices, err := i.List(ctx, domain.InvoiceFilter{ID: ID})
+	if err != nil {
+		return domain.Invoice{}, err
+	}
+
+	return invoices[0], nil
+}
+
+// List lists invoices with optional filter.
+func (i invoiceStore) List(ctx context.Context, filter domain.InvoiceFilter) ([]domain.Invoice, error) {
+	query := `
+	SELECT * FROM invoices
+	WHERE (@kind = '' OR kind = @kind)
+	AND (@user_id = 0 OR order_id IN (SELECT id FROM orders WHERE user_id = @user_id))
+	` + FormatAndInt("id", filter.ID) + `
+	` + FormatAndInt("order_id", filter.OrderID) + `
+	` + FormatSort(filter.Sort) + `
+	` + FormatLimitOffset(filter.Limit, filter.Offset) + `
+	`
+
+	rows, err := i.db.Query(ctx, query, pgx.NamedArgs{"kind": filter.Kind, "user_id": filter.UserID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query list invoices: %v", err)
+	}
+
+	invoices, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.Invoice])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of invoices: %v", err)
+	}
+
+	if len(invoices) == 0 {
+		return nil, domain.ErrNoInvoicesFound
+	}
+
+	return invoices, nil
+}
+
+// issueInvoice issues invoice of a locked order unless it has one.
+func issueInvoice(ctx context.Context, q querier, order domain.Order) error {
+	var exists bool
+	err := q.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM invoices WHERE order_id = @order_id AND kind = @kind)`,
+		pgx.NamedArgs{"order_id": order.ID, "kind": domain.InvoiceKindInvoice}).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to query invoice: %v", err)
+	}
+
+	if exists {
+		return nil
+	}
+
+	orders := []domain.Order{order}
+	err = fillOrders(ctx, q, orders)
+	if err != nil {
+		return err
+	}
+
+	invoice := domain.NewInvoice(orders[0])
+	return insertInvoice(ctx, q, &invoice)
+}
+
+// issueCreditNote issues a credit note of what is refunded on payments of
+// order and not credited yet, orders without invoice are left alone.
+func issueCreditNote(ctx context.Context, q querier, orderID int, reason string) error {
+	rows, err := q.Query(ctx, `SELECT * FROM invoices WHERE order_id = @order_id AND kind = @kind FOR UPDATE`,
+		pgx.NamedArgs{"order_id": orderID, "kind": domain.InvoiceKindInvoice})
+	if err != nil {
+		return fmt.Errorf("failed to query invoice: %v", err)
+	}
+
+	invoice, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.Invoice])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to scan row of invoice: %v", err)
+	}
+
+	query := `
+	SELECT
+		(SELECT COALESCE(SUM(refunded), 0) FROM payments WHERE order_id = @order_id) -
+		(SELECT COALESCE(SUM(total), 0) FROM invoices WHERE order_id = @order_id AND kind = @kind)
+	`
+
+	var due int
+	err = q.QueryRow(ctx, query, pgx.NamedArgs{"order_id": orderID, "kind": domain.InvoiceKindCreditNote}).Scan(&due)
+	if err != nil {
+		return fmt.Errorf("failed to query credit due: %v", err)
+	}
+
+	if due <= 0 {
+		return nil
+	}
+
+	note := invoice.CreditNote(due, reason)
+	return insertInvoice(ctx, q, &note)
+}
+
+// insertInvoice numbers invoice by next number of its kind in current year
+// and inserts it, numbers are taken in transaction of the invoice so none
+// is lost.
+func insertInvoice(ctx context.Context, q querier, invoice *domain.Invoice) error {
+	query := `
+	INSERT INTO invoice_sequences(kind, year, last)
+	VALUES(@kind, EXTRACT(YEAR FROM NOW())::int, 1)
+	ON CONFLICT (kind, year) DO UPDATE SET last = invoice_sequences.last + 1
+	RETURNING year, last
+	`
+
+	var year, sequence int
+	err := q.QueryRow(ctx, query, pgx.NamedArgs{"kind": invoice.Kind}).Scan(&year, &sequence)
+	if err != nil {
+		return fmt.Errorf("failed to update invoice sequence: %v", err)
+	}
+
+	invoice.SetNumber(year, sequence)
+
+	query = `
+	INSERT INTO invoices(order_id, invoice_id, kind, number, year, sequence, subtotal, discount, tax,
+		tax_inclusive, shipping, total, currency, lines, billing_address, shipping_address, reason)
+	VALUES(@order_id, @invoice_id, @kind, @number, @year, @sequence, @subtotal, @discount, @tax,
+		@tax_inclusive, @shipping, @total, @currency, @lines, @billing_address, @shipping_address, @reason)
+	RETURNING id, issued_at
+	`
+
+	args := pgx.NamedArgs{
+		"order_id":         invoice.OrderID,
+		"invoice_id":       invoice.InvoiceID,
+		"kind":             invoice.Kind,
+		"number":           invoice.Number,
+		"year":             invoice.Year,
+		"sequence":         invoice.Sequence,
+		"subtotal":         invoice.Subtotal,
+		"discount":         invoice.Discount,
+		"tax":              invoice.Tax,
+		"tax_inclusive":    invoice.TaxInclusive,
+		"shipping":         invoice.Shipping,
+		"total":            invoice.Total,
+		"currency":         invoice.Currency,
+		"lines":            invoice.Lines,
+		"billing_address":  invoice.BillingAddress,
+		"shipping_address": invoice.ShippingAddress,
+		"reason":           invoice.Reason,
+	}
+
+	err = q.QueryRow(ctx, query, args).Scan(&invoice.ID, &invoice.IssuedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert invoice: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package postgres_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+	"github.com/mortezadadgar/ecommerce-api/postgres"
+)
+
+func TestInvoiceService_Issue(t *testing.T) {
+	db := newCartTestDB(t, "invoices_issue")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	orders := postgres.NewOrderStore(db)
+	payments := postgres.NewPaymentStore(db)
+	store := postgres.NewInvoiceStore(db)
+
+	// pay places and pays an order through payment events.
+	pay := func(intentID string) domain.Order {
+		t.Helper()
+
+		_, err := postgres.NewCartStore(db).AddItem(ctx, domain.CartOwner{UserID: 1}, domain.CartItem{ProductID: 1, Quantity: 1})
+		if err != nil {
+			t.Fatalf("AddItem: %v", err)
+		}
+
+		order, err := orders.Checkout(ctx, 1, domain.CheckoutDetails{})
+		if err != nil {
+			t.Fatalf("Checkout: %v", err)
+		}
+
+		payment := domain.NewPayment(order, "mock", domain.PaymentIntent{ID: intentID, Status: domain.PaymentStatusAuthorized, Amount: order.Total})
+		err = payments.Create(ctx, &payment)
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		err = payments.HandleEvent(ctx, "mock", domain.PaymentEvent{ID: "evt_" + intentID, Type: domain.PaymentEventSucceeded,
+			IntentID: intentID, Amount: order.Total})
+		if err != nil {
+			t.Fatalf("HandleEvent: %v", err)
+		}
+
+		return order
+	}
+
+	first := pay("pi_1")
+	second := pay("pi_2")
+
+	year := time.Now().Year()
+	for i, order := range []domain.Order{first, second} {
+		invoices, err := store.List(ctx, domain.InvoiceFilter{OrderID: order.ID})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+
+		want := fmt.Sprintf("INV-%d-%06d", year, i+1)
+		if len(invoices) != 1 || invoices[0].Number != want || invoices[0].Total != order.Total {
+			t.Errorf("expected invoice %s of %d, got: %#v", want, order.Total, invoices)
+		}
+	}
+
+	invoices, err := store.List(ctx, domain.InvoiceFilter{UserID: 1})
+	if err != nil || len(invoices) != 2 {
+		t.Errorf("expected invoices of user, got: %#v, %v", invoices, err)
+	}
+
+	_, err = store.List(ctx, domain.InvoiceFilter{UserID: 2})
+	if err != domain.ErrNoInvoicesFound {
+		t.Errorf("expected %q from List of other user, got %q", domain.ErrNoInvoicesFound, err)
+	}
+
+	// orders are invoiced once.
+	_, err = orders.UpdateStatus(ctx, first.ID, domain.OrderStatusFulfilling, 0, "")
+	if err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+
+	for i, refunded := range []int{100, first.Total} {
+		err = payments.HandleEvent(ctx, "mock", domain.PaymentEvent{ID: fmt.Sprintf("evt_refund_%d", i),
+			Type: domain.PaymentEventRefunded, IntentID: "pi_1", Amount: refunded})
+		if err != nil {
+			t.Fatalf("HandleEvent: %v", err)
+		}
+	}
+
+	notes, err := store.List(ctx, domain.InvoiceFilter{OrderID: first.ID, Kind: domain.InvoiceKindCreditNote, Sort: "id"})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+
+	if len(notes) != 2 || notes[0].Total != 100 || notes[1].Total != first.Total-100 {
+		t.Fatalf("expected credit notes of refunds, got: %#v", notes)
+	}
+
+	want := fmt.Sprintf("CN-%d-%06d", year, 2)
+	if notes[1].Number != want || notes[1].InvoiceID == nil {
+		t.Errorf("expected credit note %s against invoice, got: %#v", want, notes[1])
+	}
+
+	_, err = store.List(ctx, domain.InvoiceFilter{OrderID: second.ID, Kind: domain.InvoiceKindCreditNote})
+	if err != domain.ErrNoInvoicesFound {
+		t.Errorf("expected %q from List, got %q", domain.ErrNoInvoicesFound, err)
+	}
+}
@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// lockNamespace keeps advisory locks of the application apart from locks
+// taken by other applications sharing the database.
+const lockNamespace = 0x65636f6d
+
+// locker represents a lock shared by instances through advisory locks.
+type locker struct {
+	db *pgxpool.Pool
+}
+
+// NewLocker returns a new instance of Locker.
+func NewLocker(db *pgxpool.Pool) locker {
+	return locker{db: db}
+}
+
+// TryRun runs fn holding advisory lock of name, the lock is held by a
+// transaction so it is released even when the instance holding it dies.
+func (l locker) TryRun(ctx context.Context, name string, fn func(ctx context.Context) error) (bool, error) {
+	tx, err := l.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	var locked bool
+	err = tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock(@namespace, hashtext(@name))`,
+		pgx.NamedArgs{"namespace": lockNamespace, "name": name}).Scan(&locked)
+	if err != nil {
+		return false, fmt.Errorf("failed to take advisory lock: %v", err)
+	}
+
+	if !locked {
+		return false, nil
+	}
+
+	err = fn(ctx)
+	if err != nil {
+		return true, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return true, fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return true, nil
+}
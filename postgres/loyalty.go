@@ -0,0 +1,446 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// loyaltyStore represents loyalty points database.
+type loyaltyStore struct {
+	db *pgxpool.Pool
+}
+
+// NewLoyaltyStore returns a new instance of LoyaltyStore.
+func NewLoyaltyStore(db *pgxpool.Pool) loyaltyStore {
+	return loyaltyStore{db: db}
+}
+
+// GetRules returns earn rules of loyalty points.
+func (l loyaltyStore) GetRules(ctx context.Context) (domain.LoyaltyRules, error) {
+	return getLoyaltyRules(ctx, l.db)
+}
+
+// SetRules replaces earn rules of loyalty points.
+func (l loyaltyStore) SetRules(ctx context.Context, rules domain.LoyaltyRules) (domain.LoyaltyRules, error) {
+	tx, err := l.db.Begin(ctx)
+	if err != nil {
+		return domain.LoyaltyRules{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `DELETE FROM loyalty_earn_rules`)
+	if err != nil {
+		return domain.LoyaltyRules{}, fmt.Errorf("failed to delete loyalty earn rules: %v", err)
+	}
+
+	query := `
+	INSERT INTO loyalty_earn_rules(category_id, points_per_unit)
+	VALUES(@category_id, @points_per_unit)
+	`
+
+	if rules.PointsPerUnit > 0 {
+		_, err = tx.Exec(ctx, query, pgx.NamedArgs{"category_id": nil, "points_per_unit": rules.PointsPerUnit})
+		if err != nil {
+			return domain.LoyaltyRules{}, fmt.Errorf("failed to insert loyalty earn rule: %v", err)
+		}
+	}
+
+	for _, bonus := range rules.BonusCategories {
+		args := pgx.NamedArgs{
+			"category_id":     bonus.CategoryID,
+			"points_per_unit": bonus.PointsPerUnit,
+		}
+
+		_, err = tx.Exec(ctx, query, args)
+		if err != nil {
+			pgErr := pgError(err)
+			if pgErr.Code == pgerrcode.ForeignKeyViolation && pgErr.ConstraintName == "loyalty_earn_rules_category_id_fkey" {
+				return domain.LoyaltyRules{}, domain.ErrLoyaltyInvalidCategory
+			}
+			return domain.LoyaltyRules{}, fmt.Errorf("failed to insert loyalty earn rule: %v", err)
+		}
+	}
+
+	rules, err = getLoyaltyRules(ctx, tx)
+	if err != nil {
+		return domain.LoyaltyRules{}, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.LoyaltyRules{}, fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return rules, nil
+}
+
+// Get returns loyalty points of user with its transactions from newest,
+// users without any have no balance.
+func (l loyaltyStore) Get(ctx context.Context, userID int) (domain.LoyaltyAccount, error) {
+	query := `
+	SELECT id, order_id, kind, points, balance, note, created_at FROM loyalty_transactions
+	WHERE user_id = @user_id
+	ORDER BY id DESC
+	`
+
+	rows, err := l.db.Query(ctx, query, pgx.NamedArgs{"user_id": userID})
+	if err != nil {
+		return domain.LoyaltyAccount{}, fmt.Errorf("failed to query list loyalty transactions: %v", err)
+	}
+
+	transactions, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.LoyaltyTransaction])
+	if err != nil {
+		return domain.LoyaltyAccount{}, fmt.Errorf("failed to scan rows of loyalty transactions: %v", err)
+	}
+
+	account := domain.LoyaltyAccount{
+		UserID:       userID,
+		Currency:     domain.PaymentCurrency,
+		Transactions: transactions,
+	}
+
+	if len(transactions) > 0 {
+		account.Balance = transactions[0].Balance
+		account.Value = account.Balance * domain.LoyaltyPointValue
+	}
+
+	return account, nil
+}
+
+// ExpirePoints expires what is left of points earned or restored before,
+// each user is recorded an expire transaction.
+func (l loyaltyStore) ExpirePoints(ctx context.Context, before time.Time) (int, error) {
+	tx, err := l.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+	SELECT DISTINCT user_id FROM loyalty_transactions
+	WHERE remaining > 0 AND created_at < @before
+	ORDER BY user_id
+	`
+
+	rows, err := tx.Query(ctx, query, pgx.NamedArgs{"before": before})
+	if err != nil {
+		return 0, fmt.Errorf("failed to query expired loyalty points: %v", err)
+	}
+
+	userIDs, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan rows of expired loyalty points: %v", err)
+	}
+
+	query = `
+	WITH expired AS (
+		UPDATE loyalty_transactions t
+		SET remaining = 0
+		FROM (
+			SELECT id, remaining FROM loyalty_transactions
+			WHERE user_id = @user_id AND remaining > 0 AND created_at < @before
+			FOR UPDATE
+		) old
+		WHERE t.id = old.id
+		RETURNING old.remaining
+	)
+	SELECT COALESCE(SUM(remaining), 0) FROM expired
+	`
+
+	var expired int
+	for _, userID := range userIDs {
+		_, err = lockLoyalty(ctx, tx, userID)
+		if err != nil {
+			return 0, err
+		}
+
+		var points int
+		err = tx.QueryRow(ctx, query, pgx.NamedArgs{"user_id": userID, "before": before}).Scan(&points)
+		if err != nil {
+			return 0, fmt.Errorf("failed to expire loyalty points: %v", err)
+		}
+
+		if points == 0 {
+			continue
+		}
+
+		err = insertLoyalty(ctx, tx, userID, domain.LoyaltyExpire, -points, nil, "points expired")
+		if err != nil {
+			return 0, err
+		}
+		expired++
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return expired, nil
+}
+
+// getLoyaltyRules returns earn rules of loyalty points.
+func getLoyaltyRules(ctx context.Context, q querier) (domain.LoyaltyRules, error) {
+	query := `
+	SELECT COALESCE(category_id, 0), points_per_unit FROM loyalty_earn_rules
+	ORDER BY category_id NULLS FIRST
+	`
+
+	rows, err := q.Query(ctx, query)
+	if err != nil {
+		return domain.LoyaltyRules{}, fmt.Errorf("failed to query list loyalty earn rules: %v", err)
+	}
+
+	rules := domain.LoyaltyRules{BonusCategories: []domain.LoyaltyBonus{}}
+	var bonus domain.LoyaltyBonus
+	_, err = pgx.ForEachRow(rows, []any{&bonus.CategoryID, &bonus.PointsPerUnit}, func() error {
+		if bonus.CategoryID == 0 {
+			rules.PointsPerUnit = bonus.PointsPerUnit
+		} else {
+			rules.BonusCategories = append(rules.BonusCategories, bonus)
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.LoyaltyRules{}, fmt.Errorf("failed to scan rows of loyalty earn rules: %v", err)
+	}
+
+	return rules, nil
+}
+
+// lockLoyalty locks user to append to its loyalty points ledger and
+// returns its balance.
+func lockLoyalty(ctx context.Context, q querier, userID int) (int, error) {
+	err := q.QueryRow(ctx, `SELECT id FROM users WHERE id = @user_id FOR UPDATE`,
+		pgx.NamedArgs{"user_id": userID}).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, domain.ErrNoUsersFound
+		}
+		return 0, fmt.Errorf("failed to lock user: %v", err)
+	}
+
+	query := `
+	SELECT balance FROM loyalty_transactions
+	WHERE user_id = @user_id
+	ORDER BY id DESC
+	LIMIT 1
+	`
+
+	var balance int
+	err = q.QueryRow(ctx, query, pgx.NamedArgs{"user_id": userID}).Scan(&balance)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("failed to query loyalty balance: %v", err)
+	}
+
+	return balance, nil
+}
+
+// insertLoyalty appends points to loyalty ledger of user, points added are
+// left to be redeemed. ErrLoyaltyBalance is returned when balance would go
+// negative.
+func insertLoyalty(ctx context.Context, q querier, userID int, kind string, points int, orderID *int, note string) error {
+	balance, err := lockLoyalty(ctx, q, userID)
+	if err != nil {
+		return err
+	}
+
+	if balance+points < 0 {
+		return domain.ErrLoyaltyBalance
+	}
+
+	var remaining int
+	if points > 0 {
+		remaining = points
+	}
+
+	query := `
+	INSERT INTO loyalty_transactions(user_id, order_id, kind, points, balance, remaining, note)
+	VALUES(@user_id, @order_id, @kind, @points, @balance, @remaining, @note)
+	`
+
+	args := pgx.NamedArgs{
+		"user_id":   userID,
+		"order_id":  orderID,
+		"kind":      kind,
+		"points":    points,
+		"balance":   balance + points,
+		"remaining": remaining,
+		"note":      note,
+	}
+
+	_, err = q.Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("failed to insert loyalty transaction: %v", err)
+	}
+
+	return nil
+}
+
+// redeemPoints takes points of user off what is left of its oldest earned
+// points and records their redemption on order.
+func redeemPoints(ctx context.Context, q querier, order domain.Order, points int) error {
+	balance, err := lockLoyalty(ctx, q, order.UserID)
+	if err != nil {
+		return err
+	}
+
+	if balance < points {
+		return domain.ErrLoyaltyBalance
+	}
+
+	err = takePoints(ctx, q, order, points)
+	if err != nil {
+		return err
+	}
+
+	return insertLoyalty(ctx, q, order.UserID, domain.LoyaltyRedeem, -points, &order.ID, "")
+}
+
+// takePoints takes points off what is left of points of user of a locked
+// loyalty ledger, points earned by order are taken first and then the
+// oldest ones.
+func takePoints(ctx context.Context, q querier, order domain.Order, points int) error {
+	query := `
+	WITH lots AS (
+		SELECT id, remaining, SUM(remaining) OVER (
+			ORDER BY CASE WHEN order_id = @order_id AND kind = @kind THEN 0 ELSE 1 END, id
+		) - remaining AS taken
+		FROM loyalty_transactions
+		WHERE user_id = @user_id AND remaining > 0
+	)
+	UPDATE loyalty_transactions t
+	SET remaining = t.remaining - LEAST(lots.remaining, @points - lots.taken)
+	FROM lots
+	WHERE t.id = lots.id AND lots.taken < @points
+	`
+
+	args := pgx.NamedArgs{
+		"user_id":  order.UserID,
+		"order_id": order.ID,
+		"kind":     domain.LoyaltyEarn,
+		"points":   points,
+	}
+
+	_, err := q.Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("failed to take loyalty points: %v", err)
+	}
+
+	return nil
+}
+
+// awardPoints awards points of a paid order to its user by earn rules.
+func awardPoints(ctx context.Context, q querier, order domain.Order) error {
+	rules, err := getLoyaltyRules(ctx, q)
+	if err != nil {
+		return err
+	}
+
+	if rules.PointsPerUnit == 0 && len(rules.BonusCategories) == 0 {
+		return nil
+	}
+
+	orders := []domain.Order{order}
+	err = fillOrders(ctx, q, orders)
+	if err != nil {
+		return err
+	}
+
+	productIDs := make([]int, 0, len(orders[0].Lines))
+	for _, line := range orders[0].Lines {
+		if line.ProductID != nil {
+			productIDs = append(productIDs, *line.ProductID)
+		}
+	}
+
+	rows, err := q.Query(ctx, `SELECT id, category_id FROM products WHERE id = ANY(@ids)`,
+		pgx.NamedArgs{"ids": productIDs})
+	if err != nil {
+		return fmt.Errorf("failed to query product categories: %v", err)
+	}
+
+	categories := make(map[int]int)
+	var productID, categoryID int
+	_, err = pgx.ForEachRow(rows, []any{&productID, &categoryID}, func() error {
+		categories[productID] = categoryID
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan rows of product categories: %v", err)
+	}
+
+	points := rules.Points(orders[0], categories)
+	if points <= 0 {
+		return nil
+	}
+
+	return insertLoyalty(ctx, q, order.UserID, domain.LoyaltyEarn, points, &order.ID, "")
+}
+
+// reverseLoyalty gives points redeemed on a cancelled or refunded order
+// back and takes points earned by it back. Earned points already redeemed
+// are taken off the rest of balance of user, as much as it has.
+func reverseLoyalty(ctx context.Context, q querier, order domain.Order) error {
+	_, err := lockLoyalty(ctx, q, order.UserID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	SELECT COALESCE(-SUM(points), 0) FROM loyalty_transactions
+	WHERE order_id = @order_id AND kind IN ('redeem', 'restore')
+	`
+
+	var redeemed int
+	err = q.QueryRow(ctx, query, pgx.NamedArgs{"order_id": order.ID}).Scan(&redeemed)
+	if err != nil {
+		return fmt.Errorf("failed to query loyalty redemptions: %v", err)
+	}
+
+	if redeemed > 0 {
+		err = insertLoyalty(ctx, q, order.UserID, domain.LoyaltyRestore, redeemed, &order.ID, "order "+order.Status)
+		if err != nil {
+			return err
+		}
+	}
+
+	query = `
+	SELECT COALESCE(SUM(points), 0) FROM loyalty_transactions
+	WHERE order_id = @order_id AND kind IN ('earn', 'revoke')
+	`
+
+	var earned int
+	err = q.QueryRow(ctx, query, pgx.NamedArgs{"order_id": order.ID}).Scan(&earned)
+	if err != nil {
+		return fmt.Errorf("failed to query loyalty earnings: %v", err)
+	}
+
+	balance, err := lockLoyalty(ctx, q, order.UserID)
+	if err != nil {
+		return err
+	}
+
+	revoked := earned
+	if revoked > balance {
+		revoked = balance
+	}
+
+	if revoked <= 0 {
+		return nil
+	}
+
+	err = takePoints(ctx, q, order, revoked)
+	if err != nil {
+		return err
+	}
+
+	return insertLoyalty(ctx, q, order.UserID, domain.LoyaltyRevoke, -revoked, &order.ID, "order "+order.Status)
+}
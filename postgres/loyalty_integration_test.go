@@ -0,0 +1,213 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+	"github.com/mortezadadgar/ecommerce-api/postgres"
+)
+
+func TestLoyaltyService_Rules(t *testing.T) {
+	db := newCartTestDB(t, "loyalty_rules")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := postgres.NewLoyaltyStore(db)
+
+	rules, err := store.GetRules(ctx)
+	if err != nil {
+		t.Fatalf("GetRules: %v", err)
+	}
+
+	if rules.PointsPerUnit != 0 || len(rules.BonusCategories) != 0 {
+		t.Errorf("expected no rules, got: %+v", rules)
+	}
+
+	_, err = store.SetRules(ctx, domain.LoyaltyRules{
+		PointsPerUnit:   1,
+		BonusCategories: []domain.LoyaltyBonus{{CategoryID: 2, PointsPerUnit: 1}},
+	})
+	if err != domain.ErrLoyaltyInvalidCategory {
+		t.Errorf("expected ErrLoyaltyInvalidCategory, got: %v", err)
+	}
+
+	rules, err = store.SetRules(ctx, domain.LoyaltyRules{
+		PointsPerUnit:   1,
+		BonusCategories: []domain.LoyaltyBonus{{CategoryID: 1, PointsPerUnit: 4}},
+	})
+	if err != nil {
+		t.Fatalf("SetRules: %v", err)
+	}
+
+	if rules.PointsPerUnit != 1 || len(rules.BonusCategories) != 1 || rules.BonusCategories[0].PointsPerUnit != 4 {
+		t.Errorf("expected base and bonus rules, got: %+v", rules)
+	}
+}
+
+func TestLoyaltyService_EarnAndRedeem(t *testing.T) {
+	db := newCartTestDB(t, "loyalty_earn_redeem")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := postgres.NewLoyaltyStore(db)
+	_, err := store.SetRules(ctx, domain.LoyaltyRules{
+		PointsPerUnit:   1,
+		BonusCategories: []domain.LoyaltyBonus{{CategoryID: 1, PointsPerUnit: 4}},
+	})
+	if err != nil {
+		t.Fatalf("SetRules: %v", err)
+	}
+
+	product := domain.Product{SKU: "SKU-2", Name: "product2", CategoryID: 1, Price: 1000, Quantity: 5}
+	err = postgres.NewProductStore(db).Create(ctx, &product)
+	if err != nil {
+		t.Fatalf("product Create: %v", err)
+	}
+
+	carts := postgres.NewCartStore(db)
+	orders := postgres.NewOrderStore(db)
+
+	_, err = carts.AddItem(ctx, domain.CartOwner{UserID: 1}, domain.CartItem{ProductID: product.ID, Quantity: 2})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	order, err := orders.Checkout(ctx, 1, domain.CheckoutDetails{})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+
+	_, err = orders.UpdateStatus(ctx, order.ID, domain.OrderStatusPaid, 0, "")
+	if err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+
+	// 20.00 of a bonus category earns 5 points per unit.
+	account, err := store.Get(ctx, 1)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	if account.Balance != 100 || len(account.Transactions) != 1 || account.Transactions[0].Kind != domain.LoyaltyEarn {
+		t.Fatalf("expected %d points earned, got: %+v", 100, account)
+	}
+
+	_, err = carts.AddItem(ctx, domain.CartOwner{UserID: 1}, domain.CartItem{ProductID: product.ID, Quantity: 1})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	_, err = orders.Checkout(ctx, 1, domain.CheckoutDetails{RedeemPoints: 150})
+	if err != domain.ErrLoyaltyBalance {
+		t.Errorf("expected ErrLoyaltyBalance, got: %v", err)
+	}
+
+	redeemed, err := orders.Checkout(ctx, 1, domain.CheckoutDetails{RedeemPoints: 60})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+
+	if redeemed.Total != 940 || redeemed.LoyaltyDiscount != 60 || redeemed.Discount != 60 {
+		t.Errorf("expected 60 points off order, got: %+v", redeemed)
+	}
+
+	account, err = store.Get(ctx, 1)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	if account.Balance != 40 || account.Transactions[0].Points != -60 {
+		t.Errorf("expected %d points left, got: %+v", 40, account)
+	}
+
+	_, err = orders.Cancel(ctx, redeemed.ID, 1)
+	if err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+
+	_, err = orders.UpdateStatus(ctx, order.ID, domain.OrderStatusRefunded, 0, "")
+	if err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+
+	// redeemed points are given back, earned points already redeemed are
+	// taken off the restored ones.
+	account, err = store.Get(ctx, 1)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	if account.Balance != 0 || len(account.Transactions) != 4 {
+		t.Fatalf("expected %d points left, got: %+v", 0, account)
+	}
+
+	if account.Transactions[0].Kind != domain.LoyaltyRevoke || account.Transactions[0].Points != -100 ||
+		account.Transactions[1].Kind != domain.LoyaltyRestore || account.Transactions[1].Points != 60 {
+		t.Errorf("expected restored and revoked points, got: %+v", account.Transactions)
+	}
+}
+
+func TestLoyaltyService_ExpirePoints(t *testing.T) {
+	db := newCartTestDB(t, "loyalty_expire")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := postgres.NewLoyaltyStore(db)
+	_, err := store.SetRules(ctx, domain.LoyaltyRules{PointsPerUnit: 10})
+	if err != nil {
+		t.Fatalf("SetRules: %v", err)
+	}
+
+	product := domain.Product{SKU: "SKU-2", Name: "product2", CategoryID: 1, Price: 500, Quantity: 5}
+	err = postgres.NewProductStore(db).Create(ctx, &product)
+	if err != nil {
+		t.Fatalf("product Create: %v", err)
+	}
+
+	_, err = postgres.NewCartStore(db).AddItem(ctx, domain.CartOwner{UserID: 1}, domain.CartItem{ProductID: product.ID, Quantity: 1})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	order, err := postgres.NewOrderStore(db).Checkout(ctx, 1, domain.CheckoutDetails{})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+
+	_, err = postgres.NewOrderStore(db).UpdateStatus(ctx, order.ID, domain.OrderStatusPaid, 0, "")
+	if err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+
+	expired, err := store.ExpirePoints(ctx, time.Now().Add(-time.Hour))
+	if err != nil || expired != 0 {
+		t.Errorf("expected recent points to be kept, got: %d, %v", expired, err)
+	}
+
+	expired, err = store.ExpirePoints(ctx, time.Now().Add(time.Hour))
+	if err != nil || expired != 1 {
+		t.Fatalf("expected points to expire, got: %d, %v", expired, err)
+	}
+
+	account, err := store.Get(ctx, 1)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	if account.Balance != 0 || account.Transactions[0].Kind != domain.LoyaltyExpire || account.Transactions[0].Points != -50 {
+		t.Errorf("expected %d points expired, got: %+v", 50, account)
+	}
+
+	expired, err = store.ExpirePoints(ctx, time.Now().Add(time.Hour))
+	if err != nil || expired != 0 {
+		t.Errorf("expected points to expire once, got: %d, %v", expired, err)
+	}
+}
@@ -0,0 +1,118 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+	"github.com/mortezadadgar/ecommerce-api/postgres"
+)
+
+func TestLocker_TryRun(t *testing.T) {
+	db := newCartTestDB(t, "locker_try_run")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	locker := postgres.NewLocker(db)
+
+	ran, err := locker.TryRun(ctx, "job", func(ctx context.Context) error {
+		// other instances can not run job meanwhile.
+		innerRan, err := locker.TryRun(ctx, "job", func(ctx context.Context) error { return nil })
+		if err != nil || innerRan {
+			t.Errorf("expected job locked, got ran %v with %v", innerRan, err)
+		}
+
+		otherRan, err := locker.TryRun(ctx, "other", func(ctx context.Context) error { return nil })
+		if err != nil || !otherRan {
+			t.Errorf("expected other job not locked, got ran %v with %v", otherRan, err)
+		}
+
+		return nil
+	})
+	if err != nil || !ran {
+		t.Fatalf("expected job to run alone, got ran %v with %v", ran, err)
+	}
+
+	ran, err = locker.TryRun(ctx, "job", func(ctx context.Context) error { return nil })
+	if err != nil || !ran {
+		t.Errorf("expected lock released, got ran %v with %v", ran, err)
+	}
+}
+
+func TestMaintenance(t *testing.T) {
+	db := newCartTestDB(t, "maintenance")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tokens := postgres.NewTokenStore(db)
+	for _, expiry := range []time.Time{time.Now().Add(-time.Hour), time.Now().Add(time.Hour)} {
+		token, err := domain.GenerateToken(1, 16, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token.Expiry = expiry
+
+		err = tokens.Create(ctx, token)
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	deleted, err := tokens.DeleteExpired(ctx)
+	if err != nil || deleted != 1 {
+		t.Errorf("expected 1 expired token deleted, got %d with %v", deleted, err)
+	}
+
+	carts := postgres.NewCartStore(db)
+	guest := domain.CartOwner{Token: []byte("guest")}
+	_, err = carts.AddItem(ctx, guest, domain.CartItem{ProductID: 1, Quantity: 10})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	_, err = carts.AddItem(ctx, domain.CartOwner{UserID: 1}, domain.CartItem{ProductID: 1, Quantity: 1})
+	if err != domain.ErrInsufficientStock {
+		t.Errorf("expected %q from AddItem, got %q", domain.ErrInsufficientStock, err)
+	}
+
+	err = postgres.NewStockSubscriptionStore(db).Subscribe(ctx, &domain.StockSubscription{ProductID: 1, UserID: 1})
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	_, err = db.Exec(ctx, `UPDATE stock_reservations SET expires_at = NOW() - INTERVAL '1 minute'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	released, err := carts.ReleaseExpiredReservations(ctx)
+	if err != nil || released != 1 {
+		t.Errorf("expected 1 reservation released, got %d with %v", released, err)
+	}
+
+	var notified int
+	err = db.QueryRow(ctx, `SELECT COUNT(*) FROM notifications WHERE kind = $1`, domain.NotificationBackInStock).Scan(&notified)
+	if err != nil || notified != 1 {
+		t.Errorf("expected subscriber notified of released stock, got %d with %v", notified, err)
+	}
+
+	deleted, err = carts.DeleteStaleGuests(ctx, time.Now().Add(-time.Hour))
+	if err != nil || deleted != 0 {
+		t.Errorf("expected fresh guest cart kept, got %d deleted with %v", deleted, err)
+	}
+
+	deleted, err = carts.DeleteStaleGuests(ctx, time.Now().Add(time.Minute))
+	if err != nil || deleted != 1 {
+		t.Errorf("expected stale guest cart deleted, got %d with %v", deleted, err)
+	}
+
+	_, err = carts.GetByOwner(ctx, guest)
+	if err != domain.ErrNoCartsFound {
+		t.Errorf("expected %q from GetByOwner, got %q", domain.ErrNoCartsFound, err)
+	}
+}
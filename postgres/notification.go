@@ -0,0 +1,269 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// notificationStore represents notifications outbox database.
+type notificationStore struct {
+	db *pgxpool.Pool
+}
+
+// NewNotificationStore returns a new instance of NotificationStore.
+func NewNotificationStore(db *pgxpool.Pool) notificationStore {
+	return notificationStore{db: db}
+}
+
+// Deliver sends pending notifications through notifier, notifications
+// being delivered by another call are skipped.
+func (n notificationStore) Deliver(ctx context.Context, notifier domain.Notifier, limit int) (int, error) {
+	tx, err := n.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+	SELECT * FROM notifications
+	WHERE sent_at IS NULL AND attempts < @max_attempts
+	ORDER BY id
+	LIMIT @limit
+	FOR UPDATE SKIP LOCKED
+	`
+
+	args := pgx.NamedArgs{
+		"max_attempts": domain.NotificationMaxAttempts,
+		"limit":        limit,
+	}
+
+	rows, err := tx.Query(ctx, query, args)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query pending notifications: %v", err)
+	}
+
+	notifications, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.Notification])
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan rows of notifications: %v", err)
+	}
+
+	query = `
+	UPDATE notifications
+	SET attempts = attempts + 1,
+		sent_at  = CASE WHEN @sent THEN NOW() END
+	WHERE id = @id
+	`
+
+	sent := 0
+	for _, notification := range notifications {
+		notifyErr := notifier.Notify(ctx, notification)
+		if notifyErr != nil {
+			log.Printf("[ERROR]: failed to deliver notification %d: %v", notification.ID, notifyErr)
+		} else {
+			sent++
+		}
+
+		_, err = tx.Exec(ctx, query, pgx.NamedArgs{"id": notification.ID, "sent": notifyErr == nil})
+		if err != nil {
+			return 0, fmt.Errorf("failed to update notification: %v", err)
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return sent, nil
+}
+
+// stockSubscriptionStore represents back in stock subscriptions database.
+type stockSubscriptionStore struct {
+	db *pgxpool.Pool
+}
+
+// NewStockSubscriptionStore returns a new instance of
+// StockSubscriptionStore.
+func NewStockSubscriptionStore(db *pgxpool.Pool) stockSubscriptionStore {
+	return stockSubscriptionStore{db: db}
+}
+
+// Subscribe subscribes a user to a product out of stock, subscribing again
+// renews a notified subscription.
+func (s stockSubscriptionStore) Subscribe(ctx context.Context, subscription *domain.StockSubscription) error {
+	products, err := getProducts(ctx, s.db, []int{subscription.ProductID})
+	if err != nil {
+		return err
+	}
+
+	product, ok := products[subscription.ProductID]
+	switch {
+	case !ok:
+		return domain.ErrNoProductsFound
+	case product.IsBundle():
+		return domain.ErrBundleStockMovement
+	case product.Available > 0:
+		return domain.ErrProductInStock
+	}
+
+	query := `
+	INSERT INTO stock_subscriptions(product_id, user_id, email)
+	SELECT @product_id, id, email FROM users WHERE id = @user_id
+	ON CONFLICT (product_id, user_id) DO UPDATE
+	SET email       = EXCLUDED.email,
+		created_at  = NOW(),
+		notified_at = NULL
+	RETURNING *
+	`
+
+	args := pgx.NamedArgs{
+		"product_id": subscription.ProductID,
+		"user_id":    subscription.UserID,
+	}
+
+	rows, err := s.db.Query(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("failed to insert stock subscription: %v", err)
+	}
+
+	*subscription, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.StockSubscription])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrNoUsersFound
+		}
+		if pgError(err).Code == pgerrcode.ForeignKeyViolation {
+			return domain.ErrNoProductsFound
+		}
+		return fmt.Errorf("failed to scan row of stock subscription: %v", err)
+	}
+
+	return nil
+}
+
+// Unsubscribe deletes subscription of a user to a product.
+func (s stockSubscriptionStore) Unsubscribe(ctx context.Context, productID int, userID int) error {
+	query := `
+	DELETE FROM stock_subscriptions
+	WHERE product_id = @product_id AND user_id = @user_id
+	`
+
+	result, err := s.db.Exec(ctx, query, pgx.NamedArgs{"product_id": productID, "user_id": userID})
+	if err != nil {
+		return fmt.Errorf("failed to delete stock subscription: %v", err)
+	}
+
+	if result.RowsAffected() != 1 {
+		return domain.ErrNoStockSubscriptionsFound
+	}
+
+	return nil
+}
+
+// stockLevel represents available quantity of a product at a point in time.
+type stockLevel struct {
+	product   domain.Product
+	available int
+}
+
+// stockLevels returns available quantity of non bundle products.
+func stockLevels(ctx context.Context, q querier, ids []int) (map[int]stockLevel, error) {
+	query := `
+	SELECT p.id, p.sku, p.name, p.reorder_threshold, p.quantity - COALESCE(r.quantity, 0)
+	FROM products p
+	LEFT JOIN active_reservations r ON r.product_id = p.id
+	WHERE p.id = ANY(@ids) AND p.type <> 'bundle'
+	`
+
+	rows, err := q.Query(ctx, query, pgx.NamedArgs{"ids": ids})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock levels: %v", err)
+	}
+
+	levels := make(map[int]stockLevel)
+	var level stockLevel
+	p := &level.product
+	_, err = pgx.ForEachRow(rows, []any{&p.ID, &p.SKU, &p.Name, &p.ReorderThreshold, &level.available}, func() error {
+		levels[p.ID] = level
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of stock levels: %v", err)
+	}
+
+	return levels, nil
+}
+
+// queueStockNotifications queues a low stock notification for staff of
+// products crossing down to their reorder threshold and back in stock
+// notifications for subscribers of products available again.
+func queueStockNotifications(ctx context.Context, q querier, before map[int]stockLevel, after map[int]stockLevel) error {
+	for id, level := range after {
+		from := before[id].available
+
+		if domain.BelowReorderThreshold(from, level.available, level.product.ReorderThreshold) {
+			err := insertNotification(ctx, q, domain.LowStockNotification(level.product, level.available))
+			if err != nil {
+				return err
+			}
+		}
+
+		if !domain.BackInStock(from, level.available) {
+			continue
+		}
+
+		query := `
+		UPDATE stock_subscriptions
+		SET notified_at = NOW()
+		WHERE product_id = @product_id AND notified_at IS NULL
+		RETURNING email
+		`
+
+		rows, err := q.Query(ctx, query, pgx.NamedArgs{"product_id": id})
+		if err != nil {
+			return fmt.Errorf("failed to update stock subscriptions: %v", err)
+		}
+
+		emails, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return fmt.Errorf("failed to scan rows of stock subscriptions: %v", err)
+		}
+
+		for _, email := range emails {
+			err = insertNotification(ctx, q, domain.BackInStockNotification(level.product, email))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// insertNotification queues a notification for delivery.
+func insertNotification(ctx context.Context, q querier, notification domain.Notification) error {
+	query := `
+	INSERT INTO notifications(kind, recipient, subject, body)
+	VALUES(@kind, @recipient, @subject, @body)
+	`
+
+	args := pgx.NamedArgs{
+		"kind":      notification.Kind,
+		"recipient": notification.Recipient,
+		"subject":   notification.Subject,
+		"body":      notification.Body,
+	}
+
+	_, err := q.Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("failed to insert notification: %v", err)
+	}
+
+	return nil
+}
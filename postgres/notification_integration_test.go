@@ -0,0 +1,88 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+	"github.com/mortezadadgar/ecommerce-api/postgres"
+)
+
+// notifierFunc adapts a function to domain.Notifier.
+type notifierFunc func(ctx context.Context, notification domain.Notification) error
+
+func (f notifierFunc) Notify(ctx context.Context, notification domain.Notification) error {
+	return f(ctx, notification)
+}
+
+func TestNotificationService_StockNotifications(t *testing.T) {
+	db := newCartTestDB(t, "notifications_stock")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	threshold := 3
+	product, err := postgres.NewProductStore(db).Update(ctx, 1, domain.ProductUpdate{ReorderThreshold: &threshold, Version: 1})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	subscription := domain.StockSubscription{ProductID: 1, UserID: 1}
+	err = postgres.NewStockSubscriptionStore(db).Subscribe(ctx, &subscription)
+	if err != domain.ErrProductInStock {
+		t.Errorf("expected %q from Subscribe, got %q", domain.ErrProductInStock, err)
+	}
+
+	inventory := postgres.NewInventoryStore(db)
+	adjustment := domain.StockMovementCreate{
+		ProductID:   1,
+		WarehouseID: domain.DefaultWarehouseID,
+		Type:        domain.StockMovementAdjustment,
+		Quantity:    -product.Quantity,
+	}
+	_, err = inventory.RecordMovements(ctx, adjustment.CreateModel(0))
+	if err != nil {
+		t.Fatalf("RecordMovements: %v", err)
+	}
+
+	err = postgres.NewStockSubscriptionStore(db).Subscribe(ctx, &subscription)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	receipt := domain.StockMovementCreate{
+		ProductID:   1,
+		WarehouseID: domain.DefaultWarehouseID,
+		Type:        domain.StockMovementReceipt,
+		Quantity:    2,
+	}
+	_, err = inventory.RecordMovements(ctx, receipt.CreateModel(0))
+	if err != nil {
+		t.Fatalf("RecordMovements: %v", err)
+	}
+
+	var kinds []string
+	notifier := notifierFunc(func(_ context.Context, n domain.Notification) error {
+		kinds = append(kinds, n.Kind)
+		return nil
+	})
+
+	sent, err := postgres.NewNotificationStore(db).Deliver(ctx, notifier, 10)
+	if err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+
+	if sent != 2 || kinds[0] != domain.NotificationLowStock || kinds[1] != domain.NotificationBackInStock {
+		t.Errorf("expected low stock then back in stock, got: %v", kinds)
+	}
+
+	sent, err = postgres.NewNotificationStore(db).Deliver(ctx, notifier, 10)
+	if err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+
+	if sent != 0 {
+		t.Errorf("expected notifications to be delivered once, got %d more", sent)
+	}
+}
@@ -0,0 +1,552 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// orderStore represents orders database.
+type orderStore struct {
+	db *pgxpool.Pool
+}
+
+// NewOrderStore returns a new instance of OrderStore.
+func NewOrderStore(db *pgxpool.Pool) orderStore {
+	return orderStore{db: db}
+}
+
+// GetByID get order by id from database.
+func (o orderStore) GetByID(ctx context.Context, ID int) (domain.Order, error) {
+	orders, err := o.List(ctx, domain.OrderFilter{ID: ID})
+	if err != nil {
+		return domain.Order{}, err
+	}
+
+	return orders[0], nil
+}
+
+// List lists orders with optional filter.
+func (o orderStore) List(ctx context.Context, filter domain.OrderFilter) ([]domain.Order, error) {
+	query := `
+	SELECT * FROM orders
+	WHERE 1=1
+	` + FormatAndInt("id", filter.ID) + `
+	` + FormatAndInt("user_id", filter.UserID) + `
+	` + FormatSort(filter.Sort) + `
+	` + FormatLimitOffset(filter.Limit, filter.Offset) + `
+	`
+
+	rows, err := o.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query list orders: %v", err)
+	}
+
+	orders, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.Order])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of orders: %v", err)
+	}
+
+	if len(orders) == 0 {
+		return nil, domain.ErrNoOrdersFound
+	}
+
+	err = fillOrders(ctx, o.db, orders)
+	if err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// Checkout places an order from user's cart in a single transaction,
+// stock of products is taken and the cart is emptied. Digital products are
+// granted once the order is paid.
+func (o orderStore) Checkout(ctx context.Context, userID int, details domain.CheckoutDetails) (domain.Order, error) {
+	tx, err := o.db.Begin(ctx)
+	if err != nil {
+		return domain.Order{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	cartID, err := findCart(ctx, tx, domain.CartOwner{UserID: userID})
+	if err != nil {
+		if errors.Is(err, domain.ErrNoCartsFound) {
+			return domain.Order{}, domain.ErrEmptyCart
+		}
+		return domain.Order{}, err
+	}
+
+	// destination and shipping method are only kept along the order, a
+	// failed checkout leaves cart as it was.
+	if details.Destination != nil {
+		err = setCartDestination(ctx, tx, cartID, *details.Destination)
+		if err != nil {
+			return domain.Order{}, err
+		}
+	}
+
+	if details.ShippingMethodID != 0 {
+		err = setCartShippingMethod(ctx, tx, cartID, details.ShippingMethodID)
+		if err != nil {
+			return domain.Order{}, err
+		}
+	}
+
+	err = lockCartCoupons(ctx, tx, cartID)
+	if err != nil {
+		return domain.Order{}, err
+	}
+
+	carts := []domain.Cart{{ID: cartID, UserID: userID}}
+	err = fillCarts(ctx, tx, carts)
+	if err != nil {
+		return domain.Order{}, err
+	}
+
+	cart := carts[0]
+	if len(cart.Items) == 0 {
+		return domain.Order{}, domain.ErrEmptyCart
+	}
+
+	if details.Shipping != nil {
+		if cart.ShippingRequest() != details.Shipping.Request {
+			return domain.Order{}, domain.ErrShippingQuoteChanged
+		}
+		cart.ApplyShipping(details.Shipping.Rate)
+	}
+
+	productIDs := make([]int, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+
+	products, err := getProducts(ctx, tx, productIDs)
+	if err != nil {
+		return domain.Order{}, err
+	}
+
+	for _, item := range cart.Items {
+		if products[item.ProductID].Type != domain.ProductTypeDigital {
+			continue
+		}
+
+		err = checkLicenseKeys(ctx, tx, item.ProductID, item.Quantity)
+		if err != nil {
+			return domain.Order{}, err
+		}
+	}
+
+	order := domain.NewOrder(cart, products)
+	order.ShippingAddress = details.ShippingAddress
+	order.BillingAddress = details.BillingAddress
+	points := order.ApplyLoyaltyDiscount(details.RedeemPoints)
+	err = insertOrder(ctx, tx, &order)
+	if err != nil {
+		return domain.Order{}, err
+	}
+
+	if points > 0 {
+		err = redeemPoints(ctx, tx, order, points)
+		if err != nil {
+			return domain.Order{}, err
+		}
+	}
+
+	err = redeemCoupons(ctx, tx, cartID, order, cart.Discounts)
+	if err != nil {
+		return domain.Order{}, err
+	}
+
+	err = convertCartRecovery(ctx, tx, cartID, order)
+	if err != nil {
+		return domain.Order{}, err
+	}
+
+	backordered, err := takeStock(ctx, tx, cartID, domain.OrderStockReference(order.ID))
+	if err != nil {
+		return domain.Order{}, err
+	}
+
+	order.ApplyBackorders(backordered)
+	for _, line := range order.Lines {
+		if line.Backordered == 0 {
+			continue
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE order_lines SET backordered = @backordered WHERE id = @id`,
+			pgx.NamedArgs{"id": line.ID, "backordered": line.Backordered})
+		if err != nil {
+			return domain.Order{}, fmt.Errorf("failed to update backordered quantity: %v", err)
+		}
+	}
+
+	err = insertStatusChange(ctx, tx, domain.OrderStatusChange{
+		OrderID:   order.ID,
+		ToStatus:  order.Status,
+		ChangedBy: &userID,
+	})
+	if err != nil {
+		return domain.Order{}, err
+	}
+
+	paid, err := payWithStoredValue(ctx, tx, order, details)
+	if err != nil {
+		return domain.Order{}, err
+	}
+
+	// orders with nothing left to pay, free ones included, are paid right
+	// away as no payment would ever be started for them.
+	if paid == order.Total {
+		note := "paid with gift cards, store credit and loyalty points"
+		if paid == 0 && order.LoyaltyDiscount == 0 {
+			note = "nothing to pay"
+		}
+
+		err = changeStatus(ctx, tx, &order, domain.OrderStatusPaid, 0, note)
+		if err != nil {
+			return domain.Order{}, err
+		}
+	}
+
+	err = clearCart(ctx, tx, cartID)
+	if err != nil {
+		return domain.Order{}, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.Order{}, fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return order, nil
+}
+
+// UpdateStatus moves an order to status when transition is allowed.
+func (o orderStore) UpdateStatus(ctx context.Context, ID int, status string, changedBy int, note string) (domain.Order, error) {
+	tx, err := o.db.Begin(ctx)
+	if err != nil {
+		return domain.Order{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	order, err := lockOrder(ctx, tx, ID)
+	if err != nil {
+		return domain.Order{}, err
+	}
+
+	err = changeStatus(ctx, tx, &order, status, changedBy, note)
+	if err != nil {
+		return domain.Order{}, err
+	}
+
+	return commitOrder(ctx, tx, order)
+}
+
+// Cancel cancels an order of user, orders of other users are not found.
+func (o orderStore) Cancel(ctx context.Context, ID int, userID int) (domain.Order, error) {
+	tx, err := o.db.Begin(ctx)
+	if err != nil {
+		return domain.Order{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	order, err := lockOrder(ctx, tx, ID)
+	if err != nil {
+		return domain.Order{}, err
+	}
+
+	if order.UserID != userID {
+		return domain.Order{}, domain.ErrNoOrdersFound
+	}
+
+	if !order.CanCancel() {
+		return domain.Order{}, domain.ErrOrderNotCancellable
+	}
+
+	err = changeStatus(ctx, tx, &order, domain.OrderStatusCancelled, userID, "cancelled by customer")
+	if err != nil {
+		return domain.Order{}, err
+	}
+
+	return commitOrder(ctx, tx, order)
+}
+
+// History lists status changes of an order from oldest.
+func (o orderStore) History(ctx context.Context, ID int) ([]domain.OrderStatusChange, error) {
+	query := `
+	SELECT * FROM order_status_history
+	WHERE order_id = @order_id
+	ORDER BY id
+	`
+
+	rows, err := o.db.Query(ctx, query, pgx.NamedArgs{"order_id": ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order status history: %v", err)
+	}
+
+	history, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.OrderStatusChange])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of order status history: %v", err)
+	}
+
+	if len(history) == 0 {
+		return nil, domain.ErrNoOrdersFound
+	}
+
+	return history, nil
+}
+
+// lockOrder selects an order for update.
+func lockOrder(ctx context.Context, q querier, ID int) (domain.Order, error) {
+	rows, err := q.Query(ctx, `SELECT * FROM orders WHERE id = @id FOR UPDATE`, pgx.NamedArgs{"id": ID})
+	if err != nil {
+		return domain.Order{}, err
+	}
+
+	order, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.Order])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Order{}, domain.ErrNoOrdersFound
+		}
+		return domain.Order{}, fmt.Errorf("failed to scan row of order: %v", err)
+	}
+
+	return order, nil
+}
+
+// changeStatus moves a locked order to status, records the change,
+// restocks products of cancelled orders and gives their gift card and
+// store credit balance back, grants digital products of paid orders,
+// awards their loyalty points and invoices them. Loyalty points and
+// download grants of cancelled and refunded orders are reversed.
+func changeStatus(ctx context.Context, q querier, order *domain.Order, status string, changedBy int, note string) error {
+	change, err := order.Transition(status, changedBy, note)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	UPDATE orders
+	SET status     = @status,
+		updated_at = NOW(),
+		version    = version + 1
+	WHERE id = @id
+	RETURNING updated_at, version
+	`
+
+	err = q.QueryRow(ctx, query, pgx.NamedArgs{"id": order.ID, "status": order.Status}).
+		Scan(&order.UpdatedAt, &order.Version)
+	if err != nil {
+		return fmt.Errorf("failed to update order status: %v", err)
+	}
+
+	err = insertStatusChange(ctx, q, change)
+	if err != nil {
+		return err
+	}
+
+	if order.Status == domain.OrderStatusCancelled {
+		// coupons redeemed by a cancelled order are available again.
+		_, err = q.Exec(ctx, `DELETE FROM coupon_redemptions WHERE order_id = @order_id`,
+			pgx.NamedArgs{"order_id": order.ID})
+		if err != nil {
+			return fmt.Errorf("failed to delete coupon redemptions: %v", err)
+		}
+
+		err = restoreStoredValue(ctx, q, *order)
+		if err != nil {
+			return err
+		}
+
+		err = reverseLoyalty(ctx, q, *order)
+		if err != nil {
+			return err
+		}
+
+		err = revokeOrderGrants(ctx, q, order.ID)
+		if err != nil {
+			return err
+		}
+
+		return restock(ctx, q, order.ID)
+	}
+
+	if order.Status == domain.OrderStatusRefunded {
+		err = reverseLoyalty(ctx, q, *order)
+		if err != nil {
+			return err
+		}
+
+		return revokeOrderGrants(ctx, q, order.ID)
+	}
+
+	if order.Status == domain.OrderStatusPaid {
+		err = grantOrder(ctx, q, *order)
+		if err != nil {
+			return err
+		}
+
+		err = awardPoints(ctx, q, *order)
+		if err != nil {
+			return err
+		}
+
+		return issueInvoice(ctx, q, *order)
+	}
+
+	return nil
+}
+
+// insertStatusChange records a change of order status.
+func insertStatusChange(ctx context.Context, q querier, change domain.OrderStatusChange) error {
+	query := `
+	INSERT INTO order_status_history(order_id, from_status, to_status, changed_by, note)
+	VALUES(@order_id, @from_status, @to_status, @changed_by, @note)
+	`
+
+	args := pgx.NamedArgs{
+		"order_id":    change.OrderID,
+		"from_status": change.FromStatus,
+		"to_status":   change.ToStatus,
+		"changed_by":  change.ChangedBy,
+		"note":        change.Note,
+	}
+
+	_, err := q.Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("failed to insert order status change: %v", err)
+	}
+
+	return nil
+}
+
+// commitOrder loads lines of order and commits transaction.
+func commitOrder(ctx context.Context, tx pgx.Tx, order domain.Order) (domain.Order, error) {
+	orders := []domain.Order{order}
+	err := fillOrders(ctx, tx, orders)
+	if err != nil {
+		return domain.Order{}, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.Order{}, fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return orders[0], nil
+}
+
+// insertOrder inserts an order along with its lines.
+func insertOrder(ctx context.Context, q querier, order *domain.Order) error {
+	query := `
+	INSERT INTO orders(user_id, status, subtotal, discount, loyalty_discount, tax, total, tax_inclusive,
+		shipping, shipping_method_id, shipping_method, shipping_address, billing_address)
+	VALUES(@user_id, @status, @subtotal, @discount, @loyalty_discount, @tax, @total, @tax_inclusive,
+		@shipping, @shipping_method_id, @shipping_method, @shipping_address, @billing_address)
+	RETURNING id, created_at, updated_at, version
+	`
+
+	args := pgx.NamedArgs{
+		"user_id":  order.UserID,
+		"status":   order.Status,
+		"subtotal": order.Subtotal,
+		"discount": order.Discount,
+		"tax":      order.Tax,
+		"total":    order.Total,
+
+		"loyalty_discount": order.LoyaltyDiscount,
+
+		"tax_inclusive": order.TaxInclusive,
+
+		"shipping":           order.Shipping,
+		"shipping_method_id": order.ShippingMethodID,
+		"shipping_method":    order.ShippingMethod,
+		"shipping_address":   order.ShippingAddress,
+		"billing_address":    order.BillingAddress,
+	}
+
+	err := q.QueryRow(ctx, query, args).Scan(&order.ID, &order.CreatedAt, &order.UpdatedAt, &order.Version)
+	if err != nil {
+		return fmt.Errorf("failed to insert order: %v", err)
+	}
+
+	query = `
+	INSERT INTO order_lines(order_id, product_id, name, sku, unit_price, quantity, discount, line_total,
+		tax_category, tax_rate, tax, shippable)
+	VALUES(@order_id, @product_id, @name, @sku, @unit_price, @quantity, @discount, @line_total,
+		@tax_category, @tax_rate, @tax, @shippable)
+	RETURNING id
+	`
+
+	for i := range order.Lines {
+		line := &order.Lines[i]
+		line.OrderID = order.ID
+
+		args := pgx.NamedArgs{
+			"order_id":   line.OrderID,
+			"product_id": line.ProductID,
+			"name":       line.Name,
+			"sku":        line.SKU,
+			"unit_price": line.UnitPrice,
+			"quantity":   line.Quantity,
+			"discount":   line.Discount,
+			"line_total": line.LineTotal,
+
+			"tax_category": line.TaxCategory,
+			"tax_rate":     line.TaxRate,
+			"tax":          line.Tax,
+
+			"shippable": line.Shippable,
+		}
+
+		err = q.QueryRow(ctx, query, args).Scan(&line.ID)
+		if err != nil {
+			return fmt.Errorf("failed to insert order line: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// fillOrders loads lines of orders.
+func fillOrders(ctx context.Context, q querier, orders []domain.Order) error {
+	ids := make([]int, 0, len(orders))
+	for _, o := range orders {
+		ids = append(ids, o.ID)
+	}
+
+	query := `
+	SELECT * FROM order_lines
+	WHERE order_id = ANY(@ids)
+	ORDER BY id
+	`
+
+	rows, err := q.Query(ctx, query, pgx.NamedArgs{"ids": ids})
+	if err != nil {
+		return fmt.Errorf("failed to query list order lines: %v", err)
+	}
+
+	lines, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.OrderLine])
+	if err != nil {
+		return fmt.Errorf("failed to scan rows of order lines: %v", err)
+	}
+
+	orderLines := make(map[int][]domain.OrderLine)
+	for _, line := range lines {
+		orderLines[line.OrderID] = append(orderLines[line.OrderID], line)
+	}
+
+	for i := range orders {
+		orders[i].Lines = orderLines[orders[i].ID]
+		if orders[i].Lines == nil {
+			orders[i].Lines = []domain.OrderLine{}
+		}
+	}
+
+	return nil
+}
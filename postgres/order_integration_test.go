@@ -0,0 +1,181 @@
+package postgres_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+	"github.com/mortezadadgar/ecommerce-api/postgres"
+)
+
+func TestOrderService_Checkout(t *testing.T) {
+	db := newCartTestDB(t, "orders_checkout")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	product := domain.Product{SKU: "SKU-2", Name: "product2", CategoryID: 1, Price: 15, Quantity: 5}
+	err := postgres.NewProductStore(db).Create(ctx, &product)
+	if err != nil {
+		t.Fatalf("product Create: %v", err)
+	}
+
+	user := domain.CartOwner{UserID: 1}
+	_, err = postgres.NewCartStore(db).AddItem(ctx, user, domain.CartItem{ProductID: product.ID, Quantity: 2})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	got, err := postgres.NewOrderStore(db).Checkout(ctx, 1, domain.CheckoutDetails{})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+
+	if got.Status != domain.OrderStatusPending || got.Total != 30 {
+		t.Errorf("expected pending order of total %d, got: %#v", 30, got)
+	}
+
+	if len(got.Lines) != 1 || got.Lines[0].SKU != "SKU-2" || got.Lines[0].UnitPrice != 15 {
+		t.Errorf("expected snapshot of product, got: %#v", got.Lines)
+	}
+
+	want, err := postgres.NewOrderStore(db).GetByID(ctx, got.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+
+	if !reflect.DeepEqual(want.Lines, got.Lines) {
+		t.Errorf("mismatch\n got: %#v\nwant: %#v", got.Lines, want.Lines)
+	}
+
+	product, err = postgres.NewProductStore(db).GetByID(ctx, product.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+
+	if product.Quantity != 3 || product.Available != 3 {
+		t.Errorf("expected quantity of %d, got: %d", 3, product.Quantity)
+	}
+
+	cart, err := postgres.NewCartStore(db).GetByOwner(ctx, user)
+	if err != nil {
+		t.Fatalf("GetByOwner: %v", err)
+	}
+
+	if len(cart.Items) != 0 {
+		t.Errorf("expected empty cart, got: %#v", cart.Items)
+	}
+
+	_, err = postgres.NewOrderStore(db).Checkout(ctx, 1, domain.CheckoutDetails{})
+	if err != domain.ErrEmptyCart {
+		t.Errorf("expected %q from Checkout, got %q", domain.ErrEmptyCart, err)
+	}
+
+	orders, err := postgres.NewOrderStore(db).List(ctx, domain.OrderFilter{UserID: 1})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+
+	if len(orders) != 1 {
+		t.Errorf("expected length of %d, got: %d", 1, len(orders))
+	}
+}
+
+func TestOrderService_Cancel(t *testing.T) {
+	db := newCartTestDB(t, "orders_cancel")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := postgres.NewCartStore(db).AddItem(ctx, domain.CartOwner{UserID: 1}, domain.CartItem{ProductID: 1, Quantity: 4})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	order, err := postgres.NewOrderStore(db).Checkout(ctx, 1, domain.CheckoutDetails{})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+
+	_, err = postgres.NewOrderStore(db).Cancel(ctx, order.ID, 2)
+	if err != domain.ErrNoOrdersFound {
+		t.Errorf("expected %q from Cancel, got %q", domain.ErrNoOrdersFound, err)
+	}
+
+	_, err = postgres.NewOrderStore(db).UpdateStatus(ctx, order.ID, domain.OrderStatusShipped, 0, "")
+	if err != domain.ErrInvalidOrderTransition {
+		t.Errorf("expected %q from UpdateStatus, got %q", domain.ErrInvalidOrderTransition, err)
+	}
+
+	order, err = postgres.NewOrderStore(db).Cancel(ctx, order.ID, 1)
+	if err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+
+	if order.Status != domain.OrderStatusCancelled {
+		t.Errorf("expected status %q, got: %q", domain.OrderStatusCancelled, order.Status)
+	}
+
+	product, err := postgres.NewProductStore(db).GetByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+
+	if product.Quantity != 10 {
+		t.Errorf("expected restocked quantity of %d, got: %d", 10, product.Quantity)
+	}
+
+	history, err := postgres.NewOrderStore(db).History(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+
+	if len(history) != 2 || history[1].ToStatus != domain.OrderStatusCancelled {
+		t.Errorf("expected pending and cancelled history, got: %#v", history)
+	}
+}
+
+func TestOrderService_CheckoutFree(t *testing.T) {
+	db := newCartTestDB(t, "orders_checkout_free")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	product := domain.Product{SKU: "SKU-2", Name: "sample", CategoryID: 1, Quantity: 5}
+	err := postgres.NewProductStore(db).Create(ctx, &product)
+	if err != nil {
+		t.Fatalf("product Create: %v", err)
+	}
+
+	_, err = postgres.NewCartStore(db).AddItem(ctx, domain.CartOwner{UserID: 1}, domain.CartItem{ProductID: product.ID, Quantity: 1})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	order, err := postgres.NewOrderStore(db).Checkout(ctx, 1, domain.CheckoutDetails{})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+
+	if order.Total != 0 || order.Status != domain.OrderStatusPaid {
+		t.Errorf("expected free order to be paid, got: %#v", order)
+	}
+
+	order, err = postgres.NewOrderStore(db).Cancel(ctx, order.ID, 1)
+	if err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+
+	if order.Status != domain.OrderStatusCancelled {
+		t.Errorf("expected paid order to be cancelled, got: %q", order.Status)
+	}
+
+	_, err = postgres.NewOrderStore(db).Cancel(ctx, order.ID, 1)
+	if err != domain.ErrOrderNotCancellable {
+		t.Errorf("expected %q from Cancel, got %q", domain.ErrOrderNotCancellable, err)
+	}
+}
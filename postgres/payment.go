@@ -0,0 +1,341 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// paymentStore represents payments database.
+type paymentStore struct {
+	db *pgxpool.Pool
+}
+
+// NewPaymentStore returns a new instance of PaymentStore.
+func NewPaymentStore(db *pgxpool.Pool) paymentStore {
+	return paymentStore{db: db}
+}
+
+// GetByID get payment by id from database.
+func (p paymentStore) GetByID(ctx context.Context, ID int) (domain.Payment, error) {
+	payments, err := p.List(ctx, domain.PaymentFilter{ID: ID})
+	if err != nil {
+		return domain.Payment{}, err
+	}
+
+	return payments[0], nil
+}
+
+// List lists payments with optional filter.
+func (p paymentStore) List(ctx context.Context, filter domain.PaymentFilter) ([]domain.Payment, error) {
+	query := `
+	SELECT * FROM payments
+	WHERE 1=1
+	` + FormatAndInt("id", filter.ID) + `
+	` + FormatAndInt("order_id", filter.OrderID) + `
+	` + FormatSort(filter.Sort) + `
+	` + FormatLimitOffset(filter.Limit, filter.Offset) + `
+	`
+
+	rows, err := p.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query list payments: %v", err)
+	}
+
+	payments, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.Payment])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of payments: %v", err)
+	}
+
+	if len(payments) == 0 {
+		return nil, domain.ErrNoPaymentsFound
+	}
+
+	return payments, nil
+}
+
+// Create records a payment and moves its pending order to awaiting
+// payment.
+func (p paymentStore) Create(ctx context.Context, payment *domain.Payment) error {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	order, err := lockOrder(ctx, tx, payment.OrderID)
+	if err != nil {
+		return err
+	}
+
+	if !order.CanPay() {
+		return domain.ErrOrderNotPayable
+	}
+
+	// a second intent could be paid along the open one and charge customer
+	// twice.
+	open, err := hasPayments(ctx, tx, order.ID, 0,
+		[]string{domain.PaymentStatusPending, domain.PaymentStatusAuthorized})
+	if err != nil {
+		return err
+	}
+
+	if open {
+		return domain.ErrPaymentInProgress
+	}
+
+	err = insertPayment(ctx, tx, payment)
+	if err != nil {
+		return err
+	}
+
+	if order.Status == domain.OrderStatusPending {
+		err = changeStatus(ctx, tx, &order, domain.OrderStatusAwaitingPayment, 0, "payment started")
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return nil
+}
+
+// Sync updates payment from state of its intent and moves its order
+// accordingly.
+func (p paymentStore) Sync(ctx context.Context, ID int, intent domain.PaymentIntent) (domain.Payment, error) {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return domain.Payment{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	payment, err := syncPayment(ctx, tx, ID, intent)
+	if err != nil {
+		return domain.Payment{}, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.Payment{}, fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return payment, nil
+}
+
+// HandleEvent records a webhook event and applies it to its payment, events
+// are applied once per provider. Events of no interest are only recorded.
+func (p paymentStore) HandleEvent(ctx context.Context, provider string, event domain.PaymentEvent) error {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+	INSERT INTO payment_events(provider, event_id, type)
+	VALUES(@provider, @event_id, @type)
+	ON CONFLICT DO NOTHING
+	`
+
+	args := pgx.NamedArgs{
+		"provider": provider,
+		"event_id": event.ID,
+		"type":     event.Type,
+	}
+
+	result, err := tx.Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("failed to insert payment event: %v", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrDuplicatedPaymentEvent
+	}
+
+	if event.Type != "" && event.IntentID != "" {
+		payment, err := lockPayment(ctx, tx, "provider = @provider AND intent_id = @intent_id",
+			pgx.NamedArgs{"provider": provider, "intent_id": event.IntentID})
+		if err != nil {
+			return err
+		}
+
+		payment.ApplyEvent(event)
+
+		err = savePayment(ctx, tx, &payment, fmt.Sprintf("%s (event %s)", event.Type, event.ID))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return nil
+}
+
+// insertPayment inserts a payment.
+func insertPayment(ctx context.Context, q querier, payment *domain.Payment) error {
+	query := `
+	INSERT INTO payments(order_id, provider, intent_id, status, amount, captured, refunded, currency)
+	VALUES(@order_id, @provider, @intent_id, @status, @amount, @captured, @refunded, @currency)
+	RETURNING id, created_at, updated_at
+	`
+
+	args := pgx.NamedArgs{
+		"order_id":  payment.OrderID,
+		"provider":  payment.Provider,
+		"intent_id": payment.IntentID,
+		"status":    payment.Status,
+		"amount":    payment.Amount,
+		"captured":  payment.Captured,
+		"refunded":  payment.Refunded,
+		"currency":  payment.Currency,
+	}
+
+	err := q.QueryRow(ctx, query, args).Scan(&payment.ID, &payment.CreatedAt, &payment.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert payment: %v", err)
+	}
+
+	return nil
+}
+
+// syncPayment updates a payment from state of its intent and moves its
+// order accordingly.
+func syncPayment(ctx context.Context, q querier, ID int, intent domain.PaymentIntent) (domain.Payment, error) {
+	payment, err := lockPayment(ctx, q, "id = @id", pgx.NamedArgs{"id": ID})
+	if err != nil {
+		return domain.Payment{}, err
+	}
+
+	payment.ApplyIntent(intent)
+
+	err = savePayment(ctx, q, &payment, "payment "+payment.Status)
+	if err != nil {
+		return domain.Payment{}, err
+	}
+
+	return payment, nil
+}
+
+// lockPayment selects a payment matching condition for update.
+func lockPayment(ctx context.Context, q querier, condition string, args pgx.NamedArgs) (domain.Payment, error) {
+	rows, err := q.Query(ctx, `SELECT * FROM payments WHERE `+condition+` FOR UPDATE`, args)
+	if err != nil {
+		return domain.Payment{}, fmt.Errorf("failed to query payment: %v", err)
+	}
+
+	payment, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.Payment])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Payment{}, domain.ErrNoPaymentsFound
+		}
+		return domain.Payment{}, fmt.Errorf("failed to scan row of payment: %v", err)
+	}
+
+	return payment, nil
+}
+
+// savePayment updates state of a locked payment, credits refunds of it and
+// moves its order to status of payment when transition is allowed, note is
+// recorded in order history and credit notes.
+func savePayment(ctx context.Context, q querier, payment *domain.Payment, note string) error {
+	query := `
+	UPDATE payments
+	SET status     = @status,
+		captured   = @captured,
+		refunded   = @refunded,
+		updated_at = NOW()
+	WHERE id = @id
+	RETURNING updated_at
+	`
+
+	args := pgx.NamedArgs{
+		"id":       payment.ID,
+		"status":   payment.Status,
+		"captured": payment.Captured,
+		"refunded": payment.Refunded,
+	}
+
+	err := q.QueryRow(ctx, query, args).Scan(&payment.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update payment: %v", err)
+	}
+
+	if payment.Refunded > 0 {
+		err = issueCreditNote(ctx, q, payment.OrderID, note)
+		if err != nil {
+			return err
+		}
+	}
+
+	status := payment.OrderStatus()
+	if status == "" {
+		return nil
+	}
+
+	// a cancelled payment only cancels its order when no other payment has
+	// paid or may still pay it.
+	if status == domain.OrderStatusCancelled {
+		active, err := hasPayments(ctx, q, payment.OrderID, payment.ID, []string{
+			domain.PaymentStatusPending,
+			domain.PaymentStatusAuthorized,
+			domain.PaymentStatusSucceeded,
+		})
+		if err != nil {
+			return err
+		}
+
+		if active {
+			return nil
+		}
+	}
+
+	order, err := lockOrder(ctx, q, payment.OrderID)
+	if err != nil {
+		return err
+	}
+
+	// payments may report a state order already moved past, e.g. a refund
+	// of a shipped order, these are recorded on payment only.
+	if !domain.CanTransition(order.Status, status) {
+		return nil
+	}
+
+	return changeStatus(ctx, q, &order, status, 0, note)
+}
+
+// hasPayments reports whether order has payments other than excludeID in
+// one of statuses.
+func hasPayments(ctx context.Context, q querier, orderID int, excludeID int, statuses []string) (bool, error) {
+	query := `
+	SELECT EXISTS(
+		SELECT 1 FROM payments
+		WHERE order_id = @order_id AND id <> @exclude_id AND status = ANY(@statuses)
+	)
+	`
+
+	args := pgx.NamedArgs{
+		"order_id":   orderID,
+		"exclude_id": excludeID,
+		"statuses":   statuses,
+	}
+
+	var exists bool
+	err := q.QueryRow(ctx, query, args).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to query payments of order: %v", err)
+	}
+
+	return exists, nil
+}
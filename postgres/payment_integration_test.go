@@ -0,0 +1,140 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+	"github.com/mortezadadgar/ecommerce-api/postgres"
+)
+
+func TestPaymentService_HandleEvent(t *testing.T) {
+	db := newCartTestDB(t, "payments_events")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := postgres.NewCartStore(db).AddItem(ctx, domain.CartOwner{UserID: 1}, domain.CartItem{ProductID: 1, Quantity: 1})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	order, err := postgres.NewOrderStore(db).Checkout(ctx, 1, domain.CheckoutDetails{})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+
+	intent := domain.PaymentIntent{ID: "pi_1", Status: domain.PaymentStatusAuthorized, Amount: order.Total}
+	payment := domain.NewPayment(order, "mock", intent)
+	err = postgres.NewPaymentStore(db).Create(ctx, &payment)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	event := domain.PaymentEvent{ID: "evt_1", Type: domain.PaymentEventSucceeded, IntentID: "pi_1", Amount: order.Total}
+	err = postgres.NewPaymentStore(db).HandleEvent(ctx, "mock", event)
+	if err != nil {
+		t.Fatalf("HandleEvent: %v", err)
+	}
+
+	err = postgres.NewPaymentStore(db).HandleEvent(ctx, "mock", event)
+	if err != domain.ErrDuplicatedPaymentEvent {
+		t.Errorf("expected %q from HandleEvent, got %q", domain.ErrDuplicatedPaymentEvent, err)
+	}
+
+	err = postgres.NewPaymentStore(db).HandleEvent(ctx, "mock", domain.PaymentEvent{ID: "evt_2", Type: domain.PaymentEventSucceeded, IntentID: "pi_2"})
+	if err != domain.ErrNoPaymentsFound {
+		t.Errorf("expected %q from HandleEvent, got %q", domain.ErrNoPaymentsFound, err)
+	}
+
+	payment, err = postgres.NewPaymentStore(db).GetByID(ctx, payment.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+
+	if payment.Status != domain.PaymentStatusSucceeded || payment.Captured != order.Total {
+		t.Errorf("expected captured payment, got: %#v", payment)
+	}
+
+	order, err = postgres.NewOrderStore(db).GetByID(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+
+	if order.Status != domain.OrderStatusPaid {
+		t.Errorf("expected status %q, got: %q", domain.OrderStatusPaid, order.Status)
+	}
+
+	history, err := postgres.NewOrderStore(db).History(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+
+	if len(history) != 3 {
+		t.Errorf("expected pending, awaiting payment and paid history, got: %#v", history)
+	}
+}
+
+func TestPaymentService_OpenPayments(t *testing.T) {
+	db := newCartTestDB(t, "payments_open")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	product := domain.Product{SKU: "SKU-2", Name: "product2", CategoryID: 1, Price: 100, Quantity: 5}
+	err := postgres.NewProductStore(db).Create(ctx, &product)
+	if err != nil {
+		t.Fatalf("product Create: %v", err)
+	}
+
+	_, err = postgres.NewStoreCreditStore(db).Adjust(ctx, 1, domain.CreditAdjustment{Amount: 40})
+	if err != nil {
+		t.Fatalf("store credit Adjust: %v", err)
+	}
+
+	_, err = postgres.NewCartStore(db).AddItem(ctx, domain.CartOwner{UserID: 1}, domain.CartItem{ProductID: product.ID, Quantity: 1})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	order, err := postgres.NewOrderStore(db).Checkout(ctx, 1, domain.CheckoutDetails{UseStoreCredit: true})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+
+	store := postgres.NewPaymentStore(db)
+	payment := domain.NewPayment(order, "mock", domain.PaymentIntent{ID: "pi_1", Status: domain.PaymentStatusPending, Amount: 60})
+	err = store.Create(ctx, &payment)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	second := domain.NewPayment(order, "mock", domain.PaymentIntent{ID: "pi_2", Status: domain.PaymentStatusPending, Amount: 60})
+	err = store.Create(ctx, &second)
+	if err != domain.ErrPaymentInProgress {
+		t.Errorf("expected %q from Create, got %q", domain.ErrPaymentInProgress, err)
+	}
+
+	// store credit paid part of order, it is kept open for another intent.
+	event := domain.PaymentEvent{ID: "evt_1", Type: domain.PaymentEventCancelled, IntentID: "pi_1"}
+	err = store.HandleEvent(ctx, "mock", event)
+	if err != nil {
+		t.Fatalf("HandleEvent: %v", err)
+	}
+
+	order, err = postgres.NewOrderStore(db).GetByID(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+
+	if order.Status != domain.OrderStatusAwaitingPayment {
+		t.Errorf("expected status %q, got: %q", domain.OrderStatusAwaitingPayment, order.Status)
+	}
+
+	err = store.Create(ctx, &second)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+}
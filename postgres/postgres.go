@@ -9,6 +9,7 @@ import (
 	"time"
 
 	// postgres driver.
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -21,6 +22,14 @@ var (
 	ErrCommitTransaction = errors.New("failed to commit transaction")
 )
 
+// querier is implemented by both connection pool and transactions so
+// helpers can run either inside or outside of a transaction.
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 // Postgres represents Postgres connection pool.
 type Postgres struct {
 	DB *pgxpool.Pool
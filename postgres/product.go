@@ -24,20 +24,62 @@ func NewProductStore(db *pgxpool.Pool) productStore {
 // Create creates a new product in database.
 func (p productStore) Create(ctx context.Context, product *domain.Product) error {
 	query := `
-	 INSERT INTO products(name, description, category_id, price, quantity)
-	 VALUES(@name, @description, @category, @price, @quantity)
+	 INSERT INTO products(sku, name, description, category_id, price, quantity,
+		reorder_threshold, tax_category, weight, length, width, height,
+		inventory_policy, backorder_limit, expected_ship_date,
+		type, bundle_pricing, bundle_discount)
+	 VALUES(@sku, @name, @description, @category, @price, @quantity,
+		@reorder_threshold, @tax_category, @weight, @length, @width, @height,
+		@inventory_policy, @backorder_limit, @expected_ship_date,
+		@type, @bundle_pricing, @bundle_discount)
 	 RETURNING id, version
 	`
 
+	if product.Type == "" {
+		product.Type = domain.ProductTypeSimple
+	}
+
+	if product.BundlePricing == "" {
+		product.BundlePricing = domain.BundlePricingFixed
+	}
+
+	if product.InventoryPolicy == "" {
+		product.InventoryPolicy = domain.InventoryPolicyDeny
+	}
+
+	if product.TaxCategory == "" {
+		product.TaxCategory = domain.DefaultTaxCategory
+	}
+
 	args := pgx.NamedArgs{
-		"name":        &product.Name,
-		"description": &product.Description,
-		"category":    &product.CategoryID,
-		"price":       &product.Price,
-		"quantity":    &product.Quantity,
+		"sku":             &product.SKU,
+		"name":            &product.Name,
+		"description":     &product.Description,
+		"category":        &product.CategoryID,
+		"price":           &product.Price,
+		"quantity":        &product.Quantity,
+		"type":            &product.Type,
+		"bundle_pricing":  &product.BundlePricing,
+		"bundle_discount": &product.BundleDiscount,
+
+		"reorder_threshold":  &product.ReorderThreshold,
+		"tax_category":       &product.TaxCategory,
+		"weight":             &product.Weight,
+		"length":             &product.Length,
+		"width":              &product.Width,
+		"height":             &product.Height,
+		"inventory_policy":   &product.InventoryPolicy,
+		"backorder_limit":    &product.BackorderLimit,
+		"expected_ship_date": product.ExpectedShipDate,
+	}
+
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBeginTransaction, err)
 	}
+	defer tx.Rollback(ctx)
 
-	err := p.db.QueryRow(ctx, query, args).Scan(&product.ID, &product.Version)
+	err = tx.QueryRow(ctx, query, args).Scan(&product.ID, &product.Version)
 	if err != nil {
 		pgErr := pgError(err)
 		switch pgErr.Code {
@@ -49,9 +91,42 @@ func (p productStore) Create(ctx context.Context, product *domain.Product) error
 			if pgErr.ConstraintName == "products_name_key" {
 				return domain.ErrDuplicatedProduct
 			}
+			if pgErr.ConstraintName == "products_sku_key" {
+				return domain.ErrDuplicatedSKU
+			}
+		}
+		return err
+	}
+
+	if product.IsBundle() {
+		err = insertComponents(ctx, tx, product.ID, product.Components)
+		if err != nil {
+			return err
+		}
+	} else if product.Quantity != 0 {
+		err = insertMovements(ctx, tx, []domain.StockMovement{{
+			ProductID:   product.ID,
+			WarehouseID: domain.DefaultWarehouseID,
+			Type:        domain.StockMovementReceipt,
+			Quantity:    product.Quantity,
+			Note:        "initial stock",
+		}})
+		if err != nil {
+			return err
 		}
+	}
+
+	products := []domain.Product{*product}
+	err = applyStock(ctx, tx, products)
+	if err != nil {
 		return err
 	}
+	*product = products[0]
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
 
 	return nil
 }
@@ -91,18 +166,36 @@ func (p productStore) List(ctx context.Context, filter domain.ProductFilter) ([]
 		return nil, domain.ErrNoProductsFound
 	}
 
+	err = applyStock(ctx, p.db, products)
+	if err != nil {
+		return nil, err
+	}
+
 	return products, nil
 }
 
-// Update updates a product by id in database.
+// Update updates a product by id in database, a new quantity is recorded
+// as an adjustment of the stock ledger.
 func (p productStore) Update(ctx context.Context, ID int, input domain.ProductUpdate) (domain.Product, error) {
 	query := `
 	UPDATE products
-	SET name        = COALESCE(@name,name),
+	SET sku         = COALESCE(@sku, sku),
+		name        = COALESCE(@name,name),
 		description = COALESCE(@description, description),
 		category_id = COALESCE(@category, category_id),
 		price       = COALESCE(@price, price),
-		quantity    = COALESCE(@quantity, quantity),
+		reorder_threshold = COALESCE(@reorder_threshold, reorder_threshold),
+		tax_category      = COALESCE(@tax_category, tax_category),
+		weight            = COALESCE(@weight, weight),
+		length            = COALESCE(@length, length),
+		width             = COALESCE(@width, width),
+		height            = COALESCE(@height, height),
+		inventory_policy  = CASE WHEN type = 'bundle' THEN inventory_policy
+							ELSE COALESCE(@inventory_policy, inventory_policy) END,
+		backorder_limit   = COALESCE(@backorder_limit, backorder_limit),
+		expected_ship_date = COALESCE(@expected_ship_date, expected_ship_date),
+		bundle_pricing  = COALESCE(@bundle_pricing, bundle_pricing),
+		bundle_discount = COALESCE(@bundle_discount, bundle_discount),
 		updated_at  = NOW(),
 		version     = version + 1
 	WHERE id = @id AND version = @version
@@ -110,16 +203,35 @@ func (p productStore) Update(ctx context.Context, ID int, input domain.ProductUp
 	`
 
 	args := pgx.NamedArgs{
+		"sku":         &input.SKU,
 		"name":        &input.Name,
 		"description": &input.Description,
 		"category":    &input.CategoryID,
 		"price":       &input.Price,
-		"quantity":    &input.Quantity,
 		"version":     &input.Version,
 		"id":          &ID,
+
+		"bundle_pricing":  &input.BundlePricing,
+		"bundle_discount": &input.BundleDiscount,
+
+		"reorder_threshold":  &input.ReorderThreshold,
+		"tax_category":       &input.TaxCategory,
+		"weight":             &input.Weight,
+		"length":             &input.Length,
+		"width":              &input.Width,
+		"height":             &input.Height,
+		"inventory_policy":   &input.InventoryPolicy,
+		"backorder_limit":    &input.BackorderLimit,
+		"expected_ship_date": input.ExpectedShipDate,
+	}
+
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return domain.Product{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
 	}
+	defer tx.Rollback(ctx)
 
-	row, err := p.db.Query(ctx, query, args)
+	row, err := tx.Query(ctx, query, args)
 	if err != nil {
 		return domain.Product{}, fmt.Errorf("failed to query update product: %v", err)
 	}
@@ -136,6 +248,9 @@ func (p productStore) Update(ctx context.Context, ID int, input domain.ProductUp
 			if pgErr.ConstraintName == "products_name_key" {
 				return domain.Product{}, domain.ErrDuplicatedProduct
 			}
+			if pgErr.ConstraintName == "products_sku_key" {
+				return domain.Product{}, domain.ErrDuplicatedSKU
+			}
 		}
 
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -145,7 +260,38 @@ func (p productStore) Update(ctx context.Context, ID int, input domain.ProductUp
 		return domain.Product{}, fmt.Errorf("failed to scan rows of product: %v", err)
 	}
 
-	return product, nil
+	if input.Quantity != nil && !product.IsBundle() && *input.Quantity != product.Quantity {
+		err = adjustStock(ctx, tx, product.ID, *input.Quantity-product.Quantity)
+		if err != nil {
+			return domain.Product{}, err
+		}
+
+		query = `SELECT quantity, version FROM products WHERE id = @id`
+		err = tx.QueryRow(ctx, query, pgx.NamedArgs{"id": product.ID}).Scan(&product.Quantity, &product.Version)
+		if err != nil {
+			return domain.Product{}, fmt.Errorf("failed to query product stock: %v", err)
+		}
+	}
+
+	products := []domain.Product{product}
+	err = applyStock(ctx, tx, products)
+	if err != nil {
+		return domain.Product{}, err
+	}
+
+	if input.Price != nil {
+		err = queuePriceDrops(ctx, tx, products[0])
+		if err != nil {
+			return domain.Product{}, err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.Product{}, fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return products[0], nil
 }
 
 // Delete deletes a product by id from database.
@@ -170,3 +316,52 @@ func (p productStore) Delete(ctx context.Context, ID int) error {
 
 	return nil
 }
+
+// productType returns type of a product.
+func productType(ctx context.Context, q querier, ID int) (string, error) {
+	query := `
+	SELECT type FROM products
+	WHERE id = @id
+	`
+
+	var typ string
+	err := q.QueryRow(ctx, query, pgx.NamedArgs{"id": ID}).Scan(&typ)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", domain.ErrNoProductsFound
+		}
+		return "", err
+	}
+
+	return typ, nil
+}
+
+// getProducts returns products of ids keyed by their id.
+func getProducts(ctx context.Context, q querier, ids []int) (map[int]domain.Product, error) {
+	query := `
+	SELECT * FROM products
+	WHERE id = ANY(@ids)
+	`
+
+	rows, err := q.Query(ctx, query, pgx.NamedArgs{"ids": ids})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query list products: %v", err)
+	}
+
+	products, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.Product])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of products: %v", err)
+	}
+
+	err = applyStock(ctx, q, products)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[int]domain.Product, len(products))
+	for _, p := range products {
+		result[p.ID] = p
+	}
+
+	return result, nil
+}
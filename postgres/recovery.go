@@ -0,0 +1,237 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// cartRecoveryStore represents abandoned cart recoveries database.
+type cartRecoveryStore struct {
+	db *pgxpool.Pool
+}
+
+// NewCartRecoveryStore returns a new instance of CartRecoveryStore.
+func NewCartRecoveryStore(db *pgxpool.Pool) cartRecoveryStore {
+	return cartRecoveryStore{db: db}
+}
+
+// DetectAbandoned records carts of users with items left alone since before
+// and queues their recovery emails, carts are emailed once until they are
+// updated again. Carts being recovered by another call are skipped.
+func (c cartRecoveryStore) DetectAbandoned(ctx context.Context, before time.Time, secret []byte, limit int) (int, error) {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+	SELECT c.id, c.user_id, u.email FROM carts c
+	INNER JOIN users u ON u.id = c.user_id
+	WHERE c.updated_at < @before
+		AND EXISTS(SELECT 1 FROM cart_items i WHERE i.cart_id = c.id)
+		AND NOT EXISTS(SELECT 1 FROM cart_recoveries r WHERE r.cart_id = c.id AND r.sent_at >= c.updated_at)
+	ORDER BY c.updated_at
+	LIMIT @limit
+	FOR UPDATE OF c SKIP LOCKED
+	`
+
+	rows, err := tx.Query(ctx, query, pgx.NamedArgs{"before": before, "limit": limit})
+	if err != nil {
+		return 0, fmt.Errorf("failed to query abandoned carts: %v", err)
+	}
+
+	var carts []domain.Cart
+	emails := make(map[int]string)
+	var cart domain.Cart
+	var email string
+	_, err = pgx.ForEachRow(rows, []any{&cart.ID, &cart.UserID, &email}, func() error {
+		carts = append(carts, cart)
+		emails[cart.ID] = email
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan rows of abandoned carts: %v", err)
+	}
+
+	if len(carts) == 0 {
+		return 0, nil
+	}
+
+	err = fillCarts(ctx, tx, carts)
+	if err != nil {
+		return 0, err
+	}
+
+	query = `
+	INSERT INTO cart_recoveries(cart_id, user_id, email, items, total)
+	VALUES(@cart_id, @user_id, @email, @items, @total)
+	RETURNING id, sent_at
+	`
+
+	for _, cart := range carts {
+		recovery := domain.NewCartRecovery(cart, emails[cart.ID])
+
+		args := pgx.NamedArgs{
+			"cart_id": recovery.CartID,
+			"user_id": recovery.UserID,
+			"email":   recovery.Email,
+			"items":   recovery.Items,
+			"total":   recovery.Total,
+		}
+
+		err = tx.QueryRow(ctx, query, args).Scan(&recovery.ID, &recovery.SentAt)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert cart recovery: %v", err)
+		}
+
+		link := domain.CartRecoveryLink(secret, recovery.ID, recovery.SentAt)
+		err = insertNotification(ctx, tx, domain.CartRecoveryNotification(recovery, link))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return len(carts), nil
+}
+
+// Restore puts items of a recovery back into cart of its user, products
+// deleted since are left out.
+func (c cartRecoveryStore) Restore(ctx context.Context, ID int) (domain.Cart, error) {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return domain.Cart{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `SELECT * FROM cart_recoveries WHERE id = @id FOR UPDATE`, pgx.NamedArgs{"id": ID})
+	if err != nil {
+		return domain.Cart{}, fmt.Errorf("failed to query cart recovery: %v", err)
+	}
+
+	recovery, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.CartRecovery])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Cart{}, domain.ErrNoCartRecoveriesFound
+		}
+		return domain.Cart{}, fmt.Errorf("failed to scan row of cart recovery: %v", err)
+	}
+
+	cartID, err := ensureCart(ctx, tx, domain.CartOwner{UserID: recovery.UserID})
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	items, err := listCartItems(ctx, tx, []int{cartID})
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	quantities := make(map[int]int, len(items))
+	for _, item := range items {
+		quantities[item.ProductID] = item.Quantity
+	}
+
+	productIDs := make([]int, 0, len(recovery.Items))
+	for _, item := range recovery.Items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+
+	products, err := getProducts(ctx, tx, productIDs)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	for _, line := range recovery.Items {
+		if _, ok := products[line.ProductID]; !ok || quantities[line.ProductID] >= line.Quantity {
+			continue
+		}
+
+		item := domain.CartItem{ProductID: line.ProductID, Quantity: line.Quantity, UpdatedAt: time.Now()}
+		item.ID, err = setCartItem(ctx, tx, cartID, item)
+		if err != nil {
+			return domain.Cart{}, err
+		}
+
+		err = reserveStock(ctx, tx, item)
+		if err != nil {
+			return domain.Cart{}, err
+		}
+	}
+
+	query := `
+	UPDATE cart_recoveries
+	SET cart_id     = @cart_id,
+		restored_at = COALESCE(restored_at, NOW())
+	WHERE id = @id
+	`
+
+	_, err = tx.Exec(ctx, query, pgx.NamedArgs{"id": ID, "cart_id": cartID})
+	if err != nil {
+		return domain.Cart{}, fmt.Errorf("failed to update cart recovery: %v", err)
+	}
+
+	return commitCart(ctx, tx, cartID)
+}
+
+// Stats reports recovery emails sent between from and to.
+func (c cartRecoveryStore) Stats(ctx context.Context, from *time.Time, to *time.Time) (domain.CartRecoveryStats, error) {
+	query := `
+	SELECT COUNT(*), COUNT(restored_at), COUNT(converted_at), COALESCE(SUM(order_total), 0)
+	FROM cart_recoveries
+	WHERE (@from::timestamptz IS NULL OR sent_at >= @from)
+		AND (@to::timestamptz IS NULL OR sent_at < @to)
+	`
+
+	stats := domain.CartRecoveryStats{From: from, To: to}
+	err := c.db.QueryRow(ctx, query, pgx.NamedArgs{"from": from, "to": to}).
+		Scan(&stats.Sent, &stats.Restored, &stats.Converted, &stats.Revenue)
+	if err != nil {
+		return domain.CartRecoveryStats{}, fmt.Errorf("failed to query cart recovery stats: %v", err)
+	}
+
+	stats.CalculateRates()
+	return stats, nil
+}
+
+// convertCartRecovery attributes order checked out of cart to the latest
+// recovery email of cart sent within domain.CartRecoveryWindow.
+func convertCartRecovery(ctx context.Context, q querier, cartID int, order domain.Order) error {
+	query := `
+	UPDATE cart_recoveries
+	SET order_id     = @order_id,
+		order_total  = @order_total,
+		converted_at = NOW()
+	WHERE id = (
+		SELECT id FROM cart_recoveries
+		WHERE cart_id = @cart_id AND converted_at IS NULL AND sent_at > @since
+		ORDER BY sent_at DESC
+		LIMIT 1
+	)
+	`
+
+	args := pgx.NamedArgs{
+		"cart_id":     cartID,
+		"order_id":    order.ID,
+		"order_total": order.Total,
+		"since":       time.Now().Add(-domain.CartRecoveryWindow),
+	}
+
+	_, err := q.Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("failed to update cart recovery: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+	"github.com/mortezadadgar/ecommerce-api/postgres"
+)
+
+func TestCartRecoveryService(t *testing.T) {
+	db := newCartTestDB(t, "cart_recoveries")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	carts := postgres.NewCartStore(db)
+	store := postgres.NewCartRecoveryStore(db)
+	secret := []byte("secret")
+
+	cart, err := carts.AddItem(ctx, domain.CartOwner{UserID: 1}, domain.CartItem{ProductID: 1, Quantity: 2})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	if cart.UpdatedAt.IsZero() || cart.CreatedAt.After(cart.UpdatedAt) {
+		t.Errorf("expected cart timestamps, got: %v and %v", cart.CreatedAt, cart.UpdatedAt)
+	}
+
+	sent, err := store.DetectAbandoned(ctx, time.Now().Add(-time.Hour), secret, 10)
+	if err != nil {
+		t.Fatalf("DetectAbandoned: %v", err)
+	}
+
+	if sent != 0 {
+		t.Errorf("expected fresh cart not abandoned, got %d emails", sent)
+	}
+
+	sent, err = store.DetectAbandoned(ctx, time.Now().Add(time.Minute), secret, 10)
+	if err != nil {
+		t.Fatalf("DetectAbandoned: %v", err)
+	}
+
+	if sent != 1 {
+		t.Fatalf("expected 1 recovery email, got %d", sent)
+	}
+
+	// carts are emailed once until they change.
+	sent, err = store.DetectAbandoned(ctx, time.Now().Add(time.Minute), secret, 10)
+	if err != nil {
+		t.Fatalf("DetectAbandoned: %v", err)
+	}
+
+	if sent != 0 {
+		t.Errorf("expected abandoned cart emailed once, got %d emails", sent)
+	}
+
+	var recoveryID int
+	var body string
+	err = db.QueryRow(ctx, `SELECT id FROM cart_recoveries`).Scan(&recoveryID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.QueryRow(ctx, `SELECT body FROM notifications WHERE kind = $1`, domain.NotificationCartRecovery).Scan(&body)
+	if err != nil {
+		t.Fatalf("expected queued recovery email: %v", err)
+	}
+
+	err = carts.Clear(ctx, domain.CartOwner{UserID: 1})
+	if err != nil {
+		t.Fatalf("Clear: %v", err)
+	}
+
+	cart, err = store.Restore(ctx, recoveryID)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	if len(cart.Items) != 1 || cart.Items[0].ProductID != 1 || cart.Items[0].Quantity != 2 {
+		t.Errorf("expected restored cart items, got: %#v", cart.Items)
+	}
+
+	_, err = store.Restore(ctx, recoveryID+1)
+	if err != domain.ErrNoCartRecoveriesFound {
+		t.Errorf("expected %q from Restore, got %q", domain.ErrNoCartRecoveriesFound, err)
+	}
+
+	order, err := postgres.NewOrderStore(db).Checkout(ctx, 1, domain.CheckoutDetails{})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+
+	stats, err := store.Stats(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+
+	want := domain.CartRecoveryStats{Sent: 1, Restored: 1, Converted: 1, Revenue: order.Total, RestoreRate: 1, ConversionRate: 1}
+	if stats != want {
+		t.Errorf("mismatch\n got: %#v\nwant: %#v", stats, want)
+	}
+
+	future := time.Now().Add(time.Hour)
+	stats, err = store.Stats(ctx, &future, nil)
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+
+	if stats.Sent != 0 {
+		t.Errorf("expected no emails sent in future, got: %#v", stats)
+	}
+}
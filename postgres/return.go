@@ -0,0 +1,423 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// returnStore represents returns database.
+type returnStore struct {
+	db *pgxpool.Pool
+}
+
+// NewReturnStore returns a new instance of ReturnStore.
+func NewReturnStore(db *pgxpool.Pool) returnStore {
+	return returnStore{db: db}
+}
+
+// GetByID get return by id from database.
+func (r returnStore) GetByID(ctx context.Context, ID int) (domain.Return, error) {
+	returns, err := r.List(ctx, domain.ReturnFilter{ID: ID})
+	if err != nil {
+		return domain.Return{}, err
+	}
+
+	return returns[0], nil
+}
+
+// List lists returns with optional filter.
+func (r returnStore) List(ctx context.Context, filter domain.ReturnFilter) ([]domain.Return, error) {
+	query := `
+	SELECT * FROM returns
+	WHERE 1=1
+	` + FormatAndInt("id", filter.ID) + `
+	` + FormatAndInt("order_id", filter.OrderID) + `
+	` + FormatSort(filter.Sort) + `
+	` + FormatLimitOffset(filter.Limit, filter.Offset) + `
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query list returns: %v", err)
+	}
+
+	returns, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.Return])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of returns: %v", err)
+	}
+
+	if len(returns) == 0 {
+		return nil, domain.ErrNoReturnsFound
+	}
+
+	err = fillReturns(ctx, r.db, returns)
+	if err != nil {
+		return nil, err
+	}
+
+	return returns, nil
+}
+
+// Create requests a return of order lines, lines are checked against
+// quantities of order not returned yet.
+func (r returnStore) Create(ctx context.Context, rma *domain.Return) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	order, err := lockOrder(ctx, tx, rma.OrderID)
+	if err != nil {
+		return err
+	}
+
+	orders := []domain.Order{order}
+	err = fillOrders(ctx, tx, orders)
+	if err != nil {
+		return err
+	}
+
+	err = orders[0].CanReturn(*rma)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO returns(order_id, reason)
+	VALUES(@order_id, @reason)
+	RETURNING id, rma_number, status, created_at, updated_at
+	`
+
+	args := pgx.NamedArgs{
+		"order_id": rma.OrderID,
+		"reason":   rma.Reason,
+	}
+
+	err = tx.QueryRow(ctx, query, args).Scan(&rma.ID, &rma.RMANumber, &rma.Status, &rma.CreatedAt, &rma.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert return: %v", err)
+	}
+
+	query = `
+	INSERT INTO return_lines(return_id, order_line_id, quantity)
+	VALUES(@return_id, @order_line_id, @quantity)
+	RETURNING id
+	`
+
+	for i := range rma.Lines {
+		line := &rma.Lines[i]
+		line.ReturnID = rma.ID
+
+		args := pgx.NamedArgs{
+			"return_id":     line.ReturnID,
+			"order_line_id": line.OrderLineID,
+			"quantity":      line.Quantity,
+		}
+
+		err = tx.QueryRow(ctx, query, args).Scan(&line.ID)
+		if err != nil {
+			return fmt.Errorf("failed to insert return line: %v", err)
+		}
+	}
+
+	err = updateReturned(ctx, tx, rma.ID, 1)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return nil
+}
+
+// UpdateStatus moves a return to status, rejected returns free their
+// quantities to be returned again.
+func (r returnStore) UpdateStatus(ctx context.Context, ID int, status string, note string) (domain.Return, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return domain.Return{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	rma, err := lockReturn(ctx, tx, ID)
+	if err != nil {
+		return domain.Return{}, err
+	}
+
+	err = rma.Transition(status)
+	if err != nil {
+		return domain.Return{}, err
+	}
+	rma.Note = note
+
+	if rma.Status == domain.ReturnStatusRejected {
+		err = updateReturned(ctx, tx, rma.ID, -1)
+		if err != nil {
+			return domain.Return{}, err
+		}
+	}
+
+	return commitReturn(ctx, tx, rma)
+}
+
+// Receive marks items of a return as received and optionally gives them
+// back to products stock.
+func (r returnStore) Receive(ctx context.Context, ID int, restock bool) (domain.Return, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return domain.Return{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	rma, err := lockReturn(ctx, tx, ID)
+	if err != nil {
+		return domain.Return{}, err
+	}
+
+	// returns being refunded go back to received by CancelRefund only.
+	if rma.Status != domain.ReturnStatusApproved {
+		return domain.Return{}, domain.ErrInvalidReturnTransition
+	}
+
+	err = rma.Transition(domain.ReturnStatusReceived)
+	if err != nil {
+		return domain.Return{}, err
+	}
+
+	if restock {
+		err = restockReturn(ctx, tx, rma)
+		if err != nil {
+			return domain.Return{}, err
+		}
+		rma.Restocked = true
+	}
+
+	return commitReturn(ctx, tx, rma)
+}
+
+// StartRefund moves a received return to refunding, the claim is committed
+// before any money is moved so concurrent refunds of it fail.
+func (r returnStore) StartRefund(ctx context.Context, ID int) (domain.Return, error) {
+	return r.transition(ctx, ID, domain.ReturnStatusRefunding)
+}
+
+// CancelRefund moves a return being refunded back to received.
+func (r returnStore) CancelRefund(ctx context.Context, ID int) (domain.Return, error) {
+	return r.transition(ctx, ID, domain.ReturnStatusReceived)
+}
+
+// transition moves a return to status.
+func (r returnStore) transition(ctx context.Context, ID int, status string) (domain.Return, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return domain.Return{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	rma, err := lockReturn(ctx, tx, ID)
+	if err != nil {
+		return domain.Return{}, err
+	}
+
+	err = rma.Transition(status)
+	if err != nil {
+		return domain.Return{}, err
+	}
+
+	return commitReturn(ctx, tx, rma)
+}
+
+// Refund records refunded amounts by return line id on return lines and
+// their order lines along with settlement of the refund, nothing is
+// recorded when settlement fails.
+func (r returnStore) Refund(ctx context.Context, ID int, amounts map[int]int, settlement domain.ReturnSettlement) (domain.Return, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return domain.Return{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	rma, err := lockReturn(ctx, tx, ID)
+	if err != nil {
+		return domain.Return{}, err
+	}
+
+	err = rma.Transition(domain.ReturnStatusRefunded)
+	if err != nil {
+		return domain.Return{}, err
+	}
+
+	query := `
+	WITH line AS (
+		UPDATE return_lines
+		SET refunded = refunded + @amount
+		WHERE id = @id AND return_id = @return_id
+		RETURNING order_line_id
+	)
+	UPDATE order_lines
+	SET refunded = refunded + @amount
+	WHERE id = (SELECT order_line_id FROM line)
+	`
+
+	for _, line := range rma.Lines {
+		amount := amounts[line.ID]
+		if amount == 0 {
+			continue
+		}
+
+		args := pgx.NamedArgs{
+			"id":        line.ID,
+			"return_id": rma.ID,
+			"amount":    amount,
+		}
+
+		_, err = tx.Exec(ctx, query, args)
+		if err != nil {
+			return domain.Return{}, fmt.Errorf("failed to update refunded amount: %v", err)
+		}
+
+		rma.Refunded += amount
+	}
+
+	if settlement.PaymentID != 0 {
+		if settlement.StoreCredit {
+			_, err = refundStoreCredit(ctx, tx, settlement.PaymentID, settlement.Amount, settlement.Note)
+		} else {
+			_, err = syncPayment(ctx, tx, settlement.PaymentID, settlement.Intent)
+		}
+		if err != nil {
+			return domain.Return{}, err
+		}
+	}
+
+	return commitReturn(ctx, tx, rma)
+}
+
+// lockReturn selects a return for update.
+func lockReturn(ctx context.Context, q querier, ID int) (domain.Return, error) {
+	rows, err := q.Query(ctx, `SELECT * FROM returns WHERE id = @id FOR UPDATE`, pgx.NamedArgs{"id": ID})
+	if err != nil {
+		return domain.Return{}, fmt.Errorf("failed to query return: %v", err)
+	}
+
+	rma, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.Return])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Return{}, domain.ErrNoReturnsFound
+		}
+		return domain.Return{}, fmt.Errorf("failed to scan row of return: %v", err)
+	}
+
+	returns := []domain.Return{rma}
+	err = fillReturns(ctx, q, returns)
+	if err != nil {
+		return domain.Return{}, err
+	}
+
+	return returns[0], nil
+}
+
+// updateReturned adds quantities of return lines times sign to returned
+// quantities of their order lines.
+func updateReturned(ctx context.Context, q querier, returnID int, sign int) error {
+	query := `
+	UPDATE order_lines l
+	SET returned = l.returned + r.quantity * @sign
+	FROM return_lines r
+	WHERE r.order_line_id = l.id AND r.return_id = @return_id
+	`
+
+	_, err := q.Exec(ctx, query, pgx.NamedArgs{"return_id": returnID, "sign": sign})
+	if err != nil {
+		return fmt.Errorf("failed to update returned quantities: %v", err)
+	}
+
+	return nil
+}
+
+// commitReturn saves state of a locked return and commits transaction.
+func commitReturn(ctx context.Context, tx pgx.Tx, rma domain.Return) (domain.Return, error) {
+	query := `
+	UPDATE returns
+	SET status     = @status,
+		note       = @note,
+		restocked  = @restocked,
+		refunded   = @refunded,
+		updated_at = NOW()
+	WHERE id = @id
+	RETURNING updated_at
+	`
+
+	args := pgx.NamedArgs{
+		"id":        rma.ID,
+		"status":    rma.Status,
+		"note":      rma.Note,
+		"restocked": rma.Restocked,
+		"refunded":  rma.Refunded,
+	}
+
+	err := tx.QueryRow(ctx, query, args).Scan(&rma.UpdatedAt)
+	if err != nil {
+		return domain.Return{}, fmt.Errorf("failed to update return: %v", err)
+	}
+
+	returns := []domain.Return{rma}
+	err = fillReturns(ctx, tx, returns)
+	if err != nil {
+		return domain.Return{}, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.Return{}, fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return returns[0], nil
+}
+
+// fillReturns loads lines of returns.
+func fillReturns(ctx context.Context, q querier, returns []domain.Return) error {
+	ids := make([]int, 0, len(returns))
+	for _, r := range returns {
+		ids = append(ids, r.ID)
+	}
+
+	query := `
+	SELECT * FROM return_lines
+	WHERE return_id = ANY(@ids)
+	ORDER BY id
+	`
+
+	rows, err := q.Query(ctx, query, pgx.NamedArgs{"ids": ids})
+	if err != nil {
+		return fmt.Errorf("failed to query list return lines: %v", err)
+	}
+
+	lines, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.ReturnLine])
+	if err != nil {
+		return fmt.Errorf("failed to scan rows of return lines: %v", err)
+	}
+
+	returnLines := make(map[int][]domain.ReturnLine)
+	for _, line := range lines {
+		returnLines[line.ReturnID] = append(returnLines[line.ReturnID], line)
+	}
+
+	for i := range returns {
+		returns[i].Lines = returnLines[returns[i].ID]
+		if returns[i].Lines == nil {
+			returns[i].Lines = []domain.ReturnLine{}
+		}
+	}
+
+	return nil
+}
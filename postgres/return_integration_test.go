@@ -0,0 +1,150 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+	"github.com/mortezadadgar/ecommerce-api/postgres"
+)
+
+func TestReturnService_Lifecycle(t *testing.T) {
+	db := newCartTestDB(t, "returns_lifecycle")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := postgres.NewCartStore(db).AddItem(ctx, domain.CartOwner{UserID: 1}, domain.CartItem{ProductID: 1, Quantity: 3})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	order, err := postgres.NewOrderStore(db).Checkout(ctx, 1, domain.CheckoutDetails{})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+
+	input := domain.ReturnCreate{Reason: "damaged", Lines: []domain.ReturnLineCreate{{OrderLineID: order.Lines[0].ID, Quantity: 2}}}
+
+	rma := input.CreateModel(order.ID)
+	err = postgres.NewReturnStore(db).Create(ctx, &rma)
+	if err != domain.ErrOrderNotReturnable {
+		t.Errorf("expected %q from Create, got %q", domain.ErrOrderNotReturnable, err)
+	}
+
+	for _, status := range []string{
+		domain.OrderStatusPaid,
+		domain.OrderStatusFulfilling,
+		domain.OrderStatusShipped,
+		domain.OrderStatusDelivered,
+	} {
+		_, err = postgres.NewOrderStore(db).UpdateStatus(ctx, order.ID, status, 0, "")
+		if err != nil {
+			t.Fatalf("UpdateStatus: %v", err)
+		}
+	}
+
+	rma = input.CreateModel(order.ID)
+	err = postgres.NewReturnStore(db).Create(ctx, &rma)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if rma.RMANumber == "" || rma.Status != domain.ReturnStatusRequested {
+		t.Errorf("expected requested return with RMA number, got: %#v", rma)
+	}
+
+	again := input.CreateModel(order.ID)
+	err = postgres.NewReturnStore(db).Create(ctx, &again)
+	if err != domain.ErrInvalidReturnQuantity {
+		t.Errorf("expected %q from Create, got %q", domain.ErrInvalidReturnQuantity, err)
+	}
+
+	_, err = postgres.NewReturnStore(db).Receive(ctx, rma.ID, true)
+	if err != domain.ErrInvalidReturnTransition {
+		t.Errorf("expected %q from Receive, got %q", domain.ErrInvalidReturnTransition, err)
+	}
+
+	_, err = postgres.NewReturnStore(db).UpdateStatus(ctx, rma.ID, domain.ReturnStatusApproved, "")
+	if err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+
+	rma, err = postgres.NewReturnStore(db).Receive(ctx, rma.ID, true)
+	if err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+
+	product, err := postgres.NewProductStore(db).GetByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+
+	if product.Quantity != 9 {
+		t.Errorf("expected restocked quantity of %d, got: %d", 9, product.Quantity)
+	}
+
+	_, err = postgres.NewReturnStore(db).Refund(ctx, rma.ID, map[int]int{rma.Lines[0].ID: 15}, domain.ReturnSettlement{})
+	if err != domain.ErrInvalidReturnTransition {
+		t.Errorf("expected %q from unclaimed Refund, got %q", domain.ErrInvalidReturnTransition, err)
+	}
+
+	_, err = postgres.NewReturnStore(db).StartRefund(ctx, rma.ID)
+	if err != nil {
+		t.Fatalf("StartRefund: %v", err)
+	}
+
+	_, err = postgres.NewReturnStore(db).StartRefund(ctx, rma.ID)
+	if err != domain.ErrInvalidReturnTransition {
+		t.Errorf("expected %q from claimed StartRefund, got %q", domain.ErrInvalidReturnTransition, err)
+	}
+
+	rma, err = postgres.NewReturnStore(db).CancelRefund(ctx, rma.ID)
+	if err != nil {
+		t.Fatalf("CancelRefund: %v", err)
+	}
+
+	if rma.Status != domain.ReturnStatusReceived {
+		t.Errorf("expected status %q, got: %q", domain.ReturnStatusReceived, rma.Status)
+	}
+
+	_, err = postgres.NewReturnStore(db).StartRefund(ctx, rma.ID)
+	if err != nil {
+		t.Fatalf("StartRefund: %v", err)
+	}
+
+	// nothing is recorded when settlement of refund fails.
+	settlement := domain.ReturnSettlement{PaymentID: 1000, Amount: 15, StoreCredit: true}
+	_, err = postgres.NewReturnStore(db).Refund(ctx, rma.ID, map[int]int{rma.Lines[0].ID: 15}, settlement)
+	if err != domain.ErrNoPaymentsFound {
+		t.Errorf("expected %q from Refund, got %q", domain.ErrNoPaymentsFound, err)
+	}
+
+	rma, err = postgres.NewReturnStore(db).GetByID(ctx, rma.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+
+	if rma.Status != domain.ReturnStatusRefunding || rma.Refunded != 0 {
+		t.Errorf("expected return left refunding, got: %#v", rma)
+	}
+
+	rma, err = postgres.NewReturnStore(db).Refund(ctx, rma.ID, map[int]int{rma.Lines[0].ID: 15}, domain.ReturnSettlement{})
+	if err != nil {
+		t.Fatalf("Refund: %v", err)
+	}
+
+	if rma.Status != domain.ReturnStatusRefunded || rma.Refunded != 15 || rma.Lines[0].Refunded != 15 {
+		t.Errorf("expected refunded return, got: %#v", rma)
+	}
+
+	order, err = postgres.NewOrderStore(db).GetByID(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+
+	if order.Lines[0].Returned != 2 || order.Lines[0].Refunded != 15 {
+		t.Errorf("expected returned and refunded order line, got: %#v", order.Lines[0])
+	}
+}
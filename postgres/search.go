@@ -21,7 +21,7 @@ func NewSearchStore(db *pgxpool.Pool) searchStore {
 
 // Search full searches database.
 func (s searchStore) Search(ctx context.Context, query string) (results []domain.Search, err error) {
-	categories, err := fullSearchName[domain.Category](ctx, s.db, "categories", query)
+	categories, err := fullSearchName[domain.Category](ctx, s.db, "categories", "category_translations", "category_id", query)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -30,11 +30,16 @@ func (s searchStore) Search(ctx context.Context, query string) (results []domain
 		results = append(results, domain.Search{Categories: &categories[i]})
 	}
 
-	products, err := fullSearchName[domain.Product](ctx, s.db, "products", query)
+	products, err := fullSearchName[domain.Product](ctx, s.db, "products", "product_translations", "product_id", query)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	err = applyStock(ctx, s.db, products)
+	if err != nil {
+		return nil, err
+	}
+
 	for i := range products {
 		results = append(results, domain.Search{Prodcuts: &products[i]})
 	}
@@ -46,10 +51,16 @@ func (s searchStore) Search(ctx context.Context, query string) (results []domain
 	return results, nil
 }
 
-func fullSearchName[T any](ctx context.Context, db *pgxpool.Pool, table string, query string) (results []T, err error) {
+// fullSearchName searches names and descriptions of table and of every
+// locale in its translations table.
+func fullSearchName[T any](ctx context.Context, db *pgxpool.Pool, table string, translations string, column string, query string) (results []T, err error) {
 	sqlQuery := `
 	SELECT * FROM ` + table + `
-	WHERE to_tsvector('simple', name) @@ to_tsquery('simple', @query);
+	WHERE to_tsvector('simple', name || ' ' || description) @@ to_tsquery('simple', @query)
+	OR id IN (
+		SELECT ` + column + ` FROM ` + translations + `
+		WHERE to_tsvector('simple', name || ' ' || description) @@ to_tsquery('simple', @query)
+	);
 	`
 
 	args := pgx.NamedArgs{
@@ -0,0 +1,482 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// shipmentStore represents shipments database.
+type shipmentStore struct {
+	db *pgxpool.Pool
+}
+
+// NewShipmentStore returns a new instance of ShipmentStore.
+func NewShipmentStore(db *pgxpool.Pool) shipmentStore {
+	return shipmentStore{db: db}
+}
+
+// GetByID get shipment by id from database.
+func (s shipmentStore) GetByID(ctx context.Context, ID int) (domain.Shipment, error) {
+	shipments, err := s.List(ctx, domain.ShipmentFilter{ID: ID})
+	if err != nil {
+		return domain.Shipment{}, err
+	}
+
+	return shipments[0], nil
+}
+
+// List lists shipments with optional filter.
+func (s shipmentStore) List(ctx context.Context, filter domain.ShipmentFilter) ([]domain.Shipment, error) {
+	shipments, err := listShipments(ctx, s.db, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(shipments) == 0 {
+		return nil, domain.ErrNoShipmentsFound
+	}
+
+	return shipments, nil
+}
+
+// Create creates a shipment of order lines, lines are checked against
+// quantities of order left to ship and the order moves to fulfilling.
+func (s shipmentStore) Create(ctx context.Context, shipment *domain.Shipment) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	order, err := lockOrder(ctx, tx, shipment.OrderID)
+	if err != nil {
+		return err
+	}
+
+	orders := []domain.Order{order}
+	err = fillOrders(ctx, tx, orders)
+	if err != nil {
+		return err
+	}
+
+	err = orders[0].CanShip(*shipment)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO shipments(order_id, carrier, tracking_number)
+	VALUES(@order_id, @carrier, @tracking_number)
+	RETURNING id, status, created_at, updated_at
+	`
+
+	args := pgx.NamedArgs{
+		"order_id":        shipment.OrderID,
+		"carrier":         shipment.Carrier,
+		"tracking_number": shipment.TrackingNumber,
+	}
+
+	err = tx.QueryRow(ctx, query, args).Scan(&shipment.ID, &shipment.Status, &shipment.CreatedAt, &shipment.UpdatedAt)
+	if err != nil {
+		if isDuplicatedTrackingNumber(err) {
+			return domain.ErrDuplicatedTrackingNumber
+		}
+		return fmt.Errorf("failed to insert shipment: %v", err)
+	}
+
+	query = `
+	INSERT INTO shipment_lines(shipment_id, order_line_id, quantity)
+	VALUES(@shipment_id, @order_line_id, @quantity)
+	RETURNING id
+	`
+
+	for i := range shipment.Lines {
+		line := &shipment.Lines[i]
+		line.ShipmentID = shipment.ID
+
+		args := pgx.NamedArgs{
+			"shipment_id":   line.ShipmentID,
+			"order_line_id": line.OrderLineID,
+			"quantity":      line.Quantity,
+		}
+
+		err = tx.QueryRow(ctx, query, args).Scan(&line.ID)
+		if err != nil {
+			return fmt.Errorf("failed to insert shipment line: %v", err)
+		}
+	}
+
+	err = updateShipped(ctx, tx, shipment.ID, 1)
+	if err != nil {
+		return err
+	}
+
+	err = syncFulfillment(ctx, tx, &order, 0, fmt.Sprintf("shipment %d created", shipment.ID))
+	if err != nil {
+		return err
+	}
+
+	shipment.Events = []domain.ShipmentEvent{}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return nil
+}
+
+// Update updates carrier and tracking number of a shipment by id.
+func (s shipmentStore) Update(ctx context.Context, ID int, input domain.ShipmentUpdate) (domain.Shipment, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return domain.Shipment{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	shipment, err := lockShipment(ctx, tx, "id = @id", pgx.NamedArgs{"id": ID})
+	if err != nil {
+		return domain.Shipment{}, err
+	}
+
+	input.UpdateModel(&shipment)
+
+	query := `
+	UPDATE shipments
+	SET carrier         = @carrier,
+		tracking_number = @tracking_number,
+		updated_at      = NOW()
+	WHERE id = @id
+	RETURNING updated_at
+	`
+
+	args := pgx.NamedArgs{
+		"id":              shipment.ID,
+		"carrier":         shipment.Carrier,
+		"tracking_number": shipment.TrackingNumber,
+	}
+
+	err = tx.QueryRow(ctx, query, args).Scan(&shipment.UpdatedAt)
+	if err != nil {
+		if isDuplicatedTrackingNumber(err) {
+			return domain.Shipment{}, domain.ErrDuplicatedTrackingNumber
+		}
+		return domain.Shipment{}, fmt.Errorf("failed to update shipment: %v", err)
+	}
+
+	return commitShipment(ctx, tx, shipment)
+}
+
+// UpdateStatus moves a shipment to status, records it in tracking history
+// and moves its order by progress of its shipments.
+func (s shipmentStore) UpdateStatus(ctx context.Context, ID int, input domain.ShipmentStatusUpdate, changedBy int) (domain.Shipment, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return domain.Shipment{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	shipment, err := lockShipment(ctx, tx, "id = @id", pgx.NamedArgs{"id": ID})
+	if err != nil {
+		return domain.Shipment{}, err
+	}
+
+	from := shipment.Status
+	event, err := shipment.Transition(input.Status, input.Description, time.Now())
+	if err != nil {
+		return domain.Shipment{}, err
+	}
+
+	err = saveShipment(ctx, tx, &shipment, from, event, changedBy)
+	if err != nil {
+		return domain.Shipment{}, err
+	}
+
+	return commitShipment(ctx, tx, shipment)
+}
+
+// HandleTrackingEvent applies a carrier event to shipment of its tracking
+// number once, events not allowed by status of shipment are only recorded.
+func (s shipmentStore) HandleTrackingEvent(ctx context.Context, carrier string, event domain.TrackingEvent) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	shipment, err := lockShipment(ctx, tx, "carrier = @carrier AND tracking_number = @tracking_number",
+		pgx.NamedArgs{"carrier": carrier, "tracking_number": event.TrackingNumber})
+	if err != nil {
+		return err
+	}
+
+	from := shipment.Status
+	record, err := shipment.Transition(event.Status, event.Description, event.OccurredAt)
+	if err != nil && !errors.Is(err, domain.ErrInvalidShipmentTransition) {
+		return err
+	}
+	record.EventID = &event.ID
+
+	err = saveShipment(ctx, tx, &shipment, from, record, 0)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return nil
+}
+
+// listShipments lists shipments with optional filter along with their lines
+// and tracking history.
+func listShipments(ctx context.Context, q querier, filter domain.ShipmentFilter) ([]domain.Shipment, error) {
+	query := `
+	SELECT * FROM shipments
+	WHERE 1=1
+	` + FormatAndInt("id", filter.ID) + `
+	` + FormatAndInt("order_id", filter.OrderID) + `
+	` + FormatSort(filter.Sort) + `
+	` + FormatLimitOffset(filter.Limit, filter.Offset) + `
+	`
+
+	rows, err := q.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query list shipments: %v", err)
+	}
+
+	shipments, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.Shipment])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of shipments: %v", err)
+	}
+
+	err = fillShipments(ctx, q, shipments)
+	if err != nil {
+		return nil, err
+	}
+
+	return shipments, nil
+}
+
+// lockShipment selects a shipment matching condition for update.
+func lockShipment(ctx context.Context, q querier, condition string, args pgx.NamedArgs) (domain.Shipment, error) {
+	rows, err := q.Query(ctx, `SELECT * FROM shipments WHERE `+condition+` FOR UPDATE`, args)
+	if err != nil {
+		return domain.Shipment{}, fmt.Errorf("failed to query shipment: %v", err)
+	}
+
+	shipment, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.Shipment])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Shipment{}, domain.ErrNoShipmentsFound
+		}
+		return domain.Shipment{}, fmt.Errorf("failed to scan row of shipment: %v", err)
+	}
+
+	shipments := []domain.Shipment{shipment}
+	err = fillShipments(ctx, q, shipments)
+	if err != nil {
+		return domain.Shipment{}, err
+	}
+
+	return shipments[0], nil
+}
+
+// saveShipment updates status of a locked shipment moved from status,
+// records event in its tracking history and moves its order by progress of
+// its shipments. Quantities of cancelled shipments are freed to ship again.
+func saveShipment(ctx context.Context, q querier, shipment *domain.Shipment, from string,
+	event domain.ShipmentEvent, changedBy int,
+) error {
+	// order is locked before its lines are touched, as shipments are created.
+	order, err := lockOrder(ctx, q, shipment.OrderID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO shipment_events(shipment_id, carrier, event_id, status, description, occurred_at)
+	VALUES(@shipment_id, @carrier, @event_id, @status, @description, @occurred_at)
+	ON CONFLICT DO NOTHING
+	RETURNING id, created_at
+	`
+
+	args := pgx.NamedArgs{
+		"shipment_id": event.ShipmentID,
+		"carrier":     event.Carrier,
+		"event_id":    event.EventID,
+		"status":      event.Status,
+		"description": event.Description,
+		"occurred_at": event.OccurredAt,
+	}
+
+	err = q.QueryRow(ctx, query, args).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrDuplicatedTrackingEvent
+		}
+		return fmt.Errorf("failed to insert shipment event: %v", err)
+	}
+
+	query = `
+	UPDATE shipments
+	SET status       = @status,
+		shipped_at   = @shipped_at,
+		delivered_at = @delivered_at,
+		updated_at   = NOW()
+	WHERE id = @id
+	RETURNING updated_at
+	`
+
+	args = pgx.NamedArgs{
+		"id":           shipment.ID,
+		"status":       shipment.Status,
+		"shipped_at":   shipment.ShippedAt,
+		"delivered_at": shipment.DeliveredAt,
+	}
+
+	err = q.QueryRow(ctx, query, args).Scan(&shipment.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update shipment: %v", err)
+	}
+
+	if from != domain.ShipmentStatusCancelled && shipment.Status == domain.ShipmentStatusCancelled {
+		err = updateShipped(ctx, q, shipment.ID, -1)
+		if err != nil {
+			return err
+		}
+	}
+
+	return syncFulfillment(ctx, q, &order, changedBy,
+		fmt.Sprintf("shipment %d %s", shipment.ID, shipment.Status))
+}
+
+// syncFulfillment moves a locked order by progress of its shipments, note
+// is recorded in order history.
+func syncFulfillment(ctx context.Context, q querier, order *domain.Order, changedBy int, note string) error {
+	orders := []domain.Order{*order}
+	err := fillOrders(ctx, q, orders)
+	if err != nil {
+		return err
+	}
+	*order = orders[0]
+
+	shipments, err := listShipments(ctx, q, domain.ShipmentFilter{OrderID: order.ID})
+	if err != nil {
+		return err
+	}
+
+	for _, status := range domain.FulfillmentPath(order.Status, order.FulfillmentStatus(shipments)) {
+		err = changeStatus(ctx, q, order, status, changedBy, note)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateShipped adds quantities of shipment lines times sign to shipped
+// quantities of their order lines.
+func updateShipped(ctx context.Context, q querier, shipmentID int, sign int) error {
+	query := `
+	UPDATE order_lines l
+	SET shipped = l.shipped + s.quantity * @sign
+	FROM shipment_lines s
+	WHERE s.order_line_id = l.id AND s.shipment_id = @shipment_id
+	`
+
+	_, err := q.Exec(ctx, query, pgx.NamedArgs{"shipment_id": shipmentID, "sign": sign})
+	if err != nil {
+		return fmt.Errorf("failed to update shipped quantities: %v", err)
+	}
+
+	return nil
+}
+
+// commitShipment loads lines and events of shipment and commits
+// transaction.
+func commitShipment(ctx context.Context, tx pgx.Tx, shipment domain.Shipment) (domain.Shipment, error) {
+	shipments := []domain.Shipment{shipment}
+	err := fillShipments(ctx, tx, shipments)
+	if err != nil {
+		return domain.Shipment{}, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.Shipment{}, fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return shipments[0], nil
+}
+
+// fillShipments loads lines and tracking history of shipments.
+func fillShipments(ctx context.Context, q querier, shipments []domain.Shipment) error {
+	ids := make([]int, 0, len(shipments))
+	for _, s := range shipments {
+		ids = append(ids, s.ID)
+	}
+
+	rows, err := q.Query(ctx, `SELECT * FROM shipment_lines WHERE shipment_id = ANY(@ids) ORDER BY id`,
+		pgx.NamedArgs{"ids": ids})
+	if err != nil {
+		return fmt.Errorf("failed to query list shipment lines: %v", err)
+	}
+
+	lines, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.ShipmentLine])
+	if err != nil {
+		return fmt.Errorf("failed to scan rows of shipment lines: %v", err)
+	}
+
+	rows, err = q.Query(ctx, `SELECT * FROM shipment_events WHERE shipment_id = ANY(@ids) ORDER BY occurred_at, id`,
+		pgx.NamedArgs{"ids": ids})
+	if err != nil {
+		return fmt.Errorf("failed to query list shipment events: %v", err)
+	}
+
+	events, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.ShipmentEvent])
+	if err != nil {
+		return fmt.Errorf("failed to scan rows of shipment events: %v", err)
+	}
+
+	shipmentLines := make(map[int][]domain.ShipmentLine)
+	for _, line := range lines {
+		shipmentLines[line.ShipmentID] = append(shipmentLines[line.ShipmentID], line)
+	}
+
+	shipmentEvents := make(map[int][]domain.ShipmentEvent)
+	for _, event := range events {
+		shipmentEvents[event.ShipmentID] = append(shipmentEvents[event.ShipmentID], event)
+	}
+
+	for i := range shipments {
+		shipments[i].Lines = shipmentLines[shipments[i].ID]
+		if shipments[i].Lines == nil {
+			shipments[i].Lines = []domain.ShipmentLine{}
+		}
+
+		shipments[i].Events = shipmentEvents[shipments[i].ID]
+		if shipments[i].Events == nil {
+			shipments[i].Events = []domain.ShipmentEvent{}
+		}
+	}
+
+	return nil
+}
+
+// isDuplicatedTrackingNumber reports whether err is violation of unique
+// tracking numbers of carriers.
+func isDuplicatedTrackingNumber(err error) bool {
+	pgErr := pgError(err)
+	return pgErr.Code == pgerrcode.UniqueViolation && pgErr.ConstraintName == "shipments_tracking_number_key"
+}
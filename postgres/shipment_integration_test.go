@@ -0,0 +1,163 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+	"github.com/mortezadadgar/ecommerce-api/postgres"
+)
+
+func TestShipmentService_Fulfillment(t *testing.T) {
+	db := newCartTestDB(t, "shipments_fulfillment")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := postgres.NewCartStore(db).AddItem(ctx, domain.CartOwner{UserID: 1}, domain.CartItem{ProductID: 1, Quantity: 3})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	orders := postgres.NewOrderStore(db)
+	order, err := orders.Checkout(ctx, 1, domain.CheckoutDetails{})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+	lineID := order.Lines[0].ID
+
+	store := postgres.NewShipmentStore(db)
+	shipment := func(tracking string, quantity int) domain.Shipment {
+		return domain.ShipmentCreate{
+			Carrier:        "mock",
+			TrackingNumber: tracking,
+			Lines:          []domain.ShipmentLineCreate{{OrderLineID: lineID, Quantity: quantity}},
+		}.CreateModel(order.ID)
+	}
+
+	first := shipment("T1", 2)
+	err = store.Create(ctx, &first)
+	if err != domain.ErrOrderNotFulfillable {
+		t.Errorf("expected %q from Create, got %q", domain.ErrOrderNotFulfillable, err)
+	}
+
+	_, err = orders.UpdateStatus(ctx, order.ID, domain.OrderStatusPaid, 0, "")
+	if err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+
+	first = shipment("T1", 2)
+	err = store.Create(ctx, &first)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	expectOrder := func(status string, shipped int) {
+		t.Helper()
+
+		order, err := orders.GetByID(ctx, order.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+
+		if order.Status != status || order.Lines[0].Shipped != shipped {
+			t.Errorf("expected order %s with %d shipped, got: %s with %d shipped",
+				status, shipped, order.Status, order.Lines[0].Shipped)
+		}
+	}
+	expectOrder(domain.OrderStatusFulfilling, 2)
+
+	extra := shipment("T2", 2)
+	err = store.Create(ctx, &extra)
+	if err != domain.ErrInvalidShipmentQuantity {
+		t.Errorf("expected %q from Create, got %q", domain.ErrInvalidShipmentQuantity, err)
+	}
+
+	duplicate := shipment("T1", 1)
+	err = store.Create(ctx, &duplicate)
+	if err != domain.ErrDuplicatedTrackingNumber {
+		t.Errorf("expected %q from Create, got %q", domain.ErrDuplicatedTrackingNumber, err)
+	}
+
+	// cancelled shipments free their lines.
+	cancelled := shipment("", 1)
+	err = store.Create(ctx, &cancelled)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	_, err = store.UpdateStatus(ctx, cancelled.ID, domain.ShipmentStatusUpdate{Status: domain.ShipmentStatusCancelled}, 1)
+	if err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+	expectOrder(domain.OrderStatusFulfilling, 2)
+
+	first, err = store.UpdateStatus(ctx, first.ID, domain.ShipmentStatusUpdate{Status: domain.ShipmentStatusInTransit}, 1)
+	if err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+
+	if first.ShippedAt == nil || len(first.Events) != 1 {
+		t.Errorf("expected shipped shipment with an event, got: %#v", first)
+	}
+	expectOrder(domain.OrderStatusPartiallyShipped, 2)
+
+	second := shipment("T2", 1)
+	err = store.Create(ctx, &second)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	event := domain.TrackingEvent{ID: "e1", TrackingNumber: "T2", Status: domain.ShipmentStatusDelivered, OccurredAt: time.Now()}
+	err = store.HandleTrackingEvent(ctx, "mock", event)
+	if err != nil {
+		t.Fatalf("HandleTrackingEvent: %v", err)
+	}
+
+	err = store.HandleTrackingEvent(ctx, "mock", event)
+	if err != domain.ErrDuplicatedTrackingEvent {
+		t.Errorf("expected %q from HandleTrackingEvent, got %q", domain.ErrDuplicatedTrackingEvent, err)
+	}
+	expectOrder(domain.OrderStatusShipped, 3)
+
+	err = store.HandleTrackingEvent(ctx, "mock", domain.TrackingEvent{ID: "e2", TrackingNumber: "T1",
+		Status: domain.ShipmentStatusDelivered, OccurredAt: time.Now()})
+	if err != nil {
+		t.Fatalf("HandleTrackingEvent: %v", err)
+	}
+	expectOrder(domain.OrderStatusDelivered, 3)
+
+	err = store.HandleTrackingEvent(ctx, "mock", domain.TrackingEvent{ID: "e3", TrackingNumber: "T9"})
+	if err != domain.ErrNoShipmentsFound {
+		t.Errorf("expected %q from HandleTrackingEvent, got %q", domain.ErrNoShipmentsFound, err)
+	}
+
+	history, err := orders.History(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+
+	var statuses []string
+	for _, change := range history {
+		statuses = append(statuses, change.ToStatus)
+	}
+
+	want := []string{
+		domain.OrderStatusPaid,
+		domain.OrderStatusFulfilling,
+		domain.OrderStatusPartiallyShipped,
+		domain.OrderStatusShipped,
+		domain.OrderStatusDelivered,
+	}
+	if len(statuses) != len(want) {
+		t.Fatalf("expected history %v, got: %v", want, statuses)
+	}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Errorf("expected history %v, got: %v", want, statuses)
+			break
+		}
+	}
+}
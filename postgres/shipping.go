@@ -0,0 +1,194 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// shippingStore represents shipping zones and methods database.
+type shippingStore struct {
+	db *pgxpool.Pool
+}
+
+// NewShippingStore returns a new instance of ShippingStore.
+func NewShippingStore(db *pgxpool.Pool) shippingStore {
+	return shippingStore{db: db}
+}
+
+// CreateZone creates a new shipping zone in database.
+func (s shippingStore) CreateZone(ctx context.Context, zone *domain.ShippingZone) error {
+	query := `
+	INSERT INTO shipping_zones(name, country, region, postal_pattern, priority)
+	VALUES(@name, @country, @region, @postal_pattern, @priority)
+	RETURNING id, created_at
+	`
+
+	args := pgx.NamedArgs{
+		"name":           zone.Name,
+		"country":        zone.Country,
+		"region":         zone.Region,
+		"postal_pattern": zone.PostalPattern,
+		"priority":       zone.Priority,
+	}
+
+	err := s.db.QueryRow(ctx, query, args).Scan(&zone.ID, &zone.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert shipping zone: %v", err)
+	}
+
+	return nil
+}
+
+// GetZone get shipping zone by id from database.
+func (s shippingStore) GetZone(ctx context.Context, ID int) (domain.ShippingZone, error) {
+	zones, err := listShippingZones(ctx, s.db, ID)
+	if err != nil {
+		return domain.ShippingZone{}, err
+	}
+
+	if len(zones) == 0 {
+		return domain.ShippingZone{}, domain.ErrNoShippingZonesFound
+	}
+
+	return zones[0], nil
+}
+
+// ListZones lists shipping zones along with their methods.
+func (s shippingStore) ListZones(ctx context.Context) ([]domain.ShippingZone, error) {
+	zones, err := listShippingZones(ctx, s.db, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(zones) == 0 {
+		return nil, domain.ErrNoShippingZonesFound
+	}
+
+	return zones, nil
+}
+
+// DeleteZone deletes a shipping zone by id from database, its methods are
+// deleted by cascade.
+func (s shippingStore) DeleteZone(ctx context.Context, ID int) error {
+	result, err := s.db.Exec(ctx, `DELETE FROM shipping_zones WHERE id = @id`, pgx.NamedArgs{"id": ID})
+	if err != nil {
+		return fmt.Errorf("failed to delete from shipping zones: %v", err)
+	}
+
+	if rows := result.RowsAffected(); rows != 1 {
+		return domain.ErrNoShippingZonesFound
+	}
+
+	return nil
+}
+
+// CreateMethod creates a new shipping method of a zone in database.
+func (s shippingStore) CreateMethod(ctx context.Context, method *domain.ShippingMethod) error {
+	query := `
+	INSERT INTO shipping_methods(zone_id, name, type, rate, tiers, free_above, active)
+	VALUES(@zone_id, @name, @type, @rate, @tiers, @free_above, @active)
+	RETURNING id, created_at
+	`
+
+	args := pgx.NamedArgs{
+		"zone_id":    method.ZoneID,
+		"name":       method.Name,
+		"type":       method.Type,
+		"rate":       method.Rate,
+		"tiers":      method.Tiers,
+		"free_above": method.FreeAbove,
+		"active":     method.Active,
+	}
+
+	err := s.db.QueryRow(ctx, query, args).Scan(&method.ID, &method.CreatedAt)
+	if err != nil {
+		pgErr := pgError(err)
+		if pgErr.Code == pgerrcode.ForeignKeyViolation && pgErr.ConstraintName == "shipping_methods_zone_id_fkey" {
+			return domain.ErrNoShippingZonesFound
+		}
+		return fmt.Errorf("failed to insert shipping method: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteMethod deletes a shipping method by id from database, carts
+// selecting it are left without a method.
+func (s shippingStore) DeleteMethod(ctx context.Context, ID int) error {
+	result, err := s.db.Exec(ctx, `DELETE FROM shipping_methods WHERE id = @id`, pgx.NamedArgs{"id": ID})
+	if err != nil {
+		return fmt.Errorf("failed to delete from shipping methods: %v", err)
+	}
+
+	if rows := result.RowsAffected(); rows != 1 {
+		return domain.ErrNoShippingMethodsFound
+	}
+
+	return nil
+}
+
+// Rates quotes rates of request by shipping methods in database.
+func (s shippingStore) Rates(ctx context.Context, request domain.ShippingRequest) ([]domain.ShippingRate, error) {
+	zones, err := listShippingZones(ctx, s.db, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.NewShippingTable(zones).Rates(ctx, request)
+}
+
+// listShippingZones lists shipping zones along with their methods, all
+// zones are listed when ID is zero.
+func listShippingZones(ctx context.Context, q querier, ID int) ([]domain.ShippingZone, error) {
+	query := `
+	SELECT * FROM shipping_zones
+	WHERE 1=1
+	` + FormatAndInt("id", ID) + `
+	ORDER BY id
+	`
+
+	rows, err := q.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query list shipping zones: %v", err)
+	}
+
+	zones, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.ShippingZone])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of shipping zones: %v", err)
+	}
+
+	ids := make([]int, 0, len(zones))
+	for _, zone := range zones {
+		ids = append(ids, zone.ID)
+	}
+
+	rows, err = q.Query(ctx, `SELECT * FROM shipping_methods WHERE zone_id = ANY(@ids) ORDER BY id`,
+		pgx.NamedArgs{"ids": ids})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query list shipping methods: %v", err)
+	}
+
+	methods, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.ShippingMethod])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of shipping methods: %v", err)
+	}
+
+	zoneMethods := make(map[int][]domain.ShippingMethod)
+	for _, m := range methods {
+		zoneMethods[m.ZoneID] = append(zoneMethods[m.ZoneID], m)
+	}
+
+	for i := range zones {
+		zones[i].Methods = zoneMethods[zones[i].ID]
+		if zones[i].Methods == nil {
+			zones[i].Methods = []domain.ShippingMethod{}
+		}
+	}
+
+	return zones, nil
+}
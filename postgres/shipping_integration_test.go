@@ -0,0 +1,123 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+	"github.com/mortezadadgar/ecommerce-api/postgres"
+)
+
+func TestOrderService_Shipping(t *testing.T) {
+	db := newCartTestDB(t, "orders_shipping")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	product := domain.Product{Name: "boxed", CategoryID: 1, Price: 1000, Quantity: 10, Weight: 800}
+	err := postgres.NewProductStore(db).Create(ctx, &product)
+	if err != nil {
+		t.Fatalf("product Create: %v", err)
+	}
+
+	store := postgres.NewShippingStore(db)
+	zone := domain.ShippingZoneCreate{Name: "domestic", Country: "us"}.CreateModel()
+	err = store.CreateZone(ctx, &zone)
+	if err != nil {
+		t.Fatalf("CreateZone: %v", err)
+	}
+
+	method := domain.ShippingMethodCreate{
+		Name:  "ground",
+		Type:  domain.ShippingWeight,
+		Tiers: []domain.ShippingTier{{UpTo: 1000, Cost: 500}, {UpTo: 5000, Cost: 900}},
+	}.CreateModel(zone.ID)
+	err = store.CreateMethod(ctx, &method)
+	if err != nil {
+		t.Fatalf("CreateMethod: %v", err)
+	}
+
+	owner := domain.CartOwner{UserID: 1}
+	carts := postgres.NewCartStore(db)
+	_, err = carts.AddItem(ctx, owner, domain.CartItem{ProductID: product.ID, Quantity: 2})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	_, err = carts.SetDestination(ctx, owner, domain.Location{Country: "US"})
+	if err != nil {
+		t.Fatalf("SetDestination: %v", err)
+	}
+
+	cart, err := carts.SetShippingMethod(ctx, owner, method.ID)
+	if err != nil {
+		t.Fatalf("SetShippingMethod: %v", err)
+	}
+
+	if cart.ShippingMethodID == nil || *cart.ShippingMethodID != method.ID {
+		t.Errorf("expected shipping method %d, got: %v", method.ID, cart.ShippingMethodID)
+	}
+
+	request := cart.ShippingRequest()
+	rates, err := store.Rates(ctx, request)
+	if err != nil {
+		t.Fatalf("Rates: %v", err)
+	}
+
+	if len(rates) != 1 || rates[0].Cost != 900 {
+		t.Fatalf("expected rate of %d, got: %v", 900, rates)
+	}
+
+	// a quote of other contents is refused and destination given along is
+	// not kept.
+	stale := domain.ShippingQuote{Request: request, Rate: rates[0]}
+	stale.Request.Weight = 800
+	_, err = postgres.NewOrderStore(db).Checkout(ctx, 1, domain.CheckoutDetails{
+		Destination:      &domain.Location{Country: "CA"},
+		ShippingMethodID: method.ID,
+		Shipping:         &stale,
+	})
+	if err != domain.ErrShippingQuoteChanged {
+		t.Errorf("expected %q, got %q", domain.ErrShippingQuoteChanged, err)
+	}
+
+	cart, err = carts.GetByOwner(ctx, owner)
+	if err != nil {
+		t.Fatalf("GetByOwner: %v", err)
+	}
+
+	if cart.Destination.Country != "US" {
+		t.Errorf("expected destination of failed checkout to be rolled back, got: %#v", cart.Destination)
+	}
+
+	_, err = postgres.NewOrderStore(db).Checkout(ctx, 1, domain.CheckoutDetails{ShippingMethodID: method.ID + 1})
+	if err != domain.ErrNoShippingMethodsFound {
+		t.Errorf("expected %q, got %q", domain.ErrNoShippingMethodsFound, err)
+	}
+
+	order, err := postgres.NewOrderStore(db).Checkout(ctx, 1, domain.CheckoutDetails{
+		Shipping: &domain.ShippingQuote{Request: request, Rate: rates[0]},
+	})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+
+	if order.Shipping != 900 || order.Total != 2900 || order.ShippingMethod != "ground" {
+		t.Errorf("expected shipping on order, got: %#v", order)
+	}
+
+	got, err := postgres.NewOrderStore(db).GetByID(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+
+	if got.Shipping != 900 || got.ShippingMethodID == nil || *got.ShippingMethodID != method.ID {
+		t.Errorf("expected stored shipping, got: %#v", got)
+	}
+
+	_, err = carts.SetShippingMethod(ctx, owner, method.ID+1)
+	if err != domain.ErrNoShippingMethodsFound {
+		t.Errorf("expected %q, got %q", domain.ErrNoShippingMethodsFound, err)
+	}
+}
@@ -0,0 +1,570 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// applyStock computes bundles and available quantity of products taking
+// active reservations into account.
+func applyStock(ctx context.Context, q querier, products []domain.Product) error {
+	err := applyBundles(ctx, q, products)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+
+	query := `
+	SELECT product_id, quantity FROM active_reservations
+	WHERE product_id = ANY(@ids)
+	`
+
+	rows, err := q.Query(ctx, query, pgx.NamedArgs{"ids": ids})
+	if err != nil {
+		return fmt.Errorf("failed to query reserved stock: %v", err)
+	}
+
+	reserved := make(map[int]int)
+	var productID, quantity int
+	_, err = pgx.ForEachRow(rows, []any{&productID, &quantity}, func() error {
+		reserved[productID] = quantity
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan rows of reserved stock: %v", err)
+	}
+
+	for i := range products {
+		products[i].ApplyReservations(reserved[products[i].ID])
+	}
+
+	return nil
+}
+
+// backorderedStock returns quantities of products waiting for stock on
+// order lines by product id.
+func backorderedStock(ctx context.Context, q querier, ids []int) (map[int]int, error) {
+	query := `
+	SELECT product_id, SUM(backordered)::int FROM order_lines
+	WHERE product_id = ANY(@ids) AND backordered > 0
+	GROUP BY product_id
+	`
+
+	rows, err := q.Query(ctx, query, pgx.NamedArgs{"ids": ids})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query backordered stock: %v", err)
+	}
+
+	backordered := make(map[int]int)
+	var productID, quantity int
+	_, err = pgx.ForEachRow(rows, []any{&productID, &quantity}, func() error {
+		backordered[productID] = quantity
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of backordered stock: %v", err)
+	}
+
+	return backordered, nil
+}
+
+// reserveStock replaces reservations of a cart item with ones holding its
+// quantity, components are reserved for bundles. It returns
+// ErrInsufficientStock when stock not reserved by other carts falls short
+// and product can not be backordered, products falling to their reorder
+// threshold queue a low stock notification.
+func reserveStock(ctx context.Context, q querier, item domain.CartItem) error {
+	// products are locked in order of id so concurrent reservations can
+	// not deadlock, stock is read by the next statements to see
+	// reservations committed while waiting for locks.
+	query := `
+	SELECT id FROM products
+	WHERE id = @product_id OR id IN (
+		SELECT product_id FROM bundle_components WHERE bundle_id = @product_id
+	)
+	ORDER BY id
+	FOR UPDATE
+	`
+
+	args := pgx.NamedArgs{
+		"product_id": item.ProductID,
+		"quantity":   item.Quantity,
+	}
+
+	rows, err := q.Query(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("failed to lock products: %v", err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return fmt.Errorf("failed to scan rows of products: %v", err)
+	}
+
+	before, err := stockLevels(ctx, q, ids)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec(ctx, `DELETE FROM stock_reservations WHERE cart_item_id = @id`,
+		pgx.NamedArgs{"id": item.ID})
+	if err != nil {
+		return fmt.Errorf("failed to delete stock reservations: %v", err)
+	}
+
+	query = `
+	SELECT p.id, n.quantity, p.quantity - COALESCE(r.quantity, 0), n.direct,
+		p.inventory_policy, p.backorder_limit, COALESCE(b.backordered, 0)
+	FROM (
+		SELECT product_id, quantity * @quantity::int AS quantity, false AS direct
+		FROM bundle_components WHERE bundle_id = @product_id
+		UNION ALL
+		SELECT id, @quantity::int, true FROM products
+		WHERE id = @product_id AND type <> 'bundle'
+	) n
+	INNER JOIN products p ON p.id = n.product_id
+	LEFT JOIN active_reservations r ON r.product_id = p.id
+	LEFT JOIN (
+		SELECT product_id, SUM(backordered)::int AS backordered
+		FROM order_lines WHERE backordered > 0
+		GROUP BY product_id
+	) b ON b.product_id = p.id
+	`
+
+	rows, err = q.Query(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("failed to query stock: %v", err)
+	}
+
+	type need struct {
+		productID   int
+		quantity    int
+		available   int
+		direct      bool
+		product     domain.Product
+		backordered int
+	}
+
+	var needs []need
+	var n need
+	dest := []any{&n.productID, &n.quantity, &n.available, &n.direct,
+		&n.product.InventoryPolicy, &n.product.BackorderLimit, &n.backordered}
+	_, err = pgx.ForEachRow(rows, dest, func() error {
+		needs = append(needs, n)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan rows of stock: %v", err)
+	}
+
+	if len(needs) == 0 {
+		return domain.ErrInsufficientStock
+	}
+
+	query = `
+	INSERT INTO stock_reservations(cart_item_id, product_id, quantity, expires_at)
+	VALUES(@cart_item_id, @product_id, @quantity, NOW() + make_interval(secs => @expiry))
+	`
+
+	for _, n := range needs {
+		// only products added by themselves may be sold beyond stock,
+		// components of bundles never are.
+		if n.quantity > n.available &&
+			(!n.direct || !n.product.AllowsBackorder(n.quantity-n.available, n.backordered)) {
+			return domain.ErrInsufficientStock
+		}
+
+		args := pgx.NamedArgs{
+			"cart_item_id": item.ID,
+			"product_id":   n.productID,
+			"quantity":     n.quantity,
+			"expiry":       domain.ReservationExpiry.Seconds(),
+		}
+
+		_, err = q.Exec(ctx, query, args)
+		if err != nil {
+			return fmt.Errorf("failed to insert stock reservation: %v", err)
+		}
+	}
+
+	after, err := stockLevels(ctx, q, ids)
+	if err != nil {
+		return err
+	}
+
+	return queueStockNotifications(ctx, q, before, after)
+}
+
+// extendReservations pushes back expiry of active reservations of a cart.
+func extendReservations(ctx context.Context, q querier, cartID int) error {
+	query := `
+	UPDATE stock_reservations
+	SET expires_at = NOW() + make_interval(secs => @expiry)
+	WHERE expires_at > NOW() AND cart_item_id IN (
+		SELECT id FROM cart_items WHERE cart_id = @cart_id
+	)
+	`
+
+	args := pgx.NamedArgs{
+		"cart_id": cartID,
+		"expiry":  domain.ReservationExpiry.Seconds(),
+	}
+
+	_, err := q.Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("failed to extend stock reservations: %v", err)
+	}
+
+	return nil
+}
+
+// releaseExpiredReservations deletes expired reservations, it returns
+// quantity released by product and number of reservations deleted.
+func releaseExpiredReservations(ctx context.Context, q querier) (map[int]int, int, error) {
+	query := `
+	DELETE FROM stock_reservations
+	WHERE expires_at <= NOW()
+	RETURNING product_id, quantity
+	`
+
+	rows, err := q.Query(ctx, query)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to delete expired stock reservations: %v", err)
+	}
+
+	released := make(map[int]int)
+	var productID, quantity int
+	tag, err := pgx.ForEachRow(rows, []any{&productID, &quantity}, func() error {
+		released[productID] += quantity
+		return nil
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to scan rows of stock reservations: %v", err)
+	}
+
+	return released, int(tag.RowsAffected()), nil
+}
+
+// reservedItems returns which of cart items hold active reservations.
+func reservedItems(ctx context.Context, q querier, itemIDs []int) (map[int]bool, error) {
+	query := `
+	SELECT DISTINCT cart_item_id FROM stock_reservations
+	WHERE cart_item_id = ANY(@ids) AND expires_at > NOW()
+	`
+
+	rows, err := q.Query(ctx, query, pgx.NamedArgs{"ids": itemIDs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock reservations: %v", err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of stock reservations: %v", err)
+	}
+
+	result := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		result[id] = true
+	}
+
+	return result, nil
+}
+
+// takeStock takes quantities of cart items out of products stock by
+// recording sale movements under reference, bundles take their components.
+// Warehouses are drawn from in order of id and stock reserved by other
+// carts is not taken. Quantities falling short are backordered for
+// products allowing it, it returns backordered quantity by product id or
+// ErrInsufficientStock.
+func takeStock(ctx context.Context, q querier, cartID int, reference string) (map[int]int, error) {
+	query := `
+	SELECT n.product_id, SUM(n.quantity)::int, SUM(n.direct)::int
+	FROM (
+		SELECT b.product_id, b.quantity * i.quantity AS quantity, 0 AS direct
+		FROM cart_items i
+		INNER JOIN bundle_components b ON b.bundle_id = i.product_id
+		WHERE i.cart_id = @cart_id
+		UNION ALL
+		SELECT i.product_id, i.quantity, i.quantity
+		FROM cart_items i
+		INNER JOIN products p ON p.id = i.product_id
+		WHERE i.cart_id = @cart_id AND p.type <> 'bundle'
+	) n
+	GROUP BY n.product_id
+	ORDER BY n.product_id
+	`
+
+	rows, err := q.Query(ctx, query, pgx.NamedArgs{"cart_id": cartID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cart stock: %v", err)
+	}
+
+	needs := make(map[int]int)
+	direct := make(map[int]int)
+	var ids []int
+	var productID, quantity, directQuantity int
+	_, err = pgx.ForEachRow(rows, []any{&productID, &quantity, &directQuantity}, func() error {
+		needs[productID] = quantity
+		direct[productID] = directQuantity
+		ids = append(ids, productID)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of cart stock: %v", err)
+	}
+
+	query = `
+	SELECT p.id, p.quantity, p.inventory_policy, p.backorder_limit,
+		COALESCE((
+			SELECT SUM(backordered) FROM order_lines
+			WHERE product_id = p.id AND backordered > 0
+		), 0)::int
+	FROM products p
+	WHERE p.id = ANY(@ids)
+	ORDER BY p.id
+	FOR UPDATE
+	`
+
+	rows, err = q.Query(ctx, query, pgx.NamedArgs{"ids": ids})
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock products: %v", err)
+	}
+
+	products := make(map[int]domain.Product)
+	backordered := make(map[int]int)
+	var product domain.Product
+	var pending int
+	dest := []any{&product.ID, &product.Quantity, &product.InventoryPolicy, &product.BackorderLimit, &pending}
+	_, err = pgx.ForEachRow(rows, dest, func() error {
+		products[product.ID] = product
+		backordered[product.ID] = pending
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of products: %v", err)
+	}
+
+	query = `
+	SELECT r.product_id, SUM(r.quantity)::int
+	FROM stock_reservations r
+	INNER JOIN cart_items i ON i.id = r.cart_item_id
+	WHERE r.product_id = ANY(@ids) AND r.expires_at > NOW() AND i.cart_id <> @cart_id
+	GROUP BY r.product_id
+	`
+
+	rows, err = q.Query(ctx, query, pgx.NamedArgs{"ids": ids, "cart_id": cartID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reserved stock: %v", err)
+	}
+
+	reserved := make(map[int]int)
+	_, err = pgx.ForEachRow(rows, []any{&productID, &quantity}, func() error {
+		reserved[productID] = quantity
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of reserved stock: %v", err)
+	}
+
+	before, err := stockLevels(ctx, q, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	locations, err := locationStock(ctx, q, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var movements []domain.StockMovement
+	shortfalls := make(map[int]int)
+	for _, id := range ids {
+		take := needs[id]
+		if free := products[id].Quantity - reserved[id]; take > free {
+			take = free
+			if take < 0 {
+				take = 0
+			}
+
+			// only products added by themselves may be backordered,
+			// components of bundles never are.
+			shortfall := needs[id] - take
+			if shortfall > direct[id] || !products[id].AllowsBackorder(shortfall, backordered[id]) {
+				return nil, domain.ErrInsufficientStock
+			}
+			shortfalls[id] = shortfall
+		}
+
+		taken, err := domain.AllocateStock(take, locations[id])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, t := range taken {
+			movements = append(movements, domain.StockMovement{
+				ProductID:   id,
+				WarehouseID: t.WarehouseID,
+				Type:        domain.StockMovementSale,
+				Quantity:    -t.OnHand,
+				Reference:   reference,
+			})
+		}
+	}
+
+	// reservations of cart are consumed by the sale.
+	query = `
+	DELETE FROM stock_reservations
+	WHERE cart_item_id IN (SELECT id FROM cart_items WHERE cart_id = @cart_id)
+	`
+
+	_, err = q.Exec(ctx, query, pgx.NamedArgs{"cart_id": cartID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete stock reservations: %v", err)
+	}
+
+	err = applyMovements(ctx, q, movements)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := stockLevels(ctx, q, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	err = queueStockNotifications(ctx, q, before, after)
+	if err != nil {
+		return nil, err
+	}
+
+	return shortfalls, nil
+}
+
+// restock gives quantities of order lines taken out of stock back to the
+// warehouses they were sold from, bundles give back their components.
+func restock(ctx context.Context, q querier, orderID int) error {
+	lines := `
+	SELECT product_id, quantity - backordered FROM order_lines
+	WHERE order_id = @id
+	`
+
+	reference := domain.OrderStockReference(orderID)
+	err := restockLines(ctx, q, lines, orderID, orderID, reference, "order cancelled")
+	if err != nil {
+		return err
+	}
+
+	// backordered quantities were never taken, they are not waiting for
+	// stock anymore.
+	_, err = q.Exec(ctx, `UPDATE order_lines SET backordered = 0 WHERE order_id = @id`,
+		pgx.NamedArgs{"id": orderID})
+	if err != nil {
+		return fmt.Errorf("failed to update backordered quantities: %v", err)
+	}
+
+	return nil
+}
+
+// restockReturn gives returned quantities of a return back to the
+// warehouses its order was sold from.
+func restockReturn(ctx context.Context, q querier, rma domain.Return) error {
+	lines := `
+	SELECT l.product_id, r.quantity
+	FROM return_lines r
+	INNER JOIN order_lines l ON l.id = r.order_line_id
+	WHERE r.return_id = @id
+	`
+
+	return restockLines(ctx, q, lines, rma.ID, rma.OrderID, rma.RMANumber, "return received")
+}
+
+// restockLines records return movements for product lines selected by a
+// query taking @id, lines of deleted products are skipped. Quantities go
+// back to warehouses of sale movements of order and the rest to the
+// default warehouse.
+func restockLines(ctx context.Context, q querier, lines string, ID int, orderID int, reference string, note string) error {
+	query := `
+	WITH lines(product_id, quantity) AS (` + lines + `)
+	SELECT product_id, SUM(quantity)::int
+	FROM (
+		SELECT b.product_id, b.quantity * l.quantity AS quantity
+		FROM lines l
+		INNER JOIN bundle_components b ON b.bundle_id = l.product_id
+		UNION ALL
+		SELECT l.product_id, l.quantity
+		FROM lines l
+		INNER JOIN products p ON p.id = l.product_id
+		WHERE p.type <> 'bundle'
+	) c
+	GROUP BY product_id
+	ORDER BY product_id
+	`
+
+	rows, err := q.Query(ctx, query, pgx.NamedArgs{"id": ID})
+	if err != nil {
+		return fmt.Errorf("failed to query restocked lines: %v", err)
+	}
+
+	quantities := make(map[int]int)
+	var ids []int
+	var productID, quantity int
+	_, err = pgx.ForEachRow(rows, []any{&productID, &quantity}, func() error {
+		quantities[productID] = quantity
+		ids = append(ids, productID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan rows of restocked lines: %v", err)
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query = `
+	SELECT product_id, warehouse_id, -SUM(quantity)::int AS on_hand
+	FROM stock_movements
+	WHERE reference = @reference AND type = 'sale'
+	GROUP BY product_id, warehouse_id
+	ORDER BY product_id, warehouse_id
+	`
+
+	args := pgx.NamedArgs{"reference": domain.OrderStockReference(orderID)}
+	rows, err = q.Query(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("failed to query sale movements: %v", err)
+	}
+
+	sales, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.LocationStock])
+	if err != nil {
+		return fmt.Errorf("failed to scan rows of sale movements: %v", err)
+	}
+
+	sold := make(map[int][]domain.LocationStock)
+	for _, s := range sales {
+		sold[s.ProductID] = append(sold[s.ProductID], s)
+	}
+
+	var movements []domain.StockMovement
+	for _, id := range ids {
+		for _, r := range domain.RestoreStock(id, quantities[id], sold[id]) {
+			movements = append(movements, domain.StockMovement{
+				ProductID:   id,
+				WarehouseID: r.WarehouseID,
+				Type:        domain.StockMovementReturn,
+				Quantity:    r.OnHand,
+				Reference:   reference,
+				Note:        note,
+			})
+		}
+	}
+
+	return recordMovements(ctx, q, movements)
+}
@@ -0,0 +1,219 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// taxStore represents tax zones database.
+type taxStore struct {
+	db *pgxpool.Pool
+}
+
+// NewTaxStore returns a new instance of TaxStore.
+func NewTaxStore(db *pgxpool.Pool) taxStore {
+	return taxStore{db: db}
+}
+
+// CreateZone creates a new tax zone along with its rates in database.
+func (t taxStore) CreateZone(ctx context.Context, zone *domain.TaxZone) error {
+	tx, err := t.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+	INSERT INTO tax_zones(name, country, region, postal_pattern, priority, prices_include_tax)
+	VALUES(@name, @country, @region, @postal_pattern, @priority, @prices_include_tax)
+	RETURNING id, created_at
+	`
+
+	args := pgx.NamedArgs{
+		"name":               zone.Name,
+		"country":            zone.Country,
+		"region":             zone.Region,
+		"postal_pattern":     zone.PostalPattern,
+		"priority":           zone.Priority,
+		"prices_include_tax": zone.PricesIncludeTax,
+	}
+
+	err = tx.QueryRow(ctx, query, args).Scan(&zone.ID, &zone.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert tax zone: %v", err)
+	}
+
+	err = insertTaxRates(ctx, tx, zone.ID, zone.Rates)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return nil
+}
+
+// GetZone get tax zone by id from database.
+func (t taxStore) GetZone(ctx context.Context, ID int) (domain.TaxZone, error) {
+	zones, err := listTaxZones(ctx, t.db, ID)
+	if err != nil {
+		return domain.TaxZone{}, err
+	}
+
+	if len(zones) == 0 {
+		return domain.TaxZone{}, domain.ErrNoTaxZonesFound
+	}
+
+	return zones[0], nil
+}
+
+// ListZones lists tax zones along with their rates.
+func (t taxStore) ListZones(ctx context.Context) ([]domain.TaxZone, error) {
+	zones, err := listTaxZones(ctx, t.db, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(zones) == 0 {
+		return nil, domain.ErrNoTaxZonesFound
+	}
+
+	return zones, nil
+}
+
+// DeleteZone deletes a tax zone by id from database, its rates are deleted
+// by cascade.
+func (t taxStore) DeleteZone(ctx context.Context, ID int) error {
+	result, err := t.db.Exec(ctx, `DELETE FROM tax_zones WHERE id = @id`, pgx.NamedArgs{"id": ID})
+	if err != nil {
+		return fmt.Errorf("failed to delete from tax zones: %v", err)
+	}
+
+	if rows := result.RowsAffected(); rows != 1 {
+		return domain.ErrNoTaxZonesFound
+	}
+
+	return nil
+}
+
+// SetRates replaces rates of a tax zone.
+func (t taxStore) SetRates(ctx context.Context, zoneID int, rates []domain.TaxRate) (domain.TaxZone, error) {
+	tx, err := t.db.Begin(ctx)
+	if err != nil {
+		return domain.TaxZone{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `SELECT id FROM tax_zones WHERE id = @id FOR UPDATE`, pgx.NamedArgs{"id": zoneID})
+	if err != nil {
+		return domain.TaxZone{}, fmt.Errorf("failed to query tax zone: %v", err)
+	}
+
+	if result.RowsAffected() != 1 {
+		return domain.TaxZone{}, domain.ErrNoTaxZonesFound
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM tax_rates WHERE zone_id = @zone_id`, pgx.NamedArgs{"zone_id": zoneID})
+	if err != nil {
+		return domain.TaxZone{}, fmt.Errorf("failed to delete tax rates: %v", err)
+	}
+
+	err = insertTaxRates(ctx, tx, zoneID, rates)
+	if err != nil {
+		return domain.TaxZone{}, err
+	}
+
+	zones, err := listTaxZones(ctx, tx, zoneID)
+	if err != nil {
+		return domain.TaxZone{}, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.TaxZone{}, fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return zones[0], nil
+}
+
+// insertTaxRates inserts rates of a tax zone.
+func insertTaxRates(ctx context.Context, q querier, zoneID int, rates []domain.TaxRate) error {
+	query := `
+	INSERT INTO tax_rates(zone_id, tax_category, name, rate)
+	VALUES(@zone_id, @tax_category, @name, @rate)
+	`
+
+	for _, r := range rates {
+		args := pgx.NamedArgs{
+			"zone_id":      zoneID,
+			"tax_category": r.TaxCategory,
+			"name":         r.Name,
+			"rate":         r.Rate,
+		}
+
+		_, err := q.Exec(ctx, query, args)
+		if err != nil {
+			return fmt.Errorf("failed to insert tax rate: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// listTaxZones lists tax zones along with their rates, all zones are
+// listed when ID is zero.
+func listTaxZones(ctx context.Context, q querier, ID int) ([]domain.TaxZone, error) {
+	query := `
+	SELECT * FROM tax_zones
+	WHERE 1=1
+	` + FormatAndInt("id", ID) + `
+	ORDER BY id
+	`
+
+	rows, err := q.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query list tax zones: %v", err)
+	}
+
+	zones, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.TaxZone])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of tax zones: %v", err)
+	}
+
+	ids := make([]int, 0, len(zones))
+	for _, zone := range zones {
+		ids = append(ids, zone.ID)
+	}
+
+	rows, err = q.Query(ctx, `SELECT * FROM tax_rates WHERE zone_id = ANY(@ids) ORDER BY tax_category`,
+		pgx.NamedArgs{"ids": ids})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query list tax rates: %v", err)
+	}
+
+	rates, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.TaxRate])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of tax rates: %v", err)
+	}
+
+	zoneRates := make(map[int][]domain.TaxRate)
+	for _, r := range rates {
+		zoneRates[r.ZoneID] = append(zoneRates[r.ZoneID], r)
+	}
+
+	for i := range zones {
+		zones[i].Rates = zoneRates[zones[i].ID]
+		if zones[i].Rates == nil {
+			zones[i].Rates = []domain.TaxRate{}
+		}
+	}
+
+	return zones, nil
+}
@@ -0,0 +1,80 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+	"github.com/mortezadadgar/ecommerce-api/postgres"
+)
+
+func TestCartService_Tax(t *testing.T) {
+	db := newCartTestDB(t, "carts_tax")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	product := domain.Product{Name: "book", CategoryID: 1, Price: 1000, Quantity: 10, TaxCategory: "reduced"}
+	err := postgres.NewProductStore(db).Create(ctx, &product)
+	if err != nil {
+		t.Fatalf("product Create: %v", err)
+	}
+
+	zone := domain.TaxZoneCreate{
+		Name:    "germany",
+		Country: "de",
+		Rates:   []domain.TaxRate{{TaxCategory: "standard", Name: "VAT", Rate: 1900}},
+	}.CreateModel()
+	err = postgres.NewTaxStore(db).CreateZone(ctx, &zone)
+	if err != nil {
+		t.Fatalf("CreateZone: %v", err)
+	}
+
+	zone, err = postgres.NewTaxStore(db).SetRates(ctx, zone.ID, []domain.TaxRate{
+		{TaxCategory: "standard", Name: "VAT", Rate: 1900},
+		{TaxCategory: "reduced", Name: "reduced VAT", Rate: 700},
+	})
+	if err != nil {
+		t.Fatalf("SetRates: %v", err)
+	}
+
+	if len(zone.Rates) != 2 {
+		t.Errorf("expected %d rates, got: %#v", 2, zone.Rates)
+	}
+
+	owner := domain.CartOwner{UserID: 1}
+	_, err = postgres.NewCartStore(db).AddItem(ctx, owner, domain.CartItem{ProductID: product.ID, Quantity: 2})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	cart, err := postgres.NewCartStore(db).SetDestination(ctx, owner, domain.Location{Country: "De", PostalCode: "10115"})
+	if err != nil {
+		t.Fatalf("SetDestination: %v", err)
+	}
+
+	if cart.Destination.Country != "DE" || cart.Totals.Tax != 140 || cart.Totals.GrandTotal != 2140 {
+		t.Errorf("expected taxed cart, got: %#v", cart)
+	}
+
+	order, err := postgres.NewOrderStore(db).Checkout(ctx, 1, domain.CheckoutDetails{})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+
+	line := order.Lines[0]
+	if order.Tax != 140 || order.Total != 2140 || line.TaxCategory != "reduced" || line.TaxRate != 700 || line.Tax != 140 {
+		t.Errorf("expected tax breakdown on order, got: %#v", order)
+	}
+
+	err = postgres.NewTaxStore(db).DeleteZone(ctx, zone.ID)
+	if err != nil {
+		t.Fatalf("DeleteZone: %v", err)
+	}
+
+	_, err = postgres.NewTaxStore(db).GetZone(ctx, zone.ID)
+	if err != domain.ErrNoTaxZonesFound {
+		t.Errorf("expected %q, got %q", domain.ErrNoTaxZonesFound, err)
+	}
+}
@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
@@ -69,3 +70,13 @@ func (t tokenStore) GetUserID(ctx context.Context, plainToken string) (int, erro
 
 	return userID, nil
 }
+
+// DeleteExpired deletes expired tokens from database.
+func (t tokenStore) DeleteExpired(ctx context.Context) (int, error) {
+	result, err := t.db.Exec(ctx, `DELETE FROM tokens WHERE expiry <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired tokens: %v", err)
+	}
+
+	return int(result.RowsAffected()), nil
+}
@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// translationStore represents translations database of a resource.
+type translationStore struct {
+	db *pgxpool.Pool
+
+	// table and column holds translations table and its owner column.
+	table  string
+	column string
+
+	// errNotFound is returned when owner of translations does not exist.
+	errNotFound error
+}
+
+// NewProductTranslationStore returns a new instance of TranslationStore
+// for products.
+func NewProductTranslationStore(db *pgxpool.Pool) translationStore {
+	return translationStore{
+		db:          db,
+		table:       "product_translations",
+		column:      "product_id",
+		errNotFound: domain.ErrNoProductsFound,
+	}
+}
+
+// NewCategoryTranslationStore returns a new instance of TranslationStore
+// for categories.
+func NewCategoryTranslationStore(db *pgxpool.Pool) translationStore {
+	return translationStore{
+		db:          db,
+		table:       "category_translations",
+		column:      "category_id",
+		errNotFound: domain.ErrNoCategoryFound,
+	}
+}
+
+// List lists translations of owner.
+func (t translationStore) List(ctx context.Context, ownerID int) ([]domain.Translation, error) {
+	query := `
+	SELECT ` + t.column + ` AS owner_id, locale, name, description
+	FROM ` + t.table + `
+	WHERE ` + t.column + ` = @owner_id
+	ORDER BY locale
+	`
+
+	rows, err := t.db.Query(ctx, query, pgx.NamedArgs{"owner_id": ownerID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query list translations: %v", err)
+	}
+
+	translations, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.Translation])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of translations: %v", err)
+	}
+
+	if len(translations) == 0 {
+		return nil, domain.ErrNoTranslationFound
+	}
+
+	return translations, nil
+}
+
+// Set creates or replaces translation of owner for its locale.
+func (t translationStore) Set(ctx context.Context, ownerID int, translation *domain.Translation) error {
+	query := `
+	INSERT INTO ` + t.table + `(` + t.column + `, locale, name, description)
+	VALUES(@owner_id, @locale, @name, @description)
+	ON CONFLICT(` + t.column + `, locale) DO UPDATE
+	SET name        = EXCLUDED.name,
+		description = EXCLUDED.description,
+		updated_at  = NOW()
+	`
+
+	args := pgx.NamedArgs{
+		"owner_id":    ownerID,
+		"locale":      &translation.Locale,
+		"name":        &translation.Name,
+		"description": &translation.Description,
+	}
+
+	_, err := t.db.Exec(ctx, query, args)
+	if err != nil {
+		pgErr := pgError(err)
+		if pgErr.Code == pgerrcode.ForeignKeyViolation {
+			return t.errNotFound
+		}
+		return err
+	}
+
+	translation.OwnerID = ownerID
+
+	return nil
+}
+
+// Delete deletes translation of owner for a locale.
+func (t translationStore) Delete(ctx context.Context, ownerID int, locale string) error {
+	query := `
+	DELETE FROM ` + t.table + `
+	WHERE ` + t.column + ` = @owner_id AND locale = @locale
+	`
+
+	args := pgx.NamedArgs{
+		"owner_id": ownerID,
+		"locale":   locale,
+	}
+
+	result, err := t.db.Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("failed to delete from translations: %v", err)
+	}
+
+	if rows := result.RowsAffected(); rows != 1 {
+		return domain.ErrNoTranslationFound
+	}
+
+	return nil
+}
+
+// Resolve returns the first available translation in order of locales
+// for each owner, owners without any translation are left out.
+func (t translationStore) Resolve(ctx context.Context, ownerIDs []int, locales []string) (map[int]domain.Translation, error) {
+	if len(ownerIDs) == 0 || len(locales) == 0 {
+		return map[int]domain.Translation{}, nil
+	}
+
+	query := `
+	SELECT DISTINCT ON (` + t.column + `)
+		` + t.column + ` AS owner_id, locale, name, description
+	FROM ` + t.table + `
+	WHERE ` + t.column + ` = ANY(@owner_ids) AND locale = ANY(@locales)
+	ORDER BY ` + t.column + `, array_position(@locales, locale)
+	`
+
+	args := pgx.NamedArgs{
+		"owner_ids": ownerIDs,
+		"locales":   locales,
+	}
+
+	rows, err := t.db.Query(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query resolve translations: %v", err)
+	}
+
+	translations, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.Translation])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of translations: %v", err)
+	}
+
+	result := make(map[int]domain.Translation, len(translations))
+	for _, translation := range translations {
+		result[translation.OwnerID] = translation
+	}
+
+	return result, nil
+}
@@ -70,7 +70,7 @@ func (u userStore) GetByEmail(ctx context.Context, email string) (domain.User, e
 // List lists users with optional filter.
 func (u userStore) List(ctx context.Context, filter domain.UserFilter) ([]domain.User, error) {
 	query := `
-	SELECT id, email, password_hash
+	SELECT id, email, password_hash, admin
 	FROM users
 	WHERE 1=1
 	` + FormatAnd("email", filter.Email) + `
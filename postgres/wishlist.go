@@ -0,0 +1,527 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// wishlistStore represents wishlists database.
+type wishlistStore struct {
+	db *pgxpool.Pool
+}
+
+// NewWishlistStore returns a new instance of WishlistStore.
+func NewWishlistStore(db *pgxpool.Pool) wishlistStore {
+	return wishlistStore{db: db}
+}
+
+// Create creates a new wishlist of user in database.
+func (w wishlistStore) Create(ctx context.Context, wishlist *domain.Wishlist) error {
+	query := `
+	INSERT INTO wishlists(user_id, name)
+	VALUES(@user_id, @name)
+	RETURNING id, created_at, updated_at
+	`
+
+	args := pgx.NamedArgs{"user_id": wishlist.UserID, "name": wishlist.Name}
+	err := w.db.QueryRow(ctx, query, args).Scan(&wishlist.ID, &wishlist.CreatedAt, &wishlist.UpdatedAt)
+	if err != nil {
+		pgErr := pgError(err)
+		if pgErr.Code == pgerrcode.UniqueViolation {
+			if pgErr.ConstraintName == "wishlists_user_id_name_key" {
+				return domain.ErrDuplicatedWishlist
+			}
+		}
+		return fmt.Errorf("failed to insert wishlist: %v", err)
+	}
+
+	wishlist.Items = []domain.WishlistItem{}
+	return nil
+}
+
+// GetByID get wishlist of user by id from database.
+func (w wishlistStore) GetByID(ctx context.Context, userID int, ID int) (domain.Wishlist, error) {
+	query := `SELECT * FROM wishlists WHERE id = @id AND user_id = @user_id`
+	return getWishlist(ctx, w.db, query, pgx.NamedArgs{"id": ID, "user_id": userID})
+}
+
+// GetShared get wishlist by its share token from database.
+func (w wishlistStore) GetShared(ctx context.Context, token string) (domain.Wishlist, error) {
+	query := `SELECT * FROM wishlists WHERE share_token = @token`
+	return getWishlist(ctx, w.db, query, pgx.NamedArgs{"token": token})
+}
+
+// List lists wishlists of user with their items.
+func (w wishlistStore) List(ctx context.Context, userID int) ([]domain.Wishlist, error) {
+	query := `
+	SELECT * FROM wishlists
+	WHERE user_id = @user_id
+	ORDER BY id
+	`
+
+	rows, err := w.db.Query(ctx, query, pgx.NamedArgs{"user_id": userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query list wishlists: %v", err)
+	}
+
+	wishlists, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.Wishlist])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rows of wishlists: %v", err)
+	}
+
+	if len(wishlists) == 0 {
+		return nil, domain.ErrNoWishlistsFound
+	}
+
+	err = fillWishlists(ctx, w.db, wishlists)
+	if err != nil {
+		return nil, err
+	}
+
+	return wishlists, nil
+}
+
+// Update updates a wishlist of user by id in database.
+func (w wishlistStore) Update(ctx context.Context, userID int, ID int, input domain.WishlistUpdate) (domain.Wishlist, error) {
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		input.Name = &name
+	}
+
+	query := `
+	UPDATE wishlists
+	SET name       = COALESCE(@name, name),
+		updated_at = NOW()
+	WHERE id = @id AND user_id = @user_id
+	RETURNING *
+	`
+
+	rows, err := w.db.Query(ctx, query, pgx.NamedArgs{"id": ID, "user_id": userID, "name": input.Name})
+	if err != nil {
+		return domain.Wishlist{}, fmt.Errorf("failed to query update wishlist: %v", err)
+	}
+
+	wishlist, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.Wishlist])
+	if err != nil {
+		pgErr := pgError(err)
+		if pgErr.Code == pgerrcode.UniqueViolation {
+			if pgErr.ConstraintName == "wishlists_user_id_name_key" {
+				return domain.Wishlist{}, domain.ErrDuplicatedWishlist
+			}
+		}
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Wishlist{}, domain.ErrNoWishlistsFound
+		}
+
+		return domain.Wishlist{}, fmt.Errorf("failed to scan row of wishlist: %v", err)
+	}
+
+	wishlists := []domain.Wishlist{wishlist}
+	err = fillWishlists(ctx, w.db, wishlists)
+	if err != nil {
+		return domain.Wishlist{}, err
+	}
+
+	return wishlists[0], nil
+}
+
+// Delete deletes a wishlist of user by id from database.
+func (w wishlistStore) Delete(ctx context.Context, userID int, ID int) error {
+	result, err := w.db.Exec(ctx, `DELETE FROM wishlists WHERE id = @id AND user_id = @user_id`,
+		pgx.NamedArgs{"id": ID, "user_id": userID})
+	if err != nil {
+		return fmt.Errorf("failed to delete from wishlists: %v", err)
+	}
+
+	if rows := result.RowsAffected(); rows != 1 {
+		return domain.ErrNoWishlistsFound
+	}
+
+	return nil
+}
+
+// Share sets share token of a wishlist of user.
+func (w wishlistStore) Share(ctx context.Context, userID int, ID int, token string) (domain.Wishlist, error) {
+	query := `
+	UPDATE wishlists SET share_token = @token
+	WHERE id = @id AND user_id = @user_id
+	RETURNING *
+	`
+
+	return getWishlist(ctx, w.db, query, pgx.NamedArgs{"id": ID, "user_id": userID, "token": token})
+}
+
+// Unshare removes share token of a wishlist of user, its link stops
+// working.
+func (w wishlistStore) Unshare(ctx context.Context, userID int, ID int) (domain.Wishlist, error) {
+	query := `
+	UPDATE wishlists SET share_token = NULL
+	WHERE id = @id AND user_id = @user_id
+	RETURNING *
+	`
+
+	return getWishlist(ctx, w.db, query, pgx.NamedArgs{"id": ID, "user_id": userID})
+}
+
+// AddItem adds a product to a wishlist of user, quantity is added up when
+// product is already in wishlist.
+func (w wishlistStore) AddItem(ctx context.Context, userID int, wishlistID int, item domain.WishlistItem) (domain.Wishlist, error) {
+	tx, err := w.db.Begin(ctx)
+	if err != nil {
+		return domain.Wishlist{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = lockWishlist(ctx, tx, userID, wishlistID)
+	if err != nil {
+		return domain.Wishlist{}, err
+	}
+
+	err = addWishlistItem(ctx, tx, wishlistID, item)
+	if err != nil {
+		return domain.Wishlist{}, err
+	}
+
+	return commitWishlist(ctx, tx, wishlistID)
+}
+
+// RemoveItem removes an item from a wishlist of user.
+func (w wishlistStore) RemoveItem(ctx context.Context, userID int, wishlistID int, itemID int) (domain.Wishlist, error) {
+	tx, err := w.db.Begin(ctx)
+	if err != nil {
+		return domain.Wishlist{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = lockWishlist(ctx, tx, userID, wishlistID)
+	if err != nil {
+		return domain.Wishlist{}, err
+	}
+
+	result, err := tx.Exec(ctx, `DELETE FROM wishlist_items WHERE id = @id AND wishlist_id = @wishlist_id`,
+		pgx.NamedArgs{"id": itemID, "wishlist_id": wishlistID})
+	if err != nil {
+		return domain.Wishlist{}, fmt.Errorf("failed to delete from wishlist items: %v", err)
+	}
+
+	if rows := result.RowsAffected(); rows != 1 {
+		return domain.Wishlist{}, domain.ErrNoWishlistItemsFound
+	}
+
+	return commitWishlist(ctx, tx, wishlistID)
+}
+
+// MoveToCart moves an item of a wishlist of user into cart of user, stock
+// of the cart line is reserved.
+func (w wishlistStore) MoveToCart(ctx context.Context, userID int, wishlistID int, itemID int) (domain.Cart, error) {
+	tx, err := w.db.Begin(ctx)
+	if err != nil {
+		return domain.Cart{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = lockWishlist(ctx, tx, userID, wishlistID)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	query := `
+	DELETE FROM wishlist_items
+	WHERE id = @id AND wishlist_id = @wishlist_id
+	RETURNING product_id, quantity
+	`
+
+	var item domain.CartItem
+	err = tx.QueryRow(ctx, query, pgx.NamedArgs{"id": itemID, "wishlist_id": wishlistID}).
+		Scan(&item.ProductID, &item.Quantity)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Cart{}, domain.ErrNoWishlistItemsFound
+		}
+		return domain.Cart{}, fmt.Errorf("failed to delete from wishlist items: %v", err)
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE wishlists SET updated_at = NOW() WHERE id = @id`,
+		pgx.NamedArgs{"id": wishlistID})
+	if err != nil {
+		return domain.Cart{}, fmt.Errorf("failed to update wishlist: %v", err)
+	}
+
+	cartID, err := ensureCart(ctx, tx, domain.CartOwner{UserID: userID})
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	err = addCartItem(ctx, tx, cartID, item)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	return commitCart(ctx, tx, cartID)
+}
+
+// MoveFromCart moves an item of cart of user into a wishlist of user, its
+// reservations are released by cascade. Default wishlist of user is used
+// and created when wishlistID is zero.
+func (w wishlistStore) MoveFromCart(ctx context.Context, userID int, cartItemID int, wishlistID int) (domain.Wishlist, error) {
+	tx, err := w.db.Begin(ctx)
+	if err != nil {
+		return domain.Wishlist{}, fmt.Errorf("%w: %v", ErrBeginTransaction, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if wishlistID == 0 {
+		wishlistID, err = ensureWishlist(ctx, tx, userID, domain.DefaultWishlistName)
+	} else {
+		err = lockWishlist(ctx, tx, userID, wishlistID)
+	}
+	if err != nil {
+		return domain.Wishlist{}, err
+	}
+
+	item, err := lockCartItem(ctx, tx, domain.CartOwner{UserID: userID}, cartItemID)
+	if err != nil {
+		return domain.Wishlist{}, err
+	}
+
+	err = addWishlistItem(ctx, tx, wishlistID, domain.WishlistItem{ProductID: item.ProductID, Quantity: item.Quantity})
+	if err != nil {
+		return domain.Wishlist{}, err
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM cart_items WHERE id = @id`, pgx.NamedArgs{"id": cartItemID})
+	if err != nil {
+		return domain.Wishlist{}, fmt.Errorf("failed to delete from cart items: %v", err)
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE carts SET updated_at = NOW() WHERE id = @id`, pgx.NamedArgs{"id": item.CartID})
+	if err != nil {
+		return domain.Wishlist{}, fmt.Errorf("failed to update cart: %v", err)
+	}
+
+	return commitWishlist(ctx, tx, wishlistID)
+}
+
+// getWishlist queries a single wishlist and loads its items.
+func getWishlist(ctx context.Context, q querier, query string, args pgx.NamedArgs) (domain.Wishlist, error) {
+	rows, err := q.Query(ctx, query, args)
+	if err != nil {
+		return domain.Wishlist{}, fmt.Errorf("failed to query wishlist: %v", err)
+	}
+
+	wishlist, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[domain.Wishlist])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Wishlist{}, domain.ErrNoWishlistsFound
+		}
+		return domain.Wishlist{}, fmt.Errorf("failed to scan row of wishlist: %v", err)
+	}
+
+	wishlists := []domain.Wishlist{wishlist}
+	err = fillWishlists(ctx, q, wishlists)
+	if err != nil {
+		return domain.Wishlist{}, err
+	}
+
+	return wishlists[0], nil
+}
+
+// lockWishlist locks a wishlist of user for update.
+func lockWishlist(ctx context.Context, q querier, userID int, ID int) error {
+	query := `SELECT id FROM wishlists WHERE id = @id AND user_id = @user_id FOR UPDATE`
+
+	err := q.QueryRow(ctx, query, pgx.NamedArgs{"id": ID, "user_id": userID}).Scan(&ID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrNoWishlistsFound
+		}
+		return fmt.Errorf("failed to lock wishlist: %v", err)
+	}
+
+	return nil
+}
+
+// ensureWishlist returns id of a wishlist of user by name and creates one
+// when missing.
+func ensureWishlist(ctx context.Context, q querier, userID int, name string) (int, error) {
+	query := `
+	INSERT INTO wishlists(user_id, name)
+	VALUES(@user_id, @name)
+	ON CONFLICT(user_id, name) DO UPDATE SET name = EXCLUDED.name
+	RETURNING id
+	`
+
+	var ID int
+	err := q.QueryRow(ctx, query, pgx.NamedArgs{"user_id": userID, "name": name}).Scan(&ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert wishlist: %v", err)
+	}
+
+	return ID, nil
+}
+
+// addWishlistItem inserts an item into wishlist at current price of its
+// product, merging it into the existing item of the same product.
+func addWishlistItem(ctx context.Context, q querier, wishlistID int, item domain.WishlistItem) error {
+	products, err := getProducts(ctx, q, []int{item.ProductID})
+	if err != nil {
+		return err
+	}
+
+	product, ok := products[item.ProductID]
+	if !ok {
+		return domain.ErrWishlistInvalidProductID
+	}
+
+	query := `
+	INSERT INTO wishlist_items(wishlist_id, product_id, quantity, seen_price)
+	VALUES(@wishlist_id, @product_id, @quantity, @seen_price)
+	ON CONFLICT(wishlist_id, product_id) DO UPDATE
+	SET quantity   = wishlist_items.quantity + EXCLUDED.quantity,
+		seen_price = EXCLUDED.seen_price
+	`
+
+	args := pgx.NamedArgs{
+		"wishlist_id": wishlistID,
+		"product_id":  item.ProductID,
+		"quantity":    item.Quantity,
+		"seen_price":  product.Price,
+	}
+
+	_, err = q.Exec(ctx, query, args)
+	if err != nil {
+		pgErr := pgError(err)
+		if pgErr.Code == pgerrcode.ForeignKeyViolation {
+			if pgErr.ConstraintName == "wishlist_items_product_id_fkey" {
+				return domain.ErrWishlistInvalidProductID
+			}
+		}
+		return fmt.Errorf("failed to insert wishlist item: %v", err)
+	}
+
+	return nil
+}
+
+// commitWishlist marks wishlist as updated, loads it with its items and
+// commits transaction.
+func commitWishlist(ctx context.Context, tx pgx.Tx, ID int) (domain.Wishlist, error) {
+	query := `
+	UPDATE wishlists SET updated_at = NOW()
+	WHERE id = @id
+	RETURNING *
+	`
+
+	wishlist, err := getWishlist(ctx, tx, query, pgx.NamedArgs{"id": ID})
+	if err != nil {
+		return domain.Wishlist{}, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return domain.Wishlist{}, fmt.Errorf("%w: %v", ErrCommitTransaction, err)
+	}
+
+	return wishlist, nil
+}
+
+// fillWishlists loads items of wishlists with their current products.
+func fillWishlists(ctx context.Context, q querier, wishlists []domain.Wishlist) error {
+	ids := make([]int, 0, len(wishlists))
+	for _, w := range wishlists {
+		ids = append(ids, w.ID)
+	}
+
+	query := `
+	SELECT * FROM wishlist_items
+	WHERE wishlist_id = ANY(@ids)
+	ORDER BY added_at, id
+	`
+
+	rows, err := q.Query(ctx, query, pgx.NamedArgs{"ids": ids})
+	if err != nil {
+		return fmt.Errorf("failed to query list wishlist items: %v", err)
+	}
+
+	items, err := pgx.CollectRows(rows, pgx.RowToStructByName[domain.WishlistItem])
+	if err != nil {
+		return fmt.Errorf("failed to scan rows of wishlist items: %v", err)
+	}
+
+	productIDs := make([]int, 0, len(items))
+	for _, item := range items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+
+	products, err := getProducts(ctx, q, productIDs)
+	if err != nil {
+		return err
+	}
+
+	for i := range wishlists {
+		wishlists[i].Items = []domain.WishlistItem{}
+		for _, item := range items {
+			if item.WishlistID == wishlists[i].ID {
+				wishlists[i].Items = append(wishlists[i].Items, item)
+			}
+		}
+		wishlists[i].ApplyProducts(products)
+	}
+
+	return nil
+}
+
+// queuePriceDrops records current price of product on wishlists holding
+// it and queues a notification for every owner who saw it at a higher
+// price.
+func queuePriceDrops(ctx context.Context, q querier, product domain.Product) error {
+	query := `
+	WITH changed AS (
+		UPDATE wishlist_items i
+		SET seen_price = @price
+		FROM wishlist_items old
+		WHERE old.id = i.id AND i.product_id = @product_id AND i.seen_price <> @price
+		RETURNING i.wishlist_id, old.seen_price
+	)
+	SELECT u.email, MAX(c.seen_price) FROM changed c
+	INNER JOIN wishlists w ON w.id = c.wishlist_id
+	INNER JOIN users u ON u.id = w.user_id
+	WHERE c.seen_price > @price
+	GROUP BY u.email
+	ORDER BY u.email
+	`
+
+	rows, err := q.Query(ctx, query, pgx.NamedArgs{"product_id": product.ID, "price": product.Price})
+	if err != nil {
+		return fmt.Errorf("failed to update wishlist items: %v", err)
+	}
+
+	drops := make(map[string]int)
+	var emails []string
+	var email string
+	var from int
+	_, err = pgx.ForEachRow(rows, []any{&email, &from}, func() error {
+		emails = append(emails, email)
+		drops[email] = from
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan rows of price drops: %v", err)
+	}
+
+	for _, email := range emails {
+		err = insertNotification(ctx, q, domain.PriceDropNotification(product, drops[email], email))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
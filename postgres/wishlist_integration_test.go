@@ -0,0 +1,189 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+	"github.com/mortezadadgar/ecommerce-api/postgres"
+)
+
+func TestWishlistService(t *testing.T) {
+	db := newCartTestDB(t, "wishlists")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := postgres.NewWishlistStore(db)
+
+	wishlist := domain.Wishlist{UserID: 1, Name: "Birthday"}
+	err := store.Create(ctx, &wishlist)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	err = store.Create(ctx, &domain.Wishlist{UserID: 1, Name: "Birthday"})
+	if err != domain.ErrDuplicatedWishlist {
+		t.Errorf("expected ErrDuplicatedWishlist, got: %v", err)
+	}
+
+	_, err = store.AddItem(ctx, 1, wishlist.ID, domain.WishlistItem{ProductID: 2, Quantity: 1})
+	if err != domain.ErrWishlistInvalidProductID {
+		t.Errorf("expected ErrWishlistInvalidProductID, got: %v", err)
+	}
+
+	_, err = store.AddItem(ctx, 1, wishlist.ID, domain.WishlistItem{ProductID: 1, Quantity: 1})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	got, err := store.AddItem(ctx, 1, wishlist.ID, domain.WishlistItem{ProductID: 1, Quantity: 2})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	if len(got.Items) != 1 || got.Items[0].Quantity != 3 || got.Items[0].Name != "product" {
+		t.Fatalf("expected merged item of product, got: %+v", got.Items)
+	}
+
+	_, err = store.GetByID(ctx, 2, wishlist.ID)
+	if err != domain.ErrNoWishlistsFound {
+		t.Errorf("expected wishlist of other user not found, got: %v", err)
+	}
+
+	shared, err := store.Share(ctx, 1, wishlist.ID, "token")
+	if err != nil {
+		t.Fatalf("Share: %v", err)
+	}
+
+	if shared.ShareToken == nil || *shared.ShareToken != "token" {
+		t.Errorf("expected share token, got: %v", shared.ShareToken)
+	}
+
+	got, err = store.GetShared(ctx, "token")
+	if err != nil {
+		t.Fatalf("GetShared: %v", err)
+	}
+
+	if got.ID != wishlist.ID || len(got.Items) != 1 {
+		t.Errorf("expected shared wishlist with its items, got: %+v", got)
+	}
+
+	_, err = store.Unshare(ctx, 1, wishlist.ID)
+	if err != nil {
+		t.Fatalf("Unshare: %v", err)
+	}
+
+	_, err = store.GetShared(ctx, "token")
+	if err != domain.ErrNoWishlistsFound {
+		t.Errorf("expected unshared wishlist not found, got: %v", err)
+	}
+
+	cart, err := store.MoveToCart(ctx, 1, wishlist.ID, got.Items[0].ID)
+	if err != nil {
+		t.Fatalf("MoveToCart: %v", err)
+	}
+
+	if len(cart.Items) != 1 || cart.Items[0].Quantity != 3 {
+		t.Fatalf("expected item moved to cart, got: %+v", cart.Items)
+	}
+
+	got, err = store.GetByID(ctx, 1, wishlist.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+
+	if len(got.Items) != 0 {
+		t.Errorf("expected item removed from wishlist, got: %+v", got.Items)
+	}
+
+	// default wishlist is created for items moved without one.
+	got, err = store.MoveFromCart(ctx, 1, cart.Items[0].ID, 0)
+	if err != nil {
+		t.Fatalf("MoveFromCart: %v", err)
+	}
+
+	if got.Name != domain.DefaultWishlistName || len(got.Items) != 1 {
+		t.Errorf("expected item moved to default wishlist, got: %+v", got)
+	}
+
+	cart, err = postgres.NewCartStore(db).GetByOwner(ctx, domain.CartOwner{UserID: 1})
+	if err != nil {
+		t.Fatalf("GetByOwner: %v", err)
+	}
+
+	if len(cart.Items) != 0 {
+		t.Errorf("expected item removed from cart, got: %+v", cart.Items)
+	}
+
+	wishlists, err := store.List(ctx, 1)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+
+	if len(wishlists) != 2 {
+		t.Errorf("expected 2 wishlists, got %d", len(wishlists))
+	}
+}
+
+func TestWishlistService_PriceDrop(t *testing.T) {
+	db := newCartTestDB(t, "wishlists_price_drop")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	products := postgres.NewProductStore(db)
+	store := postgres.NewWishlistStore(db)
+
+	price := 100
+	product, err := products.Update(ctx, 1, domain.ProductUpdate{Price: &price, Version: 1})
+	if err != nil {
+		t.Fatalf("product Update: %v", err)
+	}
+
+	wishlist := domain.Wishlist{UserID: 1, Name: "Birthday"}
+	err = store.Create(ctx, &wishlist)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	_, err = store.AddItem(ctx, 1, wishlist.ID, domain.WishlistItem{ProductID: 1, Quantity: 1})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	price = 120
+	product, err = products.Update(ctx, 1, domain.ProductUpdate{Price: &price, Version: product.Version})
+	if err != nil {
+		t.Fatalf("product Update: %v", err)
+	}
+
+	price = 80
+	_, err = products.Update(ctx, 1, domain.ProductUpdate{Price: &price, Version: product.Version})
+	if err != nil {
+		t.Fatalf("product Update: %v", err)
+	}
+
+	var count int
+	var body string
+	err = db.QueryRow(ctx, `SELECT COUNT(*), MAX(body) FROM notifications WHERE kind = $1`,
+		domain.NotificationPriceDrop).Scan(&count, &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 1 {
+		t.Fatalf("expected 1 price drop notification, got %d", count)
+	}
+
+	got, err := store.GetByID(ctx, 1, wishlist.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+
+	if got.Items[0].SeenPrice != 80 {
+		t.Errorf("expected seen price 80, got %d", got.Items[0].SeenPrice)
+	}
+}
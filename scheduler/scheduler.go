@@ -0,0 +1,82 @@
+// Package scheduler runs periodic jobs of the application, each job is run
+// by one instance at a time.
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mortezadadgar/ecommerce-api/domain"
+)
+
+// Job represents a job run every interval, it returns number of things it
+// handled.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) (int, error)
+}
+
+// Scheduler runs jobs under locks shared by instances, a job is skipped
+// on instances not holding its lock.
+type Scheduler struct {
+	locker domain.Locker
+	jobs   []Job
+}
+
+// New returns a new instance of Scheduler.
+func New(locker domain.Locker) *Scheduler {
+	return &Scheduler{locker: locker}
+}
+
+// Add adds a job to scheduler, jobs must be added before Run.
+func (s *Scheduler) Add(name string, interval time.Duration, run func(ctx context.Context) (int, error)) {
+	s.jobs = append(s.jobs, Job{Name: name, Interval: interval, Run: run})
+}
+
+// Run runs every job on its interval until ctx is done, it returns once
+// running jobs are finished.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, job := range s.jobs {
+		wg.Add(1)
+		go func(job Job) {
+			defer wg.Done()
+			s.runJob(ctx, job)
+		}(job)
+	}
+
+	wg.Wait()
+}
+
+// runJob runs job every interval until ctx is done.
+func (s *Scheduler) runJob(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var handled int
+			_, err := s.locker.TryRun(ctx, job.Name, func(ctx context.Context) error {
+				var err error
+				handled, err = job.Run(ctx)
+				return err
+			})
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("[ERROR]: job %s failed: %v", job.Name, err)
+				}
+				continue
+			}
+
+			if handled > 0 {
+				log.Printf("[JOB]: %s handled %d", job.Name, handled)
+			}
+		}
+	}
+}
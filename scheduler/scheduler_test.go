@@ -0,0 +1,76 @@
+package scheduler_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mortezadadgar/ecommerce-api/scheduler"
+)
+
+// locker is held by another instance for names in busy.
+type locker struct {
+	mu   sync.Mutex
+	busy map[string]bool
+}
+
+func (l *locker) TryRun(ctx context.Context, name string, fn func(ctx context.Context) error) (bool, error) {
+	l.mu.Lock()
+	busy := l.busy[name]
+	l.mu.Unlock()
+
+	if busy {
+		return false, nil
+	}
+
+	return true, fn(ctx)
+}
+
+func TestScheduler(t *testing.T) {
+	jobs := scheduler.New(&locker{busy: map[string]bool{"busy": true}})
+
+	ran := make(chan string, 10)
+	job := func(name string) func(ctx context.Context) (int, error) {
+		return func(ctx context.Context) (int, error) {
+			select {
+			case ran <- name:
+			default:
+			}
+			return 1, nil
+		}
+	}
+
+	jobs.Add("free", time.Millisecond, job("free"))
+	jobs.Add("busy", time.Millisecond, job("busy"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		jobs.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case name := <-ran:
+		if name != "free" {
+			t.Errorf("expected only free job to run, got: %s", name)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected free job to run")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected Run to return once ctx is done")
+	}
+
+	close(ran)
+	for name := range ran {
+		if name != "free" {
+			t.Errorf("expected only free job to run, got: %s", name)
+		}
+	}
+}